@@ -0,0 +1,132 @@
+// Package cache stores conversion and build results on disk, keyed by a hash
+// of everything they depend on, so that unchanged scripts are not converted
+// or compiled again.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// EnvDir is the environment variable overriding the cache directory
+const EnvDir = "BASH2GO_CACHE"
+
+// Cache is a directory of entries, each a set of named files stored under a
+// key
+type Cache struct {
+	dir string
+}
+
+// DefaultDir returns the cache directory: $BASH2GO_CACHE, or bash2go in the
+// user's cache directory
+func DefaultDir() (string, error) {
+	if dir := os.Getenv(EnvDir); dir != "" {
+		return dir, nil
+	}
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "bash2go"), nil
+}
+
+// Open returns the cache in dir, creating the directory if needed
+func Open(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %v", err)
+	}
+	return &Cache{dir: dir}, nil
+}
+
+// Key returns the key of an entry depending on parts. Parts are hashed with
+// their lengths, so that moving text from one part to the next changes the
+// key.
+func Key(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		fmt.Fprintf(h, "%d:", len(part))
+		io.WriteString(h, part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// path returns the path of a file of an entry
+func (c *Cache) path(key, name string) string {
+	return filepath.Join(c.dir, key[:2], key, name)
+}
+
+// Get returns the contents of a file of an entry, reporting false if the
+// entry or file does not exist
+func (c *Cache) Get(key, name string) ([]byte, bool) {
+	data, err := os.ReadFile(c.path(key, name))
+	return data, err == nil
+}
+
+// Put stores data as a file of an entry. Files are written atomically, so
+// concurrent readers never see partial contents.
+func (c *Cache) Put(key, name string, data []byte) error {
+	path := c.path(key, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), name+".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// GetFile copies a file of an entry to dst with the given permissions,
+// reporting false if the entry or file does not exist
+func (c *Cache) GetFile(key, name, dst string, perm fs.FileMode) (bool, error) {
+	data, ok := c.Get(key, name)
+	if !ok {
+		return false, nil
+	}
+	if err := os.WriteFile(dst, data, perm); err != nil {
+		return false, err
+	}
+	// WriteFile keeps the permissions of an existing file
+	return true, os.Chmod(dst, perm)
+}
+
+// PutFile stores the file at src as a file of an entry
+func (c *Cache) PutFile(key, name, src string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return c.Put(key, name, data)
+}
+
+// Clean removes every entry of the cache
+func (c *Cache) Clean() error {
+	entries, err := os.ReadDir(c.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(c.dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestKey tests that keys depend on every part and on how text is split
+// between parts
+func TestKey(t *testing.T) {
+	if Key("a", "b") != Key("a", "b") {
+		t.Error("Expected equal parts to give equal keys")
+	}
+	for _, other := range []string{Key("a", "c"), Key("ab"), Key("a", "b", ""), Key("ab", "")} {
+		if other == Key("a", "b") {
+			t.Errorf("Expected different parts to give different keys")
+		}
+	}
+}
+
+// TestPutGet tests storing and reading entries
+func TestPutGet(t *testing.T) {
+	c, err := Open(filepath.Join(t.TempDir(), "cache"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	key := Key("script")
+
+	if _, ok := c.Get(key, "main.go"); ok {
+		t.Error("Expected a miss in an empty cache")
+	}
+	if err := c.Put(key, "main.go", []byte("package main\n")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if data, ok := c.Get(key, "main.go"); !ok || string(data) != "package main\n" {
+		t.Errorf("Expected the stored file, got %q, %v", data, ok)
+	}
+	if _, ok := c.Get(key, "other"); ok {
+		t.Error("Expected a miss for a file not stored with the entry")
+	}
+
+	// Files are copied in and out with the requested permissions
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err := os.WriteFile(src, []byte("binary"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.PutFile(key, "binary", src); err != nil {
+		t.Fatalf("PutFile failed: %v", err)
+	}
+	dst := filepath.Join(dir, "dst")
+	if err := os.WriteFile(dst, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := c.GetFile(key, "binary", dst, 0755); !ok || err != nil {
+		t.Fatalf("GetFile failed: %v, %v", ok, err)
+	}
+	info, err := os.Stat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "binary" || info.Mode().Perm() != 0755 {
+		t.Errorf("Expected the cached binary with mode 0755, got %q with %v", data, info.Mode().Perm())
+	}
+	if ok, err := c.GetFile(Key("other"), "binary", dst, 0755); ok || err != nil {
+		t.Errorf("Expected a miss for an unknown key, got %v, %v", ok, err)
+	}
+
+	if err := c.Clean(); err != nil {
+		t.Fatalf("Clean failed: %v", err)
+	}
+	if _, ok := c.Get(key, "main.go"); ok {
+		t.Error("Expected a miss after Clean")
+	}
+}
+
+// TestDefaultDir tests overriding the cache directory from the environment
+func TestDefaultDir(t *testing.T) {
+	t.Setenv(EnvDir, "/tmp/bash2go-cache")
+	if dir, err := DefaultDir(); err != nil || dir != "/tmp/bash2go-cache" {
+		t.Errorf("Expected $%s, got %q, %v", EnvDir, dir, err)
+	}
+}
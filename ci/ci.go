@@ -0,0 +1,348 @@
+// Package ci extracts the inline shell scripts of CI workflow files, such as
+// the run steps of GitHub Actions and the script lists of GitLab CI jobs, so
+// that they can be converted to Go like any Bash script.
+package ci
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// Step is the shell script of one CI step
+type Step struct {
+	Job     string   // ID of the job
+	Name    string   // Name of the step, or its index in the job if unnamed
+	Line    int      // Line of the script in the workflow file
+	Workdir string   // Working directory of the step, or empty for the checkout
+	Env     []string // KEY=value pairs set for the step, with shell quoting
+	Shell   string   // Shell running the script, or empty for the default
+	Script  string   // Shell script of the step, with expressions replaced
+
+	// Expressions lists the CI expressions, such as ${{ github.sha }}, that
+	// were replaced by environment variable references
+	Expressions []string
+}
+
+// Standalone returns the script prefixed with the cd and export commands
+// that reproduce the working directory and environment of the step
+func (s Step) Standalone() string {
+	var b strings.Builder
+	if s.Workdir != "" {
+		fmt.Fprintf(&b, "cd %s\n", quote(s.Workdir))
+	}
+	for _, env := range s.Env {
+		fmt.Fprintf(&b, "export %s\n", env)
+	}
+	b.WriteString(s.Script)
+	if !strings.HasSuffix(s.Script, "\n") {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// PosixShell reports whether the shell of the step runs Bash-compatible
+// scripts; an empty shell is the runner's default, which is assumed to be
+// Bash
+func (s Step) PosixShell() bool {
+	fields := strings.Fields(s.Shell)
+	if len(fields) == 0 {
+		return true
+	}
+	switch path.Base(fields[0]) {
+	case "sh", "bash", "dash", "ash":
+		return true
+	}
+	return false
+}
+
+// WorkflowScript combines steps into one Bash script that performs them in
+// order. Each step starts in the directory the script was started in, the
+// workspace, with the working directory and environment of its step.
+func WorkflowScript(steps []Step) string {
+	var b strings.Builder
+	b.WriteString("#!/bin/bash\n")
+	b.WriteString("# Generated by bash2go from-ci: one block per CI step\n")
+	b.WriteString("workspace=\"$PWD\"\n")
+	for i, step := range steps {
+		fmt.Fprintf(&b, "\n# Step %d: job %s, %s (line %d)\n", i+1, step.Job, step.Name, step.Line)
+		b.WriteString("cd \"$workspace\"\n")
+		b.WriteString(step.Standalone())
+	}
+	return b.String()
+}
+
+// expressionPattern matches a CI expression such as ${{ github.sha }}
+var expressionPattern = regexp.MustCompile(`\$\{\{\s*(.*?)\s*\}\}`)
+
+// nonIdentifier matches the characters that cannot appear in a variable name
+var nonIdentifier = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// replaceExpressions replaces the CI expressions of script, which Bash
+// cannot parse, by references to environment variables named after them:
+// ${{ github.sha }} becomes ${GITHUB_SHA}, the variable GitHub Actions sets
+// for it
+func replaceExpressions(script string) (string, []string) {
+	var found []string
+	script = expressionPattern.ReplaceAllStringFunc(script, func(expr string) string {
+		found = append(found, expr)
+		inner := expressionPattern.FindStringSubmatch(expr)[1]
+		name := strings.Trim(nonIdentifier.ReplaceAllString(strings.ToUpper(inner), "_"), "_")
+		if name == "" || name[0] >= '0' && name[0] <= '9' {
+			name = "CI_" + name
+		}
+		return "${" + name + "}"
+	})
+	return script, found
+}
+
+// Parse returns the shell steps of the workflow read from r, in order. Both
+// GitHub Actions workflows, with jobs holding run steps, and GitLab CI
+// pipelines, with jobs holding script lists, are recognized.
+func Parse(r io.Reader) ([]Step, error) {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("empty workflow")
+		}
+		return nil, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("workflow is not a mapping")
+	}
+	root := doc.Content[0]
+
+	if jobs := lookup(root, "jobs"); jobs != nil {
+		return githubSteps(root, jobs)
+	}
+	return gitlabSteps(root)
+}
+
+// githubSteps returns the run steps of a GitHub Actions workflow
+func githubSteps(root, jobs *yaml.Node) ([]Step, error) {
+	if jobs.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: jobs is not a mapping", jobs.Line)
+	}
+	workflowEnv := envPairs(lookup(root, "env"), nil)
+	workflowShell, workflowDir := runDefaults(root)
+
+	var steps []Step
+	for i := 0; i+1 < len(jobs.Content); i += 2 {
+		id, job := jobs.Content[i].Value, jobs.Content[i+1]
+		jobEnv := envPairs(lookup(job, "env"), workflowEnv)
+		shell, dir := runDefaults(job)
+		if shell == "" {
+			shell = workflowShell
+		}
+		if dir == "" {
+			dir = workflowDir
+		}
+
+		list := lookup(job, "steps")
+		if list == nil {
+			continue
+		}
+		if list.Kind != yaml.SequenceNode {
+			return nil, fmt.Errorf("line %d: steps of job %s is not a list", list.Line, id)
+		}
+		for n, step := range list.Content {
+			run := lookup(step, "run")
+			if run == nil {
+				// Steps using actions have nothing to convert
+				continue
+			}
+			if run.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: run of job %s is not a string", run.Line, id)
+			}
+
+			s := Step{
+				Job:   id,
+				Name:  fmt.Sprintf("step %d", n+1),
+				Line:  scriptLine(run),
+				Env:   envPairs(lookup(step, "env"), jobEnv),
+				Shell: shell,
+			}
+			if name := lookup(step, "name"); name != nil {
+				s.Name = name.Value
+			}
+			if v := lookup(step, "shell"); v != nil {
+				s.Shell = v.Value
+			}
+			s.Workdir = dir
+			if v := lookup(step, "working-directory"); v != nil {
+				s.Workdir = v.Value
+			}
+			s.Script, s.Expressions = replaceExpressions(run.Value)
+			steps = append(steps, s)
+		}
+	}
+	return steps, nil
+}
+
+// gitlabKeywords are the top-level keys of a GitLab CI pipeline that are not
+// jobs
+var gitlabKeywords = map[string]bool{
+	"default": true, "include": true, "stages": true, "variables": true,
+	"workflow": true, "image": true, "services": true, "cache": true,
+	"before_script": true, "after_script": true,
+}
+
+// gitlabSteps returns the scripts of the jobs of a GitLab CI pipeline. Each
+// job becomes one step running its before_script, script and after_script.
+func gitlabSteps(root *yaml.Node) ([]Step, error) {
+	globalEnv := envPairs(lookup(root, "variables"), nil)
+	defaults := lookup(root, "default")
+
+	var steps []Step
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		id, job := root.Content[i].Value, root.Content[i+1]
+		// Hidden jobs starting with a dot are templates
+		if gitlabKeywords[id] || strings.HasPrefix(id, ".") || job.Kind != yaml.MappingNode {
+			continue
+		}
+		script := lookup(job, "script")
+		if script == nil {
+			continue
+		}
+
+		var lines []string
+		for _, key := range []string{"before_script", "script", "after_script"} {
+			list := lookup(job, key)
+			if list == nil && key != "script" {
+				// Jobs inherit these from default, or from the top level
+				if list = lookup(defaults, key); list == nil {
+					list = lookup(root, key)
+				}
+			}
+			commands, err := scriptLines(list, id, key)
+			if err != nil {
+				return nil, err
+			}
+			lines = append(lines, commands...)
+		}
+
+		s := Step{
+			Job:  id,
+			Name: "script",
+			Line: script.Line,
+			Env:  envPairs(lookup(job, "variables"), globalEnv),
+		}
+		s.Script, s.Expressions = replaceExpressions(strings.Join(lines, "\n") + "\n")
+		steps = append(steps, s)
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("no GitHub Actions jobs or GitLab CI scripts found")
+	}
+	return steps, nil
+}
+
+// scriptLines returns the commands of a GitLab CI script, which is either a
+// single string or a list of strings
+func scriptLines(list *yaml.Node, job, key string) ([]string, error) {
+	if list == nil {
+		return nil, nil
+	}
+	if list.Kind == yaml.ScalarNode {
+		return []string{list.Value}, nil
+	}
+	if list.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("line %d: %s of job %s is not a list", list.Line, key, job)
+	}
+	var lines []string
+	for _, item := range list.Content {
+		if item.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("line %d: %s of job %s holds a non-string entry", item.Line, key, job)
+		}
+		lines = append(lines, item.Value)
+	}
+	return lines, nil
+}
+
+// runDefaults returns the shell and working directory set under
+// defaults.run of a workflow or job
+func runDefaults(node *yaml.Node) (string, string) {
+	run := lookup(lookup(node, "defaults"), "run")
+	var shell, dir string
+	if v := lookup(run, "shell"); v != nil {
+		shell = v.Value
+	}
+	if v := lookup(run, "working-directory"); v != nil {
+		dir = v.Value
+	}
+	return shell, dir
+}
+
+// envPairs returns inherited with the variables of the env mapping added,
+// overriding inherited ones of the same name. Values are quoted, with CI
+// expressions replaced.
+func envPairs(env *yaml.Node, inherited []string) []string {
+	pairs := append([]string(nil), inherited...)
+	if env == nil || env.Kind != yaml.MappingNode {
+		return pairs
+	}
+	for i := 0; i+1 < len(env.Content); i += 2 {
+		name, value := env.Content[i].Value, env.Content[i+1].Value
+		if v := lookup(env.Content[i+1], "value"); v != nil {
+			// GitLab variables may be given with a description
+			value = v.Value
+		}
+		expanded, exprs := replaceExpressions(value)
+		if len(exprs) > 0 {
+			// Keep the variable references expandable
+			value = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "\\`").Replace(expanded) + `"`
+		} else {
+			value = quote(value)
+		}
+
+		pair := name + "=" + value
+		replaced := false
+		for j, p := range pairs {
+			if strings.HasPrefix(p, name+"=") {
+				pairs[j] = pair
+				replaced = true
+			}
+		}
+		if !replaced {
+			pairs = append(pairs, pair)
+		}
+	}
+	return pairs
+}
+
+// lookup returns the value of key in a mapping node, or nil if node is not
+// a mapping or has no such key
+func lookup(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// scriptLine returns the line the script of a run node starts on, which
+// follows the indicator line of block scalars
+func scriptLine(run *yaml.Node) int {
+	if run.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		return run.Line + 1
+	}
+	return run.Line
+}
+
+// quote quotes s as a single Bash word
+func quote(s string) string {
+	quoted, err := syntax.Quote(s, syntax.LangBash)
+	if err != nil {
+		// Only strings with null bytes cannot be quoted
+		return fmt.Sprintf("%q", s)
+	}
+	return quoted
+}
@@ -0,0 +1,110 @@
+package ci
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestParseGitHub tests extracting the run steps of a GitHub Actions workflow
+func TestParseGitHub(t *testing.T) {
+	workflow := `name: CI
+on: push
+env:
+  GOFLAGS: -mod=readonly
+defaults:
+  run:
+    working-directory: src
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    env:
+      TAG: ${{ github.sha }}
+    steps:
+      - uses: actions/checkout@v4
+      - name: Build
+        run: |
+          make build
+          echo "built ${{ github.ref_name }}"
+      - run: make test
+        working-directory: tests
+        env:
+          GOFLAGS: -v
+      - name: Windows only
+        shell: pwsh
+        run: Write-Output hi
+`
+	steps, err := Parse(strings.NewReader(workflow))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(steps) != 3 {
+		t.Fatalf("Expected 3 run steps, got %d: %+v", len(steps), steps)
+	}
+
+	build := steps[0]
+	if build.Job != "build" || build.Name != "Build" || build.Line != 17 || build.Workdir != "src" {
+		t.Errorf("Unexpected context for the build step: %+v", build)
+	}
+	if build.Script != "make build\necho \"built ${GITHUB_REF_NAME}\"\n" || len(build.Expressions) != 1 {
+		t.Errorf("Expected expressions to be replaced, got %q", build.Script)
+	}
+	if strings.Join(build.Env, " ") != `GOFLAGS='-mod=readonly' TAG="${GITHUB_SHA}"` {
+		t.Errorf("Unexpected environment: %q", build.Env)
+	}
+
+	test := steps[1]
+	if test.Name != "step 3" || test.Workdir != "tests" || strings.Join(test.Env, " ") != `GOFLAGS=-v TAG="${GITHUB_SHA}"` {
+		t.Errorf("Unexpected context for the test step: %+v", test)
+	}
+	if !build.PosixShell() || steps[2].PosixShell() {
+		t.Errorf("Expected only the pwsh step to need a non-POSIX shell")
+	}
+}
+
+// TestParseGitLab tests extracting the scripts of GitLab CI jobs
+func TestParseGitLab(t *testing.T) {
+	pipeline := `stages: [build]
+variables:
+  CGO_ENABLED: "0"
+default:
+  before_script:
+    - go version
+.template:
+  script: echo hidden
+build:
+  stage: build
+  script:
+    - go build ./...
+    - go test ./...
+`
+	steps, err := Parse(strings.NewReader(pipeline))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(steps) != 1 {
+		t.Fatalf("Expected 1 job, got %d: %+v", len(steps), steps)
+	}
+	if steps[0].Job != "build" || steps[0].Script != "go version\ngo build ./...\ngo test ./...\n" {
+		t.Errorf("Unexpected job: %+v", steps[0])
+	}
+	if len(steps[0].Env) != 1 || steps[0].Env[0] != "CGO_ENABLED=0" {
+		t.Errorf("Unexpected environment: %q", steps[0].Env)
+	}
+}
+
+// TestWorkflowScript tests combining steps into one script
+func TestWorkflowScript(t *testing.T) {
+	script := WorkflowScript([]Step{
+		{Job: "build", Name: "Build", Line: 3, Workdir: "src", Script: "make"},
+		{Job: "build", Name: "Test", Line: 5, Script: "make test\n"},
+	})
+	for _, want := range []string{
+		"workspace=\"$PWD\"\n",
+		"# Step 1: job build, Build (line 3)\ncd \"$workspace\"\ncd src\nmake\n",
+		"# Step 2: job build, Test (line 5)\ncd \"$workspace\"\nmake test\n",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Script does not contain %q:\n%s", want, script)
+		}
+	}
+}
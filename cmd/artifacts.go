@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/TFMV/bash2go/compiler"
+)
+
+// checkSignMethod verifies the --sign flag before anything is built
+func checkSignMethod() error {
+	switch signMethod {
+	case "", compiler.SignCosign, compiler.SignGPG:
+		return nil
+	default:
+		return fmt.Errorf("unknown signing method %q (want %q or %q)", signMethod, compiler.SignCosign, compiler.SignGPG)
+	}
+}
+
+// publishArtifacts signs the built binaries and writes their checksums to
+// SHA256SUMS in dir, as requested by the --sign and --checksums flags
+func publishArtifacts(binaries []string, dir string) error {
+	if signMethod != "" {
+		for _, binary := range binaries {
+			sig, err := compiler.SignBinary(binary, signMethod, signKey)
+			if err != nil {
+				return err
+			}
+			logf("Signature saved to %s\n", sig)
+		}
+	}
+
+	if checksums {
+		path := filepath.Join(dir, "SHA256SUMS")
+		if err := compiler.WriteChecksums(path, binaries); err != nil {
+			return err
+		}
+		logf("Checksums saved to %s\n", path)
+	}
+	return nil
+}
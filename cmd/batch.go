@@ -0,0 +1,354 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TFMV/bash2go/compiler"
+	"github.com/TFMV/bash2go/diagnostics"
+	"github.com/TFMV/bash2go/generator"
+)
+
+// programName returns the binary name for a script, its base name without
+// the .sh extension
+func programName(script string) string {
+	return strings.TrimSuffix(filepath.Base(script), ".sh")
+}
+
+// batchResult is the outcome of converting and building one script of a batch
+type batchResult struct {
+	report  conversionReport
+	gen     *generator.GoCodeGenerator
+	program compiler.Program
+	info    generator.BuildInfo
+	err     error
+
+	status    string        // One of the batch report statuses
+	buildTime time.Duration // Time spent compiling, over all targets
+}
+
+// buildBatch converts several Bash scripts and compiles them together into
+// binaries in outDir. Scripts are processed concurrently, and a failing
+// script does not stop the others.
+func buildBatch(scripts []string, outDir string) error {
+	options, err := generatorOptions()
+	if err != nil {
+		return err
+	}
+
+	// Fail early if the Go toolchain cannot build the result
+	if err := compiler.CheckGoVersion(buildOptions("", "")); err != nil {
+		return err
+	}
+	if err := checkSignMethod(); err != nil {
+		return err
+	}
+	platforms, err := buildTargets()
+	if err != nil {
+		return err
+	}
+	nameTmpl, err := outputTemplateFor(platforms)
+	if err != nil {
+		return err
+	}
+
+	options.BuildInfo = true
+
+	logf("Converting %d scripts to Go and compiling to %s\n", len(scripts), outDir)
+
+	tempDir, err := os.MkdirTemp("", "bash2go-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Generate every program before compiling any of them. Scripts convert
+	// concurrently, so only the batch as a whole is reported as a phase.
+	start := time.Now()
+	p := newProgress()
+	done := p.phase("convert")
+	results := make([]batchResult, len(scripts))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, batchJobs())
+	for i, script := range scripts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, script string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[i] = convertForBatch(script, outDir, tempDir, options)
+		}(i, script)
+	}
+	wg.Wait()
+	done()
+
+	// Compile the programs that converted cleanly in one shared module
+	var programs []compiler.Program
+	generators := make(map[string]*generator.GoCodeGenerator)
+	byName := make(map[string]*batchResult)
+	for i := range results {
+		result := &results[i]
+		if result.err != nil {
+			result.status = statusConvertFailed
+			continue
+		}
+		programs = append(programs, result.program)
+		generators["cmd/"+result.program.Name+"/main.go"] = result.gen
+		byName[result.program.Name] = result
+	}
+
+	findings := diagnostics.NewCollector()
+	buildOptions := buildOptions("", "")
+	buildOptions.Diagnostics = findings
+	buildOptions.Progress = p.phase
+	buildOptions.SourceMap = func(goFile string, goLine int) string {
+		if gen, ok := generators[goFile]; ok {
+			return gen.SourcePosition(goLine).String()
+		}
+		return ""
+	}
+	buildCache := commandCache()
+	var goVersion string
+	if buildCache != nil {
+		if goVersion, err = compiler.GoVersion(buildOptions); err != nil {
+			return err
+		}
+	}
+	for _, target := range platforms {
+		if len(programs) == 0 {
+			break
+		}
+
+		// Name the binaries for the target
+		targetPrograms := make([]compiler.Program, len(programs))
+		for i, program := range programs {
+			if program.Output, err = targetOutput(nameTmpl, program.Name, target); err != nil {
+				return err
+			}
+			targetPrograms[i] = program
+		}
+
+		targetOptions := buildOptions
+		target.apply(&targetOptions)
+
+		// Restore the binaries built before from the same code and options
+		// from the cache, and build the rest
+		var toBuild []compiler.Program
+		keys := make(map[string]string)
+		for _, program := range targetPrograms {
+			if buildCache != nil {
+				key, err := binaryKey(program, byName[program.Name].info, targetOptions, goVersion)
+				if err != nil {
+					return err
+				}
+				output := filepath.Join(outDir, program.Output)
+				if restoreBinary(buildCache, key, output, findings) {
+					result := byName[program.Name]
+					result.report.Binaries = append(result.report.Binaries, output)
+					continue
+				}
+				keys[program.Name] = key
+			}
+			toBuild = append(toBuild, program)
+		}
+		if len(toBuild) == 0 {
+			continue
+		}
+
+		built, err := compiler.BuildGoPrograms(toBuild, outDir, targetOptions)
+		if err != nil {
+			findings.WriteSummary(os.Stderr)
+			return fmt.Errorf("failed to build Go programs for %s: %v", target, err)
+		}
+		for i, b := range built {
+			result := byName[b.Name]
+			result.buildTime += b.Duration
+			if b.Err != nil {
+				if result.err == nil {
+					result.err = b.Err
+					if len(platforms) > 1 {
+						result.err = fmt.Errorf("%s: %v", target, b.Err)
+					}
+				}
+				continue
+			}
+			output := filepath.Join(outDir, toBuild[i].Output)
+			result.report.Binaries = append(result.report.Binaries, output)
+			if key, ok := keys[b.Name]; ok {
+				if err := storeBinary(buildCache, key, b.Name, output, findings); err != nil {
+					logf("Not caching %s: %v\n", output, err)
+				}
+			}
+		}
+	}
+
+	// Attribute findings from vetting the shared module to their scripts
+	for _, d := range findings.Items() {
+		if result, ok := byName[findingProgram(d)]; ok {
+			result.report.Diagnostics = append(result.report.Diagnostics, d)
+		}
+	}
+
+	// Aggregate the per-script results
+	var failed int
+	var reports []conversionReport
+	var binaries []string
+	for i := range results {
+		result := &results[i]
+		if result.err == nil {
+			result.report.Compiled = true
+			result.report.Output = result.report.Binaries[0]
+		}
+		if result.err == nil && reproduce {
+			// Print the checksums so builds can be verified independently
+			for _, binary := range result.report.Binaries {
+				var checksum string
+				if checksum, result.err = compiler.FileSHA256(binary); result.err != nil {
+					break
+				}
+				logf("SHA256 (%s) = %s\n", binary, checksum)
+				if len(result.report.Binaries) == 1 {
+					result.report.SHA256 = checksum
+				}
+			}
+		}
+		if result.status == "" {
+			result.status = statusOK
+			if result.err != nil {
+				result.status = statusBuildFailed
+			}
+		}
+		if result.err != nil {
+			failed++
+			result.report.Error = result.err.Error()
+			fmt.Fprintf(os.Stderr, "FAIL %s: %v\n", result.report.Input, result.err)
+			for _, d := range result.report.Diagnostics {
+				if d.Severity == diagnostics.SeverityError {
+					fmt.Fprintf(os.Stderr, "  %s\n", d)
+				}
+			}
+		} else {
+			binaries = append(binaries, result.report.Binaries...)
+			if !jsonOutput {
+				fmt.Printf("ok   %s: %s\n", result.report.Input, result.report.Metrics)
+			}
+		}
+		reports = append(reports, result.report)
+	}
+
+	logf("Compiled %d of %d binaries to %s\n", len(scripts)-failed, len(scripts), outDir)
+	p.summary()
+
+	if err := publishArtifacts(binaries, outDir); err != nil {
+		return err
+	}
+
+	if reportFile != "" {
+		report, err := newBatchReport(results, outDir, platforms, start)
+		if err != nil {
+			return err
+		}
+		if err := writeBatchReport(reportFile, report); err != nil {
+			return err
+		}
+		logf("Batch report saved to %s\n", reportFile)
+	}
+	if sarifFile != "" {
+		var items []diagnostics.Diagnostic
+		for _, report := range reports {
+			items = append(items, report.Diagnostics...)
+		}
+		if err := writeSARIF(sarifFile, scripts, items); err != nil {
+			return fmt.Errorf("failed to write SARIF: %v", err)
+		}
+	}
+
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(reports); err != nil {
+			return err
+		}
+	} else if err := findings.WriteSummary(os.Stdout); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d scripts failed", failed, len(scripts))
+	}
+	return nil
+}
+
+// convertForBatch converts one script of a batch, writing its generated code
+// to tempDir
+func convertForBatch(script, outDir, tempDir string, options generator.Options) (result batchResult) {
+	name := programName(script)
+	result.report = conversionReport{
+		Input:  script,
+		Output: filepath.Join(outDir, name),
+	}
+
+	gen, goCode, err := generateGo(script, options, nil)
+	if err != nil {
+		if gen != nil {
+			result.report.Diagnostics = gen.IR.Diagnostics.Items()
+		}
+		result.err = err
+		return result
+	}
+	result.gen = gen
+	result.report.Metrics = gen.Metrics()
+	defer func() {
+		result.report.Diagnostics = gen.IR.Diagnostics.Items()
+	}()
+
+	if err := gen.TypeCheck(goCode); err != nil {
+		result.err = fmt.Errorf("failed to generate valid Go code: %v", err)
+		return result
+	}
+
+	goFile := filepath.Join(tempDir, name+".go")
+	if err := os.WriteFile(goFile, []byte(goCode), 0644); err != nil {
+		result.err = fmt.Errorf("failed to write Go code to file: %v", err)
+		return result
+	}
+
+	info, err := scriptBuildInfo(script)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	result.info = info
+	result.program = compiler.Program{
+		Name:   name,
+		GoFile: goFile,
+		Files:  map[string]string{"bash2go_info.go": info.GoSource()},
+	}
+	return result
+}
+
+// findingProgram returns the name of the program a vet finding in the shared
+// batch module belongs to, or an empty string
+func findingProgram(d diagnostics.Diagnostic) string {
+	parts := strings.Split(filepath.ToSlash(d.File), "/")
+	if len(parts) == 3 && parts[0] == "cmd" {
+		return parts[1]
+	}
+	return ""
+}
+
+// batchJobs returns the number of scripts processed at once
+func batchJobs() int {
+	if jobs > 0 {
+		return jobs
+	}
+	return runtime.NumCPU()
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/TFMV/bash2go/compiler"
+	"github.com/TFMV/bash2go/diagnostics"
+)
+
+// Script statuses in batch reports
+const (
+	statusOK            = "ok"
+	statusConvertFailed = "conversion_failed"
+	statusBuildFailed   = "build_failed"
+)
+
+// batchReport is the consolidated result of a batch build written by
+// --report, meant for dashboards tracking a migration across many scripts
+type batchReport struct {
+	Version    string         `json:"bash2go_version"`
+	Time       string         `json:"time"`
+	OutDir     string         `json:"out_dir"`
+	Targets    []string       `json:"targets"`
+	DurationMS int64          `json:"duration_ms"`
+	Summary    batchSummary   `json:"summary"`
+	Scripts    []scriptReport `json:"scripts"`
+}
+
+// batchSummary aggregates the scripts of a batch report
+type batchSummary struct {
+	Scripts          int     `json:"scripts"`
+	Succeeded        int     `json:"succeeded"`
+	ConversionFailed int     `json:"conversion_failed"`
+	BuildFailed      int     `json:"build_failed"`
+	NativePercent    float64 `json:"native_percent"` // Over the statements of all scripts
+	Native           int     `json:"native"`
+	ExecFallbacks    int     `json:"exec_fallbacks"`
+	Unsupported      int     `json:"unsupported"`
+	Warnings         int     `json:"warnings"`
+	Errors           int     `json:"errors"`
+}
+
+// scriptReport is the entry of one script in a batch report
+type scriptReport struct {
+	Status          string  `json:"status"`
+	NativePercent   float64 `json:"native_percent"`
+	Warnings        int     `json:"warnings"`
+	Errors          int     `json:"errors"`
+	BuildDurationMS int64   `json:"build_duration_ms,omitempty"`
+	conversionReport
+}
+
+// newBatchReport summarizes the results of a batch build that started at
+// start
+func newBatchReport(results []batchResult, outDir string, targets []buildTarget, start time.Time) (batchReport, error) {
+	reportTime := start
+	if reproduce {
+		var err error
+		if reportTime, err = compiler.SourceDateEpoch(); err != nil {
+			return batchReport{}, err
+		}
+	}
+
+	report := batchReport{
+		Version:    version(),
+		Time:       reportTime.UTC().Format(time.RFC3339),
+		OutDir:     outDir,
+		DurationMS: time.Since(start).Milliseconds(),
+		Summary:    batchSummary{Scripts: len(results)},
+	}
+	for _, target := range targets {
+		report.Targets = append(report.Targets, target.String())
+	}
+
+	for _, result := range results {
+		entry := scriptReport{
+			Status:           result.status,
+			NativePercent:    result.report.Metrics.NativePercent(),
+			BuildDurationMS:  result.buildTime.Milliseconds(),
+			conversionReport: result.report,
+		}
+		for _, d := range result.report.Diagnostics {
+			switch d.Severity {
+			case diagnostics.SeverityWarning:
+				entry.Warnings++
+			case diagnostics.SeverityError:
+				entry.Errors++
+			}
+		}
+
+		summary := &report.Summary
+		switch entry.Status {
+		case statusOK:
+			summary.Succeeded++
+		case statusConvertFailed:
+			summary.ConversionFailed++
+		case statusBuildFailed:
+			summary.BuildFailed++
+		}
+		summary.Native += entry.Metrics.Native
+		summary.ExecFallbacks += entry.Metrics.ExecFallbacks
+		summary.Unsupported += entry.Metrics.Unsupported
+		summary.Warnings += entry.Warnings
+		summary.Errors += entry.Errors
+		report.Scripts = append(report.Scripts, entry)
+	}
+
+	total := report.Summary.Native + report.Summary.ExecFallbacks + report.Summary.Unsupported
+	report.Summary.NativePercent = 100
+	if total > 0 {
+		report.Summary.NativePercent = float64(report.Summary.Native) * 100 / float64(total)
+	}
+	return report, nil
+}
+
+// writeBatchReport writes report as JSON to path
+func writeBatchReport(path string, report batchReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write batch report: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/TFMV/bash2go/cache"
+	"github.com/TFMV/bash2go/compiler"
+	"github.com/TFMV/bash2go/diagnostics"
+	"github.com/TFMV/bash2go/generator"
+	"github.com/TFMV/bash2go/parser"
+	"github.com/spf13/cobra"
+)
+
+var noCache bool
+
+// buildEnv lists the environment variables that change the binaries built
+// from the same code
+var buildEnv = []string{"CGO_ENABLED", "GOFLAGS", "GOAMD64", "GOARM", "GOARM64", "GOEXPERIMENT"}
+
+func init() {
+	// Add cache command
+	cacheCmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the cache of generated code and binaries",
+		Long: `bash2go caches the generated code of scripts checked by the hook and the
+binaries built by build --out-dir, keyed by a hash of everything they depend
+on, so unchanged scripts are skipped. The cache lives in $` + cache.EnvDir + `, or
+bash2go in the user's cache directory. Pass --no-cache to bypass it.`,
+	}
+	cacheCmd.AddCommand(&cobra.Command{
+		Use:   "dir",
+		Short: "Print the cache directory",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := cache.DefaultDir()
+			if err != nil {
+				return err
+			}
+			fmt.Println(dir)
+			return nil
+		},
+	}, &cobra.Command{
+		Use:   "clean",
+		Short: "Remove every cached entry",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := openCache()
+			if err != nil {
+				return err
+			}
+			return c.Clean()
+		},
+	})
+	rootCmd.AddCommand(cacheCmd)
+}
+
+// openCache opens the default cache
+func openCache() (*cache.Cache, error) {
+	dir, err := cache.DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+	return cache.Open(dir)
+}
+
+// commandCache returns the cache used by a command, or nil if --no-cache is
+// set or the cache cannot be opened, which only costs speed
+func commandCache() *cache.Cache {
+	if noCache {
+		return nil
+	}
+	c, err := openCache()
+	if err != nil {
+		logf("Not using the cache: %v\n", err)
+		return nil
+	}
+	return c
+}
+
+// conversionKey returns the cache key of the generated code of a script
+// with the given content converted with options
+func conversionKey(script string, content []byte, options generator.Options) (string, error) {
+	fingerprint, err := json.Marshal(options)
+	if err != nil {
+		return "", err
+	}
+	return cache.Key("code", version(), script, string(content), string(fingerprint)), nil
+}
+
+// cacheableConversion reports whether the code generated for ir with options
+// depends only on the script and options. Plugins, variables resolved from
+// the environment at conversion time and scripts included with source make
+// it depend on more.
+func cacheableConversion(options generator.Options, ir *parser.IntermediateRepresentation) bool {
+	if options.Plugins || options.DefaultEnvPolicy == parser.EnvConvert || len(ir.Sources) > 0 {
+		return false
+	}
+	for _, policies := range []map[string]parser.EnvPolicy{options.EnvPolicies, ir.EnvPolicies} {
+		for _, policy := range policies {
+			if policy == parser.EnvConvert {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// cachedConversion returns the diagnostics and generated code of a cached
+// conversion, reporting false on a cache miss
+func cachedConversion(c *cache.Cache, key string) ([]diagnostics.Diagnostic, string, bool) {
+	data, ok := c.Get(key, "diagnostics.json")
+	if !ok {
+		return nil, "", false
+	}
+	var items []diagnostics.Diagnostic
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, "", false
+	}
+	goCode, ok := c.Get(key, "main.go")
+	if !ok {
+		return nil, "", false
+	}
+	return items, string(goCode), true
+}
+
+// storeConversion caches the diagnostics and generated code of a conversion
+func storeConversion(c *cache.Cache, key string, items []diagnostics.Diagnostic, goCode string) error {
+	data, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	// The diagnostics are written last, marking the entry complete
+	if err := c.Put(key, "main.go", []byte(goCode)); err != nil {
+		return err
+	}
+	return c.Put(key, "diagnostics.json", data)
+}
+
+// binaryKey returns the cache key of the binary of a program built with
+// options by the given Go toolchain version. The conversion time in info only
+// counts for reproducible builds; otherwise a restored binary reports the
+// time its script was first converted.
+func binaryKey(program compiler.Program, info generator.BuildInfo, options compiler.BuildOptions, goVersion string) (string, error) {
+	code, err := os.ReadFile(program.GoFile)
+	if err != nil {
+		return "", err
+	}
+	if !options.Reproducible {
+		info.Converted = ""
+	}
+	parts := []string{"binary", version(), goVersion, program.Name, string(code), info.GoSource(),
+		fmt.Sprint(options.GOOS, options.GOARCH, options.Static, options.LDFlags, options.Small, options.Compress,
+			options.Vet, options.Staticcheck, options.Reproducible, options.Tags)}
+	for _, name := range buildEnv {
+		parts = append(parts, os.Getenv(name))
+	}
+	return cache.Key(parts...), nil
+}
+
+// restoreBinary copies a cached binary to output and adds the vet findings
+// recorded with it to findings, reporting false on a cache miss
+func restoreBinary(c *cache.Cache, key, output string, findings *diagnostics.Collector) bool {
+	data, ok := c.Get(key, "findings.json")
+	if !ok {
+		return false
+	}
+	var items []diagnostics.Diagnostic
+	if err := json.Unmarshal(data, &items); err != nil {
+		return false
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return false
+	}
+	if ok, err := c.GetFile(key, "binary", output, 0755); !ok || err != nil {
+		return false
+	}
+	for _, d := range items {
+		findings.Add(d)
+	}
+	return true
+}
+
+// storeBinary caches a built binary with the vet findings of its program
+func storeBinary(c *cache.Cache, key, name, binary string, findings *diagnostics.Collector) error {
+	items := []diagnostics.Diagnostic{}
+	for _, d := range findings.Items() {
+		if findingProgram(d) == name {
+			items = append(items, d)
+		}
+	}
+	data, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	// The findings are written last, marking the entry complete
+	if err := c.PutFile(key, "binary", binary); err != nil {
+		return err
+	}
+	return c.Put(key, "findings.json", data)
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/TFMV/bash2go/ci"
+	"github.com/TFMV/bash2go/compiler"
+	"github.com/TFMV/bash2go/pkg/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	ciPerStep bool
+	ciJob     string
+	ciBuild   bool
+)
+
+// unsafeName matches the characters of job IDs that are replaced in
+// directory names
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+func init() {
+	// Add from-ci command
+	ciCmd := &cobra.Command{
+		Use:   "from-ci [workflow file]",
+		Short: "Convert the inline shell steps of a CI workflow to Go",
+		Long: `from-ci extracts the inline shell scripts of a CI workflow, the run steps
+of GitHub Actions or the scripts of GitLab CI jobs, and converts them to Go so
+that CI logic can be tested and run anywhere. Each step is converted with the
+working directory and environment set for it in the workflow. Expressions
+such as ${{ github.sha }} become environment variables such as $GITHUB_SHA.
+
+By default all steps become one program in <output> that performs them in
+order. With --per-step, each step becomes its own program in
+<output>/<job>/step-N.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return convertWorkflow(args[0], outputFile)
+		},
+	}
+	ciCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output directory for the generated Go code (required)")
+	ciCmd.MarkFlagRequired("output")
+	ciCmd.Flags().BoolVar(&ciPerStep, "per-step", false, "Emit one program per step instead of a single workflow program")
+	ciCmd.Flags().StringVar(&ciJob, "job", "", "Only convert the steps of this job")
+	ciCmd.Flags().BoolVar(&ciBuild, "build", false, "Also compile each program to a binary next to its Go code")
+	addEnvFlags(ciCmd)
+	addToolchainFlags(ciCmd)
+	rootCmd.AddCommand(ciCmd)
+}
+
+// convertWorkflow converts the shell steps of the CI workflow at path to Go
+// code in outDir
+func convertWorkflow(path, outDir string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	steps, err := ci.Parse(file)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	// Keep the steps a shell script can express
+	var selected []ci.Step
+	for _, step := range steps {
+		if ciJob != "" && step.Job != ciJob {
+			continue
+		}
+		if !step.PosixShell() {
+			logf("Skipping %s of job %s at line %d: shell %q is not a POSIX shell\n", step.Name, step.Job, step.Line, step.Shell)
+			continue
+		}
+		for _, expr := range step.Expressions {
+			logf("Line %d: %s is read from the environment\n", step.Line, expr)
+		}
+		selected = append(selected, step)
+	}
+	if len(selected) == 0 {
+		return fmt.Errorf("no shell steps to convert in %s", path)
+	}
+
+	genOptions, err := generatorOptions()
+	if err != nil {
+		return err
+	}
+	if ciBuild {
+		if err := compiler.CheckGoVersion(buildOptions("", "")); err != nil {
+			return err
+		}
+	}
+	options := api.Options{
+		EnvPolicy:  string(genOptions.DefaultEnvPolicy),
+		ResolveEnv: resolveEnv,
+		RuntimeEnv: runtimeEnv,
+		TypeCheck:  true,
+	}
+
+	if !ciPerStep {
+		logf("Converting %d steps of %s to a workflow program in %s\n", len(selected), path, outDir)
+		if err := convertSnippet(outDir, "workflow.sh", ci.WorkflowScript(selected), options); err != nil {
+			return err
+		}
+		return buildSnippet(outDir, "workflow")
+	}
+
+	logf("Converting %d steps of %s to Go in %s\n", len(selected), path, outDir)
+	var failed int
+	counts := make(map[string]int)
+	for _, step := range selected {
+		job := unsafeName.ReplaceAllString(step.Job, "-")
+		counts[job]++
+		name := fmt.Sprintf("step-%d", counts[job])
+		dir := filepath.Join(outDir, job, name)
+		logf("%s/%s: %s at line %d\n", job, name, step.Name, step.Line)
+		err := convertSnippet(dir, "run.sh", step.Standalone(), options)
+		if err == nil {
+			err = buildSnippet(dir, job+"-"+name)
+		}
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "%s/%s: %v\n", job, name, err)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d steps failed to convert", failed, len(selected))
+	}
+	return nil
+}
+
+// buildSnippet compiles dir/main.go to the binary dir/name with --build
+func buildSnippet(dir, name string) error {
+	if !ciBuild {
+		return nil
+	}
+	binary := filepath.Join(dir, name)
+	if err := compiler.BuildGoProgram(buildOptions(binary, filepath.Join(dir, "main.go"))); err != nil {
+		return fmt.Errorf("failed to build Go program: %v", err)
+	}
+	logf("  Compiled binary saved to %s\n", binary)
+	return nil
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// cronBuild makes from-cron compile the scheduler instead of writing Go code
+var cronBuild bool
+
+func init() {
+	// Add from-cron command
+	cronCmd := &cobra.Command{
+		Use:   "from-cron [crontab entry] [bash script]",
+		Short: "Convert a crontab entry and its script to a long-running scheduler program",
+		Long: `from-cron takes a crontab entry, such as "*/5 * * * * /opt/jobs/backup.sh",
+and generates a Go program that runs the translated script on the entry's
+schedule until it is stopped, replacing cron and Bash with one supervised
+binary. A run is skipped while the previous one is still going.
+
+The script is taken from the entry's command unless given as the second
+argument, which is required when the command is more than a script path.`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			spec, command, err := parseCrontabEntry(args[0])
+			if err != nil {
+				return err
+			}
+
+			var script string
+			if len(args) > 1 {
+				script = args[1]
+			} else if script, err = crontabScript(command); err != nil {
+				return err
+			}
+
+			schedule = spec
+			return convertBashToGo(script, outputFile, cronBuild)
+		},
+	}
+	cronCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output Go file, or binary with --build (required)")
+	cronCmd.MarkFlagRequired("output")
+	cronCmd.Flags().BoolVar(&cronBuild, "build", false, "Compile the scheduler to a binary")
+	addConversionFlags(cronCmd)
+	rootCmd.AddCommand(cronCmd)
+}
+
+// parseCrontabEntry splits a crontab entry into its schedule, either five
+// time fields or an @ descriptor such as @daily, and its command
+func parseCrontabEntry(entry string) (string, string, error) {
+	fields := strings.Fields(entry)
+	if len(fields) > 1 && fields[0] == "@every" {
+		// The interval is part of the schedule
+		return strings.Join(fields[:2], " "), strings.Join(fields[2:], " "), nil
+	}
+	if len(fields) > 0 && strings.HasPrefix(fields[0], "@") {
+		return fields[0], strings.Join(fields[1:], " "), nil
+	}
+	if len(fields) < 5 {
+		return "", "", fmt.Errorf("invalid crontab entry %q: expected five time fields or an @ descriptor", entry)
+	}
+	return strings.Join(fields[:5], " "), strings.Join(fields[5:], " "), nil
+}
+
+// crontabScript returns the script run by the command of a crontab entry,
+// such as "/opt/jobs/backup.sh" or "bash /opt/jobs/backup.sh"
+func crontabScript(command string) (string, error) {
+	fields := strings.Fields(command)
+	if len(fields) > 1 {
+		switch filepath.Base(fields[0]) {
+		case "bash", "sh":
+			fields = fields[1:]
+		}
+	}
+	if len(fields) != 1 {
+		return "", fmt.Errorf("cannot tell the script from the command %q; pass it as the second argument", command)
+	}
+	return fields[0], nil
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/TFMV/bash2go/dockerfile"
+	"github.com/TFMV/bash2go/pkg/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dockerBuilder bool
+	dockerStage   string
+)
+
+func init() {
+	// Add from-dockerfile command
+	dockerCmd := &cobra.Command{
+		Use:   "from-dockerfile [Dockerfile]",
+		Short: "Convert the RUN instructions of a Dockerfile to Go",
+		Long: `from-dockerfile extracts the shell snippets of RUN instructions, including
+heredoc RUNs, and converts them to Go. Each snippet is converted with the
+working directory and environment set by earlier WORKDIR, ENV and ARG
+instructions of its stage.
+
+By default each RUN becomes its own program in <output>/step-N. With
+--builder, all RUNs become one builder program in <output> that performs them
+in order, replacing a chain of RUN instructions.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := "Dockerfile"
+			if len(args) > 0 {
+				path = args[0]
+			}
+			return convertDockerfile(path, outputFile)
+		},
+	}
+	dockerCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output directory for the generated Go code (required)")
+	dockerCmd.MarkFlagRequired("output")
+	dockerCmd.Flags().BoolVar(&dockerBuilder, "builder", false, "Emit a single builder program performing every RUN in order")
+	dockerCmd.Flags().StringVar(&dockerStage, "stage", "", "Only convert the RUN instructions of this build stage (name or index)")
+	addEnvFlags(dockerCmd)
+	rootCmd.AddCommand(dockerCmd)
+}
+
+// convertDockerfile converts the RUN instructions of the Dockerfile at path
+// to Go code in outDir
+func convertDockerfile(path, outDir string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	runs, err := dockerfile.Parse(file)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	// Keep the instructions a shell script can express
+	var selected []dockerfile.Run
+	for _, run := range runs {
+		if dockerStage != "" && run.Stage != dockerStage {
+			continue
+		}
+		if !posixShell(run.Shell) {
+			logf("Skipping RUN at line %d: SHELL %q is not a POSIX shell\n", run.Line, run.Shell)
+			continue
+		}
+		selected = append(selected, run)
+	}
+	if len(selected) == 0 {
+		return fmt.Errorf("no RUN instructions to convert in %s", path)
+	}
+
+	genOptions, err := generatorOptions()
+	if err != nil {
+		return err
+	}
+	options := api.Options{
+		EnvPolicy:  string(genOptions.DefaultEnvPolicy),
+		ResolveEnv: resolveEnv,
+		RuntimeEnv: runtimeEnv,
+		TypeCheck:  true,
+	}
+
+	if dockerBuilder {
+		logf("Converting %d RUN instructions of %s to a builder program in %s\n", len(selected), path, outDir)
+		return convertSnippet(outDir, "builder.sh", dockerfile.BuilderScript(selected), options)
+	}
+
+	logf("Converting %d RUN instructions of %s to Go in %s\n", len(selected), path, outDir)
+	var failed int
+	for i, run := range selected {
+		dir := filepath.Join(outDir, fmt.Sprintf("step-%d", i+1))
+		logf("step-%d: RUN at line %d, stage %s\n", i+1, run.Line, run.Stage)
+		if err := convertSnippet(dir, "run.sh", run.Standalone(), options); err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "step-%d: %v\n", i+1, err)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d RUN instructions failed to convert", failed, len(selected))
+	}
+	return nil
+}
+
+// convertSnippet writes script to dir under name and converts it to
+// dir/main.go, reporting its diagnostics and coverage
+func convertSnippet(dir, name, script string, options api.Options) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %v", err)
+	}
+	scriptFile := filepath.Join(dir, name)
+	if err := os.WriteFile(scriptFile, []byte(script), 0644); err != nil {
+		return fmt.Errorf("failed to write script: %v", err)
+	}
+
+	options.Filename = scriptFile
+	result, err := api.Convert([]byte(script), options)
+	for _, d := range result.Diagnostics {
+		if d.Severity >= api.SeverityWarning {
+			fmt.Fprintf(os.Stderr, "  %s\n", d)
+		}
+	}
+	if result.Code == "" {
+		return err
+	}
+
+	goFile := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(goFile, []byte(result.Code), 0644); err != nil {
+		return fmt.Errorf("failed to write Go code to file: %v", err)
+	}
+	logf("  %s saved to %s; %s\n", name, goFile, result.Metrics)
+	return err
+}
+
+// posixShell reports whether a SHELL instruction selects a shell that runs
+// Bash-compatible scripts; nil is the default /bin/sh -c
+func posixShell(shell []string) bool {
+	if len(shell) == 0 {
+		return true
+	}
+	switch filepath.Base(shell[0]) {
+	case "sh", "bash", "dash", "ash":
+		return true
+	}
+	return false
+}
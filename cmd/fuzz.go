@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/TFMV/bash2go/fuzz"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fuzzCorpus   string
+	fuzzDuration time.Duration
+	fuzzCrashers string
+	fuzzSeed     uint64
+)
+
+func init() {
+	// Add fuzz command
+	fuzzCmd := &cobra.Command{
+		Use:   "fuzz",
+		Short: "Feed mutated Bash scripts to the transpiler to find crashes and invalid output",
+		Long: `fuzz mutates Bash scripts and runs them through parsing, IR building and
+code generation, looking for scripts that make bash2go panic or generate Go
+code that does not parse or type-check. The .sh scripts of --corpus are
+mutated, or a built-in set of scripts if no corpus is given.
+
+Each distinct failure is saved to the --crashers directory, named after the
+hash of the script, so it can be reproduced with bash2go convert. Developers
+can also run the native Go fuzz targets with
+go test -fuzz FuzzPipeline ./fuzz.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runFuzz()
+		},
+	}
+	fuzzCmd.Flags().StringVar(&fuzzCorpus, "corpus", "", "Directory of Bash scripts to mutate (default: built-in scripts)")
+	fuzzCmd.Flags().DurationVar(&fuzzDuration, "duration", time.Minute, "How long to fuzz")
+	fuzzCmd.Flags().StringVar(&fuzzCrashers, "crashers", "bash2go-crashers", "Directory the failing scripts are saved to")
+	fuzzCmd.Flags().Uint64Var(&fuzzSeed, "seed", 0, "Seed of the random mutations (default: current time)")
+	fuzzCmd.Flags().IntVarP(&jobs, "jobs", "j", 0, "Scripts checked at once (default: number of CPUs)")
+	rootCmd.AddCommand(fuzzCmd)
+}
+
+// runFuzz fuzzes the transpiler and saves the failures found
+func runFuzz() error {
+	var seeds []string
+	if fuzzCorpus != "" {
+		paths, err := filepath.Glob(filepath.Join(fuzzCorpus, "*.sh"))
+		if err != nil {
+			return err
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("no .sh scripts in %s", fuzzCorpus)
+		}
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			seeds = append(seeds, string(data))
+		}
+	}
+
+	seed := fuzzSeed
+	if seed == 0 {
+		seed = uint64(time.Now().UnixNano())
+	}
+	workers := jobs
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	fmt.Printf("Fuzzing for %s with %d workers (seed %d)\n", fuzzDuration, workers, seed)
+	failures, checked := fuzz.Run(fuzz.Options{
+		Seeds:    seeds,
+		Duration: fuzzDuration,
+		Workers:  workers,
+		Seed:     seed,
+	})
+	fmt.Printf("Checked %d scripts, found %d distinct failures\n", checked, len(failures))
+	if len(failures) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(fuzzCrashers, 0755); err != nil {
+		return fmt.Errorf("failed to create crashers directory: %v", err)
+	}
+	for _, failure := range failures {
+		sum := sha256.Sum256([]byte(failure.Script))
+		path := filepath.Join(fuzzCrashers, hex.EncodeToString(sum[:8])+".sh")
+		if err := os.WriteFile(path, []byte(failure.Script), 0644); err != nil {
+			return err
+		}
+		if err := os.WriteFile(strings.TrimSuffix(path, ".sh")+".txt", []byte(failure.Err.Error()+"\n"), 0644); err != nil {
+			return err
+		}
+		message, _, _ := strings.Cut(failure.Err.Error(), "\n")
+		fmt.Printf("FAIL %s: %s\n", path, message)
+	}
+	return fmt.Errorf("%d failures saved to %s", len(failures), fuzzCrashers)
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/TFMV/bash2go/diagnostics"
+	"github.com/TFMV/bash2go/parser"
+	"github.com/TFMV/bash2go/pkg/api"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	// Add generate command
+	generateCmd := &cobra.Command{
+		Use:   "generate [ir.json]",
+		Short: "Generate Go source code from an intermediate representation in JSON",
+		Long: `generate runs the Go code generator on an intermediate representation
+(IR) in JSON, as written by "bash2go convert --emit-ir". External tools such
+as linters, migration planners or frontends for other languages can produce
+or edit the IR and reuse bash2go's generator.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return generateFromIR(args[0], outputFile)
+		},
+	}
+	generateCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output Go file (required)")
+	generateCmd.MarkFlagRequired("output")
+	generateCmd.Flags().StringVar(&constraint, "build-constraint", "", "Build constraint expression emitted as a //go:build line in generated code")
+	addEnvFlags(generateCmd)
+	rootCmd.AddCommand(generateCmd)
+}
+
+// generateFromIR generates Go code from the IR in the JSON file irPath and
+// writes it to goFile
+func generateFromIR(irPath, goFile string) error {
+	data, err := os.ReadFile(irPath)
+	if err != nil {
+		return err
+	}
+
+	logf("Generating Go code from %s and saving to %s\n", irPath, goFile)
+	result, err := api.Generate(data, api.Options{
+		EnvPolicy:       envPolicy,
+		ResolveEnv:      resolveEnv,
+		RuntimeEnv:      runtimeEnv,
+		BuildConstraint: constraint,
+		TypeCheck:       true,
+	})
+	collector := diagnostics.NewCollector()
+	for _, d := range result.Diagnostics {
+		collector.Add(diagnostics.Diagnostic{
+			Severity: diagnostics.Severity(d.Severity),
+			Code:     d.Code,
+			Message:  d.Message,
+			File:     d.File,
+			Line:     d.Line,
+			Column:   d.Column,
+		})
+	}
+	if result.Code == "" {
+		collector.WriteSummary(os.Stderr)
+		return err
+	}
+	if err := os.WriteFile(goFile, []byte(result.Code), 0644); err != nil {
+		return fmt.Errorf("failed to write Go code to file: %v", err)
+	}
+	logf("Generated Go code saved to %s\n", goFile)
+
+	fmt.Println(result.Metrics)
+	if err != nil {
+		collector.WriteSummary(os.Stderr)
+		return err
+	}
+	return collector.WriteSummary(os.Stdout)
+}
+
+// writeIR writes ir as indented JSON to path
+func writeIR(path string, ir *parser.IntermediateRepresentation) error {
+	data, err := json.MarshalIndent(ir, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TFMV/bash2go/golden"
+	"github.com/spf13/cobra"
+)
+
+var goldenDir string
+
+func init() {
+	// Add golden command with update and verify subcommands
+	goldenCmd := &cobra.Command{
+		Use:   "golden",
+		Short: "Maintain fixtures of Bash scripts and the Go code expected for them",
+		Long: `golden manages a directory of Bash scripts, each with the Go code bash2go is
+expected to generate for it in a .go.golden file next to it. Committing the
+golden files makes every change to the generated code visible in review.`,
+	}
+	goldenCmd.PersistentFlags().StringVar(&goldenDir, "dir", "testdata/golden", "Directory of scripts and golden files")
+	addEnvFlags(goldenCmd)
+
+	updateCmd := &cobra.Command{
+		Use:   "update",
+		Short: "Rewrite the golden files with the current generated code",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return updateGolden(goldenDir)
+		},
+	}
+	verifyCmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Fail if the generated code differs from the golden files",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return verifyGolden(goldenDir)
+		},
+	}
+	goldenCmd.AddCommand(updateCmd, verifyCmd)
+	rootCmd.AddCommand(goldenCmd)
+}
+
+// updateGolden regenerates the golden files in dir
+func updateGolden(dir string) error {
+	options, err := generatorOptions()
+	if err != nil {
+		return err
+	}
+	changed, err := golden.Update(dir, golden.Generator(options))
+	if err != nil {
+		return err
+	}
+	for _, path := range changed {
+		fmt.Printf("updated %s\n", path)
+	}
+	fmt.Printf("%d golden files changed\n", len(changed))
+	return nil
+}
+
+// verifyGolden compares the generated code of the scripts in dir with their
+// golden files, printing a diff for each mismatch
+func verifyGolden(dir string) error {
+	options, err := generatorOptions()
+	if err != nil {
+		return err
+	}
+	mismatches, err := golden.Verify(dir, golden.Generator(options))
+	if err != nil {
+		return err
+	}
+	for _, m := range mismatches {
+		if strings.HasPrefix(m.Diff, "---") {
+			fmt.Printf("FAIL %s\n%s", m.Golden, m.Diff)
+		} else {
+			fmt.Printf("FAIL %s: %s\n", m.Golden, m.Diff)
+		}
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("%d golden files differ from the generated code; review the diffs and run bash2go golden update", len(mismatches))
+	}
+	fmt.Printf("ok   %s\n", dir)
+	return nil
+}
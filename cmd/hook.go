@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/TFMV/bash2go/diagnostics"
+	"github.com/TFMV/bash2go/generator"
+	"github.com/spf13/cobra"
+)
+
+var (
+	hookRegenerate bool
+	hookGoDir      string
+)
+
+func init() {
+	// Add hook command
+	hookCmd := &cobra.Command{
+		Use:   "hook [bash script...]",
+		Short: "Check staged Bash scripts in a pre-commit hook, optionally regenerating their Go code",
+		Long: `hook verifies that Bash scripts still convert cleanly, for use with
+pre-commit, husky or a plain .git/hooks/pre-commit script. Scripts are taken
+from the arguments, or from the staged .sh files if there are none. The
+version of a script staged for the commit is checked, and fails if it does
+not convert to Go code that type-checks or has error diagnostics.
+
+Each script's Go counterpart lives at <go-dir>/<name>/main.go. Without
+--regenerate, an existing counterpart that differs from the script's current
+conversion fails the hook. With --regenerate, counterparts are rewritten and
+staged so they are committed together with their scripts.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runHook(args)
+		},
+	}
+	hookCmd.Flags().BoolVar(&hookRegenerate, "regenerate", false, "Rewrite and stage the Go code of each script")
+	hookCmd.Flags().StringVar(&hookGoDir, "go-dir", "cmd", "Directory holding the generated Go packages, one per script")
+	hookCmd.Flags().BoolVar(&noCache, "no-cache", false, "Convert every script, ignoring and not updating the cache")
+	hookCmd.Flags().StringVar(&sarifFile, "sarif", "", "Write diagnostics as SARIF to this file for code review tools")
+	addEnvFlags(hookCmd)
+	rootCmd.AddCommand(hookCmd)
+}
+
+// runHook checks, and with --regenerate updates, the Go code of scripts
+func runHook(scripts []string) error {
+	if len(scripts) == 0 {
+		var err error
+		if scripts, err = stagedScripts(); err != nil {
+			return err
+		}
+	}
+
+	options, err := generatorOptions()
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	var items []diagnostics.Diagnostic
+	for _, script := range scripts {
+		found, err := hookScript(script, options)
+		items = append(items, found...)
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "bash2go: %s: %v\n", script, err)
+		}
+	}
+	if sarifFile != "" {
+		if err := writeSARIF(sarifFile, scripts, items); err != nil {
+			return fmt.Errorf("failed to write SARIF: %v", err)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d scripts failed the bash2go hook", failed, len(scripts))
+	}
+	return nil
+}
+
+// hookScript checks one script and its generated counterpart, returning
+// the diagnostics found in the script. The version of the script staged for
+// the commit is checked, and error diagnostics fail it like a conversion
+// that fails.
+func hookScript(script string, options generator.Options) ([]diagnostics.Diagnostic, error) {
+	content, err := stagedContent(script)
+	if err != nil {
+		return nil, err
+	}
+
+	// Scripts converted before with the same options are not converted or
+	// type-checked again
+	c := commandCache()
+	var key string
+	if c != nil {
+		if key, err = conversionKey(script, content, options); err != nil {
+			return nil, err
+		}
+		if items, goCode, ok := cachedConversion(c, key); ok {
+			if err := diagnosticErrors(items); err != nil {
+				return items, err
+			}
+			return items, syncGoCode(script, goCode)
+		}
+	}
+
+	gen, goCode, err := generateGoFrom(script, content, options, nil)
+	if gen == nil {
+		return nil, err
+	}
+	if err == nil {
+		if err = gen.TypeCheck(goCode); err != nil {
+			printErrors(gen.IR.Diagnostics.Items())
+			err = fmt.Errorf("failed to generate valid Go code: %v", err)
+		}
+	}
+	items := gen.IR.Diagnostics.Items()
+	if err != nil {
+		return items, err
+	}
+	if key != "" && cacheableConversion(options, gen.IR) {
+		if err := storeConversion(c, key, items, goCode); err != nil {
+			logf("Not caching %s: %v\n", script, err)
+		}
+	}
+	if err := diagnosticErrors(items); err != nil {
+		return items, err
+	}
+	return items, syncGoCode(script, goCode)
+}
+
+// diagnosticErrors prints the error diagnostics among items and returns an
+// error counting them, nil if there are none
+func diagnosticErrors(items []diagnostics.Diagnostic) error {
+	n := printErrors(items)
+	if n == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d error diagnostic(s)", n)
+}
+
+// printErrors prints the error diagnostics among items and returns their
+// number
+func printErrors(items []diagnostics.Diagnostic) int {
+	n := 0
+	for _, d := range items {
+		if d.Severity == diagnostics.SeverityError {
+			fmt.Fprintf(os.Stderr, "  %s\n", d)
+			n++
+		}
+	}
+	return n
+}
+
+// stagedContent returns the content of file staged in the git index, which
+// is what gets committed whatever the working tree holds, or else the
+// content of the file itself
+func stagedContent(file string) ([]byte, error) {
+	rel := file
+	if filepath.IsAbs(file) {
+		if wd, err := os.Getwd(); err == nil {
+			if r, err := filepath.Rel(wd, file); err == nil {
+				rel = r
+			}
+		}
+	}
+	// :./path names the staged file relative to the current directory
+	if content, err := exec.Command("git", "show", ":./"+filepath.ToSlash(rel)).Output(); err == nil {
+		return content, nil
+	}
+	return os.ReadFile(file)
+}
+
+// syncGoCode checks, and with --regenerate updates, the generated
+// counterpart of a script
+func syncGoCode(script, goCode string) error {
+	goFile := filepath.Join(hookGoDir, programName(script), "main.go")
+	existing, err := stagedContent(goFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil && bytes.Equal(existing, []byte(goCode)) {
+		return nil
+	}
+
+	if !hookRegenerate {
+		if existing == nil {
+			// Only scripts that already have Go code are kept in sync
+			return nil
+		}
+		return fmt.Errorf("%s is out of date; run bash2go hook --regenerate", goFile)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(goFile), 0755); err != nil {
+		return fmt.Errorf("failed to create package directory: %v", err)
+	}
+	if err := os.WriteFile(goFile, []byte(goCode), 0644); err != nil {
+		return fmt.Errorf("failed to write Go code to file: %v", err)
+	}
+	if output, err := exec.Command("git", "add", "--", goFile).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to stage %s: %v\n%s", goFile, err, output)
+	}
+	fmt.Printf("bash2go: regenerated %s\n", goFile)
+	return nil
+}
+
+// stagedScripts returns the Bash scripts added, copied or modified in the
+// git index
+func stagedScripts() ([]string, error) {
+	output, err := exec.Command("git", "diff", "--cached", "--name-only", "--diff-filter=ACM", "--", "*.sh").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list staged scripts: %v", err)
+	}
+
+	var scripts []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			scripts = append(scripts, line)
+		}
+	}
+	return scripts, nil
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/TFMV/bash2go/inventory"
+	"github.com/spf13/cobra"
+)
+
+var maxLocations int
+
+func init() {
+	// Add inventory command
+	inventoryCmd := &cobra.Command{
+		Use:   "inventory [dir]",
+		Short: "Survey the shell scripts of a repository before converting them",
+		Long: `inventory finds the shell scripts below a directory (the current directory by
+default), counts the Bash features and external commands they use with the
+places they are used, and converts each script to rank them by how ready
+they are for conversion: ready scripts convert to Go code that type-checks,
+partial ones convert with errors, and blocked ones do not convert at all.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root := "."
+			if len(args) > 0 {
+				root = args[0]
+			}
+			return runInventory(root)
+		},
+	}
+	inventoryCmd.Flags().BoolVar(&jsonOutput, "json", false, "Print a machine-readable JSON report to stdout")
+	inventoryCmd.Flags().IntVar(&maxLocations, "locations", 3, "Number of locations listed per feature and command (0 for none, -1 for all)")
+	inventoryCmd.Flags().BoolVar(&useGoGit, "use-go-git", false, "Rate scripts as if converted with --use-go-git")
+	inventoryCmd.Flags().StringVar(&mappingFile, "mappings", "", "Rate scripts as if converted with these command mappings")
+	addEnvFlags(inventoryCmd)
+	rootCmd.AddCommand(inventoryCmd)
+}
+
+// runInventory scans the scripts below root and prints the inventory
+func runInventory(root string) error {
+	options, err := generatorOptions()
+	if err != nil {
+		return err
+	}
+	logf("Scanning %s for shell scripts...\n", root)
+	report, err := inventory.Scan(root, options)
+	if err != nil {
+		return err
+	}
+	logf("Found %d scripts\n", len(report.Scripts))
+
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	if len(report.Scripts) == 0 {
+		return nil
+	}
+
+	fmt.Println("Scripts, by conversion readiness:")
+	width := 0
+	for _, script := range report.Scripts {
+		width = max(width, len(script.Path))
+	}
+	for _, script := range report.Scripts {
+		line := fmt.Sprintf("  %-7s %-*s %5.1f%% native, %d errors, %d warnings", script.Status, width, script.Path,
+			script.Coverage, script.Errors, script.Warnings)
+		if script.Error != "" {
+			line = fmt.Sprintf("  %-7s %-*s %s", script.Status, width, script.Path, script.Error)
+		}
+		fmt.Println(line)
+	}
+
+	printUsages("Bash features", report.Features)
+	printUsages("External commands", report.Commands)
+	return nil
+}
+
+// printUsages prints a table of feature or command usages with up to
+// maxLocations locations each
+func printUsages(title string, usages []inventory.Usage) {
+	if len(usages) == 0 {
+		return
+	}
+	fmt.Printf("\n%s:\n", title)
+	width := 0
+	for _, usage := range usages {
+		width = max(width, len(usage.Name))
+	}
+	fmt.Printf("  %-*s %5s %7s\n", width, "", "uses", "scripts")
+	for _, usage := range usages {
+		line := fmt.Sprintf("  %-*s %5d %7d", width, usage.Name, usage.Count, usage.Scripts)
+		locations := usage.Locations
+		if maxLocations >= 0 && len(locations) > maxLocations {
+			locations = locations[:maxLocations]
+		}
+		if len(locations) > 0 {
+			shown := make([]string, len(locations))
+			for i, loc := range locations {
+				shown[i] = loc.String()
+			}
+			line += "  " + strings.Join(shown, ", ")
+			if len(locations) < len(usage.Locations) {
+				line += ", ..."
+			}
+		}
+		fmt.Println(line)
+	}
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// phaseTiming is the total time spent in one phase of a conversion or build
+type phaseTiming struct {
+	name     string
+	duration time.Duration
+}
+
+// progress reports the phases of a conversion or build as they start and
+// finish, and summarizes their durations at the end. A nil progress reports
+// nothing, for commands whose output belongs to the converted program.
+type progress struct {
+	mu     sync.Mutex
+	start  time.Time
+	phases []phaseTiming
+}
+
+// newProgress returns a progress starting now
+func newProgress() *progress {
+	return &progress{start: time.Now()}
+}
+
+// phase reports that the named phase started and returns the function that
+// reports its end. Phases that run several times, such as compiling for each
+// target, add up in the summary.
+func (p *progress) phase(name string) func() {
+	if p == nil {
+		return func() {}
+	}
+	logf("%s...\n", name)
+	start := time.Now()
+	return func() {
+		elapsed := time.Since(start)
+		logf("%s done in %s\n", name, roundDuration(elapsed))
+		p.record(name, elapsed)
+	}
+}
+
+// record adds elapsed to the total time of the named phase
+func (p *progress) record(name string, elapsed time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := range p.phases {
+		if p.phases[i].name == name {
+			p.phases[i].duration += elapsed
+			return
+		}
+	}
+	p.phases = append(p.phases, phaseTiming{name: name, duration: elapsed})
+}
+
+// summary reports the total time and the time spent in each phase
+func (p *progress) summary() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	parts := make([]string, len(p.phases))
+	for i, phase := range p.phases {
+		parts[i] = fmt.Sprintf("%s %s", phase.name, roundDuration(phase.duration))
+	}
+	logf("Finished in %s (%s)\n", roundDuration(time.Since(p.start)), strings.Join(parts, ", "))
+}
+
+// roundDuration rounds d for display, keeping sub-millisecond phases visible
+func roundDuration(d time.Duration) time.Duration {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond)
+	}
+	return d.Round(time.Millisecond)
+}
@@ -1,19 +1,60 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
 
 	"github.com/TFMV/bash2go/compiler"
+	"github.com/TFMV/bash2go/diagnostics"
 	"github.com/TFMV/bash2go/generator"
 	"github.com/TFMV/bash2go/parser"
 	"github.com/spf13/cobra"
 )
 
 var (
-	outputFile string
-	rootCmd    = &cobra.Command{
+	outputFile  string
+	jsonOutput  bool
+	metricsFile string
+	envPolicy   string
+	resolveEnv  []string
+	runtimeEnv  []string
+	static      bool
+	ldflags     string
+	small       bool
+	compress    bool
+	vet         bool
+	staticcheck bool
+	outDir      string
+	reproduce   bool
+	buildTags   []string
+	constraint  string
+	project     bool
+	taskfile    bool
+	goBin       string
+	toolchain   string
+	jobs        int
+	checksums   bool
+	signMethod  string
+	signKey     string
+	targets     []string
+	outTemplate string
+	reportFile  string
+	schedule    string
+	irFile      string
+	plugins     bool
+	useGoGit    bool
+	pollTail    bool
+	mappingFile string
+	sarifFile   string
+	shellOpts   []string
+	sourcePath  []string
+	rootCmd     = &cobra.Command{
 		Use:   "bash2go",
 		Short: "bash2go is a tool that translates Bash scripts into Go programs",
 		Long: `bash2go is a tool that translates Bash scripts into Go programs,
@@ -39,48 +80,336 @@ func init() {
 	}
 	convertCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output Go file (required)")
 	convertCmd.MarkFlagRequired("output")
+	convertCmd.Flags().BoolVar(&project, "project", false, "Write a Go module project with a Makefile to the output directory")
+	convertCmd.Flags().BoolVar(&taskfile, "taskfile", false, "Write a Taskfile.yml instead of a Makefile in project mode")
+	convertCmd.Flags().StringVar(&irFile, "emit-ir", "", "Also write the intermediate representation as JSON to this file")
+	addConversionFlags(convertCmd)
 	rootCmd.AddCommand(convertCmd)
 
 	// Add build command
 	buildCmd := &cobra.Command{
-		Use:   "build [bash script]",
-		Short: "Convert a Bash script to Go and compile it to a binary",
-		Args:  cobra.ExactArgs(1),
+		Use:   "build [bash script...]",
+		Short: "Convert Bash scripts to Go and compile them to binaries",
+		Args:  cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if outDir != "" {
+				return buildBatch(args, outDir)
+			}
+			if len(args) != 1 {
+				return fmt.Errorf("building several scripts requires --out-dir")
+			}
+			if reportFile != "" {
+				return fmt.Errorf("--report requires --out-dir")
+			}
+			if outputFile == "" {
+				return fmt.Errorf("required flag \"output\" not set")
+			}
 			return convertBashToGo(args[0], outputFile, true)
 		},
 	}
-	buildCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output binary name (required)")
-	buildCmd.MarkFlagRequired("output")
+	buildCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output binary name (required for a single script)")
+	buildCmd.Flags().StringVar(&outDir, "out-dir", "", "Directory for the binaries when building several scripts")
+	buildCmd.MarkFlagsMutuallyExclusive("output", "out-dir")
+	buildCmd.Flags().StringSliceVar(&targets, "target", nil, "Platforms to build for as os/arch, such as linux/amd64,windows/arm64 (default: GOOS/GOARCH)")
+	buildCmd.Flags().StringVar(&outTemplate, "output-template", "", "Binary file name template with {{.Name}}, {{.GOOS}} and {{.GOARCH}} (default \""+matrixTemplate+"\" for several targets)")
+	buildCmd.Flags().StringVar(&reportFile, "report", "", "Write a consolidated JSON report of a --out-dir batch to this file")
+	buildCmd.Flags().IntVarP(&jobs, "jobs", "j", 0, "Scripts converted and compiled at once with --out-dir (default: number of CPUs)")
+	buildCmd.Flags().BoolVar(&static, "static", false, "Build a static binary with cgo disabled (CGO_ENABLED=0)")
+	buildCmd.Flags().StringVar(&ldflags, "ldflags", "", "Extra flags passed to the Go linker")
+	buildCmd.Flags().BoolVar(&vet, "vet", true, "Run go vet on the generated code and report findings as diagnostics")
+	buildCmd.Flags().BoolVar(&staticcheck, "staticcheck", false, "Also run staticcheck on the generated code, if installed")
+	buildCmd.Flags().StringSliceVar(&buildTags, "build-tags", nil, "Build tags passed to go build")
+	buildCmd.Flags().BoolVar(&reproduce, "reproducible", false, "Build identical binaries from identical input and print their SHA-256")
+	buildCmd.Flags().BoolVar(&checksums, "checksums", false, "Write a SHA256SUMS file next to the binaries")
+	buildCmd.Flags().StringVar(&signMethod, "sign", "", "Sign binaries with cosign or gpg, writing detached signatures next to them")
+	buildCmd.Flags().StringVar(&signKey, "sign-key", "", "cosign key file or GPG key ID used with --sign")
+	buildCmd.Flags().BoolVar(&compress, "compress", false, "Compress the binary with upx if it is installed")
+	buildCmd.Flags().BoolVar(&noCache, "no-cache", false, "Build every binary with --out-dir, ignoring and not updating the cache")
+	buildCmd.Flags().BoolVar(&small, "small", false, "Strip the symbol table and debug info (shorthand for --ldflags \"-s -w\")")
+	addConversionFlags(buildCmd)
 	rootCmd.AddCommand(buildCmd)
 }
 
-// convertBashToGo converts a Bash script to Go code and optionally compiles it
-func convertBashToGo(inputScript, outputFile string, shouldCompile bool) error {
-	fmt.Printf("Converting %s to Go", inputScript)
-	if shouldCompile {
-		fmt.Printf(" and compiling to %s\n", outputFile)
-	} else {
-		fmt.Printf(" and saving to %s\n", outputFile)
+// addConversionFlags registers the flags shared by commands that convert scripts
+func addConversionFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print a machine-readable JSON report to stdout")
+	cmd.Flags().StringVar(&metricsFile, "metrics", "", "Write conversion coverage metrics as JSON to this file")
+	cmd.Flags().StringVar(&sarifFile, "sarif", "", "Write diagnostics as SARIF to this file for code review tools")
+	cmd.Flags().StringVar(&constraint, "build-constraint", "", "Build constraint expression emitted as a //go:build line in generated code")
+	cmd.Flags().BoolVar(&plugins, "plugins", false, "Translate commands with bash2go-translate-<cmd> plugins found on PATH")
+	cmd.Flags().BoolVar(&useGoGit, "use-go-git", false, "Translate common git clone, pull, checkout and rev-parse commands into go-git calls")
+	cmd.Flags().BoolVar(&pollTail, "poll-tail", false, "Translate tail -f into Go code polling the followed files every second")
+	cmd.Flags().StringVar(&mappingFile, "mappings", "", "YAML file declaring translations and policies for external commands")
+	cmd.Flags().StringSliceVar(&shellOpts, "shopt", nil, "Shell options, such as nullglob or failglob, set when the program starts")
+	cmd.Flags().StringSliceVar(&sourcePath, "source-path", nil, "Directories searched for scripts included with source, after the directory of the script")
+	addEnvFlags(cmd)
+	addToolchainFlags(cmd)
+}
+
+// addToolchainFlags registers the flags selecting the Go toolchain. They
+// default to the BASH2GO_GO_BIN and BASH2GO_TOOLCHAIN environment variables.
+func addToolchainFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&goBin, "go-bin", os.Getenv("BASH2GO_GO_BIN"), "Go command used to build generated code (default \"go\" from PATH)")
+	cmd.Flags().StringVar(&toolchain, "go-toolchain", os.Getenv("BASH2GO_TOOLCHAIN"), "Go release to build with, such as go1.24.2 (sets GOTOOLCHAIN)")
+}
+
+// addEnvFlags registers the flags controlling environment variable expansion
+func addEnvFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&envPolicy, "env-policy", string(parser.EnvRuntime),
+		"When to expand environment variables: runtime (os.Getenv) or convert (resolve now)")
+	cmd.Flags().StringSliceVar(&resolveEnv, "resolve-env", nil, "Environment variables to resolve at conversion time")
+	cmd.Flags().StringSliceVar(&runtimeEnv, "runtime-env", nil, "Environment variables to keep as os.Getenv calls")
+}
+
+// generatorOptions builds code generation options from the command-line flags
+func generatorOptions() (generator.Options, error) {
+	policy, err := parser.ParseEnvPolicy(envPolicy)
+	if err != nil {
+		return generator.Options{}, err
+	}
+
+	options := generator.Options{
+		DefaultEnvPolicy: policy,
+		EnvPolicies:      make(map[string]parser.EnvPolicy),
+		BuildConstraint:  constraint,
+		Schedule:         schedule,
+		Plugins:          plugins,
+		GoGit:            useGoGit,
+		PollTail:         pollTail,
+		ShellOptions:     shellOpts,
+	}
+	for _, name := range resolveEnv {
+		options.EnvPolicies[name] = parser.EnvConvert
+	}
+	for _, name := range runtimeEnv {
+		options.EnvPolicies[name] = parser.EnvRuntime
+	}
+	if mappingFile != "" {
+		if options.Mappings, err = generator.LoadMappings(mappingFile); err != nil {
+			return generator.Options{}, err
+		}
+	}
+	return options, nil
+}
+
+// conversionReport is the machine-readable result printed in JSON output mode
+type conversionReport struct {
+	Input       string                   `json:"input"`
+	Output      string                   `json:"output"`
+	GoFile      string                   `json:"go_file,omitempty"`
+	Compiled    bool                     `json:"compiled"`
+	Binaries    []string                 `json:"binaries,omitempty"`
+	SHA256      string                   `json:"sha256,omitempty"`
+	Error       string                   `json:"error,omitempty"`
+	Metrics     generator.Metrics        `json:"metrics"`
+	Diagnostics []diagnostics.Diagnostic `json:"diagnostics"`
+}
+
+// metricsArtifact is the content of the file written by --metrics
+type metricsArtifact struct {
+	Script        string   `json:"script"`
+	NativePercent float64  `json:"native_percent"`
+	Native        int      `json:"native"`
+	ExecFallbacks int      `json:"exec_fallbacks"`
+	Unsupported   int      `json:"unsupported"`
+	Dependencies  []string `json:"dependencies"`
+}
+
+// writeMetrics writes the coverage metrics for a script as JSON to path
+func writeMetrics(path, script string, m generator.Metrics) error {
+	artifact := metricsArtifact{
+		Script:        script,
+		NativePercent: m.NativePercent(),
+		Native:        m.Native,
+		ExecFallbacks: m.ExecFallbacks,
+		Unsupported:   m.Unsupported,
+		Dependencies:  m.Dependencies,
 	}
 
-	// Parse the Bash script
-	result, err := parser.ParseBashScript(inputScript)
+	data, err := json.MarshalIndent(artifact, "", "  ")
 	if err != nil {
-		return fmt.Errorf("failed to parse Bash script: %v", err)
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// writeSARIF writes the diagnostics of scripts as SARIF to path. Diagnostics
+// located in a script by its base name are attributed to the script's path,
+// so that review tools can find the file.
+func writeSARIF(path string, scripts []string, items []diagnostics.Diagnostic) error {
+	paths := make(map[string]string)
+	for _, script := range scripts {
+		paths[filepath.Base(script)] = script
+	}
+	located := make([]diagnostics.Diagnostic, len(items))
+	for i, d := range items {
+		if script, ok := paths[d.File]; ok {
+			d.File = script
+		}
+		located[i] = d
 	}
 
-	// Build intermediate representation
-	ir, err := parser.BuildIR(result)
+	f, err := os.Create(path)
 	if err != nil {
-		return fmt.Errorf("failed to build intermediate representation: %v", err)
+		return err
+	}
+	if err := diagnostics.WriteSARIF(f, version(), located); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// logf prints progress messages. In JSON output mode they go to stderr so
+// that stdout only contains the report.
+func logf(format string, args ...interface{}) {
+	if jsonOutput {
+		fmt.Fprintf(os.Stderr, format, args...)
+		return
+	}
+	fmt.Printf(format, args...)
+}
+
+// generateGo parses a Bash script and generates Go code for it, reporting
+// each phase to p. If generation fails, the generator is returned with the
+// error so that its diagnostics can be reported.
+func generateGo(inputScript string, options generator.Options, p *progress) (*generator.GoCodeGenerator, string, error) {
+	return generateGoFrom(inputScript, nil, options, p)
+}
+
+// generateGoFrom is generateGo for the given content of inputScript, or
+// the file itself if content is nil
+func generateGoFrom(inputScript string, content []byte, options generator.Options, p *progress) (*generator.GoCodeGenerator, string, error) {
+	// Parse the Bash script into its intermediate representation, one
+	// statement at a time so that large scripts fit in memory
+	done := p.phase("parse")
+	var ir *parser.IntermediateRepresentation
+	var err error
+	if content != nil {
+		ir, err = parser.BuildIRFromContent(inputScript, bytes.NewReader(content), sourcePath...)
+	} else {
+		ir, err = parser.BuildIRFromFile(inputScript, sourcePath...)
+	}
+	done()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse Bash script: %v", err)
+	}
+	if irFile != "" {
+		if err := writeIR(irFile, ir); err != nil {
+			return nil, "", fmt.Errorf("failed to write intermediate representation: %v", err)
+		}
 	}
 
 	// Generate Go code
-	generator := generator.NewGoCodeGenerator(ir)
-	goCode, err := generator.Generate()
+	gen := generator.NewGoCodeGenerator(ir)
+	gen.Options = options
+	done = p.phase("generate")
+	goCode, err := gen.Generate()
+	done()
+	if err != nil {
+		ir.Diagnostics.WriteSummary(os.Stderr)
+		return gen, "", fmt.Errorf("failed to generate Go code: %v", err)
+	}
+	return gen, goCode, nil
+}
+
+// buildOptions returns compiler options from the command-line flags
+func buildOptions(outputFile, goFile string) compiler.BuildOptions {
+	options := compiler.DefaultBuildOptions(outputFile, goFile)
+	options.Static = static
+	options.LDFlags = ldflags
+	options.Small = small
+	options.Compress = compress
+	options.Vet = vet
+	options.Staticcheck = staticcheck
+	options.Reproducible = reproduce
+	options.Tags = buildTags
+	options.GoBin = goBin
+	options.Jobs = jobs
+	options.Toolchain = toolchain
+	options.Logf = logf
+	return options
+}
+
+// scriptBuildInfo returns the build metadata for a program generated from
+// script with generator.Options.BuildInfo
+func scriptBuildInfo(script string) (generator.BuildInfo, error) {
+	hash, err := compiler.FileSHA256(script)
+	if err != nil {
+		return generator.BuildInfo{}, err
+	}
+
+	converted := time.Now()
+	if reproduce {
+		if converted, err = compiler.SourceDateEpoch(); err != nil {
+			return generator.BuildInfo{}, err
+		}
+	}
+
+	return generator.BuildInfo{
+		Script:    filepath.Base(script),
+		Converted: converted.UTC().Format(time.RFC3339),
+		Version:   version(),
+		Hash:      hash,
+	}, nil
+}
+
+// convertBashToGo converts a Bash script to Go code and optionally compiles it
+func convertBashToGo(inputScript, outputFile string, shouldCompile bool) (err error) {
+	options, err := generatorOptions()
+	if err != nil {
+		return err
+	}
+
+	// Fail early if the Go toolchain cannot build the result
+	var buildPlatforms []buildTarget
+	var outputTmpl *template.Template
+	if shouldCompile {
+		if err := compiler.CheckGoVersion(buildOptions("", "")); err != nil {
+			return err
+		}
+		if err := checkSignMethod(); err != nil {
+			return err
+		}
+		if buildPlatforms, err = buildTargets(); err != nil {
+			return err
+		}
+		if outputTmpl, err = outputTemplateFor(buildPlatforms); err != nil {
+			return err
+		}
+		options.BuildInfo = true
+	}
+
+	logf("Converting %s to Go", inputScript)
+	if shouldCompile {
+		logf(" and compiling to %s\n", outputFile)
+	} else {
+		logf(" and saving to %s\n", outputFile)
+	}
+
+	p := newProgress()
+	gen, goCode, err := generateGo(inputScript, options, p)
+	if gen != nil && sarifFile != "" {
+		// Report the diagnostics even if the conversion fails later
+		defer func() {
+			if serr := writeSARIF(sarifFile, []string{inputScript}, gen.IR.Diagnostics.Items()); serr != nil && err == nil {
+				err = fmt.Errorf("failed to write SARIF: %v", serr)
+			}
+		}()
+	}
 	if err != nil {
-		return fmt.Errorf("failed to generate Go code: %v", err)
+		return err
+	}
+	ir := gen.IR
+
+	// Validate the generated code before handing it to the toolchain, so
+	// generator bugs are reported against the script
+	done := p.phase("type check")
+	err = gen.TypeCheck(goCode)
+	done()
+	if err != nil && shouldCompile {
+		ir.Diagnostics.WriteSummary(os.Stderr)
+		return fmt.Errorf("failed to generate valid Go code: %v", err)
 	}
 
 	// Determine output Go file
@@ -88,6 +417,12 @@ func convertBashToGo(inputScript, outputFile string, shouldCompile bool) error {
 	if shouldCompile {
 		// Create a temporary file for the Go code
 		goFile = filepath.Join(os.TempDir(), filepath.Base(inputScript)+".go")
+	} else if project {
+		// The output is the project directory
+		if err := os.MkdirAll(outputFile, 0755); err != nil {
+			return fmt.Errorf("failed to create project directory: %v", err)
+		}
+		goFile = filepath.Join(outputFile, "main.go")
 	} else {
 		goFile = outputFile
 	}
@@ -97,23 +432,108 @@ func convertBashToGo(inputScript, outputFile string, shouldCompile bool) error {
 		return fmt.Errorf("failed to write Go code to file: %v", err)
 	}
 
-	fmt.Printf("Generated Go code saved to %s\n", goFile)
+	logf("Generated Go code saved to %s\n", goFile)
+
+	// Complete the project around the generated code
+	if project && !shouldCompile {
+		err := compiler.WriteProject(compiler.ProjectOptions{
+			Dir:      outputFile,
+			Name:     programName(inputScript),
+			Script:   inputScript,
+			GoFile:   goFile,
+			Taskfile: taskfile,
+			Build:    buildOptions("", ""),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write project: %v", err)
+		}
+		logf("Go module project saved to %s\n", outputFile)
+	}
+
+	// Record coverage metrics for tracking migration progress
+	metrics := gen.Metrics()
+	if metricsFile != "" {
+		if err := writeMetrics(metricsFile, inputScript, metrics); err != nil {
+			return fmt.Errorf("failed to write metrics: %v", err)
+		}
+	}
 
 	// Compile if requested
+	var checksum string
+	var binaries []string
 	if shouldCompile {
-		fmt.Printf("Compiling %s to %s\n", goFile, outputFile)
+		info, err := scriptBuildInfo(inputScript)
+		if err != nil {
+			return err
+		}
+
+		// Build the Go program once per target platform
+		for _, target := range buildPlatforms {
+			name, err := targetOutput(outputTmpl, filepath.Base(outputFile), target)
+			if err != nil {
+				return err
+			}
+			binary := filepath.Join(filepath.Dir(outputFile), name)
+			logf("Compiling %s to %s (%s)\n", goFile, binary, target)
 
-		// Build the Go program
-		options := compiler.DefaultBuildOptions(outputFile, goFile)
-		if err := compiler.BuildGoProgram(options); err != nil {
-			return fmt.Errorf("failed to build Go program: %v", err)
+			options := buildOptions(binary, goFile)
+			options.Progress = p.phase
+			target.apply(&options)
+			options.Diagnostics = ir.Diagnostics
+			options.LDFlags = strings.TrimSpace(options.LDFlags + " " + info.LDFlags())
+			options.SourceMap = func(_ string, goLine int) string {
+				return gen.SourcePosition(goLine).String()
+			}
+			if err := compiler.BuildGoProgram(options); err != nil {
+				ir.Diagnostics.WriteSummary(os.Stderr)
+				return fmt.Errorf("failed to build Go program for %s: %v", target, err)
+			}
+
+			logf("Compiled binary saved to %s\n", binary)
+			binaries = append(binaries, binary)
+
+			// Print the checksum so builds can be verified independently
+			if reproduce {
+				if checksum, err = compiler.FileSHA256(binary); err != nil {
+					return err
+				}
+				logf("SHA256 (%s) = %s\n", binary, checksum)
+			}
+		}
+		if len(binaries) > 1 {
+			// A single checksum cannot describe several binaries
+			checksum = ""
 		}
+		outputFile = binaries[0]
 
-		fmt.Printf("Compiled binary saved to %s\n", outputFile)
+		if err := publishArtifacts(binaries, filepath.Dir(outputFile)); err != nil {
+			return err
+		}
 
 		// Remove the temporary Go file
 		os.Remove(goFile)
+		goFile = ""
+	}
+
+	p.summary()
+
+	// Report diagnostics collected during parsing and generation
+	if jsonOutput {
+		report := conversionReport{
+			Input:       inputScript,
+			Output:      outputFile,
+			GoFile:      goFile,
+			Compiled:    shouldCompile,
+			Binaries:    binaries,
+			SHA256:      checksum,
+			Metrics:     metrics,
+			Diagnostics: ir.Diagnostics.Items(),
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
 	}
 
-	return nil
+	fmt.Println(metrics)
+	return ir.Diagnostics.WriteSummary(os.Stdout)
 }
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/TFMV/bash2go/compiler"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	// Add run command
+	runCmd := &cobra.Command{
+		Use:   "run [bash script] [args...]",
+		Short: "Convert a Bash script to Go and run it without keeping a binary",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			code, err := runBashAsGo(args[0], args[1:])
+			if err != nil {
+				return err
+			}
+			if code != 0 {
+				os.Exit(code)
+			}
+			return nil
+		},
+	}
+	// Everything after the script name belongs to the script
+	runCmd.Flags().SetInterspersed(false)
+	addEnvFlags(runCmd)
+	addToolchainFlags(runCmd)
+	rootCmd.AddCommand(runCmd)
+}
+
+// runBashAsGo converts a Bash script to Go and runs it with args, returning
+// the program's exit code
+func runBashAsGo(inputScript string, args []string) (int, error) {
+	options, err := generatorOptions()
+	if err != nil {
+		return 0, err
+	}
+
+	_, goCode, err := generateGo(inputScript, options, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	// Write the Go code to a temporary directory
+	tempDir, err := os.MkdirTemp("", "bash2go-")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	goFile := filepath.Join(tempDir, filepath.Base(inputScript)+".go")
+	if err := os.WriteFile(goFile, []byte(goCode), 0644); err != nil {
+		return 0, fmt.Errorf("failed to write Go code to file: %v", err)
+	}
+
+	return compiler.RunGoProgram(buildOptions("", goFile), args)
+}
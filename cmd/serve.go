@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/TFMV/bash2go/compiler"
+	"github.com/TFMV/bash2go/pkg/rpc"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+)
+
+// grpcAddr is the address the gRPC service listens on
+var grpcAddr string
+
+// serveConvertEnv lets clients resolve variables from the server's
+// environment when converting
+var serveConvertEnv bool
+
+func init() {
+	// Add serve command
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the conversion API for CI systems and other languages",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if grpcAddr == "" {
+				return fmt.Errorf("no transport selected; use --grpc to set the listen address")
+			}
+			return serveGRPC(grpcAddr)
+		},
+	}
+	serveCmd.Flags().StringVar(&grpcAddr, "grpc", "", "Serve the gRPC API defined in pkg/rpc/bash2go.proto on this address, such as :50051")
+	serveCmd.Flags().BoolVar(&serveConvertEnv, "allow-convert-env", false, "Let requests and bash2go:env directives resolve environment variables when converting, returning values of the server's environment to clients")
+	serveCmd.Flags().BoolVar(&vet, "vet", true, "Run go vet on generated code in builds and report findings as diagnostics")
+	addToolchainFlags(serveCmd)
+	rootCmd.AddCommand(serveCmd)
+}
+
+// serveGRPC serves the Converter service on addr until interrupted
+func serveGRPC(addr string) error {
+	build := buildOptions("", "")
+	if err := compiler.CheckGoVersion(build); err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %v", err)
+	}
+
+	server := grpc.NewServer()
+	converter := rpc.NewServer(build)
+	converter.ConvertEnv = serveConvertEnv
+	rpc.RegisterConverterServer(server, converter)
+
+	// Finish in-flight requests on interrupt
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		server.GracefulStop()
+	}()
+
+	fmt.Fprintf(os.Stderr, "Serving gRPC on %s\n", listener.Addr())
+	return server.Serve(listener)
+}
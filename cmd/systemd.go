@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/TFMV/bash2go/compiler"
+	"github.com/TFMV/bash2go/generator"
+	"github.com/TFMV/bash2go/parser"
+	"github.com/TFMV/bash2go/systemd"
+	"github.com/spf13/cobra"
+)
+
+var (
+	unitBuild  bool
+	binaryPath string
+)
+
+func init() {
+	// Add from-systemd command
+	systemdCmd := &cobra.Command{
+		Use:   "from-systemd [unit file]",
+		Short: "Convert the scripts of a systemd service to one program with start/stop subcommands",
+		Long: `from-systemd converts the ExecStart and ExecStop scripts of a systemd
+service unit, with ExecStartPre, ExecStartPost, ExecStopPost and ExecReload,
+into one Go program. ExecStart* commands become its start subcommand,
+ExecStop* commands its stop subcommand and ExecReload its reload subcommand,
+which also runs when the program receives SIGHUP.
+
+Commands that run a .sh script are converted from the script, found at its
+path or next to the unit file. Other commands, including "bash -c" scripts,
+are converted from the command line itself. The extracted scripts are saved
+in the output directory with the Go code and an updated unit file running
+the program.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return convertUnit(args[0], outputFile)
+		},
+	}
+	systemdCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output directory for the Go code and unit file (required)")
+	systemdCmd.MarkFlagRequired("output")
+	systemdCmd.Flags().BoolVar(&unitBuild, "build", false, "Also compile the program into the output directory")
+	systemdCmd.Flags().StringVar(&binaryPath, "binary-path", "", "Path of the installed program in the updated unit (default /usr/local/bin/<unit name>)")
+	addEnvFlags(systemdCmd)
+	addToolchainFlags(systemdCmd)
+	rootCmd.AddCommand(systemdCmd)
+}
+
+// convertUnit converts the scripts of the service unit at path to a Go
+// program in outDir, next to the unit rewritten to run it
+func convertUnit(path, outDir string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	unit, err := systemd.Parse(file)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	names := unit.Subcommands()
+	if len(names) == 0 {
+		return fmt.Errorf("no Exec commands to convert in %s", path)
+	}
+
+	options, err := generatorOptions()
+	if err != nil {
+		return err
+	}
+	var info generator.BuildInfo
+	if unitBuild {
+		if err := compiler.CheckGoVersion(buildOptions("", "")); err != nil {
+			return err
+		}
+		if info, err = scriptBuildInfo(path); err != nil {
+			return err
+		}
+		options.BuildInfo = true
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %v", err)
+	}
+
+	// Convert the script of each subcommand
+	logf("Converting %s to Go in %s\n", path, outDir)
+	var irs []*parser.IntermediateRepresentation
+	for _, name := range names {
+		script, err := subcommandScript(unit, name, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		scriptFile := filepath.Join(outDir, name+".sh")
+		if err := os.WriteFile(scriptFile, []byte(script), 0644); err != nil {
+			return fmt.Errorf("failed to write script: %v", err)
+		}
+
+		result, err := parser.ParseBashString(script)
+		if err != nil {
+			return fmt.Errorf("failed to parse the %s script: %v", name, err)
+		}
+		result.Filename = filepath.Base(scriptFile)
+		ir, err := parser.BuildIR(result)
+		if err != nil {
+			return fmt.Errorf("failed to build intermediate representation: %v", err)
+		}
+		irs = append(irs, ir)
+	}
+
+	ir, err := parser.CombineCommands(names, irs)
+	if err != nil {
+		return err
+	}
+	ir.Filename = filepath.Base(path)
+
+	// The rewritten unit reloads the program with SIGHUP
+	if slices.Contains(names, systemd.Reload) {
+		options.SignalCommands = map[string]string{"HUP": systemd.Reload}
+	}
+	gen := generator.NewGoCodeGenerator(ir)
+	gen.Options = options
+	goCode, err := gen.Generate()
+	if err != nil {
+		ir.Diagnostics.WriteSummary(os.Stderr)
+		return fmt.Errorf("failed to generate Go code: %v", err)
+	}
+	if err := gen.TypeCheck(goCode); err != nil {
+		ir.Diagnostics.WriteSummary(os.Stderr)
+		return fmt.Errorf("failed to generate valid Go code: %v", err)
+	}
+
+	goFile := filepath.Join(outDir, "main.go")
+	if err := os.WriteFile(goFile, []byte(goCode), 0644); err != nil {
+		return fmt.Errorf("failed to write Go code to file: %v", err)
+	}
+	logf("Generated Go code saved to %s\n", goFile)
+
+	unitName := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if unitBuild {
+		binary := filepath.Join(outDir, unitName)
+		options := buildOptions(binary, goFile)
+		options.Diagnostics = ir.Diagnostics
+		options.LDFlags = strings.TrimSpace(options.LDFlags + " " + info.LDFlags())
+		options.SourceMap = func(_ string, goLine int) string {
+			return gen.SourcePosition(goLine).String()
+		}
+		if err := compiler.BuildGoProgram(options); err != nil {
+			ir.Diagnostics.WriteSummary(os.Stderr)
+			return fmt.Errorf("failed to build Go program: %v", err)
+		}
+		logf("Compiled binary saved to %s\n", binary)
+	}
+
+	// Point the unit at the installed program
+	installed := binaryPath
+	if installed == "" {
+		installed = "/usr/local/bin/" + unitName
+	}
+	unitFile := filepath.Join(outDir, filepath.Base(path))
+	if err := os.WriteFile(unitFile, []byte(unit.Rewrite(installed)), 0644); err != nil {
+		return fmt.Errorf("failed to write unit file: %v", err)
+	}
+	logf("Updated unit saved to %s, running %s\n", unitFile, installed)
+
+	fmt.Println(gen.Metrics())
+	return ir.Diagnostics.WriteSummary(os.Stdout)
+}
+
+// subcommandScript returns the script performing the commands of the unit
+// that belong to the named subcommand, in order. Script files are looked up
+// at their path, then next to the unit in dir.
+func subcommandScript(unit *systemd.Unit, name, dir string) (string, error) {
+	var b strings.Builder
+	b.WriteString("#!/bin/bash\n")
+	fmt.Fprintf(&b, "# Generated by bash2go from-systemd: the %s commands of the unit\n", name)
+	for _, c := range unit.Commands {
+		if c.Subcommand() != name {
+			continue
+		}
+		if c.Prefixes != "" {
+			logf("Line %d: the %q prefix of %s is not converted\n", c.Line, c.Prefixes, c.Directive)
+		}
+		fmt.Fprintf(&b, "\n# %s at line %d\n", c.Directive, c.Line)
+
+		path, ok := c.ScriptFile()
+		if !ok {
+			b.WriteString(c.InlineScript())
+			b.WriteString("\n")
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			data, err = os.ReadFile(filepath.Join(dir, filepath.Base(path)))
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read the script of %s at line %d: %v", c.Directive, c.Line, err)
+		}
+		if args := c.Args[len(c.Args)-1]; args != path {
+			logf("Line %d: the arguments of %s are not passed to %s\n", c.Line, c.Directive, filepath.Base(path))
+		}
+		b.Write(data)
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			b.WriteString("\n")
+		}
+	}
+	return b.String(), nil
+}
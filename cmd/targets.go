@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/template"
+
+	"github.com/TFMV/bash2go/compiler"
+)
+
+// matrixTemplate is the default output template when building for several
+// targets, keeping the binaries apart
+const matrixTemplate = "{{.Name}}-{{.GOOS}}-{{.GOARCH}}"
+
+// buildTarget is a platform binaries are compiled for
+type buildTarget struct {
+	GOOS   string
+	GOARCH string
+
+	// explicit reports whether the target was selected with --target, rather
+	// than being the go command's default
+	explicit bool
+}
+
+// String returns the target in os/arch form
+func (t buildTarget) String() string {
+	return t.GOOS + "/" + t.GOARCH
+}
+
+// apply sets the target platform in build options
+func (t buildTarget) apply(options *compiler.BuildOptions) {
+	if t.explicit {
+		options.GOOS = t.GOOS
+		options.GOARCH = t.GOARCH
+	}
+}
+
+// outputName is the data available to --output-template
+type outputName struct {
+	Name   string // Binary name: the base of -o, or the script name in batch builds
+	GOOS   string
+	GOARCH string
+}
+
+// buildTargets returns the platforms selected with --target, or the platform
+// set by GOOS and GOARCH in the environment if none were given
+func buildTargets() ([]buildTarget, error) {
+	if len(targets) == 0 {
+		target := buildTarget{GOOS: os.Getenv("GOOS"), GOARCH: os.Getenv("GOARCH")}
+		if target.GOOS == "" {
+			target.GOOS = runtime.GOOS
+		}
+		if target.GOARCH == "" {
+			target.GOARCH = runtime.GOARCH
+		}
+		return []buildTarget{target}, nil
+	}
+
+	var result []buildTarget
+	seen := make(map[string]bool)
+	for _, spec := range targets {
+		goos, goarch, ok := strings.Cut(spec, "/")
+		if !ok || goos == "" || goarch == "" || strings.Contains(goarch, "/") {
+			return nil, fmt.Errorf("invalid target %q: expected os/arch, such as linux/amd64", spec)
+		}
+		if seen[spec] {
+			continue
+		}
+		seen[spec] = true
+		result = append(result, buildTarget{GOOS: goos, GOARCH: goarch, explicit: true})
+	}
+	return result, nil
+}
+
+// outputTemplateFor parses --output-template, defaulting to the bare name
+// for a single target and to matrixTemplate for several
+func outputTemplateFor(targets []buildTarget) (*template.Template, error) {
+	text := outTemplate
+	if text == "" {
+		text = "{{.Name}}"
+		if len(targets) > 1 {
+			text = matrixTemplate
+		}
+	}
+	tmpl, err := template.New("output").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output template: %v", err)
+	}
+	return tmpl, nil
+}
+
+// targetOutput returns the file name of the binary called name for target.
+// Windows binaries get an .exe extension if the template did not add one.
+func targetOutput(tmpl *template.Template, name string, target buildTarget) (string, error) {
+	if len(targets) > 1 {
+		// The extension is added back for the Windows targets only
+		name = strings.TrimSuffix(name, ".exe")
+	}
+
+	var out strings.Builder
+	err := tmpl.Execute(&out, outputName{Name: name, GOOS: target.GOOS, GOARCH: target.GOARCH})
+	if err != nil {
+		return "", fmt.Errorf("failed to render output template: %v", err)
+	}
+	output := out.String()
+	if output == "" || output != filepath.Base(output) {
+		return "", fmt.Errorf("output template must render a file name, got %q", output)
+	}
+	if target.GOOS == "windows" && !strings.EqualFold(filepath.Ext(output), ".exe") {
+		output += ".exe"
+	}
+	return output, nil
+}
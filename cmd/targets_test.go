@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+)
+
+// setTargetFlags sets the --target and --output-template flags for a test
+func setTargetFlags(t *testing.T, specs []string, text string) {
+	t.Helper()
+	savedTargets, savedTemplate := targets, outTemplate
+	t.Cleanup(func() {
+		targets, outTemplate = savedTargets, savedTemplate
+	})
+	targets, outTemplate = specs, text
+}
+
+// TestBuildTargets tests parsing --target, dropping repeated targets
+func TestBuildTargets(t *testing.T) {
+	setTargetFlags(t, []string{"linux/amd64", "windows/arm64", "linux/amd64"}, "")
+	got, err := buildTargets()
+	if err != nil {
+		t.Fatalf("buildTargets failed: %v", err)
+	}
+	want := []buildTarget{
+		{GOOS: "linux", GOARCH: "amd64", explicit: true},
+		{GOOS: "windows", GOARCH: "arm64", explicit: true},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	for _, spec := range []string{"linux", "linux/", "/amd64", "linux/arm/v7"} {
+		setTargetFlags(t, []string{spec}, "")
+		if _, err := buildTargets(); err == nil {
+			t.Errorf("Expected target %q to be rejected", spec)
+		}
+	}
+}
+
+// TestTargetOutput tests expanding the output template into the file name
+// of the binary for each target
+func TestTargetOutput(t *testing.T) {
+	linux := buildTarget{GOOS: "linux", GOARCH: "amd64"}
+	windows := buildTarget{GOOS: "windows", GOARCH: "arm64"}
+	tests := []struct {
+		targets []string
+		text    string
+		name    string
+		target  buildTarget
+		want    string
+	}{
+		// A single target keeps the name, with .exe added for Windows
+		{nil, "", "tool", linux, "tool"},
+		{nil, "", "tool", windows, "tool.exe"},
+		{nil, "", "tool.exe", windows, "tool.exe"},
+		{nil, "", "tool.exe", linux, "tool.exe"},
+
+		// Several targets default to the matrix template
+		{[]string{"linux/amd64", "windows/arm64"}, "", "tool", linux, "tool-linux-amd64"},
+		{[]string{"linux/amd64", "windows/arm64"}, "", "tool", windows, "tool-windows-arm64.exe"},
+		{[]string{"linux/amd64", "windows/arm64"}, "", "tool.exe", linux, "tool-linux-amd64"},
+
+		// Templates given with --output-template
+		{nil, "{{.Name}}_{{.GOOS}}_{{.GOARCH}}", "tool", linux, "tool_linux_amd64"},
+		{nil, "{{.GOARCH}}-{{.Name}}", "tool", windows, "arm64-tool.exe"},
+		{nil, "{{.Name}}.EXE", "tool", windows, "tool.EXE"},
+		{nil, `{{if eq .GOOS "windows"}}win{{else}}{{.GOOS}}{{end}}-{{.Name}}`, "tool", windows, "win-tool.exe"},
+	}
+	for _, test := range tests {
+		setTargetFlags(t, test.targets, test.text)
+		tmpl, err := outputTemplateFor(make([]buildTarget, max(len(test.targets), 1)))
+		if err != nil {
+			t.Fatalf("outputTemplateFor(%q) failed: %v", test.text, err)
+		}
+		got, err := targetOutput(tmpl, test.name, test.target)
+		if err != nil {
+			t.Errorf("targetOutput(%q, %q, %v) failed: %v", test.text, test.name, test.target, err)
+		} else if got != test.want {
+			t.Errorf("targetOutput(%q, %q, %v) = %q, want %q", test.text, test.name, test.target, got, test.want)
+		}
+	}
+}
+
+// TestTargetOutputErrors tests rejecting templates that do not parse, fail
+// to run or do not render a file name
+func TestTargetOutputErrors(t *testing.T) {
+	setTargetFlags(t, nil, "{{.Name")
+	if _, err := outputTemplateFor(nil); err == nil {
+		t.Errorf("Expected an unterminated action to be rejected")
+	}
+
+	for _, text := range []string{"{{.Version}}", "bin/{{.Name}}", "{{if false}}{{.Name}}{{end}}", "{{.GOOS}}/{{.Name}}"} {
+		setTargetFlags(t, nil, text)
+		tmpl, err := outputTemplateFor(nil)
+		if err != nil {
+			t.Fatalf("outputTemplateFor(%q) failed: %v", text, err)
+		}
+		if output, err := targetOutput(tmpl, "tool", buildTarget{GOOS: "linux", GOARCH: "amd64"}); err == nil {
+			t.Errorf("Expected template %q to be rejected, got %q", text, output)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/TFMV/bash2go/compiler"
+	"github.com/TFMV/bash2go/verify"
+	"github.com/spf13/cobra"
+)
+
+var (
+	corpusDir     string
+	verifyBash    string
+	verifyTimeout time.Duration
+)
+
+// Verification statuses of a corpus script, besides the batch statuses for
+// scripts that did not convert or build
+const statusMismatch = "mismatch"
+
+func init() {
+	// Add verify command
+	verifyCmd := &cobra.Command{
+		Use:   "verify --corpus dir",
+		Short: "Check that converted scripts behave like the originals on a corpus",
+		Long: `verify converts and builds every .sh script of a corpus directory, then
+runs both Bash and the binary on the inputs recorded for the script and
+reports where their exit code, standard output or created files differ.
+
+The inputs of foo.sh are read from foo.cases.yaml next to it, a list of
+cases with a name, args, stdin, env and files to create in the working
+directory. Scripts without a cases file run once without input. Each run
+starts in a new empty working directory.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return verifyCorpus(corpusDir)
+		},
+	}
+	verifyCmd.Flags().StringVar(&corpusDir, "corpus", "", "Directory of Bash scripts and their recorded inputs (required)")
+	verifyCmd.MarkFlagRequired("corpus")
+	verifyCmd.Flags().StringVar(&verifyBash, "bash", "bash", "Bash command the scripts are run with")
+	verifyCmd.Flags().DurationVar(&verifyTimeout, "timeout", verify.DefaultTimeout, "Time limit for each run of a script or binary")
+	verifyCmd.Flags().BoolVar(&jsonOutput, "json", false, "Print a machine-readable JSON report to stdout")
+	addEnvFlags(verifyCmd)
+	addToolchainFlags(verifyCmd)
+	rootCmd.AddCommand(verifyCmd)
+}
+
+// verifyReport is the verification result of one corpus script
+type verifyReport struct {
+	Script string       `json:"script"`
+	Status string       `json:"status"`
+	Error  string       `json:"error,omitempty"`
+	Cases  []caseReport `json:"cases,omitempty"`
+}
+
+// caseReport is the verification result of one case of a script
+type caseReport struct {
+	Name        string   `json:"name"`
+	Differences []string `json:"differences,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// verifyCorpus converts, builds and runs the scripts of a corpus, reporting
+// behavioral differences between the scripts and their binaries
+func verifyCorpus(dir string) error {
+	scripts, err := verify.LoadCorpus(dir)
+	if err != nil {
+		return err
+	}
+	options, err := generatorOptions()
+	if err != nil {
+		return err
+	}
+	if err := compiler.CheckGoVersion(buildOptions("", "")); err != nil {
+		return err
+	}
+	// convertForBatch adds the build info file to every program
+	options.BuildInfo = true
+
+	tempDir, err := os.MkdirTemp("", "bash2go-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	binDir := filepath.Join(tempDir, "bin")
+
+	// Convert every script, then build the programs together
+	logf("Converting and building %d scripts from %s\n", len(scripts), dir)
+	reports := make([]verifyReport, len(scripts))
+	var programs []compiler.Program
+	for i, script := range scripts {
+		reports[i] = verifyReport{Script: script.Path, Status: statusOK}
+		result := convertForBatch(script.Path, binDir, tempDir, options)
+		if result.err != nil {
+			reports[i].Status = statusConvertFailed
+			reports[i].Error = result.err.Error()
+			continue
+		}
+		programs = append(programs, result.program)
+	}
+
+	binaries := make(map[string]string)
+	if len(programs) > 0 {
+		options := buildOptions("", "")
+		options.Vet = false
+		built, err := compiler.BuildGoPrograms(programs, binDir, options)
+		if err != nil {
+			return fmt.Errorf("failed to build Go programs: %v", err)
+		}
+		for _, b := range built {
+			binaries[b.Name] = b.Output
+			for i := range reports {
+				if b.Err != nil && programName(reports[i].Script) == b.Name {
+					reports[i].Status = statusBuildFailed
+					reports[i].Error = b.Err.Error()
+				}
+			}
+		}
+	}
+
+	// Run both sides of every case
+	var failed int
+	for i, script := range scripts {
+		report := &reports[i]
+		if report.Status == statusOK {
+			report.Cases = verifyScript(script, binaries[programName(script.Path)], verifyTimeout)
+			for _, c := range report.Cases {
+				if c.Error != "" || len(c.Differences) > 0 {
+					report.Status = statusMismatch
+				}
+			}
+		}
+
+		if report.Status == statusOK {
+			if !jsonOutput {
+				fmt.Printf("ok   %s (%d cases)\n", report.Script, len(report.Cases))
+			}
+			continue
+		}
+		failed++
+		if jsonOutput {
+			continue
+		}
+		if report.Error != "" {
+			fmt.Printf("FAIL %s: %s: %s\n", report.Script, report.Status, report.Error)
+		}
+		for _, c := range report.Cases {
+			if c.Error != "" {
+				fmt.Printf("FAIL %s [%s]: %s\n", report.Script, c.Name, c.Error)
+			}
+			for _, diff := range c.Differences {
+				fmt.Printf("FAIL %s [%s]: %s\n", report.Script, c.Name, diff)
+			}
+		}
+	}
+
+	logf("Verified %d of %d scripts\n", len(scripts)-failed, len(scripts))
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(reports); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d scripts failed verification", failed, len(scripts))
+	}
+	return nil
+}
+
+// verifyScript runs a script and its binary on each of its cases
+func verifyScript(script verify.Script, binary string, timeout time.Duration) []caseReport {
+	path, err := filepath.Abs(script.Path)
+	if err != nil {
+		return []caseReport{{Name: "all", Error: err.Error()}}
+	}
+
+	reports := make([]caseReport, len(script.Cases))
+	for i, c := range script.Cases {
+		reports[i].Name = c.Name
+		want, err := verify.Run([]string{verifyBash, path}, c, timeout)
+		if err != nil {
+			reports[i].Error = fmt.Sprintf("bash: %v", err)
+			continue
+		}
+		got, err := verify.Run([]string{binary}, c, timeout)
+		if err != nil {
+			reports[i].Error = fmt.Sprintf("go: %v", err)
+			continue
+		}
+		reports[i].Differences = verify.Compare(want, got)
+	}
+	return reports
+}
@@ -0,0 +1,23 @@
+package cmd
+
+import "runtime/debug"
+
+// Version is the bash2go version, set at release time with
+// -ldflags "-X github.com/TFMV/bash2go/cmd.Version=v1.2.3"
+var Version = ""
+
+// version returns the bash2go version, falling back to the module version
+// recorded by go install
+func version() string {
+	if Version != "" {
+		return Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "devel"
+}
+
+func init() {
+	rootCmd.Version = version()
+}
@@ -0,0 +1,169 @@
+package compiler
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Program is one generated Go program in a batch build
+type Program struct {
+	Name   string // Name of the program, also used as its package directory
+	GoFile string // Path to the generated Go file
+	Output string // File name of the binary in the output directory; Name if empty
+
+	// Files holds additional source files for the program's package, by
+	// file name
+	Files map[string]string
+}
+
+// ProgramResult is the outcome of building one program of a batch
+type ProgramResult struct {
+	Name     string        // Name of the program
+	Output   string        // Path of the binary
+	Duration time.Duration // Time spent compiling and installing the binary
+	Err      error         // Why the program failed to build, or nil
+}
+
+// BuildGoPrograms compiles several generated programs into binaries in
+// outDir. All programs share one temporary module with a cmd/<name> package
+// each, so dependencies are resolved once, and up to options.Jobs programs
+// compile at a time. Failures of individual programs are reported in their
+// results; the error is for failures affecting the whole batch. The
+// OutputFile and GoFile fields of options are ignored.
+func BuildGoPrograms(programs []Program, outDir string, options BuildOptions) ([]ProgramResult, error) {
+	if err := CheckGoVersion(options); err != nil {
+		return nil, err
+	}
+
+	// Create a temporary directory if not specified
+	if options.TempDir == "" {
+		tempDir, err := os.MkdirTemp("", "bash2go-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temporary directory: %v", err)
+		}
+		options.TempDir = tempDir
+
+		// Clean up temporary directory unless keeping temp files, or the
+		// build failed and the files are needed for the report
+		defer func() {
+			if !options.KeepTempFiles {
+				os.RemoveAll(tempDir)
+			}
+		}()
+	}
+
+	// Lay out one package per program
+	var goFiles []string
+	seen := make(map[string]bool)
+	for _, program := range programs {
+		if seen[program.Name] {
+			return nil, fmt.Errorf("duplicate program name %q", program.Name)
+		}
+		seen[program.Name] = true
+
+		pkgDir := filepath.Join(options.TempDir, "cmd", program.Name)
+		if err := os.MkdirAll(pkgDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create package directory: %v", err)
+		}
+
+		goFile := filepath.Join(pkgDir, "main.go")
+		if err := copyGoFile(program.GoFile, goFile); err != nil {
+			return nil, err
+		}
+		goFiles = append(goFiles, goFile)
+
+		for name, source := range program.Files {
+			if err := os.WriteFile(filepath.Join(pkgDir, name), []byte(source), 0644); err != nil {
+				return nil, fmt.Errorf("failed to write %s: %v", name, err)
+			}
+		}
+	}
+
+	if err := setupModule(options, options.TempDir, tempModule, goFiles); err != nil {
+		return nil, err
+	}
+
+	// Catch generator bugs with static analysis before the final build
+	if options.Vet {
+		done := options.phase("vet")
+		err := vetModule(options.TempDir, options)
+		done()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	absOutDir, err := filepath.Abs(outDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve output directory: %v", err)
+	}
+
+	// Build the packages concurrently; they share the module and the build
+	// cache, and a failing program does not stop the others
+	binDir := filepath.Join(options.TempDir, "bin")
+	results := make([]ProgramResult, len(programs))
+	done := options.phase("compile")
+	parallel(len(programs), options.jobs(), func(i int) {
+		program := programs[i]
+		start := time.Now()
+		output := program.Output
+		if output == "" {
+			output = program.Name
+		}
+		results[i] = ProgramResult{
+			Name:   program.Name,
+			Output: filepath.Join(absOutDir, output),
+		}
+		results[i].Err = buildProgram(options, program, binDir, results[i].Output)
+		results[i].Duration = time.Since(start)
+	})
+	done()
+
+	// Keep the module for inspection if anything failed
+	for _, result := range results {
+		if result.Err != nil {
+			options.KeepTempFiles = true
+		}
+	}
+	return results, nil
+}
+
+// buildProgram builds one program of a batch in the shared module and
+// installs it at output
+func buildProgram(options BuildOptions, program Program, binDir, output string) error {
+	builtPath := filepath.Join(binDir, program.Name)
+	relFile := filepath.Join("cmd", program.Name, "main.go")
+	if out, err := goBuild(options, builtPath, "./"+filepath.ToSlash(filepath.Dir(relFile))); err != nil {
+		return buildFailure(options, err, out, []string{relFile}, "bash2go-failure-"+program.Name+".txt")
+	}
+
+	if options.Compress {
+		if err := compressBinary(options.context(), builtPath, options.Logf); err != nil {
+			return err
+		}
+	}
+
+	return installBinary(builtPath, output, options.Reproducible)
+}
+
+// parallel calls fn for each index below n, running at most jobs calls at
+// once, and waits for all of them to finish
+func parallel(n, jobs int, fn func(i int)) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, jobs)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
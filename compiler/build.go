@@ -1,18 +1,110 @@
 package compiler
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/TFMV/bash2go/diagnostics"
 )
 
+// tempModule is the module path of the temporary modules programs are built in
+const tempModule = "bash2go_output"
+
 // BuildOptions contains options for building the Go code
 type BuildOptions struct {
-	OutputFile    string // Name of the output binary
-	TempDir       string // Temporary directory for intermediate files
-	KeepTempFiles bool   // Whether to keep temporary files
-	GoFile        string // Path to the generated Go file
+	OutputFile    string   // Name of the output binary
+	TempDir       string   // Temporary directory for intermediate files
+	KeepTempFiles bool     // Whether to keep temporary files
+	GoFile        string   // Path to the generated Go file
+	Static        bool     // Whether to build a static binary with cgo disabled
+	LDFlags       string   // Extra flags passed to the linker
+	Small         bool     // Whether to strip the symbol table and debug info (-s -w)
+	Compress      bool     // Whether to compress the binary with upx, if installed
+	Vet           bool     // Whether to run go vet on the generated code before building
+	Staticcheck   bool     // Whether to also run staticcheck, if installed, when vetting
+	Reproducible  bool     // Whether to build byte-for-byte identical binaries from the same input
+	Tags          []string // Build tags passed to go build and go vet
+	GoBin         string   // Go command to run; "go" from PATH if empty
+	Jobs          int      // Programs compiled at once in batch builds; the CPU count if zero
+	GOOS          string   // Target operating system; the go command's default if empty
+	GOARCH        string   // Target architecture; the go command's default if empty
+	Toolchain     string   // Go release to use via GOTOOLCHAIN, such as "go1.24.2"
+
+	// Diagnostics, if set, receives findings from vetting the generated code
+	Diagnostics *diagnostics.Collector
+
+	// SourceMap, if set, returns the Bash location of a line of a generated
+	// file, given relative to the build module. It annotates failure reports.
+	SourceMap func(goFile string, goLine int) string
+
+	// Logf, if set, receives progress messages such as skipped steps
+	Logf func(format string, args ...interface{})
+
+	// Progress, if set, is called when a build phase such as "mod tidy" or
+	// "compile" starts, and returns a function called when it ends
+	Progress func(phase string) func()
+
+	// Context, if set, kills the commands the build runs when it is done,
+	// such as when the client of a server that requested the build leaves
+	Context context.Context
+}
+
+// context returns the context of the commands the build runs
+func (o BuildOptions) context() context.Context {
+	if o.Context != nil {
+		return o.Context
+	}
+	return context.Background()
+}
+
+// phase reports the start of a build phase to Progress, returning the
+// function that reports its end
+func (o BuildOptions) phase(name string) func() {
+	if o.Progress == nil {
+		return func() {}
+	}
+	return o.Progress(name)
+}
+
+// jobs returns the number of programs compiled at once in batch builds
+func (o BuildOptions) jobs() int {
+	if o.Jobs > 0 {
+		return o.Jobs
+	}
+	return runtime.NumCPU()
+}
+
+// ldflags returns the linker flags for the build, or an empty string if none
+func (o BuildOptions) ldflags() string {
+	flags := o.LDFlags
+	if o.Small {
+		flags = strings.TrimSpace("-s -w " + flags)
+	}
+	if o.Reproducible {
+		flags = strings.TrimSpace(flags + " -buildid=")
+	}
+	return flags
+}
+
+// buildArgs returns the arguments passed to go build
+func (o BuildOptions) buildArgs(output string, targets ...string) []string {
+	args := []string{"build"}
+	if o.Reproducible {
+		// Keep the temp directory and VCS state out of the binary
+		args = append(args, "-trimpath", "-buildvcs=false")
+	}
+	if len(o.Tags) > 0 {
+		args = append(args, "-tags", strings.Join(o.Tags, ","))
+	}
+	if flags := o.ldflags(); flags != "" {
+		args = append(args, "-ldflags", flags)
+	}
+	args = append(args, "-o", output)
+	return append(args, targets...)
 }
 
 // DefaultBuildOptions returns default build options
@@ -22,11 +114,16 @@ func DefaultBuildOptions(outputFile, goFile string) BuildOptions {
 		TempDir:       "", // Will be set to a generated temp dir if empty
 		KeepTempFiles: false,
 		GoFile:        goFile,
+		Vet:           true,
 	}
 }
 
 // BuildGoProgram compiles a Go source file into a binary
 func BuildGoProgram(options BuildOptions) error {
+	if err := CheckGoVersion(options); err != nil {
+		return err
+	}
+
 	// Create a temporary directory if not specified
 	if options.TempDir == "" {
 		tempDir, err := os.MkdirTemp("", "bash2go-")
@@ -35,67 +132,188 @@ func BuildGoProgram(options BuildOptions) error {
 		}
 		options.TempDir = tempDir
 
-		// Clean up temporary directory if not keeping temp files
-		if !options.KeepTempFiles {
-			defer os.RemoveAll(tempDir)
-		}
+		// Clean up temporary directory unless keeping temp files, or the
+		// build failed and the files are needed for the report
+		defer func() {
+			if !options.KeepTempFiles {
+				os.RemoveAll(tempDir)
+			}
+		}()
 	}
 
-	// Copy or move the Go file to the temp directory
-	goFileName := filepath.Base(options.GoFile)
-	tempGoFile := filepath.Join(options.TempDir, goFileName)
+	// Set up the Go module in the temp directory
+	goFileName, err := prepareModule(options, options.TempDir, options.GoFile)
+	if err != nil {
+		return err
+	}
 
-	if options.GoFile != tempGoFile {
-		data, err := os.ReadFile(options.GoFile)
+	// Catch generator bugs with static analysis before the final build
+	if options.Vet {
+		done := options.phase("vet")
+		err := vetModule(options.TempDir, options)
+		done()
 		if err != nil {
-			return fmt.Errorf("failed to read Go file: %v", err)
+			return err
 		}
+	}
 
-		if err := os.WriteFile(tempGoFile, data, 0644); err != nil {
-			return fmt.Errorf("failed to write Go file to temp directory: %v", err)
-		}
+	// Resolve the output path before building, since the build runs in the
+	// temp directory
+	outputPath, err := filepath.Abs(options.OutputFile)
+	if err != nil {
+		return fmt.Errorf("failed to resolve output path: %v", err)
 	}
 
-	// Initialize a Go module
-	cmd := exec.Command("go", "mod", "init", "bash2go_output")
-	cmd.Dir = options.TempDir
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to initialize Go module: %v\n%s", err, output)
+	// Build the binary inside the temp directory
+	builtPath := filepath.Join(options.TempDir, "bash2go-output")
+	done := options.phase("compile")
+	output, err := goBuild(options, builtPath, goFileName)
+	done()
+	if err != nil {
+		options.KeepTempFiles = true
+		return buildFailure(options, err, output, []string{goFileName}, failureReportName)
 	}
 
-	// Download dependencies
-	cmd = exec.Command("go", "mod", "tidy")
-	cmd.Dir = options.TempDir
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to tidy Go module: %v\n%s", err, output)
+	// Compress the binary before it is moved into place
+	if options.Compress {
+		done := options.phase("compress")
+		err := compressBinary(options.context(), builtPath, options.Logf)
+		done()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Move the binary to the requested path
+	if err := installBinary(builtPath, outputPath, options.Reproducible); err != nil {
+		return err
 	}
 
-	// Build the binary
-	cmd = exec.Command("go", "build", "-o", options.OutputFile, goFileName)
-	cmd.Dir = options.TempDir
+	return nil
+}
+
+// goBuild runs go build in the temp directory of options, writing the
+// binaries for the target packages to output. It returns the toolchain
+// output on failure.
+func goBuild(options BuildOptions, output string, targets ...string) ([]byte, error) {
+	cmd := options.goCommand(options.TempDir, options.buildArgs(output, targets...)...)
+	var env []string
+	if options.Static {
+		// Disable cgo so the binary has no libc dependency and runs in
+		// scratch containers
+		env = append(env, "CGO_ENABLED=0")
+	}
+	if options.GOOS != "" {
+		env = append(env, "GOOS="+options.GOOS)
+	}
+	if options.GOARCH != "" {
+		env = append(env, "GOARCH="+options.GOARCH)
+	}
+	if len(env) > 0 {
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		cmd.Env = append(cmd.Env, env...)
+	}
 	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to build Go program: %v\n%s", err, output)
+		return output, fmt.Errorf("failed to build Go program: %v\n%s", err, output)
 	}
+	return nil, nil
+}
 
-	// Move the binary to the current directory if it's not already there
-	if filepath.Dir(options.OutputFile) == "." {
-		currentDir, err := os.Getwd()
-		if err != nil {
-			return fmt.Errorf("failed to get current directory: %v", err)
+// prepareModule copies goFile into dir and sets up a Go module around it,
+// returning the name of the copied file
+func prepareModule(options BuildOptions, dir, goFile string) (string, error) {
+	// Copy or move the Go file to the temp directory
+	goFileName := filepath.Base(goFile)
+	tempGoFile := filepath.Join(dir, goFileName)
+
+	if goFile != tempGoFile {
+		if err := copyGoFile(goFile, tempGoFile); err != nil {
+			return "", err
 		}
+	}
+
+	if err := setupModule(options, dir, tempModule, []string{tempGoFile}); err != nil {
+		return "", err
+	}
+
+	return goFileName, nil
+}
+
+// copyGoFile copies the Go source file src to dst in the temp directory
+func copyGoFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("failed to read Go file: %v", err)
+	}
+
+	if err := os.WriteFile(dst, data, 0644); err != nil {
+		return fmt.Errorf("failed to write Go file to temp directory: %v", err)
+	}
+	return nil
+}
+
+// installBinary copies the binary at src to dst, creating parent directories
+// as needed. The binary is written next to dst and renamed into place, so an
+// existing file at dst is replaced atomically. For reproducible builds the
+// modification time is fixed as well.
+func installBinary(src, dst string, reproducible bool) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("failed to read output binary: %v", err)
+	}
 
-		outputPath := filepath.Join(options.TempDir, options.OutputFile)
-		finalPath := filepath.Join(currentDir, options.OutputFile)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+"-")
+	if err != nil {
+		return fmt.Errorf("failed to write output binary: %v", err)
+	}
+	defer os.Remove(tmp.Name())
 
-		data, err := os.ReadFile(outputPath)
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write output binary: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write output binary: %v", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return fmt.Errorf("failed to set output binary mode: %v", err)
+	}
+	if reproducible {
+		modTime, err := SourceDateEpoch()
 		if err != nil {
-			return fmt.Errorf("failed to read output binary: %v", err)
+			return err
 		}
-
-		if err := os.WriteFile(finalPath, data, 0755); err != nil {
-			return fmt.Errorf("failed to write output binary: %v", err)
+		if err := os.Chtimes(tmp.Name(), modTime, modTime); err != nil {
+			return fmt.Errorf("failed to set output binary time: %v", err)
 		}
 	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return fmt.Errorf("failed to write output binary: %v", err)
+	}
+	return nil
+}
 
+// setupModule initializes a Go module in dir for the given Go files, using
+// pinned dependency versions where possible
+func setupModule(options BuildOptions, dir, modulePath string, goFiles []string) error {
+	pinned, err := writeGoModule(dir, modulePath, goFiles)
+	if err != nil {
+		return fmt.Errorf("failed to initialize Go module: %v", err)
+	}
+
+	// Resolve dependencies bash2go does not pin
+	if !pinned {
+		defer options.phase("mod tidy")()
+		cmd := options.goCommand(dir, "mod", "tidy")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to tidy Go module: %v\n%s", err, output)
+		}
+	}
 	return nil
 }
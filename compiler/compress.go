@@ -0,0 +1,26 @@
+package compiler
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// compressBinary compresses the binary at path in place with upx. If upx is
+// not installed the binary is left as is and the skip is reported through
+// logf, since compression only saves space and never changes behavior.
+func compressBinary(ctx context.Context, path string, logf func(format string, args ...interface{})) error {
+	upx, err := exec.LookPath("upx")
+	if err != nil {
+		if logf != nil {
+			logf("upx not found in PATH; skipping compression of %s\n", path)
+		}
+		return nil
+	}
+
+	cmd := exec.CommandContext(ctx, upx, "-q", "--best", path)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to compress binary: %v\n%s", err, output)
+	}
+	return nil
+}
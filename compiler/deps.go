@@ -0,0 +1,116 @@
+package compiler
+
+import (
+	_ "embed"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// goVersion is the Go language version declared in generated go.mod files
+const goVersion = "1.24"
+
+// pinnedModules maps the third-party modules generated code may import to the
+// known-good versions bash2go builds against
+var pinnedModules = map[string]string{
+	"github.com/robfig/cron/v3": "v3.0.1",
+}
+
+// pinnedSums holds the go.sum entries for pinnedModules
+//
+//go:embed deps/go.sum
+var pinnedSums []byte
+
+// writeGoModule writes go.mod and go.sum for the generated programs in dir,
+// declaring the module modulePath.
+// Imports of pinned modules are required at their pinned versions. It reports
+// whether every third-party import was pinned; if not, the caller has to
+// resolve the remaining dependencies with go mod tidy.
+func writeGoModule(dir, modulePath string, goFiles []string) (bool, error) {
+	var imports []string
+	for _, goFile := range goFiles {
+		fileImports, err := fileImports(goFile)
+		if err != nil {
+			return false, err
+		}
+		imports = append(imports, fileImports...)
+	}
+
+	pinned := true
+	required := make(map[string]string)
+	for _, path := range imports {
+		if isStdlib(path) {
+			continue
+		}
+		module, version, ok := pinnedModule(path)
+		if !ok {
+			pinned = false
+			continue
+		}
+		required[module] = version
+	}
+
+	var modules []string
+	for module := range required {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+
+	var goMod strings.Builder
+	fmt.Fprintf(&goMod, "module %s\n\ngo %s\n", modulePath, goVersion)
+	if len(modules) > 0 {
+		goMod.WriteString("\nrequire (\n")
+		for _, module := range modules {
+			fmt.Fprintf(&goMod, "\t%s %s\n", module, required[module])
+		}
+		goMod.WriteString(")\n")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod.String()), 0644); err != nil {
+		return false, fmt.Errorf("failed to write go.mod: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "go.sum"), pinnedSums, 0644); err != nil {
+		return false, fmt.Errorf("failed to write go.sum: %v", err)
+	}
+	return pinned, nil
+}
+
+// fileImports returns the import paths of a Go source file
+func fileImports(goFile string) ([]string, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), goFile, nil, parser.ImportsOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read imports: %v", err)
+	}
+
+	var imports []string
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read imports: %v", err)
+		}
+		imports = append(imports, path)
+	}
+	return imports, nil
+}
+
+// pinnedModule returns the pinned module providing the package path
+func pinnedModule(path string) (string, string, bool) {
+	for module, version := range pinnedModules {
+		if path == module || strings.HasPrefix(path, module+"/") {
+			return module, version, true
+		}
+	}
+	return "", "", false
+}
+
+// isStdlib reports whether path is a standard library package, whose first
+// element never contains a dot
+func isStdlib(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
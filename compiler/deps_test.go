@@ -0,0 +1,122 @@
+package compiler
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeGoFile writes a Go source file named name in dir
+func writeGoFile(t *testing.T, dir, name, source string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestWriteGoModule tests that imports of pinned modules are required at
+// their pinned versions with their go.sum entries, so that the program
+// builds offline
+func TestWriteGoModule(t *testing.T) {
+	dir := t.TempDir()
+	main := writeGoFile(t, dir, "main.go", `package main
+
+import (
+	"fmt"
+
+	"github.com/robfig/cron/v3"
+)
+
+func main() {
+	_, err := cron.ParseStandard("*/5 * * * *")
+	fmt.Println(err)
+}
+`)
+	pinned, err := writeGoModule(dir, "example.com/job", []string{main})
+	if err != nil {
+		t.Fatalf("writeGoModule failed: %v", err)
+	}
+	if !pinned {
+		t.Errorf("Expected every import to be pinned")
+	}
+
+	goMod, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "module example.com/job\n\ngo " + goVersion + "\n\nrequire (\n\tgithub.com/robfig/cron/v3 v3.0.1\n)\n"
+	if string(goMod) != want {
+		t.Errorf("Expected go.mod:\n%s\ngot:\n%s", want, goMod)
+	}
+	goSum, err := os.ReadFile(filepath.Join(dir, "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(goSum, pinnedSums) || !bytes.Contains(goSum, []byte("github.com/robfig/cron/v3 v3.0.1 h1:")) {
+		t.Errorf("Expected the pinned go.sum, got:\n%s", goSum)
+	}
+
+	if testing.Short() {
+		t.Skip("skipping building the module in short mode")
+	}
+	modCache, err := exec.Command("go", "env", "GOMODCACHE").Output()
+	if err != nil {
+		t.Fatalf("go env GOMODCACHE failed: %v", err)
+	}
+	zip := filepath.Join(strings.TrimSpace(string(modCache)), "cache", "download", "github.com", "robfig", "cron", "v3", "@v", "v3.0.1.zip")
+	if _, err := os.Stat(zip); err != nil {
+		t.Skip("the pinned modules are not in the module cache")
+	}
+
+	// Without network access or changes to go.mod and go.sum
+	cmd := exec.Command("go", "build", "-o", filepath.Join(dir, "job"), ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOPROXY=off", "GOFLAGS=-mod=readonly", "GOTOOLCHAIN=local")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Building offline failed: %v\n%s", err, output)
+	}
+}
+
+// TestWriteGoModuleUnpinned tests that modules without a pinned version are
+// left for go mod tidy, and that programs using only the standard library
+// require nothing
+func TestWriteGoModuleUnpinned(t *testing.T) {
+	dir := t.TempDir()
+	main := writeGoFile(t, dir, "main.go", "package main\n\nimport (\n\t\"os\"\n\t\"example.com/lib/v2\"\n)\n")
+	pinned, err := writeGoModule(dir, "example.com/job", []string{main})
+	if err != nil {
+		t.Fatalf("writeGoModule failed: %v", err)
+	}
+	if pinned {
+		t.Errorf("Expected example.com/lib/v2 not to be pinned")
+	}
+
+	dir = t.TempDir()
+	main = writeGoFile(t, dir, "main.go", "package main\n\nimport \"os/exec\"\n")
+	pinned, err = writeGoModule(dir, "example.com/job", []string{main})
+	if err != nil {
+		t.Fatalf("writeGoModule failed: %v", err)
+	}
+	goMod, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pinned || strings.Contains(string(goMod), "require") {
+		t.Errorf("Expected a standard library program to require nothing, got pinned %v and:\n%s", pinned, goMod)
+	}
+}
+
+// TestWriteGoModuleInvalidSource tests that Go files whose imports cannot
+// be read are reported
+func TestWriteGoModuleInvalidSource(t *testing.T) {
+	dir := t.TempDir()
+	main := writeGoFile(t, dir, "main.go", "package main\n\nimport fmt\n")
+	if _, err := writeGoModule(dir, "example.com/job", []string{main}); err == nil {
+		t.Errorf("Expected an error for imports that do not parse")
+	}
+}
@@ -0,0 +1,79 @@
+package compiler
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+)
+
+// projectTemplates holds the task runner files written into projects
+//
+//go:embed project/*.tmpl
+var projectTemplates embed.FS
+
+// ProjectOptions describes a standalone Go module generated from a script
+type ProjectOptions struct {
+	Dir      string // Directory to write the project to
+	Name     string // Module path and binary name
+	Script   string // Path to the Bash script the project was generated from
+	GoFile   string // Path to the generated Go file
+	Taskfile bool   // Whether to write a Taskfile.yml instead of a Makefile
+
+	// Build selects the Go toolchain used to resolve dependencies
+	Build BuildOptions
+}
+
+// WriteProject writes a Go module containing the generated program to
+// options.Dir, together with a Makefile or Taskfile.yml with build, test,
+// lint and transpile targets. The transpile target regenerates main.go from
+// the script with bash2go.
+func WriteProject(options ProjectOptions) error {
+	if err := os.MkdirAll(options.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create project directory: %v", err)
+	}
+
+	mainFile := filepath.Join(options.Dir, "main.go")
+	if options.GoFile != mainFile {
+		if err := copyGoFile(options.GoFile, mainFile); err != nil {
+			return err
+		}
+	}
+
+	if err := setupModule(options.Build, options.Dir, options.Name, []string{mainFile}); err != nil {
+		return err
+	}
+
+	// Refer to the script relative to the project so the targets keep
+	// working when both are moved together
+	script := options.Script
+	absDir, errDir := filepath.Abs(options.Dir)
+	absScript, errScript := filepath.Abs(options.Script)
+	if errDir == nil && errScript == nil {
+		if rel, err := filepath.Rel(absDir, absScript); err == nil {
+			script = rel
+		}
+	}
+
+	name := "Makefile"
+	if options.Taskfile {
+		name = "Taskfile.yml"
+	}
+	tmpl, err := template.ParseFS(projectTemplates, "project/"+name+".tmpl")
+	if err != nil {
+		return fmt.Errorf("failed to load %s template: %v", name, err)
+	}
+
+	f, err := os.Create(filepath.Join(options.Dir, name))
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", name, err)
+	}
+	defer f.Close()
+
+	data := struct{ Name, Script string }{options.Name, filepath.ToSlash(script)}
+	if err := tmpl.Execute(f, data); err != nil {
+		return fmt.Errorf("failed to write %s: %v", name, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,96 @@
+package compiler
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// failureReportName is the name of the report written into the kept temp
+// directory when a program fails to build
+const failureReportName = "bash2go-failure.txt"
+
+// BuildError is returned when the Go toolchain fails to build generated
+// code. The temporary module is kept for inspection, along with a report
+// mapping the compiler output back to the Bash script.
+type BuildError struct {
+	Err     error  // The underlying build error
+	TempDir string // The kept temporary module
+	Report  string // Path of the failure report, empty if it could not be written
+}
+
+// Error implements the error interface
+func (e *BuildError) Error() string {
+	if e.Report == "" {
+		return fmt.Sprintf("%v\nbuild files kept in %s", e.Err, e.TempDir)
+	}
+	return fmt.Sprintf("%v\nbuild files kept in %s; failure report written to %s", e.Err, e.TempDir, e.Report)
+}
+
+// Unwrap returns the underlying build error
+func (e *BuildError) Unwrap() error {
+	return e.Err
+}
+
+// buildFailure creates the BuildError for a failed build in the temp module
+// of options, writing the report to reportName in the module. goFiles are
+// the generated files, relative to the module.
+func buildFailure(options BuildOptions, err error, output []byte, goFiles []string, reportName string) *BuildError {
+	buildErr := &BuildError{Err: err, TempDir: options.TempDir}
+	path := filepath.Join(options.TempDir, reportName)
+	report := failureReport(options, output, goFiles)
+	if os.WriteFile(path, report, 0644) == nil {
+		buildErr.Report = path
+	}
+	return buildErr
+}
+
+// failureReport formats the compiler output, with each message annotated
+// with the Bash location it maps to, followed by the numbered generated
+// source
+func failureReport(options BuildOptions, output []byte, goFiles []string) []byte {
+	var b bytes.Buffer
+	b.WriteString("bash2go build failure report\n\nCompiler output:\n")
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		b.WriteString(line)
+		if m := findingPattern.FindStringSubmatch(line); m != nil {
+			goLine, _ := strconv.Atoi(m[2])
+			if loc := options.sourceLocation(m[1], goLine); loc != "" {
+				fmt.Fprintf(&b, " (%s)", loc)
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	for _, goFile := range goFiles {
+		data, err := os.ReadFile(filepath.Join(options.TempDir, goFile))
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&b, "\nGenerated source %s:\n", goFile)
+		for i, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+			fmt.Fprintf(&b, "%5d  %s", i+1, line)
+			if loc := options.sourceLocation(goFile, i+1); loc != "" {
+				fmt.Fprintf(&b, "  // %s", loc)
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.Bytes()
+}
+
+// sourceLocation returns the Bash location of a line of generated code, or
+// an empty string if it is unknown
+func (o BuildOptions) sourceLocation(goFile string, goLine int) string {
+	if o.SourceMap == nil {
+		return ""
+	}
+	return o.SourceMap(filepath.ToSlash(goFile), goLine)
+}
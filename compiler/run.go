@@ -0,0 +1,76 @@
+package compiler
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+)
+
+// RunGoProgram compiles the Go source file options.GoFile in a temporary
+// module with the toolchain selected by options and runs it with args, streaming stdin, stdout and stderr. Interrupt and termination
+// signals are forwarded to the program, and its exit code is returned. No
+// artifact is left behind.
+//
+// The program is built and executed directly rather than with go run, since
+// go run reports every failing exit code as 1.
+func RunGoProgram(options BuildOptions, args []string) (int, error) {
+	if err := CheckGoVersion(options); err != nil {
+		return 0, err
+	}
+
+	tempDir, err := os.MkdirTemp("", "bash2go-run-")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	goFileName, err := prepareModule(options, tempDir, options.GoFile)
+	if err != nil {
+		return 0, err
+	}
+
+	// Build the program inside the temp module
+	binary := filepath.Join(tempDir, "program")
+	cmd := options.goCommand(tempDir, "build", "-o", binary, goFileName)
+	done := options.phase("compile")
+	output, err := cmd.CombinedOutput()
+	done()
+	if err != nil {
+		return 0, fmt.Errorf("failed to build Go program: %v\n%s", err, output)
+	}
+
+	// Run it attached to our standard streams
+	cmd = exec.Command(binary, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("failed to start Go program: %v", err)
+	}
+
+	// Forward signals while the program runs
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		for sig := range signals {
+			cmd.Process.Signal(sig)
+		}
+	}()
+
+	err = cmd.Wait()
+	signal.Stop(signals)
+	close(signals)
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to run Go program: %v", err)
+	}
+	return 0, nil
+}
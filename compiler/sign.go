@@ -0,0 +1,83 @@
+package compiler
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Signing methods supported by SignBinary
+const (
+	SignCosign = "cosign"
+	SignGPG    = "gpg"
+)
+
+// WriteChecksums writes a SHA256SUMS file at path listing the SHA-256 of
+// each file, in the format read by sha256sum -c. Files are listed by their
+// path relative to the directory of the SHA256SUMS file.
+func WriteChecksums(path string, files []string) error {
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return fmt.Errorf("failed to resolve checksum directory: %v", err)
+	}
+
+	var lines []string
+	for _, file := range files {
+		sum, err := FileSHA256(file)
+		if err != nil {
+			return err
+		}
+
+		name := file
+		if abs, err := filepath.Abs(file); err == nil {
+			if rel, err := filepath.Rel(dir, abs); err == nil {
+				name = rel
+			}
+		}
+		lines = append(lines, fmt.Sprintf("%s  %s\n", sum, filepath.ToSlash(name)))
+	}
+	sort.Strings(lines)
+
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "")), 0644); err != nil {
+		return fmt.Errorf("failed to write checksums: %v", err)
+	}
+	return nil
+}
+
+// SignBinary signs the file at path with method, SignCosign or SignGPG, and
+// returns the path of the detached signature. key selects the cosign key
+// file or the GPG key ID; the tool's default key is used if it is empty.
+func SignBinary(path, method, key string) (string, error) {
+	var sigPath string
+	var args []string
+	switch method {
+	case SignCosign:
+		sigPath = path + ".sig"
+		args = []string{"sign-blob", "--yes", "--output-signature", sigPath}
+		if key != "" {
+			args = append(args, "--key", key)
+		}
+	case SignGPG:
+		sigPath = path + ".asc"
+		args = []string{"--batch", "--yes", "--armor", "--detach-sign", "--output", sigPath}
+		if key != "" {
+			args = append(args, "--local-user", key)
+		}
+	default:
+		return "", fmt.Errorf("unknown signing method %q (want %q or %q)", method, SignCosign, SignGPG)
+	}
+
+	tool, err := exec.LookPath(method)
+	if err != nil {
+		return "", fmt.Errorf("%s is required to sign binaries but was not found in PATH", method)
+	}
+
+	cmd := exec.Command(tool, append(args, path)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to sign %s: %v\n%s", path, err, output)
+	}
+	return sigPath, nil
+}
@@ -0,0 +1,116 @@
+package compiler
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestWriteChecksums tests that sha256sum -c accepts the SHA256SUMS file
+// written for binaries, and rejects it once a binary is tampered with
+func TestWriteChecksums(t *testing.T) {
+	sha256sum, err := exec.LookPath("sha256sum")
+	if err != nil {
+		t.Skip("sha256sum is not installed")
+	}
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	var binaries []string
+	for _, name := range []string{"tool", "backup"} {
+		path := filepath.Join(dir, "bin", name)
+		if err := os.WriteFile(path, []byte("binary "+name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		binaries = append(binaries, path)
+	}
+
+	sums := filepath.Join(dir, "SHA256SUMS")
+	if err := WriteChecksums(sums, binaries); err != nil {
+		t.Fatalf("WriteChecksums failed: %v", err)
+	}
+	data, err := os.ReadFile(sums)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "  bin/tool\n") || !strings.Contains(string(data), "  bin/backup\n") {
+		t.Errorf("Expected paths relative to the SHA256SUMS file, got:\n%s", data)
+	}
+
+	check := func() error {
+		cmd := exec.Command(sha256sum, "-c", "SHA256SUMS")
+		cmd.Dir = dir
+		return cmd.Run()
+	}
+	if err := check(); err != nil {
+		t.Fatalf("Expected the checksums to verify, got %v", err)
+	}
+	if err := os.WriteFile(binaries[0], []byte("tampered"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := check(); err == nil {
+		t.Errorf("Expected the tampered binary to be rejected")
+	}
+}
+
+// TestSignBinaryGPG tests that the signature of a binary verifies with
+// gpg, and that it no longer does once the binary is tampered with
+func TestSignBinaryGPG(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping generating a GPG key in short mode")
+	}
+	gpg, err := exec.LookPath(SignGPG)
+	if err != nil {
+		t.Skip("gpg is not installed")
+	}
+	home, err := os.MkdirTemp("", "gnupg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GNUPGHOME", home)
+	t.Cleanup(func() {
+		// Stop the agent gpg started for the keyring
+		exec.Command("gpgconf", "--kill", "gpg-agent").Run()
+		os.RemoveAll(home)
+	})
+	key := "bash2go test <test@example.com>"
+	if output, err := exec.Command(gpg, "--batch", "--passphrase", "", "--quick-gen-key", key, "default", "sign", "never").CombinedOutput(); err != nil {
+		t.Fatalf("Generating a key failed: %v\n%s", err, output)
+	}
+
+	binary := filepath.Join(t.TempDir(), "tool")
+	if err := os.WriteFile(binary, []byte("binary"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	sig, err := SignBinary(binary, SignGPG, "test@example.com")
+	if err != nil {
+		t.Fatalf("SignBinary failed: %v", err)
+	}
+	if sig != binary+".asc" {
+		t.Errorf("Expected the signature at %s.asc, got %s", binary, sig)
+	}
+
+	verify := func() error {
+		return exec.Command(gpg, "--batch", "--verify", sig, binary).Run()
+	}
+	if err := verify(); err != nil {
+		t.Fatalf("Expected the signature to verify, got %v", err)
+	}
+	if err := os.WriteFile(binary, []byte("tampered"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := verify(); err == nil {
+		t.Errorf("Expected the signature of the tampered binary to be rejected")
+	}
+}
+
+// TestSignBinaryUnknownMethod tests that unknown signing methods fail
+// without running anything
+func TestSignBinaryUnknownMethod(t *testing.T) {
+	if _, err := SignBinary("tool", "minisign", ""); err == nil || !strings.Contains(err.Error(), "unknown signing method") {
+		t.Errorf("Expected an unknown signing method error, got %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package compiler
+
+import (
+	"fmt"
+	"go/version"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// minGoVersion is the oldest Go toolchain that can build generated programs
+const minGoVersion = "go" + goVersion
+
+// goBin returns the Go command selected by the options
+func (o BuildOptions) goBin() string {
+	if o.GoBin != "" {
+		return o.GoBin
+	}
+	return "go"
+}
+
+// goCommand returns a command running the selected Go toolchain in dir
+func (o BuildOptions) goCommand(dir string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(o.context(), o.goBin(), args...)
+	cmd.Dir = dir
+	if o.Toolchain != "" {
+		// The go command switches to the requested release itself
+		cmd.Env = append(os.Environ(), "GOTOOLCHAIN="+o.Toolchain)
+	}
+	return cmd
+}
+
+// GoVersion returns the version of the Go toolchain selected by options,
+// such as "go1.24.2"
+func GoVersion(options BuildOptions) (string, error) {
+	output, err := options.goCommand("", "env", "GOVERSION").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("failed to run Go toolchain %s: %v\n%s", options.goBin(), err, output)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// CheckGoVersion verifies that the Go toolchain selected by options exists
+// and is recent enough to build generated programs
+func CheckGoVersion(options BuildOptions) error {
+	current, err := GoVersion(options)
+	if err != nil {
+		return err
+	}
+
+	if !supportedGoVersion(current) {
+		release, _ := parseGoVersion(current)
+		return fmt.Errorf("Go toolchain %s is %s, but bash2go requires %s or newer", options.goBin(), release, minGoVersion)
+	}
+	return nil
+}
+
+// parseGoVersion returns the Go version in the GOVERSION a toolchain
+// reports, such as "go1.24.2" or "go1.21rc1". Development builds report
+// versions such as "devel go1.25-abcdef Tue Jan 7 10:00:00 2025 +0000",
+// with the release they lead to after "devel". It reports false if there is
+// no valid version.
+func parseGoVersion(s string) (string, bool) {
+	for _, field := range strings.Fields(s) {
+		if version.IsValid(field) {
+			return field, true
+		}
+	}
+	return "", false
+}
+
+// supportedGoVersion reports whether the toolchain reporting the GOVERSION
+// current can build generated programs. Unrecognized versions, such as those
+// of custom builds, are given the benefit of the doubt.
+func supportedGoVersion(current string) bool {
+	release, ok := parseGoVersion(current)
+	return !ok || version.Compare(release, minGoVersion) >= 0
+}
@@ -0,0 +1,62 @@
+package compiler
+
+import "testing"
+
+// TestParseGoVersion tests extracting the Go version from GOVERSION
+func TestParseGoVersion(t *testing.T) {
+	tests := []struct {
+		goVersion string
+		want      string
+		ok        bool
+	}{
+		{"go1.24.2", "go1.24.2", true},
+		{"go1.24", "go1.24", true},
+		{"go1.21rc1", "go1.21rc1", true},
+		{"go1.23.4-custom", "go1.23.4-custom", true},
+		{"  go1.24.2\n", "go1.24.2", true},
+		{"devel go1.25-abcdef Tue Jan 7 10:00:00 2025 +0000", "go1.25-abcdef", true},
+		{"devel +abcdef Tue Jan 7 10:00:00 2025 +0000", "", false},
+		{"", "", false},
+		{"1.24.2", "", false},
+	}
+	for _, test := range tests {
+		got, ok := parseGoVersion(test.goVersion)
+		if got != test.want || ok != test.ok {
+			t.Errorf("parseGoVersion(%q) = %q, %v, want %q, %v", test.goVersion, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+// TestSupportedGoVersion tests comparing the Go version with the oldest one
+// that builds generated programs
+func TestSupportedGoVersion(t *testing.T) {
+	if minGoVersion != "go1.24" {
+		t.Fatalf("Expected the cases to be updated for %s", minGoVersion)
+	}
+	tests := []struct {
+		goVersion string
+		want      bool
+	}{
+		{"go1.24", true},
+		{"go1.24.0", true},
+		{"go1.24.2", true},
+		{"go1.25.1", true},
+		{"go1.100", true},
+		{"go1.25rc1", true},
+		{"go1.24rc1", true},
+		{"go1.23rc1", false},
+		{"go1.23.12", false},
+		{"go1.21rc1", false},
+		{"go1.9", false},
+		{"go1.23.4-custom", false},
+		{"devel go1.25-abcdef Tue Jan 7 10:00:00 2025 +0000", true},
+		{"devel go1.22-abcdef Tue Jan 7 10:00:00 2023 +0000", false},
+		{"devel +abcdef Tue Jan 7 10:00:00 2025 +0000", true},
+		{"", true},
+	}
+	for _, test := range tests {
+		if got := supportedGoVersion(test.goVersion); got != test.want {
+			t.Errorf("supportedGoVersion(%q) = %v, want %v", test.goVersion, got, test.want)
+		}
+	}
+}
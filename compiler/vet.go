@@ -0,0 +1,82 @@
+package compiler
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/TFMV/bash2go/diagnostics"
+)
+
+// findingPattern matches a finding reported by go vet or staticcheck, such as
+// "./script.sh.go:12:2: unreachable code"
+var findingPattern = regexp.MustCompile(`(?m)^(?:vet: )?(?:\./)?([^\s:]+\.go):(\d+):(\d+): (.+)$`)
+
+// vetModule runs go vet, and staticcheck if requested, on the module in dir
+// and adds their findings to the diagnostics in options. Analysis never stops
+// the build on its own: compile errors it finds resurface from go build.
+func vetModule(dir string, options BuildOptions) error {
+	args := []string{"vet"}
+	if len(options.Tags) > 0 {
+		args = append(args, "-tags", strings.Join(options.Tags, ","))
+	}
+	cmd := options.goCommand(dir, append(args, "./...")...)
+	output, err := cmd.CombinedOutput()
+	if n := addFindings(options, diagnostics.CodeVet, output); err != nil && n == 0 {
+		return fmt.Errorf("failed to vet Go program: %v\n%s", err, output)
+	}
+
+	if !options.Staticcheck {
+		return nil
+	}
+
+	staticcheck, err := exec.LookPath("staticcheck")
+	if err != nil {
+		if options.Logf != nil {
+			options.Logf("staticcheck not found in PATH; skipping it\n")
+		}
+		return nil
+	}
+
+	args = nil
+	if len(options.Tags) > 0 {
+		args = append(args, "-tags", strings.Join(options.Tags, ","))
+	}
+	cmd = exec.CommandContext(options.context(), staticcheck, append(args, "./...")...)
+	cmd.Dir = dir
+	output, err = cmd.CombinedOutput()
+	if n := addFindings(options, diagnostics.CodeStaticcheck, output); err != nil && n == 0 {
+		return fmt.Errorf("failed to run staticcheck: %v\n%s", err, output)
+	}
+	return nil
+}
+
+// addFindings adds each finding in output to the diagnostics of options as a
+// warning with the given code, returning the number of findings. Findings
+// keep their position in the generated code, and their message names the
+// Bash location it maps to, if known.
+func addFindings(options BuildOptions, code string, output []byte) int {
+	matches := findingPattern.FindAllSubmatch(output, -1)
+	if options.Diagnostics == nil {
+		return len(matches)
+	}
+	for _, m := range matches {
+		line, _ := strconv.ParseUint(string(m[2]), 10, 0)
+		column, _ := strconv.ParseUint(string(m[3]), 10, 0)
+		message := string(m[4])
+		if loc := options.sourceLocation(string(m[1]), int(line)); loc != "" {
+			message += " (" + loc + ")"
+		}
+		options.Diagnostics.Add(diagnostics.Diagnostic{
+			Severity: diagnostics.SeverityWarning,
+			Code:     code,
+			Message:  message,
+			File:     string(m[1]),
+			Line:     uint(line),
+			Column:   uint(column),
+		})
+	}
+	return len(matches)
+}
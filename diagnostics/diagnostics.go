@@ -0,0 +1,201 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Severity classifies a diagnostic.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+// String returns the lowercase name of the severity.
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return fmt.Sprintf("severity(%d)", int(s))
+	}
+}
+
+// MarshalJSON encodes the severity as its name.
+func (s Severity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON decodes a severity from its name.
+func (s *Severity) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	switch name {
+	case "info":
+		*s = SeverityInfo
+	case "warning":
+		*s = SeverityWarning
+	case "error":
+		*s = SeverityError
+	default:
+		return fmt.Errorf("unknown severity %q", name)
+	}
+	return nil
+}
+
+// Common diagnostic codes.
+const (
+	CodeUnsupported  = "unsupported-construct"
+	CodeExecFallback = "exec-fallback"
+	CodePlaceholder  = "placeholder"
+	CodeDirective    = "directive"
+	CodeVet          = "go-vet"
+	CodeStaticcheck  = "staticcheck"
+	CodeTypeCheck    = "type-check"
+	CodePlugin       = "plugin"
+	CodeMapping      = "mapping"
+)
+
+// Diagnostic is a single message about the conversion of a script.
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code,omitempty"`
+	Message  string   `json:"message"`
+	File     string   `json:"file,omitempty"`
+	Line     uint     `json:"line,omitempty"`
+	Column   uint     `json:"column,omitempty"`
+}
+
+// String formats the diagnostic as "file:line:col: severity: message [code]".
+func (d Diagnostic) String() string {
+	var b strings.Builder
+	if loc := d.location(); loc != "" {
+		b.WriteString(loc)
+		b.WriteString(": ")
+	}
+	b.WriteString(d.Severity.String())
+	b.WriteString(": ")
+	b.WriteString(d.Message)
+	if d.Code != "" {
+		b.WriteString(" [")
+		b.WriteString(d.Code)
+		b.WriteString("]")
+	}
+	return b.String()
+}
+
+// location formats the source location of the diagnostic.
+func (d Diagnostic) location() string {
+	switch {
+	case d.Line == 0:
+		return d.File
+	case d.File == "":
+		return fmt.Sprintf("line %d", d.Line)
+	case d.Column == 0:
+		return fmt.Sprintf("%s:%d", d.File, d.Line)
+	default:
+		return fmt.Sprintf("%s:%d:%d", d.File, d.Line, d.Column)
+	}
+}
+
+// Collector accumulates diagnostics from the parser and generator. It is
+// safe for concurrent use.
+type Collector struct {
+	mu    sync.Mutex
+	items []Diagnostic
+	seen  map[Diagnostic]bool
+}
+
+// NewCollector creates an empty Collector.
+func NewCollector() *Collector {
+	return &Collector{}
+}
+
+// Add records a diagnostic. Identical diagnostics are recorded once, so the
+// same IR may be generated repeatedly without duplicating messages.
+func (c *Collector) Add(d Diagnostic) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.seen == nil {
+		c.seen = make(map[Diagnostic]bool)
+	}
+	if c.seen[d] {
+		return
+	}
+	c.seen[d] = true
+	c.items = append(c.items, d)
+}
+
+// Items returns the recorded diagnostics ordered by file and position.
+func (c *Collector) Items() []Diagnostic {
+	c.mu.Lock()
+	items := make([]Diagnostic, len(c.items))
+	copy(items, c.items)
+	c.mu.Unlock()
+
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return items
+}
+
+// Count returns the number of diagnostics with the given severity.
+func (c *Collector) Count(severity Severity) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := 0
+	for _, d := range c.items {
+		if d.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
+
+// HasErrors reports whether any error diagnostics were recorded.
+func (c *Collector) HasErrors() bool {
+	return c.Count(SeverityError) > 0
+}
+
+// WriteSummary writes a human-readable summary of the diagnostics to w.
+// Nothing is written when no diagnostics were recorded.
+func (c *Collector) WriteSummary(w io.Writer) error {
+	items := c.Items()
+	if len(items) == 0 {
+		return nil
+	}
+
+	_, err := fmt.Fprintf(w, "Diagnostics: %d error(s), %d warning(s), %d info\n",
+		c.Count(SeverityError), c.Count(SeverityWarning), c.Count(SeverityInfo))
+	if err != nil {
+		return err
+	}
+	for _, d := range items {
+		if _, err := fmt.Fprintf(w, "  %s\n", d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package diagnostics
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestCollector tests recording, ordering, and counting diagnostics
+func TestCollector(t *testing.T) {
+	c := NewCollector()
+	c.Add(Diagnostic{Severity: SeverityInfo, Code: CodeExecFallback, Message: "grep executed externally", File: "a.sh", Line: 7, Column: 1})
+	c.Add(Diagnostic{Severity: SeverityError, Code: CodeUnsupported, Message: "unsupported construct: coproc", File: "a.sh", Line: 3, Column: 5})
+
+	items := c.Items()
+	if len(items) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %d", len(items))
+	}
+
+	if items[0].Line != 3 {
+		t.Fatalf("Expected diagnostics ordered by line, got %v", items)
+	}
+
+	if !c.HasErrors() {
+		t.Fatal("Expected HasErrors to be true")
+	}
+
+	if got := items[0].String(); got != "a.sh:3:5: error: unsupported construct: coproc [unsupported-construct]" {
+		t.Fatalf("Unexpected diagnostic string: %s", got)
+	}
+
+	// Verify the summary
+	var buf bytes.Buffer
+	if err := c.WriteSummary(&buf); err != nil {
+		t.Fatalf("WriteSummary failed: %v", err)
+	}
+
+	if !strings.HasPrefix(buf.String(), "Diagnostics: 1 error(s), 0 warning(s), 1 info") {
+		t.Fatalf("Unexpected summary: %s", buf.String())
+	}
+}
+
+// TestDiagnosticJSON tests the JSON encoding of diagnostics
+func TestDiagnosticJSON(t *testing.T) {
+	d := Diagnostic{Severity: SeverityWarning, Message: "word splitting changed", Line: 2}
+
+	data, err := json.Marshal(d)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	if !strings.Contains(string(data), `"severity":"warning"`) {
+		t.Fatalf("Expected severity name in JSON, got %s", data)
+	}
+
+	var decoded Diagnostic
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if decoded != d {
+		t.Fatalf("Expected %+v after round trip, got %+v", d, decoded)
+	}
+}
+
+// TestWriteSARIF tests encoding diagnostics as a SARIF log
+func TestWriteSARIF(t *testing.T) {
+	items := []Diagnostic{
+		{Severity: SeverityError, Code: CodeUnsupported, Message: "unsupported construct: coproc", File: "scripts/a.sh", Line: 3, Column: 5},
+		{Severity: SeverityInfo, Code: CodeExecFallback, Message: "grep executed externally", File: "scripts/a.sh", Line: 7},
+		{Severity: SeverityWarning, Message: "no location"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, "v1.2.3", items); err != nil {
+		t.Fatalf("WriteSARIF failed: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("Invalid SARIF: %v", err)
+	}
+	if log.Version != SARIFVersion || len(log.Runs) != 1 {
+		t.Fatalf("Unexpected log: %s", buf.String())
+	}
+	run := log.Runs[0]
+	if run.Tool.Driver.Version != "v1.2.3" || len(run.Tool.Driver.Rules) != 2 || run.Tool.Driver.Rules[1].ID != CodeUnsupported {
+		t.Errorf("Unexpected rules: %+v", run.Tool.Driver)
+	}
+	if len(run.Results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(run.Results))
+	}
+
+	first := run.Results[0]
+	if first.Level != "error" || first.RuleID != CodeUnsupported || len(first.Locations) != 1 {
+		t.Errorf("Unexpected result: %+v", first)
+	}
+	location := first.Locations[0].PhysicalLocation
+	if location.ArtifactLocation.URI != "scripts/a.sh" || location.Region.StartLine != 3 || location.Region.StartColumn != 5 {
+		t.Errorf("Unexpected location: %+v", location)
+	}
+	if run.Results[1].Level != "note" || run.Results[2].Locations != nil {
+		t.Errorf("Unexpected results: %+v", run.Results[1:])
+	}
+}
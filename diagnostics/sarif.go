@@ -0,0 +1,155 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"slices"
+)
+
+// SARIFVersion is the version of the SARIF format written by WriteSARIF.
+const SARIFVersion = "2.1.0"
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// ruleDescriptions describe the common diagnostic codes as SARIF rules.
+var ruleDescriptions = map[string]string{
+	CodeUnsupported:  "Bash construct that bash2go cannot translate",
+	CodeExecFallback: "Command executed as an external process instead of native Go",
+	CodePlaceholder:  "Construct translated to placeholder code that needs review",
+	CodeDirective:    "Invalid bash2go directive",
+	CodeVet:          "go vet finding in the generated code",
+	CodeStaticcheck:  "staticcheck finding in the generated code",
+	CodeTypeCheck:    "Type error in the generated code",
+	CodePlugin:       "Translation plugin failure",
+	CodeMapping:      "Command mapping failure or forbidden command",
+}
+
+// sarifLog is the root object of a SARIF file.
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription *sarifText   `json:"shortDescription,omitempty"`
+	DefaultConfig    sarifDefault `json:"defaultConfiguration"`
+}
+
+type sarifDefault struct {
+	Level string `json:"level"`
+}
+
+type sarifText struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId,omitempty"`
+	Level     string          `json:"level"`
+	Message   sarifText       `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifact `json:"artifactLocation"`
+	Region           *sarifRegion  `json:"region,omitempty"`
+}
+
+type sarifArtifact struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   uint `json:"startLine"`
+	StartColumn uint `json:"startColumn,omitempty"`
+}
+
+// sarifLevel returns the SARIF level of a severity.
+func sarifLevel(s Severity) string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// WriteSARIF writes diagnostics to w as a SARIF log, the format read by code
+// review tools and security scanners. version is the bash2go version
+// reported as the tool version. File names are written as relative URIs.
+func WriteSARIF(w io.Writer, version string, items []Diagnostic) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "bash2go",
+			Version:        version,
+			InformationURI: "https://github.com/TFMV/bash2go",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	// Describe each code once, at the highest severity it is reported with
+	levels := make(map[string]Severity)
+	for _, d := range items {
+		if severity, ok := levels[d.Code]; d.Code != "" && (!ok || d.Severity > severity) {
+			levels[d.Code] = d.Severity
+		}
+	}
+	codes := make([]string, 0, len(levels))
+	for code := range levels {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	for _, code := range codes {
+		rule := sarifRule{ID: code, DefaultConfig: sarifDefault{Level: sarifLevel(levels[code])}}
+		if text, ok := ruleDescriptions[code]; ok {
+			rule.ShortDescription = &sarifText{Text: text}
+		}
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+	}
+
+	for _, d := range items {
+		result := sarifResult{
+			RuleID:  d.Code,
+			Level:   sarifLevel(d.Severity),
+			Message: sarifText{Text: d.Message},
+		}
+		if d.File != "" {
+			location := sarifPhysicalLocation{ArtifactLocation: sarifArtifact{URI: filepath.ToSlash(d.File)}}
+			if d.Line > 0 {
+				location.Region = &sarifRegion{StartLine: d.Line, StartColumn: d.Column}
+			}
+			result.Locations = []sarifLocation{{PhysicalLocation: location}}
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{Version: SARIFVersion, Schema: sarifSchema, Runs: []sarifRun{run}})
+}
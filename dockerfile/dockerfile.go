@@ -0,0 +1,367 @@
+// Package dockerfile extracts the shell snippets of RUN instructions from
+// Dockerfiles, so that they can be converted to Go like any Bash script.
+package dockerfile
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// Run is the shell script of one RUN instruction
+type Run struct {
+	Stage   string   // Name of the build stage, or its index if unnamed
+	Line    int      // Line of the RUN instruction
+	Workdir string   // Directory set by WORKDIR, or empty if none was set
+	Env     []string // KEY=value pairs set by ENV and ARG, with shell quoting
+	Shell   []string // Shell set by SHELL, or nil for the default /bin/sh -c
+	Script  string   // Shell script run by the instruction
+}
+
+// Standalone returns the script prefixed with the cd and export commands
+// that reproduce the working directory and environment of the instruction
+func (r Run) Standalone() string {
+	var b strings.Builder
+	if r.Workdir != "" {
+		fmt.Fprintf(&b, "cd %s\n", quote(r.Workdir))
+	}
+	for _, env := range r.Env {
+		fmt.Fprintf(&b, "export %s\n", env)
+	}
+	b.WriteString(r.Script)
+	if !strings.HasSuffix(r.Script, "\n") {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// BuilderScript combines runs into one Bash script that performs them in
+// order. Each step starts in the working directory and environment of its
+// instruction.
+func BuilderScript(runs []Run) string {
+	var b strings.Builder
+	b.WriteString("#!/bin/bash\n")
+	b.WriteString("# Generated by bash2go from-dockerfile: one step per RUN instruction\n")
+	for i, run := range runs {
+		fmt.Fprintf(&b, "\n# Step %d: RUN at line %d, stage %s\n", i+1, run.Line, run.Stage)
+		b.WriteString(run.Standalone())
+	}
+	return b.String()
+}
+
+// heredocPattern matches a heredoc marker such as <<EOF, <<-EOF or <<"EOF"
+var heredocPattern = regexp.MustCompile(`<<(-?)(["']?)([A-Za-z_][A-Za-z0-9_]*)(["']?)`)
+
+// runFlagPattern matches the options of a RUN instruction, such as
+// --mount=type=cache,target=/root/.cache
+var runFlagPattern = regexp.MustCompile(`^(?:--\S+\s+)+`)
+
+// stage is the state of a build stage that RUN instructions depend on
+type stage struct {
+	name    string
+	workdir string
+	env     []string
+	shell   []string
+}
+
+// setEnv sets name to the quoted value in the stage environment
+func (s *stage) setEnv(name, value string) {
+	pair := name + "=" + value
+	for i, env := range s.env {
+		if strings.HasPrefix(env, name+"=") {
+			s.env[i] = pair
+			return
+		}
+	}
+	s.env = append(s.env, pair)
+}
+
+// Parse returns the RUN instructions of the Dockerfile read from r, in order
+func Parse(r io.Reader) ([]Run, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	escape := byte('\\')
+	var runs []Run
+	var stages []*stage
+	var current *stage
+	directives := true
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			// Parser directives are only recognized before anything else
+			if directives {
+				if value, ok := strings.CutPrefix(strings.ReplaceAll(strings.ToLower(line), " ", ""), "#escape="); ok && len(value) == 1 {
+					escape = value[0]
+				}
+			}
+			continue
+		}
+		directives = false
+
+		// Join continuation lines, dropping comments between them
+		start := i + 1
+		text := line
+		for strings.HasSuffix(text, string(escape)) && i+1 < len(lines) {
+			text = strings.TrimSuffix(text, string(escape))
+			if escape == '\\' {
+				// Keep the layout; Bash reads the continuation the same way
+				text += "\\\n"
+			} else {
+				text += " "
+			}
+			i++
+			for i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "#") {
+				i++
+			}
+			if i < len(lines) {
+				text += strings.TrimRight(lines[i], " \t")
+			}
+		}
+
+		keyword, args := text, ""
+		if n := strings.IndexAny(text, " \t"); n >= 0 {
+			keyword, args = text[:n], strings.TrimSpace(text[n:])
+		}
+		switch strings.ToUpper(keyword) {
+		case "FROM":
+			current = newStage(args, stages)
+			stages = append(stages, current)
+		case "WORKDIR":
+			if current == nil {
+				return nil, fmt.Errorf("line %d: WORKDIR before FROM", start)
+			}
+			dir := unquote(args)
+			if !path.IsAbs(dir) {
+				base := current.workdir
+				if base == "" {
+					base = "/"
+				}
+				dir = path.Join(base, dir)
+			}
+			current.workdir = dir
+		case "ENV":
+			if current == nil {
+				return nil, fmt.Errorf("line %d: ENV before FROM", start)
+			}
+			parseEnv(current, args)
+		case "ARG":
+			// Arguments before the first FROM only apply to FROM lines
+			if current == nil {
+				continue
+			}
+			for _, word := range splitWords(args) {
+				if name, value, ok := strings.Cut(word, "="); ok {
+					current.setEnv(name, value)
+				}
+			}
+		case "SHELL":
+			if current == nil {
+				return nil, fmt.Errorf("line %d: SHELL before FROM", start)
+			}
+			var shell []string
+			if err := json.Unmarshal([]byte(args), &shell); err != nil {
+				return nil, fmt.Errorf("line %d: SHELL requires a JSON array: %v", start, err)
+			}
+			current.shell = shell
+		case "RUN":
+			if current == nil {
+				return nil, fmt.Errorf("line %d: RUN before FROM", start)
+			}
+			args = runFlagPattern.ReplaceAllString(args, "")
+
+			var script string
+			var err error
+			if markers := heredocPattern.FindAllStringSubmatch(args, -1); markers != nil {
+				script, i, err = heredocScript(args, markers, lines, i)
+			} else {
+				script = commandScript(args)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", start, err)
+			}
+
+			runs = append(runs, Run{
+				Stage:   current.name,
+				Line:    start,
+				Workdir: current.workdir,
+				Env:     append([]string(nil), current.env...),
+				Shell:   current.shell,
+				Script:  script,
+			})
+		}
+	}
+	return runs, nil
+}
+
+// newStage starts the build stage declared by the arguments of a FROM
+// instruction. A stage based on an earlier one inherits its state.
+func newStage(args string, stages []*stage) *stage {
+	s := &stage{name: strconv.Itoa(len(stages))}
+	fields := strings.Fields(args)
+	for len(fields) > 0 && strings.HasPrefix(fields[0], "--") {
+		fields = fields[1:]
+	}
+	if len(fields) >= 3 && strings.EqualFold(fields[1], "AS") {
+		s.name = fields[2]
+	}
+	if len(fields) > 0 {
+		for _, base := range stages {
+			if base.name == fields[0] {
+				s.workdir = base.workdir
+				s.env = append([]string(nil), base.env...)
+				s.shell = base.shell
+			}
+		}
+	}
+	return s
+}
+
+// parseEnv applies an ENV instruction in either the KEY=value or the legacy
+// "KEY value" form
+func parseEnv(s *stage, args string) {
+	name, value, _ := strings.Cut(args, " ")
+	if !strings.Contains(name, "=") {
+		s.setEnv(name, quote(strings.TrimSpace(value)))
+		return
+	}
+	for _, word := range splitWords(args) {
+		if name, value, ok := strings.Cut(word, "="); ok {
+			s.setEnv(name, value)
+		}
+	}
+}
+
+// commandScript returns the script of a RUN instruction in shell form, or
+// the quoted command line of one in exec form
+func commandScript(args string) string {
+	if !strings.HasPrefix(args, "[") {
+		return args
+	}
+
+	var argv []string
+	if err := json.Unmarshal([]byte(args), &argv); err != nil {
+		// Not valid JSON, so Docker runs it in a shell as written
+		return args
+	}
+	words := make([]string, len(argv))
+	for i, arg := range argv {
+		words[i] = quote(arg)
+	}
+	return strings.Join(words, " ")
+}
+
+// heredocScript returns the script of a RUN instruction with heredocs,
+// whose bodies follow line i, and the index of the last line consumed. A
+// RUN consisting of a single heredoc runs its body as the script; otherwise
+// the heredocs feed the command, which Bash handles as written.
+func heredocScript(args string, markers [][]string, lines []string, i int) (string, int, error) {
+	var bodies []string
+	for _, marker := range markers {
+		stripTabs, delim := marker[1] == "-", marker[3]
+		var body []string
+		for {
+			i++
+			if i >= len(lines) {
+				return "", i, fmt.Errorf("heredoc %s is not terminated", delim)
+			}
+			line := lines[i]
+			if stripTabs {
+				line = strings.TrimLeft(line, "\t")
+			}
+			if line == delim {
+				break
+			}
+			body = append(body, line)
+		}
+		bodies = append(bodies, strings.Join(body, "\n"))
+	}
+
+	if len(markers) == 1 && strings.TrimSpace(heredocPattern.ReplaceAllString(args, "")) == "" {
+		return bodies[0] + "\n", i, nil
+	}
+
+	var b strings.Builder
+	b.WriteString(args)
+	for n, marker := range markers {
+		fmt.Fprintf(&b, "\n%s\n%s", bodies[n], marker[3])
+	}
+	b.WriteString("\n")
+	return b.String(), i, nil
+}
+
+// splitWords splits s at unquoted whitespace, keeping quotes and escapes in
+// the words
+func splitWords(s string) []string {
+	var words []string
+	var word strings.Builder
+	var inWord bool
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' && i+1 < len(s) {
+				word.WriteByte(c)
+				i++
+				c = s[i]
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '\\' && i+1 < len(s):
+			word.WriteByte(c)
+			i++
+			c = s[i]
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+			continue
+		}
+		word.WriteByte(c)
+		inWord = true
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// quote quotes s as a single Bash word
+func quote(s string) string {
+	quoted, err := syntax.Quote(s, syntax.LangBash)
+	if err != nil {
+		// Only strings with null bytes cannot be quoted
+		return strconv.Quote(s)
+	}
+	return quoted
+}
+
+// unquote removes the quotes around a Dockerfile argument, if any
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,107 @@
+package dockerfile
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestParse tests extracting RUN instructions with their context
+func TestParse(t *testing.T) {
+	dockerfile := `# syntax=docker/dockerfile:1
+FROM golang:1.24 AS build
+WORKDIR /src
+ENV CGO_ENABLED=0 GOFLAGS="-mod=readonly"
+# Install dependencies
+RUN apt-get update && \
+    # comments inside continuations are dropped
+    apt-get install -y git
+RUN --mount=type=cache,target=/root/.cache go build -o /out/app .
+RUN ["echo", "exec form", "$HOME"]
+
+FROM build AS test
+WORKDIR tests
+RUN go test ./...
+`
+	runs, err := Parse(strings.NewReader(dockerfile))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(runs) != 4 {
+		t.Fatalf("Expected 4 RUN instructions, got %d: %+v", len(runs), runs)
+	}
+
+	first := runs[0]
+	if first.Stage != "build" || first.Line != 6 || first.Workdir != "/src" {
+		t.Errorf("Unexpected context for the first RUN: %+v", first)
+	}
+	if first.Script != "apt-get update && \\\n    apt-get install -y git" {
+		t.Errorf("Unexpected continuation handling: %q", first.Script)
+	}
+	if len(first.Env) != 2 || first.Env[1] != `GOFLAGS="-mod=readonly"` {
+		t.Errorf("Unexpected environment: %q", first.Env)
+	}
+
+	if runs[1].Script != "go build -o /out/app ." {
+		t.Errorf("Expected RUN flags to be dropped, got %q", runs[1].Script)
+	}
+	if runs[2].Script != `echo 'exec form' '$HOME'` {
+		t.Errorf("Expected the exec form to be quoted, got %q", runs[2].Script)
+	}
+
+	// The test stage inherits from the build stage
+	last := runs[3]
+	if last.Stage != "test" || last.Workdir != "/src/tests" || len(last.Env) != 2 {
+		t.Errorf("Expected the test stage to inherit from build, got %+v", last)
+	}
+}
+
+// TestParseHeredoc tests RUN instructions with heredocs
+func TestParseHeredoc(t *testing.T) {
+	dockerfile := "FROM alpine\n" +
+		"RUN <<EOF\n" +
+		"set -e\n" +
+		"echo one\n" +
+		"EOF\n" +
+		"RUN cat <<-END > /etc/motd\n" +
+		"\thello\n" +
+		"\tEND\n" +
+		"RUN echo done\n"
+
+	runs, err := Parse(strings.NewReader(dockerfile))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(runs) != 3 {
+		t.Fatalf("Expected 3 RUN instructions, got %d: %+v", len(runs), runs)
+	}
+	if runs[0].Script != "set -e\necho one\n" {
+		t.Errorf("Expected the heredoc body as the script, got %q", runs[0].Script)
+	}
+	if runs[1].Script != "cat <<-END > /etc/motd\nhello\nEND\n" {
+		t.Errorf("Expected the heredoc to feed the command, got %q", runs[1].Script)
+	}
+	if runs[2].Line != 9 {
+		t.Errorf("Expected the last RUN on line 9, got %d", runs[2].Line)
+	}
+
+	if _, err := Parse(strings.NewReader("FROM alpine\nRUN <<EOF\necho\n")); err == nil {
+		t.Error("Expected an error for an unterminated heredoc")
+	}
+}
+
+// TestBuilderScript tests combining RUN instructions into one script
+func TestBuilderScript(t *testing.T) {
+	runs := []Run{
+		{Stage: "0", Line: 2, Script: "echo one"},
+		{Stage: "0", Line: 4, Workdir: "/app", Env: []string{"MODE=prod"}, Script: "make"},
+	}
+	script := BuilderScript(runs)
+	for _, want := range []string{
+		"\n# Step 1: RUN at line 2, stage 0\necho one\n",
+		"\n# Step 2: RUN at line 4, stage 0\ncd /app\nexport MODE=prod\nmake\n",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Expected %q in the builder script, got:\n%s", want, script)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Package fuzz feeds generated and mutated Bash scripts through the whole
+// conversion pipeline, from parsing to Go code generation, to find inputs
+// that crash the transpiler or make it emit invalid Go. It backs both the
+// native Go fuzz targets and the bash2go fuzz command.
+package fuzz
+
+import (
+	"errors"
+	"fmt"
+	"go/format"
+	"go/scanner"
+	"math/rand/v2"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TFMV/bash2go/diagnostics"
+	"github.com/TFMV/bash2go/generator"
+	"github.com/TFMV/bash2go/parser"
+)
+
+// MaxScriptSize bounds the scripts produced by Mutate
+const MaxScriptSize = 4096
+
+// Seeds are small scripts covering the constructs bash2go translates, used
+// when no corpus is given. Every seed passes Check. The failures fuzzing has
+// found are kept in testdata/fuzz/FuzzPipeline, which go test checks too.
+var Seeds = []string{
+	"echo hello\n",
+	"grep -q \"$USER\" /etc/passwd && echo found\n",
+	"NAME=world\necho \"hello $NAME\"\n",
+	"greet() {\n\tlocal who=$1\n\techo \"hi $who\"\n\treturn 0\n}\ngreet me\n",
+	"for i in 1 2 3; do\n\techo $i\ndone\n",
+	"for ((i = 0; i < 3; i++)); do\n\techo $i\ndone\n",
+	"ls -la | grep foo | wc -l > count.txt\n",
+	"(cd /tmp && ls)\n",
+	"sleep 1 &\nwait\n",
+	"mkdir -p out && cp a.txt out/ || echo failed >&2\n",
+	"case $1 in\n\tstart) echo start ;;\n\t*) echo usage; exit 1 ;;\nesac\n",
+	"x=$(date +%s)\necho ${x:-none} ${#x} ${x%%0*}\n",
+	"cat <<EOF\nhello $USER\nEOF\n",
+	"set -euo pipefail\ntrap 'echo bye' EXIT\nexport PATH=\"$PATH:/opt/bin\"\n",
+	"declare -A m\nm[key]=value\necho \"${m[key]}\" $(( 1 + 2 * 3 ))\n",
+}
+
+// tokens are fragments of Bash syntax inserted by Mutate
+var tokens = []string{
+	"\n", ";", " ", "|", "||", "&&", "&", "(", ")", "{", "}", "!", "'", "\"",
+	"`", "$", "$(", "${", "$((", "))", "[[", "]]", "[", "]", "<", ">", ">>",
+	"2>&1", "<<EOF\n", "\nEOF\n", "<<<", "$@", "$#", "$?", "$$", "$0", "$1",
+	"${x:-y}", "${x#*/}", "${x%.*}", "${#x}", "${x:1:2}", "${a[@]}",
+	"x=1", "local y", "export z=2", "declare -a a", "if true; then ",
+	"; fi", "for i in a b; do ", "; done", "while false; do ", "case x in ",
+	"*) ;; ", "esac", "f() { ", "return 1", "exit 2", "echo", "cd", "test",
+	"shift", "getopts ab opt", "read -r v", "trap '' INT", "source x.sh",
+	"*.txt", "~", "\\", "#", "-n", "--",
+}
+
+// Check runs script through parsing, IR building and code generation. It
+// returns an error if the pipeline panics or produces Go code that does not
+// parse or type-check, listing the type errors one per line. Scripts the
+// pipeline rejects with an error are not failures.
+func Check(script string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		return nil
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		return nil
+	}
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	var syntaxErr scanner.ErrorList
+	if errors.As(err, &syntaxErr) {
+		return fmt.Errorf("generated invalid Go code: %v", err)
+	}
+	if err != nil {
+		return nil
+	}
+	if _, err := format.Source([]byte(code)); err != nil {
+		return fmt.Errorf("generated invalid Go code: %v", err)
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		var typeErrors []string
+		for _, d := range ir.Diagnostics.Items() {
+			if d.Code == diagnostics.CodeTypeCheck {
+				typeErrors = append(typeErrors, d.Message)
+			}
+		}
+		return fmt.Errorf("generated invalid Go code: %s", strings.Join(typeErrors, "\n"))
+	}
+	return nil
+}
+
+// Mutate returns a variation of script: fragments of Bash syntax inserted,
+// ranges deleted or duplicated, or lines of other seeds spliced in
+func Mutate(rng *rand.Rand, script string, seeds []string) string {
+	for n := 1 + rng.IntN(4); n > 0; n-- {
+		pos := 0
+		if len(script) > 0 {
+			pos = rng.IntN(len(script) + 1)
+		}
+		switch rng.IntN(4) {
+		case 0:
+			script = script[:pos] + tokens[rng.IntN(len(tokens))] + script[pos:]
+		case 1:
+			if end := pos + rng.IntN(16); end <= len(script) {
+				script = script[:pos] + script[end:]
+			}
+		case 2:
+			if end := pos + rng.IntN(32); end <= len(script) {
+				script = script[:end] + script[pos:end] + script[end:]
+			}
+		case 3:
+			if len(seeds) > 0 {
+				lines := strings.SplitAfter(seeds[rng.IntN(len(seeds))], "\n")
+				script = script[:pos] + lines[rng.IntN(len(lines))] + script[pos:]
+			}
+		}
+	}
+	if len(script) > MaxScriptSize {
+		script = script[:MaxScriptSize]
+	}
+	return script
+}
+
+// positions matches the line:column positions and the generated code lines
+// in error messages, which differ between occurrences of the same bug
+var positions = regexp.MustCompile(`\d+:\d+: |line \d+: `)
+
+// Failure is an input that failed Check
+type Failure struct {
+	Script string
+	Err    error
+}
+
+// Options configures Run
+type Options struct {
+	Seeds    []string      // Scripts to mutate; Seeds if empty
+	Duration time.Duration // How long to run
+	Workers  int           // Scripts checked at once; 1 if zero
+	Seed     uint64        // Seed of the random mutations
+}
+
+// Run checks mutations of the seeds until the duration elapses and returns
+// the failures found, at most one per distinct error message, with the
+// number of scripts checked
+func Run(options Options) ([]Failure, int) {
+	seeds := options.Seeds
+	if len(seeds) == 0 {
+		seeds = Seeds
+	}
+	workers := max(options.Workers, 1)
+	deadline := time.Now().Add(options.Duration)
+
+	var mu sync.Mutex
+	var failures []Failure
+	var checked int
+	seen := make(map[string]bool)
+	report := func(script string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		checked++
+		if err == nil {
+			return
+		}
+		// Failures are told apart by their message, not their stack or
+		// position
+		key, _, _ := strings.Cut(err.Error(), "\n")
+		key = positions.ReplaceAllString(key, "")
+		if !seen[key] {
+			seen[key] = true
+			failures = append(failures, Failure{Script: script, Err: err})
+		}
+	}
+
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rng := rand.New(rand.NewPCG(options.Seed, uint64(w)))
+			for _, seed := range seeds[w*len(seeds)/workers : (w+1)*len(seeds)/workers] {
+				report(seed, Check(seed))
+			}
+			for time.Now().Before(deadline) {
+				script := Mutate(rng, seeds[rng.IntN(len(seeds))], seeds)
+				report(script, Check(script))
+			}
+		}()
+	}
+	wg.Wait()
+	return failures, checked
+}
@@ -0,0 +1,55 @@
+package fuzz
+
+import (
+	"math/rand/v2"
+	"strings"
+	"testing"
+	"time"
+)
+
+// FuzzPipeline checks that no script crashes the transpiler or makes it
+// generate invalid Go
+func FuzzPipeline(f *testing.F) {
+	for _, seed := range Seeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, script string) {
+		if err := Check(script); err != nil {
+			t.Fatalf("%v\nscript:\n%s", err, script)
+		}
+	})
+}
+
+// TestMutate tests that mutations stay within bounds and vary the input
+func TestMutate(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	changed := 0
+	for range 100 {
+		script := Mutate(rng, strings.Repeat(Seeds[1], 300), Seeds)
+		if len(script) > MaxScriptSize {
+			t.Fatalf("Mutation exceeds %d bytes: %d", MaxScriptSize, len(script))
+		}
+		if Mutate(rng, Seeds[1], Seeds) != Seeds[1] {
+			changed++
+		}
+	}
+	if changed < 50 {
+		t.Errorf("Expected most mutations to change the script, got %d of 100", changed)
+	}
+}
+
+// TestRun tests that Run checks the seeds and reports failures once
+func TestRun(t *testing.T) {
+	failures, checked := Run(Options{Duration: 50 * time.Millisecond, Workers: 2, Seed: 1})
+	if checked < len(Seeds) {
+		t.Errorf("Expected at least the %d seeds to be checked, got %d", len(Seeds), checked)
+	}
+	seen := make(map[string]bool)
+	for _, failure := range failures {
+		key, _, _ := strings.Cut(failure.Err.Error(), "\n")
+		if seen[key] {
+			t.Errorf("Failure reported twice: %s", key)
+		}
+		seen[key] = true
+	}
+}
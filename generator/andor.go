@@ -0,0 +1,101 @@
+package generator
+
+import (
+	"fmt"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+// generateAndOr generates Go code for a command list joined with && or ||.
+// The right operand runs only if the left one succeeds, for &&, or fails,
+// for ||; a failure of the right operand fails the list like any command.
+func (g *GoCodeGenerator) generateAndOr(list parser.AndOr) (string, error) {
+	cond, err := g.listCond(list.X)
+	if err != nil {
+		return "", err
+	}
+	if list.Op == "||" {
+		cond = "!(" + cond + ")"
+	}
+	body, err := g.generateStatements(list.Y)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("if %s {\n%s\n}", cond, body), nil
+}
+
+// andOrCond converts a command list joined with && or || into a Go boolean
+// expression that holds if the list succeeds, as in if cmd1 && cmd2
+func (g *GoCodeGenerator) andOrCond(list parser.AndOr) (string, error) {
+	x, err := g.listCond(list.X)
+	if err != nil {
+		return "", err
+	}
+	y, err := g.listCond(list.Y)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("(%s) %s (%s)", x, list.Op, y), nil
+}
+
+// listCond converts the statements of an operand of && or || into a Go
+// boolean expression that holds if they succeed. Tests and arithmetic are
+// plain conditions; other statements run in a function whose error tells
+// whether they failed.
+func (g *GoCodeGenerator) listCond(statements []parser.Statement) (string, error) {
+	if len(statements) == 1 {
+		stmt := statements[0]
+		outer := g.pos
+		g.pos = stmt.Pos
+		defer func() { g.pos = outer }()
+
+		switch stmt.Type {
+		case parser.StatementTest:
+			test := stmt.Value.(parser.TestExpr)
+			return g.testCond(&test), nil
+		case parser.StatementArithmetic:
+			arithmetic := stmt.Value.(parser.Arithmetic)
+			return g.arithCond(&arithmetic), nil
+		case parser.StatementAndOr:
+			return g.andOrCond(stmt.Value.(parser.AndOr))
+		case parser.StatementNegation:
+			cond, err := g.listCond(stmt.Value.(parser.Negation).Statements)
+			return "!(" + cond + ")", err
+		case parser.StatementCommand:
+			if cond, ok := g.bracketTest(stmt.Value.(parser.Command)); ok {
+				return cond, nil
+			}
+		}
+	}
+
+	code, err := g.generateStatements(statements)
+	if err != nil {
+		return "", err
+	}
+
+	// In scripts reading $?, statements such as tests only record their
+	// exit status, which the list fails with if it is not 0
+	if g.usesShellVar("?") {
+		g.RequiredImports["fmt"] = true
+		code = fmt.Sprintf(`%s
+	%s
+	if status := %s; status != "0" {
+		return fmt.Errorf("exit status %%s", status)
+	}`, g.setShellVarCode("?", `"0"`), code, g.shellVarRef("?"))
+	}
+	return fmt.Sprintf("func() error {\n%s\n}() == nil", bodyWithReturn(code)), nil
+}
+
+// generateNegation generates Go code for a negated pipeline, as in ! cmd.
+// Like in Bash, neither the failure nor the success of the pipeline ends the
+// script; only $? records the inverted exit status.
+func (g *GoCodeGenerator) generateNegation(negation parser.Negation) (string, error) {
+	cond, err := g.listCond(negation.Statements)
+	if err != nil {
+		return "", err
+	}
+	if !g.usesShellVar("?") {
+		return "_ = " + cond, nil
+	}
+	return fmt.Sprintf("if %s {\n%s\n} else {\n%s\n}", cond, g.setShellVarCode("?", `"1"`), g.setShellVarCode("?", `"0"`)), nil
+}
@@ -0,0 +1,298 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/TFMV/bash2go/diagnostics"
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	runtimeHelpers["arithValue"] = runtimeHelper{
+		Source: `// arithValue converts the value of a variable into an integer for
+// arithmetic. Like in Bash, empty and non-numeric values count as 0.
+func arithValue(s string) int {
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 0, 0)
+	if err != nil {
+		return 0
+	}
+	return int(n)
+}`,
+		Imports: []string{"strconv", "strings"},
+	}
+	runtimeHelpers["arithSet"] = runtimeHelper{
+		Source: `// arithSet assigns the result of an arithmetic assignment to a variable and
+// returns it, so that assignments can be used inside expressions
+func arithSet(v *string, value int) int {
+	*v = strconv.Itoa(value)
+	return value
+}`,
+		Imports: []string{"strconv"},
+	}
+	runtimeHelpers["boolInt"] = runtimeHelper{
+		Source: `// boolInt converts the result of a comparison into 1 or 0
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}`,
+	}
+	runtimeHelpers["arithDivisor"] = runtimeHelper{
+		Source: `// arithDivisor returns the divisor of a division or remainder. A divisor
+// of 0 ends the program with the located error message msg, instead of a
+// panic.
+func arithDivisor(n int, msg string) int {
+	if n == 0 {
+		fmt.Fprintln(os.Stderr, msg)
+		os.Exit(1)
+	}
+	return n
+}`,
+		Imports: []string{"fmt", "os"},
+	}
+	runtimeHelpers["arithPow"] = runtimeHelper{
+		Source: `// arithPow raises base to a non-negative integer power
+func arithPow(base, exp int) int {
+	result := 1
+	for ; exp > 0; exp-- {
+		result *= base
+	}
+	return result
+}`,
+	}
+}
+
+// arithStatement generates Go code for an arithmetic expression evaluated
+// for its side effects, as in (( i++ )) and let. Assignments to variables
+// become plain Go assignments; other expressions are evaluated and dropped.
+func (g *GoCodeGenerator) arithStatement(a parser.Arithmetic) string {
+	if a.Op == "," {
+		return g.arithStatement(*a.X) + "\n" + g.arithStatement(*a.Y)
+	}
+	if a.IsAssign() {
+		if target, ok := g.arithTarget(a.X); ok {
+			if g.isIntVar(target) {
+				return fmt.Sprintf("%s = %s", target, g.arithAssignValue(a, target))
+			}
+			g.RequiredImports["strconv"] = true
+			return fmt.Sprintf("%s = strconv.Itoa(%s)", target, g.arithAssignValue(a, target))
+		}
+		return "// Unsupported arithmetic assignment"
+	}
+	if g.usesShellVar("?") {
+		// (( expr )) fails if expr is zero
+		g.requireHelper("boolInt")
+		g.RequiredImports["strconv"] = true
+		return g.setShellVarCode("?", fmt.Sprintf("strconv.Itoa(boolInt(!(%s)))", g.arithCond(&a)))
+	}
+	return "_ = " + g.arithExpr(&a)
+}
+
+// arithCond converts an arithmetic expression into a Go boolean expression
+// that holds if the expression is non-zero, as in if (( a < b )).
+func (g *GoCodeGenerator) arithCond(a *parser.Arithmetic) string {
+	switch a.Op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		return fmt.Sprintf("%s %s %s", g.arithOperand(a.X), a.Op, g.arithOperand(a.Y))
+	case "&&", "||":
+		return fmt.Sprintf("%s %s %s", g.arithCondOperand(a.X), a.Op, g.arithCondOperand(a.Y))
+	case "!":
+		return "!" + g.arithCondOperand(a.X)
+	}
+	return g.arithExpr(a) + " != 0"
+}
+
+// arithCondOperand is arithCond for the operand of a logical operator
+func (g *GoCodeGenerator) arithCondOperand(a *parser.Arithmetic) string {
+	if a.Op == "!" {
+		return g.arithCond(a)
+	}
+	return "(" + g.arithCond(a) + ")"
+}
+
+// arithExpr converts an arithmetic expression into a Go int expression
+func (g *GoCodeGenerator) arithExpr(a *parser.Arithmetic) string {
+	switch {
+	case a.Op == "":
+		if !a.IsVar {
+			if n, err := strconv.ParseInt(a.Value, 0, 0); err == nil {
+				return strconv.FormatInt(n, 10)
+			}
+		}
+		if a.IsVar && g.isIntVar(a.Value) && g.isScriptVariable(a.Value) {
+			// Integer variables need no conversion
+			g.readVars[a.Value] = true
+			return a.Value
+		}
+		g.requireHelper("arithValue")
+		if a.IsVar {
+			return fmt.Sprintf("arithValue(%s)", g.varRef(a.Value))
+		}
+		return fmt.Sprintf("arithValue(%s)", g.goArg(a.Value))
+	case a.IsAssign():
+		target, ok := g.arithTarget(a.X)
+		if !ok {
+			return "0"
+		}
+		set := "arithSet"
+		if g.isIntVar(target) {
+			set = "arithSetInt"
+		}
+		g.requireHelper(set)
+		value := fmt.Sprintf("%s(&%s, %s)", set, target, g.arithAssignValue(*a, target))
+		// The postfix forms evaluate to the value before the assignment
+		if a.Post && a.Op == "++" {
+			return value + " - 1"
+		} else if a.Post && a.Op == "--" {
+			return value + " + 1"
+		}
+		return value
+	case a.Op == "?":
+		return fmt.Sprintf("func() int {\nif %s {\nreturn %s\n}\nreturn %s\n}()",
+			g.arithCond(a.X), g.arithExpr(a.Y), g.arithExpr(a.Z))
+	case a.Op == ",":
+		return fmt.Sprintf("func() int {\n_ = %s\nreturn %s\n}()", g.arithExpr(a.X), g.arithExpr(a.Y))
+	case a.Y == nil:
+		switch a.Op {
+		case "!":
+			g.requireHelper("boolInt")
+			return fmt.Sprintf("boolInt(%s == 0)", g.arithExpr(a.X))
+		case "~":
+			return "^" + g.arithOperand(a.X)
+		case "-":
+			return "-" + g.arithOperand(a.X)
+		}
+		return g.arithExpr(a.X)
+	case a.Op == "**":
+		g.requireHelper("arithPow")
+		return fmt.Sprintf("arithPow(%s, %s)", g.arithExpr(a.X), g.arithExpr(a.Y))
+	case a.Op == "&&" || a.Op == "||" || a.IsComparison():
+		g.requireHelper("boolInt")
+		return fmt.Sprintf("boolInt(%s)", g.arithCond(a))
+	case a.Op == "/" || a.Op == "%":
+		return fmt.Sprintf("%s %s %s", g.arithOperand(a.X), a.Op, g.arithDivisor(a.Y))
+	}
+	return fmt.Sprintf("%s %s %s", g.arithOperand(a.X), a.Op, g.arithOperand(a.Y))
+}
+
+// arithDivisor is arithOperand for the divisor of / and %, which is checked
+// for 0 unless it is a non-zero number
+func (g *GoCodeGenerator) arithDivisor(a *parser.Arithmetic) string {
+	divisor := g.arithOperand(a)
+	if n, err := strconv.Atoi(divisor); err == nil && n != 0 {
+		return divisor
+	}
+	msg := "division by 0"
+	if loc := g.location(g.pos); loc != "" {
+		msg = loc + ": " + msg
+	}
+	g.requireHelper("arithDivisor")
+	return fmt.Sprintf("arithDivisor(%s, %s)", divisor, strconv.Quote(msg))
+}
+
+// arithOperand is arithExpr for the operand of an operator. Go's operator
+// precedence differs from Bash's, so nested operations are parenthesized.
+func (g *GoCodeGenerator) arithOperand(a *parser.Arithmetic) string {
+	if isArithOperation(a) {
+		return "(" + g.arithExpr(a) + ")"
+	}
+	return g.arithExpr(a)
+}
+
+// isArithOperation reports whether the Go expression for a is an operation
+// rather than an operand or a call
+func isArithOperation(a *parser.Arithmetic) bool {
+	switch {
+	case a.IsAssign():
+		return a.Post
+	case a.Y == nil:
+		return a.Op == "-" || a.Op == "~"
+	case a.IsComparison():
+		return false
+	}
+	switch a.Op {
+	case "**", "?", ",", "&&", "||":
+		return false
+	}
+	return true
+}
+
+// arithAssignValue returns the Go int expression for the new value of the
+// target of an arithmetic assignment such as x += 2 or x++
+func (g *GoCodeGenerator) arithAssignValue(a parser.Arithmetic, target string) string {
+	current := target
+	if !g.isIntVar(target) {
+		g.requireHelper("arithValue")
+		current = fmt.Sprintf("arithValue(%s)", target)
+	}
+	switch a.Op {
+	case "=":
+		return g.arithExpr(a.Y)
+	case "++":
+		return current + " + 1"
+	case "--":
+		return current + " - 1"
+	case "/=", "%=":
+		return fmt.Sprintf("%s %s %s", current, strings.TrimSuffix(a.Op, "="), g.arithDivisor(a.Y))
+	}
+	return fmt.Sprintf("%s %s %s", current, strings.TrimSuffix(a.Op, "="), g.arithOperand(a.Y))
+}
+
+// arithTarget returns the Go variable an arithmetic assignment assigns to.
+// Only script variables can be assigned; other targets are reported as
+// unsupported.
+func (g *GoCodeGenerator) arithTarget(x *parser.Arithmetic) (string, bool) {
+	if x != nil && x.IsVar && g.isConstant(x.Value) {
+		g.unsupported++
+		g.IR.Diagnose(diagnostics.SeverityError, g.pos, diagnostics.CodeUnsupported,
+			"arithmetic assignment to read-only variable %s", x.Value)
+		return "", false
+	}
+	if x != nil && x.IsVar && g.isScriptVariable(x.Value) && !g.isAssocArray(x.Value) && !dynamicShellVars[x.Value] {
+		g.readVars[x.Value] = true
+		return x.Value, true
+	}
+	g.unsupported++
+	target := ""
+	if x != nil {
+		target = x.Value
+	}
+	g.IR.Diagnose(diagnostics.SeverityError, g.pos, diagnostics.CodeUnsupported,
+		"arithmetic assignment to %q is not supported; only script variables can be assigned", target)
+	return "", false
+}
+
+// arithExpansion converts the text between $(( and )) into a Go string
+// expression for the result of the expansion. It reports false if the text
+// is not a valid arithmetic expression.
+func (g *GoCodeGenerator) arithExpansion(expr string) (string, bool) {
+	a, err := parser.ParseArithmetic(expr)
+	if err != nil {
+		return "", false
+	}
+	g.RequiredImports["strconv"] = true
+	return fmt.Sprintf("strconv.Itoa(%s)", g.arithExpr(a)), true
+}
+
+// closingArithm returns the index just past the )) closing the $(( at the
+// start of s, or -1 if there is none
+func closingArithm(s string) int {
+	depth := 0
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				if s[i-1] != ')' {
+					return -1
+				}
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,159 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TFMV/bash2go/diagnostics"
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	runtimeHelpers["sortedKeys"] = runtimeHelper{
+		Source: `// sortedKeys returns the keys of an associative array in sorted order, so
+// that iterating over them is deterministic
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}`,
+		Imports: []string{"sort"},
+	}
+	runtimeHelpers["sortedValues"] = runtimeHelper{
+		Source: `// sortedValues returns the values of an associative array in the order of
+// their sorted keys
+func sortedValues(m map[string]string) []string {
+	keys := sortedKeys(m)
+	values := make([]string, len(keys))
+	for i, key := range keys {
+		values[i] = m[key]
+	}
+	return values
+}`,
+		Requires: []string{"sortedKeys"},
+	}
+}
+
+// isAssocArray reports whether name is declared as an associative array
+// anywhere in the script. Associative arrays are Go maps of type
+// map[string]string.
+func (g *GoCodeGenerator) isAssocArray(name string) bool {
+	return g.IR.AssocArrays[name] && !g.isTableVar(name)
+}
+
+// generateArrayAssignment generates Go code for assignments to associative
+// arrays: declarations, compound assignments and element assignments
+func (g *GoCodeGenerator) generateArrayAssignment(assign parser.Assignment) (string, error) {
+	if assign.Key != "" {
+		op := "="
+		if assign.Append {
+			op = "+="
+		}
+		return fmt.Sprintf("%s[%s] %s %s", assign.Name, g.goArg(assign.Key), op, g.goArg(assign.Value)), nil
+	}
+
+	// A declaration without elements, as in declare -A name, starts an
+	// empty array
+	elements := make([]string, 0, len(assign.Elements))
+	for _, element := range assign.Elements {
+		if element.Key == "" {
+			g.unsupported++
+			g.IR.Diagnose(diagnostics.SeverityError, g.pos, diagnostics.CodeUnsupported,
+				"associative array element without a [key]= subscript")
+			continue
+		}
+		elements = append(elements, fmt.Sprintf("%s: %s", g.goArg(element.Key), g.goArg(element.Value)))
+	}
+	if assign.Append {
+		// name+=([key]=value) adds the elements to the array
+		g.RequiredImports["maps"] = true
+		return fmt.Sprintf("maps.Copy(%s, map[string]string{%s})", assign.Name, strings.Join(elements, ", ")), nil
+	}
+	return fmt.Sprintf("%s = map[string]string{%s}", assign.Name, strings.Join(elements, ", ")), nil
+}
+
+// arrayExpansion converts the inside of a ${...} expansion of an associative
+// array into a Go string expression: ${name[key]} is the element, ${name[@]}
+// the values and ${!name[@]} the keys, joined with spaces, and ${#name[@]}
+// the number of elements. It reports false for other expansions.
+func (g *GoCodeGenerator) arrayExpansion(expr string) (string, bool) {
+	if code, ok := g.pipestatusExpansion(expr); ok {
+		return code, true
+	}
+	if code, ok := g.rematchExpansion(expr); ok {
+		return code, true
+	}
+	if list, ok := g.arrayList(expr); ok {
+		g.RequiredImports["strings"] = true
+		return fmt.Sprintf(`strings.Join(%s, " ")`, list), true
+	}
+
+	if name, ok := strings.CutPrefix(expr, "#"); ok {
+		name, key, ok := cutSubscript(name)
+		if !ok || !g.isAssocArray(name) || (key != "@" && key != "*") {
+			return "", false
+		}
+		g.RequiredImports["strconv"] = true
+		return fmt.Sprintf("strconv.Itoa(len(%s))", name), true
+	}
+
+	name, key, ok := cutSubscript(expr)
+	if !ok || !g.isAssocArray(name) {
+		return "", false
+	}
+	return fmt.Sprintf("%s[%s]", name, g.goArg(unquoteKey(key))), true
+}
+
+// arrayList converts ${name[@]} and ${!name[@]}, without the braces, into a
+// Go expression for the list of values or keys of an associative array
+func (g *GoCodeGenerator) arrayList(expr string) (string, bool) {
+	if list, ok := g.pipestatusList(expr); ok {
+		return list, true
+	}
+	if list, ok := g.rematchList(expr); ok {
+		return list, true
+	}
+	helper := "sortedValues"
+	if name, ok := strings.CutPrefix(expr, "!"); ok {
+		helper, expr = "sortedKeys", name
+	}
+	name, key, ok := cutSubscript(expr)
+	if !ok || !g.isAssocArray(name) || (key != "@" && key != "*") {
+		return "", false
+	}
+	g.requireHelper(helper)
+	return fmt.Sprintf("%s(%s)", helper, name), true
+}
+
+// cutSubscript splits name[key] into name and key
+func cutSubscript(expr string) (name, key string, ok bool) {
+	open := strings.IndexByte(expr, '[')
+	if open <= 0 || !strings.HasSuffix(expr, "]") || !isValidVarName(expr[:open]) {
+		return "", "", false
+	}
+	return expr[:open], expr[open+1 : len(expr)-1], true
+}
+
+// unquoteKey removes the quotes around a quoted key such as ["a b"]
+func unquoteKey(key string) string {
+	if len(key) >= 2 && (key[0] == '"' || key[0] == '\'') && key[len(key)-1] == key[0] {
+		return key[1 : len(key)-1]
+	}
+	return key
+}
+
+// loopItems returns a Go expression for the list a for loop iterates over.
+// A single ${name[@]} or ${!name[@]} ranges over the associative array;
+// other words are expanded and split on whitespace.
+func (g *GoCodeGenerator) loopItems(items string) string {
+	if strings.HasPrefix(items, "${") && strings.HasSuffix(items, "}") {
+		if list, ok := g.arrayList(items[2 : len(items)-1]); ok {
+			return list
+		}
+	}
+	g.RequiredImports["strings"] = true
+	return fmt.Sprintf("strings.Fields(%s)", g.goArg(items))
+}
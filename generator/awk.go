@@ -0,0 +1,434 @@
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	utilities["awk"] = (*GoCodeGenerator).generateAwk
+
+	runtimeHelpers["awk"] = runtimeHelper{
+		Source: `// awkRecord is the current input line of an awk program translated to Go
+type awkRecord struct {
+	line   string
+	fields []string
+	nr     int
+}
+
+// awkFieldIndex is a negative field index, which ends an awk program
+type awkFieldIndex int
+
+// field returns the field i of the record, the whole line for 0 and "" past
+// the last field
+func (r *awkRecord) field(i int) string {
+	switch {
+	case i < 0:
+		panic(awkFieldIndex(i))
+	case i == 0:
+		return r.line
+	case i > len(r.fields):
+		return ""
+	}
+	return r.fields[i-1]
+}
+
+// awk runs an awk program on each line of files, or of stdin without files,
+// splitting it into fields at sep: blanks for " ", a single character, or a
+// regular expression. The program returns the line to print, if any.
+func awk(stdio *streams, stdin io.Reader, sep string, program func(r *awkRecord) (string, bool), files ...string) (err error) {
+	split := strings.Fields
+	switch {
+	case len(sep) == 1 && sep != " ":
+		split = func(line string) []string {
+			if line == "" {
+				return nil
+			}
+			return strings.Split(line, sep)
+		}
+	case len(sep) > 1:
+		re := regexp.MustCompile(sep)
+		split = func(line string) []string {
+			if line == "" {
+				return nil
+			}
+			return re.Split(line, -1)
+		}
+	}
+
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	out := bufio.NewWriter(stdio.Stdout)
+	defer out.Flush()
+	r := &awkRecord{}
+	defer func() {
+		if e := recover(); e != nil {
+			index, ok := e.(awkFieldIndex)
+			if !ok {
+				panic(e)
+			}
+			out.Flush()
+			fmt.Fprintf(stdio.Stderr, "awk: run time error: negative field index $%d\n", int(index))
+			err = exitError(2)
+		}
+	}()
+	for _, name := range files {
+		var input io.Reader = stdin
+		if name != "-" {
+			f, err := os.Open(name)
+			if err != nil {
+				out.Flush()
+				fmt.Fprintf(stdio.Stderr, "awk: cannot open %s (%s)\n", name, utilityMessage(err))
+				return exitError(2)
+			}
+			defer f.Close()
+			input = f
+		}
+		lines := bufio.NewReader(input)
+		for {
+			line, err := lines.ReadString('\n')
+			if line != "" {
+				r.nr++
+				r.line = strings.TrimSuffix(line, "\n")
+				r.fields = split(r.line)
+				if text, ok := program(r); ok {
+					out.WriteString(text + "\n")
+				}
+			}
+			if err != nil {
+				break
+			}
+		}
+	}
+	return nil
+}`,
+		Imports:  []string{"bufio", "fmt", "io", "os", "regexp", "strings"},
+		Requires: []string{"exitError", "utilityError"},
+	}
+	runtimeHelpers["awkCompare"] = runtimeHelper{
+		Source: `// awkCompare compares two values of an awk program, as numbers if both look
+// like numbers and otherwise as strings
+func awkCompare(a, b string) int {
+	x, errA := strconv.ParseFloat(strings.TrimSpace(a), 64)
+	y, errB := strconv.ParseFloat(strings.TrimSpace(b), 64)
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+	return cmp.Compare(x, y)
+}`,
+		Imports: []string{"cmp", "strconv", "strings"},
+	}
+	runtimeHelpers["awkMatch"] = runtimeHelper{
+		Source: `// awkRegexps caches the compiled regular expressions of awk programs
+var awkRegexps struct {
+	sync.Mutex
+	compiled map[string]*regexp.Regexp
+}
+
+// awkMatch reports whether s matches the regular expression of an awk
+// program, which is valid Go syntax
+func awkMatch(pattern, s string) bool {
+	awkRegexps.Lock()
+	re := awkRegexps.compiled[pattern]
+	if re == nil {
+		if awkRegexps.compiled == nil {
+			awkRegexps.compiled = make(map[string]*regexp.Regexp)
+		}
+		re = regexp.MustCompile(pattern)
+		awkRegexps.compiled[pattern] = re
+	}
+	awkRegexps.Unlock()
+	return re.MatchString(s)
+}`,
+		Imports: []string{"regexp", "sync"},
+	}
+}
+
+// generateAwk translates awk one-liners made of a pattern, a print action or
+// both, such as '{print $2}' and '$3 > 10', into a call of the awk helper,
+// with the -F option. It reports false for other programs and options, so
+// that awk is executed.
+func (g *GoCodeGenerator) generateAwk(cmd parser.Command) (string, bool) {
+	options, operands, ok := g.utilityArgs(cmd, "", "F")
+	if !ok || len(operands) == 0 || g.expandsArg(cmd, operands[0]) {
+		return "", false
+	}
+	sep := " "
+	for _, option := range options {
+		value, err := strconv.Unquote(option.value)
+		if err != nil || value == "" || value == "t" {
+			return "", false
+		}
+		sep = awkEscapes(value)
+		if len(sep) > 1 {
+			if _, err := regexp.Compile(sep); err != nil {
+				return "", false
+			}
+		}
+	}
+	src, err := strconv.Unquote(g.goArg(cmd.Args[operands[0]]))
+	if err != nil {
+		// The program is only known at runtime
+		return "", false
+	}
+	for _, i := range operands[1:] {
+		// Operands assigning variables are not files
+		if strings.Contains(cmd.Args[i], "=") {
+			return "", false
+		}
+	}
+
+	p := &awkParser{src: src}
+	program, ok := p.program()
+	if !ok {
+		return "", false
+	}
+	for _, helper := range p.helpers {
+		g.requireHelper(helper)
+	}
+	for _, imp := range p.imports {
+		g.RequiredImports[imp] = true
+	}
+	g.requireHelper("awk")
+	call := fmt.Sprintf("awk(%s, %s, %s, %s%s)", g.stdio(), g.utilityInput(cmd), strconv.Quote(sep), program, g.operandArgs(cmd, operands[1:]))
+	return g.checkErr(cmd, call), true
+}
+
+// awkEscapes replaces the escape sequences of an awk string
+func awkEscapes(s string) string {
+	return strings.NewReplacer(`\t`, "\t", `\n`, "\n", `\"`, `"`, `\/`, "/", `\\`, `\`).Replace(s)
+}
+
+// awkParser parses the awk one-liners translated into Go, recording the
+// helpers and imports their Go code needs
+type awkParser struct {
+	src     string
+	pos     int
+	helpers []string
+	imports []string
+}
+
+// program returns a Go function literal for the program, which returns the
+// line the action prints and whether the pattern matches
+func (p *awkParser) program() (string, bool) {
+	cond, output := "true", "r.line"
+	if !p.peek("{") {
+		var ok bool
+		if cond, ok = p.pattern(); !ok {
+			return "", false
+		}
+	}
+	if p.eat("{") {
+		if !p.eat("print") || p.pos < len(p.src) && isAwkName(p.src[p.pos]) {
+			return "", false
+		}
+		var ok bool
+		if output, ok = p.printList(); !ok {
+			return "", false
+		}
+		p.eat(";")
+		if !p.eat("}") {
+			return "", false
+		}
+	}
+	p.space()
+	if p.pos < len(p.src) {
+		return "", false
+	}
+	return fmt.Sprintf("func(r *awkRecord) (string, bool) {\nreturn %s, %s\n}", output, cond), true
+}
+
+// pattern parses a pattern: a regular expression matching the line, a
+// field matching or not matching one, a comparison, or NF, true for lines
+// with fields, any of them negated with !
+func (p *awkParser) pattern() (string, bool) {
+	if p.eat("!") {
+		cond, ok := p.pattern()
+		return "!(" + cond + ")", ok
+	}
+	if p.peek("/") {
+		re, ok := p.regexp()
+		return fmt.Sprintf("awkMatch(%s, r.line)", re), ok
+	}
+
+	left, leftString, ok := p.term()
+	if !ok {
+		return "", false
+	}
+	for _, op := range []string{"!~", "~", ">=", "<=", "==", "!=", ">", "<"} {
+		if !p.eat(op) {
+			continue
+		}
+		if strings.HasSuffix(op, "~") {
+			re, ok := p.regexp()
+			cond := fmt.Sprintf("awkMatch(%s, %s)", re, left)
+			if op == "!~" {
+				cond = "!" + cond
+			}
+			return cond, ok
+		}
+		right, rightString, ok := p.term()
+		if leftString || rightString {
+			// Comparisons with strings compare strings
+			p.imports = append(p.imports, "strings")
+			return fmt.Sprintf("strings.Compare(%s, %s) %s 0", left, right, op), ok
+		}
+		p.helpers = append(p.helpers, "awkCompare")
+		return fmt.Sprintf("awkCompare(%s, %s) %s 0", left, right, op), ok
+	}
+	if left == "strconv.Itoa(len(r.fields))" {
+		p.imports = p.imports[:len(p.imports)-1]
+		return "len(r.fields) > 0", true
+	}
+	return "", false
+}
+
+// printList parses the expressions print prints, separated by spaces
+func (p *awkParser) printList() (string, bool) {
+	if p.peek(";") || p.peek("}") {
+		return "r.line", true
+	}
+	var items []string
+	for {
+		// Juxtaposed terms are concatenated
+		var terms []string
+		for !p.peek(",") && !p.peek(";") && !p.peek("}") && p.pos < len(p.src) {
+			term, _, ok := p.term()
+			if !ok {
+				return "", false
+			}
+			terms = append(terms, term)
+		}
+		if len(terms) == 0 {
+			return "", false
+		}
+		items = append(items, strings.Join(terms, " + "))
+		if !p.eat(",") {
+			return strings.Join(items, ` + " " + `), true
+		}
+	}
+}
+
+// term parses a field ($1, $NF or $(NF-1)), NF, NR, a string or an integer
+// into a Go string expression, reporting whether it is a string constant
+func (p *awkParser) term() (string, bool, bool) {
+	p.space()
+	rest := p.src[p.pos:]
+	switch {
+	case strings.HasPrefix(rest, "$NF"):
+		p.pos += len("$NF")
+		return "r.field(len(r.fields))", false, true
+	case strings.HasPrefix(rest, "$(NF-"):
+		p.pos += len("$(NF-")
+		n, ok := p.integer()
+		if !ok || !p.eat(")") {
+			return "", false, false
+		}
+		return fmt.Sprintf("r.field(len(r.fields)-%d)", n), false, true
+	case strings.HasPrefix(rest, "$"):
+		p.pos++
+		n, ok := p.integer()
+		if !ok {
+			return "", false, false
+		}
+		if n == 0 {
+			return "r.line", false, true
+		}
+		return fmt.Sprintf("r.field(%d)", n), false, true
+	case p.name("NF"):
+		p.imports = append(p.imports, "strconv")
+		return "strconv.Itoa(len(r.fields))", false, true
+	case p.name("NR"):
+		p.imports = append(p.imports, "strconv")
+		return "strconv.Itoa(r.nr)", false, true
+	case strings.HasPrefix(rest, `"`):
+		for i := 1; i < len(rest); i++ {
+			switch rest[i] {
+			case '\\':
+				i++
+			case '"':
+				p.pos += i + 1
+				return strconv.Quote(awkEscapes(rest[1:i])), true, true
+			}
+		}
+		return "", false, false
+	}
+	n, ok := p.integer()
+	return strconv.Quote(strconv.Itoa(n)), false, ok
+}
+
+// regexp parses a regular expression literal into a Go string expression,
+// reporting false if Go does not support it
+func (p *awkParser) regexp() (string, bool) {
+	if !p.eat("/") {
+		return "", false
+	}
+	for i := p.pos; i < len(p.src); i++ {
+		switch p.src[i] {
+		case '\\':
+			i++
+		case '/':
+			pattern := strings.ReplaceAll(p.src[p.pos:i], `\/`, "/")
+			p.pos = i + 1
+			if _, err := regexp.Compile(pattern); err != nil {
+				return "", false
+			}
+			p.helpers = append(p.helpers, "awkMatch")
+			return strconv.Quote(pattern), true
+		}
+	}
+	return "", false
+}
+
+// integer parses a decimal integer
+func (p *awkParser) integer() (int, bool) {
+	p.space()
+	start := p.pos
+	for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+		p.pos++
+	}
+	n, err := strconv.Atoi(p.src[start:p.pos])
+	return n, err == nil
+}
+
+// name consumes the variable name if it comes next
+func (p *awkParser) name(name string) bool {
+	rest := p.src[p.pos:]
+	if !strings.HasPrefix(rest, name) || len(rest) > len(name) && isAwkName(rest[len(name)]) {
+		return false
+	}
+	p.pos += len(name)
+	return true
+}
+
+// isAwkName reports whether c can be part of a name
+func isAwkName(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// space skips blanks
+func (p *awkParser) space() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// peek reports whether s comes next, after blanks
+func (p *awkParser) peek(s string) bool {
+	p.space()
+	return strings.HasPrefix(p.src[p.pos:], s)
+}
+
+// eat consumes s if it comes next, after blanks
+func (p *awkParser) eat(s string) bool {
+	if !p.peek(s) {
+		return false
+	}
+	p.pos += len(s)
+	return true
+}
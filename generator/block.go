@@ -0,0 +1,19 @@
+package generator
+
+import "github.com/TFMV/bash2go/parser"
+
+// generateBlock generates an inline Go block for a brace group. Its
+// redirections replace the standard streams in the block, so every command
+// of the group reads or writes the redirected files.
+func (g *GoCodeGenerator) generateBlock(block parser.Block) (string, error) {
+	body, err := g.generateStatements(block.Statements)
+	if err != nil {
+		return "", err
+	}
+
+	scope := newCleanupScope()
+	scope.add("// Command group")
+	g.addRedirects(scope, block.Redirects, standardStreams, "a command group")
+	scope.add(body)
+	return scope.String(), nil
+}
@@ -0,0 +1,89 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// infoFlag is the command-line flag that makes programs generated with
+// Options.BuildInfo print their build metadata
+const infoFlag = "--bash2go-info"
+
+func init() {
+	runtimeHelpers["buildInfo"] = runtimeHelper{
+		Source: `// Build metadata, set with -ldflags -X when bash2go builds the program
+var (
+	bash2goScript    string
+	bash2goConverted string
+	bash2goVersion   string
+	bash2goHash      string
+)
+
+// printBuildInfo prints the build metadata of the program
+func printBuildInfo() {
+	fmt.Printf("script:    %s\n", bash2goScript)
+	fmt.Printf("converted: %s\n", bash2goConverted)
+	fmt.Printf("bash2go:   %s\n", bash2goVersion)
+	fmt.Printf("sha256:    %s\n", bash2goHash)
+}`,
+		Imports: []string{"fmt"},
+	}
+}
+
+// BuildInfo is the metadata stamped into programs generated with
+// Options.BuildInfo
+type BuildInfo struct {
+	Script    string // Name of the source script
+	Converted string // Conversion time in RFC 3339 format
+	Version   string // Version of bash2go
+	Hash      string // Hex-encoded SHA-256 of the source script
+}
+
+// LDFlags returns the linker flags that set the metadata in the program
+func (info BuildInfo) LDFlags() string {
+	vars := []struct{ name, value string }{
+		{"bash2goScript", info.Script},
+		{"bash2goConverted", info.Converted},
+		{"bash2goVersion", info.Version},
+		{"bash2goHash", info.Hash},
+	}
+
+	var flags []string
+	for _, v := range vars {
+		// Quote values so names with spaces survive flag splitting
+		flags = append(flags, fmt.Sprintf("-X 'main.%s=%s'", v.name, strings.ReplaceAll(v.value, "'", "")))
+	}
+	return strings.Join(flags, " ")
+}
+
+// GoSource returns a Go file for package main that sets the metadata when
+// the program starts. It serves builds where several programs share one
+// linker invocation and so cannot be told apart by LDFlags.
+func (info BuildInfo) GoSource() string {
+	return fmt.Sprintf(`// Code generated by bash2go. DO NOT EDIT.
+
+package main
+
+func init() {
+	bash2goScript = %q
+	bash2goConverted = %q
+	bash2goVersion = %q
+	bash2goHash = %q
+}
+`, info.Script, info.Converted, info.Version, info.Hash)
+}
+
+// buildInfoPrologue returns the statements at the start of main that print
+// the build metadata when the program is run with infoFlag
+func (g *GoCodeGenerator) buildInfoPrologue() []string {
+	if !g.Options.BuildInfo {
+		return nil
+	}
+	g.requireHelper("buildInfo")
+	return []string{
+		fmt.Sprintf("if len(os.Args) == 2 && os.Args[1] == %q {", infoFlag),
+		"\tprintBuildInfo()",
+		"\treturn",
+		"}",
+	}
+}
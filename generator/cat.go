@@ -0,0 +1,58 @@
+package generator
+
+import (
+	"fmt"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	utilities["cat"] = (*GoCodeGenerator).generateCat
+
+	runtimeHelpers["cat"] = runtimeHelper{
+		Source: `// cat copies files, or stdin without files, to the standard output, like
+// cat. It fails with status 1 if a file cannot be read, and with the status
+// of cat killed by SIGPIPE if the output is a pipe closed by its reader.
+func cat(stdio *streams, stdin io.Reader, files ...string) error {
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	failed := false
+	for _, name := range files {
+		input, ok := openInput(stdio, "cat", name, stdin)
+		if !ok {
+			failed = true
+			continue
+		}
+		_, err := io.Copy(stdio.Stdout, input)
+		input.Close()
+		if errors.Is(err, syscall.EPIPE) {
+			return exitError(141)
+		}
+		if err != nil {
+			utilityError(stdio, "cat", name, err)
+			failed = true
+		}
+	}
+	if failed {
+		return exitError(1)
+	}
+	return nil
+}`,
+		Imports:  []string{"errors", "io", "syscall"},
+		Requires: []string{"exitError", "openInput"},
+	}
+}
+
+// generateCat translates cat without options, copying its files or its
+// standard input, such as a here-document, into a call of the cat helper.
+// It reports false for options, such as -n, so that cat is executed.
+func (g *GoCodeGenerator) generateCat(cmd parser.Command) (string, bool) {
+	options, operands, ok := g.utilityArgs(cmd, "", "")
+	if !ok || len(options) > 0 {
+		return "", false
+	}
+	g.requireHelper("cat")
+	call := fmt.Sprintf("cat(%s, %s%s)", g.stdio(), g.utilityInput(cmd), g.operandArgs(cmd, operands))
+	return g.checkErr(cmd, call), true
+}
@@ -0,0 +1,66 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// cleanupScope collects generated statements that acquire resources such as
+// files, pipes, temporary directories, and processes, pairing each with a
+// deferred release. The statements are wrapped in a function literal so the
+// deferred calls run as soon as the translated Bash statement finishes, also
+// inside loops and on early error returns, rather than when run returns.
+type cleanupScope struct {
+	lines     []string
+	resources int
+}
+
+// newCleanupScope starts an empty cleanup scope.
+func newCleanupScope() *cleanupScope {
+	return &cleanupScope{}
+}
+
+// acquire adds code that acquires a resource, followed by a deferred call to
+// release it. The code must return from the scope if the acquisition fails.
+func (s *cleanupScope) acquire(code, release string) {
+	s.lines = append(s.lines, code, "defer "+release)
+	s.resources++
+}
+
+// release adds a deferred call that releases the resources the code added
+// after it acquires as it runs, such as the temporary files of process
+// substitutions.
+func (s *cleanupScope) release(call string) {
+	s.lines = append(s.lines, "defer "+call)
+	s.resources++
+}
+
+// add adds code that uses the acquired resources.
+func (s *cleanupScope) add(code string) {
+	s.lines = append(s.lines, code)
+}
+
+// String returns the Go code for the scope. Errors returned inside the scope
+// are passed on to the enclosing function.
+func (s *cleanupScope) String() string {
+	body := strings.Join(s.lines, "\n")
+	if s.resources == 0 {
+		return "{\n" + body + "\n}"
+	}
+	return fmt.Sprintf(`if err := func() error {
+		%s
+	}(); err != nil {
+		return err
+	}`, bodyWithReturn(body))
+}
+
+// openFile returns code that opens a file through the open call, which must
+// return (*os.File, error), storing it in name and reporting failures with
+// the source location of the statement being generated.
+func (g *GoCodeGenerator) openFile(name, open, what string) string {
+	g.RequiredImports["os"] = true
+	return fmt.Sprintf(`%s, err := %s
+	if err != nil {
+		%s
+	}`, name, open, g.errReturnAt(g.pos, what))
+}
@@ -0,0 +1,82 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	runtimeHelpers["setEnv"] = runtimeHelper{
+		Source: `// setEnv sets an environment variable for a command prefixed with an
+// assignment, as in NAME=value cmd, and returns the function restoring it
+// once the command has run
+func setEnv(name, value string) func() {
+	old, ok := os.LookupEnv(name)
+	os.Setenv(name, value)
+	return func() {
+		if ok {
+			os.Setenv(name, old)
+		} else {
+			os.Unsetenv(name)
+		}
+	}
+}`,
+		Imports: []string{"os"},
+	}
+}
+
+// execEnv returns the code giving the exec.Cmd named cmd the environment of
+// the program with the prefix assignments of command, or "" if it has none.
+// It records that the assignments are applied.
+func (g *GoCodeGenerator) execEnv(command parser.Command) string {
+	if len(command.Env) == 0 {
+		return ""
+	}
+	g.cmdEnvApplied = true
+	g.RequiredImports["os"] = true
+	pairs := make([]string, len(command.Env))
+	for i, assign := range command.Env {
+		pairs[i] = strconv.Quote(assign.Name+"=") + " + " + g.goArg(assign.Value)
+	}
+	return fmt.Sprintf("\ncmd.Env = append(os.Environ(), %s)", strings.Join(pairs, ", "))
+}
+
+// withPrefixEnv wraps the Go code of a command run in the program, such as a
+// builtin or a function, with its prefix assignments. Like in Bash they only
+// apply while it runs: script variables get their value back afterwards, as
+// do environment variables. read takes IFS from the assignments itself.
+func (g *GoCodeGenerator) withPrefixEnv(cmd parser.Command, code string) string {
+	var set, restore []string
+	for i, assign := range cmd.Env {
+		if assign.Name == "IFS" && cmd.Name == "read" {
+			continue
+		}
+		value := g.goArg(assign.Value)
+		if g.isScriptVariable(assign.Name) && !g.isIntVar(assign.Name) && !g.isConstant(assign.Name) && !g.isAssocArray(assign.Name) {
+			saved := fmt.Sprintf("saved%d", i)
+			set = append(set, fmt.Sprintf("%s := %s\n%s = %s", saved, assign.Name, assign.Name, value))
+			restore = append(restore, fmt.Sprintf("%s = %s", assign.Name, saved))
+			continue
+		}
+		if g.isTableVar(assign.Name) {
+			saved := fmt.Sprintf("saved%d", i)
+			set = append(set, fmt.Sprintf("%s := %s\n%s", saved, g.shellVarRef(assign.Name), g.setShellVarCode(assign.Name, value)))
+			restore = append(restore, g.setShellVarCode(assign.Name, saved))
+			continue
+		}
+		g.requireHelper("setEnv")
+		set = append(set, fmt.Sprintf("restore%d := setEnv(%s, %s)", i, strconv.Quote(assign.Name), value))
+		restore = append(restore, fmt.Sprintf("restore%d()", i))
+	}
+	if len(set) == 0 {
+		return code
+	}
+	// Restored in reverse, so that repeated names get their first value back
+	for i, j := 0, len(restore)-1; i < j; i, j = i+1, j-1 {
+		restore[i], restore[j] = restore[j], restore[i]
+	}
+	return fmt.Sprintf("{\n%s\n%s\n%s\n}", strings.Join(set, "\n"), code, strings.Join(restore, "\n"))
+}
@@ -0,0 +1,198 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	runtimeHelpers["captureOutput"] = runtimeHelper{
+		Source: `// captureOutput runs the commands of a command substitution in a subshell
+// with the standard streams of stdio, their standard output captured, and
+// returns the output without its trailing newlines. Like in Bash, a failing command does
+// not abort the script; the substitution expands to the output up to the
+// failure. Ending with exit or return is not a failure to report.
+func captureOutput(stdio *streams, commands func(stdio *streams) error) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		fmt.Fprintln(stdio.Stderr, err)
+		return ""
+	}
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		r.Close()
+		output <- string(data)
+	}()
+
+	captured := stdio.redirected()
+	captured.Stdout = w
+	err = subshell(func() error { return commands(captured) })
+	w.Close()
+	var exit exitError
+	if err != nil && !errors.As(err, &exit) {
+		fmt.Fprintln(stdio.Stderr, err)
+	}
+	return strings.TrimRight(<-output, "\n")
+}`,
+		Imports:  []string{"errors", "fmt", "io", "os", "strings"},
+		Requires: []string{"exitError", "streams", "subshell"},
+	}
+	runtimeHelpers["processSubstitution"] = runtimeHelper{
+		Source: `// processSubstFiles lists the temporary files holding the output of
+// process substitutions, in the order they were created
+var processSubstFiles []string
+
+// processSubstitution runs the commands of a process substitution <(...)
+// in a subshell with the standard streams of stdio, their standard output
+// going to a temporary file, and returns the name of the file for the
+// command the substitution is an argument of to read. Unlike in Bash, the commands
+// finish before that command starts.
+func processSubstitution(stdio *streams, commands func(stdio *streams) error) string {
+	file, err := os.CreateTemp("", "bash2go-")
+	if err != nil {
+		fmt.Fprintln(stdio.Stderr, err)
+		return os.DevNull
+	}
+	processSubstFiles = append(processSubstFiles, file.Name())
+
+	output := stdio.redirected()
+	output.Stdout = file
+	err = subshell(func() error { return commands(output) })
+	file.Close()
+	if err != nil {
+		fmt.Fprintln(stdio.Stderr, err)
+	}
+	return file.Name()
+}
+
+// removeProcessSubstitutions removes the temporary files of the process
+// substitutions created since there were n of them
+func removeProcessSubstitutions(n int) {
+	for _, name := range processSubstFiles[n:] {
+		os.Remove(name)
+	}
+	processSubstFiles = processSubstFiles[:n]
+}`,
+		Imports:  []string{"fmt", "os"},
+		Requires: []string{"streams", "subshell"},
+	}
+}
+
+// commandSubstitution converts the commands of a $(...) command substitution
+// into a Go string expression for their output. It reports false if the
+// commands cannot be translated.
+func (g *GoCodeGenerator) commandSubstitution(script string) (string, bool) {
+	commands, ok := g.substitutionCommands(script)
+	if !ok {
+		return "", false
+	}
+	g.requireHelper("captureOutput")
+	return fmt.Sprintf("captureOutput(%s, %s)", g.stdio(), commands), true
+}
+
+// processSubstitution converts the commands of a <(...) process substitution
+// into a Go string expression for the name of a file holding their output.
+// It reports false if the commands cannot be translated.
+func (g *GoCodeGenerator) processSubstitution(script string) (string, bool) {
+	commands, ok := g.substitutionCommands(script)
+	if !ok {
+		return "", false
+	}
+	g.requireHelper("processSubstitution")
+	g.processSubsts++
+	return fmt.Sprintf("processSubstitution(%s, %s)", g.stdio(), commands), true
+}
+
+// substitutionCommands converts the commands of a command or process
+// substitution into a Go closure running them with the streams it is given.
+// Like the statements of a subshell, the closure restores the script
+// variables they assign when it returns, and the helpers running it restore
+// the working directory and the environment.
+func (g *GoCodeGenerator) substitutionCommands(script string) (string, bool) {
+	subshell, err := parser.ParseSubshell(g.IR, script, g.pos)
+	if err != nil {
+		return "", false
+	}
+	// The commands run in a subshell, which exit ends
+	g.subshells++
+	body, err := g.generateStatements(subshell.Statements)
+	g.subshells--
+	if err != nil {
+		return "", false
+	}
+
+	var lines []string
+	for _, name := range subshell.Vars {
+		if restore := g.restoreVar(name); restore != "" {
+			lines = append(lines, restore)
+		}
+	}
+	lines = withReturn(append(lines, body))
+	return fmt.Sprintf("func(%s *streams) error {\n%s\n}", g.stdio(), strings.Join(lines, "\n")), true
+}
+
+// releaseProcessSubstitutions wraps the code of a statement with process
+// substitutions so that their temporary files are removed once it has run
+func releaseProcessSubstitutions(code string) string {
+	scope := newCleanupScope()
+	scope.release("removeProcessSubstitutions(len(processSubstFiles))")
+	scope.add(code)
+	return scope.String()
+}
+
+// closingParen returns the index of the parenthesis closing the $( or <( at
+// the start of s, skipping quoted text and nested parentheses, or -1 if there is
+// none
+func closingParen(s string) int {
+	depth := 0
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return -1
+			}
+			i += end + 1
+		case '"':
+			end := closingQuote(s[i:])
+			if end < 0 {
+				return -1
+			}
+			i += end
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// closingQuote returns the index of the double quote closing the one at the
+// start of s, skipping command substitutions within, or -1 if there is none
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		case '$':
+			if i+1 < len(s) && s[i+1] == '(' {
+				end := closingParen(s[i:])
+				if end < 0 {
+					return -1
+				}
+				i += end
+			}
+		}
+	}
+	return -1
+}
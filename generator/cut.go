@@ -0,0 +1,208 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	utilities["cut"] = (*GoCodeGenerator).generateCut
+
+	runtimeHelpers["cut"] = runtimeHelper{
+		Source: `// cutOptions are the options of a cut command translated to Go: the list
+// of bytes, or of fields separated by delim, such as 1,3-5,7-
+type cutOptions struct {
+	list          string
+	fields        bool
+	delim         string
+	onlyDelimited bool
+}
+
+// cutRange is a range of the list of a cut command, hi being 0 for ranges
+// without an end
+type cutRange struct {
+	lo, hi int
+}
+
+// cut prints the selected bytes or fields of each line of files, or of stdin
+// without files, like cut. With onlyDelimited, lines without delim are not
+// printed. It fails with status 1 if the list or the delimiter is invalid,
+// or if a file cannot be read.
+func cut(stdio *streams, stdin io.Reader, opts cutOptions, files ...string) error {
+	ranges, err := cutList(opts.list, opts.fields)
+	if err == nil && opts.fields && len(opts.delim) > 1 {
+		err = errors.New("the delimiter must be a single character")
+	}
+	if err != nil {
+		fmt.Fprintf(stdio.Stderr, "cut: %v\nTry 'cut --help' for more information.\n", err)
+		return exitError(1)
+	}
+	if opts.delim == "" {
+		opts.delim = "\x00"
+	}
+	selected := func(n int) bool {
+		for _, r := range ranges {
+			if n >= r.lo && (r.hi == 0 || n <= r.hi) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	out := bufio.NewWriter(stdio.Stdout)
+	defer out.Flush()
+	failed := false
+	for _, name := range files {
+		input, ok := openInput(stdio, "cut", name, stdin)
+		if !ok {
+			failed = true
+			continue
+		}
+		lines := bufio.NewReader(input)
+		for {
+			line, err := lines.ReadString('\n')
+			if line != "" {
+				line = strings.TrimSuffix(line, "\n")
+				var b strings.Builder
+				if !opts.fields {
+					for i := 0; i < len(line); i++ {
+						if selected(i + 1) {
+							b.WriteByte(line[i])
+						}
+					}
+					out.WriteString(b.String() + "\n")
+				} else if parts := strings.Split(line, opts.delim); len(parts) > 1 {
+					first := true
+					for i, part := range parts {
+						if selected(i + 1) {
+							if !first {
+								b.WriteString(opts.delim)
+							}
+							b.WriteString(part)
+							first = false
+						}
+					}
+					out.WriteString(b.String() + "\n")
+				} else if !opts.onlyDelimited {
+					out.WriteString(line + "\n")
+				}
+			}
+			if err != nil {
+				if err != io.EOF {
+					utilityError(stdio, "cut", name, err)
+					failed = true
+				}
+				break
+			}
+		}
+		input.Close()
+	}
+	if failed {
+		return exitError(1)
+	}
+	return nil
+}
+
+// cutList parses the list of a cut command, of fields or of bytes, into its
+// ranges, with the error messages of cut
+func cutList(list string, fields bool) ([]cutRange, error) {
+	kind, numbered, invalid := "byte or character", "byte/character positions", "byte/character position"
+	if fields {
+		kind, numbered, invalid = "field", "fields", "field value"
+	}
+	invalidChar := func(c rune) bool {
+		return c != ',' && c != '-' && (c < '0' || c > '9')
+	}
+	if i := strings.IndexFunc(list, invalidChar); i >= 0 {
+		return nil, fmt.Errorf("invalid %s '%s'", invalid, list[i:])
+	}
+	var ranges []cutRange
+	for _, item := range strings.Split(list, ",") {
+		lo, hi, isRange := strings.Cut(item, "-")
+		if item == "-" {
+			return nil, errors.New("invalid range with no endpoint: -")
+		}
+		if !isRange {
+			n, _ := strconv.Atoi(item)
+			if n < 1 {
+				return nil, fmt.Errorf("%s are numbered from 1", numbered)
+			}
+			ranges = append(ranges, cutRange{n, n})
+			continue
+		}
+		r := cutRange{1, 0}
+		var err error
+		if lo != "" {
+			r.lo, err = strconv.Atoi(lo)
+		}
+		if err == nil && hi != "" {
+			r.hi, err = strconv.Atoi(hi)
+			if err == nil && r.hi < 1 {
+				r.lo = 0
+			}
+		}
+		switch {
+		case err != nil:
+			return nil, fmt.Errorf("invalid %s range", kind)
+		case r.lo < 1:
+			return nil, fmt.Errorf("%s are numbered from 1", numbered)
+		case r.hi != 0 && r.hi < r.lo:
+			return nil, errors.New("invalid decreasing range")
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}`,
+		Imports:  []string{"bufio", "errors", "fmt", "io", "strconv", "strings"},
+		Requires: []string{"exitError", "openInput"},
+	}
+}
+
+// generateCut translates cut with a list of fields, with -f, -d and -s, or
+// of bytes, with -c and -b, into a call of the cut helper, which validates
+// the list and delimiter like cut. It reports false for other options, for
+// several lists and for the options only valid with fields given with bytes,
+// so that cut is executed.
+func (g *GoCodeGenerator) generateCut(cmd parser.Command) (string, bool) {
+	options, operands, ok := g.utilityArgs(cmd, "sn", "fcbd")
+	if !ok {
+		return "", false
+	}
+	var list, delim string
+	fields, onlyDelimited := false, false
+	for _, option := range options {
+		switch option.name {
+		case 'f', 'c', 'b':
+			if list != "" {
+				return "", false
+			}
+			list, fields = option.value, option.name == 'f'
+		case 'd':
+			delim = option.value
+		case 's':
+			onlyDelimited = true
+		}
+	}
+	if list == "" || !fields && (delim != "" || onlyDelimited) {
+		return "", false
+	}
+
+	opts := "list: " + list
+	if fields {
+		if delim == "" {
+			delim = strconv.Quote("\t")
+		}
+		opts += ", fields: true, delim: " + delim
+		if onlyDelimited {
+			opts += ", onlyDelimited: true"
+		}
+	}
+	g.requireHelper("cut")
+	call := fmt.Sprintf("cut(%s, %s, cutOptions{%s}%s)", g.stdio(), g.utilityInput(cmd), opts, g.operandArgs(cmd, operands))
+	return g.checkErr(cmd, call), true
+}
@@ -0,0 +1,148 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/TFMV/bash2go/diagnostics"
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	runtimeHelpers["arithSetInt"] = runtimeHelper{
+		Source: `// arithSetInt assigns the result of an arithmetic assignment to a variable
+// declared with declare -i and returns it
+func arithSetInt(v *int, value int) int {
+	*v = value
+	return value
+}`,
+	}
+}
+
+// isIntVar reports whether name was declared as an integer with declare -i,
+// which makes it a Go int rather than a string.
+func (g *GoCodeGenerator) isIntVar(name string) bool {
+	return g.IR.IntegerVars[name] && !g.isAssocArray(name) && !g.isTableVar(name)
+}
+
+// isConstant reports whether name is a read-only variable translated into
+// a Go constant.
+func (g *GoCodeGenerator) isConstant(name string) bool {
+	_, ok := g.constants[name]
+	return ok
+}
+
+// findConstants records the read-only variables that can be Go constants:
+// those assigned a literal value once, at the top level of the script.
+// Integer constants need a literal number.
+func (g *GoCodeGenerator) findConstants() {
+	g.constants = make(map[string]string)
+	assigned := make(map[string][]parser.Assignment)
+	record := func(stmt parser.Statement) {
+		if assign, ok := stmt.Value.(parser.Assignment); ok && g.IR.ReadonlyVars[assign.Name] {
+			assigned[assign.Name] = append(assigned[assign.Name], assign)
+		}
+	}
+	walkStatements(g.IR.MainStatements, record)
+	for _, function := range g.IR.Functions {
+		walkStatements(function.Statements, record)
+	}
+
+	topLevel := make(map[string]bool)
+	for _, stmt := range g.IR.MainStatements {
+		if assign, ok := stmt.Value.(parser.Assignment); ok {
+			topLevel[assign.Name] = true
+		}
+	}
+	for name, assigns := range assigned {
+		assign := assigns[0]
+		if len(assigns) != 1 || !topLevel[name] || g.isTableVar(name) || g.isAssocArray(name) || assign.Key != "" || len(assign.Elements) > 0 ||
+			strings.ContainsAny(assign.Value, "$`") {
+			continue
+		}
+		if !g.isIntVar(name) {
+			g.constants[name] = strconv.Quote(assign.Value)
+		} else if n, err := strconv.ParseInt(assign.Value, 0, 0); err == nil {
+			g.constants[name] = strconv.FormatInt(n, 10)
+		}
+	}
+}
+
+// addConstants declares the constants found by findConstants, in a stable
+// order, and reports the read-only variables that stay variables.
+func (g *GoCodeGenerator) addConstants() {
+	names := make([]string, 0, len(g.IR.ReadonlyVars))
+	for name := range g.IR.ReadonlyVars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if value, ok := g.constants[name]; ok {
+			g.Generator.AddGlobal(fmt.Sprintf("const %s = %s", name, value))
+			continue
+		}
+		g.IR.Diagnose(diagnostics.SeverityWarning, parser.Position{}, diagnostics.CodeUnsupported,
+			"read-only variable %s is not assigned a literal value once, so it is not a constant and assignments to it are not rejected", name)
+	}
+}
+
+// intAssignment generates Go code assigning value, which like in Bash is
+// evaluated as an arithmetic expression, to the integer variable name, or
+// adding it with +=. An arithmetic expansion making up the whole value is
+// evaluated directly.
+func (g *GoCodeGenerator) intAssignment(name, value string, add bool) string {
+	op := "="
+	if add {
+		op = "+="
+	}
+	expr := value
+	if strings.HasPrefix(value, "$((") && closingArithm(value) == len(value) {
+		expr = value[3 : len(value)-2]
+	}
+	if a, err := parser.ParseArithmetic(expr); err == nil && expr != "" {
+		return fmt.Sprintf("%s %s %s", name, op, g.arithExpr(a))
+	}
+	g.requireHelper("arithValue")
+	return fmt.Sprintf("%s %s arithValue(%s)", name, op, g.goArg(value))
+}
+
+// walkStatements calls fn for each of statements and the statements nested
+// in them.
+func walkStatements(statements []parser.Statement, fn func(parser.Statement)) {
+	for _, stmt := range statements {
+		fn(stmt)
+		switch v := stmt.Value.(type) {
+		case parser.If:
+			walkStatements(v.Condition, fn)
+			walkStatements(v.ThenBlock, fn)
+			for _, elif := range v.ElifBlocks {
+				walkStatements(elif[0], fn)
+				walkStatements(elif[1], fn)
+			}
+			walkStatements(v.ElseBlock, fn)
+		case parser.Loop:
+			walkStatements(v.Init, fn)
+			walkStatements(v.Condition, fn)
+			walkStatements(v.Update, fn)
+			walkStatements(v.Body, fn)
+		case parser.Subshell:
+			walkStatements(v.Statements, fn)
+		case parser.Block:
+			walkStatements(v.Statements, fn)
+		case parser.Negation:
+			walkStatements(v.Statements, fn)
+		case parser.AndOr:
+			walkStatements(v.X, fn)
+			walkStatements(v.Y, fn)
+		case parser.Getopts:
+			for _, option := range v.Options {
+				walkStatements(option.Body, fn)
+			}
+			walkStatements(v.Invalid, fn)
+			walkStatements(v.Missing, fn)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TFMV/bash2go/diagnostics"
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	runtimeHelpers["execProcess"] = runtimeHelper{
+		Source: `// execProcess replaces the program with the command of argv, as exec does.
+// Where the process cannot be replaced, on Windows or while the standard
+// streams are redirected to other files than those the program started
+// with, the command runs to completion and the program exits with its
+// status instead.
+func execProcess(stdio *streams, argv []string) error {
+	path, err := exec.LookPath(argv[0])
+	if err != nil {
+		return err
+	}
+	if runtime.GOOS != "windows" && stdio.Stdin.Fd() == 0 && stdio.Stdout.Fd() == 1 && stdio.Stderr.Fd() == 2 {
+		return syscall.Exec(path, argv, os.Environ())
+	}
+	cmd := exec.Command(path, argv[1:]...)
+	cmd.Args[0] = argv[0]
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = stdio.Stdin, stdio.Stdout, stdio.Stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.ExitCode())
+		}
+		return err
+	}
+	os.Exit(0)
+	return nil
+}`,
+		Imports:  []string{"errors", "os", "os/exec", "runtime", "syscall"},
+		Requires: []string{"streams"},
+	}
+}
+
+// generateExec generates Go code for the exec builtin. With a command, the
+// program is replaced by it; without one, its redirections apply to the
+// standard streams for the rest of the script.
+func (g *GoCodeGenerator) generateExec(cmd parser.Command) string {
+	if len(cmd.Args) > 0 && strings.HasPrefix(cmd.Args[0], "-") || cmd.Stdin != "" || cmd.Heredoc != nil {
+		g.unsupported++
+		g.IR.Diagnose(diagnostics.SeverityError, cmd.Pos, diagnostics.CodeUnsupported,
+			"exec options, here-strings and here-documents are not supported")
+		return fmt.Sprintf("// Unsupported exec: %s", commentText(strings.Join(cmd.Args, " ")))
+	}
+	if len(cmd.Args) == 0 {
+		// The redirections are applied here, for good
+		g.cmdRedirected = true
+		return g.execRedirects(cmd.Redirects)
+	}
+
+	// Redirections of the command are applied around it like for other
+	// builtins, so it runs with the redirected streams
+	g.requireHelper("execProcess")
+	return g.checkErr(cmd, fmt.Sprintf("execProcess(%s, %s)", g.stdio(), g.globArgs(cmd.Args, cmd.Globs, cmd.Splits)))
+}
+
+// execRedirects generates Go code replacing the standard streams of the
+// shell with the redirections of exec without a command. The files opened
+// stay open until the program exits.
+func (g *GoCodeGenerator) execRedirects(redirects []parser.Redirection) string {
+	g.requireHelper("streams")
+	lines := []string{"// Redirect the standard streams for the rest of the script"}
+	files := 0
+	for _, redirection := range redirects {
+		if _, source, ok := redirectTarget(redirection); ok && source == "" {
+			files++
+		}
+	}
+	file := 0
+	for _, redirection := range redirects {
+		fds, source, ok := redirectTarget(redirection)
+		if !ok {
+			g.unsupported++
+			g.IR.Diagnose(diagnostics.SeverityError, redirection.Pos, diagnostics.CodeUnsupported,
+				"unsupported redirection %s%s of exec", redirection.Fd, redirection.Op)
+			lines = append(lines, fmt.Sprintf("// Unsupported redirection: %s%s %s", redirection.Fd, redirection.Op, commentText(redirection.Filename)))
+			continue
+		}
+
+		if source == "" {
+			file++
+			source = "file"
+			if files > 1 {
+				source = fmt.Sprintf("file%d", file)
+			}
+			lines = append(lines, fmt.Sprintf("// Redirect %s%s %s", redirection.Fd, redirection.Op, commentText(redirection.Filename)),
+				g.openFile(source, g.redirectOpenCall(redirection), "redirection"))
+		} else {
+			source = standardStreams[source]
+		}
+		for _, fd := range fds {
+			lines = append(lines, fmt.Sprintf("%s = %s", standardStreams[fd], source))
+		}
+	}
+	return "{\n" + strings.Join(lines, "\n") + "\n}"
+}
@@ -0,0 +1,278 @@
+package generator
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/TFMV/bash2go/diagnostics"
+	"github.com/TFMV/bash2go/parser"
+)
+
+// envPolicy returns the expansion policy for an environment variable.
+// Explicit per-variable settings take precedence over script directives,
+// which take precedence over the generator default.
+func (g *GoCodeGenerator) envPolicy(name string) parser.EnvPolicy {
+	if g.Options.RuntimeEnvOnly {
+		return parser.EnvRuntime
+	}
+	if policy, ok := g.Options.EnvPolicies[name]; ok {
+		return policy
+	}
+	if policy, ok := g.IR.EnvPolicies[name]; ok {
+		return policy
+	}
+	if g.Options.DefaultEnvPolicy != "" {
+		return g.Options.DefaultEnvPolicy
+	}
+	return parser.EnvRuntime
+}
+
+// isScriptVariable reports whether name is assigned somewhere in the script,
+// as opposed to being inherited from the environment, and modeled as a Go
+// variable of the same name.
+func (g *GoCodeGenerator) isScriptVariable(name string) bool {
+	if g.isTableVar(name) {
+		return false
+	}
+	if _, ok := g.IR.Variables[name]; ok {
+		return true
+	}
+	for _, function := range g.IR.Functions {
+		if _, ok := function.LocalVars[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// varRef returns a Go expression for the value of a Bash variable. Script
+// variables map to Go variables of the same name; environment variables are
+// either read with os.Getenv at runtime or resolved now, per envPolicy.
+// Under set -u, reading an unset environment variable ends the program.
+func (g *GoCodeGenerator) varRef(name string) string {
+	if expr, ok := g.positionalRef(name); ok {
+		return expr
+	}
+	if g.isShellVar(name) {
+		return g.shellVarRef(name)
+	}
+	if name == "PIPESTATUS" {
+		// $PIPESTATUS is the first element
+		g.requireHelper("pipestatus")
+		return `pipestatusAt("0")`
+	}
+	if name == "BASH_REMATCH" {
+		// $BASH_REMATCH is the whole match
+		g.requireHelper("rematch")
+		return `rematchAt("0")`
+	}
+	if g.isAssocArray(name) {
+		// $name refers to the element with key 0
+		return name + `["0"]`
+	}
+	if g.isScriptVariable(name) {
+		g.readVars[name] = true
+		if g.isIntVar(name) {
+			g.RequiredImports["strconv"] = true
+			return "strconv.Itoa(" + name + ")"
+		}
+		return name
+	}
+	if g.envPolicy(name) == parser.EnvConvert {
+		return strconv.Quote(os.Getenv(name))
+	}
+	if g.nounset() {
+		g.requireHelper("nounsetEnv")
+		return "nounsetEnv(" + strconv.Quote(name) + ")"
+	}
+	g.RequiredImports["os"] = true
+	return "os.Getenv(" + strconv.Quote(name) + ")"
+}
+
+// defaultRef is varRef for the parameter of an expansion with a default
+// value, such as ${name:-def}, which set -u allows to be unset.
+func (g *GoCodeGenerator) defaultRef(name string) string {
+	if g.isScriptVariable(name) || g.isShellVar(name) || g.envPolicy(name) == parser.EnvConvert {
+		return g.varRef(name)
+	}
+	g.RequiredImports["os"] = true
+	return "os.Getenv(" + strconv.Quote(name) + ")"
+}
+
+// goArg converts a Bash word, as extracted by the parser, into a Go string
+// expression. Variable references become varRef expressions and literal
+// text, where \$ is a dollar sign, is quoted; mixed words are joined with
+// string concatenation.
+func (g *GoCodeGenerator) goArg(word string) string {
+	var parts []string
+	var lit strings.Builder
+
+	flush := func() {
+		if lit.Len() > 0 {
+			parts = append(parts, strconv.Quote(lit.String()))
+			lit.Reset()
+		}
+	}
+
+	for i := 0; i < len(word); i++ {
+		// <(commands)
+		if strings.HasPrefix(word[i:], "<(") {
+			if end := closingParen(word[i:]); end > 0 {
+				if expr, ok := g.processSubstitution(word[i+2 : i+end]); ok {
+					flush()
+					parts = append(parts, expr)
+					i += end
+					continue
+				}
+			}
+		}
+
+		// An escaped dollar sign, as in \$HOME or '$HOME', is literal
+		if strings.HasPrefix(word[i:], `\$`) {
+			lit.WriteByte('$')
+			i++
+			continue
+		}
+
+		if word[i] != '$' || i+1 >= len(word) {
+			lit.WriteByte(word[i])
+			continue
+		}
+
+		// $((expression))
+		if strings.HasPrefix(word[i:], "$((") {
+			if end := closingArithm(word[i:]); end > 0 {
+				if expr, ok := g.arithExpansion(word[i+3 : i+end-2]); ok {
+					flush()
+					parts = append(parts, expr)
+					i += end - 1
+					continue
+				}
+			}
+		}
+
+		// $(commands)
+		if word[i+1] == '(' {
+			if end := closingParen(word[i:]); end > 0 {
+				if expr, ok := g.commandSubstitution(word[i+2 : i+end]); ok {
+					flush()
+					parts = append(parts, expr)
+					i += end
+					continue
+				}
+			}
+		}
+
+		// ${NAME}, ${name[key]} and ${NAME:-default}
+		if word[i+1] == '{' {
+			end := closingBrace(word[i:])
+			name := ""
+			if end > 0 {
+				name = word[i+2 : i+end]
+			}
+			expr, ok := g.positionalRef(name)
+			if !ok {
+				expr, ok = g.arrayExpansion(name)
+			}
+			if !ok {
+				expr, ok = g.paramExpansion(name)
+			}
+			if ok {
+				flush()
+				parts = append(parts, expr)
+				i += end
+				continue
+			}
+			if !isValidVarName(name) && !dynamicShellVars[name] {
+				if end > 0 {
+					// An expansion left as text would go unnoticed
+					g.unsupported++
+					g.IR.Diagnose(diagnostics.SeverityError, g.pos, diagnostics.CodeUnsupported,
+						"parameter expansion ${%s} is not supported", name)
+				}
+				lit.WriteByte(word[i])
+				continue
+			}
+			flush()
+			parts = append(parts, g.varRef(name))
+			i += end
+			continue
+		}
+
+		// $1 to $9, $#, $@ and $*
+		if expr, ok := g.positionalRef(word[i+1 : i+2]); ok {
+			flush()
+			parts = append(parts, expr)
+			i++
+			continue
+		}
+
+		// $?, $!, $$ and $0
+		if strings.IndexByte("?!$0", word[i+1]) >= 0 {
+			flush()
+			parts = append(parts, g.varRef(word[i+1:i+2]))
+			i++
+			continue
+		}
+
+		// $NAME
+		if !isValidVarNameStart(word[i+1]) {
+			lit.WriteByte(word[i])
+			continue
+		}
+		j := i + 1
+		for j < len(word) && isValidVarNameChar(word[j]) {
+			j++
+		}
+		flush()
+		parts = append(parts, g.varRef(word[i+1:j]))
+		i = j - 1
+	}
+	flush()
+
+	if len(parts) == 0 {
+		return `""`
+	}
+	return strings.Join(parts, " + ")
+}
+
+// closingBrace returns the index of the brace closing the ${ at the start of
+// s, skipping nested expansions as in ${map[${key}]}, or -1 if there is none
+func closingBrace(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// hereString converts the word of a here-string into a Go string expression
+// for the input it feeds, which like in Bash ends with a newline.
+func (g *GoCodeGenerator) hereString(word string) string {
+	expr := g.goArg(word)
+	if s, err := strconv.Unquote(expr); err == nil {
+		return strconv.Quote(s + "\n")
+	}
+	return expr + ` + "\n"`
+}
+
+// isValidVarName checks if a string is a valid Bash variable name
+func isValidVarName(s string) bool {
+	if s == "" || !isValidVarNameStart(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isValidVarNameChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,276 @@
+package generator
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	utilities["find"] = (*GoCodeGenerator).generateFind
+
+	runtimeHelpers["find"] = runtimeHelper{
+		Source: `// findOptions are the tests and action of a find expression translated to
+// Go. The exec command runs for each file, with {} replaced by its path, or
+// once for all of them with batch, as in -exec ... {} +.
+type findOptions struct {
+	names       []string
+	kind, mtime string
+	delete      bool
+	exec        []string
+	batch       bool
+}
+
+// find walks the directory trees of paths, or of the current directory
+// without paths, and prints the files whose base names match all the names
+// patterns, of the kind f, d or l and modified mtime days ago, as in -mtime
+// +7, like find. Files are visited in lexical order. With delete or exec, the
+// files are deleted, or passed to the command, instead of being printed. It
+// fails with status 1 if a file cannot be read or deleted, or if the command
+// fails with batch.
+func find(stdio *streams, opts findOptions, paths ...string) error {
+	var days int
+	if opts.mtime != "" {
+		days, _ = strconv.Atoi(strings.TrimLeft(opts.mtime, "+-"))
+	}
+	now := time.Now()
+	match := func(d fs.DirEntry) bool {
+		for _, name := range opts.names {
+			name = strings.ReplaceAll(name, "[!", "[^")
+			if ok, _ := filepath.Match(name, d.Name()); !ok {
+				return false
+			}
+		}
+		switch opts.kind {
+		case "f":
+			if !d.Type().IsRegular() {
+				return false
+			}
+		case "d":
+			if !d.IsDir() {
+				return false
+			}
+		case "l":
+			if d.Type()&fs.ModeSymlink == 0 {
+				return false
+			}
+		}
+		if opts.mtime != "" {
+			info, err := d.Info()
+			if err != nil {
+				return false
+			}
+			age := int(now.Sub(info.ModTime()) / (24 * time.Hour))
+			switch opts.mtime[0] {
+			case '+':
+				return age > days
+			case '-':
+				return age < days
+			}
+			return age == days
+		}
+		return true
+	}
+
+	out := bufio.NewWriter(stdio.Stdout)
+	defer out.Flush()
+	failed := false
+	run := func(files ...string) bool {
+		var args []string
+		for _, arg := range opts.exec[1:] {
+			switch {
+			case !opts.batch:
+				args = append(args, strings.ReplaceAll(arg, "{}", files[0]))
+			case arg == "{}":
+				args = append(args, files...)
+			default:
+				args = append(args, arg)
+			}
+		}
+		out.Flush()
+		cmd := exec.Command(opts.exec[0], args...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = stdio.Stdin, stdio.Stdout, stdio.Stderr
+		err := cmd.Run()
+		var execErr *exec.Error
+		if errors.As(err, &execErr) {
+			err := execErr.Err
+			if errors.Is(err, exec.ErrNotFound) {
+				err = syscall.ENOENT
+			}
+			utilityError(stdio, "find", "'"+opts.exec[0]+"'", err)
+		}
+		return err == nil
+	}
+
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	var batch []string
+	for _, root := range paths {
+		var deleted []string
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			// Paths are printed under root as given, which Join would clean
+			if path != root {
+				rel, _ := filepath.Rel(root, path)
+				path = strings.TrimSuffix(root, "/") + "/" + rel
+			}
+			if err != nil {
+				utilityError(stdio, "find", "'"+path+"'", err)
+				failed = true
+				return nil
+			}
+			if !match(d) {
+				return nil
+			}
+			switch {
+			case opts.delete:
+				deleted = append(deleted, path)
+			case opts.batch:
+				batch = append(batch, path)
+			case opts.exec != nil:
+				run(path)
+			default:
+				out.WriteString(path + "\n")
+			}
+			return nil
+		})
+		// Directories are deleted after their files, and never the current
+		// directory
+		for i := len(deleted) - 1; i >= 0; i-- {
+			if deleted[i] == "." {
+				continue
+			}
+			if err := os.Remove(deleted[i]); err != nil {
+				utilityError(stdio, "find", "cannot delete '"+deleted[i]+"'", err)
+				failed = true
+			}
+		}
+	}
+	if len(batch) > 0 && !run(batch...) {
+		failed = true
+	}
+	if failed {
+		return exitError(1)
+	}
+	return nil
+}`,
+		Imports:  []string{"bufio", "errors", "io/fs", "os", "os/exec", "path/filepath", "strconv", "strings", "syscall", "time"},
+		Requires: []string{"exitError", "utilityError"},
+	}
+}
+
+// findMtime matches the day counts of -mtime, such as +7, -1 and 0
+var findMtime = regexp.MustCompile(`^[+-]?[0-9]+$`)
+
+// generateFind translates find with starting points and an expression of the
+// tests -name, -type and -mtime followed by at most one of the actions
+// -print, -delete and -exec into a call of the find helper. It reports false
+// for other expressions, including operators, options such as -maxdepth and
+// tests given more than once, so that find is executed.
+func (g *GoCodeGenerator) generateFind(cmd parser.Command) (string, bool) {
+	// The starting points are the arguments before the expression
+	i := 0
+	for ; i < len(cmd.Args); i++ {
+		if literal, ok := g.literalArg(cmd, i); ok && (strings.HasPrefix(literal, "-") || literal == "(" || literal == "!") {
+			break
+		}
+	}
+	paths := make([]int, i)
+	for j := range paths {
+		paths[j] = j
+	}
+
+	var fields, names []string
+	seen := map[string]bool{}
+	action := false
+	for i < len(cmd.Args) {
+		primary, ok := g.literalArg(cmd, i)
+		if !ok || action {
+			return "", false
+		}
+		i++
+		switch primary {
+		case "-name":
+			if i == len(cmd.Args) || g.expandsArg(cmd, i) {
+				return "", false
+			}
+			name := g.goArg(cmd.Args[i])
+			if literal, err := strconv.Unquote(name); err == nil {
+				if _, err := filepath.Match(strings.ReplaceAll(literal, "[!", "[^"), ""); err != nil {
+					return "", false
+				}
+			}
+			names = append(names, name)
+			i++
+		case "-type", "-mtime":
+			if i == len(cmd.Args) || seen[primary] {
+				return "", false
+			}
+			value, ok := g.literalArg(cmd, i)
+			switch {
+			case !ok:
+				return "", false
+			case primary == "-type" && (value == "f" || value == "d" || value == "l"):
+				fields = append(fields, "kind: "+strconv.Quote(value))
+			case primary == "-mtime" && findMtime.MatchString(value):
+				fields = append(fields, "mtime: "+strconv.Quote(value))
+			default:
+				return "", false
+			}
+			seen[primary] = true
+			i++
+		case "-print":
+			action = true
+		case "-delete":
+			fields = append(fields, "delete: true")
+			action = true
+		case "-exec":
+			command, batch, end, ok := g.findExec(cmd, i)
+			if !ok {
+				return "", false
+			}
+			fields = append(fields, "exec: []string{"+strings.Join(command, ", ")+"}")
+			if batch {
+				fields = append(fields, "batch: true")
+			}
+			action = true
+			i = end
+		default:
+			return "", false
+		}
+	}
+	if len(names) > 0 {
+		fields = append([]string{"names: []string{" + strings.Join(names, ", ") + "}"}, fields...)
+	}
+
+	g.requireHelper("find")
+	call := fmt.Sprintf("find(%s, findOptions{%s}%s)", g.stdio(), strings.Join(fields, ", "), g.operandArgs(cmd, paths))
+	return g.checkErr(cmd, call), true
+}
+
+// findExec returns the Go arguments of the command of an -exec action whose
+// arguments start at index i of cmd, whether it ends with {} +, and the index
+// following it. It reports false for commands whose arguments expand to
+// several words, which are only known at runtime.
+func (g *GoCodeGenerator) findExec(cmd parser.Command, i int) ([]string, bool, int, bool) {
+	var command []string
+	for ; i < len(cmd.Args); i++ {
+		literal, ok := g.literalArg(cmd, i)
+		switch {
+		case ok && (literal == ";" || literal == `\;`):
+			return command, false, i + 1, len(command) > 0
+		case ok && literal == "{}" && i+1 < len(cmd.Args) && len(command) > 0:
+			if next, ok := g.literalArg(cmd, i+1); ok && next == "+" {
+				return append(command, `"{}"`), true, i + 2, true
+			}
+		case g.expandsArg(cmd, i):
+			return nil, false, 0, false
+		}
+		command = append(command, g.goArg(cmd.Args[i]))
+	}
+	return nil, false, 0, false
+}
@@ -0,0 +1,100 @@
+package generator
+
+import (
+	"go/token"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+// reservedFuncNames lists the functions of every generated program, which
+// translated functions must not redeclare.
+var reservedFuncNames = map[string]bool{
+	"init": true,
+	"main": true,
+	"run":  true,
+}
+
+// funcIdent returns the Go identifier of the translated function of the
+// script function name, used both for its declaration and for calls of it.
+// Bash function names such as my-func or log::info, Go keywords and
+// predeclared identifiers are mangled into identifiers no other function
+// of the script has.
+func (g *GoCodeGenerator) funcIdent(name string) string {
+	if g.funcIdents == nil {
+		g.funcIdents = funcIdents(g.IR.Functions)
+	}
+	if ident, ok := g.funcIdents[name]; ok {
+		return ident
+	}
+	return name
+}
+
+// funcIdents returns the Go identifier of each of functions. Names that are
+// usable as they are keep them; the others take the first free identifier
+// made of their letters, digits and underscores.
+func funcIdents(functions map[string]*parser.Function) map[string]string {
+	names := make([]string, 0, len(functions))
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	idents := make(map[string]string, len(names))
+	taken := make(map[string]bool, len(names))
+	var mangled []string
+	for _, name := range names {
+		if usableFuncIdent(name) {
+			idents[name] = name
+			taken[name] = true
+		} else {
+			mangled = append(mangled, name)
+		}
+	}
+	for _, name := range mangled {
+		base := mangleFuncName(name)
+		ident := base
+		for i := 2; taken[ident] || !usableFuncIdent(ident); i++ {
+			ident = base + "_" + strconv.Itoa(i)
+		}
+		idents[name] = ident
+		taken[ident] = true
+	}
+	return idents
+}
+
+// usableFuncIdent reports whether name can name a translated function as it
+// is, without clashing with an imported package or a runtime helper such as
+// the stdio streams every translated function takes.
+func usableFuncIdent(name string) bool {
+	return token.IsIdentifier(name) && !reservedFuncNames[name] && types.Universe.Lookup(name) == nil &&
+		!importedPackageNames[name] && !helperIdents()[name]
+}
+
+// mangleFuncName replaces the runs of characters of name that Go
+// identifiers cannot hold with an underscore, and prefixes names that do not
+// start with a letter, so that log::info becomes log_info.
+func mangleFuncName(name string) string {
+	var b strings.Builder
+	pending := false
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !isValidVarNameChar(c) {
+			pending = true
+			continue
+		}
+		if pending && b.Len() > 0 {
+			b.WriteByte('_')
+		}
+		pending = false
+		b.WriteByte(c)
+	}
+	ident := b.String()
+	if ident == "" || !isValidVarNameStart(ident[0]) {
+		ident = "fn_" + ident
+	}
+	return ident
+}
@@ -4,6 +4,7 @@ package generator
 import (
 	"bytes"
 	"fmt"
+	"go/build/constraint"
 	"go/format"
 	"sort"
 	"strings"
@@ -67,10 +68,10 @@ func (cb *CodeBuilder) Format() (string, error) {
 // CodeGenerator is the enterprise-grade code generator.
 type CodeGenerator struct {
 	packageName string
+	constraint  string
 	imports     map[string]bool
 	globals     []string
 	functions   []Function
-	cb          *CodeBuilder
 }
 
 // NewCodeGenerator creates a new CodeGenerator for a given package name.
@@ -78,10 +79,24 @@ func NewCodeGenerator(packageName string) *CodeGenerator {
 	return &CodeGenerator{
 		packageName: packageName,
 		imports:     make(map[string]bool),
-		cb:          NewCodeBuilder(),
 	}
 }
 
+// SetBuildConstraint sets the build constraint expression, such as
+// "linux && !cgo", written as a //go:build line at the top of the file. An
+// empty expression removes the constraint.
+func (cg *CodeGenerator) SetBuildConstraint(expr string) error {
+	if expr == "" {
+		cg.constraint = ""
+		return nil
+	}
+	if _, err := constraint.Parse("//go:build " + expr); err != nil {
+		return fmt.Errorf("invalid build constraint %q: %w", expr, err)
+	}
+	cg.constraint = expr
+	return nil
+}
+
 // AddImport registers an import package, avoiding duplicates.
 func (cg *CodeGenerator) AddImport(pkg string) {
 	cg.imports[pkg] = true
@@ -113,15 +128,25 @@ func (cg *CodeGenerator) AddFunction(fn Function) {
 }
 
 // Build constructs the complete Go source file and returns the formatted source code.
+// Each call starts from an empty buffer, so Build may be called repeatedly.
 func (cg *CodeGenerator) Build() (string, error) {
+	cb := NewCodeBuilder()
+	cb.buf.Grow(cg.sizeHint())
+
+	// Build constraint.
+	if cg.constraint != "" {
+		cb.WriteLine("//go:build " + cg.constraint)
+		cb.WriteLine("")
+	}
+
 	// Package declaration.
-	cg.cb.WriteLine(fmt.Sprintf("package %s", cg.packageName))
-	cg.cb.WriteLine("")
+	cb.WriteLine(fmt.Sprintf("package %s", cg.packageName))
+	cb.WriteLine("")
 
 	// Imports block.
 	if len(cg.imports) > 0 {
-		cg.cb.WriteLine("import (")
-		cg.cb.Indent()
+		cb.WriteLine("import (")
+		cb.Indent()
 		// Sort imports for consistency.
 		importKeys := make([]string, 0, len(cg.imports))
 		for imp := range cg.imports {
@@ -129,26 +154,26 @@ func (cg *CodeGenerator) Build() (string, error) {
 		}
 		sort.Strings(importKeys)
 		for _, imp := range importKeys {
-			cg.cb.WriteLine(fmt.Sprintf("\"%s\"", imp))
+			cb.WriteLine(fmt.Sprintf("\"%s\"", imp))
 		}
-		cg.cb.Outdent()
-		cg.cb.WriteLine(")")
-		cg.cb.WriteLine("")
+		cb.Outdent()
+		cb.WriteLine(")")
+		cb.WriteLine("")
 	}
 
 	// Global variables and declarations.
 	for _, global := range cg.globals {
-		cg.cb.WriteLine(global)
+		cb.WriteLine(global)
 	}
 	if len(cg.globals) > 0 {
-		cg.cb.WriteLine("")
+		cb.WriteLine("")
 	}
 
 	// Functions.
 	for _, fn := range cg.functions {
 		// Write any comments.
 		for _, comment := range fn.Comments {
-			cg.cb.WriteLine("// " + comment)
+			cb.WriteLine("// " + comment)
 		}
 		// Build parameter list.
 		params := make([]string, len(fn.Parameters))
@@ -161,18 +186,33 @@ func (cg *CodeGenerator) Build() (string, error) {
 		if fn.ReturnType != "" {
 			signature += " " + fn.ReturnType
 		}
-		cg.cb.WriteLine(signature + " {")
-		cg.cb.Indent()
+		cb.WriteLine(signature + " {")
+		cb.Indent()
 		for _, line := range fn.Body {
-			cg.cb.WriteLine(line)
+			cb.WriteLine(line)
 		}
-		cg.cb.Outdent()
-		cg.cb.WriteLine("}")
-		cg.cb.WriteLine("")
+		cb.Outdent()
+		cb.WriteLine("}")
+		cb.WriteLine("")
 	}
 
 	// Return the formatted source code.
-	return cg.cb.Format()
+	return cb.Format()
+}
+
+// sizeHint estimates the size of the source built by Build, so that the
+// buffer of a large program is allocated once rather than grown repeatedly.
+func (cg *CodeGenerator) sizeHint() int {
+	n := 1024
+	for _, global := range cg.globals {
+		n += len(global) + 1
+	}
+	for _, fn := range cg.functions {
+		for _, line := range fn.Body {
+			n += len(line) + 2
+		}
+	}
+	return n
 }
 
 // GenerateMain generates a simple main function
@@ -1,9 +1,20 @@
 package generator_test
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/TFMV/bash2go/diagnostics"
 	"github.com/TFMV/bash2go/generator"
 	"github.com/TFMV/bash2go/parser"
 )
@@ -326,7 +337,7 @@ func TestGeneratePipe(t *testing.T) {
 		Body: []string{
 			"exe := gexe.New()",
 			"output := exe.Run(\"ls -la | grep file\").Stdout()",
-			"fmt.Print(output)",
+			"fmt.Fprint(stdio.Stdout, output)",
 		},
 	}
 
@@ -364,7 +375,7 @@ func TestGenerateSubshell(t *testing.T) {
 		Body: []string{
 			"func() {",
 			"\tos.Chdir(\"/tmp\")",
-			"\tfmt.Println(\"In subshell\")",
+			"\tfmt.Fprintln(stdio.Stdout, \"In subshell\")",
 			"}()",
 		},
 	}
@@ -387,7 +398,3598 @@ func TestGenerateSubshell(t *testing.T) {
 		t.Fatalf("Generated code missing cd command: %s", code)
 	}
 
-	if !strings.Contains(code, "fmt.Println(\"In subshell\")") {
+	if !strings.Contains(code, "fmt.Fprintln(stdio.Stdout, \"In subshell\")") {
 		t.Fatalf("Generated code missing echo command: %s", code)
 	}
 }
+
+// TestGenerateErrorLocations tests that runtime errors carry the Bash source location
+func TestGenerateErrorLocations(t *testing.T) {
+	script := `#!/bin/bash
+mkdir /opt/app
+cd /opt/app
+`
+
+	// Parse the script
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	result.Filename = "deploy.sh"
+
+	// Build the IR
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	// Generate the code
+	code, err := generator.NewGoCodeGenerator(ir).Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	// Verify the output
+	if !strings.Contains(code, `fmt.Errorf("deploy.sh:2: mkdir failed: %w", err)`) {
+		t.Fatalf("Generated code missing located mkdir error: %s", code)
+	}
+
+	if !strings.Contains(code, `fmt.Errorf("deploy.sh:3: cd failed: %w", err)`) {
+		t.Fatalf("Generated code missing located cd error: %s", code)
+	}
+
+	if !strings.Contains(code, "func run() error") {
+		t.Fatalf("Generated code missing run function: %s", code)
+	}
+}
+
+// TestGenerateMetrics tests the conversion coverage metrics
+func TestGenerateMetrics(t *testing.T) {
+	script := `#!/bin/bash
+mkdir /tmp/work
+cd /tmp/work
+ls -la
+`
+
+	// Parse the script
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+
+	// Build the IR
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	// Generate the code
+	gen := generator.NewGoCodeGenerator(ir)
+	if _, err := gen.Generate(); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	// Verify the metrics
+	metrics := gen.Metrics()
+	if metrics.Native != 2 {
+		t.Fatalf("Expected 2 native statements, got %d", metrics.Native)
+	}
+
+	if metrics.ExecFallbacks != 1 {
+		t.Fatalf("Expected 1 exec fallback, got %d", metrics.ExecFallbacks)
+	}
+
+	if len(metrics.Dependencies) != 0 {
+		t.Fatalf("Expected no dependencies, got %v", metrics.Dependencies)
+	}
+
+	if percent := metrics.NativePercent(); percent < 66 || percent > 67 {
+		t.Fatalf("Expected about 66.7%% native, got %.1f", percent)
+	}
+}
+
+// TestGenerateMetricsUnsupportedExpansion tests that a statement with an
+// expansion that is not translated does not count as native
+func TestGenerateMetricsUnsupportedExpansion(t *testing.T) {
+	script := `#!/bin/bash
+name=world
+echo "hello ${name:?not set}"
+echo "hello ${!name}"
+echo "hello ${name^^}"
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	if _, err := gen.Generate(); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	metrics := gen.Metrics()
+	if metrics.Native != 2 || metrics.Unsupported != 2 {
+		t.Fatalf("Expected 2 native statements and 2 unsupported expansions, got %+v", metrics)
+	}
+	if percent := metrics.NativePercent(); percent != 50 {
+		t.Fatalf("Expected 50%% native, got %.1f", percent)
+	}
+}
+
+// TestConcurrentGenerate tests that conversions can run concurrently
+func TestConcurrentGenerate(t *testing.T) {
+	scripts := []string{
+		"#!/bin/bash\nmkdir /tmp/a\nls -la\n",
+		"#!/bin/bash\necho \"hello\"\ncd /tmp\n",
+		"#!/bin/bash\nrm -rf /tmp/b\nls | grep b\n",
+	}
+
+	// Share one generator per script between several goroutines, and run
+	// the scripts themselves in parallel
+	var wg sync.WaitGroup
+	errs := make(chan error, len(scripts)*4)
+	for _, script := range scripts {
+		result, err := parser.ParseBashString(script)
+		if err != nil {
+			t.Fatalf("ParseBashString failed: %v", err)
+		}
+		ir, err := parser.BuildIR(result)
+		if err != nil {
+			t.Fatalf("BuildIR failed: %v", err)
+		}
+
+		gen := generator.NewGoCodeGenerator(ir)
+		want, err := gen.Generate()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				got, err := gen.Generate()
+				if err != nil {
+					errs <- err
+					return
+				}
+				if got != want {
+					errs <- fmt.Errorf("concurrent Generate produced different code:\n%s\nwant:\n%s", got, want)
+				}
+				gen.Metrics()
+			}()
+		}
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatal(err)
+	}
+}
+
+// TestCodeGeneratorBuildTwice tests that Build can be called repeatedly
+func TestCodeGeneratorBuildTwice(t *testing.T) {
+	cg := generator.NewCodeGenerator("main")
+	cg.AddImport("fmt")
+	cg.AddFunction(generator.Function{
+		Name: "main",
+		Body: []string{"fmt.Fprintln(stdio.Stdout, \"Hello, World!\")"},
+	})
+
+	first, err := cg.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	second, err := cg.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	if first != second {
+		t.Fatalf("Expected identical output from repeated Build calls:\n%s\n%s", first, second)
+	}
+}
+
+// TestGenerateEnvPolicy tests runtime and conversion-time environment expansion
+func TestGenerateEnvPolicy(t *testing.T) {
+	t.Setenv("DEPLOY_USER", "ci")
+	t.Setenv("DEPLOY_HOST", "build01")
+
+	script := `#!/bin/bash
+# bash2go:env convert DEPLOY_USER
+NAME="app"
+echo "$NAME by $DEPLOY_USER on $DEPLOY_HOST in $HOME"
+`
+
+	// Parse the script
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+
+	// Build the IR
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	// Generate the code, resolving DEPLOY_HOST through the options
+	gen := generator.NewGoCodeGenerator(ir)
+	gen.Options.EnvPolicies = map[string]parser.EnvPolicy{"DEPLOY_HOST": parser.EnvConvert}
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	// Verify the output
+	expected := `fmt.Fprintln(stdio.Stdout, NAME+" by "+"ci"+" on "+"build01"+" in "+os.Getenv("HOME"))`
+	if !strings.Contains(code, expected) {
+		t.Fatalf("Generated code missing expanded echo %s: %s", expected, code)
+	}
+}
+
+// TestGenerateShopt tests that shopt settings are tracked in the IR and code
+func TestGenerateShopt(t *testing.T) {
+	script := `#!/bin/bash
+shopt -s nullglob globstar
+shopt -u extglob
+`
+
+	// Parse the script
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+
+	// Build the IR
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	if !ir.ShellOptions["nullglob"] || !ir.ShellOptions["globstar"] {
+		t.Fatalf("Expected nullglob and globstar to be recorded as set, got %v", ir.ShellOptions)
+	}
+
+	if set, ok := ir.ShellOptions["extglob"]; !ok || set {
+		t.Fatalf("Expected extglob to be recorded as unset, got %v", ir.ShellOptions)
+	}
+
+	// Generate the code
+	code, err := generator.NewGoCodeGenerator(ir).Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	// Verify the output
+	for _, expected := range []string{
+		"var shellOptions = map[string]bool{}",
+		`shellOptions["nullglob"] = true`,
+		`shellOptions["globstar"] = true`,
+		`shellOptions["extglob"] = false`,
+	} {
+		if !strings.Contains(code, expected) {
+			t.Fatalf("Generated code missing %s: %s", expected, code)
+		}
+	}
+}
+
+// TestGenerateRedirectionCleanup tests that opened files are closed per statement
+func TestGenerateRedirectionCleanup(t *testing.T) {
+	script := `#!/bin/bash
+echo "first" > out.txt
+echo "second" >> out.txt
+`
+
+	// Parse the script
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+
+	// Build the IR
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	// Generate the code
+	code, err := generator.NewGoCodeGenerator(ir).Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	// Each redirection gets its own scope so the deferred Close runs when
+	// the statement finishes
+	if n := strings.Count(code, "if err := func() error {"); n != 2 {
+		t.Fatalf("Expected 2 cleanup scopes, got %d: %s", n, code)
+	}
+
+	if n := strings.Count(code, "defer file.Close()"); n != 2 {
+		t.Fatalf("Expected 2 deferred Close calls, got %d: %s", n, code)
+	}
+}
+
+// TestGenerateSpecialVars tests that special variables are served by the runtime table
+func TestGenerateSpecialVars(t *testing.T) {
+	script := `#!/bin/bash
+greet() {
+  echo "in $FUNCNAME"
+}
+false
+echo "status $?"
+ls /tmp
+echo "last $_"
+echo "$0 runs as $$"
+`
+
+	// Parse the script
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+
+	// Build the IR
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	for _, name := range []string{"?", "_", "FUNCNAME", "$", "0"} {
+		if !ir.SpecialVars[name] {
+			t.Fatalf("Expected %s to be recorded as used, got %v", name, ir.SpecialVars)
+		}
+	}
+
+	// Generate the code
+	code, err := generator.NewGoCodeGenerator(ir).Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	// Verify the output
+	for _, expected := range []string{
+		"func shellVar(name string) string",
+		`defer enterFunction("greet")()`,
+		`setShellVar("?", strconv.Itoa(exitStatus(err)))`,
+		`"status "+shellVar("?")`,
+		`setShellVar("_", "/tmp")`,
+		`"last "+shellVar("_")`,
+		`"in "+shellVar("FUNCNAME")`,
+		`shellVar("0")+" runs as "+shellVar("$")`,
+		`"$": strconv.Itoa(os.Getpid())`,
+	} {
+		if !strings.Contains(code, expected) {
+			t.Fatalf("Generated code missing %s: %s", expected, code)
+		}
+	}
+}
+
+// TestGenerateAssocArrays tests translating associative arrays into maps
+func TestGenerateAssocArrays(t *testing.T) {
+	script := `declare -A colors=([red]=ff0000 ["dark green"]=006400)
+colors[blue]=0000ff
+key=red
+echo "red is ${colors[$key]}"
+echo "${#colors[@]} colors: ${colors[@]}"
+for name in "${!colors[@]}"; do
+  echo "$name"
+done
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		`var colors = map[string]string{}`,
+		`colors = map[string]string{"red": "ff0000", "dark green": "006400"}`,
+		`colors["blue"] = "0000ff"`,
+		`"red is "+colors[key]`,
+		`strconv.Itoa(len(colors))+" colors: "+strings.Join(sortedValues(colors), " ")`,
+		`for _, name = range sortedKeys(colors) {`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGenerateHereString tests feeding here-strings to commands
+func TestGenerateHereString(t *testing.T) {
+	script := `VAR=foo
+grep foo <<< "$VAR"
+tr a-z A-Z <<< hello
+echo ignored <<< input
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		`grep(stdio, strings.NewReader(VAR+"\n"), grepOptions{}, "foo")`,
+		`tr(stdio, strings.NewReader("hello\n"), trOptions{`,
+		`fmt.Fprintln(stdio.Stdout, "ignored")`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "Unsupported redirection") {
+		t.Errorf("Expected no unsupported redirections:\n%s", code)
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGenerateArithmetic tests translating arithmetic into Go integer
+// expressions
+func TestGenerateArithmetic(t *testing.T) {
+	script := `a=6
+b=7
+echo $((a * b + 3))
+i=$((i + 1))
+(( i += 2 ))
+let "a = a ** 2" b--
+echo $(( i > 3 ? 10 : 20 )) $(( x++ ))
+for (( n = 0; n < 3; n++ )); do
+  echo "$n"
+done
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		`fmt.Fprintln(stdio.Stdout, strconv.Itoa((arithValue(a)*arithValue(b))+3))`,
+		`i = strconv.Itoa(arithValue(i) + 1)`,
+		`i = strconv.Itoa(arithValue(i) + 2)`,
+		`a = strconv.Itoa(arithPow(arithValue(a), 2))`,
+		`b = strconv.Itoa(arithValue(b) - 1)`,
+		`if arithValue(i) > 3 {`,
+		`strconv.Itoa(arithSet(&x, arithValue(x)+1)-1)`,
+		`for ; arithValue(n) < 3; n = strconv.Itoa(arithValue(n) + 1) {`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGenerateDivision tests that divisions and remainders check their
+// divisor for 0 instead of panicking, unless it is a non-zero number
+func TestGenerateDivision(t *testing.T) {
+	script := `x=0
+echo $((5 / x)) $((6 / 2))
+(( y = 3 % x ))
+z=4; (( z /= x ))
+w=8; (( w %= x ))
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		`strconv.Itoa(5/arithDivisor(arithValue(x), "line 2: division by 0"))`,
+		`strconv.Itoa(6/2)`,
+		`3 % arithDivisor(arithValue(x), "line 3: division by 0")`,
+		`arithValue(z) / arithDivisor(arithValue(x), "line 4: division by 0")`,
+		`arithValue(w) % arithDivisor(arithValue(x), "line 5: division by 0")`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGenerateParamDefaults tests translating default-value parameter
+// expansions
+func TestGenerateParamDefaults(t *testing.T) {
+	script := `name=""
+echo "${name:-world} ${TARGET-/tmp} ${TARGET:-/tmp}"
+echo "${out:=build}/bin"
+dir=${DEST:-${out}/sub}
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		`orDefault(name, "world")`,
+		`envOrDefault("TARGET", "/tmp")`,
+		`orDefault(os.Getenv("TARGET"), "/tmp")`,
+		`assignDefault(&out, "build")+"/bin"`,
+		`dir = orDefault(os.Getenv("DEST"), out+"/sub")`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGeneratePatternRemoval tests translating prefix and suffix removal
+func TestGeneratePatternRemoval(t *testing.T) {
+	script := `file=/src/archive.tar.gz
+echo "${file%.gz}" "${file#/src/}" "${file%'.*'}"
+echo "${file%.*}" "${file%%.*}" "${file##*/}"
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		`strings.TrimSuffix(file, ".gz")`,
+		`strings.TrimPrefix(file, "/src/")`,
+		`strings.TrimSuffix(file, ".*")`,
+		`removePattern(file, ".*", true, false)`,
+		`removePattern(file, ".*", true, true)`,
+		`removePattern(file, "*/", false, true)`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGeneratePatternReplacement tests translating alternate values, pattern
+// replacement and case conversion
+func TestGeneratePatternReplacement(t *testing.T) {
+	script := `path="/usr/local/bin"
+name="hello world"
+empty=
+echo "${path//\//:}" "${path/local/opt}" "${name/o*/X}" "${name//[lo]/_}"
+echo "${path/#\/usr/~}" "${name/%d/D}" "${name^}" "${name^^}" "${name^^[hw]}"
+echo "${name,,}" "${name:+set}" "[${empty:+set}]" "${HOME+home}"
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		`strings.ReplaceAll(path, "/", ":")`,
+		`strings.Replace(path, "local", "opt", 1)`,
+		`replacePattern(name, "o*", "X", "/")`,
+		`replacePattern(name, "[lo]", "_", "//")`,
+		`replacePattern(path, "\\/usr", "~", "/#")`,
+		`convertCase(name, "", true, false)`,
+		`strings.ToUpper(name)`,
+		`convertCase(name, "[hw]", true, true)`,
+		`strings.ToLower(name)`,
+		`alternate(name, "set")`,
+		`envAlternate("HOME", "home")`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if len(ir.Diagnostics.Items()) != 0 {
+		t.Errorf("Expected no diagnostics, got %v", ir.Diagnostics.Items())
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Fatalf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+
+	if testing.Short() {
+		t.Skip("skipping running the generated program in short mode")
+	}
+	want := ":usr:local:bin /usr/opt/bin hellX he___ w_r_d\n" +
+		"~/local/bin hello worlD Hello world HELLO WORLD Hello World\n" +
+		"hello world set [] home\n"
+	if got := runScript(t, t.TempDir(), script); got != want {
+		t.Errorf("Expected output %q, got %q", want, got)
+	}
+}
+
+// TestGenerateUnsupportedExpansion tests reporting parameter expansions that
+// are not translated
+func TestGenerateUnsupportedExpansion(t *testing.T) {
+	result, err := parser.ParseBashString("echo \"${name:?not set}\" \"${!ref}\"\n")
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	if _, err := gen.Generate(); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	var messages []string
+	for _, d := range ir.Diagnostics.Items() {
+		if d.Severity == diagnostics.SeverityError && d.Code == diagnostics.CodeUnsupported {
+			messages = append(messages, d.Message)
+		}
+	}
+	for _, want := range []string{"${name:?not set}", "${!ref}"} {
+		if !strings.Contains(strings.Join(messages, "\n"), want) {
+			t.Errorf("Expected %s to be reported as unsupported, got %q", want, messages)
+		}
+	}
+}
+
+// TestGenerateSubstring tests translating substring and length expansions
+func TestGenerateSubstring(t *testing.T) {
+	script := `s="hello world"
+n=2
+echo "${#s}" "${s:6}" "${s: -5:2}" "${s:n:n+1}" "${#HOME}"
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		`strconv.Itoa(utf8.RuneCountInString(s))`,
+		`substring(s, 6)`,
+		`substring(s, -5, 2)`,
+		`substring(s, arithValue(n), arithValue(n)+1)`,
+		`strconv.Itoa(utf8.RuneCountInString(os.Getenv("HOME")))`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGenerateCommandSubstitution tests capturing the output of command
+// substitutions
+func TestGenerateCommandSubstitution(t *testing.T) {
+	script := `name=$(echo "world")
+echo "hello $(echo "in $(echo deep)")"
+dir=$(pwd)
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"name = captureOutput(stdio, func(stdio *streams) error {\n\t\tfmt.Fprintln(stdio.Stdout, \"world\")",
+		`fmt.Fprintln(stdio.Stdout, "in "+captureOutput(stdio, func(stdio *streams) error {`,
+		"dir = captureOutput(stdio, func(stdio *streams) error {\n\t\t{\n\t\t\tdir, err := os.Getwd()",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "$(") {
+		t.Errorf("Expected no substitution to be left as text:\n%s", code)
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestCommandSubstitutionIsolation tests that, like in Bash, the commands
+// of a command substitution run in a subshell: changing the working
+// directory, script variables or the environment does not affect the script
+func TestCommandSubstitutionIsolation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping go run in short mode")
+	}
+
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("EvalSymlinks failed: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatalf("Mkdir failed: %v", err)
+	}
+
+	script := `DIR=$(cd sub && pwd)
+pwd
+echo "$DIR"
+x=1
+y=$(x=9; echo $x)
+echo "$x $y"
+z=$(export LEAK=1; echo ok)
+echo "${LEAK:-unset} $z"
+`
+	want := fmt.Sprintf("%s\n%s\n1 9\nunset ok\n", dir, filepath.Join(dir, "sub"))
+	if got := runScript(t, dir, script); got != want {
+		t.Errorf("Output = %q, want %q", got, want)
+	}
+}
+
+// TestProcessSubstitutionIsolation tests that, like in Bash, the commands of
+// a process substitution run in a subshell, which does not change the
+// working directory, script variables or the environment of the script
+func TestProcessSubstitutionIsolation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping go run in short mode")
+	}
+
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("EvalSymlinks failed: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatalf("Mkdir failed: %v", err)
+	}
+
+	script := `n=1
+cat <(cd sub; n=3; export LEAK=1; pwd)
+pwd
+echo "$n ${LEAK:-unset}"
+`
+	want := fmt.Sprintf("%s\n%s\n1 unset\n", filepath.Join(dir, "sub"), dir)
+	if got := runScript(t, dir, script); got != want {
+		t.Errorf("Output = %q, want %q", got, want)
+	}
+}
+
+// TestGenerateProcessSubstitution tests translating input process
+// substitutions into temporary files
+func TestGenerateProcessSubstitution(t *testing.T) {
+	script := `echo <(echo hi)
+file=<(pwd)
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"fmt.Fprintln(stdio.Stdout, processSubstitution(stdio, func(stdio *streams) error {\n\t\t\tfmt.Fprintln(stdio.Stdout, \"hi\")",
+		"file = processSubstitution(stdio, func(stdio *streams) error {",
+		"defer removeProcessSubstitutions(len(processSubstFiles))",
+		"func processSubstitution(stdio *streams, commands func(stdio *streams) error) string {",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "<(echo hi)") {
+		t.Errorf("Expected no substitution to be left as text:\n%s", code)
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGenerateGlobs tests expanding glob patterns in arguments and loops
+func TestGenerateGlobs(t *testing.T) {
+	script := `echo *.log "*.txt"
+for f in a *.log; do
+  echo "$f"
+done
+rm -f old.txt *.tmp
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	gen.Options.ShellOptions = []string{"nullglob"}
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		`fmt.Fprintln(stdio.Stdout, strings.Join(append(globExpand("*.log"), []string{"*.txt"}...), " "))`,
+		`range append(strings.Fields("a"), globExpand("*.log")...) {`,
+		"os.Remove(\"old.txt\")",
+		"for _, name := range globExpand(\"*.tmp\") {\n\t\tif err := os.Remove(name)",
+		`shellOptions["nullglob"] = true`,
+		"func globExpand(pattern string) []string {",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+
+	gen.Options.ShellOptions = []string{"dotglob"}
+	if _, err := gen.Generate(); err == nil {
+		t.Error("Expected an unsupported shell option to be rejected")
+	}
+}
+
+// TestGenerateExtendedTest tests translating [[ ]] into native Go conditions
+func TestGenerateExtendedTest(t *testing.T) {
+	script := `x=foobar
+if [[ $x == foo* && ! -d $x ]]; then
+  echo glob
+fi
+if [[ $x =~ ^[a-z]+$ || $x != "bar" ]]; then
+  echo regexp
+fi
+n=0
+while [[ ${#x} -gt $n ]]; do
+  (( n++ ))
+done
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		`if (patternMatch("foo*", x)) && !(fileTest("-d", x)) {`,
+		`if (regexMatch("^[a-z]+$", x)) || (x != "bar") {`,
+		`for arithValue(strconv.Itoa(utf8.RuneCountInString(x))) > arithValue(n) {`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGenerateRematch tests reading the text matched by [[ =~ ]] and its
+// subexpressions from BASH_REMATCH
+func TestGenerateRematch(t *testing.T) {
+	script := `v=release-1.24
+if [[ $v =~ ^release-([0-9]+)\.[0-9]+$ ]]; then
+  echo "major ${BASH_REMATCH[1]} of $BASH_REMATCH (${#BASH_REMATCH[@]})"
+fi
+[[ $v =~ x(y) ]] || echo "no match: [${BASH_REMATCH[1]}]"
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		`rematchAt("1")`,
+		`rematchAt("0")`,
+		`strconv.Itoa(len(rematch))`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Fatalf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+
+	if testing.Short() {
+		t.Skip("skipping running the generated program in short mode")
+	}
+	want := "major 1 of release-1.24 (2)\nno match: []\n"
+	if got := runScript(t, t.TempDir(), script); got != want {
+		t.Errorf("Expected output %q, got %q", want, got)
+	}
+}
+
+// TestGenerateAndOr tests short-circuiting command lists joined with && and ||
+func TestGenerateAndOr(t *testing.T) {
+	script := `[[ -z $name ]] && name=default
+cd /tmp || exit 1
+if [[ -n $name ]] && (( ${#name} > 3 )); then
+  echo long
+fi
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"if name == \"\" {\n\t\tname = \"default\"",
+		"if !(func() error {\n\t\tif err := os.Chdir(\"/tmp\"); err != nil {",
+		"return nil\n\t}() == nil) {\n\t\tos.Exit(1)",
+		"if (name != \"\") && (arithValue(strconv.Itoa(utf8.RuneCountInString(name))) > 3) {",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGenerateTrap tests that trap handlers run on signals and when the
+// script exits
+func TestGenerateTrap(t *testing.T) {
+	script := `trap 'echo bye' EXIT
+trap 'echo interrupted; exit 130' INT TERM
+trap '' HUP
+trap - USR1
+exit 2
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"defer runExitTrap()",
+		"exitTrap = func() error {\n\t\tfmt.Fprintln(stdio.Stdout, \"bye\")",
+		"setTrap(func() error {\n\t\tfmt.Fprintln(stdio.Stdout, \"interrupted\")\n\t\trunExitTrap()\n\t\tos.Exit(130)",
+		"}, syscall.SIGINT, syscall.SIGTERM)",
+		"ignoreTrap(syscall.SIGHUP)",
+		"resetTrap(syscall.SIGUSR1)",
+		"runExitTrap()\n\tos.Exit(2)",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGenerateGetopts tests translating the standard getopts loop into flags
+func TestGenerateGetopts(t *testing.T) {
+	script := `while getopts "vf:" opt; do
+  case $opt in
+    v) verbose=1 ;;
+    f) file=$OPTARG ;;
+    \?) echo usage; exit 2 ;;
+  esac
+done
+echo "$verbose $file $OPTIND"
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"optIndex, err := getoptsParse(os.Args[1:], \"vf:\", stdio.Stderr, func(optName, optArg string) error {\n\t\t\topt = optName\n\t\t\tOPTARG = optArg",
+		"case \"v\":\n\t\t\t\tverbose = \"1\"",
+		"case \"f\":\n\t\t\t\tfile = OPTARG",
+		"case \"?\":\n\t\t\t\tfmt.Fprintln(stdio.Stdout, \"usage\")\n\t\t\t\tos.Exit(2)",
+		"OPTIND = strconv.Itoa(optIndex)",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGetoptsBundled tests that the translated getopts loop takes bundled
+// options and arguments joined to their option, like getopts
+func TestGetoptsBundled(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping running the generated program in short mode")
+	}
+	script := `while getopts ":vn:" opt; do
+  case $opt in
+    v) verbose=yes ;;
+    n) name=$OPTARG ;;
+    :) echo "-$OPTARG needs a name" ;;
+    \?) echo "invalid -$OPTARG" ;;
+  esac
+done
+shift $((OPTIND - 1))
+echo "verbose=$verbose name=$name rest=$*"
+`
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-vn", "bar", "a", "b"}, "verbose=yes name=bar rest=a b\n"},
+		{[]string{"-nbar", "--", "-v"}, "verbose= name=bar rest=-v\n"},
+		{[]string{"-xv", "a"}, "invalid -x\nverbose=yes name= rest=a\n"},
+		{[]string{"-v", "-n"}, "-n needs a name\nverbose=yes name= rest=\n"},
+	} {
+		if got := runScript(t, t.TempDir(), script, tt.args...); got != tt.want {
+			t.Errorf("With %q, expected output %q, got %q", tt.args, tt.want, got)
+		}
+	}
+}
+
+// TestGeneratePositional tests translating positional parameters into args
+func TestGeneratePositional(t *testing.T) {
+	script := `greet() {
+  echo "hello $1 of $#"
+}
+greet "$@"
+greet world
+echo "$*"
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"var args = os.Args[1:]",
+		"func greet(stdio *streams, args []string) error {",
+		`fmt.Fprintln(stdio.Stdout, "hello "+positionalArg(args, 1)+" of "+strconv.Itoa(len(args)))`,
+		"if err := greet(stdio, args); err != nil {\n\t\treturn err",
+		`if err := greet(stdio, []string{"world"}); err != nil {`,
+		`fmt.Fprintln(stdio.Stdout, strings.Join(args, " "))`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGenerateFunctionNames tests that functions whose names are not Go
+// identifiers, or are taken in Go, are translated under identifiers that
+// are valid and distinct
+func TestGenerateFunctionNames(t *testing.T) {
+	script := `my-func() { echo dash; }
+my_func() { echo underscore; }
+log::info() { echo "$*"; }
+main() { my-func; my_func; log::info done; }
+main "$@"
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"func my_func(stdio *streams, args []string) error {",
+		"func my_func_2(stdio *streams, args []string) error {",
+		"func log_info(stdio *streams, args []string) error {",
+		"func main_2(stdio *streams, args []string) error {",
+		"if err := my_func_2(stdio, nil); err != nil {",
+		"if err := my_func(stdio, nil); err != nil {",
+		`if err := log_info(stdio, []string{"done"}); err != nil {`,
+		"if err := main_2(stdio, args); err != nil {",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGenerateTableVariables tests that variables no Go variable can be
+// named after, such as Go keywords and imported packages, are kept in the
+// shellVars table, and that invalid names are reported as unsupported
+func TestGenerateTableVariables(t *testing.T) {
+	script := `return=5
+echo "$return"
+for type in a b; do echo "$type"; done
+f() { local case=1; echo "$case"; }
+f
+os=linux
+echo "$os"
+for i-n in 1 2; do echo x; done
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		`setShellVar("return", "5")`,
+		`fmt.Fprintln(stdio.Stdout, shellVar("return"))`,
+		"for _, item := range strings.Fields(\"a b\") {\n\t\tsetShellVar(\"type\", item)\n",
+		"\tdefer setShellVar(\"case\", shellVar(\"case\"))\n\tsetShellVar(\"case\", \"\")\n\tsetShellVar(\"case\", \"1\")\n",
+		`setShellVar("os", "linux")`,
+		"// Unsupported for loop over invalid variable i-n",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGeneratePositionalExpansions tests that default values, pattern
+// removals, lengths and substrings of numbered positional parameters read
+// args instead of being left as literal text
+func TestGeneratePositionalExpansions(t *testing.T) {
+	script := `echo "${1:-default}" "${2-none}" "${1#a}" "${1%c}" "${#1}" "${1:1:1}" "${1:=x}"
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		`orDefault(positionalArg(args, 1), "default")`,
+		`argOrDefault(args, 2, "none")`,
+		`strings.TrimPrefix(positionalArg(args, 1), "a")`,
+		`strings.TrimSuffix(positionalArg(args, 1), "c")`,
+		"strconv.Itoa(utf8.RuneCountInString(positionalArg(args, 1)))",
+		"substring(positionalArg(args, 1), 1, 1)",
+		"assignArg(args, 1, true)",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, `"${`) {
+		t.Errorf("Generated code contains an unexpanded parameter:\n%s", code)
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGenerateShift tests that shift re-slices the positional parameters
+func TestGenerateShift(t *testing.T) {
+	script := `shift
+shift 2
+shift $((OPTIND - 1))
+shift $n
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"var args = os.Args[1:]",
+		"if len(args) >= 1 {\n\t\targs = args[1:]",
+		"if len(args) >= 2 {\n\t\targs = args[2:]",
+		"if n := arithValue(os.Getenv(\"OPTIND\")) - 1; n >= 0 && n <= len(args) {\n\t\targs = args[n:]",
+		"if n := arithValue(os.Getenv(\"n\")); n >= 0 && n <= len(args) {",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGenerateUnset tests that unset empties script variables, removes them
+// from the environment and deletes array elements
+func TestGenerateUnset(t *testing.T) {
+	script := `name=value
+IFS=,
+declare -A m=([a]=1)
+f() { echo f; }
+unset name IFS 'm[a]'
+unset -v HOME
+unset -f f
+unset 1
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"name = \"\"\n\tos.Unsetenv(\"name\")",
+		`IFS = " \t\n"`,
+		`delete(m, "a")`,
+		`os.Unsetenv("HOME")`,
+		"// unset -f f",
+		"// Unsupported unset 1",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, `exec.Command("unset"`) {
+		t.Errorf("Expected unset not to run as a command:\n%s", code)
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGenerateLocal tests that local variables are scoped to their function
+func TestGenerateLocal(t *testing.T) {
+	script := `x=global
+f() {
+  local x=inner
+  local unused
+  echo "$x"
+}
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"var x string\n",
+		"func f(stdio *streams, args []string) error {\n\tvar unused string\n\t_ = unused\n\tvar x string\n\tx = \"inner\"\n\tunused = \"\"",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+	if items := ir.Diagnostics.Items(); len(items) != 0 {
+		t.Errorf("Expected local to be supported, got %v", items)
+	}
+}
+
+// TestGenerateDeclare tests translating declare -i, declare -r and readonly
+func TestGenerateDeclare(t *testing.T) {
+	script := `declare -i n=2+3
+readonly NAME=app
+declare -ir MAX=10
+(( n++ ))
+n=$((n * 2))
+echo "$NAME $n"
+if (( n < MAX )); then echo small; fi
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"const MAX = 10\n",
+		"const NAME = \"app\"\n",
+		"var n int\n",
+		"\tn = 2 + 3\n",
+		"\tn = n + 1\n",
+		"\tn = n * 2\n",
+		"fmt.Fprintln(stdio.Stdout, NAME+\" \"+strconv.Itoa(n))",
+		"if n < MAX {",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+	if items := ir.Diagnostics.Items(); len(items) != 0 {
+		t.Errorf("Expected declarations to be supported, got %v", items)
+	}
+}
+
+// TestGenerateAppend tests translating += into addition for integer
+// variables and concatenation for others, without exporting them
+func TestGenerateAppend(t *testing.T) {
+	script := `declare -i n=2
+n+=3
+s=ab
+s+=x
+declare -A m=([a]=1)
+m[a]+=2
+m+=([b]=3)
+echo "$n $s ${m[a]}"
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"\tn += 3\n",
+		"\ts += \"x\"\n",
+		"\tm[\"a\"] += \"2\"\n",
+		"\tmaps.Copy(m, map[string]string{\"b\": \"3\"})\n",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "os.Setenv") {
+		t.Errorf("Expected += not to export the variables:\n%s", code)
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGenerateNounset tests that set -u makes reading unset variables fail
+func TestGenerateNounset(t *testing.T) {
+	script := `set -u
+echo "$HOME $1 ${EDITOR:-vi}"
+set +u
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"\tshellOptions[\"nounset\"] = true\n",
+		`fmt.Fprintln(stdio.Stdout, nounsetEnv("HOME")+" "+nounsetArg(args, 1)+" "+orDefault(os.Getenv("EDITOR"), "vi"))`,
+		"\tshellOptions[\"nounset\"] = false\n",
+		"func unboundVariable(name string) {",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+	if items := ir.Diagnostics.Items(); len(items) != 0 {
+		t.Errorf("Expected set -u to be supported, got %v", items)
+	}
+}
+
+// TestGeneratePipefail tests running pipelines natively with set -o pipefail
+func TestGeneratePipefail(t *testing.T) {
+	script := `set -o pipefail
+grep -h error *.log | sort | uniq -c
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"\tshellOptions[\"pipefail\"] = true\n",
+		"errs := runPipeline(stdio,\n\t\t\tpipelineStage{cmd: exec.Command(\"grep\", append([]string{\"-h\", \"error\"}, globExpand(\"*.log\")...)...)},\n\t\t\tpipelineStage{run: func(stdio *streams) error {\n\t\t\t\tif err := sortLines(stdio, stdio.stdinReader(), sortOptions{}); err != nil {",
+		"pipelineStage{run: func(stdio *streams) error {\n\t\t\t\tif err := uniq(stdio, stdio.stdinReader(), uniqOptions{count: true}); err != nil {",
+		"if err := pipelineStatus(errs); err != nil {",
+		"func pipelineStatus(errs []error) error {",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+}
+
+// TestGeneratePipelineStages tests pipelines mixing functions and builtins,
+// which run as Go code, with external commands
+func TestGeneratePipelineStages(t *testing.T) {
+	script := `list() {
+  echo b
+  echo a
+}
+list | sort -r
+echo hello | tr a-z A-Z > upper.txt
+ls /missing 2>&1 | wc -l
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"pipelineStage{run: func(stdio *streams) error {\n\t\t\t\tif err := list(stdio, nil); err != nil {",
+		"pipelineStage{run: func(stdio *streams) error {\n\t\t\t\tif err := sortLines(stdio, stdio.stdinReader(), sortOptions{reverse: true}); err != nil {",
+		"pipelineStage{run: func(stdio *streams) error {\n\t\t\t\tfmt.Fprintln(stdio.Stdout, \"hello\")\n\t\t\t\treturn nil\n\t\t\t}},",
+		"// Redirect > upper.txt",
+		"cmd.Stderr = cmd.Stdout",
+		"func runPipeline(stdio *streams, stages ...pipelineStage) []error {",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "gexe") {
+		t.Errorf("Generated code runs the pipelines through gexe:\n%s", code)
+	}
+	if got := gen.Metrics().ExecFallbacks; got != 1 {
+		t.Errorf("ExecFallbacks = %d, want 1", got)
+	}
+}
+
+// TestPipelineLargeInput tests running a pipeline whose Go stages, before
+// and after an external command, stream more input than a pipe buffers
+func TestPipelineLargeInput(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping go run in short mode")
+	}
+	if _, err := exec.LookPath("rev"); err != nil {
+		t.Skip("rev is not installed")
+	}
+
+	dir := t.TempDir()
+	var input strings.Builder
+	matching := 0
+	for i := 1; i <= 50000; i++ {
+		line := strconv.Itoa(i)
+		input.WriteString(line + "\n")
+		if strings.Contains(line, "1") {
+			matching++
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "big.txt"), []byte(input.String()), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	output := runScript(t, dir, "cat big.txt | rev | grep 1 | wc -l\n")
+	if got, want := strings.TrimSpace(output), strconv.Itoa(matching); got != want {
+		t.Errorf("Pipeline output = %q, want %q", got, want)
+	}
+}
+
+// TestGenerateGrep tests translating common grep invocations into Go, and
+// executing the others
+func TestGenerateGrep(t *testing.T) {
+	script := `grep -iv error app.log
+cat app.log | grep -qE 'warn|err'
+grep -c -F -e a.b -e x *.txt
+grep -n error app.log
+grep '\(ab\)\1' app.log
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		`if err := grep(stdio, stdio.stdinReader(), grepOptions{ignoreCase: true, invert: true}, "error", "app.log"); err != nil {`,
+		`if err := grep(stdio, stdio.stdinReader(), grepOptions{quiet: true, extended: true}, "warn|err"); err != nil {`,
+		`grep(stdio, stdio.stdinReader(), grepOptions{count: true, fixed: true}, "a.b"+"\n"+"x", globExpand("*.txt")...)`,
+		"// Execute command: grep -n error app.log",
+		`// Execute command: grep \(ab\)\1 app.log`,
+		"func posixRegexp(pattern string, extended bool) string {",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+}
+
+// TestGenerateSed tests translating sed substitutions into Go, and executing
+// other sed scripts
+func TestGenerateSed(t *testing.T) {
+	script := `sed 's/foo/bar/g' in.txt
+echo a.b | sed -E -e 's/(a)\.(b)/\2 & \1/' -e 's|x/y|$|i'
+sed -i 's/^#//' *.conf
+sed -n '/start/,/end/p' in.txt
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		`sed(stdio, stdio.stdinReader(), false, false, []sedSubstitution{{pattern: "foo", replacement: "bar", global: true}}, "in.txt")`,
+		`sed(stdio, stdio.stdinReader(), true, false, []sedSubstitution{{pattern: "(a)\\.(b)", replacement: "${2} ${0} ${1}"}, {pattern: "x/y", replacement: "$$", ignoreCase: true}})`,
+		`sed(stdio, stdio.stdinReader(), false, true, []sedSubstitution{{pattern: "^#", replacement: ""}}, globExpand("*.conf")...)`,
+		"// Execute command: sed -n /start/,/end/p in.txt",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+}
+
+// TestGenerateAwk tests translating awk one-liners into Go, and executing
+// other awk programs
+func TestGenerateAwk(t *testing.T) {
+	script := `awk '{print $2}' data.txt
+cut_users() { awk -F: '$3 >= 1000 {print $1, "uid=" $3}' /etc/passwd; }
+awk '/error/ && NR > 1' app.log
+echo '$HOME'
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"awk(stdio, stdio.stdinReader(), \" \", func(r *awkRecord) (string, bool) {\n\t\treturn r.field(2), true\n\t}, \"data.txt\")",
+		"awk(stdio, stdio.stdinReader(), \":\", func(r *awkRecord) (string, bool) {\n\t\treturn r.field(1) + \" \" + \"uid=\" + r.field(3), awkCompare(r.field(3), \"1000\") >= 0\n\t}, \"/etc/passwd\")",
+		"// Execute command: awk /error/ && NR > 1 app.log",
+		`fmt.Fprintln(stdio.Stdout, "$HOME")`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+}
+
+// TestGenerateFind tests translating find into a walk of the directory
+// trees, with the tests and actions it supports
+func TestGenerateFind(t *testing.T) {
+	script := `find /var/log -type f -name '*.log' -mtime +7 -delete
+find . -name '*.tmp' -exec rm -f {} \;
+find src -name "$pattern" -exec wc -l {} +
+find . -maxdepth 1 -name '*.go'
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		`find(stdio, findOptions{names: []string{"*.log"}, kind: "f", mtime: "+7", delete: true}, "/var/log")`,
+		`find(stdio, findOptions{names: []string{"*.tmp"}, exec: []string{"rm", "-f", "{}"}}, ".")`,
+		`find(stdio, findOptions{names: []string{os.Getenv("pattern")}, exec: []string{"wc", "-l", "{}"}, batch: true}, "src")`,
+		"// Execute command: find . -maxdepth 1 -name *.go",
+		"filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+}
+
+// TestGenerateCut tests translating cut with lists of fields and of bytes
+// into Go
+func TestGenerateCut(t *testing.T) {
+	script := `cut -d: -f1,3 /etc/passwd
+cut -c1-8 <<< "$line"
+cut -s -f2 data.tsv
+cut --complement -f1 data.tsv
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		`cut(stdio, stdio.stdinReader(), cutOptions{list: "1,3", fields: true, delim: ":"}, "/etc/passwd")`,
+		`cut(stdio, strings.NewReader(os.Getenv("line")+"\n"), cutOptions{list: "1-8"})`,
+		`cut(stdio, stdio.stdinReader(), cutOptions{list: "2", fields: true, delim: "\t", onlyDelimited: true}, "data.tsv")`,
+		"// Execute command: cut --complement -f1 data.tsv",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+}
+
+// TestGenerateSort tests translating sort, alone and in pipelines, into Go
+func TestGenerateSort(t *testing.T) {
+	script := `sort names.txt
+cat sizes.txt | sort -nr
+sort -u -n a.txt b.txt
+sort -k2 -t, data.csv
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		`sortLines(stdio, stdio.stdinReader(), sortOptions{}, "names.txt")`,
+		`sortLines(stdio, stdio.stdinReader(), sortOptions{numeric: true, reverse: true})`,
+		`sortLines(stdio, stdio.stdinReader(), sortOptions{unique: true, numeric: true}, "a.txt", "b.txt")`,
+		"sort.SliceStable(lines, func(i, j int) bool {",
+		"// Execute command: sort -k2 -t, data.csv",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+}
+
+// TestGenerateUniq tests translating uniq, alone and in pipelines, into Go
+func TestGenerateUniq(t *testing.T) {
+	script := `sort words.txt | uniq -c
+uniq -d sorted.txt
+uniq in.txt out.txt
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"pipelineStage{run: func(stdio *streams) error {\n\t\t\t\tif err := uniq(stdio, stdio.stdinReader(), uniqOptions{count: true}); err != nil {",
+		`uniq(stdio, stdio.stdinReader(), uniqOptions{repeated: true}, "sorted.txt")`,
+		"// Execute command: uniq in.txt out.txt",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+}
+
+// TestGenerateHeadTail tests translating head and tail into Go, with tail -f
+// only translated when polling is enabled
+func TestGenerateHeadTail(t *testing.T) {
+	script := `head -n 5 access.log
+ps aux | head -3
+tail -n +2 "$report"
+tail -f app.log
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		`head(stdio, stdio.stdinReader(), "5", "access.log")`,
+		"pipelineStage{run: func(stdio *streams) error {\n\t\t\t\tif err := head(stdio, stdio.stdinReader(), \"3\"); err != nil {",
+		`tail(stdio, stdio.stdinReader(), "+2", false, os.Getenv("report"))`,
+		"// Execute command: tail -f app.log",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+
+	gen = generator.NewGoCodeGenerator(ir)
+	gen.Options.PollTail = true
+	code, err = gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if want := `tail(stdio, stdio.stdinReader(), "10", true, "app.log")`; !strings.Contains(code, want) {
+		t.Errorf("Generated code does not contain %q:\n%s", want, code)
+	}
+}
+
+// TestGenerateTr tests translating tr with literal sets into Go
+func TestGenerateTr(t *testing.T) {
+	script := `echo "$name" | tr '[:lower:]' '[:upper:]'
+tr -d '\n' < list.txt
+tr -s ' ' <<< "$line"
+tr -c a-z _
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		`tr(stdio, stdio.stdinReader(), trOptions{set1: "abcdefghijklmnopqrstuvwxyz", set2: "ABCDEFGHIJKLMNOPQRSTUVWXYZ"})`,
+		`tr(stdio, stdio.stdinReader(), trOptions{set1: "\n", delete: true})`,
+		`tr(stdio, strings.NewReader(os.Getenv("line")+"\n"), trOptions{set1: " ", squeeze: true})`,
+		"// Execute command: tr -c a-z _",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+}
+
+// TestGenerateWc tests translating wc, alone and in pipelines, into Go
+func TestGenerateWc(t *testing.T) {
+	script := `lines=$(grep -c x f | wc -l)
+ls | wc -l
+wc -w -c notes.txt
+wc -m notes.txt
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"pipelineStage{run: func(stdio *streams) error {\n\t\t\t\tif err := wc(stdio, stdio.stdinReader(), wcOptions{lines: true}); err != nil {",
+		`wc(stdio, stdio.stdinReader(), wcOptions{words: true, bytes: true}, "notes.txt")`,
+		"// Execute command: wc -m notes.txt",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+}
+
+// TestGenerateCat tests translating cat into Go copying its files or its
+// standard input
+func TestGenerateCat(t *testing.T) {
+	script := `cat header.txt "$body" > page.html
+cat | gzip > out.gz
+cat -n notes.txt
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		`cat(stdio, stdio.stdinReader(), "header.txt", os.Getenv("body"))`,
+		"pipelineStage{run: func(stdio *streams) error {\n\t\t\t\tif err := cat(stdio, stdio.stdinReader()); err != nil {",
+		"// Execute command: cat -n notes.txt",
+		"io.Copy(stdio.Stdout, input)",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+}
+
+// TestGenerateMv tests translating mv into Go code renaming or copying
+// the files
+func TestGenerateMv(t *testing.T) {
+	script := `mv -f build/app "$dest"
+mv *.log archive/
+mv -i old.txt new.txt
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		`mv(stdio, "build/app", os.Getenv("dest"))`,
+		`mv(stdio, append(globExpand("*.log"), []string{"archive/"}...)...)`,
+		"// Execute command: mv -i old.txt new.txt",
+		"os.Rename(source, dest)",
+		"errors.Is(err, syscall.EXDEV)",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+}
+
+// TestGenerateTouch tests translating touch into Go code creating the files
+// or updating their times
+func TestGenerateTouch(t *testing.T) {
+	script := `touch "$stamp" ready.flag
+touch -c cache.db
+touch -d yesterday old.txt
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		`touch(stdio, false, os.Getenv("stamp"), "ready.flag")`,
+		`touch(stdio, true, "cache.db")`,
+		"// Execute command: touch -d yesterday old.txt",
+		"os.Chtimes(name, now, now)",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+}
+
+// TestGenerateExternalCommand tests that external commands run with
+// exec.Command, sharing the standard streams of the program, and build
+func TestGenerateExternalCommand(t *testing.T) {
+	script := `ls /tmp/x
+ls -l
+'x", "a"); println("INJECTED"); cmd = exec.Command("x' arg
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"// Execute command: ls /tmp/x\n\t{\n\t\tcmd := exec.Command(\"ls\", \"/tmp/x\")",
+		"cmd.Stdin, cmd.Stdout, cmd.Stderr = stdio.Stdin, stdio.Stdout, stdio.Stderr",
+		`exec.Command("ls", "-l")`,
+		`exec.Command("x\", \"a\"); println(\"INJECTED\"); cmd = exec.Command(\"x", "arg")`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "exe.Run") || strings.Contains(code, "\tprintln(") {
+		t.Errorf("Generated code runs commands with gexe or the command name as code:\n%s", code)
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGenerateWait tests running commands as background jobs and waiting
+// for them
+func TestGenerateWait(t *testing.T) {
+	script := `sleep 1 &
+pid=$!
+wait "$pid"
+sleep 2 &
+wait
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"\tsetShellVar(\"!\", strconv.Itoa(startJob(stdio, func(stdio *streams) error {\n\t\t// Execute command: sleep 1\n\t\t{\n\t\t\tcmd := exec.Command(\"sleep\", \"1\")",
+		"\tpid = shellVar(\"!\")\n",
+		"\tif err := waitJob(pid); err != nil {\n\t\treturn fmt.Errorf(\"line 3: wait failed: %w\", err)\n\t}",
+		"exec.Command(\"sleep\", \"2\")",
+		"\twaitJobs()\n",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestBackgroundJobStreams tests that the redirections of a background job
+// do not apply to the script, whose output goes to its standard output while
+// the job runs
+func TestBackgroundJobStreams(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping go run in short mode")
+	}
+	if _, err := exec.LookPath("mkfifo"); err != nil {
+		t.Skip("mkfifo is not installed")
+	}
+
+	script := `mkfifo fifo
+cat fifo > job.txt &
+echo main
+echo data > fifo
+wait
+cat job.txt
+`
+	if got, want := runScript(t, t.TempDir(), script), "main\ndata\n"; got != want {
+		t.Errorf("Output = %q, want %q", got, want)
+	}
+}
+
+// TestBackgroundJobVariables tests that background jobs take the values the
+// variables they use have when they start, like the loop variable here, and
+// that their assignments do not change the variables of the script
+func TestBackgroundJobVariables(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping go run in short mode")
+	}
+
+	script := `for i in 1 2 3; do
+  echo "job $i" >job$i.txt &
+done
+wait
+cat job1.txt job2.txt job3.txt
+n=0
+read n &
+wait
+echo "n=$n"
+`
+	if got, want := runScript(t, t.TempDir(), script), "job 1\njob 2\njob 3\nn=0\n"; got != want {
+		t.Errorf("Output = %q, want %q", got, want)
+	}
+}
+
+// TestGenerateBackgroundExit tests waiting for the background jobs still
+// running when the script ends or exits
+func TestGenerateBackgroundExit(t *testing.T) {
+	script := `trap 'echo bye' EXIT
+sleep 1 &
+echo "$?"
+exit 2
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"\tdefer waitJobs()\n\tdefer runExitTrap()\n",
+		"\trunExitTrap()\n\twaitJobs()\n\tos.Exit(2)\n",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+
+	// Scripts without background commands do not wait
+	result, err = parser.ParseBashString("exit 2\n")
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err = parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+	code, err = generator.NewGoCodeGenerator(ir).Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if strings.Contains(code, "waitJobs") {
+		t.Errorf("Generated code waits for jobs without background commands:\n%s", code)
+	}
+}
+
+// TestGenerateSubshellIsolation tests generating subshells that restore
+// the variables they assign, the working directory and the environment, and
+// whose exit ends only the subshell
+func TestGenerateSubshellIsolation(t *testing.T) {
+	script := `declare -i count=1
+name=outer
+(
+  name=inner
+  count=2
+  cd /tmp
+  exit 3
+)
+echo "$name $count $?"
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"\tif err := subshell(func() error {\n\t\tdefer func(saved int) { count = saved }(count)\n\t\tdefer func(saved string) { name = saved }(name)\n",
+		"\t\tsetShellVar(\"?\", strconv.Itoa(3))\n\t\treturn exitError(3)\n",
+		"\t}); err != nil {\n\t\tsetShellVar(\"?\", strconv.Itoa(exitStatus(err)))\n\t\treturn err\n\t}\n\tsetShellVar(\"?\", \"0\")\n",
+		"func subshell(commands func() error) error {",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "os.Exit(3)") {
+		t.Errorf("exit in a subshell ends the program:\n%s", code)
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGenerateExitStatus tests recording the exit status of every command
+// in $? for scripts that read it, failing commands ending the script outside
+// conditions whether it reads $? or not, and return ending functions with a
+// status and no unreachable code after it
+func TestGenerateExitStatus(t *testing.T) {
+	script := `check() {
+  return 3
+}
+greet() {
+  echo hi
+  return 0
+}
+check || echo "check failed with $?"
+cd /tmp
+name=value
+(( 1 > 2 ))
+exit $?
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"func check(stdio *streams, args []string) error {\n\tsetShellVar(\"?\", \"3\")\n\treturn exitError(3)\n}\n",
+		"func greet(stdio *streams, args []string) error {\n\tfmt.Fprintln(stdio.Stdout, \"hi\")\n\tsetShellVar(\"?\", \"0\")\n\treturn nil\n}\n",
+		"\t\tif err := check(stdio, nil); err != nil {\n\t\t\treturn err\n\t\t}\n",
+		"\t\tfmt.Fprintln(stdio.Stdout, \"check failed with \"+shellVar(\"?\"))\n",
+		"\tif err := func() error {\n\t\tif err := os.Chdir(\"/tmp\"); err != nil {\n",
+		"\t}(); err != nil {\n\t\tsetShellVar(\"?\", strconv.Itoa(exitStatus(err)))\n\t\treturn err\n\t}\n\tsetShellVar(\"?\", \"0\")\n",
+		"\tname = \"value\"\n\tsetShellVar(\"?\", \"0\")\n",
+		"\tsetShellVar(\"?\", strconv.Itoa(boolInt(!(1 > 2))))\n",
+		"os.Exit(exitArg(shellVar(\"?\")))",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+
+	// Without $?, return N fails the function
+	result, err = parser.ParseBashString("check() {\n  return 3\n}\ncheck\n")
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err = parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+	gen = generator.NewGoCodeGenerator(ir)
+	code, err = gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if want := "\treturn exitError(3)\n}\n"; !strings.Contains(code, want) {
+		t.Errorf("Generated code does not contain %q:\n%s", want, code)
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+
+	// A failing command ends the script the same way whether it reads $? or not
+	for _, script := range []string{"ls /missing\necho done\n", "ls /missing\necho \"done $?\"\n"} {
+		result, err := parser.ParseBashString(script)
+		if err != nil {
+			t.Fatalf("ParseBashString failed: %v", err)
+		}
+		ir, err := parser.BuildIR(result)
+		if err != nil {
+			t.Fatalf("BuildIR failed: %v", err)
+		}
+		code, err := generator.NewGoCodeGenerator(ir).Generate()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		if want := "return fmt.Errorf(\"line 1: ls failed: %w\", err)"; !strings.Contains(code, want) {
+			t.Errorf("Generated code for %q does not contain %q:\n%s", script, want, code)
+		}
+	}
+}
+
+// TestGenerateSetErrexit tests that set -e needs no code, since failing
+// commands end the script already, while set +e, which cannot be honoured,
+// is reported
+func TestGenerateSetErrexit(t *testing.T) {
+	result, err := parser.ParseBashString("set -e\nset +e\n")
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+	if _, err := generator.NewGoCodeGenerator(ir).Generate(); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	items := ir.Diagnostics.Items()
+	if len(items) != 1 || items[0].Line != 2 || !strings.Contains(items[0].Message, "set +e") {
+		t.Errorf("Expected one diagnostic for set +e on line 2, got %v", items)
+	}
+}
+
+// TestGenerateTestBuiltin tests generating test and [ ] as native conditions
+func TestGenerateTestBuiltin(t *testing.T) {
+	script := `if [ "$a" = "*" ]; then echo same; fi
+if [ $n -gt 2 -a ! -f "$f" ]; then echo big; fi
+if test -z "$a" -o "$a" != x; then echo other; fi
+[ -d /tmp ]
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		`if os.Getenv("a") == "*" {`,
+		`if (arithValue(os.Getenv("n")) > 2) && (!(fileTest("-f", os.Getenv("f")))) {`,
+		`if (os.Getenv("a") == "") || (os.Getenv("a") != "x") {`,
+		`_ = fileTest("-d", "/tmp")`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "exe.Run") {
+		t.Errorf("Generated code runs test through gexe:\n%s", code)
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGenerateExecStreams tests running external commands with the
+// standard streams of the program rather than combining their output
+func TestGenerateExecStreams(t *testing.T) {
+	script := `make build
+echo "$?"
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	want := `cmd := exec.Command("make", "build")
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = stdio.Stdin, stdio.Stdout, stdio.Stderr
+		if err := cmd.Run(); err != nil {
+			setShellVar("?", strconv.Itoa(exitStatus(err)))
+			return fmt.Errorf("line 1: make failed: %w", err)
+		}
+		setShellVar("?", "0")`
+	if !strings.Contains(code, want) {
+		t.Errorf("Generated code does not contain %q:\n%s", want, code)
+	}
+	if strings.Contains(code, "CombinedOutput") {
+		t.Errorf("Generated code combines the output streams:\n%s", code)
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGenerateBlock tests generating brace groups as inline blocks whose
+// redirections apply to every command of the group
+func TestGenerateBlock(t *testing.T) {
+	script := `{ echo start; echo more; } > build.log 2>&1
+{ echo plain; }
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"\t\tfile, err := os.OpenFile(\"build.log\", os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0644)\n",
+		"\t\tdefer file.Close()\n\t\tstdio := stdio.redirected()\n\t\tstdio.Stdout = file\n",
+		"\t\tstdio.Stderr = stdio.Stdout\n\t\tfmt.Fprintln(stdio.Stdout, \"start\")\n\t\tfmt.Fprintln(stdio.Stdout, \"more\")\n",
+		"\t{\n\t\t// Command group\n\t\tfmt.Fprintln(stdio.Stdout, \"plain\")\n",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if strings.Count(code, "fmt.Fprintln(stdio.Stdout, \"start\")") != 1 {
+		t.Errorf("Expected the group to run once:\n%s", code)
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+	if n := len(ir.Diagnostics.Items()); n != 0 {
+		t.Errorf("Expected no diagnostics, got %v", ir.Diagnostics.Items())
+	}
+}
+
+// TestGeneratePrefixAssignments tests that assignments prefixed to commands
+// only apply while they run
+func TestGeneratePrefixAssignments(t *testing.T) {
+	script := `FOO=bar printenv FOO
+IFS=, read -r x y <<< "1,2"
+greet() { echo "$GREETING"; }
+GREETING=hi greet
+LC_ALL=C sort data.txt | uniq -c
+while read -r line; do echo "$line"; done < in.txt
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		`cmd.Env = append(os.Environ(), "FOO="+"bar")`,
+		`readVars(stdio, bufio.NewReader(strings.NewReader("1,2\n")), "", true, ",", &x, &y)`,
+		"restore0 := setEnv(\"GREETING\", \"hi\")\n\t\tif err := greet(stdio, nil); err != nil {",
+		`restore0 := setEnv("LC_ALL", "C")`,
+		"stdio.Stdin = file\n\t\tfor func() error {",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	for _, unwanted := range []string{"FOO = ", "IFS = ", "var FOO", "var IFS"} {
+		if strings.Contains(code, unwanted) {
+			t.Errorf("Generated code contains %q:\n%s", unwanted, code)
+		}
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGenerateNegation tests inverting the exit status of pipelines
+// prefixed with !
+func TestGenerateNegation(t *testing.T) {
+	script := `if ! cmp -s a b; then
+  echo differ
+fi
+! cmp -s a b
+echo "status $?"
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"\tif !(func() error {\n",
+		"cmd := exec.Command(\"cmp\", \"-s\", \"a\", \"b\")",
+		"\t}() == nil) {\n\t\tfmt.Fprintln(stdio.Stdout, \"differ\")\n\t\tsetShellVar(\"?\", \"0\")\n\t}\n",
+		"\t}() == nil {\n\t\tsetShellVar(\"?\", \"1\")\n\t} else {\n\t\tsetShellVar(\"?\", \"0\")\n\t}\n",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGenerateRead tests translating read into reads of the buffered
+// standard input
+func TestGenerateRead(t *testing.T) {
+	script := `read -p "Name: " name
+read -r first rest
+read
+read a b <<< "$name"
+read -a items
+echo "$name $first $rest $REPLY $a $b"
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"\tif err := readVars(stdio, stdio.stdinReader(), \"Name: \", false, \" \\t\\n\", &name); err != nil {\n\t\treturn fmt.Errorf(\"line 1: read failed: %w\", err)\n\t}",
+		"readVars(stdio, stdio.stdinReader(), \"\", true, \" \\t\\n\", &first, &rest)",
+		"readReply(stdio, stdio.stdinReader(), \"\", false, &REPLY)",
+		"readVars(stdio, bufio.NewReader(strings.NewReader(name+\"\\n\")), \"\", false, \" \\t\\n\", &a, &b)",
+		"// Unsupported read: -a items",
+		"var name string\n",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+	if msgs := ir.Diagnostics.Items(); len(msgs) != 1 || msgs[0].Message != "read -a is not supported" {
+		t.Errorf("Expected read -a to be reported, got %v", msgs)
+	}
+}
+
+// TestGeneratePrintf tests translating printf formats into fmt.Printf
+// calls, reusing the format while arguments remain
+func TestGeneratePrintf(t *testing.T) {
+	script := `count=3
+printf "%s: %d\n" "$USER" "$count"
+printf '%-4s|%05.1f|%q\n' a 2.5 'b c' d
+printf '%s\n' "$@"
+printf "100%%\n"
+printf "$fmt" x
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"\t\tinvalidNumber := false\n" +
+			"\t\tfmt.Fprintf(stdio.Stdout, \"%s: %d\\n\", os.Getenv(\"USER\"), printfInt(stdio, count, &invalidNumber))\n" +
+			"\t\tif err := printfStatus(invalidNumber); err != nil {\n",
+		"\tfmt.Fprintf(stdio.Stdout, \"%-4s|%05.1f|%s\\n\", \"a\", float64(2.5), shellQuote(\"b c\"))\n" +
+			"\tfmt.Fprintf(stdio.Stdout, \"%-4s|%05.1f|%s\\n\", \"d\", float64(0), shellQuote(\"\"))\n",
+		"\tfor _, values := range printfArgs(args, 1) {\n\t\tfmt.Fprintf(stdio.Stdout, \"%s\\n\", values[0])\n\t}\n",
+		"\tfmt.Fprint(stdio.Stdout, \"100%\\n\")\n",
+		"exec.Command(\"printf\", os.Getenv(\"fmt\"), \"x\")",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestPrintfInvalidNumber tests that printf prints invalid numbers as 0 and
+// then fails with status 1, like in Bash
+func TestPrintfInvalidNumber(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping running a generated program in short mode")
+	}
+	script := `n=abc
+printf '%d|%.1f\n' "$n" 1.5 || echo "status $?"
+printf '%d\n' "$@" || echo "args $?"
+printf '%d\n' 7 && echo ok
+`
+	want := "0|1.5\nstatus 1\n1\n0\nargs 1\n7\nok\n"
+	if got := runScript(t, t.TempDir(), script, "1", "x"); got != want {
+		t.Errorf("Expected output %q, got %q", want, got)
+	}
+}
+
+// TestGenerateSplit tests splitting unquoted expansions at $IFS
+func TestGenerateSplit(t *testing.T) {
+	script := `LIST="a b  c"
+for x in $LIST; do
+  echo "$x"
+done
+echo $LIST "$LIST"
+IFS=,
+printf '%s\n' $LIST
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"var IFS = \" \\t\\n\"\n",
+		"func splitFields(s, ifs string) []string {",
+		"\tfor _, x = range splitFields(LIST, IFS) {\n",
+		"\tfmt.Fprintln(stdio.Stdout, strings.Join(append(splitFields(LIST, IFS), []string{LIST}...), \" \"))\n",
+		"range printfArgs(splitFields(LIST, IFS), 1)",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestSplitUnsetIFS tests that unset IFS restores splitting at blanks and
+// that unset variables read as unset when the program runs
+func TestSplitUnsetIFS(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping running the generated program in short mode")
+	}
+	script := `LIST="a,b c"
+IFS=,
+for x in $LIST; do echo "<$x>"; done
+unset IFS
+for x in $LIST; do echo "[$x]"; done
+unset LIST
+echo "${LIST:-gone}"
+export TOKEN=secret
+unset TOKEN
+echo "${TOKEN-unset}"
+`
+	want := "<a>\n<b c>\n[a,b]\n[c]\ngone\nunset\n"
+	if got := runScript(t, t.TempDir(), script); got != want {
+		t.Errorf("Expected output %q, got %q", want, got)
+	}
+}
+
+// TestGenerateCommandRedirects tests redirecting the streams of commands
+func TestGenerateCommandRedirects(t *testing.T) {
+	script := `make > build.log 2>&1
+ls missing 2> /dev/null
+echo "warning" >&2
+echo "done" &>> build.log
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"\t\tcmd := exec.Command(\"make\")\n" +
+			"\t\tcmd.Stdin, cmd.Stdout, cmd.Stderr = stdio.Stdin, stdio.Stdout, stdio.Stderr\n" +
+			"\t\t// Redirect > build.log\n" +
+			"\t\tfile, err := os.OpenFile(\"build.log\", os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0644)\n",
+		"\t\tcmd.Stdout = file\n\t\tcmd.Stderr = cmd.Stdout\n\t\tif err := cmd.Run(); err != nil {\n",
+		"\t\tfile, err := os.OpenFile(\"/dev/null\", os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0644)\n",
+		"\t\tcmd.Stderr = file\n",
+		"\t\tstdio := stdio.redirected()\n\t\tstdio.Stdout = stdio.Stderr\n\t\tfmt.Fprintln(stdio.Stdout, \"warning\")\n",
+		"os.OpenFile(\"build.log\", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)",
+		"\t\tstdio.Stderr = file\n\t\tfmt.Fprintln(stdio.Stdout, \"done\")\n",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "exe.Run") {
+		t.Errorf("Expected redirected commands to run through exec.Command:\n%s", code)
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGenerateExec tests replacing the program and redirecting its streams
+// with exec
+func TestGenerateExec(t *testing.T) {
+	script := `exec > out.log 2>&1
+exec 3< in.txt
+exec make "$target" > build.log
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"\t\tfile, err := os.OpenFile(\"out.log\", os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0644)\n",
+		"\t\tstdio.Stdout = file\n\t\tstdio.Stderr = stdio.Stdout\n\t}\n",
+		"// Unsupported redirection: 3< in.txt",
+		"\t\tstdio := stdio.redirected()\n\t\tstdio.Stdout = file\n\t\tif err := execProcess(stdio, []string{\"make\", os.Getenv(\"target\")}); err != nil {\n",
+		"return syscall.Exec(path, argv, os.Environ())",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "defer file.Close()\n\t\tstdio.Stdout = file\n\t\tstdio.Stderr = stdio.Stdout") {
+		t.Errorf("Expected the files of exec to stay open:\n%s", code)
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGeneratePipestatus tests recording the exit statuses of pipelines in PIPESTATUS
+func TestGeneratePipestatus(t *testing.T) {
+	script := `grep error app.log | sort | uniq -c
+echo "${PIPESTATUS[0]} ${PIPESTATUS[@]} ${#PIPESTATUS[@]} $PIPESTATUS $?"
+for status in "${PIPESTATUS[@]}"; do
+  echo "$status"
+done
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"var pipestatus []int",
+		"\t\tsetPipestatus(errs)\n",
+		`pipestatusAt("0")+" "+strings.Join(pipestatusList(), " ")+" "+strconv.Itoa(len(pipestatus))+" "+pipestatusAt("0")+" "+shellVar("?")`,
+		"range pipestatusList()",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGenerateHeredoc tests feeding here-documents to standard input
+func TestGenerateHeredoc(t *testing.T) {
+	script := "name=world\n" +
+		"cat <<EOF\nHello, $name \\$HOME\nEOF\n" +
+		"cat <<'EOF'\nraw $name\nEOF\n" +
+		"cat <<-EOF | tr a-z A-Z\n\tindented $name\n\tEOF\n" +
+		"read -r first rest <<EOF\n$name says hi\nEOF\n" +
+		"echo \"$?\"\n"
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		`cat(stdio, strings.NewReader("Hello, "+name+" $HOME\n"))`,
+		`cat(stdio, strings.NewReader("raw $name\n"))`,
+		`cat(stdio, strings.NewReader("indented "+name+"\n"))`,
+		`bufio.NewReader(strings.NewReader(name+" says hi\n"))`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "Unsupported redirection") {
+		t.Errorf("Expected the here-documents to be supported:\n%s", code)
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGenerateNestedControlFlow tests generating statements nested in the
+// bodies of compound commands
+func TestGenerateNestedControlFlow(t *testing.T) {
+	script := `for i in 1 2 3; do
+  if [ -n "$i" ]; then
+    for j in a b; do
+      last="$i$j"
+      echo "$last"
+    done
+  fi
+  (( n++ ))
+done
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		"if i != \"\" {",
+		"for _, j = range",
+		"last = i + j",
+		"fmt.Fprintln(stdio.Stdout, last)",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if n := strings.Count(code, "fmt.Fprintln(stdio.Stdout, last)"); n != 1 {
+		t.Errorf("Expected the nested echo once, got %d times:\n%s", n, code)
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGenerateElif tests generating elif clauses as an else if chain
+func TestGenerateElif(t *testing.T) {
+	script := `if [ -d "$f" ]; then
+  echo dir
+elif [ -z "$f" ]; then
+  echo empty
+else
+  echo other
+fi
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	want := `if fileTest("-d", os.Getenv("f")) {
+		fmt.Fprintln(stdio.Stdout, "dir")
+	} else if os.Getenv("f") == "" {
+		fmt.Fprintln(stdio.Stdout, "empty")
+	} else {
+		fmt.Fprintln(stdio.Stdout, "other")
+	}`
+	if !strings.Contains(code, want) {
+		t.Errorf("Generated code does not contain %q:\n%s", want, code)
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestGenerateNoDuplicates tests that statements nested in functions and
+// pipelines are generated once
+func TestGenerateNoDuplicates(t *testing.T) {
+	script := `greet() {
+  if [ -n "$1" ]; then
+    echo "hello $1"
+  fi
+}
+greet world
+echo "$PATH" | tr : ' '
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for want, count := range map[string]int{
+		`fmt.Fprintln(stdio.Stdout, "hello "+`: 1,
+		`trOptions{set1: ":"`:                  1,
+	} {
+		if n := strings.Count(code, want); n != count {
+			t.Errorf("Expected %q %d times, got %d:\n%s", want, count, n, code)
+		}
+	}
+	if strings.Contains(code, "gexe") {
+		t.Errorf("Expected no stage of the pipeline to run on its own:\n%s", code)
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// TestTypeCheck tests type checking of generated code and mapping it back to the script
+func TestTypeCheck(t *testing.T) {
+	script := `#!/bin/bash
+echo "start"
+cd /tmp
+`
+
+	// Parse the script
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	result.Filename = "move.sh"
+
+	// Build the IR
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	// Generate the code
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if strings.Contains(code, "bash2go:line") {
+		t.Fatalf("Generated code contains line markers: %s", code)
+	}
+
+	if err := gen.TypeCheck(code); err != nil {
+		t.Fatalf("TypeCheck failed: %v: %v", err, ir.Diagnostics.Items())
+	}
+
+	// Verify the source map
+	for i, line := range strings.Split(code, "\n") {
+		var want uint
+		switch {
+		case strings.Contains(line, `fmt.Fprintln(stdio.Stdout, "start")`):
+			want = 2
+		case strings.Contains(line, `os.Chdir("/tmp")`):
+			want = 3
+		default:
+			continue
+		}
+		if pos := gen.SourcePosition(i + 1); pos.File != "move.sh" || pos.Line != want {
+			t.Fatalf("Expected line %d to map to move.sh:%d, got %s", i+1, want, pos)
+		}
+	}
+
+	if pos := gen.SourcePosition(1); pos.IsValid() {
+		t.Fatalf("Expected the package clause to have no source position, got %s", pos)
+	}
+}
+
+// TestGenerateBuildConstraint tests that a build constraint is emitted at the top of the file
+func TestGenerateBuildConstraint(t *testing.T) {
+	// Parse the script
+	result, err := parser.ParseBashString("#!/bin/bash\necho hi\n")
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+
+	// Build the IR
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	// Generate the code
+	gen := generator.NewGoCodeGenerator(ir)
+	gen.Options.BuildConstraint = "linux && !cgo"
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if !strings.HasPrefix(code, "//go:build linux && !cgo\n\npackage main") {
+		t.Fatalf("Generated code does not start with the build constraint: %s", code)
+	}
+
+	// Invalid expressions are rejected
+	gen.Options.BuildConstraint = "linux &&"
+	if _, err := gen.Generate(); err == nil {
+		t.Fatal("Expected Generate to reject an invalid build constraint")
+	}
+}
+
+// TestGenerateBuildInfo tests the generated --bash2go-info flag and its linker flags
+func TestGenerateBuildInfo(t *testing.T) {
+	// Parse the script
+	result, err := parser.ParseBashString("#!/bin/bash\necho hi\n")
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+
+	// Build the IR
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	// Generate the code
+	gen := generator.NewGoCodeGenerator(ir)
+	gen.Options.BuildInfo = true
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	// Verify the output
+	for _, expected := range []string{
+		"bash2goScript    string",
+		"func printBuildInfo()",
+		`if len(os.Args) == 2 && os.Args[1] == "--bash2go-info" {`,
+	} {
+		if !strings.Contains(code, expected) {
+			t.Fatalf("Generated code missing %s: %s", expected, code)
+		}
+	}
+
+	info := generator.BuildInfo{Script: "my script.sh", Converted: "2024-01-02T03:04:05Z", Version: "v1.0.0", Hash: "abc"}
+	flags := info.LDFlags()
+	for _, expected := range []string{
+		"-X 'main.bash2goScript=my script.sh'",
+		"-X 'main.bash2goConverted=2024-01-02T03:04:05Z'",
+		"-X 'main.bash2goVersion=v1.0.0'",
+		"-X 'main.bash2goHash=abc'",
+	} {
+		if !strings.Contains(flags, expected) {
+			t.Fatalf("LDFlags missing %s: %s", expected, flags)
+		}
+	}
+}
+
+// TestGenerateSchedule tests generating a scheduler that runs the script on a cron schedule
+func TestGenerateSchedule(t *testing.T) {
+	// Parse the script
+	result, err := parser.ParseBashString("#!/bin/bash\necho tick\n")
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+
+	// Build the IR
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	// Generate the code
+	gen := generator.NewGoCodeGenerator(ir)
+	gen.Options.Schedule = "*/5 * * * *"
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	for _, want := range []string{
+		`"github.com/robfig/cron/v3"`,
+		`scheduler.AddFunc("*/5 * * * *", func() {`,
+		"<-scheduler.Stop().Done()",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q: %s", want, code)
+		}
+	}
+
+	// The scheduler type-checks despite the unresolved third-party import
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v", err)
+	}
+
+	// Invalid schedules are rejected
+	for _, spec := range []string{"* * *", "@reboot"} {
+		gen.Options.Schedule = spec
+		if _, err := gen.Generate(); err == nil {
+			t.Errorf("Expected Generate to reject the schedule %q", spec)
+		}
+	}
+}
+
+// TestGenerateSubcommands tests programs combined from several scripts
+func TestGenerateSubcommands(t *testing.T) {
+	var irs []*parser.IntermediateRepresentation
+	for _, script := range []string{"echo starting\n", "echo stopping\n", "echo reloading\n"} {
+		result, err := parser.ParseBashString(script)
+		if err != nil {
+			t.Fatalf("ParseBashString failed: %v", err)
+		}
+		ir, err := parser.BuildIR(result)
+		if err != nil {
+			t.Fatalf("BuildIR failed: %v", err)
+		}
+		irs = append(irs, ir)
+	}
+
+	// Combine the scripts into one program
+	ir, err := parser.CombineCommands([]string{"start", "stop", "reload"}, irs)
+	if err != nil {
+		t.Fatalf("CombineCommands failed: %v", err)
+	}
+
+	// Generate the code
+	gen := generator.NewGoCodeGenerator(ir)
+	gen.Options.SignalCommands = map[string]string{"HUP": "reload"}
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"func start(stdio *streams, args []string) error {",
+		`"reload": reload,`,
+		"commands[os.Args[1]] == nil",
+		"signal.Notify(signals, syscall.SIGHUP)",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q: %s", want, code)
+		}
+	}
+	if strings.Contains(code, "func run() error") {
+		t.Errorf("Expected no run function in a combined program: %s", code)
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("TypeCheck failed: %v", err)
+	}
+
+	// Commands must not clash with functions of the scripts
+	result, err := parser.ParseBashString("stop() { echo; }\nstop\n")
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	clash, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+	if _, err := parser.CombineCommands([]string{"start", "stop"}, []*parser.IntermediateRepresentation{clash, irs[1]}); err == nil {
+		t.Errorf("Expected CombineCommands to reject the clashing function")
+	}
+}
+
+// TestGeneratePlugins tests translating commands with plugins on PATH
+func TestGeneratePlugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins in this test are shell scripts")
+	}
+
+	// Install a plugin for deploy that records its request, and one for
+	// broken that fails
+	dir := t.TempDir()
+	requestFile := filepath.Join(dir, "request.json")
+	plugins := map[string]string{
+		"deploy": "cat > " + requestFile + "\n" +
+			`printf '%s\n' '{"code": "if _, err := strconv.Atoi(\"1\"); err != nil {\n\treturn err\n}", "imports": ["strconv"]}'`,
+		"broken": "echo 'unknown target' >&2\nexit 1",
+	}
+	for name, body := range plugins {
+		path := filepath.Join(dir, generator.PluginPrefix+name)
+		if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0o755); err != nil {
+			t.Fatalf("Failed to write plugin: %v", err)
+		}
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	result, err := parser.ParseBashString("TARGET=prod\ndeploy \"$TARGET\"\nbroken\n")
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	// Generate with plugins enabled
+	gen := generator.NewGoCodeGenerator(ir)
+	gen.Options.Plugins = true
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if !strings.Contains(code, `strconv.Atoi("1")`) || !strings.Contains(code, `"strconv"`) {
+		t.Errorf("Expected the plugin's code and imports, got:\n%s", code)
+	}
+	if metrics := gen.Metrics(); metrics.ExecFallbacks != 1 {
+		t.Errorf("Expected only broken to be executed, got %+v", metrics)
+	}
+
+	var warned bool
+	for _, d := range ir.Diagnostics.Items() {
+		warned = warned || d.Code == "plugin" && strings.Contains(d.Message, "unknown target")
+	}
+	if !warned {
+		t.Errorf("Expected a warning about the failing plugin, got %v", ir.Diagnostics.Items())
+	}
+
+	// The plugin received the command with its arguments as Go expressions
+	data, err := os.ReadFile(requestFile)
+	if err != nil {
+		t.Fatalf("Plugin was not run: %v", err)
+	}
+	var request generator.PluginRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		t.Fatalf("Invalid request: %v", err)
+	}
+	if request.Command.Name != "deploy" || len(request.GoArgs) != 1 || request.ErrReturn == "" {
+		t.Errorf("Unexpected request: %s", data)
+	}
+}
+
+// TestGenerateMappings tests translating commands declared in a mappings
+// file
+func TestGenerateMappings(t *testing.T) {
+	mappings, err := generator.ParseMappings(strings.NewReader(`commands:
+  notify:
+    template: |
+      if _, err := strconv.Atoi({{index .Args 0}}); err != nil {
+      	{{.ErrReturn}}
+      }
+    imports: [strconv]
+  rsync:
+    policy: exec
+  telnet:
+    policy: error
+    message: use ssh instead
+`))
+	if err != nil {
+		t.Fatalf("ParseMappings failed: %v", err)
+	}
+
+	generate := func(script string) (*parser.IntermediateRepresentation, *generator.GoCodeGenerator, string, error) {
+		result, err := parser.ParseBashString(script)
+		if err != nil {
+			t.Fatalf("ParseBashString failed: %v", err)
+		}
+		ir, err := parser.BuildIR(result)
+		if err != nil {
+			t.Fatalf("BuildIR failed: %v", err)
+		}
+		gen := generator.NewGoCodeGenerator(ir)
+		gen.Options.Mappings = mappings
+		code, err := gen.Generate()
+		return ir, gen, code, err
+	}
+
+	_, gen, code, err := generate("COUNT=3\nnotify \"$COUNT\"\nrsync -a src dst\n")
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{`strconv.Atoi(COUNT)`, `"strconv"`, `notify failed: %w`, `rsync`} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if metrics := gen.Metrics(); metrics.Native != 2 || metrics.ExecFallbacks != 1 {
+		t.Errorf("Expected notify to be native and rsync executed, got %+v", metrics)
+	}
+
+	// Forbidden commands fail the conversion
+	ir, _, _, err := generate("echo hi\ntelnet example.com\n")
+	if err == nil || !strings.Contains(err.Error(), "use ssh instead") {
+		t.Errorf("Expected telnet to be rejected, got %v", err)
+	}
+	if items := ir.Diagnostics.Items(); len(items) == 0 || items[len(items)-1].Code != "mapping" {
+		t.Errorf("Expected a mapping diagnostic, got %v", items)
+	}
+
+	// Invalid mappings are rejected when loaded
+	for _, bad := range []string{
+		"commands:\n  ls:\n    policy: skip\n",
+		"commands:\n  ls:\n    template: \"{{.Args\"\n",
+		"commands:\n  ls:\n    policy: exec\n    imports: [os]\n",
+		"command:\n  ls:\n    policy: exec\n",
+	} {
+		if _, err := generator.ParseMappings(strings.NewReader(bad)); err == nil {
+			t.Errorf("Expected ParseMappings to reject %q", bad)
+		}
+	}
+}
+
+// TestGenerateGoGit tests translating git commands into go-git calls
+func TestGenerateGoGit(t *testing.T) {
+	script := `REPO=https://example.com/tool.git
+git clone --depth 1 -b main "$REPO" tool
+git checkout -b feature origin/main
+git rev-parse --short HEAD
+git pull -q
+git status
+git checkout -- file.txt
+`
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	// Without the option git is executed
+	gen := generator.NewGoCodeGenerator(ir)
+	code, err := gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if strings.Contains(code, "go-git") {
+		t.Errorf("Expected no go-git calls without GoGit:\n%s", code)
+	}
+
+	gen = generator.NewGoCodeGenerator(ir)
+	gen.Options.GoGit = true
+	code, err = gen.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{
+		`gitClone(stdio, REPO, "tool", "main", 1, false)`,
+		`gitCheckout("feature", true, "origin/main")`,
+		`gitRevParse(stdio, "HEAD", "short")`,
+		`gitPull(stdio, "origin", "", true)`,
+		`"github.com/go-git/go-git/v5/plumbing"`,
+		`func gitRepository()`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	// git status and checking out paths have no translation
+	if metrics := gen.Metrics(); metrics.ExecFallbacks != 2 {
+		t.Errorf("Expected 2 git commands to be executed, got %+v", metrics)
+	}
+
+	// The go-git package is named git, which type-checking has to know.
+	// Only the translated commands are kept, as executed ones do not
+	// type-check yet.
+	ir.MainStatements = ir.MainStatements[:5]
+	if code, err = gen.Generate(); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if err := gen.TypeCheck(code); err != nil {
+		t.Errorf("Expected the go-git code to type-check, got %v: %v", err, ir.Diagnostics.Items())
+	}
+}
+
+// runScript converts script and runs the generated program in dir with
+// args, failing the test if the program fails or does not end within a
+// minute
+func runScript(t *testing.T, dir, script string, args ...string) string {
+	t.Helper()
+	result, err := parser.ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+	code, err := generator.NewGoCodeGenerator(ir).Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(path, []byte(code), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(t.Context(), time.Minute)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "go", append([]string{"run", path}, args...)...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Running the program failed: %v\n%s\n%s", err, output, code)
+	}
+	return string(output)
+}
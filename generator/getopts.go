@@ -0,0 +1,122 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	runtimeHelpers["getoptsParse"] = runtimeHelper{
+		Source: `// getoptsParse parses the options at the start of args like a getopts loop
+// with spec, calling option with each option letter in command line order
+// and its argument, if spec gives it one. Like getopts, it takes bundled
+// options (-vf file) and arguments joined to their option (-ffile), and stops
+// at the first operand or after --. An invalid option, or one missing its
+// argument, is reported on stderr and passed to option as "?". If spec starts
+// with a colon, errors are silent instead: the letter is passed as the
+// argument of "?" for an invalid option, and of ":" for a missing argument.
+// It returns the index of the first operand for OPTIND, counting the program
+// name, or the first error option returns.
+func getoptsParse(args []string, spec string, stderr io.Writer, option func(name, value string) error) (int, error) {
+	silent := strings.HasPrefix(spec, ":")
+	spec = strings.TrimPrefix(spec, ":")
+	fail := func(name, letter, message string) error {
+		if silent {
+			return option(name, letter)
+		}
+		fmt.Fprintf(stderr, "%s: %s -- %s\n", os.Args[0], message, letter)
+		return option("?", "")
+	}
+	i := 0
+	for ; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			i++
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			break
+		}
+		for j := 1; j < len(arg); j++ {
+			letter := arg[j : j+1]
+			k := strings.Index(spec, letter)
+			var err error
+			switch {
+			case k < 0 || letter == ":":
+				err = fail("?", letter, "illegal option")
+			case !strings.HasPrefix(spec[k+1:], ":"):
+				err = option(letter, "")
+			case j+1 < len(arg):
+				// The rest of the word is the argument
+				err = option(letter, arg[j+1:])
+				j = len(arg)
+			case i+1 < len(args):
+				i++
+				err = option(letter, args[i])
+			default:
+				err = fail(":", letter, "option requires an argument")
+			}
+			if err != nil {
+				return i + 2, err
+			}
+		}
+	}
+	return i + 1, nil
+}`,
+		Imports: []string{"fmt", "io", "os", "strings"},
+	}
+}
+
+// generateGetopts generates Go code for the standard getopts loop. The
+// options are parsed like getopts does, and the case arm of each option runs
+// when the option is met, so options run in command line order, as in the
+// loop. Options with an argument set OPTARG to it; invalid options, and
+// options missing their argument, run the \? arm, or the : arm for missing
+// arguments in silent mode. A failing case arm fails the loop. OPTIND is set
+// to the index of the first operand afterwards.
+func (g *GoCodeGenerator) generateGetopts(getopts parser.Getopts) (string, error) {
+	g.requireHelper("getoptsParse")
+	g.RequiredImports["os"] = true
+	g.RequiredImports["strconv"] = true
+
+	lines := []string{
+		fmt.Sprintf("// Parse the options of getopts %s", strconv.Quote(getopts.Spec)),
+		fmt.Sprintf("optIndex, err := getoptsParse(os.Args[1:], %s, %s.Stderr, func(optName, optArg string) error {",
+			strconv.Quote(getopts.Spec), g.stdio()),
+		getopts.Name + " = optName",
+		"OPTARG = optArg",
+		"switch optName {",
+	}
+	for _, option := range getopts.Options {
+		body, err := g.generateStatements(option.Body)
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, fmt.Sprintf("case %s:", strconv.Quote(option.Letter)), bodyWithReturn(body))
+	}
+	invalid, err := g.generateStatements(getopts.Invalid)
+	if err != nil {
+		return "", err
+	}
+	lines = append(lines, `case "?":`, bodyWithReturn(invalid))
+	if getopts.Missing != nil {
+		missing, err := g.generateStatements(getopts.Missing)
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, `case ":":`, bodyWithReturn(missing))
+	}
+	lines = append(lines,
+		"}",
+		"return nil",
+		"})",
+		"OPTIND = strconv.Itoa(optIndex)",
+		"if err != nil {",
+		"return err",
+		"}",
+	)
+	return "{\n" + strings.Join(lines, "\n") + "\n}", nil
+}
@@ -0,0 +1,81 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+// globList converts words, of which those at the indices in globs are glob
+// patterns, into a Go []string expression with the patterns expanded to the
+// matching file names, the words at the indices in splits split at $IFS and
+// "$@" expanded to the positional parameters. Runs of other words are
+// converted with list.
+func (g *GoCodeGenerator) globList(words []string, globs, splits []int, list func(words []string) string) string {
+	isGlob := make(map[int]bool, len(globs))
+	for _, i := range globs {
+		isGlob[i] = true
+	}
+	isSplit := make(map[int]bool, len(splits))
+	for _, i := range splits {
+		isSplit[i] = true
+	}
+
+	var expr string
+	for i := 0; i < len(words); {
+		var part string
+		if isGlob[i] {
+			g.requireHelper("globExpand")
+			part = fmt.Sprintf("globExpand(%s)", g.goArg(words[i]))
+			i++
+		} else if isSplit[i] {
+			part = g.splitWord(words[i])
+			i++
+		} else if isArgsWord(words[i]) {
+			part = "args"
+			if expr == "" && i+1 < len(words) {
+				// Appending to args itself could overwrite the parameters
+				part = "append([]string(nil), args...)"
+			}
+			i++
+		} else {
+			j := i
+			for j < len(words) && !isGlob[j] && !isSplit[j] && !isArgsWord(words[j]) {
+				j++
+			}
+			part = list(words[i:j])
+			i = j
+		}
+
+		if expr == "" {
+			expr = part
+		} else {
+			expr = fmt.Sprintf("append(%s, %s...)", expr, part)
+		}
+	}
+	return expr
+}
+
+// globArgs converts the arguments of a command into a Go []string expression
+// with its glob patterns expanded and its unquoted expansions split
+func (g *GoCodeGenerator) globArgs(args []string, globs, splits []int) string {
+	return g.globList(args, globs, splits, func(words []string) string {
+		var exprs []string
+		for _, word := range words {
+			exprs = append(exprs, g.goArg(word))
+		}
+		return fmt.Sprintf("[]string{%s}", strings.Join(exprs, ", "))
+	})
+}
+
+// forEachItems returns a Go expression for the list a for-each loop iterates
+// over, with its glob patterns expanded and its unquoted expansions split
+func (g *GoCodeGenerator) forEachItems(loop parser.Loop) string {
+	if len(loop.Globs) == 0 && len(loop.Splits) == 0 && !spreadsArgs(loop.Words) {
+		return g.loopItems(loop.Items)
+	}
+	return g.globList(loop.Words, loop.Globs, loop.Splits, func(words []string) string {
+		return g.loopItems(strings.Join(words, " "))
+	})
+}
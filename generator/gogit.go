@@ -0,0 +1,191 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+// goGitImport is the import path of go-git, used with Options.GoGit
+const goGitImport = "github.com/go-git/go-git/v5"
+
+// goGitCommand translates a git command into go-git calls when
+// Options.GoGit is set. Only clone, pull, checkout and rev-parse in their
+// common forms are translated; anything else, including global options such
+// as -C, reports false so that git is executed.
+func (g *GoCodeGenerator) goGitCommand(cmd parser.Command) (string, bool) {
+	if !g.Options.GoGit || cmd.Name != "git" || len(cmd.Args) == 0 {
+		return "", false
+	}
+
+	var call string
+	var ok bool
+	switch cmd.Args[0] {
+	case "clone":
+		call, ok = g.goGitClone(cmd.Args[1:])
+	case "pull":
+		call, ok = g.goGitPull(cmd.Args[1:])
+	case "checkout":
+		call, ok = g.goGitCheckout(cmd.Args[1:])
+	case "rev-parse":
+		call, ok = g.goGitRevParse(cmd.Args[1:])
+	}
+	if !ok {
+		return "", false
+	}
+	return g.checkErr(cmd, call), true
+}
+
+// goGitClone translates the arguments of git clone
+func (g *GoCodeGenerator) goGitClone(args []string) (string, bool) {
+	branch, depth, quiet := `""`, "0", "false"
+	var operands []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-q" || arg == "--quiet":
+			quiet = "true"
+		case arg == "--single-branch":
+			// Clones of a branch only fetch that branch
+		case (arg == "-b" || arg == "--branch") && i+1 < len(args):
+			i++
+			branch = g.goArg(args[i])
+		case strings.HasPrefix(arg, "--branch="):
+			branch = g.goArg(strings.TrimPrefix(arg, "--branch="))
+		case arg == "--depth" && i+1 < len(args):
+			i++
+			if depth = args[i]; !isCount(depth) {
+				return "", false
+			}
+		case strings.HasPrefix(arg, "--depth="):
+			if depth = strings.TrimPrefix(arg, "--depth="); !isCount(depth) {
+				return "", false
+			}
+		case strings.HasPrefix(arg, "-"):
+			return "", false
+		default:
+			operands = append(operands, arg)
+		}
+	}
+
+	var url, dir string
+	switch len(operands) {
+	case 1:
+		url, dir = g.goArg(operands[0]), `""`
+	case 2:
+		url, dir = g.goArg(operands[0]), g.goArg(operands[1])
+	default:
+		return "", false
+	}
+
+	g.requireHelper("gitClone")
+	return fmt.Sprintf("gitClone(%s, %s, %s, %s, %s, %s)", g.stdio(), url, dir, branch, depth, quiet), true
+}
+
+// goGitPull translates the arguments of git pull
+func (g *GoCodeGenerator) goGitPull(args []string) (string, bool) {
+	quiet := "false"
+	var operands []string
+	for _, arg := range args {
+		switch {
+		case arg == "-q" || arg == "--quiet":
+			quiet = "true"
+		case arg == "--ff-only":
+			// go-git only fast-forwards
+		case strings.HasPrefix(arg, "-"):
+			return "", false
+		default:
+			operands = append(operands, arg)
+		}
+	}
+	if len(operands) > 2 {
+		return "", false
+	}
+
+	remote, branch := `"origin"`, `""`
+	if len(operands) > 0 {
+		remote = g.goArg(operands[0])
+	}
+	if len(operands) > 1 {
+		branch = g.goArg(operands[1])
+	}
+
+	g.requireHelper("gitPull")
+	return fmt.Sprintf("gitPull(%s, %s, %s, %s)", g.stdio(), remote, branch, quiet), true
+}
+
+// goGitCheckout translates the arguments of git checkout that switch
+// branches; checking out paths is left to git
+func (g *GoCodeGenerator) goGitCheckout(args []string) (string, bool) {
+	create := "false"
+	var operands []string
+	for _, arg := range args {
+		switch {
+		case arg == "-q" || arg == "--quiet":
+			// go-git prints nothing when switching branches
+		case arg == "-b":
+			create = "true"
+		case strings.HasPrefix(arg, "-"):
+			return "", false
+		default:
+			operands = append(operands, arg)
+		}
+	}
+
+	start := `""`
+	switch {
+	case len(operands) == 1:
+	case len(operands) == 2 && create == "true":
+		start = g.goArg(operands[1])
+	default:
+		return "", false
+	}
+
+	g.requireHelper("gitCheckout")
+	return fmt.Sprintf("gitCheckout(%s, %s, %s)", g.goArg(operands[0]), create, start), true
+}
+
+// goGitRevParse translates git rev-parse of a single revision, with --short
+// or --abbrev-ref HEAD, and git rev-parse --show-toplevel
+func (g *GoCodeGenerator) goGitRevParse(args []string) (string, bool) {
+	mode := ""
+	var operands []string
+	for _, arg := range args {
+		switch arg {
+		case "--verify":
+			// Only changes how bad revisions are reported
+		case "--short", "--abbrev-ref", "--show-toplevel":
+			if mode != "" {
+				return "", false
+			}
+			mode = strings.TrimPrefix(arg, "--")
+		default:
+			if strings.HasPrefix(arg, "-") {
+				return "", false
+			}
+			operands = append(operands, arg)
+		}
+	}
+
+	rev := `""`
+	switch {
+	case mode == "show-toplevel" && len(operands) == 0:
+	case mode == "abbrev-ref" && len(operands) == 1 && operands[0] == "HEAD":
+		rev = `"HEAD"`
+	case (mode == "" || mode == "short") && len(operands) == 1:
+		rev = g.goArg(operands[0])
+	default:
+		return "", false
+	}
+
+	g.requireHelper("gitRevParse")
+	return fmt.Sprintf("gitRevParse(%s, %s, %s)", g.stdio(), rev, strconv.Quote(mode)), true
+}
+
+// isCount reports whether s is a literal positive number
+func isCount(s string) bool {
+	n, err := strconv.Atoi(s)
+	return err == nil && n > 0
+}
@@ -0,0 +1,271 @@
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	utilities["grep"] = (*GoCodeGenerator).generateGrep
+
+	runtimeHelpers["grep"] = runtimeHelper{
+		Source: `// grepOptions are the options of a grep command translated to Go
+type grepOptions struct {
+	ignoreCase, invert, count, quiet, extended, fixed bool
+}
+
+// grep prints the lines of files, or of stdin without files, that match
+// pattern, like grep. Patterns are basic regular expressions unless extended
+// or fixed, one per line. It fails with status 1 if no line matches, and 2
+// if the pattern is invalid or a file cannot be read.
+func grep(stdio *streams, stdin io.Reader, opts grepOptions, pattern string, files ...string) error {
+	var exprs []string
+	for _, p := range strings.Split(pattern, "\n") {
+		if opts.fixed {
+			p = regexp.QuoteMeta(p)
+		} else {
+			p = posixRegexp(p, opts.extended)
+		}
+		exprs = append(exprs, "(?:"+p+")")
+	}
+	expr := strings.Join(exprs, "|")
+	if opts.ignoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		fmt.Fprintf(stdio.Stderr, "grep: %v\n", err)
+		return exitError(2)
+	}
+
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	out := bufio.NewWriter(stdio.Stdout)
+	defer out.Flush()
+	matched, failed := false, false
+	for _, name := range files {
+		input, ok := openInput(stdio, "grep", name, stdin)
+		if !ok {
+			failed = true
+			continue
+		}
+		prefix := ""
+		if len(files) > 1 {
+			prefix = name + ":"
+			if name == "-" {
+				prefix = "(standard input):"
+			}
+		}
+		count := 0
+		lines := bufio.NewReader(input)
+		for {
+			line, err := lines.ReadString('\n')
+			if line != "" && re.MatchString(strings.TrimSuffix(line, "\n")) != opts.invert {
+				if opts.quiet {
+					input.Close()
+					return nil
+				}
+				matched = true
+				count++
+				if !opts.count {
+					out.WriteString(prefix + strings.TrimSuffix(line, "\n") + "\n")
+				}
+			}
+			if err != nil {
+				if err != io.EOF {
+					utilityError(stdio, "grep", name, err)
+					failed = true
+				}
+				break
+			}
+		}
+		input.Close()
+		if opts.count {
+			fmt.Fprintf(out, "%s%d\n", prefix, count)
+		}
+	}
+	switch {
+	case failed:
+		return exitError(2)
+	case !matched:
+		return exitError(1)
+	}
+	return nil
+}`,
+		Imports:  []string{"bufio", "fmt", "io", "regexp", "strings"},
+		Requires: []string{"exitError", "openInput", "posixRegexp"},
+	}
+	runtimeHelpers["posixRegexp"] = runtimeHelper{
+		Source: `// posixRegexp converts a POSIX regular expression, basic unless extended,
+// into the syntax of Go. Like in GNU tools, \+, \? and \| are operators of
+// basic expressions, and \< and \> match at word boundaries.
+func posixRegexp(pattern string, extended bool) string {
+	var b strings.Builder
+	// start reports whether the expression or a group starts here, where *
+	// is literal and, in basic expressions, ^ is an anchor
+	start := true
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		wasStart := start
+		start = false
+		switch {
+		case c == '[':
+			end := bracketEnd(pattern, i)
+			if end < 0 {
+				b.WriteString("\\[")
+				continue
+			}
+			b.WriteByte('[')
+			j := i + 1
+			if pattern[j] == '^' {
+				b.WriteByte('^')
+				j++
+			}
+			if pattern[j] == ']' {
+				b.WriteString("\\]")
+				j++
+			}
+			b.WriteString(strings.ReplaceAll(pattern[j:end], "\\", "\\\\"))
+			b.WriteByte(']')
+			i = end
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			c = pattern[i]
+			switch {
+			case c == '<' || c == '>':
+				b.WriteString("\\b")
+			case !extended && strings.IndexByte("(|", c) >= 0:
+				b.WriteByte(c)
+				start = true
+			case !extended && strings.IndexByte(")}{+?", c) >= 0:
+				b.WriteByte(c)
+			case strings.IndexByte("wWsSbBnt123456789", c) >= 0:
+				b.WriteString("\\" + string(c))
+			default:
+				b.WriteString(regexp.QuoteMeta(string(c)))
+			}
+		case !extended && strings.IndexByte("(){}|+?", c) >= 0:
+			b.WriteString("\\" + string(c))
+		case extended && (c == '(' || c == '|'):
+			b.WriteByte(c)
+			start = true
+		case c == '*' && wasStart:
+			b.WriteString("\\*")
+		case c == '^' && !extended && !wasStart:
+			b.WriteString("\\^")
+		case c == '^':
+			b.WriteByte(c)
+			start = true
+		case c == '$' && !extended && !basicEnd(pattern[i+1:]):
+			b.WriteString("\\$")
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// bracketEnd returns the index of the ] closing the bracket expression
+// starting at index i of pattern, or -1 if it is not closed
+func bracketEnd(pattern string, i int) int {
+	j := i + 1
+	if j < len(pattern) && pattern[j] == '^' {
+		j++
+	}
+	if j < len(pattern) && pattern[j] == ']' {
+		j++
+	}
+	for ; j < len(pattern); j++ {
+		switch {
+		case pattern[j] == '[' && j+1 < len(pattern) && strings.IndexByte(":=.", pattern[j+1]) >= 0:
+			end := strings.Index(pattern[j+2:], string(pattern[j+1])+"]")
+			if end < 0 {
+				return -1
+			}
+			j += end + 3
+		case pattern[j] == ']':
+			return j
+		}
+	}
+	return -1
+}
+
+// basicEnd reports whether a $ followed by rest ends a basic regular
+// expression or one of its groups or alternatives, where it is an anchor
+func basicEnd(rest string) bool {
+	return rest == "" || strings.HasPrefix(rest, "\\)") || strings.HasPrefix(rest, "\\|")
+}`,
+		Imports: []string{"regexp", "strings"},
+	}
+}
+
+// backreference matches the back-references of a regular expression, which
+// Go regular expressions do not support
+var backreference = regexp.MustCompile(`\\[1-9]`)
+
+// generateGrep translates grep with the options -q, -i, -v, -c, -E, -F and
+// -e into a call of the grep helper. It reports false for other options,
+// for a missing pattern and for patterns with back-references, so that grep
+// is executed.
+func (g *GoCodeGenerator) generateGrep(cmd parser.Command) (string, bool) {
+	options, operands, ok := g.utilityArgs(cmd, "qivcEF", "e")
+	if !ok {
+		return "", false
+	}
+	var fields, patterns []string
+	fixed := false
+	for _, option := range options {
+		switch option.name {
+		case 'q':
+			fields = append(fields, "quiet: true")
+		case 'i':
+			fields = append(fields, "ignoreCase: true")
+		case 'v':
+			fields = append(fields, "invert: true")
+		case 'c':
+			fields = append(fields, "count: true")
+		case 'E':
+			fields = append(fields, "extended: true")
+		case 'F':
+			fields = append(fields, "fixed: true")
+			fixed = true
+		case 'e':
+			patterns = append(patterns, option.value)
+		}
+	}
+	if len(patterns) == 0 {
+		// The first operand is the pattern
+		if len(operands) == 0 || g.expandsArg(cmd, operands[0]) {
+			return "", false
+		}
+		patterns = []string{g.goArg(cmd.Args[operands[0]])}
+		operands = operands[1:]
+	}
+	for _, pattern := range patterns {
+		if literal, err := strconv.Unquote(pattern); err == nil && !fixed && backreference.MatchString(literal) {
+			return "", false
+		}
+	}
+
+	g.requireHelper("grep")
+	call := fmt.Sprintf("grep(%s, %s, grepOptions{%s}, %s%s)", g.stdio(), g.utilityInput(cmd), strings.Join(uniqueFields(fields), ", "),
+		strings.Join(patterns, ` + "\n" + `), g.operandArgs(cmd, operands))
+	return g.checkErr(cmd, call), true
+}
+
+// uniqueFields returns fields without repetitions, for struct literals of
+// options given more than once
+func uniqueFields(fields []string) []string {
+	var unique []string
+	for _, field := range fields {
+		if !contains(unique, field) {
+			unique = append(unique, field)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,173 @@
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	utilities["head"] = (*GoCodeGenerator).generateHead
+
+	runtimeHelpers["head"] = runtimeHelper{
+		Source: `// head prints the first lines of files, or of stdin without files, like
+// head: as many as the lines count, as in 10, or all but the last ones for
+// counts such as -10. It fails with status 1 if the count is invalid or a
+// file cannot be read.
+func head(stdio *streams, stdin io.Reader, lines string, files ...string) error {
+	n, sign, ok := lineCount(stdio, "head", lines)
+	if !ok {
+		return exitError(1)
+	}
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	out := bufio.NewWriter(stdio.Stdout)
+	defer out.Flush()
+	failed, first := false, true
+	for _, name := range files {
+		input, ok := openForReading(stdio, "head", name, stdin)
+		if !ok {
+			failed = true
+			continue
+		}
+		if len(files) > 1 {
+			fileHeader(out, name, first)
+			first = false
+		}
+		var kept []string
+		r := bufio.NewReader(input)
+		if name == "-" {
+			// The lines past those printed stay buffered for the next
+			// command reading stdin
+			r = bufio.NewReader(stdin)
+		}
+		for count := 0; sign == '-' || count < n; count++ {
+			line, err := r.ReadString('\n')
+			if sign != '-' {
+				out.WriteString(line)
+			} else if kept = append(kept, line); line != "" && len(kept) > n {
+				out.WriteString(kept[0])
+				kept = kept[1:]
+			}
+			if err != nil {
+				if err != io.EOF {
+					utilityError(stdio, "head", "error reading '"+name+"'", err)
+					failed = true
+				}
+				break
+			}
+		}
+		input.Close()
+	}
+	if failed {
+		return exitError(1)
+	}
+	return nil
+}`,
+		Imports:  []string{"bufio", "io"},
+		Requires: []string{"exitError", "fileHeader", "lineCount", "openForReading", "utilityError"},
+	}
+	runtimeHelpers["lineCount"] = runtimeHelper{
+		Source: `// lineCount parses the number of lines of head or tail, such as 10, +10 or
+// -10, returning its sign, and reports an invalid number
+func lineCount(stdio *streams, utility, lines string) (int, byte, bool) {
+	digits, sign := lines, byte(0)
+	if digits != "" && (digits[0] == '+' || digits[0] == '-') {
+		digits, sign = digits[1:], digits[0]
+	}
+	n, err := strconv.Atoi(digits)
+	if err != nil || strings.TrimLeft(digits, "0123456789") != "" {
+		fmt.Fprintf(stdio.Stderr, "%s: invalid number of lines: '%s'\n", utility, lines)
+		return 0, 0, false
+	}
+	return n, sign, true
+}`,
+		Imports:  []string{"fmt", "strconv", "strings"},
+		Requires: []string{"streams"},
+	}
+	runtimeHelpers["openForReading"] = runtimeHelper{
+		Source: `// openForReading opens a file operand of head or tail, "-" naming its
+// standard input, and reports failures the way they do
+func openForReading(stdio *streams, utility, name string, stdin io.Reader) (io.ReadCloser, bool) {
+	if name == "-" {
+		return io.NopCloser(stdin), true
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		utilityError(stdio, utility, "cannot open '"+name+"' for reading", err)
+		return nil, false
+	}
+	return f, true
+}`,
+		Imports:  []string{"io", "os"},
+		Requires: []string{"utilityError"},
+	}
+	runtimeHelpers["fileHeader"] = runtimeHelper{
+		Source: `// fileHeader prints the header head and tail print before the lines of
+// each of several files, as in "==> notes.txt <=="
+func fileHeader(out io.Writer, name string, first bool) {
+	if name == "-" {
+		name = "standard input"
+	}
+	if !first {
+		io.WriteString(out, "\n")
+	}
+	fmt.Fprintf(out, "==> %s <==\n", name)
+}`,
+		Imports: []string{"fmt", "io"},
+	}
+}
+
+// lineCountNumber matches the numbers of lines of head and tail known when
+// converting, such as 10, +10 and -10
+var lineCountNumber = regexp.MustCompile(`^[+-]?[0-9]+$`)
+
+// generateHead translates head with -n or a number of lines, as in head -5,
+// into a call of the head helper. It reports false for other options, such
+// as -c, so that head is executed.
+func (g *GoCodeGenerator) generateHead(cmd parser.Command) (string, bool) {
+	lines, _, operands, ok := g.lineCountArgs(cmd, "")
+	if !ok {
+		return "", false
+	}
+	g.requireHelper("head")
+	call := fmt.Sprintf("head(%s, %s, %s%s)", g.stdio(), g.utilityInput(cmd), lines, g.operandArgs(cmd, operands))
+	return g.checkErr(cmd, call), true
+}
+
+// lineCountArgs splits the arguments of head or tail into the Go expression
+// of their number of lines, given with -n or as in -5 and 10 by default,
+// their other options, in flags, and the indices of their operands. It
+// reports false for other options and for invalid numbers known when
+// converting.
+func (g *GoCodeGenerator) lineCountArgs(cmd parser.Command, flags string) (string, []utilityFlag, []int, bool) {
+	options, operands, ok := g.utilityArgs(cmd, flags+"0123456789", "n")
+	if !ok {
+		return "", nil, nil, false
+	}
+	lines := strconv.Quote("10")
+	var others []utilityFlag
+	digits := ""
+	for i, option := range options {
+		switch {
+		case option.name >= '0' && option.name <= '9':
+			// The digits of an option such as -15 are options of their own
+			if i == 0 || options[i-1].name < '0' || options[i-1].name > '9' {
+				digits = ""
+			}
+			digits += string(option.name)
+			lines = strconv.Quote(digits)
+		case option.name == 'n':
+			lines = option.value
+		default:
+			others = append(others, option)
+		}
+	}
+	if literal, err := strconv.Unquote(lines); err == nil && !lineCountNumber.MatchString(literal) {
+		return "", nil, nil, false
+	}
+	return lines, others, operands, true
+}
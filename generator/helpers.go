@@ -0,0 +1,394 @@
+package generator
+
+import "sort"
+
+// runtimeHelper is a piece of Go source emitted into generated programs when
+// a translated statement needs it.
+type runtimeHelper struct {
+	Source   string   // Declarations to emit at package level
+	Imports  []string // Packages the source uses
+	Requires []string // Other helpers the source uses
+}
+
+// runtimeHelpers lists the helpers available to generated code by name.
+var runtimeHelpers = map[string]runtimeHelper{
+	"shellOptions": {
+		Source: `// shellOptions holds the shell options set with shopt
+var shellOptions = map[string]bool{}`,
+	},
+	"globExpand": {
+		Source: `// globExpand expands a glob pattern like the shell, honouring the
+// nullglob, failglob and globstar options. Patterns without matches expand
+// to themselves, without the escapes of their quoted parts, unless nullglob
+// is set; with failglob set they end the program.
+func globExpand(pattern string) []string {
+	var found []string
+	if shellOptions["globstar"] && strings.Contains(pattern, "**") {
+		found = globStar(pattern)
+	} else {
+		found, _ = filepath.Glob(pattern)
+	}
+	var matches []string
+	for _, name := range found {
+		if !hiddenMatch(pattern, name) {
+			matches = append(matches, name)
+		}
+	}
+	if len(matches) == 0 {
+		if shellOptions["failglob"] {
+			fmt.Fprintln(os.Stderr, "no match:", pattern)
+			os.Exit(1)
+		}
+		if shellOptions["nullglob"] {
+			return nil
+		}
+		return []string{unescapePattern(pattern)}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// hiddenMatch reports whether name, a match of pattern, has a hidden part
+// starting with a dot where the pattern has none. Like in the shell, hidden
+// files only match patterns that spell out the leading dot.
+func hiddenMatch(pattern, name string) bool {
+	patterns := strings.Split(pattern, "/")
+	names := strings.Split(filepath.ToSlash(name), "/")
+	if len(patterns) != len(names) {
+		// Parts matched by ** have no counterpart in the pattern
+		patterns, names = patterns[len(patterns)-1:], names[len(names)-1:]
+	}
+	for i, part := range names {
+		if strings.HasPrefix(part, ".") && !strings.HasPrefix(patterns[i], ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// unescapePattern removes the backslashes escaping characters of a pattern.
+func unescapePattern(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == '\\' && i+1 < len(pattern) {
+			i++
+		}
+		b.WriteByte(pattern[i])
+	}
+	return b.String()
+}
+
+// globStar expands a pattern containing ** by walking the directory tree
+// below the part of the pattern before the first **.
+func globStar(pattern string) []string {
+	root, rest, _ := strings.Cut(pattern, "**")
+	root = strings.TrimSuffix(root, "/")
+	if root == "" {
+		root = "."
+	}
+	rest = strings.TrimPrefix(rest, "/")
+	restParts := len(strings.Split(rest, "/"))
+
+	var matches []string
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == root {
+			return nil
+		}
+		if rest == "" {
+			matches = append(matches, path)
+			return nil
+		}
+		parts := strings.Split(filepath.ToSlash(path), "/")
+		if len(parts) < restParts {
+			return nil
+		}
+		tail := strings.Join(parts[len(parts)-restParts:], "/")
+		if ok, _ := filepath.Match(rest, tail); ok {
+			matches = append(matches, path)
+		}
+		return nil
+	})
+	return matches
+}`,
+		Imports:  []string{"fmt", "io/fs", "os", "path/filepath", "sort", "strings"},
+		Requires: []string{"shellOptions"},
+	},
+	"patternMatch": {
+		Source: `// patternMatch reports whether s matches a shell pattern, honouring the
+// nocasematch option and, when extglob is set, the ?(...), *(...), +(...)
+// and @(...) pattern lists.
+func patternMatch(pattern, s string) bool {
+	expr := globToRegexp(pattern, shellOptions["extglob"])
+	if shellOptions["nocasematch"] {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return pattern == s
+	}
+	return re.MatchString(s)
+}
+
+// globToRegexp converts a shell pattern into an anchored regular expression.
+func globToRegexp(pattern string, extglob bool) string {
+	var b strings.Builder
+	b.WriteString("^")
+	depth := 0
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		if extglob && i+1 < len(pattern) && pattern[i+1] == '(' && strings.IndexByte("?*+@", c) >= 0 {
+			b.WriteString("(?:")
+			depth++
+			i++
+			continue
+		}
+		switch {
+		case c == ')' && depth > 0:
+			depth--
+			b.WriteString(")")
+			// The operator precedes the group in the pattern but follows it
+			// in the regular expression.
+			if op := extglobOperator(pattern[:i]); op != "" {
+				b.WriteString(op)
+			}
+		case c == '|' && depth > 0:
+			b.WriteString("|")
+		case c == '*':
+			b.WriteString(".*")
+		case c == '?':
+			b.WriteString(".")
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				b.WriteString(regexp.QuoteMeta("["))
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// extglobOperator returns the regular expression quantifier for the extglob
+// group that closes at the end of prefix.
+func extglobOperator(prefix string) string {
+	depth := 0
+	for i := len(prefix) - 1; i > 0; i-- {
+		switch prefix[i] {
+		case ')':
+			depth++
+		case '(':
+			if depth > 0 {
+				depth--
+				continue
+			}
+			switch prefix[i-1] {
+			case '?':
+				return "?"
+			case '*':
+				return "*"
+			case '+':
+				return "+"
+			}
+			return ""
+		}
+	}
+	return ""
+}`,
+		Imports:  []string{"regexp", "strings"},
+		Requires: []string{"shellOptions"},
+	},
+	"gitRepository": {
+		Source: `// gitRepository opens the git repository containing the working directory
+func gitRepository() (*git.Repository, error) {
+	return git.PlainOpenWithOptions(".", &git.PlainOpenOptions{DetectDotGit: true})
+}`,
+		Imports: []string{goGitImport},
+	},
+	"gitClone": {
+		Source: `// gitClone clones url into dir like git clone, which names dir after the
+// last element of url when it is empty. An empty branch clones the remote's
+// default branch, and a depth of zero clones the whole history.
+func gitClone(stdio *streams, url, dir, branch string, depth int, quiet bool) error {
+	if dir == "" {
+		dir = strings.TrimSuffix(url[strings.LastIndexAny(strings.TrimRight(url, "/"), "/:")+1:], ".git")
+		dir = strings.TrimRight(dir, "/")
+	}
+	options := &git.CloneOptions{URL: url, Depth: depth}
+	if branch != "" {
+		options.ReferenceName = plumbing.NewBranchReferenceName(branch)
+		options.SingleBranch = true
+	}
+	if !quiet {
+		fmt.Fprintf(stdio.Stderr, "Cloning into '%s'...\n", dir)
+		options.Progress = stdio.Stderr
+	}
+	_, err := git.PlainClone(dir, false, options)
+	return err
+}`,
+		Imports:  []string{"fmt", "strings", goGitImport, goGitImport + "/plumbing"},
+		Requires: []string{"streams"},
+	},
+	"gitPull": {
+		Source: `// gitPull fast-forwards the current branch from remote like git pull
+// --ff-only, pulling branch if it is not empty. Being up to date is not an
+// error.
+func gitPull(stdio *streams, remote, branch string, quiet bool) error {
+	repo, err := gitRepository()
+	if err != nil {
+		return err
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	options := &git.PullOptions{RemoteName: remote}
+	if branch != "" {
+		options.ReferenceName = plumbing.NewBranchReferenceName(branch)
+	}
+	if !quiet {
+		options.Progress = stdio.Stderr
+	}
+	err = worktree.Pull(options)
+	if errors.Is(err, git.NoErrAlreadyUpToDate) {
+		if !quiet {
+			fmt.Fprintln(stdio.Stdout, "Already up to date.")
+		}
+		return nil
+	}
+	return err
+}`,
+		Imports:  []string{"errors", "fmt", goGitImport, goGitImport + "/plumbing"},
+		Requires: []string{"gitRepository", "streams"},
+	},
+	"gitCheckout": {
+		Source: `// gitCheckout switches the working tree to a branch, tag or commit like git
+// checkout. A branch only found on origin is created at the remote branch.
+// With create set, a new branch is created at start, or at HEAD if start is
+// empty.
+func gitCheckout(ref string, create bool, start string) error {
+	repo, err := gitRepository()
+	if err != nil {
+		return err
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	branch := plumbing.NewBranchReferenceName(ref)
+	if create {
+		options := &git.CheckoutOptions{Branch: branch, Create: true}
+		if start != "" {
+			hash, err := repo.ResolveRevision(plumbing.Revision(start))
+			if err != nil {
+				return err
+			}
+			options.Hash = *hash
+		}
+		return worktree.Checkout(options)
+	}
+	if _, err := repo.Reference(branch, false); err == nil {
+		return worktree.Checkout(&git.CheckoutOptions{Branch: branch})
+	}
+	if remote, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", ref), true); err == nil {
+		return worktree.Checkout(&git.CheckoutOptions{Branch: branch, Hash: remote.Hash(), Create: true})
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return fmt.Errorf("pathspec '%s' did not match any file(s) known to git", ref)
+	}
+	return worktree.Checkout(&git.CheckoutOptions{Hash: *hash})
+}`,
+		Imports:  []string{"fmt", goGitImport, goGitImport + "/plumbing"},
+		Requires: []string{"gitRepository"},
+	},
+	"gitRevParse": {
+		Source: `// gitRevParse prints what git rev-parse prints for rev in mode: "" for the
+// commit hash, "short" for its first seven digits, "abbrev-ref" for the
+// branch HEAD is on and "show-toplevel" for the root of the working tree.
+func gitRevParse(stdio *streams, rev, mode string) error {
+	repo, err := gitRepository()
+	if err != nil {
+		return err
+	}
+	switch mode {
+	case "show-toplevel":
+		worktree, err := repo.Worktree()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdio.Stdout, worktree.Filesystem.Root())
+		return nil
+	case "abbrev-ref":
+		head, err := repo.Head()
+		if err != nil {
+			return err
+		}
+		if head.Name().IsBranch() {
+			fmt.Fprintln(stdio.Stdout, head.Name().Short())
+		} else {
+			fmt.Fprintln(stdio.Stdout, "HEAD")
+		}
+		return nil
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return err
+	}
+	if mode == "short" {
+		fmt.Fprintln(stdio.Stdout, hash.String()[:7])
+	} else {
+		fmt.Fprintln(stdio.Stdout, hash.String())
+	}
+	return nil
+}`,
+		Imports:  []string{"fmt", goGitImport + "/plumbing"},
+		Requires: []string{"gitRepository", "streams"},
+	},
+}
+
+// requireHelper marks a runtime helper, and the helpers it depends on, for
+// inclusion in the generated program.
+func (g *GoCodeGenerator) requireHelper(name string) {
+	if g.helpers[name] {
+		return
+	}
+	helper, ok := runtimeHelpers[name]
+	if !ok {
+		panic("generator: unknown runtime helper " + name)
+	}
+
+	g.helpers[name] = true
+	for _, imp := range helper.Imports {
+		g.RequiredImports[imp] = true
+	}
+	for _, dep := range helper.Requires {
+		g.requireHelper(dep)
+	}
+}
+
+// addHelpers adds the source of every required helper to the generated
+// program in a stable order.
+func (g *GoCodeGenerator) addHelpers() {
+	names := make([]string, 0, len(g.helpers))
+	for name := range g.helpers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		g.Generator.AddGlobal(runtimeHelpers[name].Source)
+		g.Generator.AddGlobal("")
+	}
+}
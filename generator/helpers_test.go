@@ -0,0 +1,160 @@
+package generator
+
+import (
+	"go/ast"
+	"go/importer"
+	goparser "go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+// TestRuntimeHelpersTypeCheck tests that every runtime helper using only the
+// standard library compiles
+func TestRuntimeHelpersTypeCheck(t *testing.T) {
+	g := NewGoCodeGenerator(parser.NewIntermediateRepresentation())
+	for name := range runtimeHelpers {
+		if !usesThirdParty(name) {
+			g.requireHelper(name)
+		}
+	}
+
+	g.addHelpers()
+	for imp := range g.RequiredImports {
+		g.Generator.AddImport(imp)
+	}
+	g.Generator.AddFunction(Function{Name: "main"})
+
+	code, err := g.Generator.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	fset := token.NewFileSet()
+	file, err := goparser.ParseFile(fset, "helpers.go", code, 0)
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v\n%s", err, code)
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("main", fset, []*ast.File{file}, nil); err != nil {
+		t.Fatalf("Type check failed: %v\n%s", err, code)
+	}
+}
+
+// usesThirdParty reports whether a helper or one it requires imports a
+// package outside the standard library, which this module does not require
+func usesThirdParty(name string) bool {
+	helper := runtimeHelpers[name]
+	for _, imp := range helper.Imports {
+		if isThirdParty(imp) {
+			return true
+		}
+	}
+	for _, dep := range helper.Requires {
+		if usesThirdParty(dep) {
+			return true
+		}
+	}
+	return false
+}
+
+// TestPatternMatchHelper tests the generated pattern matching helper by
+// running it, since the helper only exists as generated source
+func TestPatternMatchHelper(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping go run in short mode")
+	}
+
+	g := NewGoCodeGenerator(parser.NewIntermediateRepresentation())
+	g.requireHelper("patternMatch")
+	g.RequiredImports["fmt"] = true
+	g.addHelpers()
+	for imp := range g.RequiredImports {
+		g.Generator.AddImport(imp)
+	}
+	g.Generator.AddFunction(Function{
+		Name: "main",
+		Body: []string{
+			`fmt.Println(patternMatch("*.log", "app.log"), patternMatch("*.log", "app.txt"))`,
+			`fmt.Println(patternMatch("[a-c]?", "b1"), patternMatch("[!a-c]?", "b1"))`,
+			`fmt.Println(patternMatch("APP*", "app.log"))`,
+			`shellOptions["nocasematch"] = true`,
+			`fmt.Println(patternMatch("APP*", "app.log"))`,
+			`shellOptions["extglob"] = true`,
+			`fmt.Println(patternMatch("+(ab).txt", "ababab.txt"), patternMatch("@(x|y)z", "yz"), patternMatch("?(a)b", "aab"))`,
+		},
+	})
+
+	code, err := g.Generator.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(path, []byte(code), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	output, err := exec.Command("go", "run", path).CombinedOutput()
+	if err != nil {
+		t.Fatalf("go run failed: %v\n%s", err, output)
+	}
+
+	expected := "true false\ntrue false\nfalse\ntrue\ntrue true false\n"
+	if string(output) != expected {
+		t.Fatalf("Expected output:\n%s\nGot:\n%s", expected, output)
+	}
+}
+
+// TestPosixRegexpHelper tests the conversion of the POSIX regular expressions
+// of grep into Go syntax by running the generated helper
+func TestPosixRegexpHelper(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping go run in short mode")
+	}
+
+	g := NewGoCodeGenerator(parser.NewIntermediateRepresentation())
+	g.requireHelper("posixRegexp")
+	g.RequiredImports["fmt"] = true
+	g.addHelpers()
+	for imp := range g.RequiredImports {
+		g.Generator.AddImport(imp)
+	}
+	g.Generator.AddFunction(Function{
+		Name: "main",
+		Body: []string{
+			`fmt.Println(posixRegexp("a+b(c)", false), posixRegexp("a\\+\\(b\\|c\\)", false))`,
+			`fmt.Println(posixRegexp("*a^b$c$", false), posixRegexp("\\(^a$\\)", false))`,
+			`fmt.Println(posixRegexp("[]a\\]x[[:digit:]]", false), posixRegexp("\\<w\\>", false))`,
+			`fmt.Println(posixRegexp("(a|b)+$", true), posixRegexp("x[", true))`,
+		},
+	})
+
+	code, err := g.Generator.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(path, []byte(code), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	output, err := exec.Command("go", "run", path).CombinedOutput()
+	if err != nil {
+		t.Fatalf("go run failed: %v\n%s", err, output)
+	}
+
+	expected := "a\\+b\\(c\\) a+(b|c)\n\\*a\\^b\\$c$ (^a$)\n[\\]a\\\\]x[[:digit:]] \\bw\\b\n(a|b)+$ x\\[\n"
+	if string(output) != expected {
+		t.Fatalf("Expected output:\n%s\nGot:\n%s", expected, output)
+	}
+}
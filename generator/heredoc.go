@@ -0,0 +1,48 @@
+package generator
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+// stdinInput returns a Go string expression for the input that the
+// here-string or here-document of cmd feeds to its standard input, or an
+// empty string if it has neither
+func (g *GoCodeGenerator) stdinInput(cmd parser.Command) string {
+	switch {
+	case cmd.Heredoc != nil:
+		return g.heredocBody(*cmd.Heredoc)
+	case cmd.Stdin != "":
+		return g.hereString(cmd.Stdin)
+	}
+	return ""
+}
+
+// heredocBody converts the body of a here-document into a Go string
+// expression, expanding its expansions when the program runs
+func (g *GoCodeGenerator) heredocBody(heredoc parser.Heredoc) string {
+	var parts []string
+	var lit strings.Builder
+	for _, part := range heredoc.Parts {
+		if !part.Expansion {
+			lit.WriteString(part.Text)
+			continue
+		}
+		expr := g.goArg(part.Text)
+		if s, err := strconv.Unquote(expr); err == nil {
+			lit.WriteString(s)
+			continue
+		}
+		if lit.Len() > 0 {
+			parts = append(parts, strconv.Quote(lit.String()))
+			lit.Reset()
+		}
+		parts = append(parts, expr)
+	}
+	if lit.Len() > 0 || len(parts) == 0 {
+		parts = append(parts, strconv.Quote(lit.String()))
+	}
+	return strings.Join(parts, " + ")
+}
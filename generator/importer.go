@@ -0,0 +1,14 @@
+//go:build !js
+
+package generator
+
+import (
+	"go/importer"
+	"go/types"
+)
+
+// packageImporter returns the importer TypeCheck resolves imports with: the
+// export data of the installed Go toolchain
+func packageImporter() types.Importer {
+	return importer.Default()
+}
@@ -0,0 +1,23 @@
+//go:build js
+
+package generator
+
+import (
+	"errors"
+	"go/types"
+)
+
+// noImporter fails every import, since a WebAssembly host has neither a Go
+// toolchain nor os/exec to query one
+type noImporter struct{}
+
+// Import implements types.Importer
+func (noImporter) Import(path string) (*types.Package, error) {
+	return nil, errors.New("imports are not available in WebAssembly")
+}
+
+// packageImporter returns the importer TypeCheck resolves imports with.
+// Failed imports are skipped, so only the package itself is checked.
+func packageImporter() types.Importer {
+	return noImporter{}
+}
@@ -0,0 +1,173 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TFMV/bash2go/diagnostics"
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	runtimeHelpers["jobs"] = runtimeHelper{
+		Source: `// jobs tracks the commands the script runs in the background by job ID.
+// The IDs stand in for the process IDs Bash keeps in $!
+var jobs = struct {
+	sync.Mutex
+	last int
+	all  map[int]*job
+}{all: map[int]*job{}}
+
+// job is a command running in the background
+type job struct {
+	done chan struct{}
+	err  error
+}
+
+// startJob runs commands in the background and returns the ID of the job.
+// The commands run with a copy of the streams of stdio, so that their
+// redirections do not affect the script, and like in Bash their standard
+// input is /dev/null.
+func startJob(stdio *streams, commands func(stdio *streams) error) int {
+	j := &job{done: make(chan struct{})}
+	jobs.Lock()
+	jobs.last++
+	id := jobs.last
+	jobs.all[id] = j
+	jobs.Unlock()
+
+	background := stdio.redirected()
+	null, err := os.Open(os.DevNull)
+	if err == nil {
+		background.Stdin = null
+	}
+	go func() {
+		defer close(j.done)
+		j.err = commands(background)
+		if null != nil {
+			null.Close()
+		}
+	}()
+	return id
+}
+
+// waitJobs waits for all background jobs to finish, as wait does
+func waitJobs() {
+	jobs.Lock()
+	all := make([]*job, 0, len(jobs.all))
+	for _, j := range jobs.all {
+		all = append(all, j)
+	}
+	jobs.Unlock()
+
+	for _, j := range all {
+		<-j.done
+	}
+}
+
+// waitJob waits for the background job with the given ID to finish, as
+// wait ID does, and returns its error
+func waitJob(id string) error {
+	n, _ := strconv.Atoi(id)
+	jobs.Lock()
+	j, ok := jobs.all[n]
+	jobs.Unlock()
+	if !ok {
+		return fmt.Errorf("wait: pid %s is not a child of this shell", id)
+	}
+	<-j.done
+	return j.err
+}`,
+		Imports:  []string{"fmt", "os", "strconv", "sync"},
+		Requires: []string{"streams"},
+	}
+}
+
+// generateBackground generates Go code running a command in the background
+// as a job, whose ID becomes $!. Like in Bash, a failing job does not fail
+// the script; its error is returned by wait.
+func (g *GoCodeGenerator) generateBackground(background parser.Background) (string, error) {
+	g.inJob = true
+	cmdCode, err := g.generateCommand(background.Command)
+	g.inJob = false
+	if err != nil {
+		return "", err
+	}
+	g.requireHelper("jobs")
+	code := fmt.Sprintf("startJob(%[1]s, func(%[1]s *streams) error {\n%[2]s\n})", g.stdio(), bodyWithReturn(cmdCode))
+	if g.usesShellVar("!") {
+		g.RequiredImports["strconv"] = true
+		code = g.setShellVarCode("!", fmt.Sprintf("strconv.Itoa(%s)", code))
+	}
+
+	// Like the subshell a job runs in in Bash, the job takes the values the
+	// variables it uses have when it starts, such as that of a loop
+	// variable, and its assignments do not change them for the script
+	var snapshot []string
+	for _, name := range background.Vars {
+		switch {
+		case !g.isScriptVariable(name), g.isConstant(name):
+		case g.isAssocArray(name):
+			g.RequiredImports["maps"] = true
+			snapshot = append(snapshot, fmt.Sprintf("%s := maps.Clone(%s)", name, name))
+		default:
+			snapshot = append(snapshot, fmt.Sprintf("%s := %s", name, name))
+		}
+	}
+	if len(snapshot) == 0 {
+		return code, nil
+	}
+	return fmt.Sprintf("{\n%s\n%s\n}", strings.Join(snapshot, "\n"), code), nil
+}
+
+// usesJobs reports whether the script runs commands in the background.
+func (g *GoCodeGenerator) usesJobs() bool {
+	found := false
+	record := func(stmt parser.Statement) {
+		if stmt.Type == parser.StatementBackground {
+			found = true
+		}
+	}
+	walkStatements(g.IR.MainStatements, record)
+	for _, function := range g.IR.Functions {
+		walkStatements(function.Statements, record)
+	}
+	return found
+}
+
+// jobsPrologue returns the statements emitted at the start of run to wait
+// for the background jobs still running when the script ends. Bash leaves
+// them running after it exits, but a Go program ending would kill them, so
+// the program waits for them instead.
+func (g *GoCodeGenerator) jobsPrologue() []string {
+	if !g.usesJobs() {
+		return nil
+	}
+	g.requireHelper("jobs")
+	return []string{"defer waitJobs()"}
+}
+
+// generateWait generates Go code for the wait builtin. Without arguments it
+// waits for all background jobs; with job IDs from $! it waits for those
+// jobs and fails like the last of them.
+func (g *GoCodeGenerator) generateWait(cmd parser.Command) string {
+	g.requireHelper("jobs")
+	if len(cmd.Args) == 0 {
+		if g.usesShellVar("?") {
+			return "waitJobs()\n" + g.setShellVarCode("?", `"0"`)
+		}
+		return "waitJobs()"
+	}
+	if strings.HasPrefix(cmd.Args[0], "-") {
+		g.unsupported++
+		g.IR.Diagnose(diagnostics.SeverityError, cmd.Pos, diagnostics.CodeUnsupported,
+			"wait %s is not supported", cmd.Args[0])
+		return fmt.Sprintf("// Unsupported wait: %s", commentText(strings.Join(cmd.Args, " ")))
+	}
+
+	var lines []string
+	for _, arg := range cmd.Args {
+		lines = append(lines, g.checkStatus(fmt.Sprintf("waitJob(%s)", g.goArg(arg)), g.errReturn(cmd)))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,46 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+// localDecls returns the declarations of the variables function declares
+// local, as Go variables scoped to the translated function that shadow
+// script variables of the same name. Like in Bash, they start out empty.
+// It must be called once the body of the function has been generated, so
+// that locals the body never reads can be marked as used. Table variables
+// are cleared instead, and get their value back when the function returns.
+func (g *GoCodeGenerator) localDecls(function *parser.Function) []string {
+	names := make([]string, 0, len(function.LocalVars))
+	for name := range function.LocalVars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var lines []string
+	for _, name := range names {
+		if !isValidVarName(name) {
+			continue
+		}
+		if g.isTableVar(name) {
+			lines = append(lines, fmt.Sprintf("defer %s", g.setShellVarCode(name, g.shellVarRef(name))),
+				g.setShellVarCode(name, `""`))
+			continue
+		}
+		if g.isAssocArray(name) {
+			lines = append(lines, fmt.Sprintf("var %s = map[string]string{}", name))
+		} else if g.isIntVar(name) {
+			lines = append(lines, fmt.Sprintf("var %s int", name))
+		} else {
+			lines = append(lines, fmt.Sprintf("var %s string", name))
+		}
+		if !g.readVars[name] {
+			// Go rejects variables that are assigned but never read
+			lines = append(lines, "_ = "+name)
+		}
+	}
+	return lines
+}
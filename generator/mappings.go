@@ -0,0 +1,195 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/TFMV/bash2go/diagnostics"
+	"github.com/TFMV/bash2go/parser"
+	"gopkg.in/yaml.v3"
+)
+
+// Mapping policies for commands without a template
+const (
+	// MappingExec runs the command as an external command without
+	// consulting plugins
+	MappingExec = "exec"
+	// MappingError makes conversion fail if the script runs the command
+	MappingError = "error"
+)
+
+// Mapping declares how an external command is translated: either with a
+// Go template or with a policy
+type Mapping struct {
+	// Template renders the Go statements replacing the command; see
+	// MappingData for the fields it can use
+	Template string `yaml:"template,omitempty"`
+	// Imports lists the packages the rendered code needs
+	Imports []string `yaml:"imports,omitempty"`
+	// Policy is MappingExec or MappingError
+	Policy string `yaml:"policy,omitempty"`
+	// Message explains the MappingError policy to the script's author
+	Message string `yaml:"message,omitempty"`
+
+	tmpl *template.Template
+}
+
+// MappingData is the data a mapping template is executed with
+type MappingData struct {
+	Name      string   // Command name
+	Args      []string // Go string expression for each argument
+	ArgList   string   // Args separated by commas, for variadic calls
+	ErrReturn string   // Statement returning err annotated with the command's location
+	Stdin     string   // Go string expression for the input of a here-string or here-document, or empty
+}
+
+// Mappings maps command names to their translation
+type Mappings map[string]*Mapping
+
+// mappingsFile is the layout of a mappings file
+type mappingsFile struct {
+	Commands Mappings `yaml:"commands"`
+}
+
+// ParseMappings reads command mappings in YAML, such as:
+//
+//	commands:
+//	  deploy:
+//	    template: |
+//	      if err := deployer.Deploy({{.ArgList}}); err != nil {
+//	      	{{.ErrReturn}}
+//	      }
+//	    imports: [example.com/deployer]
+//	  rsync:
+//	    policy: exec
+//	  telnet:
+//	    policy: error
+//	    message: use ssh instead
+func ParseMappings(r io.Reader) (Mappings, error) {
+	var file mappingsFile
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(file.Commands))
+	for name := range file.Commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := file.Commands[name].compile(name); err != nil {
+			return nil, fmt.Errorf("command %s: %v", name, err)
+		}
+	}
+	if file.Commands == nil {
+		file.Commands = make(Mappings)
+	}
+	return file.Commands, nil
+}
+
+// LoadMappings reads command mappings from a YAML file
+func LoadMappings(path string) (Mappings, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	mappings, err := ParseMappings(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return mappings, nil
+}
+
+// compile validates the mapping and parses its template
+func (m *Mapping) compile(name string) error {
+	if m == nil {
+		return fmt.Errorf("no template or policy")
+	}
+	switch {
+	case m.Template != "" && m.Policy != "":
+		return fmt.Errorf("template and policy are mutually exclusive")
+	case m.Template == "" && len(m.Imports) > 0:
+		return fmt.Errorf("imports require a template")
+	case m.Template == "" && m.Policy == "":
+		return fmt.Errorf("no template or policy")
+	case m.Policy != "" && m.Policy != MappingExec && m.Policy != MappingError:
+		return fmt.Errorf("unknown policy %q (want %s or %s)", m.Policy, MappingExec, MappingError)
+	case m.Template == "":
+		return nil
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(m.Template)
+	if err != nil {
+		return err
+	}
+	m.tmpl = tmpl
+	return nil
+}
+
+// mappedCommand translates cmd according to its entry in Options.Mappings.
+// It reports false if the command has no template mapping or its template
+// fails, in which case the command runs as an external command.
+func (g *GoCodeGenerator) mappedCommand(cmd parser.Command) (string, bool, error) {
+	mapping := g.Options.Mappings[cmd.Name]
+	if mapping == nil || mapping.Policy == MappingExec {
+		return "", false, nil
+	}
+	if mapping.Policy == MappingError {
+		msg := fmt.Sprintf("%s is not allowed by the command mappings", cmd.Name)
+		if mapping.Message != "" {
+			msg += ": " + mapping.Message
+		}
+		g.IR.Diagnose(diagnostics.SeverityError, cmd.Pos, diagnostics.CodeMapping, "%s", msg)
+		if loc := g.location(cmd.Pos); loc != "" {
+			msg = loc + ": " + msg
+		}
+		return "", false, fmt.Errorf("%s", msg)
+	}
+
+	// Mappings built in code rather than by ParseMappings are compiled on
+	// each use
+	tmpl := mapping.tmpl
+	if tmpl == nil {
+		compiled := *mapping
+		if err := compiled.compile(cmd.Name); err != nil {
+			return "", false, fmt.Errorf("mapping for %s: %v", cmd.Name, err)
+		}
+		tmpl = compiled.tmpl
+	}
+
+	data := MappingData{
+		Name:      cmd.Name,
+		Args:      make([]string, len(cmd.Args)),
+		ErrReturn: g.errReturn(cmd),
+	}
+	for i, arg := range cmd.Args {
+		data.Args[i] = g.goArg(arg)
+	}
+	data.ArgList = strings.Join(data.Args, ", ")
+	data.Stdin = g.stdinInput(cmd)
+
+	var out bytes.Buffer
+	err := tmpl.Execute(&out, data)
+	if err == nil {
+		err = checkStatements(out.String())
+	}
+	if err != nil {
+		g.IR.Diagnose(diagnostics.SeverityWarning, cmd.Pos, diagnostics.CodeMapping,
+			"mapping for %s failed, executing it as an external command: %v", cmd.Name, err)
+		return "", false, nil
+	}
+
+	for _, imp := range mapping.Imports {
+		g.RequiredImports[imp] = true
+	}
+	return fmt.Sprintf("// %s translated by its mapping\n%s", cmd.Name, strings.TrimSpace(out.String())), true, nil
+}
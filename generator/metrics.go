@@ -0,0 +1,82 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/TFMV/bash2go/diagnostics"
+	"github.com/TFMV/bash2go/parser"
+)
+
+// Metrics summarizes how much of a script was translated to native Go code.
+type Metrics struct {
+	Native        int      `json:"native"`         // Statements translated to native Go
+	ExecFallbacks int      `json:"exec_fallbacks"` // Statements run as external commands
+	Unsupported   int      `json:"unsupported"`    // Constructs that could not be translated
+	Dependencies  []string `json:"dependencies"`   // Third-party packages imported by the generated code
+}
+
+// Total returns the number of statements and constructs considered.
+func (m Metrics) Total() int {
+	return m.Native + m.ExecFallbacks + m.Unsupported
+}
+
+// NativePercent returns the percentage of statements translated natively.
+func (m Metrics) NativePercent() float64 {
+	if m.Total() == 0 {
+		return 100
+	}
+	return float64(m.Native) * 100 / float64(m.Total())
+}
+
+// String formats the metrics as a one-line summary.
+func (m Metrics) String() string {
+	deps := "none"
+	if len(m.Dependencies) > 0 {
+		deps = strings.Join(m.Dependencies, ", ")
+	}
+	return fmt.Sprintf("Coverage: %.1f%% native (%d native, %d exec fallbacks, %d unsupported); dependencies: %s",
+		m.NativePercent(), m.Native, m.ExecFallbacks, m.Unsupported, deps)
+}
+
+// Metrics returns the coverage metrics of the last Generate call.
+func (g *GoCodeGenerator) Metrics() Metrics {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	m := g.metrics
+	m.Unsupported = 0
+	for _, d := range g.IR.Diagnostics.Items() {
+		if d.Severity == diagnostics.SeverityError && d.Code == diagnostics.CodeUnsupported {
+			m.Unsupported++
+		}
+	}
+
+	m.Dependencies = []string{}
+	for imp := range g.RequiredImports {
+		if isThirdParty(imp) {
+			m.Dependencies = append(m.Dependencies, imp)
+		}
+	}
+	sort.Strings(m.Dependencies)
+
+	return m
+}
+
+// isSimpleStatement reports whether a statement is counted on its own in the
+// metrics. Compound statements are covered by the statements they contain.
+func isSimpleStatement(t parser.StatementType) bool {
+	switch t {
+	case parser.StatementIf, parser.StatementLoop, parser.StatementSubshell, parser.StatementFunction, parser.StatementAndOr, parser.StatementGetopts, parser.StatementBlock, parser.StatementNegation:
+		return false
+	default:
+		return true
+	}
+}
+
+// isThirdParty reports whether an import path is outside the standard library.
+func isThirdParty(importPath string) bool {
+	first, _, _ := strings.Cut(importPath, "/")
+	return strings.Contains(first, ".")
+}
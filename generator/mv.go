@@ -0,0 +1,170 @@
+package generator
+
+import (
+	"fmt"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	utilities["mv"] = (*GoCodeGenerator).generateMv
+
+	runtimeHelpers["mv"] = runtimeHelper{
+		Source: `// mv moves files like mv -f: it renames the first file to the last one, or
+// the files into the last one when it is a directory or there are several
+// of them, copying and removing the files that are on another file system.
+// It fails with status 1 if a file cannot be moved.
+func mv(stdio *streams, files ...string) error {
+	switch len(files) {
+	case 0:
+		fmt.Fprintln(stdio.Stderr, "mv: missing file operand")
+		fmt.Fprintln(stdio.Stderr, "Try 'mv --help' for more information.")
+		return exitError(1)
+	case 1:
+		fmt.Fprintf(stdio.Stderr, "mv: missing destination file operand after '%s'\n", files[0])
+		fmt.Fprintln(stdio.Stderr, "Try 'mv --help' for more information.")
+		return exitError(1)
+	}
+	sources, target := files[:len(files)-1], files[len(files)-1]
+	info, err := os.Stat(target)
+	into := err == nil && info.IsDir()
+	if len(sources) > 1 && !into {
+		if err == nil {
+			err = syscall.ENOTDIR
+		}
+		fmt.Fprintf(stdio.Stderr, "mv: target '%s': %s\n", target, utilityMessage(err))
+		return exitError(1)
+	}
+	failed := false
+	for _, source := range sources {
+		dest := target
+		if into && strings.HasSuffix(target, "/") {
+			dest = target + filepath.Base(source)
+		} else if into {
+			dest = target + "/" + filepath.Base(source)
+		}
+		if !mvFile(stdio, source, dest) {
+			failed = true
+		}
+	}
+	if failed {
+		return exitError(1)
+	}
+	return nil
+}
+
+// mvFile moves source to dest for mv, reporting why it cannot
+func mvFile(stdio *streams, source, dest string) bool {
+	info, err := os.Lstat(source)
+	if err != nil {
+		fmt.Fprintf(stdio.Stderr, "mv: cannot stat '%s': %s\n", source, utilityMessage(err))
+		return false
+	}
+	if existing, err := os.Lstat(dest); err == nil {
+		switch {
+		case os.SameFile(info, existing):
+			fmt.Fprintf(stdio.Stderr, "mv: '%s' and '%s' are the same file\n", source, dest)
+			return false
+		case info.IsDir() && !existing.IsDir():
+			fmt.Fprintf(stdio.Stderr, "mv: cannot overwrite non-directory '%s' with directory '%s'\n", dest, source)
+			return false
+		case !info.IsDir() && existing.IsDir():
+			fmt.Fprintf(stdio.Stderr, "mv: cannot overwrite directory '%s' with non-directory\n", dest)
+			return false
+		}
+	}
+	err = os.Rename(source, dest)
+	if errors.Is(err, syscall.EINVAL) {
+		fmt.Fprintf(stdio.Stderr, "mv: cannot move '%s' to a subdirectory of itself, '%s'\n", source, dest)
+		return false
+	}
+	if errors.Is(err, syscall.EEXIST) {
+		// Like mv, directories not empty are not said to exist
+		err = syscall.ENOTEMPTY
+	}
+	if errors.Is(err, syscall.EXDEV) {
+		// Files are copied to other file systems, replacing dest
+		if err = os.Remove(dest); err == nil || errors.Is(err, fs.ErrNotExist) {
+			if err = mvCopy(source, dest); err == nil {
+				err = os.RemoveAll(source)
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(stdio.Stderr, "mv: cannot move '%s' to '%s': %s\n", source, dest, utilityMessage(err))
+		return false
+	}
+	return true
+}
+
+// mvCopy copies source to dest for mv across file systems, with the
+// contents of directories, as symbolic links for those, and with the modes
+// and modification times of the files
+func mvCopy(source, dest string) error {
+	info, err := os.Lstat(source)
+	if err != nil {
+		return err
+	}
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		link, err := os.Readlink(source)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(link, dest)
+	case info.IsDir():
+		if err := os.Mkdir(dest, 0700); err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(source)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := mvCopy(filepath.Join(source, entry.Name()), filepath.Join(dest, entry.Name())); err != nil {
+				return err
+			}
+		}
+	case info.Mode().IsRegular():
+		in, err := os.Open(source)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		if err := out.Close(); err != nil {
+			return err
+		}
+	default:
+		return &fs.PathError{Op: "copy", Path: source, Err: syscall.EINVAL}
+	}
+	if err := os.Chmod(dest, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(dest, info.ModTime(), info.ModTime())
+}`,
+		Imports:  []string{"errors", "fmt", "io", "io/fs", "os", "path/filepath", "strings", "syscall"},
+		Requires: []string{"exitError", "utilityError"},
+	}
+}
+
+// generateMv translates mv, with -f only, into a call of the mv helper,
+// which renames the files with os.Rename or copies them to other file
+// systems. It reports false for other options, such as -i and -t, so that
+// mv is executed.
+func (g *GoCodeGenerator) generateMv(cmd parser.Command) (string, bool) {
+	_, operands, ok := g.utilityArgs(cmd, "f", "")
+	if !ok {
+		return "", false
+	}
+	g.requireHelper("mv")
+	call := fmt.Sprintf("mv(%s%s)", g.stdio(), g.operandArgs(cmd, operands))
+	return g.checkErr(cmd, call), true
+}
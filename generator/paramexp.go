@@ -0,0 +1,398 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	runtimeHelpers["orDefault"] = runtimeHelper{
+		Source: `// orDefault returns value, or def if value is empty, as ${name:-def} does
+func orDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}`,
+	}
+	runtimeHelpers["envOrDefault"] = runtimeHelper{
+		Source: `// envOrDefault returns the value of an environment variable, or def if it
+// is unset, as ${name-def} does
+func envOrDefault(name, def string) string {
+	if value, ok := os.LookupEnv(name); ok {
+		return value
+	}
+	return def
+}`,
+		Imports: []string{"os"},
+	}
+	runtimeHelpers["alternate"] = runtimeHelper{
+		Source: `// alternate returns alt if value is not empty, and an empty string
+// otherwise, as ${name:+alt} does
+func alternate(value, alt string) string {
+	if value == "" {
+		return ""
+	}
+	return alt
+}`,
+	}
+	runtimeHelpers["envAlternate"] = runtimeHelper{
+		Source: `// envAlternate returns alt if an environment variable is set, and an empty
+// string otherwise, as ${name+alt} does
+func envAlternate(name, alt string) string {
+	if _, ok := os.LookupEnv(name); ok {
+		return alt
+	}
+	return ""
+}`,
+		Imports: []string{"os"},
+	}
+	runtimeHelpers["argAlternate"] = runtimeHelper{
+		Source: `// argAlternate returns alt if args has a positional parameter $n, and an
+// empty string otherwise, as ${n+alt} does
+func argAlternate(args []string, n int, alt string) string {
+	if n > len(args) {
+		return ""
+	}
+	return alt
+}`,
+	}
+	runtimeHelpers["replacePattern"] = runtimeHelper{
+		Source: `// replacePattern replaces the longest text of s matching a shell pattern
+// with repl, as ${name/pattern/repl} does. The operator tells which matches
+// are replaced: the first for "/", all of them for "//", one at the start
+// of s for "/#" and one at its end for "/%".
+func replacePattern(s, pattern, repl, op string) string {
+	switch op {
+	case "/#":
+		for end := len(s); end >= 0; end-- {
+			if (end == len(s) || utf8.RuneStart(s[end])) && patternMatch(pattern, s[:end]) {
+				return repl + s[end:]
+			}
+		}
+		return s
+	case "/%":
+		for start := 0; start <= len(s); start++ {
+			if (start == len(s) || utf8.RuneStart(s[start])) && patternMatch(pattern, s[start:]) {
+				return s[:start] + repl
+			}
+		}
+		return s
+	}
+	if pattern == "" {
+		return s
+	}
+	if s == "" && patternMatch(pattern, s) {
+		return repl
+	}
+	var result strings.Builder
+	for i := 0; i < len(s); {
+		end := -1
+		for j := len(s); j > i; j-- {
+			if (j == len(s) || utf8.RuneStart(s[j])) && patternMatch(pattern, s[i:j]) {
+				end = j
+				break
+			}
+		}
+		if end < 0 {
+			_, size := utf8.DecodeRuneInString(s[i:])
+			result.WriteString(s[i : i+size])
+			i += size
+			continue
+		}
+		result.WriteString(repl)
+		if op != "//" {
+			result.WriteString(s[end:])
+			return result.String()
+		}
+		i = end
+	}
+	return result.String()
+}`,
+		Imports:  []string{"strings", "unicode/utf8"},
+		Requires: []string{"patternMatch"},
+	}
+	runtimeHelpers["convertCase"] = runtimeHelper{
+		Source: `// convertCase converts the first character of s, or all of them, to upper
+// or lower case if it matches a shell pattern, as ${name^pattern} and
+// ${name,,pattern} do. An empty pattern matches any character.
+func convertCase(s, pattern string, upper, all bool) string {
+	chars := []rune(s)
+	for i, c := range chars {
+		if i > 0 && !all {
+			break
+		}
+		if pattern != "" && !patternMatch(pattern, string(c)) {
+			continue
+		}
+		if upper {
+			chars[i] = unicode.ToUpper(c)
+		} else {
+			chars[i] = unicode.ToLower(c)
+		}
+	}
+	return string(chars)
+}`,
+		Imports:  []string{"unicode"},
+		Requires: []string{"patternMatch"},
+	}
+	runtimeHelpers["removePattern"] = runtimeHelper{
+		Source: `// removePattern removes the shortest or longest prefix or suffix of s that
+// matches a shell pattern, as ${name#pattern}, ${name##pattern},
+// ${name%pattern} and ${name%%pattern} do
+func removePattern(s, pattern string, suffix, longest bool) string {
+	for n := 0; n <= len(s); n++ {
+		// Try the shortest match first unless the longest is wanted
+		i := n
+		if longest {
+			i = len(s) - n
+		}
+		if suffix {
+			i = len(s) - i
+		}
+		if i < len(s) && !utf8.RuneStart(s[i]) {
+			continue
+		}
+		if suffix && patternMatch(pattern, s[i:]) {
+			return s[:i]
+		}
+		if !suffix && patternMatch(pattern, s[:i]) {
+			return s[i:]
+		}
+	}
+	return s
+}`,
+		Imports:  []string{"unicode/utf8"},
+		Requires: []string{"patternMatch"},
+	}
+	runtimeHelpers["substring"] = runtimeHelper{
+		Source: `// substring returns the characters of s from offset on, or at most length
+// of them if given, as ${name:offset:length} does. A negative offset counts
+// from the end of s and a negative length leaves off as many characters at
+// the end. Offsets and lengths out of range give an empty string.
+func substring(s string, offset int, length ...int) string {
+	chars := []rune(s)
+	if offset < 0 {
+		offset += len(chars)
+	}
+	if offset < 0 || offset > len(chars) {
+		return ""
+	}
+	end := len(chars)
+	if len(length) > 0 {
+		if length[0] < 0 {
+			end += length[0]
+		} else if offset+length[0] < end {
+			end = offset + length[0]
+		}
+	}
+	if end < offset {
+		return ""
+	}
+	return string(chars[offset:end])
+}`,
+	}
+	runtimeHelpers["assignDefault"] = runtimeHelper{
+		Source: `// assignDefault assigns def to a variable if it is empty and returns the
+// variable's value, as ${name:=def} does
+func assignDefault(v *string, def string) string {
+	if *v == "" {
+		*v = def
+	}
+	return *v
+}`,
+	}
+}
+
+// paramExpansion converts the inside of a ${...} expansion with a default or
+// alternate value, a pattern to remove or replace, a case conversion, a
+// substring or a length into a Go string expression. It reports false for
+// other expansions.
+//
+// Script variables are Go strings that exist from the start, so they cannot
+// be told apart from unset ones when empty: for them ${name-def},
+// ${name=def} and ${name+alt} behave like ${name:-def}, ${name:=def} and
+// ${name:+alt}. Only environment
+// variables are looked up to tell unset from empty. Numbered positional
+// parameters, such as ${1:-def}, are read from args.
+func (g *GoCodeGenerator) paramExpansion(expr string) (string, bool) {
+	p, err := parser.ParseParamExpansion(expr)
+	if err != nil || g.isAssocArray(p.Name) {
+		return "", false
+	}
+	numbered := isNumberedArg(p.Name)
+	if !numbered && !isValidVarName(p.Name) && !g.isShellVar(p.Name) {
+		return "", false
+	}
+	switch {
+	case p.IsRemoval():
+		return g.patternRemoval(p), true
+	case p.IsReplacement():
+		return g.patternReplacement(p), true
+	case p.IsCaseConversion():
+		return g.caseConversion(p), true
+	case p.Length:
+		// Bash counts characters, not bytes
+		g.RequiredImports["strconv"] = true
+		g.RequiredImports["unicode/utf8"] = true
+		return fmt.Sprintf("strconv.Itoa(utf8.RuneCountInString(%s))", g.varRef(p.Name)), true
+	case p.IsSubstring():
+		g.requireHelper("substring")
+		args := []string{g.varRef(p.Name), g.arithExpr(p.Offset)}
+		if p.Count != nil {
+			args = append(args, g.arithExpr(p.Count))
+		}
+		return fmt.Sprintf("substring(%s)", strings.Join(args, ", ")), true
+	}
+	def := g.goArg(p.Word)
+
+	if p.IsAlternate() {
+		return g.alternateValue(p, def), true
+	}
+	if p.IsAssign() && numbered {
+		// Positional parameters cannot be assigned
+		g.requireHelper("assignArg")
+		return fmt.Sprintf("assignArg(args, %s, %t)", p.Name, p.CheckNull()), true
+	}
+	if p.IsAssign() {
+		if !isValidVarName(p.Name) || !g.isScriptVariable(p.Name) {
+			return "", false
+		}
+		g.requireHelper("assignDefault")
+		return fmt.Sprintf("assignDefault(&%s, %s)", p.Name, def), true
+	}
+
+	if numbered {
+		// Unlike $1, the parameter may be unset under set -u
+		if p.CheckNull() {
+			g.requireHelper("orDefault")
+			g.requireHelper("positionalArg")
+			return fmt.Sprintf("orDefault(positionalArg(args, %s), %s)", p.Name, def), true
+		}
+		g.requireHelper("argOrDefault")
+		return fmt.Sprintf("argOrDefault(args, %s, %s)", p.Name, def), true
+	}
+
+	if !p.CheckNull() && !g.isScriptVariable(p.Name) && !g.isShellVar(p.Name) {
+		if g.envPolicy(p.Name) == parser.EnvConvert {
+			if value, ok := os.LookupEnv(p.Name); ok {
+				return strconv.Quote(value), true
+			}
+			return def, true
+		}
+		g.requireHelper("envOrDefault")
+		return fmt.Sprintf("envOrDefault(%s, %s)", strconv.Quote(p.Name), def), true
+	}
+	g.requireHelper("orDefault")
+	return fmt.Sprintf("orDefault(%s, %s)", g.defaultRef(p.Name), def), true
+}
+
+// patternRemoval converts ${name#pattern} and its variants into a Go string
+// expression. Literal patterns are removed with strings.TrimPrefix and
+// strings.TrimSuffix, for which the shortest and longest match are the same.
+func (g *GoCodeGenerator) patternRemoval(p *parser.ParamExpansion) string {
+	value := g.varRef(p.Name)
+	suffix := strings.HasPrefix(p.Op, "%")
+	pattern := g.goArg(p.Word)
+	if s, err := strconv.Unquote(pattern); err == nil {
+		if literal, ok := literalPattern(s); ok {
+			g.RequiredImports["strings"] = true
+			trim := "TrimPrefix"
+			if suffix {
+				trim = "TrimSuffix"
+			}
+			return fmt.Sprintf("strings.%s(%s, %s)", trim, value, strconv.Quote(literal))
+		}
+	}
+	g.requireHelper("removePattern")
+	return fmt.Sprintf("removePattern(%s, %s, %t, %t)", value, pattern, suffix, len(p.Op) == 2)
+}
+
+// alternateValue converts ${name:+alt} and ${name+alt} into a Go string
+// expression, given that of alt
+func (g *GoCodeGenerator) alternateValue(p *parser.ParamExpansion, alt string) string {
+	numbered := isNumberedArg(p.Name)
+	switch {
+	case numbered && p.CheckNull():
+		g.requireHelper("alternate")
+		g.requireHelper("positionalArg")
+		return fmt.Sprintf("alternate(positionalArg(args, %s), %s)", p.Name, alt)
+	case numbered:
+		g.requireHelper("argAlternate")
+		return fmt.Sprintf("argAlternate(args, %s, %s)", p.Name, alt)
+	case !p.CheckNull() && !g.isScriptVariable(p.Name) && !g.isShellVar(p.Name):
+		if g.envPolicy(p.Name) == parser.EnvConvert {
+			if _, ok := os.LookupEnv(p.Name); ok {
+				return alt
+			}
+			return `""`
+		}
+		g.requireHelper("envAlternate")
+		return fmt.Sprintf("envAlternate(%s, %s)", strconv.Quote(p.Name), alt)
+	}
+	g.requireHelper("alternate")
+	return fmt.Sprintf("alternate(%s, %s)", g.defaultRef(p.Name), alt)
+}
+
+// patternReplacement converts ${name/pattern/string} and its variants into a
+// Go string expression. The first or every occurrence of a literal pattern
+// is replaced with strings.Replace or strings.ReplaceAll.
+func (g *GoCodeGenerator) patternReplacement(p *parser.ParamExpansion) string {
+	value := g.varRef(p.Name)
+	pattern := g.goArg(p.Word)
+	repl := g.goArg(p.Replacement)
+	if s, err := strconv.Unquote(pattern); err == nil && (p.Op == "/" || p.Op == "//") {
+		if literal, ok := literalPattern(s); ok && literal != "" {
+			g.RequiredImports["strings"] = true
+			if p.Op == "//" {
+				return fmt.Sprintf("strings.ReplaceAll(%s, %s, %s)", value, strconv.Quote(literal), repl)
+			}
+			return fmt.Sprintf("strings.Replace(%s, %s, %s, 1)", value, strconv.Quote(literal), repl)
+		}
+	}
+	g.requireHelper("replacePattern")
+	return fmt.Sprintf("replacePattern(%s, %s, %s, %s)", value, pattern, repl, strconv.Quote(p.Op))
+}
+
+// caseConversion converts ${name^^}, ${name,} and their variants into a Go
+// string expression. Converting every character needs no pattern matching
+// without a pattern, with strings.ToUpper or strings.ToLower.
+func (g *GoCodeGenerator) caseConversion(p *parser.ParamExpansion) string {
+	value := g.varRef(p.Name)
+	upper := strings.HasPrefix(p.Op, "^")
+	all := len(p.Op) == 2
+	if p.Word == "" && all {
+		g.RequiredImports["strings"] = true
+		if upper {
+			return fmt.Sprintf("strings.ToUpper(%s)", value)
+		}
+		return fmt.Sprintf("strings.ToLower(%s)", value)
+	}
+	g.requireHelper("convertCase")
+	return fmt.Sprintf("convertCase(%s, %s, %t, %t)", value, g.goArg(p.Word), upper, all)
+}
+
+// literalPattern returns the text a shell pattern matches if it has no
+// wildcards, with its escapes removed
+func literalPattern(pattern string) (string, bool) {
+	var literal strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			literal.WriteByte(pattern[i])
+		case strings.IndexByte("*?[", c) >= 0:
+			return "", false
+		case c == '(' && i > 0 && strings.IndexByte("+@!", pattern[i-1]) >= 0:
+			// An extglob pattern list
+			return "", false
+		default:
+			literal.WriteByte(c)
+		}
+	}
+	return literal.String(), true
+}
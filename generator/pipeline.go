@@ -0,0 +1,206 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	runtimeHelpers["runPipeline"] = runtimeHelper{
+		Source: `// pipelineStage is a command of a pipeline: an external command, or Go code
+// running with the standard streams it is given
+type pipelineStage struct {
+	cmd *exec.Cmd
+	run func(stdio *streams) error
+}
+
+// runPipeline runs the commands of a pipeline with the standard streams of
+// stdio, each reading the output of the previous one, and waits for all of
+// them. It returns the error of each command, nil for those that succeeded.
+// Like in the shell all the commands run concurrently: external commands as
+// processes, the Go code of the others in goroutines with streams of their
+// own.
+func runPipeline(stdio *streams, stages ...pipelineStage) []error {
+	n := len(stages)
+	errs := make([]error, n)
+	stdin := make([]*os.File, n)
+	stdout := make([]*os.File, n)
+	stdin[0], stdout[n-1] = stdio.Stdin, stdio.Stdout
+	// ends closes the pipes of stage i, once only it uses them
+	ends := func(i int) {
+		if i > 0 && stdin[i] != nil {
+			stdin[i].Close()
+		}
+		if i < n-1 && stdout[i] != nil {
+			stdout[i].Close()
+		}
+	}
+	for i := 0; i < n-1; i++ {
+		r, w, err := os.Pipe()
+		if err != nil {
+			for i := range stages {
+				ends(i)
+				errs[i] = err
+			}
+			return errs
+		}
+		stdout[i], stdin[i+1] = w, r
+	}
+
+	var wg sync.WaitGroup
+	for i, stage := range stages {
+		if stage.run != nil {
+			s := stdio.redirected()
+			s.Stdin, s.Stdout = stdin[i], stdout[i]
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = stage.run(s)
+				ends(i)
+			}()
+			continue
+		}
+		if stage.cmd.Stdin == nil {
+			stage.cmd.Stdin = stdin[i]
+		}
+		stage.cmd.Stdout, stage.cmd.Stderr = stdout[i], stdio.Stderr
+		errs[i] = stage.cmd.Start()
+		// Only the commands keep the pipes open, so that a command exiting
+		// early ends the commands writing to it, as in the shell
+		ends(i)
+	}
+	wg.Wait()
+	for i, stage := range stages {
+		if stage.cmd != nil && errs[i] == nil {
+			errs[i] = stage.cmd.Wait()
+		}
+	}
+	return errs
+}`,
+		Imports:  []string{"os", "os/exec", "sync"},
+		Requires: []string{"streams"},
+	}
+	runtimeHelpers["pipelineStatus"] = runtimeHelper{
+		Source: `// pipelineStatus returns the error deciding the status of a pipeline from
+// the errors of its commands: that of the last command, or while set -o
+// pipefail is in effect that of the first command that failed
+func pipelineStatus(errs []error) error {
+	if shellOptions["pipefail"] {
+		for _, err := range errs {
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return errs[len(errs)-1]
+}`,
+		Requires: []string{"shellOptions"},
+	}
+}
+
+// generatePipe generates Go code for a pipe. Commands without a native
+// translation run as external commands connected by OS pipes, the others as
+// Go code reading and writing the pipes, so that the status of each is
+// known; the pipeline fails like its last command, or under set -o pipefail
+// like its first failing command.
+func (g *GoCodeGenerator) generatePipe(pipe parser.Pipe) (string, error) {
+	if len(pipe.Commands) == 0 {
+		return "// Empty pipe", nil
+	}
+
+	fallbacks := g.metrics.ExecFallbacks
+	var stages, text []string
+	for _, cmd := range pipe.Commands {
+		stage, err := g.pipelineStage(cmd)
+		if err != nil {
+			return "", err
+		}
+		stages = append(stages, stage)
+		text = append(text, strings.TrimSpace(cmd.Name+" "+strings.Join(cmd.Args, " ")))
+	}
+	if g.metrics.ExecFallbacks > fallbacks {
+		// The pipeline is a single statement running external commands
+		g.metrics.ExecFallbacks = fallbacks + 1
+	}
+	g.requireHelper("runPipeline")
+
+	status := "errs[len(errs)-1]"
+	if g.mayUseShellOption("pipefail") {
+		g.requireHelper("pipelineStatus")
+		status = "pipelineStatus(errs)"
+	}
+
+	pos := pipe.Commands[0].Pos
+	check := g.checkStatus(status, g.errReturnAt(pos, "pipeline"))
+	if g.usesPipestatus() {
+		g.requireHelper("pipestatus")
+		check = "setPipestatus(errs)\n" + check
+	}
+	return fmt.Sprintf("// Run pipeline: %s\n{\nerrs := runPipeline(%s,\n%s,\n)\n%s\n}",
+		commentText(strings.Join(text, " | ")), g.stdio(), strings.Join(stages, ",\n"), check), nil
+}
+
+// pipelineStage returns a Go expression for the stage of a pipeline running
+// cmd: its exec.Cmd if it runs as an external command, or else a closure
+// running its Go code and returning its status as an error. Redirections of
+// the stage apply after those of the pipeline, as in Bash.
+func (g *GoCodeGenerator) pipelineStage(cmd parser.Command) (string, error) {
+	g.pipeStage, g.pipeExternal = len(cmd.Redirects) == 0, false
+	g.statusSet = false
+	// Like in Bash the stage runs as a subshell, which exit ends
+	g.subshells++
+	code, err := g.generateCommand(cmd)
+	g.subshells--
+	external := g.pipeExternal
+	g.pipeStage, g.pipeExternal = false, false
+	if err != nil {
+		return "", err
+	}
+	if external {
+		return fmt.Sprintf("pipelineStage{cmd: %s}", g.execCommand(cmd)), nil
+	}
+
+	// Scripts reading $? get the status of the commands there
+	result := "return nil"
+	if g.usesShellVar("?") {
+		code = g.recordStatus(cmd, code)
+		g.requireHelper("statusError")
+		result = "return statusError()"
+	}
+	if endsWithReturn(code) {
+		result = ""
+	}
+	return fmt.Sprintf("pipelineStage{run: func(%s *streams) error {\n%s\n%s\n}}", g.stdio(), code, result), nil
+}
+
+// execCommand returns a Go expression creating the exec.Cmd running cmd as
+// an external command. A here-string becomes its standard input, and prefix
+// assignments are added to its environment.
+func (g *GoCodeGenerator) execCommand(cmd parser.Command) string {
+	g.RequiredImports["os/exec"] = true
+	args := ""
+	if len(cmd.Globs) > 0 || len(cmd.Splits) > 0 || spreadsArgs(cmd.Args) {
+		args = ", " + g.globArgs(cmd.Args, cmd.Globs, cmd.Splits) + "..."
+	} else {
+		for _, arg := range cmd.Args {
+			args += ", " + g.goArg(arg)
+		}
+	}
+	expr := fmt.Sprintf("exec.Command(%s%s)", g.goArg(cmd.Name), args)
+	setup := ""
+	if input := g.stdinInput(cmd); input != "" {
+		g.RequiredImports["strings"] = true
+		setup = fmt.Sprintf("\ncmd.Stdin = strings.NewReader(%s)", input)
+	}
+	// Applied outside of generateCommand, the assignments are none of an
+	// enclosing command
+	applied := g.cmdEnvApplied
+	setup += g.execEnv(cmd)
+	g.cmdEnvApplied = applied
+	if setup == "" {
+		return expr
+	}
+	return fmt.Sprintf("func() *exec.Cmd {\ncmd := %s%s\nreturn cmd\n}()", expr, setup)
+}
@@ -0,0 +1,82 @@
+package generator
+
+import "fmt"
+
+func init() {
+	runtimeHelpers["pipestatus"] = runtimeHelper{
+		Source: `// pipestatus holds the exit status of each command of the last pipeline,
+// as PIPESTATUS does
+var pipestatus []int
+
+// setPipestatus records the exit statuses of the commands of a pipeline
+// from their errors
+func setPipestatus(errs []error) {
+	pipestatus = make([]int, len(errs))
+	for i, err := range errs {
+		pipestatus[i] = exitStatus(err)
+	}
+}
+
+// pipestatusAt returns ${PIPESTATUS[index]}, or an empty string if the last
+// pipeline had no such command
+func pipestatusAt(index string) string {
+	i, err := strconv.Atoi(index)
+	if err != nil || i < 0 || i >= len(pipestatus) {
+		return ""
+	}
+	return strconv.Itoa(pipestatus[i])
+}
+
+// pipestatusList returns the elements of ${PIPESTATUS[@]}
+func pipestatusList() []string {
+	list := make([]string, len(pipestatus))
+	for i, status := range pipestatus {
+		list[i] = strconv.Itoa(status)
+	}
+	return list
+}`,
+		Imports:  []string{"strconv"},
+		Requires: []string{"shellVars"},
+	}
+}
+
+// usesPipestatus reports whether the script reads PIPESTATUS, in which case
+// pipelines record the exit status of each of their commands.
+func (g *GoCodeGenerator) usesPipestatus() bool {
+	return g.IR.SpecialVars["PIPESTATUS"]
+}
+
+// pipestatusExpansion converts the inside of a ${...} expansion of
+// PIPESTATUS into a Go string expression: ${PIPESTATUS[n]} is the status of
+// the nth command of the last pipeline, ${PIPESTATUS[@]} all of them joined
+// with spaces and ${#PIPESTATUS[@]} their number. It reports false for other
+// expansions.
+func (g *GoCodeGenerator) pipestatusExpansion(expr string) (string, bool) {
+	if list, ok := g.pipestatusList(expr); ok {
+		g.RequiredImports["strings"] = true
+		return fmt.Sprintf(`strings.Join(%s, " ")`, list), true
+	}
+	switch expr {
+	case "#PIPESTATUS[@]", "#PIPESTATUS[*]":
+		g.requireHelper("pipestatus")
+		g.RequiredImports["strconv"] = true
+		return "strconv.Itoa(len(pipestatus))", true
+	}
+	name, key, ok := cutSubscript(expr)
+	if !ok || name != "PIPESTATUS" {
+		return "", false
+	}
+	g.requireHelper("pipestatus")
+	return fmt.Sprintf("pipestatusAt(%s)", g.goArg(key)), true
+}
+
+// pipestatusList converts ${PIPESTATUS[@]}, without the braces, into a Go
+// expression for the list of statuses.
+func (g *GoCodeGenerator) pipestatusList(expr string) (string, bool) {
+	switch expr {
+	case "PIPESTATUS[@]", "PIPESTATUS[*]":
+		g.requireHelper("pipestatus")
+		return "pipestatusList()", true
+	}
+	return "", false
+}
@@ -0,0 +1,140 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/TFMV/bash2go/diagnostics"
+	bashparser "github.com/TFMV/bash2go/parser"
+)
+
+// PluginPrefix is the name prefix of translation plugins: the command foo
+// is translated by the executable bash2go-translate-foo found on PATH
+const PluginPrefix = "bash2go-translate-"
+
+// PluginProtocolVersion is the version of the plugin request and response
+// format
+const PluginProtocolVersion = 1
+
+// pluginTimeout bounds the time a plugin may take to translate a command
+const pluginTimeout = 30 * time.Second
+
+// PluginRequest is the JSON document a plugin receives on stdin
+type PluginRequest struct {
+	Version int                `json:"version"`
+	Command bashparser.Command `json:"command"`
+	// GoArgs holds a Go string expression for each argument of the command,
+	// with variable references expanded
+	GoArgs []string `json:"goArgs"`
+	// ErrReturn is the statement that returns the error err from the
+	// enclosing function, annotated with the command's location
+	ErrReturn string `json:"errReturn"`
+	// GoStdin is a Go string expression for the input a here-string or
+	// here-document feeds to the command, or empty if it has neither
+	GoStdin string `json:"goStdin,omitempty"`
+}
+
+// PluginResponse is the JSON document a plugin writes to stdout. An empty
+// Code declines the command, which then runs as an external command.
+type PluginResponse struct {
+	Code    string   `json:"code"`              // Go statements replacing the command
+	Imports []string `json:"imports,omitempty"` // Import paths the code needs
+}
+
+// pluginCommand translates cmd with its plugin, if Options.Plugins is set
+// and one is installed. Commands listed in Options.Mappings are never passed
+// to plugins. It reports false if no plugin translated the command. Plugin
+// failures are reported as warnings.
+func (g *GoCodeGenerator) pluginCommand(cmd bashparser.Command) (string, bool) {
+	if !g.Options.Plugins || cmd.Name == "" || strings.ContainsAny(cmd.Name, `/\$`) {
+		return "", false
+	}
+	if g.Options.Mappings[cmd.Name] != nil {
+		return "", false
+	}
+	if g.plugins == nil {
+		g.plugins = make(map[string]string)
+	}
+	path, ok := g.plugins[cmd.Name]
+	if !ok {
+		path, _ = exec.LookPath(PluginPrefix + cmd.Name)
+		g.plugins[cmd.Name] = path
+	}
+	if path == "" {
+		return "", false
+	}
+
+	request := PluginRequest{
+		Version:   PluginProtocolVersion,
+		Command:   cmd,
+		GoArgs:    make([]string, len(cmd.Args)),
+		ErrReturn: g.errReturn(cmd),
+	}
+	for i, arg := range cmd.Args {
+		request.GoArgs[i] = g.goArg(arg)
+	}
+	request.GoStdin = g.stdinInput(cmd)
+
+	response, err := runPlugin(path, request)
+	if err == nil && response.Code != "" {
+		err = checkStatements(response.Code)
+	}
+	if err != nil {
+		g.IR.Diagnose(diagnostics.SeverityWarning, cmd.Pos, diagnostics.CodePlugin,
+			"plugin %s failed, executing %s as an external command: %v", PluginPrefix+cmd.Name, cmd.Name, err)
+		return "", false
+	}
+	if response.Code == "" {
+		return "", false
+	}
+
+	for _, imp := range response.Imports {
+		g.RequiredImports[imp] = true
+	}
+	return fmt.Sprintf("// %s translated by %s\n%s", cmd.Name, PluginPrefix+cmd.Name, response.Code), true
+}
+
+// runPlugin runs the plugin at path with request on stdin and decodes its
+// response
+func runPlugin(path string, request PluginRequest) (PluginResponse, error) {
+	var response PluginResponse
+	input, err := json.Marshal(request)
+	if err != nil {
+		return response, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pluginTimeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	plugin := exec.CommandContext(ctx, path)
+	plugin.Stdin = bytes.NewReader(input)
+	plugin.Stdout = &stdout
+	plugin.Stderr = &stderr
+	if err := plugin.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return response, fmt.Errorf("%v: %s", err, msg)
+		}
+		return response, err
+	}
+
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return response, fmt.Errorf("invalid response: %v", err)
+	}
+	return response, nil
+}
+
+// checkStatements reports whether code parses as a list of Go statements
+func checkStatements(code string) error {
+	src := "package plugin\nfunc _() error {\n" + code + "\nreturn nil\n}\n"
+	if _, err := parser.ParseFile(token.NewFileSet(), "plugin.go", src, 0); err != nil {
+		return fmt.Errorf("invalid Go code: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	runtimeHelpers["positionalArg"] = runtimeHelper{
+		Source: `// positionalArg returns the positional parameter $n of args, or an empty
+// string if there are fewer parameters
+func positionalArg(args []string, n int) string {
+	if n > len(args) {
+		return ""
+	}
+	return args[n-1]
+}`,
+	}
+	runtimeHelpers["argOrDefault"] = runtimeHelper{
+		Source: `// argOrDefault returns the positional parameter $n of args, or def if
+// there are fewer parameters, as ${n-def} does
+func argOrDefault(args []string, n int, def string) string {
+	if n > len(args) {
+		return def
+	}
+	return args[n-1]
+}`,
+	}
+	runtimeHelpers["assignArg"] = runtimeHelper{
+		Source: `// assignArg returns the positional parameter $n of args for ${n=def}, or
+// ${n:=def} if null is set. Bash cannot assign positional parameters and
+// abandons the command line then; the program ends with status 1.
+func assignArg(args []string, n int, null bool) string {
+	if n <= len(args) && (!null || args[n-1] != "") {
+		return args[n-1]
+	}
+	fmt.Fprintf(os.Stderr, "$%d: cannot assign in this way\n", n)
+	os.Exit(1)
+	return ""
+}`,
+		Imports: []string{"fmt", "os"},
+	}
+}
+
+// isNumberedArg reports whether name is a numbered positional parameter,
+// such as 1 or 10, rather than #, @ or *.
+func isNumberedArg(name string) bool {
+	return parser.IsPositional(name) && strings.Trim(name, "0123456789") == ""
+}
+
+// usesArgs reports whether the script reads its positional parameters, in
+// which case the generated program keeps them in the args variable.
+func (g *GoCodeGenerator) usesArgs() bool {
+	for name := range g.IR.SpecialVars {
+		if parser.IsPositional(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// positionalRef returns a Go expression for the value of the positional
+// parameter name, such as 1, # or @, reporting false if name is not one.
+// The parameters are read from args, which is the parameter of translated
+// functions and the command line arguments in run. Under set -u, reading a
+// parameter past the last one ends the program.
+func (g *GoCodeGenerator) positionalRef(name string) (string, bool) {
+	if !parser.IsPositional(name) {
+		return "", false
+	}
+	switch name {
+	case "#":
+		g.RequiredImports["strconv"] = true
+		return "strconv.Itoa(len(args))", true
+	case "@", "*":
+		g.RequiredImports["strings"] = true
+		return `strings.Join(args, " ")`, true
+	}
+	if g.nounset() {
+		g.requireHelper("nounsetArg")
+		return fmt.Sprintf("nounsetArg(args, %s)", name), true
+	}
+	g.requireHelper("positionalArg")
+	return fmt.Sprintf("positionalArg(args, %s)", name), true
+}
+
+// isArgsWord reports whether word is "$@" or $*, which expand to one word
+// per positional parameter.
+func isArgsWord(word string) bool {
+	switch word {
+	case "$@", "${@}", "$*":
+		return true
+	}
+	return false
+}
+
+// spreadsArgs reports whether any of words expands to the positional
+// parameters.
+func spreadsArgs(words []string) bool {
+	for _, word := range words {
+		if isArgsWord(word) {
+			return true
+		}
+	}
+	return false
+}
+
+// generateFunctionCall generates Go code calling the translated function of
+// cmd with its arguments as positional parameters. A failing function fails
+// the caller like a failing command.
+func (g *GoCodeGenerator) generateFunctionCall(cmd parser.Command) string {
+	args := "nil"
+	if len(cmd.Args) > 0 {
+		args = g.globArgs(cmd.Args, cmd.Globs, cmd.Splits)
+	}
+	return fmt.Sprintf("if err := %s(%s, %s); err != nil {\n\treturn err\n}", g.funcIdent(cmd.Name), g.stdio(), args)
+}
+
+// addArgsGlobal declares the args variable holding the positional
+// parameters of the script, if it reads them. Translated functions take
+// their own args parameter, which shadows it.
+func (g *GoCodeGenerator) addArgsGlobal() {
+	if !g.usesArgs() || len(g.IR.Subcommands) > 0 {
+		return
+	}
+	g.RequiredImports["os"] = true
+	g.Generator.AddGlobal("// args holds the positional parameters of the script")
+	g.Generator.AddGlobal("var args = os.Args[1:]")
+	g.Generator.AddGlobal("")
+}
+
+// generateShift generates Go code for the shift builtin, which drops the
+// first positional parameters by re-slicing args. Like in Bash, shifting by
+// more than there are parameters leaves them unchanged and fails, which only
+// shows in $?.
+func (g *GoCodeGenerator) generateShift(cmd parser.Command) string {
+	count, dynamic := "1", false
+	if len(cmd.Args) > 0 {
+		count, dynamic = g.shiftCount(cmd.Args[0])
+	}
+
+	var code string
+	if dynamic {
+		code = fmt.Sprintf("if n := %s; n >= 0 && n <= len(args) {\n\targs = args[n:]", count)
+	} else {
+		code = fmt.Sprintf("if len(args) >= %[1]s {\n\targs = args[%[1]s:]", count)
+	}
+	if g.usesShellVar("?") {
+		code += fmt.Sprintf("\n\t%s\n} else {\n\t%s", g.setShellVarCode("?", `"0"`), g.setShellVarCode("?", `"1"`))
+	}
+	return code + "\n}"
+}
+
+// shiftCount returns a Go int expression for the count of a shift command,
+// and whether it is only known at runtime.
+func (g *GoCodeGenerator) shiftCount(word string) (string, bool) {
+	if _, err := strconv.Atoi(word); err == nil {
+		return word, false
+	}
+	if strings.HasPrefix(word, "$((") && closingArithm(word) == len(word) {
+		if a, err := parser.ParseArithmetic(word[3 : len(word)-2]); err == nil {
+			return g.arithExpr(a), true
+		}
+	}
+	g.requireHelper("arithValue")
+	return fmt.Sprintf("arithValue(%s)", g.goArg(word)), true
+}
@@ -0,0 +1,397 @@
+package generator
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/TFMV/bash2go/diagnostics"
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	runtimeHelpers["printfArgs"] = runtimeHelper{
+		Source: `// printfArgs splits the arguments of printf into the values of each use of
+// its format, which takes verbs values and is reused while arguments remain.
+// Missing values are empty.
+func printfArgs(args []string, verbs int) [][]string {
+	var uses [][]string
+	for len(uses) == 0 || len(args) > 0 {
+		values := make([]string, verbs)
+		n := copy(values, args)
+		args = args[n:]
+		uses = append(uses, values)
+	}
+	return uses
+}`,
+	}
+	runtimeHelpers["printfNumber"] = runtimeHelper{
+		Source: `// printfInt converts an argument of an integer printf verb. Like in Bash,
+// numbers may be octal or hexadecimal, a leading quote gives the code of the
+// next character, and invalid numbers are reported, print as 0 and set
+// *invalid, for printf to fail afterwards.
+func printfInt(stdio *streams, s string, invalid *bool) int64 {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+	if (s[0] == '\'' || s[0] == '"') && len(s) > 1 {
+		r, _ := utf8.DecodeRuneInString(s[1:])
+		return int64(r)
+	}
+	n, err := strconv.ParseInt(s, 0, 64)
+	if err != nil {
+		fmt.Fprintf(stdio.Stderr, "printf: %s: invalid number\n", s)
+		*invalid = true
+	}
+	return n
+}
+
+// printfFloat converts an argument of a floating-point printf verb
+func printfFloat(stdio *streams, s string, invalid *bool) float64 {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+	if (s[0] == '\'' || s[0] == '"') && len(s) > 1 {
+		r, _ := utf8.DecodeRuneInString(s[1:])
+		return float64(r)
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		fmt.Fprintf(stdio.Stderr, "printf: %s: invalid number\n", s)
+		*invalid = true
+	}
+	return f
+}
+
+// printfStatus returns the error of printf if it met invalid numbers
+func printfStatus(invalid bool) error {
+	if invalid {
+		return exitError(1)
+	}
+	return nil
+}`,
+		Imports:  []string{"fmt", "strconv", "strings", "unicode/utf8"},
+		Requires: []string{"exitError", "streams"},
+	}
+	runtimeHelpers["shellQuote"] = runtimeHelper{
+		Source: `// shellQuote quotes s for reuse as shell input, as printf %q does.
+// Characters special to the shell are escaped with backslashes; strings
+// with control characters are quoted as $'...'.
+func shellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	if strings.ContainsFunc(s, unicode.IsControl) {
+		quoted := strconv.QuoteToASCII(s)
+		quoted = strings.ReplaceAll(quoted[1:len(quoted)-1], "\\\"", "\"")
+		return "$'" + strings.ReplaceAll(quoted, "'", "\\'") + "'"
+	}
+	var b strings.Builder
+	for i, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("_-./,:@%+=", r) && (r != '~' || i == 0) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}`,
+		Imports: []string{"strconv", "strings", "unicode"},
+	}
+	runtimeHelpers["printfEscapes"] = runtimeHelper{
+		Source: `// printfEscapes expands the backslash escapes in an argument of printf %b,
+// which also takes octal escapes as \0NNN. Output stops at \c.
+func printfEscapes(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch c := s[i]; c {
+		case 'a':
+			b.WriteByte('\a')
+		case 'b':
+			b.WriteByte('\b')
+		case 'e', 'E':
+			b.WriteByte(0x1b)
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'v':
+			b.WriteByte('\v')
+		case '\\':
+			b.WriteByte('\\')
+		case 'c':
+			return b.String()
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			// \0NNN, or \NNN
+			start, limit := i, i+3
+			if c == '0' {
+				start, limit = i+1, i+4
+			}
+			end := start
+			for end < len(s) && end < limit && s[end] >= '0' && s[end] <= '7' {
+				end++
+			}
+			n, _ := strconv.ParseUint(s[start:end], 8, 8)
+			b.WriteByte(byte(n))
+			i = end - 1
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}`,
+		Imports: []string{"strconv", "strings"},
+	}
+}
+
+// printfFormat translates the format of printf into a format for
+// fmt.Printf, expanding its backslash escapes, and returns the kinds of
+// argument its verbs take: 's' for strings, 'd' for integers, 'f' for
+// floating-point numbers, and 'q' and 'b' for the arguments of %q and %b.
+// It reports false if the format uses something with no Go translation,
+// such as a * width or \c.
+func printfFormat(format string) (string, []byte, bool) {
+	var b strings.Builder
+	var verbs []byte
+	for i := 0; i < len(format); i++ {
+		switch c := format[i]; c {
+		case '\\':
+			text, n, ok := formatEscape(format[i+1:])
+			if !ok {
+				return "", nil, false
+			}
+			b.WriteString(strings.ReplaceAll(text, "%", "%%"))
+			i += n
+		case '%':
+			if i+1 < len(format) && format[i+1] == '%' {
+				b.WriteString("%%")
+				i++
+				continue
+			}
+
+			// Flags, width and precision are the same in Go
+			j := i + 1
+			for j < len(format) && strings.IndexByte("-+ #0", format[j]) >= 0 {
+				j++
+			}
+			for j < len(format) && (format[j] >= '0' && format[j] <= '9' || format[j] == '.') {
+				j++
+			}
+			if j == len(format) {
+				return "", nil, false
+			}
+			spec := format[i:j]
+			switch verb := format[j]; verb {
+			case 's':
+				b.WriteString(spec + "s")
+				verbs = append(verbs, 's')
+			case 'c':
+				// The first character of the argument
+				b.WriteString(strings.Split(spec, ".")[0] + ".1s")
+				verbs = append(verbs, 's')
+			case 'q', 'b':
+				b.WriteString(spec + "s")
+				verbs = append(verbs, verb)
+			case 'd', 'i', 'u':
+				b.WriteString(spec + "d")
+				verbs = append(verbs, 'd')
+			case 'o', 'x', 'X':
+				b.WriteString(spec + string(verb))
+				verbs = append(verbs, 'd')
+			case 'f', 'F', 'e', 'E', 'g', 'G':
+				b.WriteString(spec + string(verb))
+				verbs = append(verbs, 'f')
+			default:
+				return "", nil, false
+			}
+			i = j
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), verbs, true
+}
+
+// formatEscape expands the backslash escape at the start of s, which
+// follows a backslash in a printf format, returning the text it stands for
+// and the number of bytes of s it takes. It reports false for \c, which
+// stops the output.
+func formatEscape(s string) (string, int, bool) {
+	if s == "" {
+		return `\`, 0, true
+	}
+	simple := map[byte]string{
+		'a': "\a", 'b': "\b", 'e': "\x1b", 'E': "\x1b", 'f': "\f", 'n': "\n",
+		'r': "\r", 't': "\t", 'v': "\v", '\\': `\`, '"': `"`, '\'': "'", '?': "?",
+	}
+	if text, ok := simple[s[0]]; ok {
+		return text, 1, true
+	}
+
+	// Numeric escapes: \NNN in octal, \xHH, \uHHHH and \UHHHHHHHH
+	base, start, limit := 8, 0, 3
+	switch s[0] {
+	case 'c':
+		return "", 0, false
+	case 'x':
+		base, start, limit = 16, 1, 3
+	case 'u':
+		base, start, limit = 16, 1, 5
+	case 'U':
+		base, start, limit = 16, 1, 9
+	}
+	end := start
+	for end < len(s) && end < limit {
+		if _, err := strconv.ParseUint(s[end:end+1], base, 8); err != nil {
+			break
+		}
+		end++
+	}
+	if end == start {
+		// Not an escape; the backslash is kept
+		return `\` + s[:1], 1, true
+	}
+	n, _ := strconv.ParseUint(s[start:end], base, 32)
+	if s[0] == 'u' || s[0] == 'U' {
+		return string(rune(n)), end, true
+	}
+	return string([]byte{byte(n)}), end, true
+}
+
+// generatePrintf generates Go code for printf with a format known when
+// converting, as calls to fmt.Fprintf with the format translated. Like in
+// Bash, the format is reused while arguments remain. It reports false if the
+// command runs the external printf instead.
+func (g *GoCodeGenerator) generatePrintf(cmd parser.Command) (string, bool) {
+	args, globs, splits := cmd.Args, cmd.Globs, cmd.Splits
+	shiftIndices := func(indices []int) []int {
+		var shifted []int
+		for _, i := range indices {
+			if i > 0 {
+				shifted = append(shifted, i-1)
+			}
+		}
+		return shifted
+	}
+	shift := func() {
+		args = args[1:]
+		globs, splits = shiftIndices(globs), shiftIndices(splits)
+	}
+	if len(args) > 0 && args[0] == "--" {
+		shift()
+	}
+	if len(args) > 0 && args[0] == "-v" {
+		g.unsupported++
+		g.IR.Diagnose(diagnostics.SeverityError, cmd.Pos, diagnostics.CodeUnsupported, "printf -v is not supported")
+		return fmt.Sprintf("// Unsupported printf: %s", commentText(strings.Join(cmd.Args, " "))), true
+	}
+	if len(args) == 0 || len(globs) > 0 && globs[0] == 0 || len(splits) > 0 && splits[0] == 0 {
+		return "", false
+	}
+	format, err := strconv.Unquote(g.goArg(args[0]))
+	if err != nil {
+		// The format is only known at runtime
+		return "", false
+	}
+	goFormat, verbs, ok := printfFormat(format)
+	if !ok {
+		return "", false
+	}
+	shift()
+
+	g.RequiredImports["fmt"] = true
+	if len(verbs) == 0 {
+		// Arguments without verbs to take them are ignored
+		return fmt.Sprintf("fmt.Fprint(%s.Stdout, %s)", g.stdio(), strconv.Quote(strings.ReplaceAll(goFormat, "%%", "%"))), true
+	}
+
+	// Arguments spread at runtime are split into uses of the format then
+	if len(globs) > 0 || len(splits) > 0 || spreadsArgs(args) {
+		g.requireHelper("printfArgs")
+		values := make([]string, len(verbs))
+		converts := false
+		for i, verb := range verbs {
+			var ok bool
+			values[i], ok = g.printfValue(verb, fmt.Sprintf("values[%d]", i))
+			converts = converts || ok
+		}
+		return g.printfChecked(cmd, converts, fmt.Sprintf("for _, values := range printfArgs(%s, %d) {\n\tfmt.Fprintf(%s.Stdout, %s, %s)\n}",
+			g.globArgs(args, globs, splits), len(verbs), g.stdio(), strconv.Quote(goFormat), strings.Join(values, ", "))), true
+	}
+
+	var calls []string
+	converts := false
+	for len(calls) == 0 || len(args) > 0 {
+		values := make([]string, len(verbs))
+		for i, verb := range verbs {
+			arg := `""`
+			if i < len(args) {
+				arg = g.goArg(args[i])
+			}
+			var ok bool
+			values[i], ok = g.printfValue(verb, arg)
+			converts = converts || ok
+		}
+		args = args[min(len(verbs), len(args)):]
+		calls = append(calls, fmt.Sprintf("fmt.Fprintf(%s.Stdout, %s, %s)", g.stdio(), strconv.Quote(goFormat), strings.Join(values, ", ")))
+	}
+	return g.printfChecked(cmd, converts, strings.Join(calls, "\n")), true
+}
+
+// printfChecked wraps the code printing with a translated format, if it
+// converts numbers at runtime, to fail like printf when one is invalid.
+// The numbers still print as 0 first.
+func (g *GoCodeGenerator) printfChecked(cmd parser.Command, converts bool, code string) string {
+	if !converts {
+		return code
+	}
+	return fmt.Sprintf("{\ninvalidNumber := false\n%s\n%s\n}", code, g.checkErr(cmd, "printfStatus(invalidNumber)"))
+}
+
+// printfValue converts the Go string expression arg into the value a verb
+// of a translated printf format takes. Numbers known when converting are
+// passed as constants; it reports true if a number is converted at runtime,
+// setting invalidNumber if it is invalid.
+func (g *GoCodeGenerator) printfValue(kind byte, arg string) (string, bool) {
+	literal, err := strconv.Unquote(arg)
+	isLiteral := err == nil && !strings.ContainsAny(literal, `'"`)
+	switch kind {
+	case 'd':
+		if isLiteral {
+			if n, err := strconv.ParseInt(strings.TrimSpace(literal), 0, 64); err == nil || literal == "" {
+				return strconv.FormatInt(n, 10), false
+			}
+		}
+		g.requireHelper("printfNumber")
+		return fmt.Sprintf("printfInt(%s, %s, &invalidNumber)", g.stdio(), arg), true
+	case 'f':
+		if isLiteral {
+			f, err := strconv.ParseFloat(strings.TrimSpace(literal), 64)
+			if (err == nil || literal == "") && !math.IsInf(f, 0) && !math.IsNaN(f) {
+				return fmt.Sprintf("float64(%s)", strconv.FormatFloat(f, 'g', -1, 64)), false
+			}
+		}
+		g.requireHelper("printfNumber")
+		return fmt.Sprintf("printfFloat(%s, %s, &invalidNumber)", g.stdio(), arg), true
+	case 'q':
+		g.requireHelper("shellQuote")
+		return fmt.Sprintf("shellQuote(%s)", arg), false
+	case 'b':
+		g.requireHelper("printfEscapes")
+		return fmt.Sprintf("printfEscapes(%s)", arg), false
+	default:
+		return arg, false
+	}
+}
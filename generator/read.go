@@ -0,0 +1,174 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/TFMV/bash2go/diagnostics"
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	runtimeHelpers["readVars"] = runtimeHelper{
+		Source: `// readLine reads a line for the read builtin, printing the prompt first if
+// the standard input is a terminal. Unless raw, a backslash escapes the next
+// character, which escaped reports for each character of the line, and a
+// backslash at the end of the line continues it on the next one. At the end
+// of the input, what was left of it is returned with io.EOF.
+func readLine(stdio *streams, input *bufio.Reader, prompt string, raw bool) (line []rune, escaped []bool, err error) {
+	if prompt != "" {
+		if info, err := stdio.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			fmt.Fprint(stdio.Stderr, prompt)
+		}
+	}
+	for {
+		var text string
+		text, err = input.ReadString('\n')
+		text = strings.TrimSuffix(text, "\n")
+		backslash := false
+		for _, r := range text {
+			if r == '\\' && !raw && !backslash {
+				backslash = true
+				continue
+			}
+			line = append(line, r)
+			escaped = append(escaped, backslash)
+			backslash = false
+		}
+		if !backslash || err != nil {
+			return line, escaped, err
+		}
+	}
+}
+
+// readReply reads a line into reply, as read without variable names does,
+// keeping its leading and trailing blanks.
+func readReply(stdio *streams, input *bufio.Reader, prompt string, raw bool, reply *string) error {
+	line, _, err := readLine(stdio, input, prompt, raw)
+	*reply = string(line)
+	return err
+}
+
+// readVars reads a line and assigns its fields, separated by the characters
+// of ifs like splitFields does, to vars, the last one holding the rest of the
+// line without the blanks of ifs around it, and without the delimiter after
+// it if it is a single field. Escaped characters do not separate fields.
+func readVars(stdio *streams, input *bufio.Reader, prompt string, raw bool, ifs string, vars ...*string) error {
+	line, escaped, err := readLine(stdio, input, prompt, raw)
+	delim := func(i int) bool {
+		return !escaped[i] && strings.ContainsRune(ifs, line[i])
+	}
+	blank := func(i int) bool {
+		return delim(i) && (line[i] == ' ' || line[i] == '\t' || line[i] == '\n')
+	}
+	start, end := 0, len(line)
+	for start < end && blank(start) {
+		start++
+	}
+	for end > start && blank(end-1) {
+		end--
+	}
+	for i, v := range vars {
+		if i == len(vars)-1 {
+			if end > start && delim(end-1) && !blank(end-1) {
+				j := end - 1
+				for j > start && blank(j-1) {
+					j--
+				}
+				single := true
+				for k := start; k < j; k++ {
+					single = single && !delim(k)
+				}
+				if single {
+					end = j
+				}
+			}
+			*v = string(line[start:end])
+			break
+		}
+		j := start
+		for j < end && !delim(j) {
+			j++
+		}
+		*v = string(line[start:j])
+		start = j
+		for start < end && blank(start) {
+			start++
+		}
+		if start < end && delim(start) && !blank(start) {
+			start++
+			for start < end && blank(start) {
+				start++
+			}
+		}
+	}
+	return err
+}`,
+		Imports:  []string{"bufio", "fmt", "os", "strings"},
+		Requires: []string{"streams"},
+	}
+}
+
+// generateRead generates Go code for the read builtin, which assigns the
+// fields of a line of the standard input, or of its here-string, to script
+// variables.
+func (g *GoCodeGenerator) generateRead(cmd parser.Command) string {
+	options, err := parser.ReadArgs(cmd.Args)
+	if err != nil {
+		g.unsupported++
+		g.IR.Diagnose(diagnostics.SeverityError, cmd.Pos, diagnostics.CodeUnsupported, "%v", err)
+		return fmt.Sprintf("// Unsupported read: %s", commentText(strings.Join(cmd.Args, " ")))
+	}
+	names := options.Names
+	if len(names) == 0 {
+		names = []string{"REPLY"}
+	}
+	vars := make([]string, len(names))
+	for i, name := range names {
+		if g.isIntVar(name) || g.isConstant(name) {
+			g.unsupported++
+			g.IR.Diagnose(diagnostics.SeverityError, cmd.Pos, diagnostics.CodeUnsupported,
+				"read into the integer or read-only variable %s is not supported", name)
+			return fmt.Sprintf("// Unsupported read into %s", name)
+		}
+		if g.isTableVar(name) || !isValidVarName(name) {
+			g.unsupported++
+			g.IR.Diagnose(diagnostics.SeverityError, cmd.Pos, diagnostics.CodeUnsupported,
+				"read into %s, which cannot be a Go variable, is not supported", name)
+			return fmt.Sprintf("// Unsupported read into %s", commentText(name))
+		}
+		vars[i] = "&" + name
+	}
+
+	g.requireHelper("readVars")
+	stdio := g.stdio()
+	input, prompt := stdio+".stdinReader()", `""`
+	if stdin := g.stdinInput(cmd); stdin != "" {
+		g.RequiredImports["strings"] = true
+		input = fmt.Sprintf("bufio.NewReader(strings.NewReader(%s))", stdin)
+	} else if options.Prompt != "" {
+		prompt = g.goArg(options.Prompt)
+	}
+	read := "readVars"
+	if len(options.Names) == 0 {
+		// The line is read whole into REPLY
+		read = "readReply"
+	}
+	args := []string{stdio, input, prompt, strconv.FormatBool(options.Raw)}
+	if len(options.Names) > 0 {
+		// IFS=, read splits at commas, with IFS set for read only
+		ifs := g.ifsRef()
+		for _, assign := range cmd.Env {
+			if assign.Name == "IFS" {
+				ifs = g.goArg(assign.Value)
+			}
+		}
+		args = append(args, ifs)
+	}
+	call := fmt.Sprintf("%s(%s)", read, strings.Join(append(args, vars...), ", "))
+
+	// read fails at the end of the input, which ends loops like
+	// while read line
+	return g.checkStatus(call, g.errReturn(cmd))
+}
@@ -0,0 +1,154 @@
+package generator
+
+import (
+	"fmt"
+
+	"github.com/TFMV/bash2go/diagnostics"
+	"github.com/TFMV/bash2go/parser"
+)
+
+// standardStreams are the standard streams of the shell, by file
+// descriptor.
+var standardStreams = map[string]string{
+	"0": "stdio.Stdin",
+	"1": "stdio.Stdout",
+	"2": "stdio.Stderr",
+}
+
+// execStreams are the streams of the exec.Cmd named cmd, by file descriptor.
+var execStreams = map[string]string{
+	"0": "cmd.Stdin",
+	"1": "cmd.Stdout",
+	"2": "cmd.Stderr",
+}
+
+// addRedirects adds code to scope applying redirections, in order, to
+// streams, the Go expressions of the streams by file descriptor. Files are
+// opened in the scope; duplicates, as in 2>&1, take the stream the other
+// descriptor has at that point. Redirections of the standard streams of the
+// shell replace those of a copy, which shadows them in the scope.
+// Unsupported redirections are reported as those of what.
+func (g *GoCodeGenerator) addRedirects(scope *cleanupScope, redirects []parser.Redirection, streams map[string]string, what string) {
+	files := 0
+	for _, redirection := range redirects {
+		if _, source, ok := redirectTarget(redirection); ok && source == "" {
+			files++
+		}
+	}
+	copied := false
+	file := 0
+	for _, redirection := range redirects {
+		fds, source, ok := redirectTarget(redirection)
+		if !ok {
+			g.unsupported++
+			g.IR.Diagnose(diagnostics.SeverityError, redirection.Pos, diagnostics.CodeUnsupported,
+				"unsupported redirection %s%s of %s", redirection.Fd, redirection.Op, what)
+			scope.add(fmt.Sprintf("// Unsupported redirection: %s%s %s", redirection.Fd, redirection.Op, commentText(redirection.Filename)))
+			continue
+		}
+
+		if source == "" {
+			// The redirection opens a file
+			file++
+			source = "file"
+			if files > 1 {
+				source = fmt.Sprintf("file%d", file)
+			}
+			scope.add(fmt.Sprintf("// Redirect %s%s %s", redirection.Fd, redirection.Op, commentText(redirection.Filename)))
+			scope.acquire(g.openFile(source, g.redirectOpenCall(redirection), "redirection"), source+".Close()")
+		} else {
+			source = streams[source]
+		}
+		if streams["1"] == standardStreams["1"] && !copied {
+			// The streams of the shell are left as they are
+			copied = true
+			scope.add(fmt.Sprintf("%[1]s := %[1]s.redirected()", g.stdio()))
+		}
+		for _, fd := range fds {
+			scope.add(fmt.Sprintf("%s = %s", streams[fd], source))
+		}
+	}
+}
+
+// redirectTarget returns the file descriptors a redirection replaces, and
+// the descriptor they are duplicated from, which is empty if the
+// redirection opens its file. It reports false if the redirection is not
+// supported: only the standard streams are redirected, and input is only
+// duplicated from input and output from output.
+func redirectTarget(redirection parser.Redirection) ([]string, string, bool) {
+	fd := redirection.Fd
+	switch redirection.Op {
+	case ">", ">|", ">>":
+		if fd == "" {
+			fd = "1"
+		}
+	case "<":
+		if fd == "" {
+			fd = "0"
+		}
+	case "&>", "&>>":
+		if fd != "" {
+			return nil, "", false
+		}
+		return []string{"1", "2"}, "", true
+	case ">&", "<&":
+		// Only duplicates of the standard streams, as in 2>&1
+		source := redirection.Filename
+		if _, ok := standardStreams[source]; !ok {
+			return nil, "", false
+		}
+		if fd == "" {
+			fd = "1"
+			if redirection.Op == "<&" {
+				fd = "0"
+			}
+		}
+		if _, ok := standardStreams[fd]; !ok || (fd == "0") != (source == "0") {
+			return nil, "", false
+		}
+		return []string{fd}, source, true
+	default:
+		return nil, "", false
+	}
+	if _, ok := standardStreams[fd]; !ok || redirection.Filename == "" {
+		return nil, "", false
+	}
+	return []string{fd}, "", true
+}
+
+// redirectOpenCall returns the call that opens the file of a redirection.
+// Output files are opened write-only, as by Bash, so that opening a named
+// pipe waits for its reader.
+func (g *GoCodeGenerator) redirectOpenCall(redirection parser.Redirection) string {
+	name := g.goArg(redirection.Filename)
+	switch redirection.Op {
+	case ">>", "&>>":
+		return fmt.Sprintf("os.OpenFile(%s, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)", name)
+	case "<":
+		return fmt.Sprintf("os.Open(%s)", name)
+	default:
+		return fmt.Sprintf("os.OpenFile(%s, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0644)", name)
+	}
+}
+
+// generateRedirected generates Go code running a command that is not run
+// through an exec.Cmd, such as a builtin or a function of the script, with
+// the standard streams replaced by its redirections.
+func (g *GoCodeGenerator) generateRedirected(code string, redirects []parser.Redirection) string {
+	scope := newCleanupScope()
+	g.addRedirects(scope, redirects, standardStreams, "a command")
+	scope.add(code)
+	return scope.String()
+}
+
+// execRedirected generates Go code running an external command with
+// redirections, which replace the streams of its exec.Cmd. args and stdin
+// are the code of its arguments and here-string.
+func (g *GoCodeGenerator) execRedirected(cmd parser.Command, args, stdin string) string {
+	scope := newCleanupScope()
+	scope.add(fmt.Sprintf("cmd := exec.Command(%s%s)", g.goArg(cmd.Name), args))
+	scope.add(fmt.Sprintf("cmd.Stdin, cmd.Stdout, cmd.Stderr = %[1]s.Stdin, %[1]s.Stdout, %[1]s.Stderr", g.stdio()) + stdin + g.execEnv(cmd))
+	g.addRedirects(scope, cmd.Redirects, execStreams, "a command")
+	scope.add(g.checkStatus("cmd.Run()", g.errReturn(cmd)))
+	return scope.String()
+}
@@ -0,0 +1,57 @@
+package generator
+
+import "fmt"
+
+func init() {
+	runtimeHelpers["rematch"] = runtimeHelper{
+		Source: `// rematch holds the text the last [[ s =~ re ]] test matched, followed by
+// the text each parenthesized subexpression matched, as BASH_REMATCH does
+var rematch []string
+
+// rematchAt returns ${BASH_REMATCH[index]}, or an empty string if the last
+// match has no such element
+func rematchAt(index string) string {
+	i, err := strconv.Atoi(index)
+	if err != nil || i < 0 || i >= len(rematch) {
+		return ""
+	}
+	return rematch[i]
+}`,
+		Imports: []string{"strconv"},
+	}
+}
+
+// rematchExpansion converts the inside of a ${...} expansion of BASH_REMATCH
+// into a Go string expression: ${BASH_REMATCH[n]} is the text the nth
+// subexpression of the last [[ =~ ]] test matched, ${BASH_REMATCH[@]} all
+// of them joined with spaces and ${#BASH_REMATCH[@]} their number. It
+// reports false for other expansions.
+func (g *GoCodeGenerator) rematchExpansion(expr string) (string, bool) {
+	if list, ok := g.rematchList(expr); ok {
+		g.RequiredImports["strings"] = true
+		return fmt.Sprintf(`strings.Join(%s, " ")`, list), true
+	}
+	switch expr {
+	case "#BASH_REMATCH[@]", "#BASH_REMATCH[*]":
+		g.requireHelper("rematch")
+		g.RequiredImports["strconv"] = true
+		return "strconv.Itoa(len(rematch))", true
+	}
+	name, key, ok := cutSubscript(expr)
+	if !ok || name != "BASH_REMATCH" {
+		return "", false
+	}
+	g.requireHelper("rematch")
+	return fmt.Sprintf("rematchAt(%s)", g.goArg(key)), true
+}
+
+// rematchList converts ${BASH_REMATCH[@]}, without the braces, into a Go
+// expression for the list of matched texts.
+func (g *GoCodeGenerator) rematchList(expr string) (string, bool) {
+	switch expr {
+	case "BASH_REMATCH[@]", "BASH_REMATCH[*]":
+		g.requireHelper("rematch")
+		return "rematch", true
+	}
+	return "", false
+}
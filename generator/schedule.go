@@ -0,0 +1,72 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/robfig/cron/v3"
+)
+
+// cronModule is the import path of the scheduler used by programs generated
+// with Options.Schedule
+const cronModule = "github.com/robfig/cron/v3"
+
+// validateSchedule checks a cron schedule set with Options.Schedule
+func validateSchedule(spec string) error {
+	if spec == "" {
+		return nil
+	}
+	if strings.TrimSpace(spec) == "@reboot" {
+		return fmt.Errorf("@reboot jobs run once at startup; convert the script without a schedule")
+	}
+	if _, err := cron.ParseStandard(spec); err != nil {
+		return fmt.Errorf("invalid cron schedule %q: %v", spec, err)
+	}
+	return nil
+}
+
+// mainBody returns the statements of the generated main function that follow
+// the build info prologue. They run the script once, or with
+// Options.Schedule start a scheduler that runs it on schedule until the
+// program is interrupted or terminated. Programs combined from several
+// scripts dispatch to a subcommand instead.
+func (g *GoCodeGenerator) mainBody() []string {
+	g.RequiredImports["fmt"] = true
+	g.RequiredImports["os"] = true
+	if len(g.IR.Subcommands) > 0 {
+		return g.subcommandMain()
+	}
+	if g.Options.Schedule == "" {
+		return []string{
+			"if err := run(); err != nil {",
+			"\tfmt.Fprintln(os.Stderr, err)",
+			"\tos.Exit(1)",
+			"}",
+		}
+	}
+
+	g.RequiredImports[cronModule] = true
+	g.RequiredImports["os/signal"] = true
+	g.RequiredImports["syscall"] = true
+	return []string{
+		"// Skip a run while the previous one is still going, like a cron job",
+		"// wrapped in flock",
+		"scheduler := cron.New(cron.WithChain(cron.SkipIfStillRunning(cron.DefaultLogger)))",
+		fmt.Sprintf("if _, err := scheduler.AddFunc(%s, func() {", strconv.Quote(g.Options.Schedule)),
+		"\tif err := run(); err != nil {",
+		"\t\tfmt.Fprintln(os.Stderr, err)",
+		"\t}",
+		"}); err != nil {",
+		"\tfmt.Fprintln(os.Stderr, err)",
+		"\tos.Exit(1)",
+		"}",
+		"scheduler.Start()",
+		"",
+		"// Run until stopped, letting a running job finish",
+		"signals := make(chan os.Signal, 1)",
+		"signal.Notify(signals, os.Interrupt, syscall.SIGTERM)",
+		"<-signals",
+		"<-scheduler.Stop().Done()",
+	}
+}
@@ -0,0 +1,301 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	utilities["sed"] = (*GoCodeGenerator).generateSed
+
+	runtimeHelpers["sed"] = runtimeHelper{
+		Source: `// sedSubstitution is an s command of a sed script translated to Go, its
+// replacement in the syntax of Regexp.Expand
+type sedSubstitution struct {
+	pattern, replacement string
+	global, ignoreCase   bool
+}
+
+// sed applies the substitutions of a sed script to each line of files, or
+// of stdin without files, and prints the result, like sed. Patterns are
+// basic regular expressions unless extended. With inPlace, the files are
+// rewritten instead. It fails with status 1 if a pattern is invalid, and 2
+// if a file cannot be read.
+func sed(stdio *streams, stdin io.Reader, extended, inPlace bool, script []sedSubstitution, files ...string) error {
+	res := make([]*regexp.Regexp, len(script))
+	for i, s := range script {
+		expr := posixRegexp(s.pattern, extended)
+		if s.ignoreCase {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			fmt.Fprintf(stdio.Stderr, "sed: %v\n", err)
+			return exitError(1)
+		}
+		res[i] = re
+	}
+	edit := func(input io.Reader, out io.Writer) error {
+		lines := bufio.NewReader(input)
+		for {
+			line, err := lines.ReadString('\n')
+			if line != "" {
+				text, newline := strings.CutSuffix(line, "\n")
+				for i, s := range script {
+					if s.global {
+						text = res[i].ReplaceAllString(text, s.replacement)
+					} else if match := res[i].FindStringSubmatchIndex(text); match != nil {
+						expanded := res[i].ExpandString(nil, s.replacement, text, match)
+						text = text[:match[0]] + string(expanded) + text[match[1]:]
+					}
+				}
+				if newline {
+					text += "\n"
+				}
+				if _, err := io.WriteString(out, text); err != nil {
+					return err
+				}
+			}
+			if err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	out := bufio.NewWriter(stdio.Stdout)
+	defer out.Flush()
+	failed := false
+	for _, name := range files {
+		if name == "-" && !inPlace {
+			if err := edit(stdin, out); err != nil {
+				utilityError(stdio, "sed", "read error on stdin", err)
+				failed = true
+			}
+			continue
+		}
+		data, err := os.ReadFile(name)
+		if err != nil {
+			utilityError(stdio, "sed", "can't read "+name, err)
+			failed = true
+			continue
+		}
+		if !inPlace {
+			edit(bytes.NewReader(data), out)
+			continue
+		}
+		var b bytes.Buffer
+		edit(bytes.NewReader(data), &b)
+		if info, err := os.Stat(name); err != nil {
+			utilityError(stdio, "sed", name, err)
+			failed = true
+		} else if err := os.WriteFile(name, b.Bytes(), info.Mode()); err != nil {
+			utilityError(stdio, "sed", "couldn't edit "+name, err)
+			failed = true
+		}
+	}
+	if failed {
+		return exitError(2)
+	}
+	return nil
+}`,
+		Imports:  []string{"bufio", "bytes", "fmt", "io", "os", "regexp", "strings"},
+		Requires: []string{"exitError", "posixRegexp", "utilityError"},
+	}
+}
+
+// generateSed translates sed with a script of s commands, known when
+// converting, into a call of the sed helper, with the options -E, -r, -i and
+// -e. It reports false for other options and scripts, including in-place
+// editing without files, so that sed is executed.
+func (g *GoCodeGenerator) generateSed(cmd parser.Command) (string, bool) {
+	options, operands, ok := g.utilityArgs(cmd, "Eri", "e")
+	if !ok {
+		return "", false
+	}
+	extended, inPlace := false, false
+	var scripts []string
+	for _, option := range options {
+		switch option.name {
+		case 'E', 'r':
+			extended = true
+		case 'i':
+			inPlace = true
+		case 'e':
+			scripts = append(scripts, option.value)
+		}
+	}
+	if len(scripts) == 0 {
+		// The first operand is the script
+		if len(operands) == 0 || g.expandsArg(cmd, operands[0]) {
+			return "", false
+		}
+		scripts = []string{g.goArg(cmd.Args[operands[0]])}
+		operands = operands[1:]
+	}
+	if inPlace && len(operands) == 0 {
+		return "", false
+	}
+
+	var substitutions []string
+	for _, expr := range scripts {
+		script, err := strconv.Unquote(expr)
+		if err != nil {
+			// The script is only known at runtime
+			return "", false
+		}
+		parsed, ok := sedSubstitutions(script)
+		if !ok {
+			return "", false
+		}
+		substitutions = append(substitutions, parsed...)
+	}
+
+	g.requireHelper("sed")
+	call := fmt.Sprintf("sed(%s, %s, %t, %t, []sedSubstitution{%s}%s)", g.stdio(), g.utilityInput(cmd), extended, inPlace,
+		strings.Join(substitutions, ", "), g.operandArgs(cmd, operands))
+	return g.checkErr(cmd, call), true
+}
+
+// sedSubstitutions parses a sed script made of s commands, separated by
+// semicolons or newlines, into sedSubstitution literals. It reports false for
+// other commands, addresses, flags other than g, i and I, empty patterns,
+// which reuse the last one, back-references in patterns and case conversions
+// in replacements.
+func sedSubstitutions(script string) ([]string, bool) {
+	var substitutions []string
+	i := 0
+	for {
+		for i < len(script) && strings.IndexByte(" \t\n;", script[i]) >= 0 {
+			i++
+		}
+		if i == len(script) {
+			break
+		}
+		if script[i] != 's' || i+1 == len(script) || strings.IndexByte("\\\n", script[i+1]) >= 0 {
+			return nil, false
+		}
+		delim := script[i+1]
+		i += 2
+
+		pattern, end, ok := sedPart(script, i, delim, true)
+		if !ok || pattern == "" || backreference.MatchString(pattern) {
+			return nil, false
+		}
+		replacement, end, ok := sedPart(script, end, delim, false)
+		if !ok {
+			return nil, false
+		}
+		expand, ok := sedReplacement(replacement)
+		if !ok {
+			return nil, false
+		}
+
+		global, ignoreCase := false, false
+		for i = end; i < len(script) && strings.IndexByte(" \t\n;", script[i]) < 0; i++ {
+			switch script[i] {
+			case 'g':
+				global = true
+			case 'i', 'I':
+				ignoreCase = true
+			default:
+				return nil, false
+			}
+		}
+
+		fields := []string{"pattern: " + strconv.Quote(pattern), "replacement: " + strconv.Quote(expand)}
+		if global {
+			fields = append(fields, "global: true")
+		}
+		if ignoreCase {
+			fields = append(fields, "ignoreCase: true")
+		}
+		substitutions = append(substitutions, "{"+strings.Join(fields, ", ")+"}")
+	}
+	return substitutions, len(substitutions) > 0
+}
+
+// sedPart returns the pattern or replacement of an s command starting at
+// index i of script and the index following the delimiter ending it, with
+// escaped delimiters unescaped. Delimiters in the bracket expressions of a
+// pattern are part of them.
+func sedPart(script string, i int, delim byte, pattern bool) (string, int, bool) {
+	var b strings.Builder
+	for ; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case c == delim:
+			return b.String(), i + 1, true
+		case c == '\\' && i+1 < len(script):
+			i++
+			if script[i] != delim {
+				b.WriteByte('\\')
+			} else if pattern && strings.IndexByte(`.*[]^$+?(){}|`, delim) >= 0 {
+				// Whether the delimiter is then an operator depends on the sed
+				return "", 0, false
+			}
+			b.WriteByte(script[i])
+		case c == '[' && pattern:
+			j := i + 1
+			if j < len(script) && script[j] == '^' {
+				j++
+			}
+			if j < len(script) && script[j] == ']' {
+				j++
+			}
+			end := strings.IndexByte(script[j:], ']')
+			if end < 0 {
+				return "", 0, false
+			}
+			b.WriteString(script[i : j+end+1])
+			i = j + end
+		case c == '\n':
+			return "", 0, false
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, false
+}
+
+// sedReplacement converts the replacement of an s command into the syntax
+// of Regexp.Expand: & is the match, \1 to \9 its groups and \n a newline.
+// It reports false for the case conversions of GNU sed.
+func sedReplacement(replacement string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(replacement); i++ {
+		c := replacement[i]
+		switch {
+		case c == '&':
+			b.WriteString("${0}")
+		case c == '$':
+			b.WriteString("$$")
+		case c == '\\' && i+1 < len(replacement):
+			i++
+			c = replacement[i]
+			switch {
+			case c >= '0' && c <= '9':
+				b.WriteString("${" + string(c) + "}")
+			case c == 'n':
+				b.WriteByte('\n')
+			case c == 't':
+				b.WriteByte('\t')
+			case strings.IndexByte("LUlEu", c) >= 0:
+				return "", false
+			default:
+				b.WriteByte(c)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), true
+}
@@ -0,0 +1,95 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/TFMV/bash2go/diagnostics"
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	runtimeHelpers["unboundVariable"] = runtimeHelper{
+		Source: `// unboundVariable reports a reference to an unset variable while set -u is
+// in effect and ends the program, like Bash
+func unboundVariable(name string) {
+	fmt.Fprintf(os.Stderr, "%s: unbound variable\n", name)
+	os.Exit(1)
+}`,
+		Imports: []string{"fmt", "os"},
+	}
+	runtimeHelpers["nounsetEnv"] = runtimeHelper{
+		Source: `// nounsetEnv returns the value of an environment variable, ending the
+// program if it is unset while set -u is in effect
+func nounsetEnv(name string) string {
+	value, ok := os.LookupEnv(name)
+	if !ok && shellOptions["nounset"] {
+		unboundVariable(name)
+	}
+	return value
+}`,
+		Imports:  []string{"os"},
+		Requires: []string{"shellOptions", "unboundVariable"},
+	}
+	runtimeHelpers["nounsetArg"] = runtimeHelper{
+		Source: `// nounsetArg returns the positional parameter $n of args, ending the
+// program if there are fewer parameters while set -u is in effect
+func nounsetArg(args []string, n int) string {
+	if n > len(args) && shellOptions["nounset"] {
+		unboundVariable("$" + strconv.Itoa(n))
+	}
+	return positionalArg(args, n)
+}`,
+		Imports:  []string{"strconv"},
+		Requires: []string{"shellOptions", "unboundVariable", "positionalArg"},
+	}
+}
+
+// supportedSetOptions lists the set options honoured by generated code.
+var supportedSetOptions = map[string]bool{
+	"nounset":  true,
+	"pipefail": true,
+}
+
+// generateSet generates Go code for the set builtin. Turning options on or
+// off updates the shellOptions table, which the runtime helpers consult.
+func (g *GoCodeGenerator) generateSet(cmd parser.Command) string {
+	options, _, setsArgs := parser.SetArgs(cmd.Args)
+	if setsArgs {
+		g.unsupported++
+		g.IR.Diagnose(diagnostics.SeverityError, cmd.Pos, diagnostics.CodeUnsupported,
+			"setting the positional parameters with set is not supported")
+		return fmt.Sprintf("// Unsupported set: %s", commentText(strings.Join(cmd.Args, " ")))
+	}
+
+	var lines []string
+	for _, option := range options {
+		if option.Name == "errexit" {
+			// Like under set -e, a command failing outside a condition ends
+			// the generated program, which set +e cannot change
+			if !option.On {
+				g.IR.Diagnose(diagnostics.SeverityWarning, cmd.Pos, diagnostics.CodeUnsupported,
+					"set +e is not supported: failing commands still end the script")
+			}
+			continue
+		}
+		if !supportedSetOptions[option.Name] {
+			g.IR.Diagnose(diagnostics.SeverityWarning, cmd.Pos, diagnostics.CodeUnsupported,
+				"set option %s has no effect in generated code", option.Name)
+			continue
+		}
+		g.requireHelper("shellOptions")
+		lines = append(lines, fmt.Sprintf("shellOptions[%s] = %t", strconv.Quote(option.Name), option.On))
+	}
+	if len(lines) == 0 {
+		return "// set has no effect"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// nounset reports whether set -u may be in effect when the generated
+// program runs, in which case reading unset variables ends it.
+func (g *GoCodeGenerator) nounset() bool {
+	return g.mayUseShellOption("nounset")
+}
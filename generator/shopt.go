@@ -0,0 +1,104 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/TFMV/bash2go/diagnostics"
+	"github.com/TFMV/bash2go/parser"
+)
+
+// supportedShellOptions lists the shopt options honoured by generated code.
+var supportedShellOptions = map[string]bool{
+	"nullglob":    true,
+	"failglob":    true,
+	"globstar":    true,
+	"extglob":     true,
+	"nocasematch": true,
+}
+
+// generateShopt generates Go code for the shopt builtin. Setting or unsetting
+// options updates the shellOptions table consulted by the glob and pattern
+// matching helpers; without -s or -u the named options are printed.
+func (g *GoCodeGenerator) generateShopt(cmd parser.Command) string {
+	g.requireHelper("shellOptions")
+
+	mode := ""
+	var names []string
+	for _, arg := range cmd.Args {
+		switch arg {
+		case "-s", "-u":
+			mode = arg
+		case "-q", "-p":
+			// Quiet and reusable output only change how options are printed
+		default:
+			names = append(names, arg)
+		}
+	}
+
+	var lines []string
+	for _, name := range names {
+		if !supportedShellOptions[name] {
+			g.IR.Diagnose(diagnostics.SeverityWarning, cmd.Pos, diagnostics.CodeUnsupported,
+				"shopt option %s has no effect in generated code", name)
+		}
+
+		switch mode {
+		case "-s":
+			lines = append(lines, fmt.Sprintf("shellOptions[%s] = true", strconv.Quote(name)))
+		case "-u":
+			lines = append(lines, fmt.Sprintf("shellOptions[%s] = false", strconv.Quote(name)))
+		default:
+			g.RequiredImports["fmt"] = true
+			lines = append(lines, fmt.Sprintf(`if shellOptions[%[1]s] {
+		fmt.Fprintf(%[2]s.Stdout, "%%s\ton\n", %[1]s)
+	} else {
+		fmt.Fprintf(%[2]s.Stdout, "%%s\toff\n", %[1]s)
+	}`, strconv.Quote(name), g.stdio()))
+		}
+	}
+
+	if len(lines) == 0 {
+		return "// shopt without option names has no effect"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// validateShellOptions checks that the shell options set by Options are ones
+// generated code honours
+func validateShellOptions(names []string) error {
+	for _, name := range names {
+		if !supportedShellOptions[name] {
+			return fmt.Errorf("unsupported shell option %q", name)
+		}
+	}
+	return nil
+}
+
+// shellOptionsPrologue returns the statements setting the shell options of
+// Options, for programs whose helpers consult them
+func (g *GoCodeGenerator) shellOptionsPrologue() []string {
+	if !g.helpers["shellOptions"] {
+		return nil
+	}
+	var lines []string
+	for _, name := range g.Options.ShellOptions {
+		lines = append(lines, fmt.Sprintf("shellOptions[%s] = true", strconv.Quote(name)))
+	}
+	return lines
+}
+
+// mayUseShellOption reports whether a shell option may be set when the
+// generated program runs, by the script or by Options
+func (g *GoCodeGenerator) mayUseShellOption(name string) bool {
+	if _, ok := g.IR.ShellOptions[name]; ok {
+		return true
+	}
+	for _, option := range g.Options.ShellOptions {
+		if option == name {
+			return true
+		}
+	}
+	return false
+}
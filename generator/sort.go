@@ -0,0 +1,149 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	utilities["sort"] = (*GoCodeGenerator).generateSort
+
+	runtimeHelpers["sortLines"] = runtimeHelper{
+		Source: `// sortOptions are the options of a sort command translated to Go
+type sortOptions struct {
+	numeric, reverse, unique bool
+}
+
+// sortLines prints the lines of files, or of stdin without files, sorted
+// like sort in the C locale: by bytes, or by their leading numbers with
+// numeric, lines with equal numbers being sorted by bytes. With unique, only
+// the first line of those comparing equal is printed. It fails with status 2
+// if a file cannot be read.
+func sortLines(stdio *streams, stdin io.Reader, opts sortOptions, files ...string) error {
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	var lines []string
+	for _, name := range files {
+		input := io.NopCloser(stdin)
+		if name != "-" {
+			f, err := os.Open(name)
+			if err != nil {
+				utilityError(stdio, "sort", "cannot read: "+name, err)
+				return exitError(2)
+			}
+			input = f
+		}
+		data, err := io.ReadAll(input)
+		input.Close()
+		if err != nil {
+			utilityError(stdio, "sort", "read failed: "+name, err)
+			return exitError(2)
+		}
+		if len(data) > 0 {
+			lines = append(lines, strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")...)
+		}
+	}
+
+	compare := func(a, b string) int {
+		if opts.numeric {
+			if c := compareNumbers(a, b); c != 0 || opts.unique {
+				return c
+			}
+		}
+		return strings.Compare(a, b)
+	}
+	sort.SliceStable(lines, func(i, j int) bool {
+		if opts.reverse {
+			return compare(lines[j], lines[i]) < 0
+		}
+		return compare(lines[i], lines[j]) < 0
+	})
+
+	out := bufio.NewWriter(stdio.Stdout)
+	defer out.Flush()
+	for i, line := range lines {
+		if opts.unique && i > 0 && compare(lines[i-1], line) == 0 {
+			continue
+		}
+		out.WriteString(line + "\n")
+	}
+	return nil
+}
+
+// compareNumbers compares the numbers at the start of a and b, after blanks,
+// like sort -n: an optional minus sign, digits and decimals, 0 if there are
+// none
+func compareNumbers(a, b string) int {
+	number := func(s string) (bool, string, string) {
+		s = strings.TrimLeft(s, " \t")
+		negative := strings.HasPrefix(s, "-")
+		if negative {
+			s = s[1:]
+		}
+		digits := func(s string) int {
+			i := 0
+			for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+				i++
+			}
+			return i
+		}
+		n := digits(s)
+		integer, fraction := strings.TrimLeft(s[:n], "0"), ""
+		if n < len(s) && s[n] == '.' {
+			fraction = strings.TrimRight(s[n+1:n+1+digits(s[n+1:])], "0")
+		}
+		return negative && (integer != "" || fraction != ""), integer, fraction
+	}
+	negA, intA, fracA := number(a)
+	negB, intB, fracB := number(b)
+	if negA != negB {
+		if negA {
+			return -1
+		}
+		return 1
+	}
+	c := cmp.Compare(len(intA), len(intB))
+	if c == 0 {
+		c = strings.Compare(intA, intB)
+	}
+	if c == 0 {
+		c = strings.Compare(fracA, fracB)
+	}
+	if negA {
+		return -c
+	}
+	return c
+}`,
+		Imports:  []string{"bufio", "cmp", "io", "os", "sort", "strings"},
+		Requires: []string{"exitError", "utilityError"},
+	}
+}
+
+// generateSort translates sort with the options -n, -r and -u into a call
+// of the sortLines helper. It reports false for other options, such as
+// sort keys, so that sort is executed.
+func (g *GoCodeGenerator) generateSort(cmd parser.Command) (string, bool) {
+	options, operands, ok := g.utilityArgs(cmd, "nru", "")
+	if !ok {
+		return "", false
+	}
+	var fields []string
+	for _, option := range options {
+		switch option.name {
+		case 'n':
+			fields = append(fields, "numeric: true")
+		case 'r':
+			fields = append(fields, "reverse: true")
+		case 'u':
+			fields = append(fields, "unique: true")
+		}
+	}
+
+	g.requireHelper("sortLines")
+	call := fmt.Sprintf("sortLines(%s, %s, sortOptions{%s}%s)", g.stdio(), g.utilityInput(cmd), strings.Join(uniqueFields(fields), ", "),
+		g.operandArgs(cmd, operands))
+	return g.checkErr(cmd, call), true
+}
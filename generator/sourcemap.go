@@ -0,0 +1,76 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+// lineMarker prefixes the comments placed before each generated statement
+// to record the line of the Bash statement it came from. The markers are
+// removed from the final code once the source map has been built.
+const lineMarker = "//bash2go:line "
+
+// lineMarkerFor returns the marker line for a statement at pos, or an empty
+// string if the position is unknown. The marker records the file of the
+// statement, which differs between the scripts of a combined program.
+func lineMarkerFor(pos parser.Position) string {
+	if !pos.IsValid() {
+		return ""
+	}
+	return fmt.Sprintf("%s%d %s\n", lineMarker, pos.Line, pos.File)
+}
+
+// stripLineMarkers removes the line markers from code and returns the
+// remaining code with the Bash position of each of its lines, indexed from
+// zero. A line maps to the nearest marker above it within the same top-level
+// declaration, or to an invalid position if there is none.
+func stripLineMarkers(code string) (string, []parser.Position) {
+	// Copy the kept lines in one pass, as generated code can be large
+	var kept strings.Builder
+	kept.Grow(len(code))
+	sourcePositions := make([]parser.Position, 0, strings.Count(code, "\n")+1)
+	var current parser.Position
+	for rest, more := code, true; more; {
+		var line string
+		line, rest, more = strings.Cut(rest, "\n")
+		trimmed := strings.TrimSpace(line)
+		if marker, ok := strings.CutPrefix(trimmed, lineMarker); ok {
+			n, file, _ := strings.Cut(marker, " ")
+			if line, err := strconv.ParseUint(n, 10, 0); err == nil {
+				current = parser.Position{File: file, Line: uint(line)}
+			}
+			continue
+		}
+		// Top-level declarations start a new scope
+		if line != "" && line[0] != '\t' && line[0] != ' ' && line != "}" {
+			current = parser.Position{}
+		}
+		if len(sourcePositions) > 0 {
+			kept.WriteByte('\n')
+		}
+		kept.WriteString(line)
+		sourcePositions = append(sourcePositions, current)
+	}
+	return kept.String(), sourcePositions
+}
+
+// SourcePosition returns the position in the Bash script of the statement
+// that produced line goLine (counting from one) of the code returned by the
+// last Generate call. The position is invalid if the line was not generated
+// from a statement.
+func (g *GoCodeGenerator) SourcePosition(goLine int) parser.Position {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if goLine < 1 || goLine > len(g.sourceMap) || !g.sourceMap[goLine-1].IsValid() {
+		return parser.Position{}
+	}
+	pos := g.sourceMap[goLine-1]
+	if pos.File == "" {
+		pos.File = g.IR.Filename
+	}
+	return pos
+}
@@ -0,0 +1,145 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// dynamicShellVars lists the special variables served by the shellVars
+// runtime table. Their values change as the script runs, so unlike ordinary
+// script variables they cannot be modeled as static Go variables.
+var dynamicShellVars = map[string]bool{
+	"?":           true,
+	"!":           true,
+	"$":           true,
+	"0":           true,
+	"_":           true,
+	"FUNCNAME":    true,
+	"BASH_SOURCE": true,
+}
+
+func init() {
+	runtimeHelpers["shellVars"] = runtimeHelper{
+		Source: `// shellVars is the table of special shell variables such as $?, $!, $_,
+// and FUNCNAME whose values change as the script runs, along with $$ and $0,
+// the process ID and name of the program
+var shellVars = struct {
+	sync.Mutex
+	values    map[string]string
+	functions []string
+}{values: map[string]string{
+	"?": "0",
+	"$": strconv.Itoa(os.Getpid()),
+	"0": os.Args[0],
+}}
+
+// shellVar returns the current value of a special shell variable
+func shellVar(name string) string {
+	shellVars.Lock()
+	defer shellVars.Unlock()
+	if name == "FUNCNAME" {
+		if n := len(shellVars.functions); n > 0 {
+			return shellVars.functions[n-1]
+		}
+		return ""
+	}
+	return shellVars.values[name]
+}
+
+// setShellVar sets the value of a special shell variable
+func setShellVar(name, value string) {
+	shellVars.Lock()
+	defer shellVars.Unlock()
+	shellVars.values[name] = value
+}
+
+// enterFunction pushes name onto the FUNCNAME stack and returns a function
+// that pops it again
+func enterFunction(name string) func() {
+	shellVars.Lock()
+	defer shellVars.Unlock()
+	shellVars.functions = append(shellVars.functions, name)
+	return func() {
+		shellVars.Lock()
+		defer shellVars.Unlock()
+		shellVars.functions = shellVars.functions[:len(shellVars.functions)-1]
+	}
+}
+
+// exitStatus converts the error of a finished command into its exit status:
+// 127 if the command was not found and 1 for other errors without a status
+func exitStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	if errors.Is(err, exec.ErrNotFound) {
+		return 127
+	}
+	return 1
+}`,
+		Imports: []string{"errors", "os", "os/exec", "strconv", "sync"},
+	}
+}
+
+// usesShellVar reports whether the script reads the special variable name,
+// in which case the generated code keeps its runtime value up to date.
+func (g *GoCodeGenerator) usesShellVar(name string) bool {
+	if name == "?" && g.inJob {
+		// Jobs run in a subshell, whose $? the script never sees; their
+		// failures are reported by wait
+		return false
+	}
+	return g.IR.SpecialVars[name]
+}
+
+// shellVarRef returns a Go expression reading a special variable from the
+// runtime table.
+func (g *GoCodeGenerator) shellVarRef(name string) string {
+	g.requireHelper("shellVars")
+	return fmt.Sprintf("shellVar(%s)", strconv.Quote(name))
+}
+
+// setShellVarCode returns a Go statement updating a special variable in the
+// runtime table.
+func (g *GoCodeGenerator) setShellVarCode(name, value string) string {
+	g.requireHelper("shellVars")
+	if name == "?" {
+		g.statusSet = true
+	}
+	return fmt.Sprintf("setShellVar(%s, %s)", strconv.Quote(name), value)
+}
+
+// functionPrologue returns the statements emitted at the start of the
+// translated function name, maintaining the FUNCNAME stack when needed.
+func (g *GoCodeGenerator) functionPrologue(name string) []string {
+	if !g.usesShellVar("FUNCNAME") {
+		return nil
+	}
+	g.requireHelper("shellVars")
+	return []string{fmt.Sprintf("defer enterFunction(%s)()", strconv.Quote(name))}
+}
+
+// scriptPrologue returns the statements emitted at the start of run.
+func (g *GoCodeGenerator) scriptPrologue() []string {
+	if !g.usesShellVar("BASH_SOURCE") {
+		return nil
+	}
+	return []string{g.setShellVarCode("BASH_SOURCE", strconv.Quote(g.IR.Filename))}
+}
+
+// lastArgCode returns a statement recording the last argument of cmd in $_,
+// or an empty string if the script never reads $_.
+func (g *GoCodeGenerator) lastArgCode(name string, args []string) string {
+	if !g.usesShellVar("_") {
+		return ""
+	}
+	last := name
+	if len(args) > 0 {
+		last = args[len(args)-1]
+	}
+	return g.setShellVarCode("_", g.goArg(last))
+}
@@ -0,0 +1,68 @@
+package generator
+
+import "fmt"
+
+func init() {
+	runtimeHelpers["splitFields"] = runtimeHelper{
+		Source: `// splitFields splits the value of an unquoted expansion into fields at the
+// characters of ifs, like the shell. Runs of blanks in ifs separate fields
+// and are trimmed at both ends; any other character of ifs ends a field,
+// along with the blanks around it, so that two of them in a row delimit an
+// empty field. An empty ifs leaves the value whole.
+func splitFields(s, ifs string) []string {
+	if ifs == "" {
+		if s == "" {
+			return nil
+		}
+		return []string{s}
+	}
+	blank := func(r rune) bool {
+		return (r == ' ' || r == '\t' || r == '\n') && strings.ContainsRune(ifs, r)
+	}
+	runes := []rune(strings.TrimFunc(s, blank))
+	var fields []string
+	start := 0
+	for i := 0; i < len(runes); {
+		if !strings.ContainsRune(ifs, runes[i]) {
+			i++
+			continue
+		}
+		fields = append(fields, string(runes[start:i]))
+		for i < len(runes) && blank(runes[i]) {
+			i++
+		}
+		if i < len(runes) && !blank(runes[i]) && strings.ContainsRune(ifs, runes[i]) {
+			i++
+			for i < len(runes) && blank(runes[i]) {
+				i++
+			}
+		}
+		start = i
+	}
+	if start < len(runes) {
+		fields = append(fields, string(runes[start:]))
+	}
+	return fields
+}`,
+		Imports: []string{"strings"},
+	}
+}
+
+// defaultIFS is the value of $IFS unless the script assigns it.
+const defaultIFS = `" \t\n"`
+
+// ifsRef returns a Go expression for the characters unquoted expansions are
+// split at: the IFS variable if the script has one, or the default blanks.
+func (g *GoCodeGenerator) ifsRef() string {
+	if g.isScriptVariable("IFS") {
+		return g.varRef("IFS")
+	}
+	return defaultIFS
+}
+
+// splitWord returns a Go []string expression for the fields of a word whose
+// unquoted expansions are split at $IFS.
+func (g *GoCodeGenerator) splitWord(word string) string {
+	g.requireHelper("splitFields")
+	return fmt.Sprintf("splitFields(%s, %s)", g.goArg(word), g.ifsRef())
+}
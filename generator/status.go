@@ -0,0 +1,150 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	runtimeHelpers["exitError"] = runtimeHelper{
+		Source: `// exitError is the error of a function or subshell ending with a non-zero
+// status through return or exit
+type exitError int
+
+func (e exitError) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
+
+// ExitCode returns the exit status
+func (e exitError) ExitCode() int {
+	return int(e)
+}`,
+		Imports: []string{"fmt"},
+	}
+	runtimeHelpers["exitArg"] = runtimeHelper{
+		Source: `// exitArg converts the argument of exit into an exit status, 0 if it is not
+// a number
+func exitArg(s string) int {
+	code, _ := strconv.Atoi(s)
+	return code
+}`,
+		Imports: []string{"strconv"},
+	}
+	runtimeHelpers["statusError"] = runtimeHelper{
+		Source: `// statusError returns the error of a command that ended with the exit status
+// recorded in $?, nil if it succeeded
+func statusError() error {
+	if code, _ := strconv.Atoi(shellVar("?")); code != 0 {
+		return exitError(code)
+	}
+	return nil
+}`,
+		Imports:  []string{"strconv"},
+		Requires: []string{"exitError", "shellVars"},
+	}
+}
+
+// recordStatus extends the code running cmd to record its exit status in $?
+// if the script reads it and the code does not record it already. Like in
+// Bash, translated commands succeed with status 0; those that can fail
+// record the status of their error, then fail as they would without $?.
+func (g *GoCodeGenerator) recordStatus(cmd parser.Command, code string) string {
+	if !g.usesShellVar("?") || g.statusSet {
+		return code
+	}
+	if _, ok := g.IR.Functions[cmd.Name]; ok {
+		// The commands of the function record its status
+		return code
+	}
+	switch cmd.Name {
+	case "exit", "return", "exec":
+		return code
+	}
+
+	if !strings.Contains(code, "return ") {
+		return code + "\n" + g.setShellVarCode("?", `"0"`)
+	}
+	// The code wraps its errors already
+	return g.checkStatus(fmt.Sprintf("func() error {\n%s\n}()", bodyWithReturn(code)), "return err")
+}
+
+// checkStatus returns Go code checking the error of a command returned by
+// call and failing with fail if it is not nil. Whether a failing command
+// ends the script does not depend on whether the script reads $?: one that
+// does only records the status there first, for the conditions the command
+// is part of and the code handling the failure to see it.
+func (g *GoCodeGenerator) checkStatus(call, fail string) string {
+	if !g.usesShellVar("?") {
+		return fmt.Sprintf("if err := %s; err != nil {\n\t%s\n}", call, fail)
+	}
+	g.RequiredImports["strconv"] = true
+	return fmt.Sprintf("if err := %s; err != nil {\n\t%s\n\t%s\n}\n%s",
+		call, g.setShellVarCode("?", "strconv.Itoa(exitStatus(err))"), fail, g.setShellVarCode("?", `"0"`))
+}
+
+// generateReturn generates Go code for the return builtin, which ends the
+// translated function with the given status as its error result. Scripts
+// reading $? also get the status there.
+func (g *GoCodeGenerator) generateReturn(cmd parser.Command) string {
+	if len(cmd.Args) == 0 {
+		// The function ends with the status of its last command
+		return "return nil"
+	}
+	code := cmd.Args[0]
+	record := ""
+	if g.usesShellVar("?") {
+		record = g.setShellVarCode("?", g.goArg(code)) + "\n"
+	}
+	if n, err := strconv.Atoi(code); err == nil {
+		if n == 0 {
+			return record + "return nil"
+		}
+		g.requireHelper("exitError")
+		return fmt.Sprintf("%sreturn exitError(%d)", record, n)
+	}
+	if record != "" {
+		g.requireHelper("statusError")
+		return record + "return statusError()"
+	}
+
+	g.requireHelper("exitError")
+	g.RequiredImports["strconv"] = true
+	return fmt.Sprintf(`if code, _ := strconv.Atoi(%s); code != 0 {
+		return exitError(code)
+	}
+	return nil`, g.goArg(code))
+}
+
+// endsWithReturn reports whether the last line of Go code is a return
+// statement, which makes a return following it unreachable
+func endsWithReturn(code string) bool {
+	code = strings.TrimRight(code, " \t\n")
+	line := strings.TrimSpace(code[strings.LastIndexByte(code, '\n')+1:])
+	return line == "return" || strings.HasPrefix(line, "return ")
+}
+
+// withReturn appends return nil to the lines of the body of a function
+// returning an error, unless its last statement returns already
+func withReturn(lines []string) []string {
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.TrimSpace(lines[i]) == "" {
+			continue
+		}
+		if endsWithReturn(lines[i]) {
+			return lines[:i+1]
+		}
+		break
+	}
+	return append(lines, "return nil")
+}
+
+// bodyWithReturn is withReturn for a function body given as code
+func bodyWithReturn(code string) string {
+	if endsWithReturn(code) {
+		return code
+	}
+	return code + "\nreturn nil"
+}
@@ -0,0 +1,61 @@
+package generator
+
+func init() {
+	runtimeHelpers["streams"] = runtimeHelper{
+		Source: `// streams are the standard streams of the shell commands run in: those of
+// the script, or those of a pipeline stage, command substitution or
+// background job, which run concurrently with streams of their own.
+// Redirections of a command apply to a copy of the streams, while those of
+// exec change the streams of the shell.
+type streams struct {
+	Stdin, Stdout, Stderr *os.File
+
+	input *inputBuffer
+}
+
+// inputBuffer buffers the standard input for read and the utilities
+// translated to Go. Copies of streams reading the same file share it.
+type inputBuffer struct {
+	file   *os.File
+	reader *bufio.Reader
+}
+
+// stdio are the standard streams of the script
+var stdio = newStreams(os.Stdin, os.Stdout, os.Stderr)
+
+// newStreams returns streams reading stdin and writing stdout and stderr
+func newStreams(stdin, stdout, stderr *os.File) *streams {
+	return &streams{Stdin: stdin, Stdout: stdout, Stderr: stderr, input: &inputBuffer{file: stdin}}
+}
+
+// redirected returns a copy of the streams for the redirections of a
+// command to replace
+func (s *streams) redirected() *streams {
+	c := *s
+	return &c
+}
+
+// stdinReader returns the buffered standard input. It starts over when the
+// standard input is replaced, as by a redirection of a command group, and
+// input it buffered is not seen by external commands started afterwards.
+func (s *streams) stdinReader() *bufio.Reader {
+	if s.input.file != s.Stdin {
+		s.input = &inputBuffer{file: s.Stdin}
+	}
+	if s.input.reader == nil {
+		s.input.reader = bufio.NewReader(s.Stdin)
+	}
+	return s.input.reader
+}`,
+		Imports: []string{"bufio", "os"},
+	}
+}
+
+// stdio returns the Go expression for the standard streams of the shell the
+// code being generated runs in. Translated functions, pipeline stages,
+// command substitutions and jobs take their streams as a parameter named
+// like the package-level streams of the script, which it shadows.
+func (g *GoCodeGenerator) stdio() string {
+	g.requireHelper("streams")
+	return "stdio"
+}
@@ -0,0 +1,84 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// subcommandMain returns the body of main for a program combined from
+// several scripts. It runs the function named by the first argument, passing
+// it the remaining arguments, and runs the commands of Options.SignalCommands
+// when their signals arrive.
+func (g *GoCodeGenerator) subcommandMain() []string {
+	names := g.IR.Subcommands
+	g.requireHelper("streams")
+	lines := []string{"commands := map[string]func(stdio *streams, args []string) error{"}
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("\t%s: %s,", strconv.Quote(name), g.funcIdent(name)))
+	}
+	lines = append(lines,
+		"}",
+		"if len(os.Args) < 2 || commands[os.Args[1]] == nil {",
+		fmt.Sprintf("\tfmt.Fprintf(os.Stderr, \"usage: %%s %s\\n\", os.Args[0])", strings.Join(names, "|")),
+		"\tos.Exit(2)",
+		"}",
+		"command := os.Args[1]",
+		"os.Args = append(os.Args[:1], os.Args[2:]...)",
+	)
+
+	// Handle signals in a stable order
+	signals := make([]string, 0, len(g.Options.SignalCommands))
+	for sig := range g.Options.SignalCommands {
+		signals = append(signals, sig)
+	}
+	sort.Strings(signals)
+	for _, sig := range signals {
+		handler := g.Options.SignalCommands[sig]
+		g.RequiredImports["os/signal"] = true
+		g.RequiredImports["syscall"] = true
+		lines = append(lines,
+			"",
+			fmt.Sprintf("// Run %s on SIG%s while another command runs", handler, sig),
+			fmt.Sprintf("if command != %s {", strconv.Quote(handler)),
+			"\tsignals := make(chan os.Signal, 1)",
+			fmt.Sprintf("\tsignal.Notify(signals, syscall.SIG%s)", sig),
+			"\tgo func() {",
+			"\t\tfor range signals {",
+			fmt.Sprintf("\t\t\tif err := %s(stdio, nil); err != nil {", g.funcIdent(handler)),
+			"\t\t\t\tfmt.Fprintln(os.Stderr, err)",
+			"\t\t\t}",
+			"\t\t}",
+			"\t}()",
+			"}",
+		)
+	}
+
+	// Run the EXIT trap and wait for background jobs once the command ends
+	var after []string
+	if g.IR.Traps["EXIT"] {
+		g.requireHelper("exitTrap")
+		after = append(after, "runExitTrap()")
+	}
+	if g.usesJobs() {
+		g.requireHelper("jobs")
+		after = append(after, "waitJobs()")
+	}
+	if len(after) > 0 {
+		lines = append(append(lines, "", "err := commands[command](stdio, os.Args[1:])"), after...)
+		return append(lines,
+			"if err != nil {",
+			"\tfmt.Fprintln(os.Stderr, err)",
+			"\tos.Exit(1)",
+			"}",
+		)
+	}
+	return append(lines,
+		"",
+		"if err := commands[command](stdio, os.Args[1:]); err != nil {",
+		"\tfmt.Fprintln(os.Stderr, err)",
+		"\tos.Exit(1)",
+		"}",
+	)
+}
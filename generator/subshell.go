@@ -0,0 +1,96 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	runtimeHelpers["subshell"] = runtimeHelper{
+		Source: `// subshell runs commands as in a subshell: the working directory and the
+// environment are restored when they finish, and exit 0 ends them
+// successfully
+func subshell(commands func() error) error {
+	dir, dirErr := os.Getwd()
+	env := os.Environ()
+	defer func() {
+		if dirErr == nil {
+			os.Chdir(dir)
+		}
+		os.Clearenv()
+		for _, entry := range env {
+			name, value, _ := strings.Cut(entry, "=")
+			os.Setenv(name, value)
+		}
+	}()
+
+	err := commands()
+	if exit, ok := err.(exitError); ok && exit == 0 {
+		return nil
+	}
+	return err
+}`,
+		Imports:  []string{"os", "strings"},
+		Requires: []string{"exitError"},
+	}
+}
+
+// generateSubshell generates Go code for a subshell. Its statements run in
+// a closure that restores the script variables they assign when it returns,
+// while the subshell helper restores the working directory and the
+// environment, so that like in Bash nothing the subshell changes leaks into
+// the script.
+func (g *GoCodeGenerator) generateSubshell(subshell parser.Subshell) (string, error) {
+	g.subshells++
+	body, err := g.generateStatements(subshell.Statements)
+	g.subshells--
+	if err != nil {
+		return "", err
+	}
+	g.requireHelper("subshell")
+
+	var lines []string
+	for _, name := range subshell.Vars {
+		if restore := g.restoreVar(name); restore != "" {
+			lines = append(lines, restore)
+		}
+	}
+	lines = withReturn(append(lines, body))
+	call := fmt.Sprintf("subshell(func() error {\n%s\n})", strings.Join(lines, "\n"))
+
+	// When the script inspects $?, the commands of the subshell record their
+	// exit status, which the subshell replaces with its own
+	return "// Execute subshell\n" + g.checkStatus(call, "return err"), nil
+}
+
+// restoreVar returns a deferred statement restoring the value the script
+// variable name has when it runs, or "" if name cannot change.
+func (g *GoCodeGenerator) restoreVar(name string) string {
+	switch {
+	case g.isConstant(name), !isValidVarName(name):
+		return ""
+	case g.isTableVar(name):
+		return "defer " + g.setShellVarCode(name, g.shellVarRef(name))
+	case g.isAssocArray(name):
+		g.RequiredImports["maps"] = true
+		return fmt.Sprintf("defer func(saved map[string]string) { %s = saved }(maps.Clone(%s))", name, name)
+	case g.isIntVar(name):
+		return fmt.Sprintf("defer func(saved int) { %s = saved }(%s)", name, name)
+	default:
+		return fmt.Sprintf("defer func(saved string) { %s = saved }(%s)", name, name)
+	}
+}
+
+// subshellExitCode returns the code ending the subshell being generated
+// with the given exit code, the way exit does in a subshell.
+func (g *GoCodeGenerator) subshellExitCode(code string) string {
+	g.requireHelper("exitError")
+	if !g.usesShellVar("?") {
+		return fmt.Sprintf("return exitError(%s)", code)
+	}
+	// A subshell ending successfully leaves $? as its last command set it
+	g.RequiredImports["strconv"] = true
+	return fmt.Sprintf("%s\nreturn exitError(%s)", g.setShellVarCode("?", fmt.Sprintf("strconv.Itoa(%s)", code)), code)
+}
@@ -0,0 +1,139 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	utilities["tail"] = (*GoCodeGenerator).generateTail
+
+	runtimeHelpers["tail"] = runtimeHelper{
+		Source: `// tail prints the last lines of files, or of stdin without files, like
+// tail: as many as the lines count, as in 10, or those from a line on for
+// counts such as +10. With follow, it then prints the data appended to the
+// files, checking them every second, and never returns. It fails with
+// status 1 if the count is invalid or a file cannot be read.
+func tail(stdio *streams, stdin io.Reader, lines string, follow bool, files ...string) error {
+	n, sign, ok := lineCount(stdio, "tail", lines)
+	if !ok {
+		return exitError(1)
+	}
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	out := bufio.NewWriter(stdio.Stdout)
+	defer out.Flush()
+	failed, first := false, true
+	var followed []*os.File
+	var names []string
+	for _, name := range files {
+		input, ok := openForReading(stdio, "tail", name, stdin)
+		if !ok {
+			failed = true
+			continue
+		}
+		if len(files) > 1 {
+			fileHeader(out, name, first)
+			first = false
+		}
+		var kept []string
+		r := bufio.NewReader(input)
+		for count := 1; ; count++ {
+			line, err := r.ReadString('\n')
+			if sign == '+' {
+				if count >= n {
+					out.WriteString(line)
+				}
+			} else if line != "" && n > 0 {
+				if kept = append(kept, line); len(kept) > n {
+					kept = kept[1:]
+				}
+			}
+			if err != nil {
+				if err != io.EOF {
+					utilityError(stdio, "tail", "error reading '"+name+"'", err)
+					failed = true
+				}
+				break
+			}
+		}
+		for _, line := range kept {
+			out.WriteString(line)
+		}
+		if f, ok := input.(*os.File); ok && follow {
+			followed = append(followed, f)
+			names = append(names, name)
+			continue
+		}
+		input.Close()
+	}
+	if follow && len(followed) > 0 {
+		last := len(followed) - 1
+		for {
+			out.Flush()
+			time.Sleep(time.Second)
+			for i, f := range followed {
+				info, err := f.Stat()
+				if err != nil {
+					continue
+				}
+				if offset, err := f.Seek(0, io.SeekCurrent); err == nil && info.Size() < offset {
+					fmt.Fprintf(stdio.Stderr, "tail: %s: file truncated\n", names[i])
+					f.Seek(0, io.SeekStart)
+				}
+				data, _ := io.ReadAll(f)
+				if len(data) == 0 {
+					continue
+				}
+				if len(followed) > 1 && i != last {
+					fileHeader(out, names[i], false)
+					last = i
+				}
+				out.Write(data)
+			}
+		}
+	}
+	if follow && failed {
+		fmt.Fprintln(stdio.Stderr, "tail: no files remaining")
+	}
+	if failed {
+		return exitError(1)
+	}
+	return nil
+}`,
+		Imports:  []string{"bufio", "fmt", "io", "os", "time"},
+		Requires: []string{"exitError", "fileHeader", "lineCount", "openForReading", "utilityError"},
+	}
+}
+
+// generateTail translates tail with -n or a number of lines, as in tail -5
+// and tail -n +2, into a call of the tail helper. tail -f is translated with
+// Options.PollTail only, since its Go code polls the files. It reports false
+// for other options and forms, so that tail is executed.
+func (g *GoCodeGenerator) generateTail(cmd parser.Command) (string, bool) {
+	lines, options, operands, ok := g.lineCountArgs(cmd, "f")
+	if !ok {
+		return "", false
+	}
+	if len(operands) > 0 {
+		// tail +5 prints the lines from the fifth on, as an obsolete form
+		if first, ok := g.literalArg(cmd, operands[0]); ok && strings.HasPrefix(first, "+") {
+			return "", false
+		}
+	}
+	if first, ok := g.literalArg(cmd, 0); ok && len(operands) > 1 && lineCountNumber.MatchString(first) {
+		// tail -5 takes a single file
+		return "", false
+	}
+	follow := len(options) > 0
+	if follow && !g.Options.PollTail {
+		return "", false
+	}
+
+	g.requireHelper("tail")
+	call := fmt.Sprintf("tail(%s, %s, %s, %t%s)", g.stdio(), g.utilityInput(cmd), lines, follow, g.operandArgs(cmd, operands))
+	return g.checkErr(cmd, call), true
+}
@@ -0,0 +1,270 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/TFMV/bash2go/diagnostics"
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	runtimeHelpers["fileTest"] = runtimeHelper{
+		Source: `// fileTest reports whether a file test of [[ ]], such as -f or -d, holds
+// for the named file. Like in Bash, symbolic links are followed except by
+// -L. Permissions are checked against the permission bits of the file.
+func fileTest(op, name string) bool {
+	stat := os.Stat
+	if op == "-L" {
+		stat = os.Lstat
+	}
+	info, err := stat(name)
+	if err != nil {
+		return false
+	}
+	mode := info.Mode()
+	switch op {
+	case "-f":
+		return mode.IsRegular()
+	case "-d":
+		return mode.IsDir()
+	case "-s":
+		return info.Size() > 0
+	case "-L":
+		return mode&os.ModeSymlink != 0
+	case "-p":
+		return mode&os.ModeNamedPipe != 0
+	case "-S":
+		return mode&os.ModeSocket != 0
+	case "-b":
+		return mode&os.ModeDevice != 0 && mode&os.ModeCharDevice == 0
+	case "-c":
+		return mode&os.ModeCharDevice != 0
+	case "-r":
+		return mode.Perm()&0444 != 0
+	case "-w":
+		return mode.Perm()&0222 != 0
+	case "-x":
+		return mode.Perm()&0111 != 0
+	}
+	return true
+}`,
+		Imports: []string{"os"},
+	}
+	runtimeHelpers["regexMatch"] = runtimeHelper{
+		Source: `// regexMatch reports whether s matches the regular expression re, as
+// [[ s =~ re ]] does, honouring the nocasematch option, and records the
+// matched text and that of the subexpressions for BASH_REMATCH. Invalid
+// regular expressions match nothing.
+func regexMatch(re, s string) bool {
+	if shellOptions["nocasematch"] {
+		re = "(?i)" + re
+	}
+	compiled, err := regexp.Compile(re)
+	if err != nil {
+		rematch = nil
+		return false
+	}
+	rematch = compiled.FindStringSubmatch(s)
+	return rematch != nil
+}`,
+		Imports:  []string{"regexp"},
+		Requires: []string{"rematch", "shellOptions"},
+	}
+}
+
+// fileTestOps lists the file tests of [[ ]] translated with fileTest
+var fileTestOps = map[string]bool{
+	"-e": true, "-a": true, "-f": true, "-d": true, "-s": true, "-L": true, "-p": true,
+	"-S": true, "-b": true, "-c": true, "-r": true, "-w": true, "-x": true,
+}
+
+// testComparisons maps the integer comparisons of [[ ]] to Go operators
+var testComparisons = map[string]string{
+	"-eq": "==", "-ne": "!=", "-lt": "<", "-le": "<=", "-gt": ">", "-ge": ">=",
+}
+
+// testStatement generates Go code for an extended test [[ ]] run as a
+// statement of its own, which only sets $? if the script reads it
+func (g *GoCodeGenerator) testStatement(t parser.TestExpr) string {
+	return g.condStatement(g.testCond(&t))
+}
+
+// condStatement generates Go code for a test run as a statement of its own,
+// given the Go boolean expression holding if it succeeds
+func (g *GoCodeGenerator) condStatement(cond string) string {
+	if !g.usesShellVar("?") {
+		return "_ = " + cond
+	}
+	g.requireHelper("boolInt")
+	g.RequiredImports["strconv"] = true
+	return g.setShellVarCode("?", fmt.Sprintf("strconv.Itoa(boolInt(!(%s)))", cond))
+}
+
+// testCommand generates Go code for the test and [ builtins run as a
+// statement of their own
+func (g *GoCodeGenerator) testCommand(cmd parser.Command) string {
+	cond, ok := g.bracketTest(cmd)
+	if !ok {
+		g.unsupported++
+		g.IR.Diagnose(diagnostics.SeverityError, cmd.Pos, diagnostics.CodeUnsupported,
+			"unsupported construct: %s %s", cmd.Name, strings.Join(cmd.Args, " "))
+		return fmt.Sprintf("// Unsupported test: %s", commentText(strings.Join(cmd.Args, " ")))
+	}
+	return g.condStatement(cond)
+}
+
+// bracketTest converts the test or [ builtin into a Go boolean expression
+// that holds if it succeeds, reporting false if its arguments are not
+// supported
+func (g *GoCodeGenerator) bracketTest(cmd parser.Command) (string, bool) {
+	args := cmd.Args
+	switch cmd.Name {
+	case "test":
+	case "[":
+		if len(args) == 0 || args[len(args)-1] != "]" {
+			return "", false
+		}
+		args = args[:len(args)-1]
+	default:
+		return "", false
+	}
+	return g.testArgsCond(args)
+}
+
+// testArgsCond converts the arguments of test into a Go boolean expression.
+// Unlike == in [[ ]], = compares strings rather than matching a pattern.
+// Like test, longer expressions are split at -o before -a.
+func (g *GoCodeGenerator) testArgsCond(args []string) (string, bool) {
+	switch len(args) {
+	case 0:
+		return "false", true
+	case 1:
+		return g.goArg(args[0]) + ` != ""`, true
+	case 2:
+		op := args[0]
+		if op == "!" {
+			return `!(` + g.goArg(args[1]) + ` != "")`, true
+		}
+		if op == "-z" || op == "-n" || (fileTestOps[op] && op != "-a") {
+			return g.testCond(&parser.TestExpr{Op: op, X: &parser.TestExpr{Word: args[1]}}), true
+		}
+		return "", false
+	case 3:
+		x, op, y := args[0], args[1], args[2]
+		switch op {
+		case "=", "==":
+			return fmt.Sprintf("%s == %s", g.goArg(x), g.goArg(y)), true
+		case "!=":
+			return fmt.Sprintf("%s != %s", g.goArg(x), g.goArg(y)), true
+		}
+		t := &parser.TestExpr{Op: op, X: &parser.TestExpr{Word: x}, Y: &parser.TestExpr{Word: y}}
+		if op == "<" || op == ">" || t.IsComparison() {
+			return g.testCond(t), true
+		}
+	}
+
+	for _, op := range []string{"-o", "-a"} {
+		for i := len(args) - 2; i > 0; i-- {
+			if args[i] != op {
+				continue
+			}
+			x, ok := g.testArgsCond(args[:i])
+			if !ok {
+				return "", false
+			}
+			y, ok := g.testArgsCond(args[i+1:])
+			if !ok {
+				return "", false
+			}
+			if op == "-o" {
+				return fmt.Sprintf("(%s) || (%s)", x, y), true
+			}
+			return fmt.Sprintf("(%s) && (%s)", x, y), true
+		}
+	}
+	if args[0] == "!" {
+		cond, ok := g.testArgsCond(args[1:])
+		return "!(" + cond + ")", ok
+	}
+	if args[0] == "(" && args[len(args)-1] == ")" {
+		return g.testArgsCond(args[1 : len(args)-1])
+	}
+	return "", false
+}
+
+// testCond converts the expression of an extended test [[ ]] into a Go
+// boolean expression
+func (g *GoCodeGenerator) testCond(t *parser.TestExpr) string {
+	switch {
+	case t.Op == "":
+		// A lone word tests that it is not empty
+		return g.goArg(t.Word) + ` != ""`
+	case t.Op == "!":
+		return "!" + g.testCondOperand(t.X)
+	case t.Op == "&&" || t.Op == "||":
+		return fmt.Sprintf("%s %s %s", g.testCondOperand(t.X), t.Op, g.testCondOperand(t.Y))
+	case t.Op == "-z":
+		return g.goArg(t.X.Word) + ` == ""`
+	case t.Op == "-n":
+		return g.goArg(t.X.Word) + ` != ""`
+	case fileTestOps[t.Op]:
+		g.requireHelper("fileTest")
+		op := t.Op
+		if op == "-a" {
+			op = "-e"
+		}
+		return fmt.Sprintf("fileTest(%s, %s)", strconv.Quote(op), g.goArg(t.X.Word))
+	case t.Op == "==" || t.Op == "!=":
+		return g.testMatch(t)
+	case t.Op == "=~":
+		g.requireHelper("regexMatch")
+		return fmt.Sprintf("regexMatch(%s, %s)", g.goArg(t.Y.Word), g.goArg(t.X.Word))
+	case t.Op == "<" || t.Op == ">":
+		return fmt.Sprintf("%s %s %s", g.goArg(t.X.Word), t.Op, g.goArg(t.Y.Word))
+	case t.IsComparison():
+		return fmt.Sprintf("%s %s %s", g.testArith(t.X.Word), testComparisons[t.Op], g.testArith(t.Y.Word))
+	}
+	g.unsupported++
+	g.IR.Diagnose(diagnostics.SeverityError, g.pos, diagnostics.CodeUnsupported,
+		"unsupported construct: %s test in [[ ]]", t.Op)
+	return "false"
+}
+
+// testCondOperand is testCond for the operand of a logical operator
+func (g *GoCodeGenerator) testCondOperand(t *parser.TestExpr) string {
+	if t.Op == "!" {
+		return g.testCond(t)
+	}
+	return "(" + g.testCond(t) + ")"
+}
+
+// testMatch converts [[ s == pattern ]] and [[ s != pattern ]] into a Go
+// boolean expression. Patterns without wildcards are compared as strings
+// unless the script may set nocasematch.
+func (g *GoCodeGenerator) testMatch(t *parser.TestExpr) string {
+	value := g.goArg(t.X.Word)
+	pattern := g.goArg(t.Y.Word)
+	if s, err := strconv.Unquote(pattern); err == nil && !g.mayUseShellOption("nocasematch") {
+		if literal, ok := literalPattern(s); ok {
+			return fmt.Sprintf("%s %s %s", value, t.Op, strconv.Quote(literal))
+		}
+	}
+	g.requireHelper("patternMatch")
+	match := fmt.Sprintf("patternMatch(%s, %s)", pattern, value)
+	if t.Op == "!=" {
+		return "!" + match
+	}
+	return match
+}
+
+// testArith converts an operand of an integer comparison of [[ ]], which
+// Bash evaluates as an arithmetic expression, into a Go int expression
+func (g *GoCodeGenerator) testArith(word string) string {
+	if a, err := parser.ParseArithmetic(word); err == nil {
+		return g.arithExpr(a)
+	}
+	g.requireHelper("arithValue")
+	return fmt.Sprintf("arithValue(%s)", g.goArg(word))
+}
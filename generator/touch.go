@@ -0,0 +1,71 @@
+package generator
+
+import (
+	"fmt"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	utilities["touch"] = (*GoCodeGenerator).generateTouch
+
+	runtimeHelpers["touch"] = runtimeHelper{
+		Source: `// touch sets the access and modification times of files to the current
+// time with os.Chtimes, like touch, creating the missing files unless
+// noCreate is set. It fails with status 1 if a file cannot be touched.
+func touch(stdio *streams, noCreate bool, files ...string) error {
+	if len(files) == 0 {
+		fmt.Fprintln(stdio.Stderr, "touch: missing file operand")
+		fmt.Fprintln(stdio.Stderr, "Try 'touch --help' for more information.")
+		return exitError(1)
+	}
+	failed := false
+	for _, name := range files {
+		now := time.Now()
+		err := os.Chtimes(name, now, now)
+		if errors.Is(err, fs.ErrNotExist) && noCreate {
+			continue
+		}
+		if errors.Is(err, fs.ErrNotExist) && !strings.HasSuffix(name, "/") {
+			var f *os.File
+			if f, err = os.OpenFile(name, os.O_WRONLY|os.O_CREATE, 0666); err == nil {
+				err = f.Close()
+			}
+			if err != nil {
+				fmt.Fprintf(stdio.Stderr, "touch: cannot touch '%s': %s\n", name, utilityMessage(err))
+				failed = true
+			}
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(stdio.Stderr, "touch: setting times of '%s': %s\n", name, utilityMessage(err))
+			failed = true
+		}
+	}
+	if failed {
+		return exitError(1)
+	}
+	return nil
+}`,
+		Imports:  []string{"errors", "fmt", "io/fs", "os", "strings", "time"},
+		Requires: []string{"exitError", "utilityError"},
+	}
+}
+
+// generateTouch translates touch, with -c or without options, into a call
+// of the touch helper. It reports false for other options, such as -d, and
+// for the operand -, naming the standard output, so that touch is executed.
+func (g *GoCodeGenerator) generateTouch(cmd parser.Command) (string, bool) {
+	options, operands, ok := g.utilityArgs(cmd, "c", "")
+	if !ok {
+		return "", false
+	}
+	for _, i := range operands {
+		if literal, ok := g.literalArg(cmd, i); ok && literal == "-" {
+			return "", false
+		}
+	}
+	g.requireHelper("touch")
+	call := fmt.Sprintf("touch(%s, %t%s)", g.stdio(), len(options) > 0, g.operandArgs(cmd, operands))
+	return g.checkErr(cmd, call), true
+}
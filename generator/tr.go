@@ -0,0 +1,236 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	utilities["tr"] = (*GoCodeGenerator).generateTr
+
+	runtimeHelpers["tr"] = runtimeHelper{
+		Source: `// trOptions are the sets and options of a tr command translated to Go, the
+// sets with their ranges and classes expanded
+type trOptions struct {
+	set1, set2      string
+	delete, squeeze bool
+}
+
+// tr copies stdin to the standard output like tr, byte by byte: it deletes
+// the bytes of set1 with delete, or else replaces them with those of set2
+// at the same position, the last one repeated when set2 is shorter. With
+// squeeze, repetitions of the bytes of the last set are then replaced with
+// a single one. It fails with status 1 if stdin cannot be read.
+func tr(stdio *streams, stdin io.Reader, opts trOptions) error {
+	var table [256]byte
+	for i := range table {
+		table[i] = byte(i)
+	}
+	var deleted, squeezed [256]bool
+	squeezeSet := opts.set1
+	switch {
+	case opts.delete:
+		for i := 0; i < len(opts.set1); i++ {
+			deleted[opts.set1[i]] = true
+		}
+		squeezeSet = opts.set2
+	case opts.set2 != "":
+		for i := 0; i < len(opts.set1); i++ {
+			table[opts.set1[i]] = opts.set2[min(i, len(opts.set2)-1)]
+		}
+		squeezeSet = opts.set2
+	}
+	if opts.squeeze {
+		for i := 0; i < len(squeezeSet); i++ {
+			squeezed[squeezeSet[i]] = true
+		}
+	}
+
+	out := bufio.NewWriter(stdio.Stdout)
+	defer out.Flush()
+	in := bufio.NewReader(stdin)
+	last := -1
+	for {
+		c, err := in.ReadByte()
+		if err != nil {
+			if err != io.EOF {
+				fmt.Fprintf(stdio.Stderr, "tr: read error: %s\n", utilityMessage(err))
+				return exitError(1)
+			}
+			return nil
+		}
+		if deleted[c] {
+			continue
+		}
+		c = table[c]
+		if squeezed[c] && int(c) == last {
+			continue
+		}
+		out.WriteByte(c)
+		last = int(c)
+	}
+}`,
+		Imports:  []string{"bufio", "fmt", "io"},
+		Requires: []string{"exitError", "utilityError"},
+	}
+}
+
+// trClasses are the character classes of tr sets in the C locale, as sets
+// of their bytes in order
+var trClasses = map[string]string{
+	"alnum":  "0-9A-Za-z",
+	"alpha":  "A-Za-z",
+	"blank":  `\t `,
+	"cntrl":  `\000-\037\177`,
+	"digit":  "0-9",
+	"graph":  "!-~",
+	"lower":  "a-z",
+	"print":  " -~",
+	"punct":  "!-/:-@[-`{-~",
+	"space":  `\t-\r `,
+	"upper":  "A-Z",
+	"xdigit": "0-9A-Fa-f",
+}
+
+// generateTr translates tr with literal sets, translating, deleting with -d
+// or squeezing with -s, into a call of the tr helper. It reports false for
+// other options, such as -c, for sets only known at runtime, for the sets
+// tr rejects and for the constructs [=c=] and [c*n], so that tr is executed.
+func (g *GoCodeGenerator) generateTr(cmd parser.Command) (string, bool) {
+	options, operands, ok := g.utilityArgs(cmd, "ds", "")
+	if !ok {
+		return "", false
+	}
+	del, squeeze := false, false
+	for _, option := range options {
+		if option.name == 'd' {
+			del = true
+		} else {
+			squeeze = true
+		}
+	}
+	sets := make([]string, len(operands))
+	for i, operand := range operands {
+		if sets[i], ok = g.literalArg(cmd, operand); !ok {
+			return "", false
+		}
+	}
+	switch {
+	case del && squeeze, !del && !squeeze:
+		ok = len(sets) == 2
+	case del:
+		ok = len(sets) == 1
+	default:
+		ok = len(sets) == 1 || len(sets) == 2
+	}
+	if !ok {
+		return "", false
+	}
+
+	set1, ok := trSet(sets[0])
+	if !ok {
+		return "", false
+	}
+	fields := []string{"set1: " + strconv.Quote(set1)}
+	if len(sets) == 2 {
+		set2, ok := trSet(sets[1])
+		if !ok || !del && (set2 == "" || !trCaseClasses(sets[0], sets[1])) {
+			return "", false
+		}
+		fields = append(fields, "set2: "+strconv.Quote(set2))
+	}
+	if del {
+		fields = append(fields, "delete: true")
+	}
+	if squeeze {
+		fields = append(fields, "squeeze: true")
+	}
+
+	g.requireHelper("tr")
+	call := fmt.Sprintf("tr(%s, %s, trOptions{%s})", g.stdio(), g.utilityInput(cmd), strings.Join(fields, ", "))
+	return g.checkErr(cmd, call), true
+}
+
+// trCaseClasses reports whether the second set of tr translating with set1
+// is valid regarding classes: only upper and lower may be used, to convert
+// the case of the opposite class making up set1
+func trCaseClasses(set1, set2 string) bool {
+	switch {
+	case !strings.Contains(set2, "[:"):
+		return true
+	case set2 == "[:upper:]":
+		return set1 == "[:lower:]"
+	case set2 == "[:lower:]":
+		return set1 == "[:upper:]"
+	}
+	return false
+}
+
+// trSet expands the escapes, ranges and classes of a set of tr into its
+// bytes. It reports false for the sets tr rejects and the constructs it
+// does not expand.
+func trSet(set string) (string, bool) {
+	// The bytes of the set, unescaped, with the escaped ones marked
+	var chars []byte
+	var escaped []bool
+	var b strings.Builder
+	for i := 0; i < len(set); i++ {
+		c := set[i]
+		switch {
+		case c == '[' && i+1 < len(set) && set[i+1] == ':':
+			end := strings.Index(set[i+2:], ":]")
+			if end < 0 {
+				return "", false
+			}
+			class, ok := trClasses[set[i+2:i+2+end]]
+			if !ok {
+				return "", false
+			}
+			members, _ := trSet(class)
+			for j := 0; j < len(members); j++ {
+				chars = append(chars, members[j])
+				escaped = append(escaped, true)
+			}
+			i += end + 3
+			continue
+		case c == '[' && i+2 < len(set) && (set[i+1] == '=' || set[i+2] == '*'):
+			return "", false
+		case c == '\\' && i+1 < len(set):
+			i++
+			c = set[i]
+			if n := strings.IndexByte("abfnrtv", c); n >= 0 {
+				c = "\a\b\f\n\r\t\v"[n]
+			} else if c >= '0' && c <= '7' {
+				j := i
+				for j < len(set) && j < i+3 && set[j] >= '0' && set[j] <= '7' {
+					j++
+				}
+				n, _ := strconv.ParseUint(set[i:j], 8, 8)
+				c, i = byte(n), j-1
+			}
+			chars = append(chars, c)
+			escaped = append(escaped, true)
+			continue
+		}
+		chars = append(chars, c)
+		escaped = append(escaped, false)
+	}
+
+	for i := 0; i < len(chars); i++ {
+		if i+2 < len(chars) && chars[i+1] == '-' && !escaped[i+1] {
+			if chars[i] > chars[i+2] {
+				return "", false
+			}
+			for c := int(chars[i]); c <= int(chars[i+2]); c++ {
+				b.WriteByte(byte(c))
+			}
+			i += 2
+			continue
+		}
+		b.WriteByte(chars[i])
+	}
+	return b.String(), true
+}
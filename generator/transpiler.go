@@ -2,19 +2,91 @@ package generator
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
+	"github.com/TFMV/bash2go/diagnostics"
 	"github.com/TFMV/bash2go/parser"
 )
 
 // Import the template.go file
 //go:generate go run -mod=mod github.com/TFMV/bash2go/generator/template.go
 
-// GoCodeGenerator generates Go code from an intermediate representation
+// GoCodeGenerator generates Go code from an intermediate representation.
+// Generate may be called concurrently; each call works on private state and
+// the exported fields and metrics reflect the most recently finished call.
 type GoCodeGenerator struct {
 	IR              *parser.IntermediateRepresentation
 	RequiredImports map[string]bool
 	Generator       *CodeGenerator
+	Options         Options
+
+	mu          sync.Mutex        // Guards the results of the last Generate call
+	pos         parser.Position   // Position of the statement being generated
+	metrics     Metrics           // Coverage counters for the current Generate call
+	unsupported int               // Statements the generator could not translate
+	helpers     map[string]bool   // Runtime helpers required by the generated code
+	sourceMap   []parser.Position // Bash position of each line of the generated code
+	plugins     map[string]string // Plugin path for each command name, "" if none
+
+	processSubsts int               // Process substitutions generated so far
+	readVars      map[string]bool   // Script variables read by the code generated so far
+	constants     map[string]string // Go constant value of each read-only variable that has one
+	inJob         bool              // Whether the code being generated runs as a background job
+	subshells     int               // Number of subshells the code being generated runs in
+	statusSet     bool              // Whether the code generated since it was cleared records $?
+	cmdRedirected bool              // Whether the exec.Cmd of the command being generated applies its redirections
+	cmdEnvApplied bool              // Whether the exec.Cmd of the command being generated applies its prefix assignments
+	pipeStage     bool              // Whether the command being generated is a stage of a pipeline
+	pipeExternal  bool              // Whether the pipeline stage generated last runs as an external command
+	funcIdents    map[string]string // Go identifier of each function of the script, once computed
+	tableVars     map[string]bool   // Script variables kept in the shellVars table, once computed
+}
+
+// Options configures code generation
+type Options struct {
+	// DefaultEnvPolicy controls when environment variable references are
+	// expanded; the zero value keeps them as os.Getenv calls at runtime.
+	DefaultEnvPolicy parser.EnvPolicy
+	// EnvPolicies overrides the policy for individual variables, taking
+	// precedence over bash2go:env directives in the script.
+	EnvPolicies map[string]parser.EnvPolicy
+	// RuntimeEnvOnly keeps every environment variable reference as an
+	// os.Getenv call, whatever the policies and directives, so that the
+	// environment of the converting process never ends up in the code.
+	RuntimeEnvOnly bool
+	// BuildConstraint, if set, is emitted as a //go:build line so the
+	// generated file only builds with matching tags.
+	BuildConstraint string
+	// BuildInfo adds a --bash2go-info flag to the program that prints the
+	// metadata set at build time with BuildInfo.LDFlags.
+	BuildInfo bool
+	// Schedule, if set, makes the program a long-running scheduler that runs
+	// the script on this cron schedule, such as "*/5 * * * *" or "@hourly".
+	Schedule string
+	// SignalCommands maps signal names such as "HUP" to the subcommand run
+	// when the signal arrives while another subcommand runs. It applies to
+	// programs combined from several scripts with parser.CombineCommands.
+	SignalCommands map[string]string
+	// Plugins translates external commands with bash2go-translate-<cmd>
+	// executables found on PATH; see PluginRequest.
+	Plugins bool
+	// Mappings declares translations and policies for external commands,
+	// taking precedence over plugins; see ParseMappings.
+	Mappings Mappings
+	// GoGit translates common git commands into go-git calls, so that the
+	// program does not need git installed. Mappings and plugins for git take
+	// precedence.
+	GoGit bool
+	// PollTail translates tail -f into Go code checking the followed files
+	// for appended data every second, instead of running tail.
+	PollTail bool
+	// ShellOptions lists shopt options set when the program starts, such as
+	// nullglob and failglob to choose what glob patterns without matches
+	// expand to.
+	ShellOptions []string
 }
 
 // TemplateData holds data for main template
@@ -48,58 +120,99 @@ func NewGoCodeGenerator(ir *parser.IntermediateRepresentation) *GoCodeGenerator
 		IR:              ir,
 		RequiredImports: make(map[string]bool),
 		Generator:       NewCodeGenerator("main"),
+		helpers:         make(map[string]bool),
+		readVars:        make(map[string]bool),
 	}
 }
 
 // Generate generates Go code from the intermediate representation
 func (g *GoCodeGenerator) Generate() (string, error) {
-	// Initialize the code generator
-	g.Generator = NewCodeGenerator("main")
-	g.RequiredImports = make(map[string]bool)
-
-	// Check if we need special imports
-	for _, stmt := range g.IR.MainStatements {
-		if stmt.Type == parser.StatementCommand {
-			cmd := stmt.Value.(parser.Command)
-			if cmd.UseGexe {
-				g.RequiredImports["github.com/vladimirvivien/gexe"] = true
-			} else if !cmd.IsBuiltin {
-				g.RequiredImports["os/exec"] = true
-			}
+	// Generate on a private copy so that concurrent calls share no state
+	run := NewGoCodeGenerator(g.IR)
+	run.Options = g.Options
+	code, err := run.generate()
+	code, sourceMap := stripLineMarkers(code)
+
+	g.mu.Lock()
+	g.sourceMap = sourceMap
+	g.RequiredImports = run.RequiredImports
+	g.Generator = run.Generator
+	g.metrics = run.metrics
+	g.mu.Unlock()
+
+	return code, err
+}
 
-			// Add fmt for echo commands
-			if cmd.Name == "echo" {
-				g.RequiredImports["fmt"] = true
-			}
-		} else if stmt.Type == parser.StatementPipe {
-			g.RequiredImports["github.com/vladimirvivien/gexe"] = true
-		}
+// generate performs the code generation for Generate
+func (g *GoCodeGenerator) generate() (string, error) {
+	if err := g.Generator.SetBuildConstraint(g.Options.BuildConstraint); err != nil {
+		return "", err
+	}
+	if err := validateSchedule(g.Options.Schedule); err != nil {
+		return "", err
+	}
+	if err := validateShellOptions(g.Options.ShellOptions); err != nil {
+		return "", err
 	}
 
-	// Add imports to the generator
-	for imp := range g.RequiredImports {
-		g.Generator.AddImport(imp)
+	g.addArgsGlobal()
+	g.findConstants()
+	g.addConstants()
+
+	// Add variables in a stable order. Like in Bash, the variables functions
+	// declare local also exist, unset, outside them.
+	declared := make(map[string]bool, len(g.IR.Variables))
+	for name := range g.IR.Variables {
+		declared[name] = true
+	}
+	for _, function := range g.IR.Functions {
+		for name := range function.LocalVars {
+			declared[name] = true
+		}
+	}
+	varNames := make([]string, 0, len(declared))
+	for name := range declared {
+		varNames = append(varNames, name)
+	}
+	sort.Strings(varNames)
+
+	for _, name := range varNames {
+		switch {
+		case g.isConstant(name), g.isTableVar(name), !isValidVarName(name):
+		case g.isAssocArray(name):
+			g.Generator.AddGlobal(fmt.Sprintf("var %s = map[string]string{}", name))
+		case g.isIntVar(name):
+			g.Generator.AddGlobal(fmt.Sprintf("var %s int", name))
+		case name == "IFS":
+			// Unquoted expansions are split at blanks until IFS is assigned
+			g.Generator.AddGlobal("var IFS = " + defaultIFS)
+		default:
+			g.Generator.AddGlobal(fmt.Sprintf("var %s string", name))
+		}
 	}
 
-	// Add variables
-	for name, value := range g.IR.Variables {
-		g.Generator.AddGlobal(fmt.Sprintf("var %s = %s", name, value))
+	// Add functions in a stable order
+	names := make([]string, 0, len(g.IR.Functions))
+	for name := range g.IR.Functions {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	// Add functions
-	for name, function := range g.IR.Functions {
-		funcBody, err := g.generateStatements(function.Statements)
+	for _, name := range names {
+		function := g.IR.Functions[name]
+		g.readVars = make(map[string]bool)
+		funcBody, err := g.generateStatementLines(function.Statements)
 		if err != nil {
 			return "", err
 		}
+		bodyLines := append(append(g.functionPrologue(name), g.localDecls(function)...), funcBody...)
 
-		// Split the function body into lines
-		bodyLines := strings.Split(funcBody, "\n")
-
-		// Create a new function
+		// Create a new function; failures are reported through the error result
 		fn := Function{
-			Name: name,
-			Body: bodyLines,
+			Name:       g.funcIdent(name),
+			Parameters: []Parameter{{Name: g.stdio(), Type: "*streams"}, {Name: "args", Type: "[]string"}},
+			ReturnType: "error",
+			Body:       withReturn(bodyLines),
 			Comments: []string{
 				fmt.Sprintf("Function %s from the original Bash script", name),
 			},
@@ -108,19 +221,34 @@ func (g *GoCodeGenerator) Generate() (string, error) {
 		g.Generator.AddFunction(fn)
 	}
 
-	// Create main function
-	mainBody, err := g.generateStatements(g.IR.MainStatements)
-	if err != nil {
-		return "", err
-	}
+	// Create the run function holding the top-level script statements.
+	// Programs combined from several scripts run them as subcommands instead.
+	if len(g.IR.Subcommands) == 0 {
+		mainBody, err := g.generateStatementLines(g.IR.MainStatements)
+		if err != nil {
+			return "", err
+		}
 
-	// Split the main body into lines
-	mainLines := strings.Split(mainBody, "\n")
+		// The EXIT trap is deferred last so that it runs before waiting for jobs
+		mainLines := append(g.scriptPrologue(), g.jobsPrologue()...)
+		mainLines = append(append(mainLines, g.exitTrapPrologue()...), mainBody...)
 
-	// Create the main function
+		runFn := Function{
+			Name:       "run",
+			ReturnType: "error",
+			Body:       withReturn(mainLines),
+			Comments: []string{
+				"run executes the statements of the original Bash script",
+			},
+		}
+
+		g.Generator.AddFunction(runFn)
+	}
+
+	// Create the main function, which runs the script once or on its schedule
 	mainFn := Function{
 		Name: "main",
-		Body: mainLines,
+		Body: append(append(g.buildInfoPrologue(), g.shellOptionsPrologue()...), g.mainBody()...),
 		Comments: []string{
 			"Main function generated from Bash script",
 		},
@@ -128,35 +256,125 @@ func (g *GoCodeGenerator) Generate() (string, error) {
 
 	g.Generator.AddFunction(mainFn)
 
+	// Add the runtime helpers used by the generated statements
+	g.addHelpers()
+
+	// Add imports now that every statement has registered what it needs
+	for imp := range g.RequiredImports {
+		g.Generator.AddImport(imp)
+	}
+
 	// Build the code
 	return g.Generator.Build()
 }
 
 // generateStatements generates Go code for a slice of statements
 func (g *GoCodeGenerator) generateStatements(statements []parser.Statement) (string, error) {
-	var result strings.Builder
+	lines, err := g.generateStatementLines(statements)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(lines, "\n"), nil
+}
 
+// generateStatementLines generates Go code for a slice of statements as the
+// lines of a function body. Function bodies are kept as lines, so building
+// them this way spares joining the code of a large script into one string
+// only to split it again.
+func (g *GoCodeGenerator) generateStatementLines(statements []parser.Statement) ([]string, error) {
+	lines := make([]string, 0, len(statements)+1)
+	status := "" // The last line if it records a constant $?
 	for _, stmt := range statements {
 		code, err := g.generateStatement(stmt)
 		if err != nil {
-			return "", err
+			return nil, err
+		}
+		if marker := lineMarkerFor(stmt.Pos); marker != "" {
+			lines = append(lines, strings.TrimSuffix(marker, "\n"))
+		}
+		for _, line := range strings.Split(code, "\n") {
+			// A command recording status 0 before return 0 records it once
+			if status != "" && line == status {
+				continue
+			}
+			status = ""
+			if isConstantStatus(line) {
+				status = line
+			}
+			lines = append(lines, line)
+		}
+		if endsWithReturn(code) {
+			// The statements after return, or exit in a subshell, never run
+			break
 		}
-		result.WriteString(code)
-		result.WriteString("\n")
 	}
+	return append(lines, ""), nil
+}
 
-	return result.String(), nil
+// isConstantStatus reports whether a line of Go code sets $? to a constant,
+// which setting again right after has no effect
+func isConstantStatus(line string) bool {
+	value, ok := strings.CutPrefix(line, `setShellVar("?", `)
+	if !ok {
+		return false
+	}
+	_, err := strconv.Unquote(strings.TrimSuffix(value, ")"))
+	return err == nil
 }
 
 // generateStatement generates Go code for a single statement
-func (g *GoCodeGenerator) generateStatement(stmt parser.Statement) (string, error) {
+func (g *GoCodeGenerator) generateStatement(stmt parser.Statement) (code string, err error) {
+	// Track the statement position for error messages, restoring the
+	// enclosing statement's position once nested generation is done
+	outer := g.pos
+	g.pos = stmt.Pos
+	defer func() { g.pos = outer }()
+
+	// Count simple statements that were neither executed externally nor
+	// left untranslated as native
+	if isSimpleStatement(stmt.Type) {
+		before := g.metrics.ExecFallbacks + g.unsupported
+		defer func() {
+			if g.metrics.ExecFallbacks+g.unsupported == before {
+				g.metrics.Native++
+			}
+		}()
+	}
+
+	// Remove the temporary files of the process substitutions of a simple
+	// statement once it has run
+	if isSimpleStatement(stmt.Type) && stmt.Type != parser.StatementReturn {
+		before := g.processSubsts
+		defer func() {
+			if err == nil && g.processSubsts > before {
+				code = releaseProcessSubstitutions(code)
+			}
+		}()
+	}
+
 	switch stmt.Type {
 	case parser.StatementCommand:
 		cmd := stmt.Value.(parser.Command)
-		return g.generateCommand(cmd)
+		g.statusSet = false
+		code, err := g.generateCommand(cmd)
+		if err != nil {
+			return "", err
+		}
+		code = g.recordStatus(cmd, code)
+		if last := g.lastArgCode(cmd.Name, cmd.Args); last != "" {
+			code += "\n" + last
+		}
+		return code, nil
 	case parser.StatementAssignment:
+		// An assignment has the status of its last command substitution, or
+		// succeeds
 		assignment := stmt.Value.(parser.Assignment)
-		return g.generateAssignment(assignment)
+		g.statusSet = false
+		code, err := g.generateAssignment(assignment)
+		if err != nil || !g.usesShellVar("?") || g.statusSet {
+			return code, err
+		}
+		return code + "\n" + g.setShellVarCode("?", `"0"`), nil
 	case parser.StatementIf:
 		ifStmt := stmt.Value.(parser.If)
 		return g.generateIf(ifStmt)
@@ -172,106 +390,131 @@ func (g *GoCodeGenerator) generateStatement(stmt parser.Statement) (string, erro
 	case parser.StatementRedirection:
 		redirection := stmt.Value.(parser.Redirection)
 		return g.generateRedirection(redirection)
+	case parser.StatementBlock:
+		return g.generateBlock(stmt.Value.(parser.Block))
+	case parser.StatementNegation:
+		return g.generateNegation(stmt.Value.(parser.Negation))
 	case parser.StatementFunction:
 		// Functions are handled separately in the Generate method
 		return "// Function declaration (handled separately)", nil
 	case parser.StatementBackground:
-		background := stmt.Value.(parser.Background)
-		// Generate the command code first
-		cmdCode, err := g.generateCommand(background.Command)
-		if err != nil {
-			return "", err
-		}
-		g.RequiredImports["sync"] = true
-		return fmt.Sprintf("go func() {\n\t%s\n}()", cmdCode), nil
+		return g.generateBackground(stmt.Value.(parser.Background))
 	case parser.StatementReturn:
 		returnStmt := stmt.Value.(parser.Return)
-		if returnStmt.Value != "" {
-			return fmt.Sprintf("return %s", returnStmt.Value), nil
+		code := returnStmt.Value
+		if code == "" {
+			code = strconv.Itoa(returnStmt.Code)
 		}
-		return fmt.Sprintf("return %d", returnStmt.Code), nil
+		return g.generateReturn(parser.Command{Name: "return", Args: []string{code}, Pos: stmt.Pos}), nil
+	case parser.StatementArithmetic:
+		return g.arithStatement(stmt.Value.(parser.Arithmetic)), nil
+	case parser.StatementTest:
+		return g.testStatement(stmt.Value.(parser.TestExpr)), nil
+	case parser.StatementGetopts:
+		return g.generateGetopts(stmt.Value.(parser.Getopts))
+	case parser.StatementAndOr:
+		return g.generateAndOr(stmt.Value.(parser.AndOr))
 	default:
-		return fmt.Sprintf("// Unsupported statement type: %v", stmt.Type), nil
+		g.unsupported++
+		g.IR.Diagnose(diagnostics.SeverityError, stmt.Pos, diagnostics.CodeUnsupported,
+			"unsupported statement type %v", stmt.Type)
+		return fmt.Sprintf("// Unsupported statement type %v at %s", stmt.Type, stmt.Pos), nil
 	}
 }
 
-// generateCommand generates Go code for a command
+// generateCommand generates Go code for a command. Redirections and prefix
+// assignments of external commands are applied to their exec.Cmd; other
+// commands run with the standard streams replaced and the variables set.
 func (g *GoCodeGenerator) generateCommand(cmd parser.Command) (string, error) {
+	if len(cmd.Env) == 0 {
+		return g.generateRedirectedCommand(cmd)
+	}
+	// Commands of substitutions in the arguments are generated in between
+	outer := g.cmdEnvApplied
+	g.cmdEnvApplied = false
+	code, err := g.generateRedirectedCommand(cmd)
+	applied := g.cmdEnvApplied || g.pipeExternal
+	g.cmdEnvApplied = outer
+	if err != nil || applied {
+		return code, err
+	}
+	return g.withPrefixEnv(cmd, code), nil
+}
+
+// generateRedirectedCommand generates Go code for a command with its
+// redirections
+func (g *GoCodeGenerator) generateRedirectedCommand(cmd parser.Command) (string, error) {
+	if len(cmd.Redirects) == 0 {
+		return g.translateCommand(cmd)
+	}
+	// Commands of substitutions in the arguments are generated in between
+	outer := g.cmdRedirected
+	g.cmdRedirected = false
+	code, err := g.translateCommand(cmd)
+	applied := g.cmdRedirected
+	g.cmdRedirected = outer
+	if err != nil || applied {
+		return code, err
+	}
+	return g.generateRedirected(code, cmd.Redirects), nil
+}
+
+// translateCommand generates Go code for a command, without its
+// redirections unless it runs as an external command
+func (g *GoCodeGenerator) translateCommand(cmd parser.Command) (string, error) {
+	// Commands of substitutions in the arguments of a pipeline stage are not
+	// stages themselves
+	stage := g.pipeStage
+	g.pipeStage = false
+
+	// Functions of the script take precedence over commands
+	if _, ok := g.IR.Functions[cmd.Name]; ok {
+		return g.generateFunctionCall(cmd), nil
+	}
+
 	// Handle built-in commands with Go equivalents
 	switch cmd.Name {
 	case "echo":
-		// Use fmt.Println instead of exec.Command
+		// Use fmt.Fprintln instead of exec.Command
 		g.RequiredImports["fmt"] = true
+		stdout := g.stdio() + ".Stdout"
 		if len(cmd.Args) == 0 {
-			return "fmt.Println()", nil
+			return fmt.Sprintf("fmt.Fprintln(%s)", stdout), nil
+		}
+
+		// Glob patterns, split expansions and "$@" expand to any number of
+		// arguments, joined by spaces
+		if len(cmd.Globs) > 0 || len(cmd.Splits) > 0 || spreadsArgs(cmd.Args) {
+			g.RequiredImports["strings"] = true
+			return fmt.Sprintf(`fmt.Fprintln(%s, strings.Join(%s, " "))`, stdout, g.globArgs(cmd.Args, cmd.Globs, cmd.Splits)), nil
 		}
 
-		// Handle quoted arguments
-		var args []string
+		// Convert each argument, expanding variable references
+		args := []string{stdout}
 		for _, arg := range cmd.Args {
-			// If the argument is already quoted, use it as is
-			if strings.HasPrefix(arg, "\"") && strings.HasSuffix(arg, "\"") {
-				// Check for variable substitution inside the string
-				if strings.Contains(arg, "$") {
-					// Replace $VAR with " + VAR + "
-					processedArg := arg
-					// Remove the outer quotes
-					processedArg = processedArg[1 : len(processedArg)-1]
-					// Replace ${VAR} with " + VAR + "
-					processedArg = strings.ReplaceAll(processedArg, "${", "\" + ")
-					processedArg = strings.ReplaceAll(processedArg, "}", " + \"")
-					// Replace $VAR with " + VAR + "
-					for _, varName := range extractVariableNames(processedArg) {
-						processedArg = strings.ReplaceAll(processedArg, "$"+varName, "\" + "+varName+" + \"")
-					}
-					// Add the outer quotes back
-					args = append(args, "\""+processedArg+"\"")
-				} else {
-					args = append(args, arg)
-				}
-			} else if strings.HasPrefix(arg, "$") {
-				// This is a variable reference
-				varName := strings.TrimPrefix(arg, "$")
-				// Handle ${VAR} format
-				if strings.HasPrefix(varName, "{") && strings.HasSuffix(varName, "}") {
-					varName = varName[1 : len(varName)-1]
-				}
-				args = append(args, varName)
-			} else {
-				// Otherwise, quote it
-				args = append(args, fmt.Sprintf("\"%s\"", arg))
-			}
+			args = append(args, g.goArg(arg))
 		}
 
-		return fmt.Sprintf("fmt.Println(%s)", strings.Join(args, ", ")), nil
+		return fmt.Sprintf("fmt.Fprintln(%s)", strings.Join(args, ", ")), nil
 	case "cd":
 		// Use os.Chdir instead of exec.Command
 		g.RequiredImports["os"] = true
 		if len(cmd.Args) == 0 {
-			return "err = os.Chdir(os.Getenv(\"HOME\"))", nil
+			return g.checkErr(cmd, "os.Chdir(os.Getenv(\"HOME\"))"), nil
 		}
 
-		// Handle the argument
-		arg := cmd.Args[0]
-		if strings.HasPrefix(arg, "$") {
-			// This is a variable reference
-			varName := strings.TrimPrefix(arg, "$")
-			// Handle ${VAR} format
-			if strings.HasPrefix(varName, "{") && strings.HasSuffix(varName, "}") {
-				varName = varName[1 : len(varName)-1]
-			}
-			return fmt.Sprintf("err = os.Chdir(%s)", varName), nil
-		}
-
-		return fmt.Sprintf("err = os.Chdir(\"%s\")", arg), nil
+		return g.checkErr(cmd, fmt.Sprintf("os.Chdir(%s)", g.goArg(cmd.Args[0]))), nil
 	case "pwd":
 		// Use os.Getwd instead of exec.Command
 		g.RequiredImports["os"] = true
-		return `dir, err := os.Getwd()
-	if err != nil {
-		return err
-	}
-	fmt.Println(dir)`, nil
+		g.RequiredImports["fmt"] = true
+		return fmt.Sprintf(`{
+		dir, err := os.Getwd()
+		if err != nil {
+			%s
+		}
+		fmt.Fprintln(%s.Stdout, dir)
+	}`, g.errReturn(cmd), g.stdio()), nil
 	case "mkdir":
 		// Use os.MkdirAll instead of exec.Command
 		g.RequiredImports["os"] = true
@@ -279,15 +522,15 @@ func (g *GoCodeGenerator) generateCommand(cmd parser.Command) (string, error) {
 			return "// Warning: mkdir command with no arguments", nil
 		}
 
-		// Handle the argument
-		arg := cmd.Args[0]
-		if strings.HasPrefix(arg, "$") {
-			// This is a variable reference
-			varName := strings.TrimPrefix(arg, "$")
-			return fmt.Sprintf("err = os.MkdirAll(%s, 0755)", varName), nil
+		// Create each directory, skipping flags such as -p
+		var calls []string
+		for _, arg := range cmd.Args {
+			if strings.HasPrefix(arg, "-") {
+				continue
+			}
+			calls = append(calls, g.checkErr(cmd, fmt.Sprintf("os.MkdirAll(%s, 0755)", g.goArg(arg))))
 		}
-
-		return fmt.Sprintf("err = os.MkdirAll(\"%s\", 0755)", arg), nil
+		return strings.Join(calls, "\n"), nil
 	case "rm":
 		// Use os.Remove or os.RemoveAll instead of exec.Command
 		g.RequiredImports["os"] = true
@@ -296,203 +539,223 @@ func (g *GoCodeGenerator) generateCommand(cmd parser.Command) (string, error) {
 		}
 
 		// Check for -r or -rf flag
-		isRecursive := false
-		var target string
-
+		remove := "os.Remove"
 		for _, arg := range cmd.Args {
 			if arg == "-r" || arg == "-rf" || arg == "-fr" {
-				isRecursive = true
-			} else if !strings.HasPrefix(arg, "-") {
-				target = arg
+				remove = "os.RemoveAll"
 			}
 		}
 
-		// Handle variable reference
-		if strings.HasPrefix(target, "$") {
-			varName := strings.TrimPrefix(target, "$")
-			if isRecursive {
-				return fmt.Sprintf("err = os.RemoveAll(%s)", varName), nil
-			}
-			return fmt.Sprintf("err = os.Remove(%s)", varName), nil
+		// Remove each target, and each file a glob pattern matches
+		isGlob := make(map[int]bool, len(cmd.Globs))
+		for _, i := range cmd.Globs {
+			isGlob[i] = true
 		}
-
-		if isRecursive {
-			return fmt.Sprintf("err = os.RemoveAll(\"%s\")", target), nil
+		var calls []string
+		for i, arg := range cmd.Args {
+			if strings.HasPrefix(arg, "-") {
+				continue
+			}
+			if !isGlob[i] {
+				calls = append(calls, g.checkErr(cmd, fmt.Sprintf("%s(%s)", remove, g.goArg(arg))))
+				continue
+			}
+			g.requireHelper("globExpand")
+			calls = append(calls, fmt.Sprintf(`for _, name := range globExpand(%s) {
+		%s
+	}`, g.goArg(arg), g.checkErr(cmd, remove+"(name)")))
 		}
-		return fmt.Sprintf("err = os.Remove(\"%s\")", target), nil
+		return strings.Join(calls, "\n"), nil
 	case "cp":
-		// Use io/ioutil or os for file copying
-		g.RequiredImports["io/ioutil"] = true
+		// Use os.ReadFile and os.WriteFile for file copying
 		g.RequiredImports["os"] = true
 		if len(cmd.Args) < 2 {
 			return "// Warning: cp command with insufficient arguments", nil
 		}
 
-		src := cmd.Args[0]
-		dst := cmd.Args[1]
-
-		// Handle variable references
-		if strings.HasPrefix(src, "$") {
-			src = strings.TrimPrefix(src, "$")
-		} else {
-			src = fmt.Sprintf("\"%s\"", src)
-		}
+		src := g.goArg(cmd.Args[0])
+		dst := g.goArg(cmd.Args[1])
 
-		if strings.HasPrefix(dst, "$") {
-			dst = strings.TrimPrefix(dst, "$")
-		} else {
-			dst = fmt.Sprintf("\"%s\"", dst)
+		return fmt.Sprintf(`{
+		data, err := os.ReadFile(%s)
+		if err != nil {
+			%s
 		}
-
-		return fmt.Sprintf(`data, err := ioutil.ReadFile(%s)
-	if err != nil {
-		return err
-	}
-	err = ioutil.WriteFile(%s, data, 0644)`, src, dst), nil
+		%s
+	}`, src, g.errReturn(cmd), g.checkErr(cmd, fmt.Sprintf("os.WriteFile(%s, data, 0644)", dst))), nil
 	case "test", "[":
-		// Use os.Stat and other Go functions for test conditions
-		g.RequiredImports["os"] = true
-
-		if len(cmd.Args) < 2 {
-			return "// Warning: test command with insufficient arguments", nil
-		}
-
-		// Handle different test conditions
-		switch cmd.Args[0] {
-		case "-f":
-			// Test if file exists
-			arg := cmd.Args[1]
-			if strings.HasPrefix(arg, "$") {
-				// This is a variable reference
-				varName := strings.TrimPrefix(arg, "$")
-				return fmt.Sprintf("_, err := os.Stat(%s); err == nil", varName), nil
-			}
-			return fmt.Sprintf("_, err := os.Stat(\"%s\"); err == nil", arg), nil
-		case "-d":
-			// Test if directory exists
-			arg := cmd.Args[1]
-			if strings.HasPrefix(arg, "$") {
-				// This is a variable reference
-				varName := strings.TrimPrefix(arg, "$")
-				return fmt.Sprintf(`info, err := os.Stat(%s)
-	if err == nil && info.IsDir()`, varName), nil
-			}
-			return fmt.Sprintf(`info, err := os.Stat("%s")
-	if err == nil && info.IsDir()`, arg), nil
-		case "-z":
-			// Test if string is empty
-			arg := cmd.Args[1]
-			if strings.HasPrefix(arg, "$") {
-				// This is a variable reference
-				varName := strings.TrimPrefix(arg, "$")
-				return fmt.Sprintf("len(%s) == 0", varName), nil
-			}
-			return fmt.Sprintf("len(\"%s\") == 0", arg), nil
-		case "-n":
-			// Test if string is not empty
-			arg := cmd.Args[1]
-			if strings.HasPrefix(arg, "$") {
-				// This is a variable reference
-				varName := strings.TrimPrefix(arg, "$")
-				return fmt.Sprintf("len(%s) > 0", varName), nil
-			}
-			return fmt.Sprintf("len(\"%s\") > 0", arg), nil
-		default:
-			// Use gexe for other test conditions
-			g.RequiredImports["github.com/vladimirvivien/gexe"] = true
-			return fmt.Sprintf("exe.Run(\"test %s\").Success()", strings.Join(cmd.Args, " ")), nil
-		}
+		return g.testCommand(cmd), nil
+	case "return":
+		return g.generateReturn(cmd), nil
+	case "source", ".":
+		// Scripts that can be included are merged into the IR by the
+		// parser, which reports why the others cannot
+		g.unsupported++
+		return fmt.Sprintf("// Unsupported %s: %s", cmd.Name, commentText(strings.Join(cmd.Args, " "))), nil
+	case "set":
+		return g.generateSet(cmd), nil
+	case "wait":
+		return g.generateWait(cmd), nil
+	case "read":
+		return g.generateRead(cmd), nil
+	case "shopt":
+		return g.generateShopt(cmd), nil
+	case "trap":
+		return g.generateTrap(cmd), nil
+	case "shift":
+		return g.generateShift(cmd), nil
+	case "unset":
+		return g.generateUnset(cmd), nil
+	case "getopts":
+		g.unsupported++
+		g.IR.Diagnose(diagnostics.SeverityError, cmd.Pos, diagnostics.CodeUnsupported,
+			"getopts is only translated in a while loop over a case statement on the option variable")
+		return "// Unsupported getopts outside a while getopts loop", nil
 	case "exit":
 		// Use os.Exit
 		g.RequiredImports["os"] = true
 		if len(cmd.Args) == 0 {
-			return "os.Exit(0)", nil
+			return g.exitCode("0"), nil
 		}
 
-		// Handle the exit code
+		// Handle the exit code; dynamic codes are converted at runtime
 		code := cmd.Args[0]
-		if strings.HasPrefix(code, "$") {
-			// This is a variable reference
-			varName := strings.TrimPrefix(code, "$")
-			return fmt.Sprintf("os.Exit(%s)", varName), nil
+		if _, err := strconv.Atoi(code); err == nil {
+			return g.exitCode(code), nil
 		}
 
-		return fmt.Sprintf("os.Exit(%s)", code), nil
+		g.requireHelper("exitArg")
+		return g.exitCode(fmt.Sprintf("exitArg(%s)", g.goArg(code))), nil
+	case "exec":
+		return g.generateExec(cmd), nil
+	case "printf":
+		if code, ok := g.generatePrintf(cmd); ok {
+			return code, nil
+		}
+		fallthrough
 	default:
-		// For external commands, use gexe
-		if cmd.UseGexe {
-			g.RequiredImports["github.com/vladimirvivien/gexe"] = true
-
-			// Build the command string
-			var cmdStr strings.Builder
-			cmdStr.WriteString(cmd.Name)
-
-			for _, arg := range cmd.Args {
-				cmdStr.WriteString(" ")
-
-				// If the argument contains spaces, quote it
-				if strings.Contains(arg, " ") && !strings.HasPrefix(arg, "\"") {
-					cmdStr.WriteString("\"")
-					cmdStr.WriteString(arg)
-					cmdStr.WriteString("\"")
-				} else {
-					cmdStr.WriteString(arg)
-				}
-			}
+		if code, ok, err := g.mappedCommand(cmd); ok || err != nil {
+			return code, err
+		}
+		if code, ok := g.pluginCommand(cmd); ok {
+			return code, nil
+		}
+		if code, ok := g.goGitCommand(cmd); ok {
+			return code, nil
+		}
+		if code, ok := g.utilityCommand(cmd); ok {
+			return code, nil
+		}
 
-			return fmt.Sprintf(`// Execute command: %s
-	output := exe.Run("%s").Stdout()
-	fmt.Print(output)`, cmdStr.String(), cmdStr.String()), nil
+		// For external commands, use exec.Command
+		g.metrics.ExecFallbacks++
+		if cmd.Name != "" {
+			g.IR.Diagnose(diagnostics.SeverityInfo, cmd.Pos, diagnostics.CodeExecFallback,
+				"%s has no native translation; executing it as an external command", cmd.Name)
+		}
+		if stage {
+			// The pipeline runs the command with its exec.Cmd
+			g.pipeExternal = true
+			return "", nil
 		}
 
-		// For other commands, use exec.Command as a fallback
 		g.RequiredImports["os/exec"] = true
 		g.RequiredImports["fmt"] = true
 
 		// Build the command arguments
-		var args []string
-		for _, arg := range cmd.Args {
-			if strings.HasPrefix(arg, "$") {
-				// This is a variable reference
-				varName := strings.TrimPrefix(arg, "$")
-				args = append(args, varName)
-			} else {
-				args = append(args, fmt.Sprintf("\"%s\"", arg))
+		argsStr := ""
+		if len(cmd.Globs) > 0 || len(cmd.Splits) > 0 || spreadsArgs(cmd.Args) {
+			argsStr = ", " + g.globArgs(cmd.Args, cmd.Globs, cmd.Splits) + "..."
+		} else if len(cmd.Args) > 0 {
+			var args []string
+			for _, arg := range cmd.Args {
+				args = append(args, g.goArg(arg))
 			}
+			argsStr = ", " + strings.Join(args, ", ")
 		}
 
-		argsStr := ""
-		if len(args) > 0 {
-			argsStr = ", " + strings.Join(args, ", ")
+		// A here-string or here-document is fed to the command's standard
+		// input
+		stdin := ""
+		if input := g.stdinInput(cmd); input != "" {
+			g.RequiredImports["strings"] = true
+			stdin = fmt.Sprintf("\n\tcmd.Stdin = strings.NewReader(%s)", input)
 		}
 
-		return fmt.Sprintf(`cmd := exec.Command("%s"%s)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to execute command: %%v", err)
-	}
-	fmt.Print(string(output))`, cmd.Name, argsStr), nil
+		// Redirections replace the streams of the command
+		if len(cmd.Redirects) > 0 {
+			g.cmdRedirected = true
+			return g.execRedirected(cmd, argsStr, stdin), nil
+		}
+
+		// The command shares the standard streams of the shell, which are
+		// those of a pipeline stage or command substitution it runs in
+		streams := fmt.Sprintf("cmd.Stdin, cmd.Stdout, cmd.Stderr = %[1]s.Stdin, %[1]s.Stdout, %[1]s.Stderr", g.stdio()) + stdin + g.execEnv(cmd)
+		comment := commentText(strings.Join(append([]string{cmd.Name}, cmd.Args...), " "))
+
+		return fmt.Sprintf(`// Execute command: %s
+	{
+		cmd := exec.Command(%s%s)
+		%s
+		%s
+	}`, comment, g.goArg(cmd.Name), argsStr, streams, g.checkStatus("cmd.Run()", g.errReturn(cmd))), nil
 	}
 }
 
 // generateAssignment generates Go code for a variable assignment
 func (g *GoCodeGenerator) generateAssignment(assign parser.Assignment) (string, error) {
-	// Handle local variables
-	if assign.IsLocal {
-		return fmt.Sprintf("var %s = %s", assign.Name, assign.Value), nil
+	if !isValidVarName(assign.Name) {
+		g.unsupported++
+		g.IR.Diagnose(diagnostics.SeverityError, g.pos, diagnostics.CodeUnsupported,
+			"%q is not a valid variable name", assign.Name)
+		return fmt.Sprintf("// Unsupported assignment to invalid variable %s", commentText(assign.Name)), nil
+	}
+	if g.isAssocArray(assign.Name) && (assign.IsAssoc || assign.Key != "") {
+		return g.generateArrayAssignment(assign)
+	}
+	if assign.Key != "" || len(assign.Elements) > 0 {
+		g.unsupported++
+		g.IR.Diagnose(diagnostics.SeverityError, g.pos, diagnostics.CodeUnsupported,
+			"indexed array %s is not supported; only associative arrays declared with declare -A are", assign.Name)
+		return fmt.Sprintf("// Unsupported indexed array assignment to %s", assign.Name), nil
 	}
 
+	if g.isConstant(assign.Name) && !assign.IsLocal {
+		return fmt.Sprintf("// %s is declared as a constant", assign.Name), nil
+	}
+	if g.isIntVar(assign.Name) && !assign.IsExport {
+		// Like in Bash, += adds to an integer variable
+		return g.intAssignment(assign.Name, assign.Value, assign.Append), nil
+	}
+
+	value := g.goArg(assign.Value)
+
 	// Handle export variables
 	if assign.IsExport {
 		g.RequiredImports["os"] = true
-		return fmt.Sprintf("os.Setenv(\"%s\", %s)", assign.Name, assign.Value), nil
+		if assign.Append {
+			value = fmt.Sprintf("os.Getenv(%q)+%s", assign.Name, value)
+		}
+		return fmt.Sprintf("os.Setenv(\"%s\", %s)", assign.Name, value), nil
 	}
 
-	// Handle regular variables
-	return fmt.Sprintf("%s := %s", assign.Name, assign.Value), nil
+	if g.isTableVar(assign.Name) {
+		if assign.Append {
+			value = g.shellVarRef(assign.Name) + " + " + value
+		}
+		return g.setShellVarCode(assign.Name, value), nil
+	}
+
+	// Handle regular variables, which are declared at package level. +=
+	// appends to their value.
+	if assign.Append {
+		return fmt.Sprintf("%s += %s", assign.Name, value), nil
+	}
+	return fmt.Sprintf("%s = %s", assign.Name, value), nil
 }
 
-// generateIf generates Go code for an if statement
+// generateIf generates Go code for an if statement, with its elif clauses
+// as an else if chain
 func (g *GoCodeGenerator) generateIf(ifStmt parser.If) (string, error) {
 	// Generate condition
 	condition, err := g.generateCondition(ifStmt.Condition, ifStmt.ConditionType)
@@ -506,21 +769,31 @@ func (g *GoCodeGenerator) generateIf(ifStmt parser.If) (string, error) {
 		return "", err
 	}
 
-	// Generate else block
-	elseBlock := ""
-	if len(ifStmt.ElseBlock) > 0 {
-		var err error
-		elseBlock, err = g.generateStatements(ifStmt.ElseBlock)
+	// Build the if statement
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("if %s {\n", condition))
+	result.WriteString(thenBlock)
+
+	// Generate elif blocks
+	for _, elif := range ifStmt.ElifBlocks {
+		condition, err := g.generateCondition(elif[0], "command")
 		if err != nil {
 			return "", err
 		}
+		block, err := g.generateStatements(elif[1])
+		if err != nil {
+			return "", err
+		}
+		result.WriteString(fmt.Sprintf("} else if %s {\n", condition))
+		result.WriteString(block)
 	}
 
-	// Build the if statement
-	var result strings.Builder
-	result.WriteString(fmt.Sprintf("if %s {\n", condition))
-	result.WriteString(thenBlock)
-	if elseBlock != "" {
+	// Generate else block
+	if len(ifStmt.ElseBlock) > 0 {
+		elseBlock, err := g.generateStatements(ifStmt.ElseBlock)
+		if err != nil {
+			return "", err
+		}
 		result.WriteString("} else {\n")
 		result.WriteString(elseBlock)
 	}
@@ -536,92 +809,29 @@ func (g *GoCodeGenerator) generateCondition(conditions []parser.Statement, condi
 	}
 
 	// For now, just use the first condition
-	stmt := conditions[0]
-	if stmt.Type == parser.StatementCommand {
-		cmd := stmt.Value.(parser.Command)
-
-		// Handle test conditions
-		if cmd.Name == "test" || cmd.Name == "[" {
-			if len(cmd.Args) >= 2 {
-				switch cmd.Args[0] {
-				case "-f":
-					// Test if file exists
-					g.RequiredImports["os"] = true
-					return fmt.Sprintf("_, err := os.Stat(\"%s\"); err == nil", cmd.Args[1]), nil
-				case "-d":
-					// Test if directory exists
-					g.RequiredImports["os"] = true
-					return fmt.Sprintf("info, err := os.Stat(\"%s\"); err == nil && info.IsDir()", cmd.Args[1]), nil
-				case "-z":
-					// Test if string is empty
-					return fmt.Sprintf("len(\"%s\") == 0", cmd.Args[1]), nil
-				case "-n":
-					// Test if string is not empty
-					return fmt.Sprintf("len(\"%s\") > 0", cmd.Args[1]), nil
-				case "=":
-					// Test if strings are equal
-					if len(cmd.Args) >= 3 {
-						return fmt.Sprintf("\"%s\" == \"%s\"", cmd.Args[1], cmd.Args[2]), nil
-					}
-				case "!=":
-					// Test if strings are not equal
-					if len(cmd.Args) >= 3 {
-						return fmt.Sprintf("\"%s\" != \"%s\"", cmd.Args[1], cmd.Args[2]), nil
-					}
-				case "-eq":
-					// Test if numbers are equal
-					if len(cmd.Args) >= 3 {
-						return fmt.Sprintf("%s == %s", cmd.Args[1], cmd.Args[2]), nil
-					}
-				case "-ne":
-					// Test if numbers are not equal
-					if len(cmd.Args) >= 3 {
-						return fmt.Sprintf("%s != %s", cmd.Args[1], cmd.Args[2]), nil
-					}
-				case "-lt":
-					// Test if number is less than
-					if len(cmd.Args) >= 3 {
-						return fmt.Sprintf("%s < %s", cmd.Args[1], cmd.Args[2]), nil
-					}
-				case "-le":
-					// Test if number is less than or equal
-					if len(cmd.Args) >= 3 {
-						return fmt.Sprintf("%s <= %s", cmd.Args[1], cmd.Args[2]), nil
-					}
-				case "-gt":
-					// Test if number is greater than
-					if len(cmd.Args) >= 3 {
-						return fmt.Sprintf("%s > %s", cmd.Args[1], cmd.Args[2]), nil
-					}
-				case "-ge":
-					// Test if number is greater than or equal
-					if len(cmd.Args) >= 3 {
-						return fmt.Sprintf("%s >= %s", cmd.Args[1], cmd.Args[2]), nil
-					}
-				}
-			}
-		}
-
-		// For other commands, use gexe
-		g.RequiredImports["github.com/vladimirvivien/gexe"] = true
-
-		// Build the command string
-		var cmdStr strings.Builder
-		cmdStr.WriteString(cmd.Name)
-
-		for _, arg := range cmd.Args {
-			cmdStr.WriteString(" ")
-			cmdStr.WriteString(arg)
-		}
+	return g.statementCond(conditions[0])
+}
 
-		return fmt.Sprintf("exe.Run(\"%s\").Success()", cmdStr.String()), nil
+// statementCond converts a statement into a Go boolean expression that holds
+// if the statement succeeds.
+func (g *GoCodeGenerator) statementCond(stmt parser.Statement) (string, error) {
+	switch stmt.Type {
+	case parser.StatementArithmetic, parser.StatementTest, parser.StatementAndOr,
+		parser.StatementNegation, parser.StatementCommand:
+		return g.listCond([]parser.Statement{stmt})
 	}
-
 	return "true", nil
 }
 
 // generateLoop generates Go code for a loop
 func (g *GoCodeGenerator) generateLoop(loop parser.Loop) (string, error) {
+	if loop.Type == "for" && loop.IsForEach && !isValidVarName(loop.RangeVar) {
+		g.unsupported++
+		g.IR.Diagnose(diagnostics.SeverityError, g.pos, diagnostics.CodeUnsupported,
+			"%q is not a valid loop variable name", loop.RangeVar)
+		return fmt.Sprintf("// Unsupported for loop over invalid variable %s", commentText(loop.RangeVar)), nil
+	}
+
 	// Generate loop body
 	body, err := g.generateStatements(loop.Body)
 	if err != nil {
@@ -632,14 +842,25 @@ func (g *GoCodeGenerator) generateLoop(loop parser.Loop) (string, error) {
 	switch loop.Type {
 	case "for":
 		if loop.IsForEach {
-			// This is a for-each loop
-			g.RequiredImports["strings"] = true
+			if g.isTableVar(loop.RangeVar) {
+				return fmt.Sprintf(`for _, item := range %s {
+		%s
+		%s
+	}`, g.forEachItems(loop), g.setShellVarCode(loop.RangeVar, "item"), body), nil
+			}
 
-			// Split the items by space
-			return fmt.Sprintf(`items := strings.Fields("%s")
-	for _, %s := range items {
+			// This is a for-each loop. Script variables used as the loop
+			// variable keep their last value after the loop.
+			assign := ":="
+			if g.isScriptVariable(loop.RangeVar) {
+				assign = "="
+			}
+			return fmt.Sprintf(`for _, %s %s range %s {
 		%s
-	}`, loop.Items, loop.RangeVar, body), nil
+	}`, loop.RangeVar, assign, g.forEachItems(loop), body), nil
+		} else if len(loop.Init) > 0 || len(loop.Condition) > 0 || len(loop.Update) > 0 {
+			// This is a C-style loop, for ((init; cond; update))
+			return g.generateCStyleLoop(loop, body)
 		} else if loop.IsRange {
 			// This is a range loop
 			return fmt.Sprintf(`for %s := %s; %s <= %s; %s++ {
@@ -679,98 +900,107 @@ func (g *GoCodeGenerator) generateLoop(loop parser.Loop) (string, error) {
 	}
 }
 
-// generatePipe generates Go code for a pipe
-func (g *GoCodeGenerator) generatePipe(pipe parser.Pipe) (string, error) {
-	if len(pipe.Commands) == 0 {
-		return "// Empty pipe", nil
+// generateCStyleLoop generates Go code for a C-style for loop with the given
+// body. A single update statement goes into the for statement itself; a
+// comma-separated list of updates ends the body instead.
+func (g *GoCodeGenerator) generateCStyleLoop(loop parser.Loop, body string) (string, error) {
+	init, err := g.generateStatements(loop.Init)
+	if err != nil {
+		return "", err
 	}
-
-	// Use gexe for pipes
-	g.RequiredImports["github.com/vladimirvivien/gexe"] = true
-
-	// Build the piped command string
-	var cmdStr strings.Builder
-
-	for i, cmd := range pipe.Commands {
-		if i > 0 {
-			cmdStr.WriteString(" | ")
+	condition, err := g.generateCondition(loop.Condition, "arithmetic")
+	if err != nil {
+		return "", err
+	}
+	if len(loop.Update) == 1 {
+		update, err := g.generateStatement(loop.Update[0])
+		if err != nil {
+			return "", err
 		}
-
-		cmdStr.WriteString(cmd.Name)
-
-		for _, arg := range cmd.Args {
-			cmdStr.WriteString(" ")
-
-			// If the argument contains spaces, quote it
-			if strings.Contains(arg, " ") && !strings.HasPrefix(arg, "\"") {
-				cmdStr.WriteString("\"")
-				cmdStr.WriteString(arg)
-				cmdStr.WriteString("\"")
-			} else {
-				cmdStr.WriteString(arg)
-			}
+		// A comment, left for an update that cannot be translated, cannot be
+		// the post statement
+		if !strings.Contains(update, "\n") && !strings.HasPrefix(update, "//") {
+			return fmt.Sprintf("%sfor ; %s; %s {\n%s}", init, condition, update, body), nil
 		}
 	}
-
-	return fmt.Sprintf(`// Execute piped command: %s
-	output := exe.Run("%s").Stdout()
-	fmt.Print(output)`, cmdStr.String(), cmdStr.String()), nil
-}
-
-// generateSubshell generates Go code for a subshell
-func (g *GoCodeGenerator) generateSubshell(subshell parser.Subshell) (string, error) {
-	// Generate subshell statements
-	stmts, err := g.generateStatements(subshell.Statements)
+	update, err := g.generateStatements(loop.Update)
 	if err != nil {
 		return "", err
 	}
+	return fmt.Sprintf("%sfor %s {\n%s%s}", init, condition, body, update), nil
+}
 
-	// Wrap in a function to create a new scope
-	return fmt.Sprintf(`// Execute subshell
-	func() {
-		%s
-	}()`, stmts), nil
+// commentText returns text for a one-line comment. Commands of substitutions
+// that span lines, as in $(a; b), are put back on one line.
+func commentText(text string) string {
+	return strings.ReplaceAll(text, "\n", "; ")
 }
 
-// generateRedirection generates Go code for a redirection
+// generateRedirection generates Go code for a redirection that is not
+// part of a command, which like in Bash only opens its file. Redirections of
+// commands are generated with the command.
 func (g *GoCodeGenerator) generateRedirection(redirection parser.Redirection) (string, error) {
-	// Use os package for redirections
-	g.RequiredImports["os"] = true
-
-	switch redirection.Op {
-	case ">":
-		// Output redirection (overwrite)
-		return fmt.Sprintf(`// Redirect output to %s
-	file, err := os.Create("%s")
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-	
-	// TODO: Execute command and write output to file`, redirection.Filename, redirection.Filename), nil
-	case ">>":
-		// Output redirection (append)
-		return fmt.Sprintf(`// Redirect output to %s (append)
-	file, err := os.OpenFile("%s", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-	
-	// TODO: Execute command and write output to file`, redirection.Filename, redirection.Filename), nil
-	case "<":
-		// Input redirection
-		return fmt.Sprintf(`// Redirect input from %s
-	file, err := os.Open("%s")
-	if err != nil {
-		return err
+	_, source, ok := redirectTarget(redirection)
+	if !ok {
+		g.unsupported++
+		g.IR.Diagnose(diagnostics.SeverityError, redirection.Pos, diagnostics.CodeUnsupported,
+			"unsupported redirection %s%s", redirection.Fd, redirection.Op)
+		return fmt.Sprintf("// Unsupported redirection: %s%s", redirection.Fd, redirection.Op), nil
 	}
-	defer file.Close()
-	
-	// TODO: Execute command with input from file`, redirection.Filename, redirection.Filename), nil
-	default:
-		return fmt.Sprintf("// Unsupported redirection: %s", redirection.Op), nil
+	if source != "" {
+		// Duplicating a stream has no effect without a command
+		return fmt.Sprintf("// Redirect %s%s %s", redirection.Fd, redirection.Op, commentText(redirection.Filename)), nil
+	}
+
+	// The opened file is closed when the statement finishes
+	scope := newCleanupScope()
+	scope.add(fmt.Sprintf("// Redirect %s%s %s", redirection.Fd, redirection.Op, commentText(redirection.Filename)))
+	scope.acquire(g.openFile("file", g.redirectOpenCall(redirection), "redirection"), "file.Close()")
+	return scope.String(), nil
+}
+
+// location formats a source position as "script.sh:42" for use in
+// generated error messages. Unknown positions fall back to the position of
+// the statement currently being generated, and to an empty string if that
+// is unknown too.
+func (g *GoCodeGenerator) location(pos parser.Position) string {
+	if !pos.IsValid() {
+		pos = g.pos
+	}
+	if !pos.IsValid() {
+		return ""
+	}
+	if pos.File == "" {
+		return fmt.Sprintf("line %d", pos.Line)
+	}
+	return fmt.Sprintf("%s:%d", pos.File, pos.Line)
+}
+
+// errReturn generates a return statement that wraps err with the command
+// name and its location in the original script, so runtime failures in the
+// compiled binary point back at the responsible Bash line.
+func (g *GoCodeGenerator) errReturn(cmd parser.Command) string {
+	return g.errReturnAt(cmd.Pos, cmd.Name)
+}
+
+// errReturnAt generates a return statement that wraps err with a description
+// of the failed operation and the source location pos.
+func (g *GoCodeGenerator) errReturnAt(pos parser.Position, what string) string {
+	g.RequiredImports["fmt"] = true
+
+	msg := what + " failed"
+	if loc := g.location(pos); loc != "" {
+		msg = loc + ": " + msg
 	}
+	msg = strings.ReplaceAll(msg, "%", "%%")
+
+	return fmt.Sprintf("return fmt.Errorf(%s, err)", strconv.Quote(msg+": %w"))
+}
+
+// checkErr generates an if statement that runs call, which must return only
+// an error, and returns the wrapped error on failure.
+func (g *GoCodeGenerator) checkErr(cmd parser.Command, call string) string {
+	return fmt.Sprintf("if err := %s; err != nil {\n\t%s\n}", call, g.errReturn(cmd))
 }
 
 // Helper function to check if a slice contains a string
@@ -0,0 +1,215 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TFMV/bash2go/diagnostics"
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	runtimeHelpers["traps"] = runtimeHelper{
+		Source: `// trapChannels holds the channels delivering the signals trapped by the
+// script, so that resetting a trap can stop its handler
+var (
+	trapMu       sync.Mutex
+	trapChannels = map[os.Signal]chan os.Signal{}
+)
+
+// setTrap runs handler whenever one of signals arrives, replacing the
+// previous traps for them. Errors from handler are printed, like the
+// messages of failing commands in a Bash trap.
+func setTrap(handler func() error, signals ...os.Signal) {
+	for _, sig := range signals {
+		resetTrap(sig)
+
+		ch := make(chan os.Signal, 1)
+		trapMu.Lock()
+		trapChannels[sig] = ch
+		trapMu.Unlock()
+		signal.Notify(ch, sig)
+		go func() {
+			for range ch {
+				if err := handler(); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+				}
+			}
+		}()
+	}
+}
+
+// resetTrap restores the default action of sig, as trap - does
+func resetTrap(sig os.Signal) {
+	trapMu.Lock()
+	defer trapMu.Unlock()
+	if ch, ok := trapChannels[sig]; ok {
+		signal.Stop(ch)
+		close(ch)
+		delete(trapChannels, sig)
+	}
+	signal.Reset(sig)
+}
+
+// ignoreTrap ignores sig, as a trap with an empty handler does
+func ignoreTrap(sig os.Signal) {
+	resetTrap(sig)
+	signal.Ignore(sig)
+}`,
+		Imports: []string{"fmt", "os", "os/signal", "sync"},
+	}
+	runtimeHelpers["exitTrap"] = runtimeHelper{
+		Source: `// exitTrap holds the handler of the EXIT trap, if the script set one
+var exitTrap func() error
+
+// runExitTrap runs the handler of the EXIT trap, if any, once per run of
+// the script
+func runExitTrap() {
+	handler := exitTrap
+	exitTrap = nil
+	if handler == nil {
+		return
+	}
+	if err := handler(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}`,
+		Imports: []string{"fmt", "os"},
+	}
+}
+
+// trapSignals maps the signal conditions trap can handle in generated code
+// to their constants in the syscall package.
+var trapSignals = map[string]string{
+	"HUP":   "syscall.SIGHUP",
+	"INT":   "syscall.SIGINT",
+	"QUIT":  "syscall.SIGQUIT",
+	"ABRT":  "syscall.SIGABRT",
+	"USR1":  "syscall.SIGUSR1",
+	"USR2":  "syscall.SIGUSR2",
+	"PIPE":  "syscall.SIGPIPE",
+	"ALRM":  "syscall.SIGALRM",
+	"TERM":  "syscall.SIGTERM",
+	"CHLD":  "syscall.SIGCHLD",
+	"CONT":  "syscall.SIGCONT",
+	"TSTP":  "syscall.SIGTSTP",
+	"WINCH": "syscall.SIGWINCH",
+}
+
+// generateTrap generates Go code for the trap builtin. Signal traps run the
+// translated handler from a goroutine fed by signal.Notify; the EXIT trap
+// is run by the deferred runExitTrap call at the start of run, and before
+// exit ends the program.
+func (g *GoCodeGenerator) generateTrap(cmd parser.Command) string {
+	if len(cmd.Args) == 0 || cmd.Args[0] == "-p" || cmd.Args[0] == "-l" {
+		g.IR.Diagnose(diagnostics.SeverityWarning, cmd.Pos, diagnostics.CodeUnsupported,
+			"trap without a handler only prints traps and has no effect in generated code")
+		return "// trap without a handler has no effect"
+	}
+
+	handler, specs := parser.TrapArgs(cmd.Args)
+	var exit bool
+	var signals []string
+	for _, spec := range specs {
+		name := parser.TrapCondition(spec)
+		switch {
+		case name == "EXIT":
+			exit = true
+		case trapSignals[name] != "":
+			signals = append(signals, trapSignals[name])
+		default:
+			g.IR.Diagnose(diagnostics.SeverityWarning, cmd.Pos, diagnostics.CodeUnsupported,
+				"trap on %s has no effect in generated code", spec)
+		}
+	}
+	if !exit && len(signals) == 0 {
+		return "// trap has no effect"
+	}
+
+	// The handler is only translated once it is known to be used, so that
+	// it requires no imports otherwise
+	handlerCode := ""
+	if handler != "-" && handler != "" {
+		code, ok := g.trapHandler(handler)
+		if !ok {
+			g.unsupported++
+			g.IR.Diagnose(diagnostics.SeverityError, cmd.Pos, diagnostics.CodeUnsupported,
+				"trap handler %q cannot be translated", handler)
+			return fmt.Sprintf("// Unsupported trap handler: %s", commentText(handler))
+		}
+		handlerCode = code
+	}
+
+	var lines []string
+	if exit {
+		g.requireHelper("exitTrap")
+		if handlerCode == "" {
+			lines = append(lines, "exitTrap = nil")
+		} else {
+			lines = append(lines, "exitTrap = "+handlerCode)
+		}
+	}
+	if len(signals) == 0 {
+		return strings.Join(lines, "\n")
+	}
+
+	g.requireHelper("traps")
+	g.RequiredImports["syscall"] = true
+	switch handler {
+	case "-":
+		for _, sig := range signals {
+			lines = append(lines, fmt.Sprintf("resetTrap(%s)", sig))
+		}
+	case "":
+		for _, sig := range signals {
+			lines = append(lines, fmt.Sprintf("ignoreTrap(%s)", sig))
+		}
+	default:
+		lines = append(lines, fmt.Sprintf("setTrap(%s, %s)", handlerCode, strings.Join(signals, ", ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// trapHandler converts the commands of a trap handler into a Go function
+// literal running them. It reports false if the commands cannot be
+// translated.
+func (g *GoCodeGenerator) trapHandler(script string) (string, bool) {
+	statements, err := parser.ParseCommandSubstitution(g.IR, script, g.pos)
+	if err != nil {
+		return "", false
+	}
+	body, err := g.generateStatements(statements)
+	if err != nil {
+		return "", false
+	}
+	return fmt.Sprintf("func() error {\n%s\n}", bodyWithReturn(body)), true
+}
+
+// exitTrapPrologue returns the statements emitted at the start of run to
+// run the EXIT trap when the script ends.
+func (g *GoCodeGenerator) exitTrapPrologue() []string {
+	if !g.IR.Traps["EXIT"] {
+		return nil
+	}
+	g.requireHelper("exitTrap")
+	return []string{"defer runExitTrap()"}
+}
+
+// exitCode returns the code ending the program with the given exit code,
+// running the EXIT trap first if the script sets one and then waiting for
+// the background jobs still running. In a subshell it ends the subshell.
+func (g *GoCodeGenerator) exitCode(code string) string {
+	if g.subshells > 0 {
+		return g.subshellExitCode(code)
+	}
+	var lines []string
+	if g.IR.Traps["EXIT"] {
+		g.requireHelper("exitTrap")
+		lines = append(lines, "runExitTrap()")
+	}
+	if g.usesJobs() {
+		g.requireHelper("jobs")
+		lines = append(lines, "waitJobs()")
+	}
+	return strings.Join(append(lines, fmt.Sprintf("os.Exit(%s)", code)), "\n")
+}
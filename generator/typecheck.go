@@ -0,0 +1,96 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	goparser "go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"github.com/TFMV/bash2go/diagnostics"
+)
+
+// TypeCheck parses and type-checks code produced by the last Generate call
+// without invoking the Go toolchain. Each problem is reported as an error
+// diagnostic at the Bash statement that produced the offending line, so that
+// generator bugs point at the script rather than at raw compiler output.
+// Imports of packages outside the standard library are not checked. An error
+// is returned if any problem was found.
+func (g *GoCodeGenerator) TypeCheck(code string) error {
+	fset := token.NewFileSet()
+	file, err := goparser.ParseFile(fset, "main.go", code, 0)
+	if err != nil {
+		var list scanner.ErrorList
+		if errors.As(err, &list) {
+			for _, e := range list {
+				g.reportTypeError(e.Pos.Line, e.Msg)
+			}
+		} else {
+			g.reportTypeError(0, err.Error())
+		}
+		return fmt.Errorf("generated code does not parse: %w", err)
+	}
+
+	// Packages that fail to import are named after the last element of their
+	// path, which is wrong for major version suffixes such as /v3
+	unresolved := make(map[string]bool)
+	for _, spec := range file.Imports {
+		if path, err := strconv.Unquote(spec.Path.Value); err == nil && isThirdParty(path) {
+			unresolved["undefined: "+importName(path)] = true
+		}
+	}
+
+	var count int
+	conf := types.Config{
+		Importer: packageImporter(),
+		Error: func(err error) {
+			typeErr, ok := err.(types.Error)
+			if !ok {
+				return
+			}
+			// Third-party packages are resolved by the build, not here
+			if strings.HasPrefix(typeErr.Msg, "could not import ") || unresolved[typeErr.Msg] {
+				return
+			}
+			count++
+			g.reportTypeError(fset.Position(typeErr.Pos).Line, typeErr.Msg)
+		},
+	}
+	conf.Check("main", fset, []*ast.File{file}, nil)
+
+	if count > 0 {
+		return fmt.Errorf("generated code has %d type error(s)", count)
+	}
+	return nil
+}
+
+// packageNames maps the import paths of third-party packages used by
+// generated code to their names where these differ from the path
+var packageNames = map[string]string{
+	goGitImport: "git",
+}
+
+// importName returns the conventional name of the package at path, its last
+// element other than a major version suffix
+func importName(path string) string {
+	if name, ok := packageNames[path]; ok {
+		return name
+	}
+	elems := strings.Split(path, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = elems[len(elems)-2]
+	}
+	return name
+}
+
+// reportTypeError adds a diagnostic for a problem found at goLine of the
+// generated code
+func (g *GoCodeGenerator) reportTypeError(goLine int, msg string) {
+	g.IR.Diagnose(diagnostics.SeverityError, g.SourcePosition(goLine), diagnostics.CodeTypeCheck,
+		"generated code line %d: %s", goLine, msg)
+}
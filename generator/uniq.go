@@ -0,0 +1,100 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	utilities["uniq"] = (*GoCodeGenerator).generateUniq
+
+	runtimeHelpers["uniq"] = runtimeHelper{
+		Source: `// uniqOptions are the options of a uniq command translated to Go
+type uniqOptions struct {
+	count, repeated, unique bool
+}
+
+// uniq prints the lines of its input file, or of stdin without one, without
+// the adjacent repetitions of a line, like uniq. With count, lines are
+// prefixed with the number of their repetitions; repeated prints only the
+// repeated lines, and unique only the others. It fails with status 1 if the
+// input cannot be read.
+func uniq(stdio *streams, stdin io.Reader, opts uniqOptions, input ...string) error {
+	name := "-"
+	if len(input) > 0 {
+		name = input[0]
+	}
+	in, ok := openInput(stdio, "uniq", name, stdin)
+	if !ok {
+		return exitError(1)
+	}
+	defer in.Close()
+
+	out := bufio.NewWriter(stdio.Stdout)
+	defer out.Flush()
+	var last string
+	n := 0
+	flush := func() {
+		if n == 0 || opts.repeated && n == 1 || opts.unique && n > 1 {
+			return
+		}
+		if opts.count {
+			fmt.Fprintf(out, "%7d ", n)
+		}
+		out.WriteString(last + "\n")
+	}
+	lines := bufio.NewReader(in)
+	for {
+		line, err := lines.ReadString('\n')
+		if line != "" {
+			line = strings.TrimSuffix(line, "\n")
+			if n > 0 && line == last {
+				n++
+			} else {
+				flush()
+				last, n = line, 1
+			}
+		}
+		if err != nil {
+			flush()
+			if err != io.EOF {
+				utilityError(stdio, "uniq", name, err)
+				return exitError(1)
+			}
+			return nil
+		}
+	}
+}`,
+		Imports:  []string{"bufio", "fmt", "io", "strings"},
+		Requires: []string{"exitError", "openInput"},
+	}
+}
+
+// generateUniq translates uniq with the options -c, -d and -u, reading its
+// standard input or an input file, into a call of the uniq helper. It
+// reports false for other options and for an output file, so that uniq is
+// executed.
+func (g *GoCodeGenerator) generateUniq(cmd parser.Command) (string, bool) {
+	options, operands, ok := g.utilityArgs(cmd, "cdu", "")
+	if !ok || len(operands) > 1 || len(operands) == 1 && g.expandsArg(cmd, operands[0]) {
+		return "", false
+	}
+	var fields []string
+	for _, option := range options {
+		switch option.name {
+		case 'c':
+			fields = append(fields, "count: true")
+		case 'd':
+			fields = append(fields, "repeated: true")
+		case 'u':
+			fields = append(fields, "unique: true")
+		}
+	}
+
+	g.requireHelper("uniq")
+	call := fmt.Sprintf("uniq(%s, %s, uniqOptions{%s}%s)", g.stdio(), g.utilityInput(cmd), strings.Join(uniqueFields(fields), ", "),
+		g.operandArgs(cmd, operands))
+	return g.checkErr(cmd, call), true
+}
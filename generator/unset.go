@@ -0,0 +1,88 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/TFMV/bash2go/diagnostics"
+	"github.com/TFMV/bash2go/parser"
+)
+
+// generateUnset generates Go code for unset. Script variables cannot be
+// removed from the Go program, so they are emptied, which is how they read
+// when unset; the variable is also removed from the environment in case it
+// was exported. unset IFS restores the default splitting at blanks, and
+// unset 'name[key]' deletes an element of an associative array.
+//
+// With -f, unset removes functions, which stay defined in the Go program
+// where they are called directly; only a warning is reported.
+func (g *GoCodeGenerator) generateUnset(cmd parser.Command) string {
+	functions := false
+	var names []string
+	for _, arg := range cmd.Args {
+		switch arg {
+		case "-f":
+			functions = true
+		case "-v":
+			functions = false
+		default:
+			names = append(names, arg)
+		}
+	}
+
+	var lines []string
+	for _, name := range names {
+		if functions {
+			if _, ok := g.IR.Functions[name]; ok {
+				g.IR.Diagnose(diagnostics.SeverityWarning, cmd.Pos, diagnostics.CodeUnsupported,
+					"unset -f %s: translated functions stay defined", name)
+				lines = append(lines, "// unset -f "+commentText(name))
+			}
+			continue
+		}
+		line, ok := g.unsetVar(name)
+		if !ok {
+			g.unsupported++
+			g.IR.Diagnose(diagnostics.SeverityError, cmd.Pos, diagnostics.CodeUnsupported,
+				"unset %s is not supported", name)
+			line = "// Unsupported unset " + commentText(name)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return "// unset"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// unsetVar returns Go code unsetting the variable or array element name,
+// reporting false if it cannot be unset
+func (g *GoCodeGenerator) unsetVar(name string) (string, bool) {
+	if array, key, ok := cutSubscript(name); ok {
+		if !g.isAssocArray(array) {
+			return "", false
+		}
+		return fmt.Sprintf("delete(%s, %s)", array, g.goArg(unquoteKey(key))), true
+	}
+	if !isValidVarName(name) || g.isConstant(name) || dynamicShellVars[name] {
+		return "", false
+	}
+
+	g.RequiredImports["os"] = true
+	unsetenv := fmt.Sprintf("os.Unsetenv(%s)", strconv.Quote(name))
+	switch {
+	case g.isTableVar(name):
+		return g.setShellVarCode(name, `""`) + "\n" + unsetenv, true
+	case !g.isScriptVariable(name):
+		return unsetenv, true
+	case g.isAssocArray(name):
+		return fmt.Sprintf("%s = map[string]string{}", name), true
+	case g.isIntVar(name):
+		return fmt.Sprintf("%s = 0\n%s", name, unsetenv), true
+	case name == "IFS":
+		// Expansions are split at blanks when IFS is unset
+		return "IFS = " + defaultIFS, true
+	}
+	return fmt.Sprintf("%s = \"\"\n%s", name, unsetenv), true
+}
@@ -0,0 +1,194 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+// utilities holds the translators of the standard utilities translated into
+// Go code, by command name. A translator reports false for the forms it does
+// not translate, which run as external commands.
+var utilities = map[string]func(g *GoCodeGenerator, cmd parser.Command) (string, bool){}
+
+func init() {
+	runtimeHelpers["openInput"] = runtimeHelper{
+		Source: `// openInput opens a file operand of a utility, "-" naming its standard
+// input, and reports failures the way the utility does
+func openInput(stdio *streams, utility, name string, stdin io.Reader) (io.ReadCloser, bool) {
+	if name == "-" {
+		return io.NopCloser(stdin), true
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		utilityError(stdio, utility, name, err)
+		return nil, false
+	}
+	return f, true
+}`,
+		Imports:  []string{"io", "os"},
+		Requires: []string{"utilityError"},
+	}
+	runtimeHelpers["utilityError"] = runtimeHelper{
+		Source: `// utilityError reports an error of a utility about name on the standard
+// error, as in "grep: missing.txt: No such file or directory"
+func utilityError(stdio *streams, utility, name string, err error) {
+	fmt.Fprintf(stdio.Stderr, "%s: %s: %s\n", utility, name, utilityMessage(err))
+}
+
+// utilityMessage returns the message of an error of a utility without the
+// operation and file name of path and system call errors, capitalized like
+// in C programs
+func utilityMessage(err error) string {
+	var errno syscall.Errno
+	var pathErr *fs.PathError
+	if errors.As(err, &errno) {
+		err = errno
+	} else if errors.As(err, &pathErr) {
+		err = pathErr.Err
+	}
+	msg := err.Error()
+	if msg != "" {
+		msg = strings.ToUpper(msg[:1]) + msg[1:]
+	}
+	return msg
+}`,
+		Imports:  []string{"errors", "fmt", "io/fs", "strings", "syscall"},
+		Requires: []string{"streams"},
+	}
+}
+
+// utilityCommand translates the common forms of standard utilities such as
+// grep into Go code. It reports false for other commands and forms, and for
+// commands with a mapping, which decides how they run instead.
+func (g *GoCodeGenerator) utilityCommand(cmd parser.Command) (string, bool) {
+	translate := utilities[cmd.Name]
+	if translate == nil || g.Options.Mappings[cmd.Name] != nil {
+		return "", false
+	}
+	return translate(g, cmd)
+}
+
+// utilityFlag is an option of a utility, with the Go expression of its
+// value for options taking one
+type utilityFlag struct {
+	name  byte
+	value string
+}
+
+// utilityArgs splits the arguments of cmd into its options and the indices
+// of its operands, the way getopt does: options are letters, alone or
+// grouped, those in valued taking the rest of the argument or the next one
+// as their value, and -- ends them. Only literal arguments are options. It
+// reports false for options not in flags or valued, such as long options.
+func (g *GoCodeGenerator) utilityArgs(cmd parser.Command, flags, valued string) ([]utilityFlag, []int, bool) {
+	var options []utilityFlag
+	var operands []int
+	for i := 0; i < len(cmd.Args); i++ {
+		literal, ok := g.literalArg(cmd, i)
+		if !ok || !strings.HasPrefix(literal, "-") || literal == "-" {
+			operands = append(operands, i)
+			continue
+		}
+		if literal == "--" {
+			for i++; i < len(cmd.Args); i++ {
+				operands = append(operands, i)
+			}
+			break
+		}
+		for j := 1; j < len(literal); j++ {
+			c := literal[j]
+			switch {
+			case strings.IndexByte(flags, c) >= 0:
+				options = append(options, utilityFlag{name: c})
+			case strings.IndexByte(valued, c) >= 0:
+				value := strconv.Quote(literal[j+1:])
+				if j+1 == len(literal) {
+					i++
+					if i == len(cmd.Args) || g.expandsArg(cmd, i) {
+						return nil, nil, false
+					}
+					value = g.goArg(cmd.Args[i])
+				}
+				options = append(options, utilityFlag{name: c, value: value})
+				j = len(literal)
+			default:
+				return nil, nil, false
+			}
+		}
+	}
+	return options, operands, true
+}
+
+// expandsArg reports whether the argument of cmd at index i may expand to
+// several words or none, being a glob pattern, an unquoted expansion or "$@"
+func (g *GoCodeGenerator) expandsArg(cmd parser.Command, i int) bool {
+	for _, j := range cmd.Globs {
+		if i == j {
+			return true
+		}
+	}
+	for _, j := range cmd.Splits {
+		if i == j {
+			return true
+		}
+	}
+	return isArgsWord(cmd.Args[i])
+}
+
+// operandArgs returns the Go arguments passing the operands of cmd at the
+// given indices to a variadic function, with their glob patterns expanded
+// and their unquoted expansions split, or "" if there are none
+func (g *GoCodeGenerator) operandArgs(cmd parser.Command, indices []int) string {
+	var words []string
+	var globs, splits []int
+	expands := false
+	for _, i := range indices {
+		for _, j := range cmd.Globs {
+			if i == j {
+				globs = append(globs, len(words))
+			}
+		}
+		for _, j := range cmd.Splits {
+			if i == j {
+				splits = append(splits, len(words))
+			}
+		}
+		expands = expands || g.expandsArg(cmd, i)
+		words = append(words, cmd.Args[i])
+	}
+	if len(words) == 0 {
+		return ""
+	}
+	if expands {
+		return ", " + g.globArgs(words, globs, splits) + "..."
+	}
+	var args string
+	for _, word := range words {
+		args += ", " + g.goArg(word)
+	}
+	return args
+}
+
+// utilityInput returns a Go expression for the standard input of a utility
+// translated into Go code: its here-string or here-document, if any
+func (g *GoCodeGenerator) utilityInput(cmd parser.Command) string {
+	if input := g.stdinInput(cmd); input != "" {
+		g.RequiredImports["strings"] = true
+		return fmt.Sprintf("strings.NewReader(%s)", input)
+	}
+	return g.stdio() + ".stdinReader()"
+}
+
+// literalArg returns the value of the argument of cmd at index i if it is
+// known when converting, with no expansions. It reports false if there is
+// no such argument.
+func (g *GoCodeGenerator) literalArg(cmd parser.Command, i int) (string, bool) {
+	if i >= len(cmd.Args) || g.expandsArg(cmd, i) {
+		return "", false
+	}
+	literal, err := strconv.Unquote(g.goArg(cmd.Args[i]))
+	return literal, err == nil
+}
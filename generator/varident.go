@@ -0,0 +1,96 @@
+package generator
+
+import (
+	"go/token"
+	"go/types"
+	"regexp"
+	"sync"
+)
+
+// importedPackageNames lists the names of the packages generated code
+// imports, which script variables must not shadow
+var importedPackageNames = map[string]bool{
+	"bufio":    true,
+	"bytes":    true,
+	"cmp":      true,
+	"errors":   true,
+	"exec":     true,
+	"filepath": true,
+	"flag":     true,
+	"fmt":      true,
+	"fs":       true,
+	"git":      true,
+	"io":       true,
+	"maps":     true,
+	"os":       true,
+	"plumbing": true,
+	"regexp":   true,
+	"runtime":  true,
+	"signal":   true,
+	"sort":     true,
+	"strconv":  true,
+	"strings":  true,
+	"sync":     true,
+	"syscall":  true,
+	"time":     true,
+	"unicode":  true,
+	"utf8":     true,
+}
+
+// helperDecl matches the package-level declarations of runtime helpers
+var helperDecl = regexp.MustCompile(`(?m)^(?:func|var|const|type) (\w+)`)
+
+// helperIdents returns the identifiers the runtime helpers declare at
+// package level
+var helperIdents = sync.OnceValue(func() map[string]bool {
+	idents := make(map[string]bool)
+	for _, helper := range runtimeHelpers {
+		for _, m := range helperDecl.FindAllStringSubmatch(helper.Source, -1) {
+			idents[m[1]] = true
+		}
+	}
+	return idents
+})
+
+// isTableVar reports whether the script variable name is kept in the
+// shellVars runtime table, like the special variables, because no Go
+// variable can take its name: a Go keyword such as type, a predeclared
+// identifier such as len, or a name the generated program already declares,
+// such as os or a translated function.
+func (g *GoCodeGenerator) isTableVar(name string) bool {
+	if g.tableVars == nil {
+		g.tableVars = make(map[string]bool)
+		funcs := make(map[string]bool, len(g.IR.Functions))
+		for name := range g.IR.Functions {
+			funcs[g.funcIdent(name)] = true
+		}
+		mark := func(name string) {
+			if isValidVarName(name) && !dynamicShellVars[name] && (funcs[name] || !usableVarIdent(name)) {
+				g.tableVars[name] = true
+			}
+		}
+		for name := range g.IR.Variables {
+			mark(name)
+		}
+		for _, function := range g.IR.Functions {
+			for name := range function.LocalVars {
+				mark(name)
+			}
+		}
+	}
+	return g.tableVars[name]
+}
+
+// usableVarIdent reports whether name can name the Go variable of a script
+// variable, functions of the script aside.
+func usableVarIdent(name string) bool {
+	return token.IsIdentifier(name) && types.Universe.Lookup(name) == nil &&
+		!reservedFuncNames[name] && name != "args" && !importedPackageNames[name] &&
+		!helperIdents()[name]
+}
+
+// isShellVar reports whether name is read and written through the shellVars
+// runtime table: a special variable or a table variable
+func (g *GoCodeGenerator) isShellVar(name string) bool {
+	return dynamicShellVars[name] || g.isTableVar(name)
+}
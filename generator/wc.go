@@ -0,0 +1,157 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TFMV/bash2go/parser"
+)
+
+func init() {
+	utilities["wc"] = (*GoCodeGenerator).generateWc
+
+	runtimeHelpers["wc"] = runtimeHelper{
+		Source: `// wcOptions are the counts a wc command translated to Go prints
+type wcOptions struct {
+	lines, words, bytes bool
+}
+
+// wc prints the selected counts of lines, words and bytes of files, or of
+// stdin without files, and their totals for several files, aligned like wc
+// does in the C locale, where words are made of printable characters. It
+// fails with status 1 if a file cannot be read.
+func wc(stdio *streams, stdin io.Reader, opts wcOptions, files ...string) error {
+	names := files
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+	selected := 0
+	for _, on := range []bool{opts.lines, opts.words, opts.bytes} {
+		if on {
+			selected++
+		}
+	}
+	// The counts are as wide as the total size of the files, unless a single
+	// count of a single file is printed, and 7 wide at least for other files
+	width := 1
+	if selected > 1 || len(names) > 1 {
+		minimum, total := 1, int64(0)
+		for i, name := range names {
+			var info os.FileInfo
+			var err error
+			if name == "-" {
+				info, err = stdio.Stdin.Stat()
+			} else {
+				info, err = os.Stat(name)
+			}
+			if err != nil && i == 0 {
+				minimum, total = 1, 0
+				break
+			}
+			if err == nil && !info.Mode().IsRegular() {
+				minimum = 7
+			} else if err == nil {
+				total += info.Size()
+			}
+		}
+		width = max(len(strconv.FormatInt(total, 10)), minimum)
+	}
+
+	out := bufio.NewWriter(stdio.Stdout)
+	defer out.Flush()
+	report := func(counts [3]int64, name string) {
+		var fields []string
+		for i, on := range []bool{opts.lines, opts.words, opts.bytes} {
+			if on {
+				fields = append(fields, fmt.Sprintf("%*d", width, counts[i]))
+			}
+		}
+		if name != "" {
+			fields = append(fields, name)
+		}
+		out.WriteString(strings.Join(fields, " ") + "\n")
+	}
+	var totals [3]int64
+	failed := false
+	buf := make([]byte, 32*1024)
+	for _, name := range names {
+		input, ok := openInput(stdio, "wc", name, stdin)
+		if !ok {
+			failed = true
+			continue
+		}
+		var counts [3]int64
+		inWord := false
+		for {
+			n, err := input.Read(buf)
+			for _, c := range buf[:n] {
+				switch {
+				case c == '\n':
+					counts[0]++
+					inWord = false
+				case c == ' ' || c >= '\t' && c <= '\r':
+					inWord = false
+				case c > ' ' && c < 127 && !inWord:
+					counts[1]++
+					inWord = true
+				}
+			}
+			counts[2] += int64(n)
+			if err != nil {
+				if err != io.EOF {
+					utilityError(stdio, "wc", name, err)
+					failed = true
+				}
+				break
+			}
+		}
+		input.Close()
+		if len(files) == 0 {
+			name = ""
+		}
+		report(counts, name)
+		for i := range totals {
+			totals[i] += counts[i]
+		}
+	}
+	if len(names) > 1 {
+		report(totals, "total")
+	}
+	if failed {
+		return exitError(1)
+	}
+	return nil
+}`,
+		Imports:  []string{"bufio", "fmt", "io", "os", "strconv", "strings"},
+		Requires: []string{"exitError", "openInput"},
+	}
+}
+
+// generateWc translates wc with the options -l, -w and -c, alone or in
+// pipelines, into a call of the wc helper. It reports false for other
+// options, such as -m, so that wc is executed.
+func (g *GoCodeGenerator) generateWc(cmd parser.Command) (string, bool) {
+	options, operands, ok := g.utilityArgs(cmd, "lwc", "")
+	if !ok {
+		return "", false
+	}
+	var fields []string
+	for _, option := range options {
+		switch option.name {
+		case 'l':
+			fields = append(fields, "lines: true")
+		case 'w':
+			fields = append(fields, "words: true")
+		case 'c':
+			fields = append(fields, "bytes: true")
+		}
+	}
+	if len(fields) == 0 {
+		fields = []string{"lines: true", "words: true", "bytes: true"}
+	}
+
+	g.requireHelper("wc")
+	call := fmt.Sprintf("wc(%s, %s, wcOptions{%s}%s)", g.stdio(), g.utilityInput(cmd), strings.Join(uniqueFields(fields), ", "),
+		g.operandArgs(cmd, operands))
+	return g.checkErr(cmd, call), true
+}
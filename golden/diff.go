@@ -0,0 +1,114 @@
+package golden
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around changes
+const diffContext = 3
+
+// edit is one line of a diff: kept (' '), removed ('-') or added ('+').
+// a and b are the numbers of lines of each side before the line.
+type edit struct {
+	op   byte
+	line string
+	a, b int
+}
+
+// Diff returns a unified diff turning a, named nameA, into b, named nameB,
+// or an empty string if they are equal
+func Diff(nameA, a, nameB, b string) string {
+	if a == b {
+		return ""
+	}
+	edits := lineEdits(strings.SplitAfter(a, "\n"), strings.SplitAfter(b, "\n"))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", nameA, nameB)
+	for start := 0; start < len(edits); {
+		// Find the next change and the run of changes close enough to it to
+		// share a hunk
+		first := start
+		for first < len(edits) && edits[first].op == ' ' {
+			first++
+		}
+		if first == len(edits) {
+			break
+		}
+		last := first
+		for i := first + 1; i < len(edits) && i <= last+2*diffContext; i++ {
+			if edits[i].op != ' ' {
+				last = i
+			}
+		}
+
+		from := max(first-diffContext, 0)
+		to := min(last+diffContext+1, len(edits))
+		var countA, countB int
+		for _, e := range edits[from:to] {
+			if e.op != '+' {
+				countA++
+			}
+			if e.op != '-' {
+				countB++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", edits[from].a+1, countA, edits[from].b+1, countB)
+		for _, e := range edits[from:to] {
+			out.WriteByte(e.op)
+			out.WriteString(e.line)
+			if !strings.HasSuffix(e.line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		start = to
+	}
+	return out.String()
+}
+
+// lineEdits returns the shortest edit script turning lines x into lines y,
+// based on their longest common subsequence
+func lineEdits(x, y []string) []edit {
+	// Drop the empty element SplitAfter leaves after a final newline
+	if len(x) > 0 && x[len(x)-1] == "" {
+		x = x[:len(x)-1]
+	}
+	if len(y) > 0 && y[len(y)-1] == "" {
+		y = y[:len(y)-1]
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:]
+	// and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var edits []edit
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			edits = append(edits, edit{' ', x[i], i, j})
+			i++
+			j++
+		case j == len(y) || i < len(x) && lcs[i+1][j] >= lcs[i][j+1]:
+			edits = append(edits, edit{'-', x[i], i, j})
+			i++
+		default:
+			edits = append(edits, edit{'+', y[j], i, j})
+			j++
+		}
+	}
+	return edits
+}
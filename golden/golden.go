@@ -0,0 +1,155 @@
+// Package golden maintains a directory of Bash scripts with the Go code
+// bash2go is expected to generate for them, so that changes to the
+// generator show up as reviewable diffs of the expected code.
+package golden
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/TFMV/bash2go/generator"
+	"github.com/TFMV/bash2go/parser"
+)
+
+// Suffix is the suffix of golden files: the expected code of foo.sh is kept
+// in foo.go.golden
+const Suffix = ".go.golden"
+
+// Mismatch is a script whose generated code differs from its golden file
+type Mismatch struct {
+	Script string
+	Golden string
+	Diff   string // Unified diff from the golden file to the generated code
+}
+
+// GenerateFunc generates Go code for the script at path
+type GenerateFunc func(path string) (string, error)
+
+// Generator returns a GenerateFunc converting scripts with options
+func Generator(options generator.Options) GenerateFunc {
+	return func(path string) (string, error) {
+		ir, err := parser.BuildIRFromFile(path)
+		if err != nil {
+			return "", err
+		}
+		gen := generator.NewGoCodeGenerator(ir)
+		gen.Options = options
+		return gen.Generate()
+	}
+}
+
+// goldenFile returns the golden file of a script
+func goldenFile(script string) string {
+	return strings.TrimSuffix(script, ".sh") + Suffix
+}
+
+// scripts returns the .sh scripts in dir
+func scripts(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.sh"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no .sh scripts in %s", dir)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// orphans returns the golden files in dir without a script
+func orphans(dir string) ([]string, error) {
+	goldens, err := filepath.Glob(filepath.Join(dir, "*"+Suffix))
+	if err != nil {
+		return nil, err
+	}
+	var found []string
+	for _, golden := range goldens {
+		script := strings.TrimSuffix(golden, Suffix) + ".sh"
+		if _, err := os.Stat(script); errors.Is(err, fs.ErrNotExist) {
+			found = append(found, golden)
+		}
+	}
+	return found, nil
+}
+
+// Verify generates the code of every script in dir and compares it with its
+// golden file. Missing golden files and golden files without a script are
+// reported as mismatches.
+func Verify(dir string, generate GenerateFunc) ([]Mismatch, error) {
+	paths, err := scripts(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var mismatches []Mismatch
+	for _, script := range paths {
+		code, err := generate(script)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", script, err)
+		}
+		golden := goldenFile(script)
+		want, err := os.ReadFile(golden)
+		if errors.Is(err, fs.ErrNotExist) {
+			mismatches = append(mismatches, Mismatch{Script: script, Golden: golden, Diff: "missing golden file"})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if diff := Diff(golden, string(want), script+" (generated)", code); diff != "" {
+			mismatches = append(mismatches, Mismatch{Script: script, Golden: golden, Diff: diff})
+		}
+	}
+
+	stale, err := orphans(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, golden := range stale {
+		mismatches = append(mismatches, Mismatch{Golden: golden, Diff: "golden file without a script"})
+	}
+	return mismatches, nil
+}
+
+// Update rewrites the golden files of the scripts in dir with their
+// generated code and removes golden files without a script. It returns the
+// golden files that changed.
+func Update(dir string, generate GenerateFunc) ([]string, error) {
+	paths, err := scripts(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for _, script := range paths {
+		code, err := generate(script)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", script, err)
+		}
+		golden := goldenFile(script)
+		if existing, err := os.ReadFile(golden); err == nil && string(existing) == code {
+			continue
+		}
+		if err := os.WriteFile(golden, []byte(code), 0644); err != nil {
+			return nil, err
+		}
+		changed = append(changed, golden)
+	}
+
+	stale, err := orphans(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, golden := range stale {
+		if err := os.Remove(golden); err != nil {
+			return nil, err
+		}
+		changed = append(changed, golden)
+	}
+	return changed, nil
+}
@@ -0,0 +1,106 @@
+package golden
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/bash2go/generator"
+)
+
+// TestFixtures checks the generated code of the repository's fixtures
+// against their golden files
+func TestFixtures(t *testing.T) {
+	mismatches, err := Verify(filepath.Join("..", "testdata", "golden"), Generator(generator.Options{}))
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	for _, m := range mismatches {
+		t.Errorf("%s is out of date; run bash2go golden update --dir testdata/golden\n%s", m.Golden, m.Diff)
+	}
+}
+
+// TestVerifyUpdate tests detecting and updating outdated golden files
+func TestVerifyUpdate(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"a.sh":               "echo a\n",
+		"b.sh":               "echo b\n",
+		"stale" + Suffix:     "package main\n",
+		"unrelated.txt":      "kept\n",
+		"b" + Suffix + ".md": "kept\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	generate := func(path string) (string, error) {
+		data, err := os.ReadFile(path)
+		return "// " + string(data), err
+	}
+
+	mismatches, err := Verify(dir, generate)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if len(mismatches) != 3 {
+		t.Fatalf("Expected 2 missing and 1 stale golden file, got %+v", mismatches)
+	}
+
+	changed, err := Update(dir, generate)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if len(changed) != 3 {
+		t.Errorf("Expected 3 changed golden files, got %v", changed)
+	}
+	if mismatches, err := Verify(dir, generate); err != nil || len(mismatches) != 0 {
+		t.Errorf("Expected updated golden files to verify, got %+v, %v", mismatches, err)
+	}
+	if changed, _ := Update(dir, generate); len(changed) != 0 {
+		t.Errorf("Expected no changes on a second update, got %v", changed)
+	}
+
+	// A changed script shows up as a diff
+	if err := os.WriteFile(filepath.Join(dir, "a.sh"), []byte("echo changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mismatches, err = Verify(dir, generate)
+	if err != nil || len(mismatches) != 1 || !strings.Contains(mismatches[0].Diff, "+// echo changed") {
+		t.Errorf("Expected a diff for a.sh, got %+v, %v", mismatches, err)
+	}
+}
+
+// TestDiff tests unified diffs of changed lines
+func TestDiff(t *testing.T) {
+	if diff := Diff("a", "same\n", "b", "same\n"); diff != "" {
+		t.Errorf("Expected no diff for equal inputs, got %q", diff)
+	}
+
+	old := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	changed := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n"
+	want := `--- old
++++ new
+@@ -1,6 +1,6 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+ 6
+@@ -10,3 +10,4 @@
+ 10
+ 11
+ 12
++13
+`
+	if diff := Diff("old", old, "new", changed); diff != want {
+		t.Errorf("Unexpected diff:\n%s\nwant:\n%s", diff, want)
+	}
+
+	if diff := Diff("old", "a\n", "new", "a"); !strings.Contains(diff, "\\ No newline at end of file") {
+		t.Errorf("Expected a missing final newline to be marked, got:\n%s", diff)
+	}
+}
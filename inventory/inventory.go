@@ -0,0 +1,489 @@
+// Package inventory scans a repository for shell scripts and reports which
+// Bash features and external commands they use, and how ready each script is
+// for conversion, to plan a migration before starting it.
+package inventory
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/TFMV/bash2go/diagnostics"
+	"github.com/TFMV/bash2go/generator"
+	"github.com/TFMV/bash2go/parser"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// Conversion readiness of a script
+const (
+	// StatusReady scripts convert to Go code that type-checks, without
+	// errors
+	StatusReady = "ready"
+	// StatusPartial scripts convert, but with unsupported constructs or
+	// generated code that does not type-check
+	StatusPartial = "partial"
+	// StatusBlocked scripts do not parse or convert at all
+	StatusBlocked = "blocked"
+)
+
+// statusRank orders statuses from most to least ready
+var statusRank = map[string]int{StatusReady: 0, StatusPartial: 1, StatusBlocked: 2}
+
+// skippedDirs are not searched for scripts
+var skippedDirs = map[string]bool{".git": true, "node_modules": true, "vendor": true}
+
+// shebang matches the interpreter line of sh and Bash scripts
+var shebang = regexp.MustCompile(`^#!\s*\S*/(env\s+)?(ba)?sh(\s|$)`)
+
+// Location is a place in a script
+type Location struct {
+	File string `json:"file"`
+	Line uint   `json:"line"`
+}
+
+// String formats the location as "file:line"
+func (l Location) String() string {
+	return fmt.Sprintf("%s:%d", l.File, l.Line)
+}
+
+// Usage aggregates the uses of a feature or command over all scripts
+type Usage struct {
+	Name      string     `json:"name"`
+	Count     int        `json:"count"`
+	Scripts   int        `json:"scripts"` // Number of scripts using it
+	Locations []Location `json:"locations"`
+}
+
+// Script is the inventory of one script
+type Script struct {
+	Path     string         `json:"path"`
+	Status   string         `json:"status"`
+	Error    string         `json:"error,omitempty"`
+	Lines    int            `json:"lines"`
+	Coverage float64        `json:"coverage"` // Percentage of statements translated natively
+	Errors   int            `json:"errors"`
+	Warnings int            `json:"warnings"`
+	Features map[string]int `json:"features"`
+	Commands map[string]int `json:"commands"`
+}
+
+// Report is the inventory of a repository
+type Report struct {
+	Root     string   `json:"root"`
+	Scripts  []Script `json:"scripts"`  // Ranked by conversion readiness
+	Features []Usage  `json:"features"` // By decreasing use
+	Commands []Usage  `json:"commands"` // External commands, by decreasing use
+}
+
+// Scan inventories the shell scripts below root, converting each with
+// options to rate its readiness
+func Scan(root string, options generator.Options) (*Report, error) {
+	paths, err := FindScripts(root)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Root: root}
+	features := make(map[string]*Usage)
+	commands := make(map[string]*Usage)
+	for _, path := range paths {
+		script, uses := scanScript(path, options)
+		report.Scripts = append(report.Scripts, script)
+		for _, use := range uses {
+			table := features
+			if use.command {
+				table = commands
+			}
+			usage := table[use.name]
+			if usage == nil {
+				usage = &Usage{Name: use.name}
+				table[use.name] = usage
+			}
+			usage.Count++
+			usage.Locations = append(usage.Locations, use.loc)
+		}
+		for name := range script.Features {
+			features[name].Scripts++
+		}
+		for name := range script.Commands {
+			commands[name].Scripts++
+		}
+	}
+
+	sort.SliceStable(report.Scripts, func(i, j int) bool {
+		a, b := report.Scripts[i], report.Scripts[j]
+		if statusRank[a.Status] != statusRank[b.Status] {
+			return statusRank[a.Status] < statusRank[b.Status]
+		}
+		if a.Coverage != b.Coverage {
+			return a.Coverage > b.Coverage
+		}
+		return a.Errors < b.Errors
+	})
+	report.Features = sortedUsages(features)
+	report.Commands = sortedUsages(commands)
+	return report, nil
+}
+
+// FindScripts returns the shell scripts below root: files named *.sh or
+// *.bash, and files starting with an sh or Bash shebang
+func FindScripts(root string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && skippedDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		switch filepath.Ext(path) {
+		case ".sh", ".bash":
+			paths = append(paths, path)
+		case "":
+			if hasShebang(path) {
+				paths = append(paths, path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// hasShebang reports whether the file at path starts with an sh or Bash
+// shebang
+func hasShebang(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	line, _ := bufio.NewReader(f).ReadString('\n')
+	return shebang.MatchString(line)
+}
+
+// use is one use of a feature or external command
+type use struct {
+	name    string
+	command bool
+	loc     Location
+}
+
+// scanScript inventories one script
+func scanScript(path string, options generator.Options) (Script, []use) {
+	script := Script{
+		Path:     path,
+		Status:   StatusBlocked,
+		Features: make(map[string]int),
+		Commands: make(map[string]int),
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		script.Error = err.Error()
+		return script, nil
+	}
+	script.Lines = strings.Count(string(data), "\n")
+
+	result, err := parser.ParseBashString(string(data))
+	if err != nil {
+		script.Error = fmt.Sprintf("parse error: %v", err)
+		return script, nil
+	}
+	uses := collectUses(path, result.File)
+	for _, u := range uses {
+		if u.command {
+			script.Commands[u.name]++
+		} else {
+			script.Features[u.name]++
+		}
+	}
+
+	rateReadiness(&script, result, options)
+	return script, uses
+}
+
+// rateReadiness converts a parsed script and records how well that went
+func rateReadiness(script *Script, result *parser.ParseResult, options generator.Options) {
+	// A generator bug must not stop the scan of the other scripts
+	defer func() {
+		if r := recover(); r != nil {
+			script.Status = StatusBlocked
+			script.Error = fmt.Sprintf("conversion panicked: %v", r)
+		}
+	}()
+
+	result.Filename = filepath.Base(script.Path)
+	ir, err := parser.BuildIR(result)
+	if err != nil {
+		script.Error = err.Error()
+		return
+	}
+	gen := generator.NewGoCodeGenerator(ir)
+	gen.Options = options
+	code, err := gen.Generate()
+	if err == nil {
+		err = gen.TypeCheck(code)
+	}
+
+	for _, d := range ir.Diagnostics.Items() {
+		switch d.Severity {
+		case diagnostics.SeverityError:
+			script.Errors++
+		case diagnostics.SeverityWarning:
+			script.Warnings++
+		}
+	}
+	script.Coverage = gen.Metrics().NativePercent()
+	switch {
+	case code == "":
+		script.Error = fmt.Sprintf("conversion failed: %v", err)
+	case err != nil || script.Errors > 0:
+		script.Status = StatusPartial
+	default:
+		script.Status = StatusReady
+	}
+}
+
+// collectUses returns the Bash features and external commands used in file
+func collectUses(path string, file *syntax.File) []use {
+	var uses []use
+	functions := make(map[string]bool)
+	branches := make(map[*syntax.IfClause]bool)
+	chained := make(map[*syntax.BinaryCmd]bool)
+	bodies := make(map[*syntax.Block]bool)
+	feature := func(name string, pos syntax.Pos) {
+		uses = append(uses, use{name: name, loc: Location{File: path, Line: pos.Line()}})
+	}
+
+	syntax.Walk(file, func(node syntax.Node) bool {
+		switch x := node.(type) {
+		case *syntax.Stmt:
+			if x.Background {
+				feature("background job", x.Pos())
+			}
+			if x.Negated {
+				feature("! negation", x.Pos())
+			}
+		case *syntax.FuncDecl:
+			functions[x.Name.Value] = true
+			if body, ok := x.Body.Cmd.(*syntax.Block); ok {
+				bodies[body] = true
+			}
+			feature("function", x.Pos())
+		case *syntax.IfClause:
+			// Walk visits the elif and else branches as clauses of their own
+			switch {
+			case !branches[x]:
+				feature("if", x.Pos())
+			case len(x.Cond) > 0:
+				feature("elif", x.Pos())
+			}
+			if x.Else != nil {
+				branches[x.Else] = true
+			}
+		case *syntax.WhileClause:
+			if x.Until {
+				feature("until loop", x.Pos())
+			} else {
+				feature("while loop", x.Pos())
+			}
+		case *syntax.ForClause:
+			switch {
+			case x.Select:
+				feature("select", x.Pos())
+			case isCStyle(x.Loop):
+				feature("for (( )) loop", x.Pos())
+			default:
+				feature("for loop", x.Pos())
+			}
+		case *syntax.CaseClause:
+			feature("case", x.Pos())
+		case *syntax.BinaryCmd:
+			// a | b | c is one pipeline, nested as (a | b) | c
+			if inner, ok := x.X.Cmd.(*syntax.BinaryCmd); ok && sameChain(x.Op, inner.Op) {
+				chained[inner] = true
+			}
+			if chained[x] {
+				break
+			}
+			switch x.Op {
+			case syntax.Pipe, syntax.PipeAll:
+				feature("pipeline", x.Pos())
+			case syntax.AndStmt, syntax.OrStmt:
+				feature("&& and ||", x.Pos())
+			}
+		case *syntax.Subshell:
+			feature("subshell", x.Pos())
+		case *syntax.Block:
+			if !bodies[x] {
+				feature("{ } group", x.Pos())
+			}
+		case *syntax.CmdSubst:
+			feature("command substitution", x.Pos())
+		case *syntax.ProcSubst:
+			feature("process substitution", x.Pos())
+		case *syntax.ArithmExp, *syntax.ArithmCmd, *syntax.LetClause:
+			feature("arithmetic", x.Pos())
+		case *syntax.TestClause:
+			feature("[[ ]] test", x.Pos())
+		case *syntax.CoprocClause:
+			feature("coproc", x.Pos())
+		case *syntax.TimeClause:
+			feature("time", x.Pos())
+		case *syntax.DeclClause:
+			feature(x.Variant.Value, x.Pos())
+		case *syntax.ArrayExpr:
+			feature("array", x.Pos())
+		case *syntax.ExtGlob:
+			feature("extglob pattern", x.Pos())
+		case *syntax.Redirect:
+			switch x.Op {
+			case syntax.Hdoc, syntax.DashHdoc:
+				feature("heredoc", x.Pos())
+			case syntax.WordHdoc:
+				feature("here-string", x.Pos())
+			default:
+				feature("redirection", x.Pos())
+			}
+		case *syntax.ParamExp:
+			if name := paramFeature(x); name != "" {
+				feature(name, x.Pos())
+			}
+		case *syntax.CallExpr:
+			if len(x.Args) == 0 {
+				break
+			}
+			name := x.Args[0].Lit()
+			switch {
+			case name == "":
+				feature("dynamic command name", x.Pos())
+			case builtinFeatures[name] != "":
+				feature(builtinFeatures[name], x.Pos())
+			case !shellBuiltins[name]:
+				uses = append(uses, use{name: name, command: true, loc: Location{File: path, Line: x.Pos().Line()}})
+			}
+		}
+		return true
+	})
+
+	// Calls of the script's own functions are not external commands
+	kept := uses[:0]
+	for _, u := range uses {
+		if !u.command || !functions[u.name] {
+			kept = append(kept, u)
+		}
+	}
+	return kept
+}
+
+// sameChain reports whether binary commands with operators a and b are parts
+// of one pipeline or one && and || list
+func sameChain(a, b syntax.BinCmdOperator) bool {
+	isPipe := func(op syntax.BinCmdOperator) bool { return op == syntax.Pipe || op == syntax.PipeAll }
+	return isPipe(a) == isPipe(b)
+}
+
+// isCStyle reports whether a for loop is of the form for ((;;))
+func isCStyle(loop syntax.Loop) bool {
+	_, ok := loop.(*syntax.CStyleLoop)
+	return ok
+}
+
+// paramFeature returns the feature a parameter expansion uses, or an empty
+// string for a plain variable reference
+func paramFeature(x *syntax.ParamExp) string {
+	switch {
+	case x.Length:
+		return "${#var} length"
+	case x.Slice != nil:
+		return "${var:offset} substring"
+	case x.Repl != nil:
+		return "${var/pattern/string} substitution"
+	case x.Excl:
+		return "${!var} indirection"
+	case x.Index != nil:
+		return "array index"
+	case x.Exp != nil:
+		switch x.Exp.Op {
+		case syntax.RemSmallPrefix, syntax.RemLargePrefix, syntax.RemSmallSuffix, syntax.RemLargeSuffix:
+			return "${var#pattern} removal"
+		case syntax.UpperFirst, syntax.UpperAll, syntax.LowerFirst, syntax.LowerAll:
+			return "${var^^} case conversion"
+		default:
+			return "${var:-default} expansion"
+		}
+	}
+	if x.Param == nil {
+		return ""
+	}
+	switch name := x.Param.Value; {
+	case name == "@" || name == "*" || name == "#" || (len(name) > 0 && name[0] >= '0' && name[0] <= '9' && name != "0"):
+		return "positional parameters"
+	case name == "?":
+		return "$? exit status"
+	case name == "$" || name == "!" || name == "0":
+		return "$" + name
+	case name == "PIPESTATUS" || name == "IFS" || name == "FUNCNAME" || name == "BASH_SOURCE":
+		return "$" + name
+	}
+	return ""
+}
+
+// builtinFeatures maps builtins that change how a script runs to the feature
+// they are reported as
+var builtinFeatures = map[string]string{
+	"trap":    "trap",
+	"getopts": "getopts",
+	"shift":   "shift",
+	"source":  "source",
+	".":       "source",
+	"eval":    "eval",
+	"exec":    "exec",
+	"read":    "read",
+	"set":     "set",
+	"wait":    "wait",
+	"printf":  "printf",
+	"shopt":   "shopt",
+	"let":     "arithmetic",
+}
+
+// shellBuiltins lists the other Bash builtins, which are not external
+// commands
+var shellBuiltins = map[string]bool{
+	"echo": true, "cd": true, "pwd": true, "exit": true, "return": true, "test": true, "[": true,
+	"true": true, "false": true, ":": true, "export": true, "unset": true, "local": true, "declare": true,
+	"typeset": true, "readonly": true, "alias": true, "unalias": true, "type": true, "command": true,
+	"builtin": true, "hash": true, "jobs": true, "fg": true, "bg": true, "kill": true, "umask": true,
+	"ulimit": true, "pushd": true, "popd": true, "dirs": true, "break": true, "continue": true,
+	"mapfile": true, "readarray": true, "enable": true, "help": true, "history": true, "disown": true,
+	"suspend": true, "times": true, "caller": true, "compgen": true, "complete": true, "logout": true,
+}
+
+// sortedUsages returns the usages in table by decreasing count, then name
+func sortedUsages(table map[string]*Usage) []Usage {
+	usages := make([]Usage, 0, len(table))
+	for _, usage := range table {
+		usages = append(usages, *usage)
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		if usages[i].Count != usages[j].Count {
+			return usages[i].Count > usages[j].Count
+		}
+		return usages[i].Name < usages[j].Name
+	})
+	return usages
+}
@@ -0,0 +1,139 @@
+package inventory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TFMV/bash2go/generator"
+)
+
+// writeScripts writes files into a temporary directory and returns it
+func writeScripts(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// TestFindScripts tests which files are taken for shell scripts
+func TestFindScripts(t *testing.T) {
+	dir := writeScripts(t, map[string]string{
+		"build.sh":                  "echo build\n",
+		"lib/util.bash":             "echo util\n",
+		"bin/deploy":                "#!/usr/bin/env bash\necho deploy\n",
+		"bin/install":               "#!/bin/sh\necho install\n",
+		"bin/tool.py":               "#!/usr/bin/env python3\n",
+		"bin/script":                "#!/usr/bin/env python3\n",
+		"README":                    "docs\n",
+		"node_modules/pkg/setup.sh": "echo vendored\n",
+		".git/hooks/pre-commit.sh":  "echo hook\n",
+	})
+
+	paths, err := FindScripts(dir)
+	if err != nil {
+		t.Fatalf("FindScripts failed: %v", err)
+	}
+	var got []string
+	for _, path := range paths {
+		rel, _ := filepath.Rel(dir, path)
+		got = append(got, filepath.ToSlash(rel))
+	}
+	want := []string{"bin/deploy", "bin/install", "build.sh", "lib/util.bash"}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected %v, got %v", want, got)
+			break
+		}
+	}
+}
+
+// TestScan tests counting features and commands and ranking scripts
+func TestScan(t *testing.T) {
+	dir := writeScripts(t, map[string]string{
+		"ready.sh": "#!/bin/bash\necho hello\nmkdir -p out\n",
+		"mixed.sh": `#!/bin/bash
+cleanup() { rm -rf "$tmp"; }
+trap cleanup EXIT
+for f in *.txt; do
+  grep -q TODO "$f" && echo "$f"
+done
+count=$(ls | wc -l)
+if [ "$count" -gt 1 ]; then
+  echo many
+elif [ "$count" -eq 1 ]; then
+  echo one
+fi
+cleanup
+`,
+		"broken.sh": "#!/bin/bash\nif then\n",
+	})
+
+	report, err := Scan(dir, generator.Options{})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(report.Scripts) != 3 {
+		t.Fatalf("Expected 3 scripts, got %d", len(report.Scripts))
+	}
+	last := report.Scripts[2]
+	if filepath.Base(last.Path) != "broken.sh" || last.Status != StatusBlocked || last.Error == "" {
+		t.Errorf("Expected broken.sh to be ranked last as blocked, got %+v", last)
+	}
+	first := report.Scripts[0]
+	if filepath.Base(first.Path) != "ready.sh" || first.Status != StatusReady {
+		t.Errorf("Expected ready.sh to be ranked first as ready, got %+v", first)
+	}
+
+	mixed := report.Scripts[1]
+	for name, count := range map[string]int{
+		"function": 1, "trap": 1, "for loop": 1, "&& and ||": 1, "pipeline": 1,
+		"command substitution": 1, "if": 1, "elif": 1,
+	} {
+		if mixed.Features[name] != count {
+			t.Errorf("Expected %d uses of %q, got %d", count, name, mixed.Features[name])
+		}
+	}
+	for _, name := range []string{"rm", "grep", "ls", "wc"} {
+		if mixed.Commands[name] != 1 {
+			t.Errorf("Expected one use of %s, got %d", name, mixed.Commands[name])
+		}
+	}
+	for _, name := range []string{"cleanup", "echo", "trap", "["} {
+		if _, ok := mixed.Commands[name]; ok {
+			t.Errorf("Expected %s not to be counted as an external command", name)
+		}
+	}
+
+	var echo, grep *Usage
+	for i := range report.Features {
+		if report.Features[i].Name == "trap" && report.Features[i].Locations[0].Line != 3 {
+			t.Errorf("Expected trap on line 3, got %v", report.Features[i].Locations)
+		}
+	}
+	for i := range report.Commands {
+		switch report.Commands[i].Name {
+		case "echo":
+			echo = &report.Commands[i]
+		case "grep":
+			grep = &report.Commands[i]
+		}
+	}
+	if echo != nil {
+		t.Error("Expected echo, a builtin, not to be listed as a command")
+	}
+	if grep == nil || grep.Count != 1 || grep.Scripts != 1 || grep.Locations[0].Line != 5 {
+		t.Errorf("Expected one use of grep on line 5, got %+v", grep)
+	}
+}
@@ -0,0 +1,30 @@
+package parser
+
+import "mvdan.cc/sh/v3/syntax"
+
+// AndOr is a command list joined with && or ||, as in cmd1 && cmd2 || cmd3.
+// Lists group to the left, so the left operand of that one is cmd1 && cmd2.
+type AndOr struct {
+	Op string      `json:"op"`          // "&&" or "||".
+	X  []Statement `json:"x,omitempty"` // Statements of the left operand, run first.
+	Y  []Statement `json:"y,omitempty"` // Statements of the right operand, run if the left one succeeds for && or fails for ||.
+}
+
+// isAndOr reports whether x joins two statements with && or ||.
+func isAndOr(x *syntax.BinaryCmd) bool {
+	return x.Op == syntax.AndStmt || x.Op == syntax.OrStmt
+}
+
+// processAndOr processes a command list joined with && or ||. What its
+// operands need from the script is recorded in ir.
+func processAndOr(ir *IntermediateRepresentation, x *syntax.BinaryCmd) Statement {
+	return Statement{
+		Type: StatementAndOr,
+		Value: AndOr{
+			Op: x.Op.String(),
+			X:  processNested(ir, []*syntax.Stmt{x.X}),
+			Y:  processNested(ir, []*syntax.Stmt{x.Y}),
+		},
+		Pos: newPosition(x.Pos()),
+	}
+}
@@ -0,0 +1,162 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// Arithmetic is a node of an arithmetic expression, as in $(( )), (( )) and
+// let. Operands are numbers, variables and other words; operators are
+// unary, binary or the ternary ?: operator.
+type Arithmetic struct {
+	Op    string      `json:"op,omitempty"`    // Operator, such as "+", "<=", "+=", "++" or "?"; empty for operands.
+	Value string      `json:"value,omitempty"` // Number or variable name of an operand, or the word of other operands.
+	IsVar bool        `json:"isVar,omitempty"` // The operand is a variable.
+	Post  bool        `json:"post,omitempty"`  // The ++ or -- operator follows its operand.
+	X     *Arithmetic `json:"x,omitempty"`     // Only or left operand, or the condition of ?:.
+	Y     *Arithmetic `json:"y,omitempty"`     // Right operand, or the value of ?: if the condition holds.
+	Z     *Arithmetic `json:"z,omitempty"`     // Value of ?: if the condition does not hold.
+}
+
+// IsAssign reports whether the operator assigns to its left operand, as =,
+// += and ++ do.
+func (a *Arithmetic) IsAssign() bool {
+	return a.Op == "++" || a.Op == "--" || (strings.HasSuffix(a.Op, "=") && !a.IsComparison())
+}
+
+// IsComparison reports whether the operator compares its operands.
+func (a *Arithmetic) IsComparison() bool {
+	switch a.Op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		return true
+	}
+	return false
+}
+
+// ParseArithmetic parses the expression of an arithmetic expansion, without
+// the surrounding $(( and )), into an expression tree.
+func ParseArithmetic(expr string) (*Arithmetic, error) {
+	x, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Arithmetic(strings.NewReader(expr))
+	if err != nil {
+		return nil, err
+	}
+	if x == nil {
+		return nil, fmt.Errorf("empty arithmetic expression")
+	}
+	return processArithm(x), nil
+}
+
+// processArithm converts an arithmetic expression into an expression tree.
+func processArithm(x syntax.ArithmExpr) *Arithmetic {
+	switch x := x.(type) {
+	case *syntax.Word:
+		if name := x.Lit(); name != "" {
+			return &Arithmetic{Value: name, IsVar: isVarName(name)}
+		}
+		if len(x.Parts) == 1 {
+			switch p := x.Parts[0].(type) {
+			case *syntax.ParamExp:
+				// $name and ${name} are the variable, as in Bash
+				if isPlainParamExp(p) {
+					return &Arithmetic{Value: p.Param.Value, IsVar: true}
+				}
+			case *syntax.SglQuoted, *syntax.DblQuoted:
+				// Quoted expressions, as in let "i += 2"
+				if a, err := ParseArithmetic(extractWordValue(x)); err == nil {
+					return a
+				}
+			}
+		}
+		return &Arithmetic{Value: extractWordValue(x)}
+	case *syntax.ParenArithm:
+		return processArithm(x.X)
+	case *syntax.UnaryArithm:
+		return &Arithmetic{Op: x.Op.String(), Post: x.Post, X: processArithm(x.X)}
+	case *syntax.BinaryArithm:
+		if x.Op == syntax.TernQuest {
+			if branches, ok := x.Y.(*syntax.BinaryArithm); ok && branches.Op == syntax.TernColon {
+				return &Arithmetic{Op: "?", X: processArithm(x.X), Y: processArithm(branches.X), Z: processArithm(branches.Y)}
+			}
+		}
+		return &Arithmetic{Op: x.Op.String(), X: processArithm(x.X), Y: processArithm(x.Y)}
+	}
+	return &Arithmetic{Value: nodeText(x)}
+}
+
+// processArithmCmd processes an arithmetic command (( expr )).
+func processArithmCmd(x *syntax.ArithmCmd) Statement {
+	return Statement{
+		Type:  StatementArithmetic,
+		Value: *processArithm(x.X),
+		Pos:   newPosition(x.Pos()),
+	}
+}
+
+// processLetClause processes the let builtin, which evaluates each of its
+// expressions in turn.
+func processLetClause(x *syntax.LetClause) []Statement {
+	statements := make([]Statement, len(x.Exprs))
+	for i, expr := range x.Exprs {
+		statements[i] = Statement{
+			Type:  StatementArithmetic,
+			Value: *processArithm(expr),
+			Pos:   newPosition(expr.Pos()),
+		}
+	}
+	return statements
+}
+
+// arithmAssignments returns the variables assigned by the arithmetic
+// expressions below node, such as i in (( i++ )), so that they are script
+// variables even if never assigned otherwise.
+func arithmAssignments(node syntax.Node) []string {
+	var names []string
+	syntax.Walk(node, func(node syntax.Node) bool {
+		var exprs []syntax.ArithmExpr
+		switch x := node.(type) {
+		case *syntax.ArithmExp:
+			exprs = []syntax.ArithmExpr{x.X}
+		case *syntax.ArithmCmd:
+			exprs = []syntax.ArithmExpr{x.X}
+		case *syntax.LetClause:
+			exprs = x.Exprs
+		case *syntax.CStyleLoop:
+			exprs = []syntax.ArithmExpr{x.Init, x.Cond, x.Post}
+		}
+		for _, expr := range exprs {
+			if expr != nil {
+				names = processArithm(expr).assignedVars(names)
+			}
+		}
+		return true
+	})
+	return names
+}
+
+// assignedVars appends the variables assigned within a to names.
+func (a *Arithmetic) assignedVars(names []string) []string {
+	if a == nil {
+		return names
+	}
+	if a.IsAssign() && a.X != nil && a.X.IsVar && isVarName(a.X.Value) {
+		names = append(names, a.X.Value)
+	}
+	names = a.X.assignedVars(names)
+	names = a.Y.assignedVars(names)
+	return a.Z.assignedVars(names)
+}
+
+// isVarName reports whether s is a valid variable name.
+func isVarName(s string) bool {
+	if s == "" || (s[0] >= '0' && s[0] <= '9') {
+		return false
+	}
+	for _, c := range s {
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
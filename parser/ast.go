@@ -1,9 +1,15 @@
 package parser
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/TFMV/bash2go/diagnostics"
 	"mvdan.cc/sh/v3/syntax"
 )
 
@@ -12,18 +18,116 @@ const debug = false
 
 // IntermediateRepresentation represents the processed AST in a format suitable for Go code generation.
 type IntermediateRepresentation struct {
+	Filename         string // Base name of the source script, if known.
 	Variables        map[string]string
 	Functions        map[string]*Function
 	MainStatements   []Statement
 	RequiredPackages map[string]bool
+	EnvPolicies      map[string]EnvPolicy   // Per-variable policies from bash2go:env directives.
+	ShellOptions     map[string]bool        // Options enabled or disabled with shopt or set anywhere in the script.
+	SpecialVars      map[string]bool        // Special variables such as $? and FUNCNAME read by the script.
+	AssocArrays      map[string]bool        // Variables declared as associative arrays with declare -A.
+	IntegerVars      map[string]bool        // Variables declared as integers with declare -i.
+	ReadonlyVars     map[string]bool        // Variables declared read-only with declare -r or readonly.
+	Traps            map[string]bool        // Conditions, such as INT and EXIT, the script sets traps for anywhere.
+	Diagnostics      *diagnostics.Collector // Warnings and errors from parsing and generation.
+	Subcommands      []string               // Functions run as subcommands of the program, if combined from several scripts.
+	Sources          []string               // Scripts included with source or ., by path, or as written if not found.
+
+	includes *includer        // Resolves the scripts included with source while the IR is built
+	function *functionScope   // Function whose body is being processed, nil outside functions
+	scopes   []*functionScope // Functions processed so far, for checkDynamicScope
+}
+
+// functionScope tracks the function whose body is being processed, so that
+// assignments to the variables it declares local stay local to it.
+type functionScope struct {
+	function *Function
+	locals   map[string]bool // Variables declared local with local, declare or typeset
+	uses     map[string]bool // Variables the function uses, by name
+	calls    map[string]bool // Commands the function calls, by name
+}
+
+// isLocal reports whether name is declared local to the function whose body
+// is being processed.
+func (ir *IntermediateRepresentation) isLocal(name string) bool {
+	return ir.function != nil && ir.function.locals[name]
+}
+
+// EnvPolicy controls when references to environment variables, i.e. variables
+// the script reads but never assigns, are expanded.
+type EnvPolicy string
+
+const (
+	// EnvRuntime keeps environment references as os.Getenv calls.
+	EnvRuntime EnvPolicy = "runtime"
+	// EnvConvert resolves environment references when the script is converted.
+	EnvConvert EnvPolicy = "convert"
+)
+
+// ParseEnvPolicy parses the name of an environment expansion policy.
+func ParseEnvPolicy(name string) (EnvPolicy, error) {
+	switch policy := EnvPolicy(name); policy {
+	case EnvRuntime, EnvConvert:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown environment policy %q (want %q or %q)", name, EnvRuntime, EnvConvert)
+	}
+}
+
+// envDirective is the comment prefix of environment policy directives, as in
+// "# bash2go:env convert HOME USER".
+const envDirective = "bash2go:env"
+
+// processEnvDirective records the policies declared by a bash2go:env comment.
+func processEnvDirective(ir *IntermediateRepresentation, c *syntax.Comment) {
+	fields := strings.Fields(c.Text)
+	if len(fields) == 0 || fields[0] != envDirective {
+		return
+	}
+
+	pos := newPosition(c.Pos())
+	if len(fields) < 3 {
+		ir.Diagnose(diagnostics.SeverityWarning, pos, diagnostics.CodeDirective,
+			"%s directive needs a policy and at least one variable name", envDirective)
+		return
+	}
+
+	policy, err := ParseEnvPolicy(fields[1])
+	if err != nil {
+		ir.Diagnose(diagnostics.SeverityWarning, pos, diagnostics.CodeDirective, "%v", err)
+		return
+	}
+	for _, name := range fields[2:] {
+		ir.EnvPolicies[name] = policy
+	}
+}
+
+// Diagnose records a diagnostic about the construct at pos.
+func (ir *IntermediateRepresentation) Diagnose(severity diagnostics.Severity, pos Position, code, format string, args ...interface{}) {
+	if ir.Diagnostics == nil {
+		ir.Diagnostics = diagnostics.NewCollector()
+	}
+	if pos.File == "" {
+		pos.File = ir.Filename
+	}
+	ir.Diagnostics.Add(diagnostics.Diagnostic{
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+		File:     pos.File,
+		Line:     pos.Line,
+		Column:   pos.Column,
+	})
 }
 
 // Function represents a Bash function definition.
 type Function struct {
-	Name       string
-	Statements []Statement
-	Parameters []string
-	LocalVars  map[string]string
+	Name       string            `json:"name"`
+	Statements []Statement       `json:"statements,omitempty"`
+	Parameters []string          `json:"parameters,omitempty"`
+	LocalVars  map[string]string `json:"localVars,omitempty"` // Variables declared local with local, declare or typeset.
+	Pos        Position          `json:"pos,omitzero"`
 }
 
 // StatementType identifies the type of a statement.
@@ -40,165 +144,823 @@ const (
 	StatementRedirection
 	StatementBackground
 	StatementReturn
+	StatementArithmetic
+	StatementTest
+	StatementAndOr
+	StatementGetopts
+	StatementBlock
+	StatementNegation
 )
 
 // Statement represents a single statement in the Bash script.
 type Statement struct {
-	Type  StatementType
-	Value interface{} // Command, Assignment, If, Loop, Pipe, Subshell, etc.
+	Type  StatementType `json:"type"`
+	Value interface{}   `json:"value"`        // Command, Assignment, If, Loop, Pipe, Subshell, etc.
+	Pos   Position      `json:"pos,omitzero"` // Location of the statement in the source script.
+}
+
+// Position identifies a location in the original Bash script.
+type Position struct {
+	File   string `json:"file,omitempty"` // Base name of the script; empty for scripts parsed from a string.
+	Line   uint   `json:"line,omitempty"`
+	Column uint   `json:"column,omitempty"`
+}
+
+// IsValid reports whether the position refers to a real source location.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String formats the position as "file:line:col", omitting unknown parts.
+func (p Position) String() string {
+	if !p.IsValid() {
+		return p.File
+	}
+	loc := fmt.Sprintf("%d", p.Line)
+	if p.Column > 0 {
+		loc += fmt.Sprintf(":%d", p.Column)
+	}
+	if p.File == "" {
+		return loc
+	}
+	return p.File + ":" + loc
+}
+
+// newPosition converts a syntax.Pos into a Position.
+func newPosition(pos syntax.Pos) Position {
+	if !pos.IsValid() {
+		return Position{}
+	}
+	return Position{Line: pos.Line(), Column: pos.Col()}
 }
 
 // Command represents a command execution.
 type Command struct {
-	Name      string
-	Args      []string
-	IsBuiltin bool
-	UseGexe   bool
+	Name      string        `json:"name"`
+	Args      []string      `json:"args,omitempty"`
+	IsBuiltin bool          `json:"isBuiltin,omitempty"`
+	UseGexe   bool          `json:"useGexe,omitempty"`
+	Globs     []int         `json:"globs,omitempty"`     // Indices of the arguments that are glob patterns, expanded to the matching file names.
+	Splits    []int         `json:"splits,omitempty"`    // Indices of the arguments whose unquoted expansions are split at $IFS.
+	Stdin     string        `json:"stdin,omitempty"`     // Word of a here-string fed to standard input, as in cmd <<< word.
+	Heredoc   *Heredoc      `json:"heredoc,omitempty"`   // Here-document fed to standard input instead, as in cmd <<EOF.
+	Redirects []Redirection `json:"redirects,omitempty"` // Other redirections of the command's streams, in order, as in cmd > file 2>&1.
+	Env       []Assignment  `json:"env,omitempty"`       // Assignments prefixed to the command, as in NAME=value cmd, which only apply while it runs.
+	Pos       Position      `json:"pos,omitzero"`        // Location of the command in the source script.
+}
+
+// Heredoc represents the body of a here-document. The body of one with an
+// unquoted delimiter has expansions; the leading tabs of its lines are already
+// stripped for <<-.
+type Heredoc struct {
+	Parts []HeredocPart `json:"parts,omitempty"`
+}
+
+// HeredocPart is a piece of the body of a here-document.
+type HeredocPart struct {
+	Text      string `json:"text,omitempty"`
+	Expansion bool   `json:"expansion,omitempty"` // Text is an expansion, as in $name or $(cmd), rather than literal text.
 }
 
 // Assignment represents a variable assignment.
 type Assignment struct {
-	Name     string
-	Value    string
-	IsLocal  bool
-	IsExport bool
+	Name     string         `json:"name"`
+	Value    string         `json:"value,omitempty"`
+	Key      string         `json:"key,omitempty"`      // Subscript of an array element assignment, as in name[key]=value.
+	Elements []ArrayElement `json:"elements,omitempty"` // Elements of a compound assignment, as in name=([key]=value).
+	IsAssoc  bool           `json:"isAssoc,omitempty"`  // Declares or replaces a whole associative array.
+	IsLocal  bool           `json:"isLocal,omitempty"`  // Assigns to a variable declared local to its function.
+	IsExport bool           `json:"isExport,omitempty"`
+	Append   bool           `json:"append,omitempty"` // Appends to the variable, as in name+=value.
+}
+
+// ArrayElement is an element of a compound array assignment.
+type ArrayElement struct {
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value,omitempty"`
 }
 
 // If represents an if-then-else statement.
 type If struct {
-	Condition     []Statement
-	ThenBlock     []Statement
-	ElseBlock     []Statement
-	ElifBlocks    [][2][]Statement // Each element is a [condition, then-block] pair.
-	ConditionType string           // "file", "string", "number", "command"
+	Condition     []Statement      `json:"condition,omitempty"`
+	ThenBlock     []Statement      `json:"thenBlock,omitempty"`
+	ElseBlock     []Statement      `json:"elseBlock,omitempty"`
+	ElifBlocks    [][2][]Statement `json:"elifBlocks,omitempty"`    // Each element is a [condition, then-block] pair.
+	ConditionType string           `json:"conditionType,omitempty"` // "file", "string", "number", "command", "arithmetic", "test"
 }
 
 // Loop represents a loop construct (for, while, until).
 type Loop struct {
-	Type      string // "for", "while", "until"
-	Init      []Statement
-	Condition []Statement
-	Update    []Statement
-	Body      []Statement
-	IsRange   bool   // for i in {1..10}
-	RangeVar  string // The loop variable
-	RangeFrom string // Start of range
-	RangeTo   string // End of range
-	IsForEach bool   // for i in items
-	Items     string // The items to iterate over
+	Type      string      `json:"type,omitempty"` // "for", "while", "until"
+	Init      []Statement `json:"init,omitempty"`
+	Condition []Statement `json:"condition,omitempty"`
+	Update    []Statement `json:"update,omitempty"`
+	Body      []Statement `json:"body,omitempty"`
+	IsRange   bool        `json:"isRange,omitempty"`   // for i in {1..10}
+	RangeVar  string      `json:"rangeVar,omitempty"`  // The loop variable
+	RangeFrom string      `json:"rangeFrom,omitempty"` // Start of range
+	RangeTo   string      `json:"rangeTo,omitempty"`   // End of range
+	IsForEach bool        `json:"isForEach,omitempty"` // for i in items
+	Items     string      `json:"items,omitempty"`     // The items to iterate over
+	Words     []string    `json:"words,omitempty"`     // The items as separate words
+	Globs     []int       `json:"globs,omitempty"`     // Indices of the words that are glob patterns
+	Splits    []int       `json:"splits,omitempty"`    // Indices of the words split at $IFS
 }
 
 // Pipe represents a piped command sequence.
 type Pipe struct {
-	Commands []Command
+	Commands []Command `json:"commands,omitempty"`
 }
 
 // Subshell represents a subshell execution.
 type Subshell struct {
-	Statements []Statement
+	Statements []Statement `json:"statements,omitempty"`
+	Vars       []string    `json:"vars,omitempty"` // Script variables assigned in the subshell, restored when it ends.
 }
 
 // Redirection represents input/output redirection.
 type Redirection struct {
-	Op       string // ">", ">>", "<", etc.
-	Command  Command
-	Filename string
+	Op       string   `json:"op,omitempty"` // ">", ">>", "<", etc.
+	Fd       string   `json:"fd,omitempty"` // File descriptor redirected, as in 2>file; empty for the default of the operator.
+	Command  Command  `json:"command"`
+	Filename string   `json:"filename,omitempty"`
+	Pos      Position `json:"pos,omitzero"`
 }
 
 // Background represents a command running in the background.
 type Background struct {
-	Command Command
+	Command Command  `json:"command"`
+	Vars    []string `json:"vars,omitempty"` // Variables the command reads or assigns, whose values the job takes when it starts.
 }
 
 // Return represents a return statement.
 type Return struct {
-	Value string
-	Code  int
+	Value string `json:"value,omitempty"`
+	Code  int    `json:"code,omitempty"`
 }
 
 // BuildIR builds an intermediate representation from a parsed result.
 func BuildIR(result *ParseResult) (*IntermediateRepresentation, error) {
+	ir := newScriptIR(result.Filename)
+
+	// Process the script one top-level statement at a time.
+	processFile(ir, result.File)
+
+	finishIR(ir)
+	return ir, nil
+}
+
+// processFile processes the statements of a whole script into ir, along
+// with the directives in the comments after the last one.
+func processFile(ir *IntermediateRepresentation, file *syntax.File) {
+	for _, stmt := range file.Stmts {
+		ir.MainStatements = append(ir.MainStatements, processStmt(ir, stmt)...)
+	}
+	for i := range file.Last {
+		processEnvDirective(ir, &file.Last[i])
+	}
+}
+
+// BuildIRFromReader parses a Bash script from r and builds its intermediate
+// representation one top-level statement at a time, so that the syntax tree
+// of the whole script is never held in memory. It produces the same IR as
+// ParseBashString followed by BuildIR, and suits very large scripts such as
+// installers with embedded payloads. name is the base name of the script.
+func BuildIRFromReader(r io.Reader, name string) (*IntermediateRepresentation, error) {
+	return buildIRFromReader(r, name, nil)
+}
+
+// buildIRFromReader is BuildIRFromReader with the includer resolving the
+// scripts the script includes, nil for the defaults.
+func buildIRFromReader(r io.Reader, name string, includes *includer) (*IntermediateRepresentation, error) {
+	ir := newScriptIR(name)
+	ir.includes = includes
+
+	// Comments after the last statement are only kept by the parser for the
+	// whole file, so a sentinel statement is appended to carry them. Every
+	// statement is visited once the next one is parsed, which leaves the
+	// sentinel as the last.
+	script := &countingReader{r: r}
+	parser := syntax.NewParser(syntax.Variant(syntax.LangBash), syntax.KeepComments(true))
+	var last *syntax.Stmt
+	err := parser.Stmts(io.MultiReader(script, strings.NewReader("\n:\n")), func(stmt *syntax.Stmt) bool {
+		if last != nil {
+			ir.MainStatements = append(ir.MainStatements, processStmt(ir, last)...)
+		}
+		last = stmt
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if last == nil || uint64(last.Pos().Offset()) <= uint64(script.n) {
+		return nil, fmt.Errorf("unexpected end of script")
+	}
+	for i := range last.Comments {
+		processEnvDirective(ir, &last.Comments[i])
+	}
+
+	finishIR(ir)
+	return ir, nil
+}
+
+// BuildIRFromFile builds the intermediate representation of the Bash script
+// at path with BuildIRFromReader, streaming the file from disk. Scripts it
+// includes with source or . are looked up in its directory and then in the
+// directories of searchPath, and included in the IR.
+func BuildIRFromFile(path string, searchPath ...string) (*IntermediateRepresentation, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return BuildIRFromContent(path, bufio.NewReader(f), searchPath...)
+}
+
+// BuildIRFromContent is BuildIRFromFile for a version of the script at path
+// read from r, such as the one staged in git, rather than from the file.
+func BuildIRFromContent(path string, r io.Reader, searchPath ...string) (*IntermediateRepresentation, error) {
+	includes := &includer{dir: filepath.Dir(path), searchPath: searchPath}
+	if abs, err := filepath.Abs(path); err == nil {
+		includes.stack = []string{abs}
+	}
+	return buildIRFromReader(r, filepath.Base(path), includes)
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// newScriptIR returns an empty IR for the script named name.
+func newScriptIR(name string) *IntermediateRepresentation {
 	ir := NewIntermediateRepresentation()
+	ir.Filename = name
 
 	// Always include these packages.
 	ir.RequiredPackages["fmt"] = true
 	ir.RequiredPackages["os"] = true
+	return ir
+}
 
-	// Walk the AST to build the intermediate representation.
-	syntax.Walk(result.File, func(node syntax.Node) bool {
-		switch x := node.(type) {
-		case *syntax.CallExpr:
-			// Process command call.
-			cmd := processCallExpr(x)
-			ir.MainStatements = append(ir.MainStatements, Statement{
-				Type:  StatementCommand,
-				Value: cmd,
-			})
-		case *syntax.Assign:
-			// Process variable assignment.
-			assign := processAssign(x)
+// processNested processes statements nested in another construct, such as
+// the body of a loop, and returns their statements.
+func processNested(ir *IntermediateRepresentation, stmts []*syntax.Stmt) []Statement {
+	statements := []Statement{}
+	for _, stmt := range stmts {
+		statements = append(statements, processStmt(ir, stmt)...)
+	}
+	return statements
+}
+
+// processStmt processes a statement nested in another construct as a script
+// of its own and returns its statements. Any kind of statement is processed
+// as at the top of the script, compound commands recursing into their bodies
+// through processStmt. What it needs from the script, the variables it
+// assigns and reads and its diagnostics, is recorded in ir.
+func processStmt(ir *IntermediateRepresentation, stmt *syntax.Stmt) []Statement {
+	sub := nestedIR(ir)
+	syntax.Walk(stmt, func(node syntax.Node) bool {
+		return visitNode(sub, node)
+	})
+
+	mergeScript(ir, sub)
+	return sub.MainStatements
+}
+
+// nestedIR returns the intermediate representation statements nested in ir
+// are processed into, sharing the scope of ir: its sourced files, the
+// function being processed and the associative arrays.
+func nestedIR(ir *IntermediateRepresentation) *IntermediateRepresentation {
+	sub := newScriptIR(ir.Filename)
+	sub.includes = ir.includes
+	sub.function = ir.function
+	for k, v := range ir.AssocArrays {
+		sub.AssocArrays[k] = v
+	}
+	return sub
+}
+
+// mergeScript records in ir what the statements of sub, processed as a
+// script of their own, need from the script: the variables they assign and
+// read, the functions they define, the options and traps they set and their
+// diagnostics.
+func mergeScript(ir, sub *IntermediateRepresentation) {
+	for k, v := range sub.Variables {
+		if _, ok := ir.Variables[k]; !ok {
+			ir.Variables[k] = v
+		}
+	}
+	for k, v := range sub.Functions {
+		ir.Functions[k] = v
+	}
+	ir.scopes = append(ir.scopes, sub.scopes...)
+	for k, v := range sub.RequiredPackages {
+		ir.RequiredPackages[k] = ir.RequiredPackages[k] || v
+	}
+	for k, v := range sub.EnvPolicies {
+		ir.EnvPolicies[k] = v
+	}
+	for k, v := range sub.ShellOptions {
+		ir.ShellOptions[k] = v
+	}
+	for k, v := range sub.SpecialVars {
+		ir.SpecialVars[k] = ir.SpecialVars[k] || v
+	}
+	ir.Sources = append(ir.Sources, sub.Sources...)
+	for k, v := range sub.AssocArrays {
+		ir.AssocArrays[k] = ir.AssocArrays[k] || v
+	}
+	for k, v := range sub.IntegerVars {
+		ir.IntegerVars[k] = ir.IntegerVars[k] || v
+	}
+	for k, v := range sub.ReadonlyVars {
+		ir.ReadonlyVars[k] = ir.ReadonlyVars[k] || v
+	}
+	for k, v := range sub.Traps {
+		ir.Traps[k] = ir.Traps[k] || v
+	}
+	for _, d := range sub.Diagnostics.Items() {
+		ir.Diagnostics.Add(d)
+	}
+}
+
+// visitNode records a syntax node in the IR, returning true so that walks
+// descend into its children.
+func visitNode(ir *IntermediateRepresentation, node syntax.Node) bool {
+	switch x := node.(type) {
+	case *syntax.CallExpr:
+		// Assignments without a command are handled as Assign nodes.
+		if len(x.Args) == 0 {
+			break
+		}
+
+		// Process command call. Its prefix assignments only apply to it, so
+		// only their values are walked.
+		addCommand(ir, processCallExpr(x))
+		walkCall(ir, x)
+		return false
+	case *syntax.Stmt:
+		if x.Negated {
+			ir.MainStatements = append(ir.MainStatements, processNegation(ir, x))
+			return false
+		}
+
+		// Commands run in the background, and commands with redirections,
+		// are processed with their statement, which holds the & and the
+		// redirections. So are brace groups, whose redirections apply to
+		// the whole group.
+		call, ok := x.Cmd.(*syntax.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			if x.Background && x.Cmd != nil {
+				ir.Diagnose(diagnostics.SeverityWarning, newPosition(x.Pos()), diagnostics.CodeUnsupported,
+					"only simple commands run in the background; this statement runs in the foreground")
+			}
+			if block, ok := x.Cmd.(*syntax.Block); ok {
+				ir.MainStatements = append(ir.MainStatements, processBlock(ir, x, block))
+				return false
+			}
+
+			// Redirections of other compound commands, such as loops, apply
+			// while they run; those of function declarations to their calls
+			if _, ok := x.Cmd.(*syntax.FuncDecl); !ok && x.Cmd != nil && len(x.Redirs) > 0 {
+				ir.MainStatements = append(ir.MainStatements, processRedirected(ir, x))
+				return false
+			}
+			break
+		}
+		switch {
+		case x.Background:
+			background := processBackground(x, call)
+			recordVars(ir, background.Value.(Background).Command)
+			ir.MainStatements = append(ir.MainStatements, background)
+		case len(x.Redirs) > 0:
+			addCommand(ir, processStmtCall(x, call))
+		default:
+			return true
+		}
+
+		// Walk the rest of the statement, but not the command again
+		visit := func(node syntax.Node) bool { return visitNode(ir, node) }
+		walkCall(ir, call)
+		for _, redirect := range x.Redirs {
+			syntax.Walk(redirect.Word, visit)
+			if redirect.Hdoc != nil {
+				syntax.Walk(redirect.Hdoc, visit)
+			}
+		}
+		return false
+	case *syntax.Assign:
+		// Options of declare, such as -A, are assignments without a name
+		if x.Name == nil {
+			break
+		}
+
+		// A declaration without a value, as in readonly name, keeps the
+		// value of the variable; one of a local variable declares it
+		local := ir.isLocal(x.Name.Value)
+		if x.Naked && !ir.AssocArrays[x.Name.Value] && !local {
+			if _, ok := ir.Variables[x.Name.Value]; !ok {
+				ir.Variables[x.Name.Value] = ""
+			}
+			break
+		}
+
+		// Process variable assignment. Variables a function assigns
+		// without declaring them local are, as in Bash, variables of the
+		// script.
+		assign := processAssign(x)
+		assign.IsAssoc = ir.AssocArrays[assign.Name] && assign.Key == ""
+		if local {
+			assign.IsLocal = true
+			if _, ok := ir.function.function.LocalVars[assign.Name]; !ok {
+				ir.function.function.LocalVars[assign.Name] = assign.Value
+			}
+		} else {
 			ir.Variables[assign.Name] = assign.Value
+		}
+		ir.MainStatements = append(ir.MainStatements, Statement{
+			Type:  StatementAssignment,
+			Value: assign,
+			Pos:   newPosition(x.Pos()),
+		})
+	case *syntax.FuncDecl:
+		// Process function declaration, with the statements of its body.
+		function := processFunction(ir, x)
+		ir.Functions[function.Name] = function
+		ir.MainStatements = append(ir.MainStatements, Statement{
+			Type:  StatementFunction,
+			Value: function,
+			Pos:   newPosition(x.Pos()),
+		})
+		return false
+	case *syntax.IfClause:
+		// Process if statement, with the statements nested in it.
+		ifStmt := processIfClause(ir, x)
+		ir.MainStatements = append(ir.MainStatements, Statement{
+			Type:  StatementIf,
+			Value: ifStmt,
+			Pos:   newPosition(x.Pos()),
+		})
+		return false
+	case *syntax.WhileClause:
+		// The standard getopts loop is processed with its case arms
+		if clause, call := getoptsLoop(x); clause != nil {
+			ir.MainStatements = append(ir.MainStatements, processGetopts(ir, x, clause, call))
+			return false
+		}
+
+		// Process while loop, with the statements nested in it.
+		loop := processWhileClause(ir, x)
+		ir.MainStatements = append(ir.MainStatements, Statement{
+			Type:  StatementLoop,
+			Value: loop,
+			Pos:   newPosition(x.Pos()),
+		})
+		return false
+	case *syntax.ForClause:
+		// Process for loop, with the statements nested in it. The loop
+		// variable is a script variable that keeps its last value after
+		// the loop.
+		loop := processForClause(ir, x)
+		if loop.IsForEach {
+			ir.Variables[loop.RangeVar] = ""
+		}
+		if iter, ok := x.Loop.(*syntax.WordIter); ok && !iter.InPos.IsValid() {
+			// for name; do iterates over the positional parameters
+			ir.SpecialVars["@"] = true
+		}
+		addArithmVars(ir, x.Loop)
+		ir.MainStatements = append(ir.MainStatements, Statement{
+			Type:  StatementLoop,
+			Value: loop,
+			Pos:   newPosition(x.Pos()),
+		})
+
+		// Walk the words of the loop, but not its body again
+		syntax.Walk(x.Loop, func(node syntax.Node) bool {
+			return visitNode(ir, node)
+		})
+		return false
+	case *syntax.BinaryCmd:
+		// Process binary command (e.g., pipe).
+		if x.Op == syntax.Pipe {
+			// The commands of the pipeline are processed with it
+			pipe := processPipe(x)
 			ir.MainStatements = append(ir.MainStatements, Statement{
-				Type:  StatementAssignment,
-				Value: assign,
-			})
-		case *syntax.FuncDecl:
-			// Process function declaration.
-			function := processFunction(x)
-			ir.Functions[function.Name] = function
-			ir.MainStatements = append(ir.MainStatements, Statement{
-				Type:  StatementFunction,
-				Value: function,
-			})
-		case *syntax.IfClause:
-			// Process if statement.
-			ifStmt := processIfClause(x)
-			ir.MainStatements = append(ir.MainStatements, Statement{
-				Type:  StatementIf,
-				Value: ifStmt,
-			})
-		case *syntax.WhileClause:
-			// Process while loop.
-			loop := processWhileClause(x)
-			ir.MainStatements = append(ir.MainStatements, Statement{
-				Type:  StatementLoop,
-				Value: loop,
-			})
-		case *syntax.ForClause:
-			// Process for loop.
-			loop := processForClause(x)
-			ir.MainStatements = append(ir.MainStatements, Statement{
-				Type:  StatementLoop,
-				Value: loop,
+				Type:  StatementPipe,
+				Value: pipe,
+				Pos:   newPosition(x.Pos()),
 			})
-		case *syntax.BinaryCmd:
-			// Process binary command (e.g., pipe).
-			if x.Op == syntax.Pipe {
-				pipe := processPipe(x)
-				ir.MainStatements = append(ir.MainStatements, Statement{
-					Type:  StatementPipe,
-					Value: pipe,
-				})
+			walkPipe(ir, x)
+		} else if isAndOr(x) {
+			// The operands are processed with the list
+			ir.MainStatements = append(ir.MainStatements, processAndOr(ir, x))
+		} else {
+			ir.Diagnose(diagnostics.SeverityWarning, newPosition(x.Pos()), diagnostics.CodeUnsupported,
+				"%s list is translated as a plain statement sequence", x.Op)
+			ir.MainStatements = append(ir.MainStatements, processStmt(ir, x.X)...)
+			ir.MainStatements = append(ir.MainStatements, processStmt(ir, x.Y)...)
+		}
+		return false
+	case *syntax.Subshell:
+		// Process subshell, with the statements nested in it.
+		subshell := processSubshell(ir, x)
+		ir.MainStatements = append(ir.MainStatements, Statement{
+			Type:  StatementSubshell,
+			Value: subshell,
+			Pos:   newPosition(x.Pos()),
+		})
+		return false
+	case *syntax.Redirect:
+		// Process redirection.
+		redirection := processRedirection(x)
+		ir.MainStatements = append(ir.MainStatements, Statement{
+			Type:  StatementRedirection,
+			Value: redirection,
+			Pos:   newPosition(x.Pos()),
+		})
+	case *syntax.Comment:
+		processEnvDirective(ir, x)
+	case *syntax.ParamExp:
+		if x.Param != nil && (specialVars[x.Param.Value] || IsPositional(x.Param.Value)) {
+			ir.SpecialVars[x.Param.Value] = true
+		}
+		// ${name:=default} assigns to name, which makes it a script variable
+		if p, err := processParamExp(x); err == nil && p.IsAssign() && isVarName(p.Name) {
+			if _, ok := ir.Variables[p.Name]; !ok {
+				ir.Variables[p.Name] = ""
 			}
-		case *syntax.Subshell:
-			// Process subshell.
-			subshell := processSubshell(x)
-			ir.MainStatements = append(ir.MainStatements, Statement{
-				Type:  StatementSubshell,
-				Value: subshell,
-			})
-		case *syntax.Redirect:
-			// Process redirection.
-			redirection := processRedirection(x)
-			ir.MainStatements = append(ir.MainStatements, Statement{
-				Type:  StatementRedirection,
-				Value: redirection,
-			})
 		}
-		return true
+		// syntax.Walk skips the offset and length of ${name:offset:length}
+		if x.Slice != nil {
+			visit := func(node syntax.Node) bool { return visitNode(ir, node) }
+			for _, expr := range []syntax.ArithmExpr{x.Slice.Offset, x.Slice.Length} {
+				if expr != nil {
+					syntax.Walk(expr, visit)
+				}
+			}
+		}
+	case *syntax.CmdSubst:
+		// The commands run when the word with the substitution is expanded
+		processCmdSubst(ir, x.Stmts)
+		return false
+	case *syntax.DeclClause:
+		// Variables declared in a function are local to it
+		if ir.function != nil {
+			for _, name := range localDeclNames(x) {
+				ir.function.locals[name] = true
+			}
+		}
+
+		// Associative arrays are recorded before the walk visits the
+		// assignments of the declaration
+		if names := assocDeclNames(x); len(names) > 0 {
+			for _, name := range names {
+				ir.AssocArrays[name] = true
+			}
+			break
+		}
+		if recordDeclAttrs(ir, x) || x.Variant.Value == "local" {
+			// Functions declare their locals; Bash rejects local elsewhere
+			break
+		}
+		ir.Diagnose(diagnostics.SeverityWarning, newPosition(x.Pos()), diagnostics.CodeUnsupported,
+			"%s is translated as a plain assignment", x.Variant.Value)
+	case *syntax.ArithmCmd:
+		addArithmVars(ir, x)
+		ir.MainStatements = append(ir.MainStatements, processArithmCmd(x))
+	case *syntax.LetClause:
+		addArithmVars(ir, x)
+		ir.MainStatements = append(ir.MainStatements, processLetClause(x)...)
+	case *syntax.ArithmExp:
+		addArithmVars(ir, x)
+	case *syntax.TestClause:
+		ir.MainStatements = append(ir.MainStatements, processTestClause(x))
+	case *syntax.ProcSubst:
+		// Only input process substitutions are translated; their commands
+		// run when the word with the substitution is expanded
+		if x.Op != syntax.CmdIn {
+			ir.Diagnose(diagnostics.SeverityError, newPosition(x.Pos()), diagnostics.CodeUnsupported,
+				"unsupported construct: output %s >( )", describeNode(x))
+			return false
+		}
+		processCmdSubst(ir, x.Stmts)
+		return false
+	case *syntax.CaseClause:
+		// The arms cannot run without the case statement choosing one
+		ir.Diagnose(diagnostics.SeverityError, newPosition(node.Pos()), diagnostics.CodeUnsupported,
+			"unsupported construct: %s", describeNode(node))
+		return false
+	case *syntax.CoprocClause:
+		// The command still runs, in the foreground
+		ir.Diagnose(diagnostics.SeverityError, newPosition(node.Pos()), diagnostics.CodeUnsupported,
+			"unsupported construct: %s", describeNode(node))
+		ir.MainStatements = append(ir.MainStatements, processStmt(ir, x.Stmt)...)
+		return false
+	case *syntax.TimeClause:
+		// The command still runs, untimed
+		ir.Diagnose(diagnostics.SeverityError, newPosition(node.Pos()), diagnostics.CodeUnsupported,
+			"unsupported construct: %s", describeNode(node))
+		if x.Stmt != nil {
+			ir.MainStatements = append(ir.MainStatements, processStmt(ir, x.Stmt)...)
+		}
+		return false
+	}
+	return true
+}
+
+// addArithmVars records the variables assigned by arithmetic expressions
+// below node as script variables.
+func addArithmVars(ir *IntermediateRepresentation, node syntax.Node) {
+	if node == nil {
+		return
+	}
+	for _, name := range arithmAssignments(node) {
+		if _, ok := ir.Variables[name]; !ok {
+			ir.Variables[name] = ""
+		}
+	}
+}
+
+// addCommand adds a command to the main statements of the IR.
+func addCommand(ir *IntermediateRepresentation, cmd Command) {
+	switch cmd.Name {
+	case "shopt":
+		recordShopt(ir, cmd)
+	case "source", ".":
+		if includeSource(ir, cmd) {
+			return
+		}
+	case "set":
+		recordSet(ir, cmd)
+	case "trap":
+		recordTrap(ir, cmd)
+	}
+	recordVars(ir, cmd)
+	ir.MainStatements = append(ir.MainStatements, Statement{
+		Type:  StatementCommand,
+		Value: cmd,
+		Pos:   cmd.Pos,
 	})
+}
 
-	return ir, nil
+// recordVars records the variables the command assigns, which is needed
+// wherever it runs, in a pipeline or in the background too.
+func recordVars(ir *IntermediateRepresentation, cmd Command) {
+	switch cmd.Name {
+	case "shift":
+		// shift changes the positional parameters the script reads
+		ir.SpecialVars["@"] = true
+	case "read":
+		for _, name := range readNames(cmd) {
+			if _, ok := ir.Variables[name]; !ok && !ir.isLocal(name) {
+				ir.Variables[name] = ""
+			}
+		}
+	}
+}
+
+// finishIR fills in what can only be set once every statement is in the IR.
+func finishIR(ir *IntermediateRepresentation) {
+	setFile(ir.MainStatements, ir.Filename)
+	for _, function := range ir.Functions {
+		if function.Pos.File != "" {
+			// Included from another script
+			continue
+		}
+		function.Pos.File = ir.Filename
+		setFile(function.Statements, ir.Filename)
+	}
+	checkDynamicScope(ir)
+}
+
+// setFile records the source file name on every statement and command
+// position in statements, recursing into nested bodies. The process functions
+// only see syntax nodes, so the file name is filled in once the IR is built.
+func setFile(statements []Statement, file string) {
+	for i := range statements {
+		stmt := &statements[i]
+		if stmt.Pos.File != "" {
+			// Statements included from another script name it already
+			continue
+		}
+		stmt.Pos.File = file
+
+		switch v := stmt.Value.(type) {
+		case Command:
+			v.Pos.File = file
+			stmt.Value = v
+		case If:
+			setFile(v.Condition, file)
+			setFile(v.ThenBlock, file)
+			setFile(v.ElseBlock, file)
+			for _, elif := range v.ElifBlocks {
+				setFile(elif[0], file)
+				setFile(elif[1], file)
+			}
+		case Loop:
+			setFile(v.Init, file)
+			setFile(v.Condition, file)
+			setFile(v.Update, file)
+			setFile(v.Body, file)
+		case Pipe:
+			for j := range v.Commands {
+				v.Commands[j].Pos.File = file
+			}
+		case Subshell:
+			setFile(v.Statements, file)
+		case Negation:
+			setFile(v.Statements, file)
+		case Block:
+			setFile(v.Statements, file)
+			for j := range v.Redirects {
+				v.Redirects[j].Pos.File = file
+			}
+		case Getopts:
+			for _, option := range v.Options {
+				setFile(option.Body, file)
+			}
+			setFile(v.Invalid, file)
+			setFile(v.Missing, file)
+		case Redirection:
+			v.Command.Pos.File = file
+			stmt.Value = v
+		case Background:
+			v.Command.Pos.File = file
+			stmt.Value = v
+		}
+	}
+}
+
+// specialVars lists the special variables whose use is recorded in the IR, so
+// that the generator only tracks their values when a script reads them.
+var specialVars = map[string]bool{
+	"?":           true,
+	"!":           true,
+	"$":           true,
+	"0":           true,
+	"_":           true,
+	"FUNCNAME":    true,
+	"BASH_SOURCE": true,
+	"PIPESTATUS":  true,
+}
+
+// IsPositional reports whether name is a positional parameter, such as 1,
+// or one of the special parameters #, @ and * describing all of them.
+func IsPositional(name string) bool {
+	switch name {
+	case "#", "@", "*":
+		return true
+	case "", "0":
+		return false
+	}
+	for _, r := range name {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// recordShopt records the options a shopt command sets or unsets. The
+// generated code tracks options at runtime; this is the static view used to
+// decide which behaviors a script relies on.
+func recordShopt(ir *IntermediateRepresentation, cmd Command) {
+	mode := ""
+	for _, arg := range cmd.Args {
+		switch {
+		case arg == "-s" || arg == "-u":
+			mode = arg
+		case strings.HasPrefix(arg, "-"):
+		case mode != "":
+			// Without -s or -u, shopt only queries the option.
+			ir.ShellOptions[arg] = mode == "-s"
+		}
+	}
+}
+
+// describeNode returns a short Bash-level description of a syntax node.
+func describeNode(node syntax.Node) string {
+	switch node.(type) {
+	case *syntax.CaseClause:
+		return "case statement"
+	case *syntax.CoprocClause:
+		return "coproc"
+	case *syntax.TimeClause:
+		return "time keyword"
+	case *syntax.ProcSubst:
+		return "process substitution"
+	default:
+		return fmt.Sprintf("%T", node)
+	}
 }
 
 // processCallExpr processes a call expression (command).
@@ -207,7 +969,8 @@ func processCallExpr(x *syntax.CallExpr) Command {
 		Name:      "",
 		Args:      []string{},
 		IsBuiltin: false,
-		UseGexe:   true, // Default to using gexe for external commands.
+		UseGexe:   true, // External commands run as subprocesses.
+		Pos:       newPosition(x.Pos()),
 	}
 
 	if len(x.Args) > 0 {
@@ -217,7 +980,7 @@ func processCallExpr(x *syntax.CallExpr) Command {
 
 		// Check if this is a builtin command that can be directly translated to Go.
 		switch cmd.Name {
-		case "echo", "printf", "cd", "pwd", "exit", "return", "test", "[", "source", "export", "read":
+		case "echo", "printf", "cd", "pwd", "exit", "return", "test", "[", "source", ".", "export", "read", "set", "shopt", "trap", "shift", "wait", "exec":
 			cmd.IsBuiltin = true
 			cmd.UseGexe = false
 		}
@@ -225,8 +988,21 @@ func processCallExpr(x *syntax.CallExpr) Command {
 		// Extract arguments from the remaining arguments.
 		for i := 1; i < len(x.Args); i++ {
 			arg := extractWordValue(x.Args[i])
+			if isGlobWord(x.Args[i]) {
+				arg = extractPatternValue(x.Args[i])
+				cmd.Globs = append(cmd.Globs, len(cmd.Args))
+			} else if isSplitWord(x.Args[i]) {
+				cmd.Splits = append(cmd.Splits, len(cmd.Args))
+			}
 			cmd.Args = append(cmd.Args, arg)
 		}
+
+		// Prefix assignments of array elements are not supported by Bash
+		for _, assign := range x.Assigns {
+			if assign.Name != nil && assign.Index == nil && assign.Array == nil {
+				cmd.Env = append(cmd.Env, processAssign(assign))
+			}
+		}
 	}
 
 	if debug {
@@ -236,6 +1012,122 @@ func processCallExpr(x *syntax.CallExpr) Command {
 	return cmd
 }
 
+// processHeredoc processes the body of a here-document. Literal text is
+// unescaped as in bash: only \$, \`, \\ and line continuations are escapes,
+// and nothing is when the delimiter is quoted.
+func processHeredoc(redirect *syntax.Redirect) *Heredoc {
+	heredoc := &Heredoc{}
+	if redirect.Hdoc == nil {
+		return heredoc
+	}
+	// Any quoting of the delimiter, as in <<'EOF' or <<\EOF, makes the body
+	// literal
+	quoted := false
+	for _, part := range redirect.Word.Parts {
+		if lit, ok := part.(*syntax.Lit); !ok || strings.Contains(lit.Value, `\`) {
+			quoted = true
+		}
+	}
+	lineStart := true
+	for _, part := range redirect.Hdoc.Parts {
+		lit, isLit := part.(*syntax.Lit)
+		if !isLit {
+			text := nodeText(part)
+			if p, ok := part.(*syntax.ParamExp); ok {
+				text = paramExpValue(p, "${"+p.Param.Value+"}")
+			}
+			heredoc.Parts = append(heredoc.Parts, HeredocPart{Text: text, Expansion: true})
+			lineStart = false
+			continue
+		}
+		value := lit.Value
+		if redirect.Op == syntax.DashHdoc {
+			value = stripHeredocTabs(value, lineStart)
+		}
+		if !quoted {
+			value = unescapeHeredoc(value)
+		}
+		if n := len(heredoc.Parts); n > 0 && !heredoc.Parts[n-1].Expansion {
+			heredoc.Parts[n-1].Text += value
+		} else {
+			heredoc.Parts = append(heredoc.Parts, HeredocPart{Text: value})
+		}
+		lineStart = strings.HasSuffix(lit.Value, "\n")
+	}
+	return heredoc
+}
+
+// stripHeredocTabs removes the leading tabs of the lines of text from the
+// body of a <<- here-document. lineStart reports whether text starts a line,
+// rather than continuing one after an expansion or a line continuation.
+func stripHeredocTabs(text string, lineStart bool) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if i > 0 || lineStart {
+			lines[i] = strings.TrimLeft(line, "\t")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// unescapeHeredoc removes the backslashes escaping $, ` and \ in literal
+// text of the body of a here-document with an unquoted delimiter.
+func unescapeHeredoc(text string) string {
+	var value strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' && i+1 < len(text) && strings.IndexByte("$`\\", text[i+1]) >= 0 {
+			i++
+		}
+		value.WriteByte(text[i])
+	}
+	return value.String()
+}
+
+// processBackground processes a simple command run in the background with &.
+func processBackground(stmt *syntax.Stmt, call *syntax.CallExpr) Statement {
+	background := Background{Command: processStmtCall(stmt, call)}
+	vars := make(map[string]bool)
+	syntax.Walk(stmt, func(node syntax.Node) bool {
+		if p, ok := node.(*syntax.ParamExp); ok && p.Param != nil && isVarName(p.Param.Value) {
+			vars[p.Param.Value] = true
+		}
+		return true
+	})
+	if background.Command.Name == "read" {
+		for _, name := range readNames(background.Command) {
+			vars[name] = true
+		}
+	}
+	for name := range vars {
+		background.Vars = append(background.Vars, name)
+	}
+	sort.Strings(background.Vars)
+	return Statement{
+		Type:  StatementBackground,
+		Value: background,
+		Pos:   newPosition(stmt.Pos()),
+	}
+}
+
+// processStmtCall processes the command of a statement, feeding it the
+// statement's here-string or here-document, if any, on standard input, and
+// recording its other redirections.
+func processStmtCall(stmt *syntax.Stmt, call *syntax.CallExpr) Command {
+	cmd := processCallExpr(call)
+	for _, redirect := range stmt.Redirs {
+		// Of several here-strings and here-documents, the last one wins
+		switch redirect.Op {
+		case syntax.WordHdoc:
+			cmd.Stdin, cmd.Heredoc = extractWordValue(redirect.Word), nil
+		case syntax.Hdoc, syntax.DashHdoc:
+			cmd.Stdin, cmd.Heredoc = "", processHeredoc(redirect)
+		default:
+			cmd.Redirects = append(cmd.Redirects, processRedirection(redirect))
+		}
+	}
+	return cmd
+}
+
 // extractWordValue extracts the string value from a Word.
 func extractWordValue(word *syntax.Word) string {
 	var value strings.Builder
@@ -244,13 +1136,20 @@ func extractWordValue(word *syntax.Word) string {
 		case *syntax.Lit:
 			value.WriteString(p.Value)
 		case *syntax.ParamExp:
-			value.WriteString("$" + p.Param.Value)
+			simple := "$" + p.Param.Value
+			if len(p.Param.Value) > 1 && IsPositional(p.Param.Value) {
+				// $10 would be $1 followed by 0
+				simple = "${" + p.Param.Value + "}"
+			}
+			value.WriteString(paramExpValue(p, simple))
 		case *syntax.DblQuoted:
 			value.WriteString(extractDblQuotedValue(p))
 		case *syntax.SglQuoted:
-			value.WriteString(p.Value)
-		case *syntax.CmdSubst:
-			value.WriteString("$(command)")
+			// Dollar signs in single quotes are escaped, as in \$, so that
+			// the generator does not expand them
+			value.WriteString(strings.ReplaceAll(p.Value, "$", `\$`))
+		case *syntax.CmdSubst, *syntax.ProcSubst, *syntax.ArithmExp:
+			value.WriteString(nodeText(p))
 		}
 	}
 	return value.String()
@@ -264,19 +1163,93 @@ func extractDblQuotedValue(dq *syntax.DblQuoted) string {
 		case *syntax.Lit:
 			value.WriteString(p.Value)
 		case *syntax.ParamExp:
-			value.WriteString("${" + p.Param.Value + "}")
+			value.WriteString(paramExpValue(p, "${"+p.Param.Value+"}"))
+		case *syntax.ArithmExp, *syntax.CmdSubst:
+			value.WriteString(nodeText(p))
 		}
 	}
 	return value.String()
 }
 
+// paramExpValue returns the text of a parameter expansion for the generator
+// to expand. Plain variable references are returned as simple, and any other
+// expansion, such as ${map[key]}, as written in the script.
+func paramExpValue(p *syntax.ParamExp, simple string) string {
+	if isPlainParamExp(p) {
+		return simple
+	}
+	return nodeText(p)
+}
+
+// isPlainParamExp reports whether p is a plain variable reference, as in
+// $name or ${name}.
+func isPlainParamExp(p *syntax.ParamExp) bool {
+	return p.Index == nil && !p.Excl && !p.Length && !p.Width && p.Slice == nil && p.Repl == nil && p.Exp == nil
+}
+
+// nodeText returns the source text of a syntax node.
+func nodeText(node syntax.Node) string {
+	var text strings.Builder
+	syntax.NewPrinter().Print(&text, node)
+	return text.String()
+}
+
+// subscriptValue returns the text of an array subscript, without the quotes
+// of a quoted associative array key.
+func subscriptValue(index syntax.ArithmExpr) string {
+	if word, ok := index.(*syntax.Word); ok {
+		return extractWordValue(word)
+	}
+	return nodeText(index)
+}
+
+// assocDeclNames returns the variables a declaration such as declare -A or
+// local -A declares as associative arrays, or nil for other declarations.
+func assocDeclNames(x *syntax.DeclClause) []string {
+	assoc := false
+	var names []string
+	for _, arg := range x.Args {
+		if arg.Name == nil {
+			if flag := arg.Value.Lit(); strings.HasPrefix(flag, "-") && strings.Contains(flag, "A") {
+				assoc = true
+			}
+			continue
+		}
+		names = append(names, arg.Name.Value)
+	}
+	if !assoc {
+		return nil
+	}
+	return names
+}
+
+// localDeclNames returns the variables a declaration in a function makes
+// local to it: those of local, and of declare and typeset without -g.
+func localDeclNames(x *syntax.DeclClause) []string {
+	switch x.Variant.Value {
+	case "local", "declare", "typeset":
+	default:
+		return nil
+	}
+	var names []string
+	for _, arg := range x.Args {
+		if arg.Name == nil {
+			if flag := arg.Value.Lit(); strings.HasPrefix(flag, "-") && strings.Contains(flag, "g") {
+				return nil
+			}
+			continue
+		}
+		names = append(names, arg.Name.Value)
+	}
+	return names
+}
+
 // processAssign processes a variable assignment.
 func processAssign(x *syntax.Assign) Assignment {
 	assign := Assignment{
-		Name:     x.Name.Value,
-		Value:    "",
-		IsLocal:  false,
-		IsExport: x.Append,
+		Name:   x.Name.Value,
+		Value:  "",
+		Append: x.Append,
 	}
 
 	// Extract the value directly
@@ -286,189 +1259,193 @@ func processAssign(x *syntax.Assign) Assignment {
 		assign.Value = extractWordValue(x.Value)
 	}
 
+	// Array elements and compound assignments
+	if x.Index != nil {
+		assign.Key = subscriptValue(x.Index)
+	}
+	if x.Array != nil {
+		for _, elem := range x.Array.Elems {
+			element := ArrayElement{}
+			if elem.Index != nil {
+				element.Key = subscriptValue(elem.Index)
+			}
+			if elem.Value != nil {
+				element.Value = extractWordValue(elem.Value)
+			}
+			assign.Elements = append(assign.Elements, element)
+		}
+	}
+
 	return assign
 }
 
-// processFunction processes a function declaration.
-func processFunction(x *syntax.FuncDecl) *Function {
+// processFunction processes a function declaration, with the statements of
+// its body. What they need from the script, including the variables they
+// assign without declaring them local, which like in Bash are variables of
+// the script, is recorded in ir.
+func processFunction(ir *IntermediateRepresentation, x *syntax.FuncDecl) *Function {
 	function := &Function{
 		Name:       x.Name.Value,
 		Statements: []Statement{},
 		Parameters: []string{},
 		LocalVars:  make(map[string]string),
+		Pos:        newPosition(x.Pos()),
+	}
+	if x.Body == nil {
+		return function
 	}
 
-	// Process function body.
-	if x.Body != nil {
-		syntax.Walk(x.Body, func(node syntax.Node) bool {
-			switch y := node.(type) {
-			case *syntax.CallExpr:
-				cmd := processCallExpr(y)
-				function.Statements = append(function.Statements, Statement{
-					Type:  StatementCommand,
-					Value: cmd,
-				})
-			case *syntax.Assign:
-				assign := processAssign(y)
-				function.LocalVars[assign.Name] = assign.Value
-				function.Statements = append(function.Statements, Statement{
-					Type:  StatementAssignment,
-					Value: assign,
-				})
-			}
-			return true
-		})
+	// The braces around the body do not make a command group, unless the
+	// function has redirections, which apply to the whole body
+	stmts := []*syntax.Stmt{x.Body}
+	if body, ok := x.Body.Cmd.(*syntax.Block); ok && len(x.Body.Redirs) == 0 {
+		stmts = body.Stmts
 	}
 
+	outer := ir.function
+	ir.function = &functionScope{function: function, locals: make(map[string]bool)}
+	function.Statements = processNested(ir, stmts)
+	recordUses(ir.function, x.Body)
+	ir.scopes = append(ir.scopes, ir.function)
+	ir.function = outer
 	return function
 }
 
-// processIfClause processes an if statement.
-func processIfClause(x *syntax.IfClause) If {
+// processIfClause processes an if statement, with its elif and else
+// clauses.
+func processIfClause(ir *IntermediateRepresentation, x *syntax.IfClause) If {
 	ifStmt := If{
-		Condition:     []Statement{},
-		ThenBlock:     []Statement{},
-		ElseBlock:     []Statement{},
-		ElifBlocks:    [][2][]Statement{},
-		ConditionType: "command", // Default condition type
-	}
-
-	// Process condition
-	if len(x.Cond) > 0 {
-		for _, cond := range x.Cond {
-			if cond.Cmd != nil {
-				switch c := cond.Cmd.(type) {
-				case *syntax.CallExpr:
-					cmd := processCallExpr(c)
-					ifStmt.Condition = append(ifStmt.Condition, Statement{
-						Type:  StatementCommand,
-						Value: cmd,
-					})
-
-					// Try to determine the condition type
-					if cmd.Name == "test" || cmd.Name == "[" {
-						// This is a test condition
-						if len(cmd.Args) >= 2 {
-							switch cmd.Args[0] {
-							case "-f", "-d", "-e":
-								ifStmt.ConditionType = "file"
-							case "-z", "-n", "=", "!=":
-								ifStmt.ConditionType = "string"
-							case "-eq", "-ne", "-lt", "-le", "-gt", "-ge":
-								ifStmt.ConditionType = "number"
-							}
-						}
-					}
-				}
-			}
+		ElseBlock:  []Statement{},
+		ElifBlocks: [][2][]Statement{},
+	}
+
+	// Process condition and then block
+	ifStmt.Condition, ifStmt.ConditionType = processCondition(ir, x.Cond)
+	ifStmt.ThenBlock = processNested(ir, x.Then)
+
+	// Each elif holds the next clause, up to an else, which has no
+	// condition
+	for clause := x.Else; clause != nil; clause = clause.Else {
+		if !clause.ThenPos.IsValid() {
+			ifStmt.ElseBlock = processNested(ir, clause.Then)
+			break
 		}
+		condition, _ := processCondition(ir, clause.Cond)
+		ifStmt.ElifBlocks = append(ifStmt.ElifBlocks, [2][]Statement{condition, processNested(ir, clause.Then)})
 	}
 
-	// Process then block
-	if len(x.Then) > 0 {
-		for _, stmt := range x.Then {
-			if stmt.Cmd != nil {
-				switch c := stmt.Cmd.(type) {
-				case *syntax.CallExpr:
-					cmd := processCallExpr(c)
-					ifStmt.ThenBlock = append(ifStmt.ThenBlock, Statement{
-						Type:  StatementCommand,
-						Value: cmd,
-					})
+	return ifStmt
+}
+
+// processCondition processes the condition of an if or elif clause and
+// returns its statements and its type.
+func processCondition(ir *IntermediateRepresentation, stmts []*syntax.Stmt) ([]Statement, string) {
+	condition := []Statement{}
+	conditionType := "command" // Default condition type
+	for _, cond := range stmts {
+		statements := processStmt(ir, cond)
+		condition = append(condition, statements...)
+		if cond.Negated {
+			continue
+		}
+		switch cond.Cmd.(type) {
+		case *syntax.ArithmCmd:
+			conditionType = "arithmetic"
+		case *syntax.TestClause:
+			conditionType = "test"
+		case *syntax.CallExpr:
+			if len(statements) == 1 && statements[0].Type == StatementCommand {
+				if t := testConditionType(statements[0].Value.(Command)); t != "" {
+					conditionType = t
 				}
 			}
 		}
 	}
+	return condition, conditionType
+}
 
-	// Process else block - fix for x.Else.Cmd undefined
-	if x.Else != nil {
-		// x.Else is a *syntax.IfClause, not an interface
-		// This means it's an elif clause, not an else clause
-		// Process it as another if statement
-		elifStmt := processIfClause(x.Else)
-		ifStmt.ElifBlocks = append(ifStmt.ElifBlocks, [][2][]Statement{
-			{elifStmt.Condition, elifStmt.ThenBlock},
-		}...)
+// testConditionType returns the type of the condition of an if statement
+// testing with test or [: "file", "string" or "number", or an empty string
+// for other commands and tests.
+func testConditionType(cmd Command) string {
+	if cmd.Name != "test" && cmd.Name != "[" || len(cmd.Args) < 2 {
+		return ""
 	}
-
-	return ifStmt
+	switch cmd.Args[0] {
+	case "-f", "-d", "-e":
+		return "file"
+	case "-z", "-n", "=", "!=":
+		return "string"
+	case "-eq", "-ne", "-lt", "-le", "-gt", "-ge":
+		return "number"
+	}
+	return ""
 }
 
 // processWhileClause processes a while loop.
-func processWhileClause(x *syntax.WhileClause) Loop {
+func processWhileClause(ir *IntermediateRepresentation, x *syntax.WhileClause) Loop {
 	loop := Loop{
-		Type:      "while",
-		Condition: []Statement{},
-		Body:      []Statement{},
+		Type: "while",
 	}
 
 	// Process condition.
-	for _, cond := range x.Cond {
-		if cond.Cmd != nil {
-			switch c := cond.Cmd.(type) {
-			case *syntax.CallExpr:
-				cmd := processCallExpr(c)
-				loop.Condition = append(loop.Condition, Statement{
-					Type:  StatementCommand,
-					Value: cmd,
-				})
-			}
-		}
-	}
+	loop.Condition = processNested(ir, x.Cond)
 
 	// Process body.
-	for _, stmt := range x.Do {
-		if stmt.Cmd != nil {
-			switch c := stmt.Cmd.(type) {
-			case *syntax.CallExpr:
-				cmd := processCallExpr(c)
-				loop.Body = append(loop.Body, Statement{
-					Type:  StatementCommand,
-					Value: cmd,
-				})
-			}
-		}
-	}
+	loop.Body = processNested(ir, x.Do)
 
 	return loop
 }
 
 // processForClause processes a for loop.
-func processForClause(x *syntax.ForClause) Loop {
+func processForClause(ir *IntermediateRepresentation, x *syntax.ForClause) Loop {
 	loop := Loop{
 		Type: "for",
 		Body: []Statement{},
 	}
 
 	// Process loop variable
-	if x.Loop != nil {
-		// This is a for-each loop
+	if iter, ok := x.Loop.(*syntax.WordIter); ok {
+		// for name in words
 		loop.IsForEach = true
-
-		// Based on the documentation, ForClause has a Loop field which is a syntax.Loop
-		// The Loop struct likely has a Name field for the loop variable
-		loop.RangeVar = "i" // Default variable name if we can't extract it
-
-		// We can't directly access x.Items, so we'll use a placeholder
-		loop.Items = "items" // Placeholder for items
-	}
-
-	// Process body
-	if len(x.Do) > 0 {
-		for _, stmt := range x.Do {
-			if stmt.Cmd != nil {
-				switch c := stmt.Cmd.(type) {
-				case *syntax.CallExpr:
-					cmd := processCallExpr(c)
-					loop.Body = append(loop.Body, Statement{
-						Type:  StatementCommand,
-						Value: cmd,
-					})
-				}
+		loop.RangeVar = iter.Name.Value
+		items := make([]string, len(iter.Items))
+		for i, item := range iter.Items {
+			items[i] = extractWordValue(item)
+		}
+		loop.Items = strings.Join(items, " ")
+		for i, item := range iter.Items {
+			if isGlobWord(item) {
+				items[i] = extractPatternValue(item)
+				loop.Globs = append(loop.Globs, i)
+			} else if isSplitWord(item) {
+				loop.Splits = append(loop.Splits, i)
+			}
+		}
+		loop.Words = items
+		if !iter.InPos.IsValid() {
+			loop.Items = "${@}"
+			loop.Words = []string{"${@}"}
+		}
+	} else if c, ok := x.Loop.(*syntax.CStyleLoop); ok {
+		// for ((init; cond; post)), each of which may be omitted
+		for _, part := range []struct {
+			expr syntax.ArithmExpr
+			list *[]Statement
+		}{{c.Init, &loop.Init}, {c.Cond, &loop.Condition}, {c.Post, &loop.Update}} {
+			if part.expr != nil {
+				*part.list = []Statement{{
+					Type:  StatementArithmetic,
+					Value: *processArithm(part.expr),
+					Pos:   newPosition(part.expr.Pos()),
+				}}
 			}
 		}
 	}
 
+	// Process body
+	loop.Body = processNested(ir, x.Do)
+
 	return loop
 }
 
@@ -496,11 +1473,12 @@ func flattenPipe(node syntax.Node) []Command {
 			commands = append(commands, flattenPipe(n.Y)...)
 		}
 	case *syntax.Stmt:
-		// Process the command in the statement
-		if n.Cmd != nil {
-			if call, ok := n.Cmd.(*syntax.CallExpr); ok {
-				commands = append(commands, processCallExpr(call))
-			}
+		// Process the command in the statement; a | b | c nests a | b
+		switch cmd := n.Cmd.(type) {
+		case *syntax.CallExpr:
+			commands = append(commands, processStmtCall(n, cmd))
+		case *syntax.BinaryCmd:
+			commands = append(commands, flattenPipe(cmd)...)
 		}
 	case *syntax.CallExpr:
 		// Process the call expression directly
@@ -510,25 +1488,62 @@ func flattenPipe(node syntax.Node) []Command {
 	return commands
 }
 
-// processSubshell processes a subshell.
-func processSubshell(x *syntax.Subshell) Subshell {
-	subshell := Subshell{
-		Statements: []Statement{},
+// walkPipe records what the commands of a pipeline need from the script,
+// walking their words without processing them as statements of their own.
+// Stages other than simple commands are reported as unsupported.
+func walkPipe(ir *IntermediateRepresentation, x *syntax.BinaryCmd) {
+	visit := func(node syntax.Node) bool { return visitNode(ir, node) }
+	for _, stmt := range []*syntax.Stmt{x.X, x.Y} {
+		switch cmd := stmt.Cmd.(type) {
+		case *syntax.BinaryCmd:
+			if cmd.Op == syntax.Pipe {
+				walkPipe(ir, cmd)
+				continue
+			}
+		case *syntax.CallExpr:
+			recordVars(ir, processCallExpr(cmd))
+			walkCall(ir, cmd)
+			for _, redirect := range stmt.Redirs {
+				syntax.Walk(redirect.Word, visit)
+				if redirect.Hdoc != nil {
+					syntax.Walk(redirect.Hdoc, visit)
+				}
+			}
+			continue
+		}
+		ir.Diagnose(diagnostics.SeverityError, newPosition(stmt.Pos()), diagnostics.CodeUnsupported,
+			"unsupported construct: %s in a pipeline", describeNode(stmt.Cmd))
 	}
+}
 
-	// Process statements in the subshell.
-	for _, stmt := range x.Stmts {
-		if stmt.Cmd != nil {
-			switch c := stmt.Cmd.(type) {
-			case *syntax.CallExpr:
-				cmd := processCallExpr(c)
-				subshell.Statements = append(subshell.Statements, Statement{
-					Type:  StatementCommand,
-					Value: cmd,
-				})
-			}
+// walkCall records what the words of a command need from the script: its
+// arguments and the values of its prefix assignments, which are not
+// assignments of the script.
+func walkCall(ir *IntermediateRepresentation, x *syntax.CallExpr) {
+	visit := func(node syntax.Node) bool { return visitNode(ir, node) }
+	for _, assign := range x.Assigns {
+		if assign.Value != nil {
+			syntax.Walk(assign.Value, visit)
 		}
 	}
+	for _, word := range x.Args {
+		syntax.Walk(word, visit)
+	}
+}
+
+// processSubshell processes a subshell.
+func processSubshell(ir *IntermediateRepresentation, x *syntax.Subshell) Subshell {
+	subshell := Subshell{}
+
+	// Process statements in the subshell, recording the variables they
+	// assign so that the generated code can restore them
+	body := nestedIR(ir)
+	subshell.Statements = processNested(body, x.Stmts)
+	for name := range body.Variables {
+		subshell.Vars = append(subshell.Vars, name)
+	}
+	sort.Strings(subshell.Vars)
+	mergeScript(ir, body)
 
 	return subshell
 }
@@ -538,6 +1553,11 @@ func processRedirection(x *syntax.Redirect) Redirection {
 	redirection := Redirection{
 		Op:       x.Op.String(),
 		Filename: "",
+		Pos:      newPosition(x.Pos()),
+	}
+
+	if x.N != nil {
+		redirection.Fd = x.N.Value
 	}
 
 	// Extract the filename
@@ -559,5 +1579,13 @@ func NewIntermediateRepresentation() *IntermediateRepresentation {
 		Functions:        make(map[string]*Function),
 		MainStatements:   []Statement{},
 		RequiredPackages: make(map[string]bool),
+		EnvPolicies:      make(map[string]EnvPolicy),
+		ShellOptions:     make(map[string]bool),
+		SpecialVars:      make(map[string]bool),
+		AssocArrays:      make(map[string]bool),
+		IntegerVars:      make(map[string]bool),
+		ReadonlyVars:     make(map[string]bool),
+		Traps:            make(map[string]bool),
+		Diagnostics:      diagnostics.NewCollector(),
 	}
 }
@@ -0,0 +1,54 @@
+package parser
+
+import "mvdan.cc/sh/v3/syntax"
+
+// Block is a group of commands run in the current shell, as in
+// { cmd1; cmd2; } > file.
+type Block struct {
+	Statements []Statement   `json:"statements,omitempty"`
+	Redirects  []Redirection `json:"redirects,omitempty"` // Redirections applied to the whole group, in order.
+}
+
+// processBlock processes a brace group and the redirections of its
+// statement. What its statements need from the script is recorded in ir.
+func processBlock(ir *IntermediateRepresentation, stmt *syntax.Stmt, x *syntax.Block) Statement {
+	block := Block{Statements: processNested(ir, x.Stmts)}
+	block.Redirects = processStmtRedirects(ir, stmt)
+	return Statement{
+		Type:  StatementBlock,
+		Value: block,
+		Pos:   newPosition(stmt.Pos()),
+	}
+}
+
+// processRedirected processes a compound command with redirections, such as
+// a loop reading a file, into a block holding the command, so that the
+// redirections apply while it runs.
+func processRedirected(ir *IntermediateRepresentation, stmt *syntax.Stmt) Statement {
+	inner := *stmt
+	inner.Redirs = nil
+	block := Block{Statements: processStmt(ir, &inner)}
+	block.Redirects = processStmtRedirects(ir, stmt)
+	return Statement{
+		Type:  StatementBlock,
+		Value: block,
+		Pos:   newPosition(stmt.Pos()),
+	}
+}
+
+// processStmtRedirects processes the redirections of a statement, recording
+// the expansions in their file names.
+func processStmtRedirects(ir *IntermediateRepresentation, stmt *syntax.Stmt) []Redirection {
+	var redirects []Redirection
+	for _, redirect := range stmt.Redirs {
+		redirects = append(redirects, processRedirection(redirect))
+
+		// Expansions in the file name are part of the script
+		if redirect.Word != nil {
+			syntax.Walk(redirect.Word, func(node syntax.Node) bool {
+				return visitNode(ir, node)
+			})
+		}
+	}
+	return redirects
+}
@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"sort"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// processCmdSubst records what the commands of a command or process
+// substitution need from the script: the variables they assign and read, and
+// the diagnostics for them. The commands themselves stay in the word the
+// substitution is part of, to run when the word is expanded.
+func processCmdSubst(ir *IntermediateRepresentation, stmts []*syntax.Stmt) {
+	processNested(ir, stmts)
+}
+
+// ParseCommandSubstitution parses the commands of a command or process
+// substitution, the text between $( or <( and ), or of a trap handler into
+// statements. pos is the
+// position of the substitution in the script of ir, which the statement
+// positions refer to. Diagnostics for the commands were recorded when ir was
+// built.
+func ParseCommandSubstitution(ir *IntermediateRepresentation, text string, pos Position) ([]Statement, error) {
+	subshell, err := ParseSubshell(ir, text, pos)
+	return subshell.Statements, err
+}
+
+// ParseSubshell parses the commands of a command or process substitution
+// like ParseCommandSubstitution into the subshell they run in, with the
+// script variables they assign, which the subshell restores when it ends.
+func ParseSubshell(ir *IntermediateRepresentation, text string, pos Position) (Subshell, error) {
+	// Blank lines in front put the commands on the line of the substitution
+	if pos.Line > 1 {
+		text = strings.Repeat("\n", int(pos.Line)-1) + text
+	}
+	result, err := ParseBashString(text)
+	if err != nil {
+		return Subshell{}, err
+	}
+	result.Filename = ir.Filename
+	sub, err := BuildIR(result)
+	if err != nil {
+		return Subshell{}, err
+	}
+	subshell := Subshell{Statements: sub.MainStatements}
+	for name := range sub.Variables {
+		subshell.Vars = append(subshell.Vars, name)
+	}
+	sort.Strings(subshell.Vars)
+	return subshell, nil
+}
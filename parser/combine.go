@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+)
+
+// CombineCommands combines the IRs of several scripts into the IR of one
+// program that runs each script as a subcommand. The statements of the
+// script irs[i] become the function names[i], and functions, variables and
+// diagnostics of all scripts are merged. It is an error for a script to
+// define a function with the name of a command, or for two scripts to define
+// the same function.
+func CombineCommands(names []string, irs []*IntermediateRepresentation) (*IntermediateRepresentation, error) {
+	if len(names) != len(irs) {
+		return nil, fmt.Errorf("%d command names for %d scripts", len(names), len(irs))
+	}
+
+	combined := NewIntermediateRepresentation()
+	for i, ir := range irs {
+		name := names[i]
+		if _, ok := combined.Functions[name]; ok {
+			return nil, fmt.Errorf("command %s is defined twice", name)
+		}
+
+		for fname, fn := range ir.Functions {
+			if _, ok := combined.Functions[fname]; ok || slices.Contains(names, fname) {
+				return nil, fmt.Errorf("function %s of the %s script conflicts with another command or function", fname, name)
+			}
+			combined.Functions[fname] = fn
+		}
+		combined.Functions[name] = &Function{
+			Name:       name,
+			Statements: ir.MainStatements,
+			LocalVars:  make(map[string]string),
+		}
+
+		for k, v := range ir.Variables {
+			if _, ok := combined.Variables[k]; !ok {
+				combined.Variables[k] = v
+			}
+		}
+		for k, v := range ir.RequiredPackages {
+			combined.RequiredPackages[k] = combined.RequiredPackages[k] || v
+		}
+		for k, v := range ir.EnvPolicies {
+			combined.EnvPolicies[k] = v
+		}
+		for k, v := range ir.ShellOptions {
+			combined.ShellOptions[k] = v
+		}
+		for k, v := range ir.SpecialVars {
+			combined.SpecialVars[k] = combined.SpecialVars[k] || v
+		}
+		combined.Sources = append(combined.Sources, ir.Sources...)
+		for k, v := range ir.AssocArrays {
+			combined.AssocArrays[k] = combined.AssocArrays[k] || v
+		}
+		for k, v := range ir.IntegerVars {
+			combined.IntegerVars[k] = combined.IntegerVars[k] || v
+		}
+		for k, v := range ir.ReadonlyVars {
+			combined.ReadonlyVars[k] = combined.ReadonlyVars[k] || v
+		}
+		for k, v := range ir.Traps {
+			combined.Traps[k] = combined.Traps[k] || v
+		}
+		for _, d := range ir.Diagnostics.Items() {
+			combined.Diagnostics.Add(d)
+		}
+	}
+	combined.Subcommands = append([]string(nil), names...)
+	return combined, nil
+}
@@ -0,0 +1,51 @@
+package parser
+
+import (
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// declFlags returns the letters of the options of a declaration, such as
+// "ir" for declare -i -r. The readonly builtin counts as declare -r.
+func declFlags(x *syntax.DeclClause) string {
+	var flags strings.Builder
+	if x.Variant.Value == "readonly" {
+		flags.WriteString("r")
+	}
+	for _, arg := range x.Args {
+		if arg.Name == nil && arg.Value != nil {
+			if flag := arg.Value.Lit(); strings.HasPrefix(flag, "-") {
+				flags.WriteString(flag[1:])
+			}
+		}
+	}
+	return flags.String()
+}
+
+// recordDeclAttrs records the integer and read-only attributes a
+// declaration gives its variables. It reports false if the declaration
+// has other effects, which are not translated.
+func recordDeclAttrs(ir *IntermediateRepresentation, x *syntax.DeclClause) bool {
+	switch x.Variant.Value {
+	case "declare", "typeset", "local", "readonly":
+	default:
+		return false
+	}
+	flags := declFlags(x)
+	if strings.Trim(flags, "irg") != "" {
+		return false
+	}
+	for _, arg := range x.Args {
+		if arg.Name == nil {
+			continue
+		}
+		if strings.Contains(flags, "i") {
+			ir.IntegerVars[arg.Name.Value] = true
+		}
+		if strings.Contains(flags, "r") {
+			ir.ReadonlyVars[arg.Name.Value] = true
+		}
+	}
+	return true
+}
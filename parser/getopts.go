@@ -0,0 +1,118 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/TFMV/bash2go/diagnostics"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// Getopts is the standard loop parsing the options of a script:
+//
+//	while getopts "ab:" opt; do
+//	  case $opt in
+//	    a) ... ;;
+//	    b) ... "$OPTARG" ... ;;
+//	    \?) ... ;;
+//	  esac
+//	done
+type Getopts struct {
+	Spec    string          `json:"spec"`              // The option letters, as given to getopts.
+	Name    string          `json:"name"`              // The variable set to each option letter.
+	Options []GetoptsOption `json:"options,omitempty"` // The options of Spec, in order.
+	Invalid []Statement     `json:"invalid,omitempty"` // Statements of the \? or * arm, run for invalid options.
+	Missing []Statement     `json:"missing,omitempty"` // Statements of the : arm, run for missing option arguments in silent mode.
+}
+
+// GetoptsOption is an option of a getopts loop.
+type GetoptsOption struct {
+	Letter string      `json:"letter"`
+	HasArg bool        `json:"hasArg,omitempty"` // Whether the option takes an argument, given in OPTARG.
+	Body   []Statement `json:"body,omitempty"`   // Statements of the case arm for the option.
+}
+
+// getoptsOptions returns the options of a getopts option spec. A leading
+// colon, selecting silent error reporting, is skipped.
+func getoptsOptions(spec string) []GetoptsOption {
+	var options []GetoptsOption
+	for _, r := range strings.TrimPrefix(spec, ":") {
+		if r == ':' {
+			if len(options) > 0 {
+				options[len(options)-1].HasArg = true
+			}
+			continue
+		}
+		options = append(options, GetoptsOption{Letter: string(r)})
+	}
+	return options
+}
+
+// getoptsLoop returns the case statement of x and the getopts command of
+// its condition if x is the standard getopts loop, whose body is a single
+// case statement on the option variable, or nil otherwise.
+func getoptsLoop(x *syntax.WhileClause) (*syntax.CaseClause, *syntax.CallExpr) {
+	if x.Until || len(x.Cond) != 1 || len(x.Do) != 1 {
+		return nil, nil
+	}
+	call, ok := x.Cond[0].Cmd.(*syntax.CallExpr)
+	if !ok || len(call.Args) != 3 || extractWordValue(call.Args[0]) != "getopts" {
+		return nil, nil
+	}
+	spec, name := extractWordValue(call.Args[1]), extractWordValue(call.Args[2])
+	if strings.ContainsAny(spec, "$`") || !syntax.ValidName(name) {
+		return nil, nil
+	}
+	clause, ok := x.Do[0].Cmd.(*syntax.CaseClause)
+	if !ok || extractWordValue(clause.Word) != "$"+name {
+		return nil, nil
+	}
+	return clause, call
+}
+
+// processGetopts processes the standard getopts loop. The option variable,
+// OPTARG and OPTIND become script variables, and what the case arms need
+// from the script is recorded in ir.
+func processGetopts(ir *IntermediateRepresentation, x *syntax.WhileClause, clause *syntax.CaseClause, call *syntax.CallExpr) Statement {
+	getopts := Getopts{
+		Spec: extractWordValue(call.Args[1]),
+		Name: extractWordValue(call.Args[2]),
+	}
+	getopts.Options = getoptsOptions(getopts.Spec)
+	for _, name := range []string{getopts.Name, "OPTARG", "OPTIND"} {
+		if _, ok := ir.Variables[name]; !ok {
+			ir.Variables[name] = ""
+		}
+	}
+
+	for _, item := range clause.Items {
+		body := processNested(ir, item.Stmts)
+		for _, pattern := range item.Patterns {
+			letter := strings.ReplaceAll(extractWordValue(pattern), `\`, "")
+			switch letter {
+			case "?", "*":
+				getopts.Invalid = body
+				continue
+			case ":":
+				getopts.Missing = body
+				continue
+			}
+			found := false
+			for i := range getopts.Options {
+				if getopts.Options[i].Letter == letter {
+					getopts.Options[i].Body = body
+					found = true
+				}
+			}
+			if !found {
+				ir.Diagnose(diagnostics.SeverityWarning, newPosition(pattern.Pos()), diagnostics.CodeUnsupported,
+					"case arm %s is not an option of getopts %q and never runs", letter, getopts.Spec)
+			}
+		}
+	}
+
+	return Statement{
+		Type:  StatementGetopts,
+		Value: getopts,
+		Pos:   newPosition(x.Pos()),
+	}
+}
@@ -0,0 +1,37 @@
+package parser
+
+import (
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// isGlobWord reports whether a word is a glob pattern, which the shell
+// replaces with the names of the files it matches. Only unquoted *, ? and
+// [...] are pattern characters; glob characters in the values of variables
+// are not considered.
+func isGlobWord(word *syntax.Word) bool {
+	for _, part := range word.Parts {
+		if lit, ok := part.(*syntax.Lit); ok && hasGlobChars(lit.Value) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasGlobChars reports whether s has an unescaped *, ? or bracket expression.
+func hasGlobChars(s string) bool {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '*', '?':
+			return true
+		case '[':
+			if strings.IndexByte(s[i+1:], ']') >= 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
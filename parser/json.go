@@ -0,0 +1,234 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+
+	"github.com/TFMV/bash2go/diagnostics"
+)
+
+// IRFormatVersion is the version of the JSON encoding of the intermediate
+// representation. It changes whenever a document of one version would be
+// read differently by another.
+const IRFormatVersion = 1
+
+// statementTypeNames are the names of statement types in JSON, indexed by
+// StatementType.
+var statementTypeNames = []string{
+	StatementCommand:     "command",
+	StatementAssignment:  "assignment",
+	StatementIf:          "if",
+	StatementLoop:        "loop",
+	StatementPipe:        "pipe",
+	StatementSubshell:    "subshell",
+	StatementFunction:    "function",
+	StatementRedirection: "redirection",
+	StatementBackground:  "background",
+	StatementReturn:      "return",
+	StatementArithmetic:  "arithmetic",
+	StatementTest:        "test",
+	StatementAndOr:       "andOr",
+	StatementGetopts:     "getopts",
+	StatementBlock:       "block",
+	StatementNegation:    "negation",
+}
+
+// String returns the name of the statement type.
+func (t StatementType) String() string {
+	if t >= 0 && int(t) < len(statementTypeNames) {
+		return statementTypeNames[t]
+	}
+	return fmt.Sprintf("statement(%d)", int(t))
+}
+
+// MarshalJSON encodes the statement type as its name.
+func (t StatementType) MarshalJSON() ([]byte, error) {
+	if t < 0 || int(t) >= len(statementTypeNames) {
+		return nil, fmt.Errorf("unknown statement type %d", int(t))
+	}
+	return json.Marshal(t.String())
+}
+
+// UnmarshalJSON decodes a statement type from its name.
+func (t *StatementType) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	for i, n := range statementTypeNames {
+		if n == name {
+			*t = StatementType(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown statement type %q", name)
+}
+
+// UnmarshalJSON decodes a statement, whose value is decoded into the struct
+// matching its type.
+func (s *Statement) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Type  StatementType   `json:"type"`
+		Value json.RawMessage `json:"value"`
+		Pos   Position        `json:"pos"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	var err error
+	switch raw.Type {
+	case StatementCommand:
+		s.Value, err = decodeValue[Command](raw.Value)
+	case StatementAssignment:
+		s.Value, err = decodeValue[Assignment](raw.Value)
+	case StatementIf:
+		s.Value, err = decodeValue[If](raw.Value)
+	case StatementLoop:
+		s.Value, err = decodeValue[Loop](raw.Value)
+	case StatementPipe:
+		s.Value, err = decodeValue[Pipe](raw.Value)
+	case StatementSubshell:
+		s.Value, err = decodeValue[Subshell](raw.Value)
+	case StatementFunction:
+		var function Function
+		function, err = decodeValue[Function](raw.Value)
+		s.Value = &function
+	case StatementRedirection:
+		s.Value, err = decodeValue[Redirection](raw.Value)
+	case StatementBackground:
+		s.Value, err = decodeValue[Background](raw.Value)
+	case StatementReturn:
+		s.Value, err = decodeValue[Return](raw.Value)
+	case StatementArithmetic:
+		s.Value, err = decodeValue[Arithmetic](raw.Value)
+	case StatementTest:
+		s.Value, err = decodeValue[TestExpr](raw.Value)
+	case StatementAndOr:
+		s.Value, err = decodeValue[AndOr](raw.Value)
+	case StatementGetopts:
+		s.Value, err = decodeValue[Getopts](raw.Value)
+	case StatementBlock:
+		s.Value, err = decodeValue[Block](raw.Value)
+	case StatementNegation:
+		s.Value, err = decodeValue[Negation](raw.Value)
+	}
+	if err != nil {
+		return fmt.Errorf("%s statement at line %d: %w", raw.Type, raw.Pos.Line, err)
+	}
+	s.Type = raw.Type
+	s.Pos = raw.Pos
+	return nil
+}
+
+// decodeValue decodes the value of a statement of type T.
+func decodeValue[T any](data json.RawMessage) (T, error) {
+	var value T
+	if len(data) == 0 || string(data) == "null" {
+		return value, fmt.Errorf("missing value")
+	}
+	err := json.Unmarshal(data, &value)
+	return value, err
+}
+
+// irDocument is the JSON encoding of an IntermediateRepresentation.
+type irDocument struct {
+	Version          int                      `json:"version"`
+	Filename         string                   `json:"filename,omitempty"`
+	Variables        map[string]string        `json:"variables,omitempty"`
+	Functions        map[string]*Function     `json:"functions,omitempty"`
+	MainStatements   []Statement              `json:"mainStatements"`
+	RequiredPackages map[string]bool          `json:"requiredPackages,omitempty"`
+	EnvPolicies      map[string]EnvPolicy     `json:"envPolicies,omitempty"`
+	ShellOptions     map[string]bool          `json:"shellOptions,omitempty"`
+	SpecialVars      map[string]bool          `json:"specialVars,omitempty"`
+	AssocArrays      map[string]bool          `json:"assocArrays,omitempty"`
+	IntegerVars      map[string]bool          `json:"integerVars,omitempty"`
+	ReadonlyVars     map[string]bool          `json:"readonlyVars,omitempty"`
+	Traps            map[string]bool          `json:"traps,omitempty"`
+	Subcommands      []string                 `json:"subcommands,omitempty"`
+	Diagnostics      []diagnostics.Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// MarshalJSON encodes the IR as a versioned JSON document, so that external
+// tools can inspect or edit it and hand it back to the generator.
+func (ir *IntermediateRepresentation) MarshalJSON() ([]byte, error) {
+	doc := irDocument{
+		Version:          IRFormatVersion,
+		Filename:         ir.Filename,
+		Variables:        ir.Variables,
+		Functions:        ir.Functions,
+		MainStatements:   ir.MainStatements,
+		RequiredPackages: ir.RequiredPackages,
+		EnvPolicies:      ir.EnvPolicies,
+		ShellOptions:     ir.ShellOptions,
+		SpecialVars:      ir.SpecialVars,
+		AssocArrays:      ir.AssocArrays,
+		IntegerVars:      ir.IntegerVars,
+		ReadonlyVars:     ir.ReadonlyVars,
+		Traps:            ir.Traps,
+		Subcommands:      ir.Subcommands,
+	}
+	if ir.Diagnostics != nil {
+		doc.Diagnostics = ir.Diagnostics.Items()
+	}
+	return json.Marshal(doc)
+}
+
+// UnmarshalJSON decodes an IR from a JSON document written by MarshalJSON or
+// by an external tool. Omitted maps are left empty, and the packages every
+// generated program imports are always required.
+func (ir *IntermediateRepresentation) UnmarshalJSON(data []byte) error {
+	var doc irDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if doc.Version != IRFormatVersion {
+		return fmt.Errorf("unsupported IR format version %d (want %d)", doc.Version, IRFormatVersion)
+	}
+
+	decoded := NewIntermediateRepresentation()
+	decoded.Filename = doc.Filename
+	decoded.MainStatements = append(decoded.MainStatements, doc.MainStatements...)
+	decoded.Subcommands = doc.Subcommands
+	for name, function := range doc.Functions {
+		if function == nil {
+			return fmt.Errorf("function %s has no definition", name)
+		}
+		if function.Name == "" {
+			function.Name = name
+		}
+		if function.LocalVars == nil {
+			function.LocalVars = make(map[string]string)
+		}
+		decoded.Functions[name] = function
+	}
+	for _, name := range doc.Subcommands {
+		if decoded.Functions[name] == nil {
+			return fmt.Errorf("subcommand %s is not a function", name)
+		}
+	}
+	maps.Copy(decoded.Variables, doc.Variables)
+	maps.Copy(decoded.RequiredPackages, doc.RequiredPackages)
+	for name, policy := range doc.EnvPolicies {
+		if _, err := ParseEnvPolicy(string(policy)); err != nil {
+			return fmt.Errorf("variable %s: %v", name, err)
+		}
+		decoded.EnvPolicies[name] = policy
+	}
+	maps.Copy(decoded.ShellOptions, doc.ShellOptions)
+	maps.Copy(decoded.SpecialVars, doc.SpecialVars)
+	maps.Copy(decoded.AssocArrays, doc.AssocArrays)
+	maps.Copy(decoded.IntegerVars, doc.IntegerVars)
+	maps.Copy(decoded.ReadonlyVars, doc.ReadonlyVars)
+	maps.Copy(decoded.Traps, doc.Traps)
+	decoded.RequiredPackages["fmt"] = true
+	decoded.RequiredPackages["os"] = true
+	for _, d := range doc.Diagnostics {
+		decoded.Diagnostics.Add(d)
+	}
+
+	*ir = *decoded
+	return nil
+}
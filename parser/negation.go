@@ -0,0 +1,21 @@
+package parser
+
+import "mvdan.cc/sh/v3/syntax"
+
+// Negation is a pipeline whose exit status is inverted, as in ! cmd. It
+// succeeds if the pipeline fails and fails if the pipeline succeeds.
+type Negation struct {
+	Statements []Statement `json:"statements,omitempty"` // Statements of the negated pipeline.
+}
+
+// processNegation processes a statement prefixed with !. What the negated
+// pipeline needs from the script is recorded in ir.
+func processNegation(ir *IntermediateRepresentation, stmt *syntax.Stmt) Statement {
+	pipeline := *stmt
+	pipeline.Negated = false
+	return Statement{
+		Type:  StatementNegation,
+		Value: Negation{Statements: processNested(ir, []*syntax.Stmt{&pipeline})},
+		Pos:   newPosition(stmt.Pos()),
+	}
+}
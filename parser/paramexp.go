@@ -0,0 +1,194 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// ParamExpansion is a parameter expansion that does more than reference a
+// variable, such as ${name:-default}, ${name%.txt}, ${name/a/b}, ${name^^},
+// ${name:1:2} and ${#name}.
+type ParamExpansion struct {
+	Name        string      `json:"name"`                  // Name of the parameter.
+	Op          string      `json:"op,omitempty"`          // Operator: ":-", "-", ":=", "=", ":+", "+", "#", "##", "%", "%%", "/", "//", "/#", "/%", "^", "^^", ",", ",," or ":" for substrings.
+	Word        string      `json:"word,omitempty"`        // Word after the operator, as extracted by the parser. Patterns escape their quoted parts with backslashes.
+	Replacement string      `json:"replacement,omitempty"` // Word replacing the pattern of a replacement.
+	Offset      *Arithmetic `json:"offset,omitempty"`      // Offset of a substring.
+	Count       *Arithmetic `json:"count,omitempty"`       // Length of a substring, if given.
+	Length      bool        `json:"length,omitempty"`      // The expansion is the length of the value, as in ${#name}.
+}
+
+// IsAssign reports whether the expansion assigns its word to the parameter,
+// as ${name:=default} does.
+func (p *ParamExpansion) IsAssign() bool {
+	return p.Op == "=" || p.Op == ":="
+}
+
+// IsAlternate reports whether the expansion gives its word if the parameter
+// is set, as ${name:+alternate} does.
+func (p *ParamExpansion) IsAlternate() bool {
+	return p.Op == "+" || p.Op == ":+"
+}
+
+// IsReplacement reports whether the expansion replaces text matching its
+// pattern with Replacement, as ${name/pattern/string} does.
+func (p *ParamExpansion) IsReplacement() bool {
+	return strings.HasPrefix(p.Op, "/")
+}
+
+// IsCaseConversion reports whether the expansion converts the case of the
+// characters matching its pattern, as ${name^^} and ${name,} do.
+func (p *ParamExpansion) IsCaseConversion() bool {
+	return strings.HasPrefix(p.Op, "^") || strings.HasPrefix(p.Op, ",")
+}
+
+// IsRemoval reports whether the expansion removes a matching prefix or
+// suffix, as ${name#pattern} and ${name%pattern} do.
+func (p *ParamExpansion) IsRemoval() bool {
+	return strings.HasPrefix(p.Op, "#") || strings.HasPrefix(p.Op, "%")
+}
+
+// IsSubstring reports whether the expansion is a substring, as in
+// ${name:offset:length}.
+func (p *ParamExpansion) IsSubstring() bool {
+	return p.Op == ":"
+}
+
+// CheckNull reports whether an empty parameter counts as unset, as with the
+// colon in ${name:-default}.
+func (p *ParamExpansion) CheckNull() bool {
+	return strings.HasPrefix(p.Op, ":")
+}
+
+// ParseParamExpansion parses the inside of a ${...} expansion, without the
+// braces, as kept in the words of the IR.
+func ParseParamExpansion(expr string) (*ParamExpansion, error) {
+	file, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Parse(strings.NewReader(": ${"+expr+"}"), "")
+	if err != nil {
+		return nil, err
+	}
+	var param *syntax.ParamExp
+	if len(file.Stmts) == 1 {
+		if call, ok := file.Stmts[0].Cmd.(*syntax.CallExpr); ok && len(call.Args) == 2 && len(call.Args[1].Parts) == 1 {
+			param, _ = call.Args[1].Parts[0].(*syntax.ParamExp)
+		}
+	}
+	if param == nil {
+		return nil, fmt.Errorf("not a parameter expansion: ${%s}", expr)
+	}
+	return processParamExp(param)
+}
+
+// processParamExp converts a parameter expansion into a ParamExpansion.
+func processParamExp(p *syntax.ParamExp) (*ParamExpansion, error) {
+	if p.Param == nil || p.Index != nil || p.Excl || p.Width {
+		return nil, fmt.Errorf("unsupported parameter expansion: %s", nodeText(p))
+	}
+	expansion := &ParamExpansion{Name: p.Param.Value}
+	if p.Repl != nil && p.Exp == nil && p.Slice == nil && !p.Length {
+		return processReplace(p, expansion)
+	}
+	if p.Length && p.Exp == nil && p.Slice == nil {
+		expansion.Length = true
+		return expansion, nil
+	}
+	if p.Slice != nil && p.Exp == nil && !p.Length {
+		// An omitted offset, as in ${name::2}, is 0
+		expansion.Op = ":"
+		expansion.Offset = &Arithmetic{Value: "0"}
+		if p.Slice.Offset != nil {
+			expansion.Offset = processArithm(p.Slice.Offset)
+		}
+		if p.Slice.Length != nil {
+			expansion.Count = processArithm(p.Slice.Length)
+		}
+		return expansion, nil
+	}
+	if p.Exp == nil || p.Length || p.Slice != nil {
+		return nil, fmt.Errorf("unsupported parameter expansion: %s", nodeText(p))
+	}
+	expansion.Op = p.Exp.Op.String()
+	switch p.Exp.Op {
+	case syntax.DefaultUnsetOrNull, syntax.DefaultUnset, syntax.AssignUnsetOrNull, syntax.AssignUnset,
+		syntax.AlternateUnsetOrNull, syntax.AlternateUnset:
+		if p.Exp.Word != nil {
+			expansion.Word = extractWordValue(p.Exp.Word)
+		}
+	case syntax.RemSmallPrefix, syntax.RemLargePrefix, syntax.RemSmallSuffix, syntax.RemLargeSuffix,
+		syntax.UpperFirst, syntax.UpperAll, syntax.LowerFirst, syntax.LowerAll:
+		if p.Exp.Word != nil {
+			expansion.Word = extractPatternValue(p.Exp.Word)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported parameter expansion: %s", nodeText(p))
+	}
+	return expansion, nil
+}
+
+// processReplace completes the ParamExpansion of ${name/pattern/string} and
+// its variants. A pattern starting with # or % is anchored at the start or
+// end of the value, which the operator records as "/#" or "/%". Bash 5.2
+// replaces an unquoted & in the string with the matched text, which is not
+// supported.
+func processReplace(p *syntax.ParamExp, expansion *ParamExpansion) (*ParamExpansion, error) {
+	expansion.Op = "/"
+	if p.Repl.All {
+		expansion.Op = "//"
+	}
+	if orig := p.Repl.Orig; orig != nil && len(orig.Parts) > 0 {
+		parts := slices.Clone(orig.Parts)
+		if lit, ok := parts[0].(*syntax.Lit); ok && !p.Repl.All && (strings.HasPrefix(lit.Value, "#") || strings.HasPrefix(lit.Value, "%")) {
+			expansion.Op += lit.Value[:1]
+			parts[0] = &syntax.Lit{Value: lit.Value[1:]}
+		}
+		expansion.Word = extractPatternValue(&syntax.Word{Parts: parts})
+	}
+	if with := p.Repl.With; with != nil {
+		for _, part := range with.Parts {
+			if lit, ok := part.(*syntax.Lit); ok && strings.Contains(strings.ReplaceAll(lit.Value, `\&`, ""), "&") {
+				return nil, fmt.Errorf("unsupported parameter expansion: & in the replacement of %s", nodeText(p))
+			}
+		}
+		expansion.Replacement = extractWordValue(with)
+	}
+	return expansion, nil
+}
+
+// extractPatternValue extracts a shell pattern from a word. Quoted parts
+// match literally, so their pattern characters are escaped.
+func extractPatternValue(word *syntax.Word) string {
+	var value strings.Builder
+	for _, part := range word.Parts {
+		switch p := part.(type) {
+		case *syntax.SglQuoted:
+			value.WriteString(escapePattern(p.Value))
+		case *syntax.DblQuoted:
+			for _, part := range p.Parts {
+				if lit, ok := part.(*syntax.Lit); ok {
+					value.WriteString(escapePattern(lit.Value))
+				} else {
+					value.WriteString(extractDblQuotedValue(&syntax.DblQuoted{Parts: []syntax.WordPart{part}}))
+				}
+			}
+		default:
+			value.WriteString(extractWordValue(&syntax.Word{Parts: []syntax.WordPart{part}}))
+		}
+	}
+	return value.String()
+}
+
+// escapePattern escapes the characters of s that are special in shell
+// patterns.
+func escapePattern(s string) string {
+	var escaped strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune(`*?[]\`, c) {
+			escaped.WriteByte('\\')
+		}
+		escaped.WriteRune(c)
+	}
+	return escaped.String()
+}
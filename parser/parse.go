@@ -1,8 +1,11 @@
 package parser
 
 import (
+	"bufio"
 	"context"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"mvdan.cc/sh/v3/syntax"
@@ -11,23 +14,38 @@ import (
 // ParseResult contains the parsed AST and any metadata from the Bash script
 type ParseResult struct {
 	File *syntax.File
-	// Additional metadata could be added here
+	// Filename is the base name of the parsed script, used when reporting
+	// source locations. It is empty for scripts parsed from a string.
+	Filename string
 }
 
-// ParseBashScript parses a Bash script file into an AST
+// ParseBashScript parses a Bash script file into an AST. The file is read
+// as it is parsed rather than loaded into memory first.
 func ParseBashScript(filePath string) (*ParseResult, error) {
-	data, err := os.ReadFile(filePath)
+	f, err := os.Open(filePath)
 	if err != nil {
 		return nil, err
 	}
+	defer f.Close()
 
-	return ParseBashString(string(data))
+	result, err := parseBash(bufio.NewReader(f))
+	if err != nil {
+		return nil, err
+	}
+	result.Filename = filepath.Base(filePath)
+
+	return result, nil
 }
 
 // ParseBashString parses a Bash script from a string into an AST
 func ParseBashString(script string) (*ParseResult, error) {
-	parser := syntax.NewParser(syntax.Variant(syntax.LangBash))
-	file, err := parser.Parse(strings.NewReader(script), "")
+	return parseBash(strings.NewReader(script))
+}
+
+// parseBash parses a Bash script from r into an AST
+func parseBash(r io.Reader) (*ParseResult, error) {
+	parser := syntax.NewParser(syntax.Variant(syntax.LangBash), syntax.KeepComments(true))
+	file, err := parser.Parse(r, "")
 	if err != nil {
 		return nil, err
 	}
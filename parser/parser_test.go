@@ -1,11 +1,14 @@
 package parser
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/TFMV/bash2go/diagnostics"
 	"mvdan.cc/sh/v3/syntax"
 )
 
@@ -199,55 +202,1250 @@ func TestExtractWordValue(t *testing.T) {
 	}
 }
 
+// TestExtractWordValueSingleQuoted tests that dollar signs in single quotes
+// are escaped, so that they are not expanded
+func TestExtractWordValueSingleQuoted(t *testing.T) {
+	result, err := ParseBashString(`awk '{print $2}' "$file"'$'`)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	call := result.File.Stmts[0].Cmd.(*syntax.CallExpr)
+
+	for i, want := range []string{`{print \$2}`, `${file}\$`} {
+		if value := extractWordValue(call.Args[i+1]); value != want {
+			t.Errorf("Expected %q, got %q", want, value)
+		}
+	}
+}
+
 // TestProcessAssign tests the processAssign function
 func TestProcessAssign(t *testing.T) {
 	script := `NAME="Test"`
 
-	// Parse the script
+	// Parse the script
+	result, err := ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+
+	// Get the Assign from the parsed script
+	var assign *syntax.Assign
+	for _, stmt := range result.File.Stmts {
+		// In the mvdan.cc/sh/v3/syntax package, assignments are part of the Cmd field
+		// We need to walk through the AST to find the Assign node
+		syntax.Walk(stmt, func(node syntax.Node) bool {
+			if a, ok := node.(*syntax.Assign); ok {
+				assign = a
+				return false // Stop walking
+			}
+			return true // Continue walking
+		})
+		if assign != nil {
+			break
+		}
+	}
+
+	if assign == nil {
+		t.Fatal("Failed to find Assign in parsed script")
+	}
+
+	// Process the Assign
+	assignment := processAssign(assign)
+
+	// Verify the assignment
+	if assignment.Name != "NAME" {
+		t.Fatalf("Expected name 'NAME', got '%s'", assignment.Name)
+	}
+
+	if !strings.Contains(assignment.Value, "Test") {
+		t.Fatalf("Expected value to contain 'Test', got '%s'", assignment.Value)
+	}
+
+	if assignment.IsLocal {
+		t.Fatal("Expected IsLocal to be false")
+	}
+
+	if assignment.IsExport {
+		t.Fatal("Expected IsExport to be false")
+	}
+	if assignment.Append {
+		t.Fatal("Expected Append to be false")
+	}
+}
+
+// TestProcessAssignAppend tests that += appends to a variable rather than
+// exporting it
+func TestProcessAssignAppend(t *testing.T) {
+	result, err := ParseBashString("s+=x")
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	call := result.File.Stmts[0].Cmd.(*syntax.CallExpr)
+
+	assignment := processAssign(call.Assigns[0])
+	if assignment.Name != "s" || assignment.Value != "x" || !assignment.Append || assignment.IsExport {
+		t.Errorf("Expected s+=x to append x to s, got %+v", assignment)
+	}
+}
+
+// TestBuildIRAssocArrays tests recording associative arrays and their
+// assignments and expansions
+func TestBuildIRAssocArrays(t *testing.T) {
+	script := `declare -A colors=([red]=ff0000 ["dark green"]=006400)
+colors[$key]=0000ff
+echo "${colors[red]}" ${!colors[@]}
+`
+	result, err := ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	if !ir.AssocArrays["colors"] {
+		t.Fatalf("Expected colors to be recorded as an associative array, got %v", ir.AssocArrays)
+	}
+	if len(ir.Diagnostics.Items()) != 0 {
+		t.Errorf("Expected no diagnostics, got %v", ir.Diagnostics.Items())
+	}
+
+	var assigns []Assignment
+	var echo Command
+	for _, stmt := range ir.MainStatements {
+		switch v := stmt.Value.(type) {
+		case Assignment:
+			assigns = append(assigns, v)
+		case Command:
+			echo = v
+		}
+	}
+	if len(assigns) != 2 {
+		t.Fatalf("Expected 2 assignments, got %+v", assigns)
+	}
+	want := []ArrayElement{{Key: "red", Value: "ff0000"}, {Key: "dark green", Value: "006400"}}
+	if !assigns[0].IsAssoc || len(assigns[0].Elements) != 2 || assigns[0].Elements[0] != want[0] || assigns[0].Elements[1] != want[1] {
+		t.Errorf("Expected the declaration to assign %v, got %+v", want, assigns[0])
+	}
+	if assigns[1].IsAssoc || assigns[1].Key != "$key" || assigns[1].Value != "0000ff" {
+		t.Errorf("Expected an element assignment to colors[$key], got %+v", assigns[1])
+	}
+	if len(echo.Args) != 2 || echo.Args[0] != "${colors[red]}" || echo.Args[1] != "${!colors[@]}" {
+		t.Errorf("Expected the array expansions to be kept, got %q", echo.Args)
+	}
+}
+
+// TestBuildIRHereString tests attaching here-strings to their commands
+func TestBuildIRHereString(t *testing.T) {
+	script := `grep foo <<< "$VAR"
+f() {
+  tr a-z A-Z <<< hello
+}
+`
+	result, err := ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	if len(ir.Diagnostics.Items()) != 0 {
+		t.Errorf("Expected no unsupported redirections, got %v", ir.Diagnostics.Items())
+	}
+	for _, stmt := range ir.MainStatements {
+		if stmt.Type == StatementRedirection {
+			t.Errorf("Expected the here-string not to be a redirection statement, got %+v", stmt)
+		}
+	}
+	grep := ir.MainStatements[0].Value.(Command)
+	if grep.Name != "grep" || grep.Stdin != "${VAR}" {
+		t.Errorf("Expected grep to read ${VAR}, got %+v", grep)
+	}
+	tr := ir.Functions["f"].Statements[0].Value.(Command)
+	if tr.Name != "tr" || tr.Stdin != "hello" || len(tr.Args) != 2 {
+		t.Errorf("Expected tr a-z A-Z to read hello, got %+v", tr)
+	}
+}
+
+// TestBuildIRArithmetic tests parsing arithmetic into expression trees
+func TestBuildIRArithmetic(t *testing.T) {
+	script := `i=$((i + 1))
+(( total += i * 2 ))
+let "n++" m=3
+for (( k = 0; k < 3; k++ )); do
+  echo "$k"
+done
+`
+	result, err := ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	if len(ir.Diagnostics.Items()) != 0 {
+		t.Errorf("Expected arithmetic to be supported, got %v", ir.Diagnostics.Items())
+	}
+	for _, name := range []string{"i", "total", "n", "m", "k"} {
+		if _, ok := ir.Variables[name]; !ok {
+			t.Errorf("Expected %s to be a script variable", name)
+		}
+	}
+	assign := ir.MainStatements[0].Value.(Assignment)
+	if assign.Value != "$((i + 1))" {
+		t.Errorf("Expected the arithmetic expansion to be kept, got %q", assign.Value)
+	}
+
+	total := ir.MainStatements[1].Value.(Arithmetic)
+	if total.Op != "+=" || total.X.Value != "total" || total.Y.Op != "*" || !total.Y.X.IsVar || total.Y.Y.Value != "2" {
+		t.Errorf("Expected total += i * 2, got %+v", total)
+	}
+	n := ir.MainStatements[2].Value.(Arithmetic)
+	if n.Op != "++" || !n.Post || n.X.Value != "n" {
+		t.Errorf("Expected the quoted n++ to be parsed, got %+v", n)
+	}
+
+	var loop Loop
+	for _, stmt := range ir.MainStatements {
+		if stmt.Type == StatementLoop {
+			loop = stmt.Value.(Loop)
+		}
+	}
+	if len(loop.Init) != 1 || len(loop.Condition) != 1 || len(loop.Update) != 1 || loop.IsForEach {
+		t.Fatalf("Expected a C-style loop, got %+v", loop)
+	}
+	if cond := loop.Condition[0].Value.(Arithmetic); cond.Op != "<" || !cond.IsComparison() {
+		t.Errorf("Expected the condition k < 3, got %+v", cond)
+	}
+}
+
+// TestParseParamExpansion tests parsing default-value, pattern-removal,
+// substring and length parameter expansions
+func TestParseParamExpansion(t *testing.T) {
+	tests := []struct {
+		expr string
+		want ParamExpansion
+	}{
+		{`HOME:-/root`, ParamExpansion{Name: "HOME", Op: ":-", Word: "/root"}},
+		{`dir-"a b"`, ParamExpansion{Name: "dir", Op: "-", Word: "a b"}},
+		{`out:=${HOME}/out`, ParamExpansion{Name: "out", Op: ":=", Word: "$HOME/out"}},
+		{`x=`, ParamExpansion{Name: "x", Op: "="}},
+		{`file%.*`, ParamExpansion{Name: "file", Op: "%", Word: ".*"}},
+		{`file##*/`, ParamExpansion{Name: "file", Op: "##", Word: "*/"}},
+		{`file#"$dir"/'*'`, ParamExpansion{Name: "file", Op: "#", Word: `${dir}/\*`}},
+		{`v:+set`, ParamExpansion{Name: "v", Op: ":+", Word: "set"}},
+		{`v+$x`, ParamExpansion{Name: "v", Op: "+", Word: "$x"}},
+		{`path//"/"/_`, ParamExpansion{Name: "path", Op: "//", Word: "/", Replacement: "_"}},
+		{`name/#?/X`, ParamExpansion{Name: "name", Op: "/#", Word: "?", Replacement: "X"}},
+		{`name/%.txt`, ParamExpansion{Name: "name", Op: "/%", Word: ".txt"}},
+		{`name/'*'/"$y"`, ParamExpansion{Name: "name", Op: "/", Word: `\*`, Replacement: "${y}"}},
+		{`name^^`, ParamExpansion{Name: "name", Op: "^^"}},
+		{`name,[AB]`, ParamExpansion{Name: "name", Op: ",", Word: "[AB]"}},
+		{`#name`, ParamExpansion{Name: "name", Length: true}},
+		{`name:2`, ParamExpansion{Name: "name", Op: ":", Offset: &Arithmetic{Value: "2"}}},
+		{`name::n`, ParamExpansion{Name: "name", Op: ":", Offset: &Arithmetic{Value: "0"}, Count: &Arithmetic{Value: "n", IsVar: true}}},
+		{`name: -3:-1`, ParamExpansion{Name: "name", Op: ":",
+			Offset: &Arithmetic{Op: "-", X: &Arithmetic{Value: "3"}}, Count: &Arithmetic{Op: "-", X: &Arithmetic{Value: "1"}}}},
+	}
+	for _, tt := range tests {
+		got, err := ParseParamExpansion(tt.expr)
+		if err != nil {
+			t.Errorf("ParseParamExpansion(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("ParseParamExpansion(%q) = %+v, want %+v", tt.expr, *got, tt.want)
+		}
+	}
+	for _, expr := range []string{"x", "!x", "x/y/&", "x:?y", "#x[@]"} {
+		if _, err := ParseParamExpansion(expr); err == nil {
+			t.Errorf("Expected ParseParamExpansion(%q) to fail", expr)
+		}
+	}
+
+	result, err := ParseBashString("echo \"${out:=build}\" ${tmp:-/tmp}\n")
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+	if _, ok := ir.Variables["out"]; !ok {
+		t.Error("Expected out, assigned by ${out:=build}, to be a script variable")
+	}
+	if _, ok := ir.Variables["tmp"]; ok {
+		t.Error("Expected tmp, only read by ${tmp:-/tmp}, not to be a script variable")
+	}
+}
+
+// TestBuildIRCommandSubstitution tests keeping command substitutions in
+// their words
+func TestBuildIRCommandSubstitution(t *testing.T) {
+	script := `name=$(echo "world")
+echo "hello $(basename "$dir")" ` + "`date`" + `
+count=$(n=1; echo $n)
+`
+	result, err := ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	if len(ir.Diagnostics.Items()) != 0 {
+		t.Errorf("Expected no placeholders, got %v", ir.Diagnostics.Items())
+	}
+	if len(ir.MainStatements) != 3 {
+		t.Fatalf("Expected the commands of the substitutions not to be statements, got %+v", ir.MainStatements)
+	}
+	if assign := ir.MainStatements[0].Value.(Assignment); assign.Value != `$(echo "world")` {
+		t.Errorf("Expected the substitution to be kept, got %q", assign.Value)
+	}
+	echo := ir.MainStatements[1].Value.(Command)
+	if len(echo.Args) != 2 || echo.Args[0] != `hello $(basename "$dir")` || echo.Args[1] != "$(date)" {
+		t.Errorf("Expected the substitutions to be kept, got %q", echo.Args)
+	}
+	if _, ok := ir.Variables["n"]; !ok {
+		t.Error("Expected n, assigned in a substitution, to be a script variable")
+	}
+
+	statements, err := ParseCommandSubstitution(ir, "n=1; echo $n", Position{Line: 3, Column: 7})
+	if err != nil {
+		t.Fatalf("ParseCommandSubstitution failed: %v", err)
+	}
+	if len(statements) != 2 || statements[1].Value.(Command).Name != "echo" || statements[1].Pos.Line != 3 {
+		t.Errorf("Expected n=1 and echo on line 3, got %+v", statements)
+	}
+}
+
+// TestBuildIRProcessSubstitution tests keeping input process substitutions in
+// their words and reporting output ones
+func TestBuildIRProcessSubstitution(t *testing.T) {
+	script := `diff <(n=1; echo $n) <(echo b)
+tee >(cat)
+`
+	result, err := ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	if len(ir.MainStatements) != 2 {
+		t.Fatalf("Expected the commands of the substitutions not to be statements, got %+v", ir.MainStatements)
+	}
+	diff := ir.MainStatements[0].Value.(Command)
+	if len(diff.Args) != 2 || !strings.HasPrefix(diff.Args[0], "<(") || diff.Args[1] != "<(echo b)" {
+		t.Errorf("Expected the substitutions to be kept, got %q", diff.Args)
+	}
+	if _, ok := ir.Variables["n"]; !ok {
+		t.Error("Expected n, assigned in a substitution, to be a script variable")
+	}
+	items := ir.Diagnostics.Items()
+	if len(items) != 1 || items[0].Severity != diagnostics.SeverityError || items[0].Line != 2 {
+		t.Errorf("Expected an error for >(cat) on line 2, got %v", items)
+	}
+}
+
+// TestBuildIRGlobs tests recording which words are glob patterns
+func TestBuildIRGlobs(t *testing.T) {
+	script := `ls -l *.log "*.txt" file?.txt "$dir"/*.go [ab].sh
+for f in a "b c" *.log; do
+  echo $f
+done
+`
+	result, err := ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	ls := ir.MainStatements[0].Value.(Command)
+	if !reflect.DeepEqual(ls.Globs, []int{1, 3, 4, 5}) {
+		t.Errorf("Expected arguments 1, 3, 4 and 5 to be glob patterns, got %v in %q", ls.Globs, ls.Args)
+	}
+	if ls.Args[2] != "*.txt" || ls.Args[4] != "${dir}/*.go" {
+		t.Errorf("Expected the quoted glob characters to be kept, got %q", ls.Args)
+	}
+
+	loop := ir.MainStatements[1].Value.(Loop)
+	if !reflect.DeepEqual(loop.Words, []string{"a", "b c", "*.log"}) || !reflect.DeepEqual(loop.Globs, []int{2}) {
+		t.Errorf("Expected *.log to be the glob pattern among the items, got %v in %q", loop.Globs, loop.Words)
+	}
+	if loop.Items != "a b c *.log" {
+		t.Errorf("Expected the items to be kept, got %q", loop.Items)
+	}
+}
+
+// TestBuildIRExtendedTest tests converting [[ ]] into expression trees
+func TestBuildIRExtendedTest(t *testing.T) {
+	script := `if [[ $x = foo"*" && ! -f "$f" ]]; then
+  echo yes
+fi
+[[ $s =~ ^[0-9]+"."$ || $n -lt 5 ]]
+`
+	result, err := ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+	if len(ir.Diagnostics.Items()) != 0 {
+		t.Errorf("Expected [[ ]] to be supported, got %v", ir.Diagnostics.Items())
+	}
+
+	ifStmt := ir.MainStatements[0].Value.(If)
+	if ifStmt.ConditionType != "test" || len(ifStmt.Condition) != 1 {
+		t.Fatalf("Expected a test condition, got %+v", ifStmt)
+	}
+	want := TestExpr{Op: "&&",
+		X: &TestExpr{Op: "==", X: &TestExpr{Word: "$x"}, Y: &TestExpr{Word: `foo\*`}},
+		Y: &TestExpr{Op: "!", X: &TestExpr{Op: "-f", X: &TestExpr{Word: "${f}"}}},
+	}
+	if got := ifStmt.Condition[0].Value.(TestExpr); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	var test *TestExpr
+	for _, stmt := range ir.MainStatements {
+		if stmt.Type == StatementTest && stmt.Pos.Line == 4 {
+			value := stmt.Value.(TestExpr)
+			test = &value
+		}
+	}
+	if test == nil || test.Op != "||" || test.X.Op != "=~" || test.X.Y.Word != `^[0-9]+\.$` || !test.Y.IsComparison() {
+		t.Errorf("Expected a regular expression with an escaped quoted dot or a comparison, got %+v", test)
+	}
+}
+
+// TestBuildIRAndOr tests processing command lists joined with && and ||
+func TestBuildIRAndOr(t *testing.T) {
+	script := `mkdir -p out && cd out || echo failed
+[[ -z $name ]] && name=default
+`
+	result, err := ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+	if len(ir.Diagnostics.Items()) != 0 {
+		t.Errorf("Expected lists to be supported, got %v", ir.Diagnostics.Items())
+	}
+	if len(ir.MainStatements) != 2 {
+		t.Fatalf("Expected the operands not to be statements of their own, got %+v", ir.MainStatements)
+	}
+
+	list := ir.MainStatements[0].Value.(AndOr)
+	if list.Op != "||" || len(list.X) != 1 || len(list.Y) != 1 || list.Y[0].Value.(Command).Name != "echo" {
+		t.Fatalf("Expected the && list to be the left operand of ||, got %+v", list)
+	}
+	left := list.X[0].Value.(AndOr)
+	if left.Op != "&&" || left.X[0].Value.(Command).Name != "mkdir" || left.Y[0].Value.(Command).Name != "cd" {
+		t.Errorf("Expected mkdir && cd, got %+v", left)
+	}
+
+	list = ir.MainStatements[1].Value.(AndOr)
+	if list.X[0].Type != StatementTest || list.Y[0].Value.(Assignment).Name != "name" {
+		t.Errorf("Expected a test and an assignment, got %+v", list)
+	}
+	if _, ok := ir.Variables["name"]; !ok {
+		t.Error("Expected name, assigned in a list, to be a script variable")
+	}
+}
+
+// TestBuildIRTrap tests that trap records the conditions it handles and the
+// variables its handler assigns
+func TestBuildIRTrap(t *testing.T) {
+	script := `trap 'rm -f "$tmp"; done=1' EXIT SIGINT 15
+trap - HUP
+trap '' QUIT
+`
+	result, err := ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+	if !reflect.DeepEqual(ir.Traps, map[string]bool{"EXIT": true, "INT": true, "TERM": true}) {
+		t.Errorf("Expected traps for EXIT, INT and TERM, got %v", ir.Traps)
+	}
+	if _, ok := ir.Variables["done"]; !ok {
+		t.Error("Expected done, assigned in a trap handler, to be a script variable")
+	}
+	cmd := ir.MainStatements[0].Value.(Command)
+	if !cmd.IsBuiltin || cmd.UseGexe {
+		t.Errorf("Expected trap to be a builtin, got %+v", cmd)
+	}
+
+	for spec, want := range map[string]string{"0": "EXIT", "sigterm": "TERM", "USR1": "USR1", "BOGUS": "", "99": ""} {
+		if got := TrapCondition(spec); got != want {
+			t.Errorf("TrapCondition(%q) = %q, want %q", spec, got, want)
+		}
+	}
+}
+
+// TestBuildIRGetopts tests detecting the standard getopts loop
+func TestBuildIRGetopts(t *testing.T) {
+	script := `while getopts ":vf:" opt; do
+  case $opt in
+    v) verbose=1 ;;
+    f) file=$OPTARG ;;
+    :) echo "-$OPTARG needs a file"; exit 2 ;;
+    \?) echo "usage: $0 [-v] [-f file]"; exit 2 ;;
+  esac
+done
+`
+	result, err := ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+	if len(ir.Diagnostics.Items()) != 0 {
+		t.Errorf("Expected the getopts loop to be supported, got %v", ir.Diagnostics.Items())
+	}
+	if len(ir.MainStatements) != 1 || ir.MainStatements[0].Type != StatementGetopts {
+		t.Fatalf("Expected a single getopts statement, got %+v", ir.MainStatements)
+	}
+
+	getopts := ir.MainStatements[0].Value.(Getopts)
+	if getopts.Spec != ":vf:" || getopts.Name != "opt" || len(getopts.Options) != 2 {
+		t.Fatalf("Expected options v and f of opt, got %+v", getopts)
+	}
+	v, f := getopts.Options[0], getopts.Options[1]
+	if v.Letter != "v" || v.HasArg || v.Body[0].Value.(Assignment).Name != "verbose" {
+		t.Errorf("Expected -v to set verbose, got %+v", v)
+	}
+	if f.Letter != "f" || !f.HasArg || f.Body[0].Value.(Assignment).Value != "$OPTARG" {
+		t.Errorf("Expected -f to take an argument, got %+v", f)
+	}
+	if len(getopts.Invalid) != 2 {
+		t.Errorf("Expected the \\? arm to hold echo and exit, got %+v", getopts.Invalid)
+	}
+	if len(getopts.Missing) != 2 {
+		t.Errorf("Expected the : arm to hold echo and exit, got %+v", getopts.Missing)
+	}
+	for _, name := range []string{"opt", "OPTARG", "OPTIND", "verbose", "file"} {
+		if _, ok := ir.Variables[name]; !ok {
+			t.Errorf("Expected %s to be a script variable", name)
+		}
+	}
+}
+
+// TestBuildIRPositional tests recording the use of positional parameters
+func TestBuildIRPositional(t *testing.T) {
+	script := `echo "$1" ${10} $#
+for arg; do
+  echo "$arg"
+done
+`
+	result, err := ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+	for _, name := range []string{"1", "10", "#", "@"} {
+		if !ir.SpecialVars[name] {
+			t.Errorf("Expected the use of $%s to be recorded, got %v", name, ir.SpecialVars)
+		}
+	}
+
+	cmd := ir.MainStatements[0].Value.(Command)
+	if want := []string{"${1}", "${10}", "$#"}; !reflect.DeepEqual(cmd.Args, want) {
+		t.Errorf("Expected args %q, got %q", want, cmd.Args)
+	}
+	loop := ir.MainStatements[1].Value.(Loop)
+	if !reflect.DeepEqual(loop.Words, []string{"${@}"}) {
+		t.Errorf("Expected for without in to iterate over \"$@\", got %+v", loop)
+	}
+
+	for name, want := range map[string]bool{"1": true, "12": true, "#": true, "@": true, "*": true, "0": false, "?": false, "x1": false} {
+		if got := IsPositional(name); got != want {
+			t.Errorf("IsPositional(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+// TestBuildIRLocal tests marking the assignments to local variables
+func TestBuildIRLocal(t *testing.T) {
+	script := `f() {
+  local x=1 y
+  y=2
+  declare -g z=3
+  total=4
+}
+`
+	result, err := ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	function := ir.Functions["f"]
+	if !reflect.DeepEqual(function.LocalVars, map[string]string{"x": "1", "y": ""}) {
+		t.Errorf("Expected x and y to be local, got %v", function.LocalVars)
+	}
+	local := map[string]bool{}
+	for _, stmt := range function.Statements {
+		if assign, ok := stmt.Value.(Assignment); ok {
+			local[assign.Name+"="+assign.Value] = assign.IsLocal
+		}
+	}
+	if want := map[string]bool{"x=1": true, "y=": true, "y=2": true, "z=3": false, "total=4": false}; !reflect.DeepEqual(local, want) {
+		t.Errorf("Expected assignments %v, got %v", want, local)
+	}
+	for _, name := range []string{"z", "total"} {
+		if _, ok := ir.Variables[name]; !ok {
+			t.Errorf("Expected %s, assigned in a function without local, to be a script variable", name)
+		}
+	}
+}
+
+// TestBuildIRDynamicScope tests warning about functions that use a variable
+// a function calling them declares local
+func TestBuildIRDynamicScope(t *testing.T) {
+	script := `log() { echo "[$prefix] $1"; (( depth++ )); }
+step() { log "$1"; }
+run() {
+  local prefix=run depth=0 unused
+  step start
+}
+own() { local prefix=own; echo "$prefix"; }
+run
+`
+	result, err := ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	var warnings []string
+	for _, d := range ir.Diagnostics.Items() {
+		warnings = append(warnings, d.Message)
+	}
+	want := []string{
+		"function log uses depth, which its caller run declares local; the translated function uses the script variable instead",
+		"function log uses prefix, which its caller run declares local; the translated function uses the script variable instead",
+	}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Errorf("Expected warnings %q, got %q", want, warnings)
+	}
+}
+
+// TestBuildIRDeclare tests recording integer and read-only variables
+func TestBuildIRDeclare(t *testing.T) {
+	script := `declare -i n=1
+readonly NAME=app
+declare -r -i MAX=3
+VERSION=1.0
+readonly VERSION
+`
+	result, err := ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	if want := map[string]bool{"n": true, "MAX": true}; !reflect.DeepEqual(ir.IntegerVars, want) {
+		t.Errorf("Expected integer variables %v, got %v", want, ir.IntegerVars)
+	}
+	if want := map[string]bool{"NAME": true, "MAX": true, "VERSION": true}; !reflect.DeepEqual(ir.ReadonlyVars, want) {
+		t.Errorf("Expected read-only variables %v, got %v", want, ir.ReadonlyVars)
+	}
+	var assigned []string
+	for _, stmt := range ir.MainStatements {
+		if assign, ok := stmt.Value.(Assignment); ok {
+			assigned = append(assigned, assign.Name+"="+assign.Value)
+		}
+	}
+	if want := []string{"n=1", "NAME=app", "MAX=3", "VERSION=1.0"}; !reflect.DeepEqual(assigned, want) {
+		t.Errorf("Expected assignments %v, got %v", want, assigned)
+	}
+	if items := ir.Diagnostics.Items(); len(items) != 0 {
+		t.Errorf("Expected declarations to be supported, got %v", items)
+	}
+}
+
+// TestSetArgs tests splitting the arguments of set into options and operands
+func TestSetArgs(t *testing.T) {
+	tests := []struct {
+		args     []string
+		options  []SetOption
+		operands []string
+		setsArgs bool
+	}{
+		{[]string{"-u"}, []SetOption{{Name: "nounset", On: true}}, nil, false},
+		{[]string{"+u"}, []SetOption{{Name: "nounset", On: false}}, nil, false},
+		{[]string{"-euo", "pipefail"}, []SetOption{{Name: "errexit", On: true}, {Name: "nounset", On: true}, {Name: "pipefail", On: true}}, nil, false},
+		{[]string{"+o", "nounset", "-x"}, []SetOption{{Name: "nounset", On: false}, {Name: "xtrace", On: true}}, nil, false},
+		{[]string{"-o"}, nil, nil, false},
+		{[]string{"--", "a", "b"}, nil, []string{"a", "b"}, true},
+		{[]string{"-u", "a"}, []SetOption{{Name: "nounset", On: true}}, []string{"a"}, true},
+	}
+	for _, tt := range tests {
+		options, operands, setsArgs := SetArgs(tt.args)
+		if !reflect.DeepEqual(options, tt.options) || !reflect.DeepEqual(operands, tt.operands) || setsArgs != tt.setsArgs {
+			t.Errorf("SetArgs(%q) = %v, %q, %t, want %v, %q, %t",
+				tt.args, options, operands, setsArgs, tt.options, tt.operands, tt.setsArgs)
+		}
+	}
+}
+
+// TestReadArgs tests parsing the options and variable names of read
+func TestReadArgs(t *testing.T) {
+	tests := []struct {
+		args    []string
+		options ReadOptions
+		err     bool
+	}{
+		{nil, ReadOptions{}, false},
+		{[]string{"line"}, ReadOptions{Names: []string{"line"}}, false},
+		{[]string{"-r", "a", "b"}, ReadOptions{Raw: true, Names: []string{"a", "b"}}, false},
+		{[]string{"-p", "Name: ", "name"}, ReadOptions{Prompt: "Name: ", Names: []string{"name"}}, false},
+		{[]string{"-rp", "> ", "x"}, ReadOptions{Prompt: "> ", Raw: true, Names: []string{"x"}}, false},
+		{[]string{"-p>", "--", "x"}, ReadOptions{Prompt: ">", Names: []string{"x"}}, false},
+		{[]string{"-a", "arr"}, ReadOptions{}, true},
+		{[]string{"-p"}, ReadOptions{}, true},
+		{[]string{"$name"}, ReadOptions{}, true},
+	}
+	for _, tt := range tests {
+		options, err := ReadArgs(tt.args)
+		if (err != nil) != tt.err {
+			t.Errorf("ReadArgs(%q) error = %v, want error %t", tt.args, err, tt.err)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(options, tt.options) {
+			t.Errorf("ReadArgs(%q) = %+v, want %+v", tt.args, options, tt.options)
+		}
+	}
+}
+
+// TestBuildIRSource tests including the scripts read with source and .
+func TestBuildIRSource(t *testing.T) {
+	dir := t.TempDir()
+	libDir := filepath.Join(dir, "lib")
+	if err := os.Mkdir(libDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		filepath.Join(dir, "main.sh"): `source "$(dirname "$0")/common.sh"
+. util.sh
+greet
+source missing.sh
+`,
+		filepath.Join(dir, "common.sh"): `NAME=world
+greet() {
+  echo "hello $NAME"
+}
+`,
+		filepath.Join(libDir, "util.sh"): `UTIL=loaded
+source loop.sh
+`,
+		filepath.Join(libDir, "loop.sh"): `source util.sh
+`,
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ir, err := BuildIRFromFile(filepath.Join(dir, "main.sh"), libDir)
+	if err != nil {
+		t.Fatalf("BuildIRFromFile failed: %v", err)
+	}
+	greet, ok := ir.Functions["greet"]
+	if !ok {
+		t.Fatalf("Expected greet from common.sh to be included, got %v", ir.Functions)
+	}
+	if greet.Pos.File != "common.sh" {
+		t.Errorf("Expected greet to be from common.sh, got %q", greet.Pos.File)
+	}
+	for _, name := range []string{"NAME", "UTIL"} {
+		if _, ok := ir.Variables[name]; !ok {
+			t.Errorf("Expected %s from an included script to be a script variable", name)
+		}
+	}
+
+	var assigned, sourced []string
+	for _, stmt := range ir.MainStatements {
+		switch v := stmt.Value.(type) {
+		case Assignment:
+			assigned = append(assigned, v.Name+"@"+stmt.Pos.File)
+		case Command:
+			if v.Name == "source" || v.Name == "." {
+				sourced = append(sourced, v.Args[0]+"@"+v.Pos.File)
+			}
+		}
+	}
+	if want := []string{"NAME@common.sh", "UTIL@util.sh"}; !reflect.DeepEqual(assigned, want) {
+		t.Errorf("Expected assignments %v, got %v", want, assigned)
+	}
+	if want := []string{"util.sh@loop.sh", "missing.sh@main.sh"}; !reflect.DeepEqual(sourced, want) {
+		t.Errorf("Expected the commands including util.sh again and missing.sh to be kept, got %v", sourced)
+	}
+	if want := []string{
+		filepath.Join(dir, "common.sh"),
+		filepath.Join(libDir, "util.sh"),
+		filepath.Join(libDir, "loop.sh"),
+		filepath.Join(libDir, "util.sh"),
+		"missing.sh",
+	}; !reflect.DeepEqual(ir.Sources, want) {
+		t.Errorf("Expected sources %v, got %v", want, ir.Sources)
+	}
+
+	var messages []string
+	for _, d := range ir.Diagnostics.Items() {
+		messages = append(messages, d.Message)
+	}
+	if want := []string{
+		"source util.sh cannot be included: scripts include each other (util.sh -> loop.sh -> util.sh)",
+		"source missing.sh cannot be included: the script is not found",
+	}; !reflect.DeepEqual(messages, want) {
+		t.Errorf("Expected diagnostics %q, got %q", want, messages)
+	}
+}
+
+// TestBuildIRBackground tests processing commands run in the background
+func TestBuildIRBackground(t *testing.T) {
+	script := `sleep 10 &
+pid=$!
+wait "$pid"
+f() {
+  worker "$1" &
+}
+`
+	result, err := ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	stmt := ir.MainStatements[0]
+	background, ok := stmt.Value.(Background)
+	if stmt.Type != StatementBackground || !ok {
+		t.Fatalf("Expected a background statement, got %+v", stmt)
+	}
+	if background.Command.Name != "sleep" || !reflect.DeepEqual(background.Command.Args, []string{"10"}) {
+		t.Errorf("Expected sleep 10 in the background, got %+v", background.Command)
+	}
+	for _, stmt := range ir.MainStatements[1:] {
+		if cmd, ok := stmt.Value.(Command); ok && cmd.Name == "sleep" {
+			t.Errorf("Expected sleep to run only in the background, got %+v", cmd)
+		}
+	}
+	if !ir.SpecialVars["!"] {
+		t.Error("Expected $! to be recorded")
+	}
+	if stmt := ir.Functions["f"].Statements[0]; stmt.Type != StatementBackground {
+		t.Errorf("Expected the function to run worker in the background, got %+v", stmt)
+	}
+}
+
+// TestBuildIRBlock tests that brace groups keep their statements and
+// redirections together
+func TestBuildIRBlock(t *testing.T) {
+	script := `{ echo start; count=1; } > "$log" 2>&1
+echo done
+`
+	result, err := ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	if len(ir.MainStatements) != 2 {
+		t.Fatalf("Expected the group and echo done, got %+v", ir.MainStatements)
+	}
+	stmt := ir.MainStatements[0]
+	block, ok := stmt.Value.(Block)
+	if stmt.Type != StatementBlock || !ok {
+		t.Fatalf("Expected a block statement, got %+v", stmt)
+	}
+	if len(block.Statements) != 2 || block.Statements[0].Type != StatementCommand || block.Statements[1].Type != StatementAssignment {
+		t.Errorf("Expected echo and an assignment in the group, got %+v", block.Statements)
+	}
+	if len(block.Redirects) != 2 {
+		t.Fatalf("Expected two redirections, got %+v", block.Redirects)
+	}
+	if r := block.Redirects[0]; r.Op != ">" || r.Fd != "" || r.Filename != "${log}" {
+		t.Errorf("Expected > ${log}, got %+v", r)
+	}
+	if r := block.Redirects[1]; r.Op != ">&" || r.Fd != "2" || r.Filename != "1" {
+		t.Errorf("Expected 2>&1, got %+v", r)
+	}
+	if _, ok := ir.Variables["count"]; !ok {
+		t.Error("Expected count to be a script variable")
+	}
+}
+
+// TestBuildIRCommandRedirects tests recording the redirections of commands
+func TestBuildIRCommandRedirects(t *testing.T) {
+	script := `make > "$log" 2>&1
+tr a b <<< "$text" &> out.txt
+f() {
+  ls 2> /dev/null
+}
+`
+	result, err := ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	make := ir.MainStatements[0].Value.(Command)
+	if len(make.Redirects) != 2 {
+		t.Fatalf("Expected two redirections, got %+v", make.Redirects)
+	}
+	if r := make.Redirects[0]; r.Op != ">" || r.Fd != "" || r.Filename != "${log}" {
+		t.Errorf("Expected > ${log}, got %+v", r)
+	}
+	if r := make.Redirects[1]; r.Op != ">&" || r.Fd != "2" || r.Filename != "1" {
+		t.Errorf("Expected 2>&1, got %+v", r)
+	}
+
+	tr := ir.MainStatements[1].Value.(Command)
+	if tr.Stdin != "${text}" || len(tr.Redirects) != 1 || tr.Redirects[0].Op != "&>" {
+		t.Errorf("Expected the here-string as input and &> out.txt, got %+v", tr)
+	}
+
+	ls := ir.Functions["f"].Statements[0].Value.(Command)
+	if len(ls.Redirects) != 1 || ls.Redirects[0].Fd != "2" || ls.Redirects[0].Filename != "/dev/null" {
+		t.Errorf("Expected 2> /dev/null in the function, got %+v", ls)
+	}
+}
+
+// TestBuildIRCompoundRedirects tests that the redirections of compound
+// commands, such as a loop reading a file, apply to the whole command
+func TestBuildIRCompoundRedirects(t *testing.T) {
+	script := `while read -r line; do echo "$line"; done < "$file"
+for i in 1 2; do echo "$i"; done > out.txt
+`
+	result, err := ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	if len(ir.MainStatements) != 2 {
+		t.Fatalf("Expected the two loops, got %+v", ir.MainStatements)
+	}
+	for i, want := range []Redirection{{Op: "<", Filename: "${file}"}, {Op: ">", Filename: "out.txt"}} {
+		stmt := ir.MainStatements[i]
+		block, ok := stmt.Value.(Block)
+		if stmt.Type != StatementBlock || !ok {
+			t.Fatalf("Expected a block statement, got %+v", stmt)
+		}
+		if len(block.Statements) != 1 || block.Statements[0].Type != StatementLoop {
+			t.Errorf("Expected the loop in the block, got %+v", block.Statements)
+		}
+		if len(block.Redirects) != 1 || block.Redirects[0].Op != want.Op || block.Redirects[0].Filename != want.Filename {
+			t.Errorf("Expected %s %s, got %+v", want.Op, want.Filename, block.Redirects)
+		}
+	}
+}
+
+// TestBuildIRPrefixAssignments tests that assignments prefixed to commands
+// only apply to them
+func TestBuildIRPrefixAssignments(t *testing.T) {
+	script := `FOO=bar printenv FOO
+IFS=, read -r x y <<< "1,2"
+`
+	result, err := ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	if len(ir.MainStatements) != 2 {
+		t.Fatalf("Expected only the two commands, got %+v", ir.MainStatements)
+	}
+	printenv := ir.MainStatements[0].Value.(Command)
+	if len(printenv.Env) != 1 || printenv.Env[0].Name != "FOO" || printenv.Env[0].Value != "bar" {
+		t.Errorf("Expected FOO=bar in the environment of printenv, got %+v", printenv.Env)
+	}
+	read := ir.MainStatements[1].Value.(Command)
+	if len(read.Env) != 1 || read.Env[0].Name != "IFS" || read.Env[0].Value != "," {
+		t.Errorf("Expected IFS=, for read, got %+v", read.Env)
+	}
+	for _, name := range []string{"FOO", "IFS"} {
+		if _, ok := ir.Variables[name]; ok {
+			t.Errorf("Expected %s not to be a script variable", name)
+		}
+	}
+}
+
+// TestBuildIRHeredoc tests processing the bodies of here-documents
+func TestBuildIRHeredoc(t *testing.T) {
+	script := "cat <<EOF\nHello, $name \\$HOME \\\"x\\\"\n$(date) ${user:-nobody}\nEOF\n" +
+		"cat <<'EOF'\nraw $name\nEOF\n" +
+		"\tcat <<-EOF\n\t\tindented \\\n\tcontinued $name\n\tEOF\n"
 	result, err := ParseBashString(script)
 	if err != nil {
 		t.Fatalf("ParseBashString failed: %v", err)
 	}
+	ir, err := BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
 
-	// Get the Assign from the parsed script
-	var assign *syntax.Assign
-	for _, stmt := range result.File.Stmts {
-		// In the mvdan.cc/sh/v3/syntax package, assignments are part of the Cmd field
-		// We need to walk through the AST to find the Assign node
-		syntax.Walk(stmt, func(node syntax.Node) bool {
-			if a, ok := node.(*syntax.Assign); ok {
-				assign = a
-				return false // Stop walking
-			}
-			return true // Continue walking
-		})
-		if assign != nil {
-			break
+	if len(ir.Diagnostics.Items()) != 0 {
+		t.Errorf("Expected no unsupported redirections, got %v", ir.Diagnostics.Items())
+	}
+	want := [][]HeredocPart{
+		{
+			{Text: "Hello, "},
+			{Text: "${name}", Expansion: true},
+			{Text: " $HOME \\\"x\\\"\n"},
+			{Text: "$(date)", Expansion: true},
+			{Text: " "},
+			{Text: "${user:-nobody}", Expansion: true},
+			{Text: "\n"},
+		},
+		{{Text: "raw $name\n"}},
+		{
+			{Text: "indented \tcontinued "},
+			{Text: "${name}", Expansion: true},
+			{Text: "\n"},
+		},
+	}
+	for i, parts := range want {
+		cmd := ir.MainStatements[i].Value.(Command)
+		if cmd.Name != "cat" || cmd.Heredoc == nil || len(cmd.Redirects) != 0 {
+			t.Fatalf("Expected cat to read a here-document, got %+v", cmd)
+		}
+		if !reflect.DeepEqual(cmd.Heredoc.Parts, parts) {
+			t.Errorf("Expected here-document %+v, got %+v", parts, cmd.Heredoc.Parts)
 		}
 	}
+}
 
-	if assign == nil {
-		t.Fatal("Failed to find Assign in parsed script")
+// TestBuildIRNegation tests processing pipelines prefixed with !
+func TestBuildIRNegation(t *testing.T) {
+	script := `if ! grep -q foo file; then
+  echo missing
+fi
+! cmp -s a b
+`
+	result, err := ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
 	}
 
-	// Process the Assign
-	assignment := processAssign(assign)
+	ifStmt, ok := ir.MainStatements[0].Value.(If)
+	if !ok || len(ifStmt.Condition) != 1 {
+		t.Fatalf("Expected an if statement with one condition, got %+v", ir.MainStatements[0])
+	}
+	cond := ifStmt.Condition[0]
+	negation, ok := cond.Value.(Negation)
+	if cond.Type != StatementNegation || !ok {
+		t.Fatalf("Expected a negated condition, got %+v", cond)
+	}
+	if len(negation.Statements) != 1 || negation.Statements[0].Value.(Command).Name != "grep" {
+		t.Errorf("Expected the condition to negate grep, got %+v", negation.Statements)
+	}
 
-	// Verify the assignment
-	if assignment.Name != "NAME" {
-		t.Fatalf("Expected name 'NAME', got '%s'", assignment.Name)
+	last := ir.MainStatements[len(ir.MainStatements)-1]
+	negation, ok = last.Value.(Negation)
+	if last.Type != StatementNegation || !ok || len(negation.Statements) != 1 {
+		t.Fatalf("Expected a negated statement, got %+v", last)
+	}
+	if cmd := negation.Statements[0].Value.(Command); cmd.Name != "cmp" || !reflect.DeepEqual(cmd.Args, []string{"-s", "a", "b"}) {
+		t.Errorf("Expected cmp -s a b to be negated, got %+v", cmd)
 	}
+}
 
-	if !strings.Contains(assignment.Value, "Test") {
-		t.Fatalf("Expected value to contain 'Test', got '%s'", assignment.Value)
+// TestBuildIRSplits tests recording which words are split at $IFS
+func TestBuildIRSplits(t *testing.T) {
+	script := `echo $a "$b" x$c "$@" ${#d} $(date) ${e[0]} *.$f
+for w in $list "$item" $(ls); do
+  echo "$w"
+done
+`
+	result, err := ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
 	}
 
-	if assignment.IsLocal {
-		t.Fatal("Expected IsLocal to be false")
+	echo := ir.MainStatements[0].Value.(Command)
+	if !reflect.DeepEqual(echo.Splits, []int{0, 2, 5}) {
+		t.Errorf("Expected arguments 0, 2 and 5 to be split, got %v in %q", echo.Splits, echo.Args)
+	}
+	if !reflect.DeepEqual(echo.Globs, []int{7}) {
+		t.Errorf("Expected the glob pattern not to be split, got globs %v", echo.Globs)
 	}
 
-	if assignment.IsExport {
-		t.Fatal("Expected IsExport to be false")
+	loop := ir.MainStatements[1].Value.(Loop)
+	if !reflect.DeepEqual(loop.Splits, []int{0, 2}) {
+		t.Errorf("Expected items 0 and 2 to be split, got %v in %q", loop.Splits, loop.Words)
+	}
+}
+
+// TestBuildIRNestedBodies tests processing statements nested in the bodies
+// of compound commands
+func TestBuildIRNestedBodies(t *testing.T) {
+	script := `for i in 1 2; do
+  if [ -n "$i" ]; then
+    total=$i
+    while read -r line; do
+      echo "$line" | tr a-z A-Z
+    done
+  fi
+  (if true; then echo sub; fi)
+done
+`
+	result, err := ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	if len(ir.MainStatements) != 1 || ir.MainStatements[0].Type != StatementLoop {
+		t.Fatalf("Expected the for loop as the only top-level statement, got %+v", ir.MainStatements)
+	}
+	forLoop := ir.MainStatements[0].Value.(Loop)
+	if len(forLoop.Body) != 2 || forLoop.Body[0].Type != StatementIf || forLoop.Body[1].Type != StatementSubshell {
+		t.Fatalf("Expected an if statement and a subshell in the for loop, got %+v", forLoop.Body)
+	}
+
+	ifStmt := forLoop.Body[0].Value.(If)
+	if len(ifStmt.ThenBlock) != 2 || ifStmt.ThenBlock[0].Type != StatementAssignment || ifStmt.ThenBlock[1].Type != StatementLoop {
+		t.Fatalf("Expected an assignment and a while loop in the then block, got %+v", ifStmt.ThenBlock)
+	}
+	whileLoop := ifStmt.ThenBlock[1].Value.(Loop)
+	if len(whileLoop.Condition) != 1 || len(whileLoop.Body) != 1 || whileLoop.Body[0].Type != StatementPipe {
+		t.Fatalf("Expected read as the condition and a pipeline as the body of the while loop, got %+v", whileLoop)
+	}
+	if _, ok := ir.Variables["total"]; !ok {
+		t.Errorf("Expected the nested assignment to make total a script variable, got %v", ir.Variables)
+	}
+	if _, ok := ir.Variables["line"]; !ok {
+		t.Errorf("Expected the nested read to make line a script variable, got %v", ir.Variables)
+	}
+
+	subshell := forLoop.Body[1].Value.(Subshell)
+	if len(subshell.Statements) != 1 || subshell.Statements[0].Type != StatementIf {
+		t.Fatalf("Expected an if statement in the subshell, got %+v", subshell.Statements)
+	}
+	if then := subshell.Statements[0].Value.(If).ThenBlock; len(then) != 1 || then[0].Value.(Command).Name != "echo" {
+		t.Errorf("Expected echo in the if statement of the subshell, got %+v", then)
+	}
+}
+
+// TestBuildIRNoDuplicates tests that statements nested in functions, compound
+// commands and pipelines are only processed where they belong
+func TestBuildIRNoDuplicates(t *testing.T) {
+	script := `build() {
+  local tmp=$1
+  if [ -n "$tmp" ]; then
+    count=1
+    ls "$tmp" | sort
+  fi
+}
+for i in 1 2; do
+  build "$i"
+done
+ls | grep foo
+`
+	result, err := ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	var types []StatementType
+	for _, stmt := range ir.MainStatements {
+		types = append(types, stmt.Type)
+	}
+	if want := []StatementType{StatementFunction, StatementLoop, StatementPipe}; !reflect.DeepEqual(types, want) {
+		t.Fatalf("Expected top-level statements %v, got %v", want, types)
+	}
+
+	build := ir.Functions["build"]
+	if len(build.Statements) != 2 || build.Statements[1].Type != StatementIf {
+		t.Fatalf("Expected the local assignment and the if statement in build, got %+v", build.Statements)
+	}
+	if assign := build.Statements[0].Value.(Assignment); !assign.IsLocal {
+		t.Errorf("Expected tmp to be assigned as a local variable, got %+v", assign)
+	}
+	then := build.Statements[1].Value.(If).ThenBlock
+	if len(then) != 2 || then[0].Type != StatementAssignment || then[1].Type != StatementPipe {
+		t.Errorf("Expected an assignment and a pipeline in the if statement, got %+v", then)
+	}
+	if _, ok := build.LocalVars["tmp"]; !ok {
+		t.Errorf("Expected tmp among the local variables of build, got %v", build.LocalVars)
+	}
+	if _, ok := ir.Variables["tmp"]; ok {
+		t.Errorf("Expected the local variable tmp not to be a script variable")
+	}
+	if _, ok := ir.Variables["count"]; !ok {
+		t.Errorf("Expected count, assigned in build, to be a script variable, got %v", ir.Variables)
+	}
+	if !ir.SpecialVars["1"] {
+		t.Errorf("Expected $1 in build to be recorded, got %v", ir.SpecialVars)
 	}
 }
 
@@ -281,7 +1479,7 @@ fi`
 	}
 
 	// Process the IfClause
-	ifStmt := processIfClause(ifClause)
+	ifStmt := processIfClause(NewIntermediateRepresentation(), ifClause)
 
 	// Verify the if statement
 	if len(ifStmt.Condition) == 0 {
@@ -297,6 +1495,44 @@ fi`
 	}
 }
 
+// TestProcessIfClauseElif tests separating elif clauses from the else block
+func TestProcessIfClauseElif(t *testing.T) {
+	script := `if [ -d "$f" ]; then
+    echo dir
+elif [ -f "$f" ]; then
+    echo file
+elif (( n > 1 )); then
+    echo many
+else
+    echo missing
+fi`
+	result, err := ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ifClause, ok := result.File.Stmts[0].Cmd.(*syntax.IfClause)
+	if !ok {
+		t.Fatal("Failed to find IfClause in parsed script")
+	}
+
+	ifStmt := processIfClause(NewIntermediateRepresentation(), ifClause)
+	if ifStmt.ConditionType != "file" || len(ifStmt.ThenBlock) != 1 {
+		t.Errorf("Expected the file test and its then block, got %+v", ifStmt)
+	}
+	if len(ifStmt.ElifBlocks) != 2 {
+		t.Fatalf("Expected two elif blocks, got %+v", ifStmt.ElifBlocks)
+	}
+	for i, want := range []StatementType{StatementCommand, StatementArithmetic} {
+		elif := ifStmt.ElifBlocks[i]
+		if len(elif[0]) != 1 || elif[0][0].Type != want || len(elif[1]) != 1 {
+			t.Errorf("Expected elif %d to have a %s condition and one statement, got %+v", i, want, elif)
+		}
+	}
+	if len(ifStmt.ElseBlock) != 1 || ifStmt.ElseBlock[0].Value.(Command).Args[0] != "missing" {
+		t.Errorf("Expected echo missing in the else block, got %+v", ifStmt.ElseBlock)
+	}
+}
+
 // TestProcessForClause tests the processForClause function
 func TestProcessForClause(t *testing.T) {
 	script := `for i in 1 2 3; do
@@ -325,13 +1561,17 @@ done`
 	}
 
 	// Process the ForClause
-	loop := processForClause(forClause)
+	loop := processForClause(NewIntermediateRepresentation(), forClause)
 
 	// Verify the loop
 	if !loop.IsForEach {
 		t.Fatal("Expected IsForEach to be true")
 	}
 
+	if loop.RangeVar != "i" || loop.Items != "1 2 3" {
+		t.Fatalf("Expected to iterate i over 1 2 3, got %s over %q", loop.RangeVar, loop.Items)
+	}
+
 	if len(loop.Body) == 0 {
 		t.Fatal("Expected non-empty body")
 	}
@@ -366,7 +1606,7 @@ done`
 	}
 
 	// Process the WhileClause
-	loop := processWhileClause(whileClause)
+	loop := processWhileClause(NewIntermediateRepresentation(), whileClause)
 
 	// Verify the loop
 	if loop.Type != "while" {
@@ -384,7 +1624,7 @@ done`
 
 // TestProcessPipe tests the processPipe function
 func TestProcessPipe(t *testing.T) {
-	script := `ls -la | grep "file"`
+	script := `ls -la | grep "file" | wc -l`
 
 	// Parse the script
 	result, err := ParseBashString(script)
@@ -413,8 +1653,12 @@ func TestProcessPipe(t *testing.T) {
 	pipe := processPipe(binaryCmd)
 
 	// Verify the pipe
-	if len(pipe.Commands) == 0 {
-		t.Fatal("Expected non-empty commands")
+	var names []string
+	for _, cmd := range pipe.Commands {
+		names = append(names, cmd.Name)
+	}
+	if want := []string{"ls", "grep", "wc"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("Expected commands %v, got %v", want, names)
 	}
 }
 
@@ -447,7 +1691,7 @@ func TestProcessSubshell(t *testing.T) {
 	}
 
 	// Process the Subshell
-	subsh := processSubshell(subshell)
+	subsh := processSubshell(NewIntermediateRepresentation(), subshell)
 
 	// Verify the subshell
 	if len(subsh.Statements) == 0 {
@@ -455,6 +1699,39 @@ func TestProcessSubshell(t *testing.T) {
 	}
 }
 
+// TestProcessSubshellVars tests recording the variables a subshell assigns,
+// which are still script variables
+func TestProcessSubshellVars(t *testing.T) {
+	script := `(
+    name=inner
+    read -r line
+    for i in 1 2; do count=$i; done
+)
+echo "$name"
+`
+	result, err := ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	ir, err := BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	if len(ir.MainStatements) != 2 || ir.MainStatements[0].Type != StatementSubshell {
+		t.Fatalf("Expected a subshell and a command, got %+v", ir.MainStatements)
+	}
+	subshell := ir.MainStatements[0].Value.(Subshell)
+	if want := []string{"count", "i", "line", "name"}; !reflect.DeepEqual(subshell.Vars, want) {
+		t.Errorf("Expected subshell variables %v, got %v", want, subshell.Vars)
+	}
+	for _, name := range subshell.Vars {
+		if _, ok := ir.Variables[name]; !ok {
+			t.Errorf("Expected %s to be a script variable", name)
+		}
+	}
+}
+
 // TestProcessRedirection tests the processRedirection function
 func TestProcessRedirection(t *testing.T) {
 	script := `echo "Hello" > file.txt`
@@ -492,3 +1769,227 @@ func TestProcessRedirection(t *testing.T) {
 		t.Fatalf("Expected filename 'file.txt', got '%s'", redirection.Filename)
 	}
 }
+
+// TestStatementPositions tests that IR statements record their source location
+func TestStatementPositions(t *testing.T) {
+	script := `#!/bin/bash
+NAME="Test"
+if [ -n "$NAME" ]; then
+    echo "$NAME"
+fi
+`
+
+	// Parse the script
+	result, err := ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	result.Filename = "test.sh"
+
+	// Build the IR
+	ir, err := BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	// Find the if statement and check the positions of it and its body
+	for _, stmt := range ir.MainStatements {
+		if stmt.Type != StatementIf {
+			continue
+		}
+
+		if got := stmt.Pos.String(); got != "test.sh:3:1" {
+			t.Fatalf("Expected if position 'test.sh:3:1', got '%s'", got)
+		}
+
+		ifStmt := stmt.Value.(If)
+		if len(ifStmt.ThenBlock) == 0 {
+			t.Fatal("Expected non-empty then block")
+		}
+
+		then := ifStmt.ThenBlock[0]
+		if then.Pos.File != "test.sh" || then.Pos.Line != 4 || then.Pos.Column != 5 {
+			t.Fatalf("Expected then position test.sh:4:5, got %s", then.Pos)
+		}
+
+		if cmd := then.Value.(Command); cmd.Pos != then.Pos {
+			t.Fatalf("Expected command position %s, got %s", then.Pos, cmd.Pos)
+		}
+		return
+	}
+
+	t.Fatal("Failed to find if statement in IR")
+}
+
+// TestBuildIRDiagnostics tests that unsupported constructs are reported
+func TestBuildIRDiagnostics(t *testing.T) {
+	script := `#!/bin/bash
+case "$1" in
+    start) echo "starting" ;;
+esac
+`
+
+	// Parse the script
+	result, err := ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+
+	// Build the IR
+	ir, err := BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	// Verify the diagnostics
+	if !ir.Diagnostics.HasErrors() {
+		t.Fatal("Expected an error diagnostic for the case statement")
+	}
+
+	items := ir.Diagnostics.Items()
+	if items[0].Line != 2 || !strings.Contains(items[0].Message, "case statement") {
+		t.Fatalf("Unexpected diagnostic: %s", items[0])
+	}
+}
+
+// TestIRJSONRoundTrip tests that the JSON encoding of the IR decodes to the
+// same IR
+func TestIRJSONRoundTrip(t *testing.T) {
+	script := `#!/bin/bash
+greet() {
+	echo "hello $1"
+}
+if [ -f config ]; then
+	cat config | grep name
+else
+	echo missing > log.txt
+fi
+for i in 1 2 3; do
+	echo $i &
+done
+`
+	result, err := ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	result.Filename = "roundtrip.sh"
+	ir, err := BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	data, err := json.Marshal(ir)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var decoded IntermediateRepresentation
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	// Empty and nil lists encode alike, so compare the encodings
+	again, err := json.Marshal(&decoded)
+	if err != nil {
+		t.Fatalf("Marshal of the decoded IR failed: %v", err)
+	}
+	if string(again) != string(data) {
+		t.Errorf("IR differs after the round trip:\n got %s\nwant %s", again, data)
+	}
+	if _, ok := decoded.MainStatements[0].Value.(*Function); !ok {
+		t.Errorf("Expected a function declaration, got %T", decoded.MainStatements[0].Value)
+	}
+
+	// Documents of other versions and unknown statements are rejected
+	for _, doc := range []string{
+		`{"version": 99, "mainStatements": []}`,
+		`{"version": 1, "mainStatements": [{"type": "goto", "value": {}}]}`,
+		`{"version": 1, "mainStatements": [{"type": "command"}]}`,
+	} {
+		if err := json.Unmarshal([]byte(doc), &decoded); err == nil {
+			t.Errorf("Expected Unmarshal to reject %s", doc)
+		}
+	}
+}
+
+// TestBuildIRFromReader tests that streaming a script statement by statement
+// builds the same IR as parsing it whole
+func TestBuildIRFromReader(t *testing.T) {
+	script := `#!/bin/bash
+# bash2go:env convert BUILD_USER
+greet() {
+	echo "hello $1"
+}
+shopt -s nullglob
+if [ -f config ]; then
+	cat config | grep name
+fi
+for i in 1 2 3; do
+	echo $i $? &
+done
+case "$1" in
+    start) echo "starting" ;;
+esac
+echo "$(date)" > log.txt
+# bash2go:env runtime HOME
+`
+	result, err := ParseBashString(script)
+	if err != nil {
+		t.Fatalf("ParseBashString failed: %v", err)
+	}
+	result.Filename = "stream.sh"
+	want, err := BuildIR(result)
+	if err != nil {
+		t.Fatalf("BuildIR failed: %v", err)
+	}
+
+	got, err := BuildIRFromReader(strings.NewReader(script), "stream.sh")
+	if err != nil {
+		t.Fatalf("BuildIRFromReader failed: %v", err)
+	}
+	for _, ir := range []*IntermediateRepresentation{want, got} {
+		ir.Diagnostics = nil
+	}
+	wantJSON, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotJSON, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("Streamed IR differs:\n got %s\nwant %s", gotJSON, wantJSON)
+	}
+	if len(got.EnvPolicies) != 2 || len(got.ShellOptions) != 1 || !got.SpecialVars["?"] {
+		t.Errorf("Expected directives, shell options and special variables, got %v, %v, %v",
+			got.EnvPolicies, got.ShellOptions, got.SpecialVars)
+	}
+
+	for _, broken := range []string{"if true; then\n", "echo a &&"} {
+		if _, err := BuildIRFromReader(strings.NewReader(broken), "broken.sh"); err == nil {
+			t.Errorf("Expected a syntax error for %q", broken)
+		}
+	}
+}
+
+// FuzzBuildIR checks that building the IR of any script that parses does
+// not panic
+func FuzzBuildIR(f *testing.F) {
+	for _, seed := range []string{
+		"echo hello\n",
+		"NAME=world\necho \"hello $NAME\"\n",
+		"f() { local x=1; echo $x; }\nf\n",
+		"declare -A m\nm[key]=value\n",
+		"if [ -f a ]; then echo a; elif true; then :; else echo b; fi\n",
+		"for i in 1 2; do echo $i; done | cat > out.txt &\n",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, script string) {
+		result, err := ParseBashString(script)
+		if err != nil {
+			return
+		}
+		BuildIR(result)
+	})
+}
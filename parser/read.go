@@ -0,0 +1,67 @@
+package parser
+
+import "fmt"
+
+// ReadOptions are the options of a read command and the variables it sets.
+type ReadOptions struct {
+	Prompt string   // Prompt of -p, printed before reading from a terminal.
+	Raw    bool     // -r keeps backslashes instead of treating them as escapes.
+	Names  []string // Variables assigned the fields of the line; without any, read assigns the whole line to REPLY.
+}
+
+// ReadArgs parses the arguments of a read command. Options other than -r
+// and -p, and arguments that are not variable names, are errors.
+func ReadArgs(args []string) (ReadOptions, error) {
+	var options ReadOptions
+	i := 0
+	for ; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			i++
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			break
+		}
+		for j := 1; j < len(arg); j++ {
+			switch arg[j] {
+			case 'r':
+				options.Raw = true
+			case 'p':
+				// The prompt is the rest of the argument or the next one
+				if j+1 < len(arg) {
+					options.Prompt = arg[j+1:]
+				} else if i+1 < len(args) {
+					i++
+					options.Prompt = args[i]
+				} else {
+					return options, fmt.Errorf("read: -p: option requires an argument")
+				}
+				j = len(arg)
+			default:
+				return options, fmt.Errorf("read -%c is not supported", arg[j])
+			}
+		}
+	}
+
+	for _, name := range args[i:] {
+		if !isVarName(name) {
+			return options, fmt.Errorf("read: `%s': not a valid identifier", name)
+		}
+		options.Names = append(options.Names, name)
+	}
+	return options, nil
+}
+
+// readNames returns the names of the variables a read command assigns, or
+// none if its arguments are invalid.
+func readNames(cmd Command) []string {
+	options, err := ReadArgs(cmd.Args)
+	if err != nil {
+		return nil
+	}
+	if len(options.Names) == 0 {
+		return []string{"REPLY"}
+	}
+	return options.Names
+}
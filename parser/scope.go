@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/TFMV/bash2go/diagnostics"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// recordUses records in scope the variables the body of its function uses,
+// by name, and the commands it calls.
+func recordUses(scope *functionScope, body *syntax.Stmt) {
+	scope.uses = make(map[string]bool)
+	scope.calls = make(map[string]bool)
+	arith := func(exprs ...syntax.ArithmExpr) {
+		for _, expr := range exprs {
+			if expr == nil {
+				continue
+			}
+			syntax.Walk(expr, func(node syntax.Node) bool {
+				// Arithmetic names variables without a dollar sign
+				if word, ok := node.(*syntax.Word); ok && isVarName(word.Lit()) {
+					scope.uses[word.Lit()] = true
+				}
+				return true
+			})
+		}
+	}
+	syntax.Walk(body, func(node syntax.Node) bool {
+		switch x := node.(type) {
+		case *syntax.ParamExp:
+			if x.Param != nil && isVarName(x.Param.Value) {
+				scope.uses[x.Param.Value] = true
+			}
+		case *syntax.Assign:
+			if x.Name != nil {
+				scope.uses[x.Name.Value] = true
+			}
+		case *syntax.CallExpr:
+			if len(x.Args) == 0 {
+				break
+			}
+			if name := x.Args[0].Lit(); name != "" {
+				scope.calls[name] = true
+			}
+			if cmd := processCallExpr(x); cmd.Name == "read" {
+				for _, name := range readNames(cmd) {
+					scope.uses[name] = true
+				}
+			}
+		case *syntax.ArithmExp:
+			arith(x.X)
+		case *syntax.ArithmCmd:
+			arith(x.X)
+		case *syntax.CStyleLoop:
+			arith(x.Init, x.Cond, x.Post)
+		case *syntax.LetClause:
+			arith(x.Exprs...)
+		}
+		return true
+	})
+}
+
+// checkDynamicScope warns about the variables functions use that a function
+// calling them, directly or through others, declares local. In Bash the
+// callee sees the local variable of its caller, while translated functions
+// only see their own locals and the script variables.
+func checkDynamicScope(ir *IntermediateRepresentation) {
+	scopes := make(map[string]*functionScope, len(ir.scopes))
+	for _, scope := range ir.scopes {
+		scopes[scope.function.Name] = scope
+	}
+	for _, name := range slices.Sorted(maps.Keys(scopes)) {
+		caller := scopes[name]
+		if len(caller.locals) == 0 {
+			continue
+		}
+		seen := map[string]bool{name: true}
+		queue := slices.Sorted(maps.Keys(caller.calls))
+		for len(queue) > 0 {
+			callee, ok := scopes[queue[0]]
+			queue = queue[1:]
+			if !ok || seen[callee.function.Name] {
+				continue
+			}
+			seen[callee.function.Name] = true
+			for _, v := range slices.Sorted(maps.Keys(callee.uses)) {
+				if caller.locals[v] && !callee.locals[v] {
+					ir.Diagnose(diagnostics.SeverityWarning, callee.function.Pos, diagnostics.CodeUnsupported,
+						"function %s uses %s, which its caller %s declares local; the translated function uses the script variable instead",
+						callee.function.Name, v, name)
+				}
+			}
+			queue = append(queue, slices.Sorted(maps.Keys(callee.calls))...)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package parser
+
+import "strings"
+
+// setOptionLetters maps the single-letter options of the set builtin to
+// the long names set -o takes.
+var setOptionLetters = map[byte]string{
+	'a': "allexport",
+	'B': "braceexpand",
+	'C': "noclobber",
+	'E': "errtrace",
+	'e': "errexit",
+	'f': "noglob",
+	'H': "histexpand",
+	'h': "hashall",
+	'k': "keyword",
+	'm': "monitor",
+	'n': "noexec",
+	'P': "physical",
+	'T': "functrace",
+	'u': "nounset",
+	'v': "verbose",
+	'x': "xtrace",
+}
+
+// SetOption is a shell option turned on or off by the set builtin.
+type SetOption struct {
+	Name string // Long name of the option, as given to set -o.
+	On   bool
+}
+
+// SetArgs splits the arguments of a set command into the options it turns
+// on or off, in order, and the positional parameters it sets. setsArgs
+// reports whether the command sets the positional parameters, which set --
+// does even without operands. Unknown option letters are returned by their
+// letter.
+func SetArgs(args []string) (options []SetOption, operands []string, setsArgs bool) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return options, args[i+1:], true
+		}
+		if len(arg) < 2 || (arg[0] != '-' && arg[0] != '+') {
+			return options, args[i:], true
+		}
+		on := arg[0] == '-'
+		for j := 1; j < len(arg); j++ {
+			if arg[j] != 'o' {
+				name, ok := setOptionLetters[arg[j]]
+				if !ok {
+					name = arg[j : j+1]
+				}
+				options = append(options, SetOption{Name: name, On: on})
+				continue
+			}
+			// Without a name, set -o and set +o print the options
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") && !strings.HasPrefix(args[i+1], "+") {
+				i++
+				options = append(options, SetOption{Name: args[i], On: on})
+			}
+		}
+	}
+	return options, nil, false
+}
+
+// recordSet records the shell options a set command turns on or off.
+func recordSet(ir *IntermediateRepresentation, cmd Command) {
+	options, _, _ := SetArgs(cmd.Args)
+	for _, option := range options {
+		ir.ShellOptions[option.Name] = option.On
+	}
+}
@@ -0,0 +1,148 @@
+package parser
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/TFMV/bash2go/diagnostics"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// includer resolves the scripts included with source or . while an IR is
+// built, and tracks the chain of includes to detect cycles.
+type includer struct {
+	dir        string   // Directory of the script being built, "" for the working directory
+	searchPath []string // Directories searched for included scripts after dir
+	stack      []string // Absolute paths of the scripts being built, outermost first
+}
+
+// scriptDirPrefixes lists the spellings of the directory of the running
+// script that source paths commonly start with. They are resolved to the
+// directory of the including script.
+var scriptDirPrefixes = []string{
+	`$(dirname "$0")/`,
+	`$(dirname $0)/`,
+	`$(dirname "${0}")/`,
+	`$(dirname "${BASH_SOURCE[0]}")/`,
+	`$(dirname "$BASH_SOURCE")/`,
+	`$(dirname "${BASH_SOURCE}")/`,
+	`${BASH_SOURCE%/*}/`,
+	`${0%/*}/`,
+}
+
+// resolve returns the path of the script name included by a source command,
+// and false if it is not found. Like for Bash, names without a slash are
+// searched for, here in the directory of the including script and then in
+// the search path; other relative names are taken relative to that
+// directory rather than to the working directory at runtime.
+func (inc *includer) resolve(name string) (string, bool) {
+	for _, prefix := range scriptDirPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			name = "./" + name[len(prefix):]
+			break
+		}
+	}
+
+	var candidates []string
+	switch {
+	case filepath.IsAbs(name):
+		candidates = []string{name}
+	case strings.Contains(name, "/"):
+		candidates = []string{filepath.Join(inc.dir, name)}
+	default:
+		candidates = []string{filepath.Join(inc.dir, name)}
+		for _, dir := range inc.searchPath {
+			candidates = append(candidates, filepath.Join(dir, name))
+		}
+	}
+	for _, path := range candidates {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// includeSource replaces a source or . command with the statements of the
+// script it includes, whose functions and variables become part of ir. It
+// reports false, with a diagnostic, if the script cannot be included at
+// conversion time.
+func includeSource(ir *IntermediateRepresentation, cmd Command) bool {
+	pos := cmd.Pos
+	if len(cmd.Args) == 0 {
+		ir.Diagnose(diagnostics.SeverityError, pos, diagnostics.CodeUnsupported,
+			"%s without a file name cannot be translated", cmd.Name)
+		return false
+	}
+	inc := ir.includes
+	if inc == nil {
+		inc = &includer{}
+	}
+
+	name := cmd.Args[0]
+	path, ok := inc.resolve(name)
+	if !ok {
+		// The conversion depends on the script not being found
+		ir.Sources = append(ir.Sources, name)
+		if strings.ContainsAny(name, "$`") {
+			ir.Diagnose(diagnostics.SeverityError, pos, diagnostics.CodeUnsupported,
+				"%s %s cannot be included: its path is only known at runtime", cmd.Name, name)
+		} else {
+			ir.Diagnose(diagnostics.SeverityError, pos, diagnostics.CodeUnsupported,
+				"%s %s cannot be included: the script is not found", cmd.Name, name)
+		}
+		return false
+	}
+	ir.Sources = append(ir.Sources, path)
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	for i, including := range inc.stack {
+		if including == abs {
+			chain := append(append([]string(nil), inc.stack[i:]...), abs)
+			for j := range chain {
+				chain[j] = filepath.Base(chain[j])
+			}
+			ir.Diagnose(diagnostics.SeverityError, pos, diagnostics.CodeUnsupported,
+				"%s %s cannot be included: scripts include each other (%s)", cmd.Name, name, strings.Join(chain, " -> "))
+			return false
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		ir.Diagnose(diagnostics.SeverityError, pos, diagnostics.CodeUnsupported,
+			"%s %s cannot be included: %v", cmd.Name, name, err)
+		return false
+	}
+	defer f.Close()
+	file, err := syntax.NewParser(syntax.Variant(syntax.LangBash), syntax.KeepComments(true)).Parse(bufio.NewReader(f), path)
+	if err != nil {
+		ir.Diagnose(diagnostics.SeverityError, pos, diagnostics.CodeUnsupported,
+			"%s %s cannot be included: %v", cmd.Name, name, err)
+		return false
+	}
+	if len(cmd.Args) > 1 {
+		ir.Diagnose(diagnostics.SeverityWarning, pos, diagnostics.CodeUnsupported,
+			"the arguments of %s are not passed to the included script as positional parameters", cmd.Name)
+	}
+
+	sub := newScriptIR(filepath.Base(path))
+	sub.includes = &includer{
+		dir:        filepath.Dir(path),
+		searchPath: inc.searchPath,
+		stack:      append(append([]string(nil), inc.stack...), abs),
+	}
+	for k, v := range ir.AssocArrays {
+		sub.AssocArrays[k] = v
+	}
+	processFile(sub, file)
+	finishIR(sub)
+
+	mergeScript(ir, sub)
+	ir.MainStatements = append(ir.MainStatements, sub.MainStatements...)
+	return true
+}
@@ -0,0 +1,44 @@
+package parser
+
+import (
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// isSplitWord reports whether the expansions of a word are split into
+// fields at the characters of $IFS, as an unquoted $name or $(cmd) is.
+// Quoted words are not split, nor are array expansions and special
+// parameters such as $@ and $#, which are expanded on their own.
+func isSplitWord(word *syntax.Word) bool {
+	split := false
+	for _, part := range word.Parts {
+		switch x := part.(type) {
+		case *syntax.ParamExp:
+			if x.Index != nil || x.Excl || x.Length || x.Width || isSpecialParam(x.Param.Value) {
+				return false
+			}
+			split = true
+		case *syntax.CmdSubst:
+			split = true
+		case *syntax.Lit:
+			// Escaped blanks are not split
+			if strings.ContainsAny(x.Value, " \t\n") {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return split
+}
+
+// isSpecialParam reports whether name is a special parameter other than a
+// positional parameter, as in $@ or $?.
+func isSpecialParam(name string) bool {
+	switch name {
+	case "@", "*", "#", "?", "$", "!", "-":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// TestExpr is a node of the expression of an extended test [[ ]]. Operands
+// are words; operators are unary, such as -f, -z and !, or binary, such as
+// ==, =~, -lt and &&.
+type TestExpr struct {
+	Op   string    `json:"op,omitempty"`   // Operator, such as "-f", "==", "=~", "-lt" or "&&"; empty for operands. The = operator is written "==".
+	Word string    `json:"word,omitempty"` // Word of an operand, as extracted by the parser. Patterns right of == and != and regular expressions right of =~ escape their quoted parts.
+	X    *TestExpr `json:"x,omitempty"`    // Only or left operand.
+	Y    *TestExpr `json:"y,omitempty"`    // Right operand.
+}
+
+// IsComparison reports whether the operator compares its operands as
+// integers, as -eq and -lt do.
+func (t *TestExpr) IsComparison() bool {
+	switch t.Op {
+	case "-eq", "-ne", "-lt", "-le", "-gt", "-ge":
+		return true
+	}
+	return false
+}
+
+// processTestClause processes an extended test [[ expr ]].
+func processTestClause(x *syntax.TestClause) Statement {
+	return Statement{
+		Type:  StatementTest,
+		Value: *processTestExpr(x.X),
+		Pos:   newPosition(x.Pos()),
+	}
+}
+
+// processTestExpr converts the expression of an extended test into an
+// expression tree.
+func processTestExpr(x syntax.TestExpr) *TestExpr {
+	switch x := x.(type) {
+	case *syntax.Word:
+		return &TestExpr{Word: extractWordValue(x)}
+	case *syntax.ParenTest:
+		return processTestExpr(x.X)
+	case *syntax.UnaryTest:
+		return &TestExpr{Op: x.Op.String(), X: processTestExpr(x.X)}
+	case *syntax.BinaryTest:
+		t := &TestExpr{Op: x.Op.String(), X: processTestExpr(x.X)}
+		word, _ := x.Y.(*syntax.Word)
+		if x.Op == syntax.TsMatchShort {
+			t.Op = "=="
+		}
+		switch {
+		case (t.Op == "==" || t.Op == "!=") && word != nil:
+			t.Y = &TestExpr{Word: extractPatternValue(word)}
+		case x.Op == syntax.TsReMatch && word != nil:
+			t.Y = &TestExpr{Word: extractRegexpValue(word)}
+		default:
+			t.Y = processTestExpr(x.Y)
+		}
+		return t
+	}
+	return &TestExpr{Word: nodeText(x)}
+}
+
+// extractRegexpValue extracts a regular expression from a word. Quoted parts
+// match literally, so their special characters are escaped.
+func extractRegexpValue(word *syntax.Word) string {
+	var value strings.Builder
+	for _, part := range word.Parts {
+		switch p := part.(type) {
+		case *syntax.SglQuoted:
+			value.WriteString(regexp.QuoteMeta(p.Value))
+		case *syntax.DblQuoted:
+			for _, part := range p.Parts {
+				if lit, ok := part.(*syntax.Lit); ok {
+					value.WriteString(regexp.QuoteMeta(lit.Value))
+				} else {
+					value.WriteString(extractDblQuotedValue(&syntax.DblQuoted{Parts: []syntax.WordPart{part}}))
+				}
+			}
+		default:
+			value.WriteString(extractWordValue(&syntax.Word{Parts: []syntax.WordPart{part}}))
+		}
+	}
+	return value.String()
+}
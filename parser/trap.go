@@ -0,0 +1,105 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// trapSignalNumbers maps the signal numbers accepted by trap to the names
+// of their conditions.
+var trapSignalNumbers = map[int]string{
+	0:  "EXIT",
+	1:  "HUP",
+	2:  "INT",
+	3:  "QUIT",
+	6:  "ABRT",
+	10: "USR1",
+	12: "USR2",
+	13: "PIPE",
+	14: "ALRM",
+	15: "TERM",
+}
+
+// trapConditions lists the conditions trap accepts by name, besides signals
+// given by number.
+var trapConditions = map[string]bool{
+	"EXIT":   true,
+	"ERR":    true,
+	"DEBUG":  true,
+	"RETURN": true,
+	"HUP":    true,
+	"INT":    true,
+	"QUIT":   true,
+	"ABRT":   true,
+	"USR1":   true,
+	"USR2":   true,
+	"PIPE":   true,
+	"ALRM":   true,
+	"TERM":   true,
+	"CHLD":   true,
+	"CONT":   true,
+	"TSTP":   true,
+	"WINCH":  true,
+}
+
+// TrapCondition returns the name of the condition a trap signal spec
+// stands for, such as INT for SIGINT, int or 2, or an empty string if the
+// spec is not one trap accepts.
+func TrapCondition(spec string) string {
+	if n, err := strconv.Atoi(spec); err == nil {
+		return trapSignalNumbers[n]
+	}
+	name := strings.TrimPrefix(strings.ToUpper(spec), "SIG")
+	if !trapConditions[name] {
+		return ""
+	}
+	return name
+}
+
+// TrapArgs splits the arguments of a trap command into its handler and
+// signal specs. A handler of "-" resets the signals; so does a lone spec
+// without a handler, which is reported as "-".
+func TrapArgs(args []string) (handler string, specs []string) {
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	switch len(args) {
+	case 0:
+		return "", nil
+	case 1:
+		return "-", args
+	}
+	return args[0], args[1:]
+}
+
+// recordTrap records the conditions a trap command sets a handler for, and
+// what the commands of the handler need from the script.
+func recordTrap(ir *IntermediateRepresentation, cmd Command) {
+	if len(cmd.Args) > 0 && strings.HasPrefix(cmd.Args[0], "-") && cmd.Args[0] != "-" && cmd.Args[0] != "--" {
+		// -p and -l only print traps and signals
+		return
+	}
+	handler, specs := TrapArgs(cmd.Args)
+	if handler == "-" || handler == "" {
+		return
+	}
+	for _, spec := range specs {
+		if name := TrapCondition(spec); name != "" {
+			ir.Traps[name] = true
+		}
+	}
+
+	// Blank lines in front put the commands on the line of the trap
+	text := handler
+	if cmd.Pos.Line > 1 {
+		text = strings.Repeat("\n", int(cmd.Pos.Line)-1) + handler
+	}
+	file, err := syntax.NewParser().Parse(strings.NewReader(text), ir.Filename)
+	if err != nil {
+		// The generator reports handlers it cannot parse
+		return
+	}
+	processNested(ir, file.Stmts)
+}
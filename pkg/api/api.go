@@ -0,0 +1,161 @@
+// Package api is the supported library interface to bash2go. It converts Bash
+// scripts to Go source code in-process, so other Go tools can embed the
+// transpiler instead of running the bash2go command.
+//
+// The types of this package are kept stable across releases; the parser and
+// generator packages behind it may change. Results are copied out of their
+// types rather than exposing them.
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/TFMV/bash2go/generator"
+	"github.com/TFMV/bash2go/parser"
+)
+
+// Options configures a conversion. The zero value converts with the same
+// defaults as the bash2go command.
+type Options struct {
+	// Filename is the name of the script used in source locations of
+	// diagnostics and generated error messages. ConvertFile sets it to the
+	// base name of the file if empty.
+	Filename string `json:"filename,omitempty"`
+
+	// EnvPolicy controls when environment variable references are expanded:
+	// "runtime" (the default) keeps them as os.Getenv calls, "convert"
+	// resolves them from the current environment during conversion.
+	EnvPolicy string `json:"envPolicy,omitempty"`
+	// ResolveEnv lists variables resolved during conversion regardless of
+	// EnvPolicy
+	ResolveEnv []string `json:"resolveEnv,omitempty"`
+	// RuntimeEnv lists variables kept as os.Getenv calls regardless of
+	// EnvPolicy
+	RuntimeEnv []string `json:"runtimeEnv,omitempty"`
+	// RuntimeEnvOnly keeps every variable as an os.Getenv call, ignoring
+	// EnvPolicy, ResolveEnv and the bash2go:env directives of the script, for
+	// services converting scripts for clients
+	RuntimeEnvOnly bool `json:"runtimeEnvOnly,omitempty"`
+
+	// BuildConstraint, if set, is emitted as a //go:build line
+	BuildConstraint string `json:"buildConstraint,omitempty"`
+
+	// TypeCheck validates the generated code with the Go type checker.
+	// Problems are added to the diagnostics and make Convert return an error.
+	TypeCheck bool `json:"typeCheck,omitempty"`
+}
+
+// Result is the outcome of a conversion
+type Result struct {
+	Code        string       `json:"code"`        // Generated Go source code of package main
+	Diagnostics []Diagnostic `json:"diagnostics"` // Problems found, in source order
+	Metrics     Metrics      `json:"metrics"`     // Share of the script translated natively
+}
+
+// Convert converts the Bash script src to Go. On a generation error the
+// result still carries the diagnostics collected so far.
+func Convert(src []byte, opts Options) (Result, error) {
+	genOptions, err := opts.generatorOptions()
+	if err != nil {
+		return Result{}, err
+	}
+
+	parsed, err := parser.ParseBashString(string(src))
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to parse Bash script: %v", err)
+	}
+	parsed.Filename = opts.Filename
+
+	ir, err := parser.BuildIR(parsed)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to build intermediate representation: %v", err)
+	}
+	return generate(ir, genOptions, opts.TypeCheck)
+}
+
+// Generate generates Go code from an intermediate representation in the
+// JSON format of parser.IntermediateRepresentation, which external tools can
+// produce or edit. Options.Filename, if set, overrides the file name recorded
+// in the IR.
+func Generate(irJSON []byte, opts Options) (Result, error) {
+	var ir parser.IntermediateRepresentation
+	if err := json.Unmarshal(irJSON, &ir); err != nil {
+		return Result{}, fmt.Errorf("failed to decode intermediate representation: %v", err)
+	}
+	return GenerateIR(&ir, opts)
+}
+
+// GenerateIR generates Go code from an intermediate representation built in
+// process, such as one decoded from another serialization. Unlike the rest of
+// this package, it exposes parser types, which may change between releases.
+func GenerateIR(ir *parser.IntermediateRepresentation, opts Options) (Result, error) {
+	genOptions, err := opts.generatorOptions()
+	if err != nil {
+		return Result{}, err
+	}
+	if opts.Filename != "" {
+		ir.Filename = opts.Filename
+	}
+	return generate(ir, genOptions, opts.TypeCheck)
+}
+
+// generate generates Go code for ir. On a generation error the result still
+// carries the diagnostics collected so far.
+func generate(ir *parser.IntermediateRepresentation, genOptions generator.Options, typeCheck bool) (Result, error) {
+	gen := generator.NewGoCodeGenerator(ir)
+	gen.Options = genOptions
+	code, err := gen.Generate()
+	if err == nil && typeCheck {
+		err = gen.TypeCheck(code)
+	}
+
+	result := Result{
+		Code:        code,
+		Diagnostics: newDiagnostics(ir.Diagnostics.Items()),
+		Metrics:     newMetrics(gen.Metrics()),
+	}
+	if err != nil {
+		return result, fmt.Errorf("failed to generate Go code: %v", err)
+	}
+	return result, nil
+}
+
+// ConvertFile reads the Bash script at path and converts it to Go
+func ConvertFile(path string, opts Options) (Result, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return Result{}, err
+	}
+	if opts.Filename == "" {
+		opts.Filename = filepath.Base(path)
+	}
+	return Convert(src, opts)
+}
+
+// generatorOptions translates opts to code generation options
+func (o Options) generatorOptions() (generator.Options, error) {
+	policy := parser.EnvRuntime
+	if o.EnvPolicy != "" {
+		var err error
+		if policy, err = parser.ParseEnvPolicy(o.EnvPolicy); err != nil {
+			return generator.Options{}, err
+		}
+	}
+
+	options := generator.Options{
+		DefaultEnvPolicy: policy,
+		EnvPolicies:      make(map[string]parser.EnvPolicy),
+		RuntimeEnvOnly:   o.RuntimeEnvOnly,
+		BuildConstraint:  o.BuildConstraint,
+	}
+	for _, name := range o.ResolveEnv {
+		options.EnvPolicies[name] = parser.EnvConvert
+	}
+	for _, name := range o.RuntimeEnv {
+		options.EnvPolicies[name] = parser.EnvRuntime
+	}
+	return options, nil
+}
@@ -0,0 +1,111 @@
+package api_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/bash2go/pkg/api"
+)
+
+// TestConvert tests converting a script from memory
+func TestConvert(t *testing.T) {
+	script := "#!/bin/bash\nNAME=world\necho \"hello $NAME\"\n"
+
+	result, err := api.Convert([]byte(script), api.Options{TypeCheck: true})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	if !strings.Contains(result.Code, "package main") {
+		t.Errorf("Expected a main package, got:\n%s", result.Code)
+	}
+	if !strings.Contains(result.Code, "NAME") {
+		t.Errorf("Expected the NAME variable in the generated code, got:\n%s", result.Code)
+	}
+	if result.Metrics.Native == 0 {
+		t.Errorf("Expected natively translated statements, got %+v", result.Metrics)
+	}
+}
+
+// TestConvertFile tests that converting a file names it in diagnostics
+func TestConvertFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deploy.sh")
+	if err := os.WriteFile(path, []byte("coproc worker\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := api.ConvertFile(path, api.Options{})
+	if err != nil {
+		t.Fatalf("ConvertFile failed: %v", err)
+	}
+	if len(result.Diagnostics) == 0 {
+		t.Fatal("Expected a diagnostic for the unsupported coproc")
+	}
+	if result.Diagnostics[0].File != "deploy.sh" {
+		t.Errorf("Expected diagnostics for deploy.sh, got %q", result.Diagnostics[0].File)
+	}
+}
+
+// TestResultJSON tests that results encode severities by name and decode
+// back into the types of the package
+func TestResultJSON(t *testing.T) {
+	result, err := api.Convert([]byte("coproc worker\necho hi\n"), api.Options{Filename: "deploy.sh"})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"severity":"error"`) {
+		t.Errorf("Expected the severity by name, got %s", data)
+	}
+
+	var decoded api.Result
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if len(decoded.Diagnostics) == 0 || decoded.Diagnostics[0].Severity != api.SeverityError {
+		t.Fatalf("Expected an error diagnostic, got %v", decoded.Diagnostics)
+	}
+	if got := decoded.Diagnostics[0].String(); !strings.HasPrefix(got, "deploy.sh:1:1: error: ") {
+		t.Errorf("Unexpected diagnostic text %q", got)
+	}
+	if decoded.Metrics.String() != result.Metrics.String() || decoded.Metrics.Unsupported == 0 {
+		t.Errorf("Expected the metrics to survive JSON, got %s", decoded.Metrics)
+	}
+}
+
+// TestConvertInvalidEnvPolicy tests that unknown options are rejected
+func TestConvertInvalidEnvPolicy(t *testing.T) {
+	if _, err := api.Convert([]byte("echo hi\n"), api.Options{EnvPolicy: "never"}); err == nil {
+		t.Error("Expected an error for an unknown environment policy")
+	}
+}
+
+// TestGenerate tests generating Go code from an IR built by another tool
+func TestGenerate(t *testing.T) {
+	ir := `{
+  "version": 1,
+  "filename": "external.sh",
+  "variables": {"GREETING": "hello"},
+  "mainStatements": [
+    {"type": "assignment", "value": {"name": "GREETING", "value": "hello"}, "pos": {"line": 1}},
+    {"type": "command", "value": {"name": "echo", "args": ["$GREETING"], "isBuiltin": true}, "pos": {"line": 2}}
+  ]
+}`
+	result, err := api.Generate([]byte(ir), api.Options{TypeCheck: true})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if !strings.Contains(result.Code, "GREETING") {
+		t.Errorf("Expected the GREETING variable in the generated code, got:\n%s", result.Code)
+	}
+
+	if _, err := api.Generate([]byte(`{"version": 2}`), api.Options{}); err == nil {
+		t.Error("Expected an error for an unsupported IR version")
+	}
+}
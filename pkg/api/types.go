@@ -0,0 +1,119 @@
+package api
+
+import (
+	"slices"
+
+	"github.com/TFMV/bash2go/diagnostics"
+	"github.com/TFMV/bash2go/generator"
+)
+
+// Severity classifies a diagnostic. It is encoded in JSON as its name.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+// String returns the lowercase name of the severity: info, warning or error
+func (s Severity) String() string {
+	return diagnostics.Severity(s).String()
+}
+
+// MarshalJSON encodes the severity as its name
+func (s Severity) MarshalJSON() ([]byte, error) {
+	return diagnostics.Severity(s).MarshalJSON()
+}
+
+// UnmarshalJSON decodes a severity from its name
+func (s *Severity) UnmarshalJSON(data []byte) error {
+	var severity diagnostics.Severity
+	if err := severity.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	*s = Severity(severity)
+	return nil
+}
+
+// Diagnostic is a problem found while converting a script
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code,omitempty"` // Kind of problem, such as unsupported-construct
+	Message  string   `json:"message"`
+	File     string   `json:"file,omitempty"`   // Script the problem is in
+	Line     uint     `json:"line,omitempty"`   // 1-based line in File, 0 if unknown
+	Column   uint     `json:"column,omitempty"` // 1-based column in Line, 0 if unknown
+}
+
+// String formats the diagnostic as "file:line:col: severity: message [code]"
+func (d Diagnostic) String() string {
+	return diagnostics.Diagnostic{
+		Severity: diagnostics.Severity(d.Severity),
+		Code:     d.Code,
+		Message:  d.Message,
+		File:     d.File,
+		Line:     d.Line,
+		Column:   d.Column,
+	}.String()
+}
+
+// newDiagnostics copies the diagnostics collected during a conversion
+func newDiagnostics(items []diagnostics.Diagnostic) []Diagnostic {
+	result := make([]Diagnostic, len(items))
+	for i, d := range items {
+		result[i] = Diagnostic{
+			Severity: Severity(d.Severity),
+			Code:     d.Code,
+			Message:  d.Message,
+			File:     d.File,
+			Line:     d.Line,
+			Column:   d.Column,
+		}
+	}
+	return result
+}
+
+// Metrics summarizes how much of a script was translated to native Go code
+type Metrics struct {
+	Native        int      `json:"native"`         // Statements translated to native Go
+	ExecFallbacks int      `json:"exec_fallbacks"` // Statements run as external commands
+	Unsupported   int      `json:"unsupported"`    // Constructs that could not be translated
+	Dependencies  []string `json:"dependencies"`   // Third-party packages imported by the generated code
+}
+
+// newMetrics copies the metrics of a conversion
+func newMetrics(m generator.Metrics) Metrics {
+	return Metrics{
+		Native:        m.Native,
+		ExecFallbacks: m.ExecFallbacks,
+		Unsupported:   m.Unsupported,
+		Dependencies:  slices.Clone(m.Dependencies),
+	}
+}
+
+// generatorMetrics converts m back for the methods implemented by the
+// generator
+func (m Metrics) generatorMetrics() generator.Metrics {
+	return generator.Metrics{
+		Native:        m.Native,
+		ExecFallbacks: m.ExecFallbacks,
+		Unsupported:   m.Unsupported,
+		Dependencies:  m.Dependencies,
+	}
+}
+
+// Total returns the number of statements and constructs considered
+func (m Metrics) Total() int {
+	return m.generatorMetrics().Total()
+}
+
+// NativePercent returns the percentage of statements translated natively
+func (m Metrics) NativePercent() float64 {
+	return m.generatorMetrics().NativePercent()
+}
+
+// String formats the metrics as a one-line summary
+func (m Metrics) String() string {
+	return m.generatorMetrics().String()
+}
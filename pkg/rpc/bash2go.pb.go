@@ -0,0 +1,1011 @@
+// Conversion service of bash2go, served by "bash2go serve --grpc ADDR".
+//
+// Regenerate the Go code after editing with:
+//
+//   protoc --go_out=. --go_opt=paths=source_relative \
+//     --go-grpc_out=. --go-grpc_opt=paths=source_relative \
+//     pkg/rpc/bash2go.proto pkg/rpc/ir.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: pkg/rpc/bash2go.proto
+
+package rpc
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Options configures a conversion
+type Options struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Name of the script used in source locations
+	Filename string `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	// When environment variables are expanded: "runtime" (default) or "convert"
+	EnvPolicy string `protobuf:"bytes,2,opt,name=env_policy,json=envPolicy,proto3" json:"env_policy,omitempty"`
+	// Variables resolved during conversion regardless of env_policy
+	ResolveEnv []string `protobuf:"bytes,3,rep,name=resolve_env,json=resolveEnv,proto3" json:"resolve_env,omitempty"`
+	// Variables kept as os.Getenv calls regardless of env_policy
+	RuntimeEnv []string `protobuf:"bytes,4,rep,name=runtime_env,json=runtimeEnv,proto3" json:"runtime_env,omitempty"`
+	// Build constraint emitted as a //go:build line
+	BuildConstraint string `protobuf:"bytes,5,opt,name=build_constraint,json=buildConstraint,proto3" json:"build_constraint,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Options) Reset() {
+	*x = Options{}
+	mi := &file_pkg_rpc_bash2go_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Options) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Options) ProtoMessage() {}
+
+func (x *Options) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpc_bash2go_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Options.ProtoReflect.Descriptor instead.
+func (*Options) Descriptor() ([]byte, []int) {
+	return file_pkg_rpc_bash2go_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Options) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *Options) GetEnvPolicy() string {
+	if x != nil {
+		return x.EnvPolicy
+	}
+	return ""
+}
+
+func (x *Options) GetResolveEnv() []string {
+	if x != nil {
+		return x.ResolveEnv
+	}
+	return nil
+}
+
+func (x *Options) GetRuntimeEnv() []string {
+	if x != nil {
+		return x.RuntimeEnv
+	}
+	return nil
+}
+
+func (x *Options) GetBuildConstraint() string {
+	if x != nil {
+		return x.BuildConstraint
+	}
+	return ""
+}
+
+// Metrics summarizes how much of a script was translated to native Go code
+type Metrics struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Native        int32                  `protobuf:"varint,1,opt,name=native,proto3" json:"native,omitempty"`
+	ExecFallbacks int32                  `protobuf:"varint,2,opt,name=exec_fallbacks,json=execFallbacks,proto3" json:"exec_fallbacks,omitempty"`
+	Unsupported   int32                  `protobuf:"varint,3,opt,name=unsupported,proto3" json:"unsupported,omitempty"`
+	Dependencies  []string               `protobuf:"bytes,4,rep,name=dependencies,proto3" json:"dependencies,omitempty"`
+	NativePercent float64                `protobuf:"fixed64,5,opt,name=native_percent,json=nativePercent,proto3" json:"native_percent,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Metrics) Reset() {
+	*x = Metrics{}
+	mi := &file_pkg_rpc_bash2go_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Metrics) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Metrics) ProtoMessage() {}
+
+func (x *Metrics) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpc_bash2go_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Metrics.ProtoReflect.Descriptor instead.
+func (*Metrics) Descriptor() ([]byte, []int) {
+	return file_pkg_rpc_bash2go_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Metrics) GetNative() int32 {
+	if x != nil {
+		return x.Native
+	}
+	return 0
+}
+
+func (x *Metrics) GetExecFallbacks() int32 {
+	if x != nil {
+		return x.ExecFallbacks
+	}
+	return 0
+}
+
+func (x *Metrics) GetUnsupported() int32 {
+	if x != nil {
+		return x.Unsupported
+	}
+	return 0
+}
+
+func (x *Metrics) GetDependencies() []string {
+	if x != nil {
+		return x.Dependencies
+	}
+	return nil
+}
+
+func (x *Metrics) GetNativePercent() float64 {
+	if x != nil {
+		return x.NativePercent
+	}
+	return 0
+}
+
+type ConvertRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Script        []byte                 `protobuf:"bytes,1,opt,name=script,proto3" json:"script,omitempty"`
+	Options       *Options               `protobuf:"bytes,2,opt,name=options,proto3" json:"options,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConvertRequest) Reset() {
+	*x = ConvertRequest{}
+	mi := &file_pkg_rpc_bash2go_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConvertRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConvertRequest) ProtoMessage() {}
+
+func (x *ConvertRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpc_bash2go_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConvertRequest.ProtoReflect.Descriptor instead.
+func (*ConvertRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_rpc_bash2go_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ConvertRequest) GetScript() []byte {
+	if x != nil {
+		return x.Script
+	}
+	return nil
+}
+
+func (x *ConvertRequest) GetOptions() *Options {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+type ConvertResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Code          string                 `protobuf:"bytes,1,opt,name=code,proto3" json:"code,omitempty"`
+	Diagnostics   []*Diagnostic          `protobuf:"bytes,2,rep,name=diagnostics,proto3" json:"diagnostics,omitempty"`
+	Metrics       *Metrics               `protobuf:"bytes,3,opt,name=metrics,proto3" json:"metrics,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConvertResponse) Reset() {
+	*x = ConvertResponse{}
+	mi := &file_pkg_rpc_bash2go_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConvertResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConvertResponse) ProtoMessage() {}
+
+func (x *ConvertResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpc_bash2go_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConvertResponse.ProtoReflect.Descriptor instead.
+func (*ConvertResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_rpc_bash2go_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ConvertResponse) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *ConvertResponse) GetDiagnostics() []*Diagnostic {
+	if x != nil {
+		return x.Diagnostics
+	}
+	return nil
+}
+
+func (x *ConvertResponse) GetMetrics() *Metrics {
+	if x != nil {
+		return x.Metrics
+	}
+	return nil
+}
+
+type CheckRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Script        []byte                 `protobuf:"bytes,1,opt,name=script,proto3" json:"script,omitempty"`
+	Options       *Options               `protobuf:"bytes,2,opt,name=options,proto3" json:"options,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckRequest) Reset() {
+	*x = CheckRequest{}
+	mi := &file_pkg_rpc_bash2go_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckRequest) ProtoMessage() {}
+
+func (x *CheckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpc_bash2go_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckRequest.ProtoReflect.Descriptor instead.
+func (*CheckRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_rpc_bash2go_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CheckRequest) GetScript() []byte {
+	if x != nil {
+		return x.Script
+	}
+	return nil
+}
+
+func (x *CheckRequest) GetOptions() *Options {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+type CheckResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Whether the script converts to Go code that type-checks
+	Ok bool `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	// Why the check failed, if it did
+	Error         string        `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Diagnostics   []*Diagnostic `protobuf:"bytes,3,rep,name=diagnostics,proto3" json:"diagnostics,omitempty"`
+	Metrics       *Metrics      `protobuf:"bytes,4,opt,name=metrics,proto3" json:"metrics,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckResponse) Reset() {
+	*x = CheckResponse{}
+	mi := &file_pkg_rpc_bash2go_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckResponse) ProtoMessage() {}
+
+func (x *CheckResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpc_bash2go_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckResponse.ProtoReflect.Descriptor instead.
+func (*CheckResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_rpc_bash2go_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CheckResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *CheckResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *CheckResponse) GetDiagnostics() []*Diagnostic {
+	if x != nil {
+		return x.Diagnostics
+	}
+	return nil
+}
+
+func (x *CheckResponse) GetMetrics() *Metrics {
+	if x != nil {
+		return x.Metrics
+	}
+	return nil
+}
+
+type BuildRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Script  []byte                 `protobuf:"bytes,1,opt,name=script,proto3" json:"script,omitempty"`
+	Options *Options               `protobuf:"bytes,2,opt,name=options,proto3" json:"options,omitempty"`
+	// Target platform; the server's platform if empty
+	Goos   string `protobuf:"bytes,3,opt,name=goos,proto3" json:"goos,omitempty"`
+	Goarch string `protobuf:"bytes,4,opt,name=goarch,proto3" json:"goarch,omitempty"`
+	// Build a static binary with cgo disabled
+	Static bool `protobuf:"varint,5,opt,name=static,proto3" json:"static,omitempty"`
+	// Strip the symbol table and debug info
+	Small         bool `protobuf:"varint,6,opt,name=small,proto3" json:"small,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BuildRequest) Reset() {
+	*x = BuildRequest{}
+	mi := &file_pkg_rpc_bash2go_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BuildRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BuildRequest) ProtoMessage() {}
+
+func (x *BuildRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpc_bash2go_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BuildRequest.ProtoReflect.Descriptor instead.
+func (*BuildRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_rpc_bash2go_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *BuildRequest) GetScript() []byte {
+	if x != nil {
+		return x.Script
+	}
+	return nil
+}
+
+func (x *BuildRequest) GetOptions() *Options {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+func (x *BuildRequest) GetGoos() string {
+	if x != nil {
+		return x.Goos
+	}
+	return ""
+}
+
+func (x *BuildRequest) GetGoarch() string {
+	if x != nil {
+		return x.Goarch
+	}
+	return ""
+}
+
+func (x *BuildRequest) GetStatic() bool {
+	if x != nil {
+		return x.Static
+	}
+	return false
+}
+
+func (x *BuildRequest) GetSmall() bool {
+	if x != nil {
+		return x.Small
+	}
+	return false
+}
+
+type BuildEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*BuildEvent_Log
+	//	*BuildEvent_BinaryChunk
+	//	*BuildEvent_Result
+	Event         isBuildEvent_Event `protobuf_oneof:"event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BuildEvent) Reset() {
+	*x = BuildEvent{}
+	mi := &file_pkg_rpc_bash2go_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BuildEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BuildEvent) ProtoMessage() {}
+
+func (x *BuildEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpc_bash2go_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BuildEvent.ProtoReflect.Descriptor instead.
+func (*BuildEvent) Descriptor() ([]byte, []int) {
+	return file_pkg_rpc_bash2go_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *BuildEvent) GetEvent() isBuildEvent_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *BuildEvent) GetLog() string {
+	if x != nil {
+		if x, ok := x.Event.(*BuildEvent_Log); ok {
+			return x.Log
+		}
+	}
+	return ""
+}
+
+func (x *BuildEvent) GetBinaryChunk() []byte {
+	if x != nil {
+		if x, ok := x.Event.(*BuildEvent_BinaryChunk); ok {
+			return x.BinaryChunk
+		}
+	}
+	return nil
+}
+
+func (x *BuildEvent) GetResult() *BuildResult {
+	if x != nil {
+		if x, ok := x.Event.(*BuildEvent_Result); ok {
+			return x.Result
+		}
+	}
+	return nil
+}
+
+type isBuildEvent_Event interface {
+	isBuildEvent_Event()
+}
+
+type BuildEvent_Log struct {
+	// Progress message
+	Log string `protobuf:"bytes,1,opt,name=log,proto3,oneof"`
+}
+
+type BuildEvent_BinaryChunk struct {
+	// Next part of the binary
+	BinaryChunk []byte `protobuf:"bytes,2,opt,name=binary_chunk,json=binaryChunk,proto3,oneof"`
+}
+
+type BuildEvent_Result struct {
+	// Final event of the stream
+	Result *BuildResult `protobuf:"bytes,3,opt,name=result,proto3,oneof"`
+}
+
+func (*BuildEvent_Log) isBuildEvent_Event() {}
+
+func (*BuildEvent_BinaryChunk) isBuildEvent_Event() {}
+
+func (*BuildEvent_Result) isBuildEvent_Event() {}
+
+type BuildResult struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Whether the binary was built; its chunks precede this event
+	Ok bool `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	// Why the build failed, if it did
+	Error string `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	// SHA-256 of the binary, hex-encoded
+	Sha256        string        `protobuf:"bytes,3,opt,name=sha256,proto3" json:"sha256,omitempty"`
+	Diagnostics   []*Diagnostic `protobuf:"bytes,4,rep,name=diagnostics,proto3" json:"diagnostics,omitempty"`
+	Metrics       *Metrics      `protobuf:"bytes,5,opt,name=metrics,proto3" json:"metrics,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BuildResult) Reset() {
+	*x = BuildResult{}
+	mi := &file_pkg_rpc_bash2go_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BuildResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BuildResult) ProtoMessage() {}
+
+func (x *BuildResult) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpc_bash2go_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BuildResult.ProtoReflect.Descriptor instead.
+func (*BuildResult) Descriptor() ([]byte, []int) {
+	return file_pkg_rpc_bash2go_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *BuildResult) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *BuildResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *BuildResult) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+func (x *BuildResult) GetDiagnostics() []*Diagnostic {
+	if x != nil {
+		return x.Diagnostics
+	}
+	return nil
+}
+
+func (x *BuildResult) GetMetrics() *Metrics {
+	if x != nil {
+		return x.Metrics
+	}
+	return nil
+}
+
+type ParseRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Script        []byte                 `protobuf:"bytes,1,opt,name=script,proto3" json:"script,omitempty"`
+	Options       *Options               `protobuf:"bytes,2,opt,name=options,proto3" json:"options,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ParseRequest) Reset() {
+	*x = ParseRequest{}
+	mi := &file_pkg_rpc_bash2go_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ParseRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ParseRequest) ProtoMessage() {}
+
+func (x *ParseRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpc_bash2go_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ParseRequest.ProtoReflect.Descriptor instead.
+func (*ParseRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_rpc_bash2go_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ParseRequest) GetScript() []byte {
+	if x != nil {
+		return x.Script
+	}
+	return nil
+}
+
+func (x *ParseRequest) GetOptions() *Options {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+type ParseResponse struct {
+	state         protoimpl.MessageState      `protogen:"open.v1"`
+	Ir            *IntermediateRepresentation `protobuf:"bytes,1,opt,name=ir,proto3" json:"ir,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ParseResponse) Reset() {
+	*x = ParseResponse{}
+	mi := &file_pkg_rpc_bash2go_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ParseResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ParseResponse) ProtoMessage() {}
+
+func (x *ParseResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpc_bash2go_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ParseResponse.ProtoReflect.Descriptor instead.
+func (*ParseResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_rpc_bash2go_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ParseResponse) GetIr() *IntermediateRepresentation {
+	if x != nil {
+		return x.Ir
+	}
+	return nil
+}
+
+type GenerateRequest struct {
+	state protoimpl.MessageState      `protogen:"open.v1"`
+	Ir    *IntermediateRepresentation `protobuf:"bytes,1,opt,name=ir,proto3" json:"ir,omitempty"`
+	// Options other than filename apply; filename overrides the one in ir if set
+	Options *Options `protobuf:"bytes,2,opt,name=options,proto3" json:"options,omitempty"`
+	// Type-check the generated code, failing the request on errors
+	TypeCheck     bool `protobuf:"varint,3,opt,name=type_check,json=typeCheck,proto3" json:"type_check,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GenerateRequest) Reset() {
+	*x = GenerateRequest{}
+	mi := &file_pkg_rpc_bash2go_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateRequest) ProtoMessage() {}
+
+func (x *GenerateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpc_bash2go_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateRequest.ProtoReflect.Descriptor instead.
+func (*GenerateRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_rpc_bash2go_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GenerateRequest) GetIr() *IntermediateRepresentation {
+	if x != nil {
+		return x.Ir
+	}
+	return nil
+}
+
+func (x *GenerateRequest) GetOptions() *Options {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+func (x *GenerateRequest) GetTypeCheck() bool {
+	if x != nil {
+		return x.TypeCheck
+	}
+	return false
+}
+
+var File_pkg_rpc_bash2go_proto protoreflect.FileDescriptor
+
+const file_pkg_rpc_bash2go_proto_rawDesc = "" +
+	"\n" +
+	"\x15pkg/rpc/bash2go.proto\x12\n" +
+	"bash2go.v1\x1a\x10pkg/rpc/ir.proto\"\xb1\x01\n" +
+	"\aOptions\x12\x1a\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x1d\n" +
+	"\n" +
+	"env_policy\x18\x02 \x01(\tR\tenvPolicy\x12\x1f\n" +
+	"\vresolve_env\x18\x03 \x03(\tR\n" +
+	"resolveEnv\x12\x1f\n" +
+	"\vruntime_env\x18\x04 \x03(\tR\n" +
+	"runtimeEnv\x12)\n" +
+	"\x10build_constraint\x18\x05 \x01(\tR\x0fbuildConstraint\"\xb5\x01\n" +
+	"\aMetrics\x12\x16\n" +
+	"\x06native\x18\x01 \x01(\x05R\x06native\x12%\n" +
+	"\x0eexec_fallbacks\x18\x02 \x01(\x05R\rexecFallbacks\x12 \n" +
+	"\vunsupported\x18\x03 \x01(\x05R\vunsupported\x12\"\n" +
+	"\fdependencies\x18\x04 \x03(\tR\fdependencies\x12%\n" +
+	"\x0enative_percent\x18\x05 \x01(\x01R\rnativePercent\"W\n" +
+	"\x0eConvertRequest\x12\x16\n" +
+	"\x06script\x18\x01 \x01(\fR\x06script\x12-\n" +
+	"\aoptions\x18\x02 \x01(\v2\x13.bash2go.v1.OptionsR\aoptions\"\x8e\x01\n" +
+	"\x0fConvertResponse\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\tR\x04code\x128\n" +
+	"\vdiagnostics\x18\x02 \x03(\v2\x16.bash2go.v1.DiagnosticR\vdiagnostics\x12-\n" +
+	"\ametrics\x18\x03 \x01(\v2\x13.bash2go.v1.MetricsR\ametrics\"U\n" +
+	"\fCheckRequest\x12\x16\n" +
+	"\x06script\x18\x01 \x01(\fR\x06script\x12-\n" +
+	"\aoptions\x18\x02 \x01(\v2\x13.bash2go.v1.OptionsR\aoptions\"\x9e\x01\n" +
+	"\rCheckResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x128\n" +
+	"\vdiagnostics\x18\x03 \x03(\v2\x16.bash2go.v1.DiagnosticR\vdiagnostics\x12-\n" +
+	"\ametrics\x18\x04 \x01(\v2\x13.bash2go.v1.MetricsR\ametrics\"\xaf\x01\n" +
+	"\fBuildRequest\x12\x16\n" +
+	"\x06script\x18\x01 \x01(\fR\x06script\x12-\n" +
+	"\aoptions\x18\x02 \x01(\v2\x13.bash2go.v1.OptionsR\aoptions\x12\x12\n" +
+	"\x04goos\x18\x03 \x01(\tR\x04goos\x12\x16\n" +
+	"\x06goarch\x18\x04 \x01(\tR\x06goarch\x12\x16\n" +
+	"\x06static\x18\x05 \x01(\bR\x06static\x12\x14\n" +
+	"\x05small\x18\x06 \x01(\bR\x05small\"\x81\x01\n" +
+	"\n" +
+	"BuildEvent\x12\x12\n" +
+	"\x03log\x18\x01 \x01(\tH\x00R\x03log\x12#\n" +
+	"\fbinary_chunk\x18\x02 \x01(\fH\x00R\vbinaryChunk\x121\n" +
+	"\x06result\x18\x03 \x01(\v2\x17.bash2go.v1.BuildResultH\x00R\x06resultB\a\n" +
+	"\x05event\"\xb4\x01\n" +
+	"\vBuildResult\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12\x16\n" +
+	"\x06sha256\x18\x03 \x01(\tR\x06sha256\x128\n" +
+	"\vdiagnostics\x18\x04 \x03(\v2\x16.bash2go.v1.DiagnosticR\vdiagnostics\x12-\n" +
+	"\ametrics\x18\x05 \x01(\v2\x13.bash2go.v1.MetricsR\ametrics\"U\n" +
+	"\fParseRequest\x12\x16\n" +
+	"\x06script\x18\x01 \x01(\fR\x06script\x12-\n" +
+	"\aoptions\x18\x02 \x01(\v2\x13.bash2go.v1.OptionsR\aoptions\"G\n" +
+	"\rParseResponse\x126\n" +
+	"\x02ir\x18\x01 \x01(\v2&.bash2go.v1.IntermediateRepresentationR\x02ir\"\x97\x01\n" +
+	"\x0fGenerateRequest\x126\n" +
+	"\x02ir\x18\x01 \x01(\v2&.bash2go.v1.IntermediateRepresentationR\x02ir\x12-\n" +
+	"\aoptions\x18\x02 \x01(\v2\x13.bash2go.v1.OptionsR\aoptions\x12\x1d\n" +
+	"\n" +
+	"type_check\x18\x03 \x01(\bR\ttypeCheck2\xce\x02\n" +
+	"\tConverter\x12B\n" +
+	"\aConvert\x12\x1a.bash2go.v1.ConvertRequest\x1a\x1b.bash2go.v1.ConvertResponse\x12<\n" +
+	"\x05Check\x12\x18.bash2go.v1.CheckRequest\x1a\x19.bash2go.v1.CheckResponse\x12;\n" +
+	"\x05Build\x12\x18.bash2go.v1.BuildRequest\x1a\x16.bash2go.v1.BuildEvent0\x01\x12<\n" +
+	"\x05Parse\x12\x18.bash2go.v1.ParseRequest\x1a\x19.bash2go.v1.ParseResponse\x12D\n" +
+	"\bGenerate\x12\x1b.bash2go.v1.GenerateRequest\x1a\x1b.bash2go.v1.ConvertResponseB!Z\x1fgithub.com/TFMV/bash2go/pkg/rpcb\x06proto3"
+
+var (
+	file_pkg_rpc_bash2go_proto_rawDescOnce sync.Once
+	file_pkg_rpc_bash2go_proto_rawDescData []byte
+)
+
+func file_pkg_rpc_bash2go_proto_rawDescGZIP() []byte {
+	file_pkg_rpc_bash2go_proto_rawDescOnce.Do(func() {
+		file_pkg_rpc_bash2go_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_pkg_rpc_bash2go_proto_rawDesc), len(file_pkg_rpc_bash2go_proto_rawDesc)))
+	})
+	return file_pkg_rpc_bash2go_proto_rawDescData
+}
+
+var file_pkg_rpc_bash2go_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_pkg_rpc_bash2go_proto_goTypes = []any{
+	(*Options)(nil),                    // 0: bash2go.v1.Options
+	(*Metrics)(nil),                    // 1: bash2go.v1.Metrics
+	(*ConvertRequest)(nil),             // 2: bash2go.v1.ConvertRequest
+	(*ConvertResponse)(nil),            // 3: bash2go.v1.ConvertResponse
+	(*CheckRequest)(nil),               // 4: bash2go.v1.CheckRequest
+	(*CheckResponse)(nil),              // 5: bash2go.v1.CheckResponse
+	(*BuildRequest)(nil),               // 6: bash2go.v1.BuildRequest
+	(*BuildEvent)(nil),                 // 7: bash2go.v1.BuildEvent
+	(*BuildResult)(nil),                // 8: bash2go.v1.BuildResult
+	(*ParseRequest)(nil),               // 9: bash2go.v1.ParseRequest
+	(*ParseResponse)(nil),              // 10: bash2go.v1.ParseResponse
+	(*GenerateRequest)(nil),            // 11: bash2go.v1.GenerateRequest
+	(*Diagnostic)(nil),                 // 12: bash2go.v1.Diagnostic
+	(*IntermediateRepresentation)(nil), // 13: bash2go.v1.IntermediateRepresentation
+}
+var file_pkg_rpc_bash2go_proto_depIdxs = []int32{
+	0,  // 0: bash2go.v1.ConvertRequest.options:type_name -> bash2go.v1.Options
+	12, // 1: bash2go.v1.ConvertResponse.diagnostics:type_name -> bash2go.v1.Diagnostic
+	1,  // 2: bash2go.v1.ConvertResponse.metrics:type_name -> bash2go.v1.Metrics
+	0,  // 3: bash2go.v1.CheckRequest.options:type_name -> bash2go.v1.Options
+	12, // 4: bash2go.v1.CheckResponse.diagnostics:type_name -> bash2go.v1.Diagnostic
+	1,  // 5: bash2go.v1.CheckResponse.metrics:type_name -> bash2go.v1.Metrics
+	0,  // 6: bash2go.v1.BuildRequest.options:type_name -> bash2go.v1.Options
+	8,  // 7: bash2go.v1.BuildEvent.result:type_name -> bash2go.v1.BuildResult
+	12, // 8: bash2go.v1.BuildResult.diagnostics:type_name -> bash2go.v1.Diagnostic
+	1,  // 9: bash2go.v1.BuildResult.metrics:type_name -> bash2go.v1.Metrics
+	0,  // 10: bash2go.v1.ParseRequest.options:type_name -> bash2go.v1.Options
+	13, // 11: bash2go.v1.ParseResponse.ir:type_name -> bash2go.v1.IntermediateRepresentation
+	13, // 12: bash2go.v1.GenerateRequest.ir:type_name -> bash2go.v1.IntermediateRepresentation
+	0,  // 13: bash2go.v1.GenerateRequest.options:type_name -> bash2go.v1.Options
+	2,  // 14: bash2go.v1.Converter.Convert:input_type -> bash2go.v1.ConvertRequest
+	4,  // 15: bash2go.v1.Converter.Check:input_type -> bash2go.v1.CheckRequest
+	6,  // 16: bash2go.v1.Converter.Build:input_type -> bash2go.v1.BuildRequest
+	9,  // 17: bash2go.v1.Converter.Parse:input_type -> bash2go.v1.ParseRequest
+	11, // 18: bash2go.v1.Converter.Generate:input_type -> bash2go.v1.GenerateRequest
+	3,  // 19: bash2go.v1.Converter.Convert:output_type -> bash2go.v1.ConvertResponse
+	5,  // 20: bash2go.v1.Converter.Check:output_type -> bash2go.v1.CheckResponse
+	7,  // 21: bash2go.v1.Converter.Build:output_type -> bash2go.v1.BuildEvent
+	10, // 22: bash2go.v1.Converter.Parse:output_type -> bash2go.v1.ParseResponse
+	3,  // 23: bash2go.v1.Converter.Generate:output_type -> bash2go.v1.ConvertResponse
+	19, // [19:24] is the sub-list for method output_type
+	14, // [14:19] is the sub-list for method input_type
+	14, // [14:14] is the sub-list for extension type_name
+	14, // [14:14] is the sub-list for extension extendee
+	0,  // [0:14] is the sub-list for field type_name
+}
+
+func init() { file_pkg_rpc_bash2go_proto_init() }
+func file_pkg_rpc_bash2go_proto_init() {
+	if File_pkg_rpc_bash2go_proto != nil {
+		return
+	}
+	file_pkg_rpc_ir_proto_init()
+	file_pkg_rpc_bash2go_proto_msgTypes[7].OneofWrappers = []any{
+		(*BuildEvent_Log)(nil),
+		(*BuildEvent_BinaryChunk)(nil),
+		(*BuildEvent_Result)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pkg_rpc_bash2go_proto_rawDesc), len(file_pkg_rpc_bash2go_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   12,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_pkg_rpc_bash2go_proto_goTypes,
+		DependencyIndexes: file_pkg_rpc_bash2go_proto_depIdxs,
+		MessageInfos:      file_pkg_rpc_bash2go_proto_msgTypes,
+	}.Build()
+	File_pkg_rpc_bash2go_proto = out.File
+	file_pkg_rpc_bash2go_proto_goTypes = nil
+	file_pkg_rpc_bash2go_proto_depIdxs = nil
+}
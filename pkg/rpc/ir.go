@@ -0,0 +1,454 @@
+package rpc
+
+import (
+	"fmt"
+
+	"github.com/TFMV/bash2go/diagnostics"
+	"github.com/TFMV/bash2go/parser"
+)
+
+// IRToProto converts an intermediate representation to its message
+func IRToProto(ir *parser.IntermediateRepresentation) (*IntermediateRepresentation, error) {
+	m := &IntermediateRepresentation{
+		Version:          parser.IRFormatVersion,
+		Filename:         ir.Filename,
+		Variables:        ir.Variables,
+		Functions:        make(map[string]*Function, len(ir.Functions)),
+		RequiredPackages: ir.RequiredPackages,
+		EnvPolicies:      make(map[string]string, len(ir.EnvPolicies)),
+		ShellOptions:     ir.ShellOptions,
+		SpecialVars:      ir.SpecialVars,
+		Subcommands:      ir.Subcommands,
+	}
+	var err error
+	if m.MainStatements, err = statementsToProto(ir.MainStatements); err != nil {
+		return nil, err
+	}
+	for name, function := range ir.Functions {
+		if m.Functions[name], err = functionToProto(function); err != nil {
+			return nil, err
+		}
+	}
+	for name, policy := range ir.EnvPolicies {
+		m.EnvPolicies[name] = string(policy)
+	}
+	if ir.Diagnostics != nil {
+		m.Diagnostics = toDiagnostics(ir.Diagnostics.Items())
+	}
+	return m, nil
+}
+
+// IRFromProto converts a message to an intermediate representation. Messages
+// of another format version, statements without a value and unknown
+// environment policies are rejected.
+func IRFromProto(m *IntermediateRepresentation) (*parser.IntermediateRepresentation, error) {
+	if m.GetVersion() != parser.IRFormatVersion {
+		return nil, fmt.Errorf("unsupported IR format version %d (want %d)", m.GetVersion(), parser.IRFormatVersion)
+	}
+
+	ir := parser.NewIntermediateRepresentation()
+	ir.Filename = m.Filename
+	ir.Subcommands = m.Subcommands
+	var err error
+	if ir.MainStatements, err = statementsFromProto(m.MainStatements); err != nil {
+		return nil, err
+	}
+	for name, function := range m.Functions {
+		if ir.Functions[name], err = functionFromProto(function); err != nil {
+			return nil, fmt.Errorf("function %s: %v", name, err)
+		}
+		if ir.Functions[name].Name == "" {
+			ir.Functions[name].Name = name
+		}
+	}
+	for _, name := range m.Subcommands {
+		if ir.Functions[name] == nil {
+			return nil, fmt.Errorf("subcommand %s is not a function", name)
+		}
+	}
+	for name, value := range m.Variables {
+		ir.Variables[name] = value
+	}
+	for name, policy := range m.EnvPolicies {
+		if ir.EnvPolicies[name], err = parser.ParseEnvPolicy(policy); err != nil {
+			return nil, fmt.Errorf("variable %s: %v", name, err)
+		}
+	}
+	for name, required := range m.RequiredPackages {
+		ir.RequiredPackages[name] = required
+	}
+	for name, enabled := range m.ShellOptions {
+		ir.ShellOptions[name] = enabled
+	}
+	for name, read := range m.SpecialVars {
+		ir.SpecialVars[name] = read
+	}
+	ir.RequiredPackages["fmt"] = true
+	ir.RequiredPackages["os"] = true
+	for _, d := range m.Diagnostics {
+		ir.Diagnostics.Add(diagnostics.Diagnostic{
+			Severity: diagnostics.Severity(d.Severity),
+			Code:     d.Code,
+			Message:  d.Message,
+			File:     d.File,
+			Line:     uint(d.Line),
+			Column:   uint(d.Column),
+		})
+	}
+	return ir, nil
+}
+
+// statementsToProto converts statements to messages
+func statementsToProto(statements []parser.Statement) ([]*Statement, error) {
+	result := make([]*Statement, len(statements))
+	for i, stmt := range statements {
+		m := &Statement{Pos: positionToProto(stmt.Pos)}
+		switch v := stmt.Value.(type) {
+		case parser.Command:
+			m.Value = &Statement_Command{Command: commandToProto(v)}
+		case parser.Assignment:
+			m.Value = &Statement_Assignment{Assignment: &Assignment{
+				Name:     v.Name,
+				Value:    v.Value,
+				IsLocal:  v.IsLocal,
+				IsExport: v.IsExport,
+			}}
+		case parser.If:
+			ifStmt, err := ifToProto(v)
+			if err != nil {
+				return nil, err
+			}
+			m.Value = &Statement_If{If: ifStmt}
+		case parser.Loop:
+			loop, err := loopToProto(v)
+			if err != nil {
+				return nil, err
+			}
+			m.Value = &Statement_Loop{Loop: loop}
+		case parser.Pipe:
+			pipe := &Pipe{Commands: make([]*Command, len(v.Commands))}
+			for j, cmd := range v.Commands {
+				pipe.Commands[j] = commandToProto(cmd)
+			}
+			m.Value = &Statement_Pipe{Pipe: pipe}
+		case parser.Subshell:
+			body, err := statementsToProto(v.Statements)
+			if err != nil {
+				return nil, err
+			}
+			m.Value = &Statement_Subshell{Subshell: &Subshell{Statements: body, Vars: v.Vars}}
+		case *parser.Function:
+			function, err := functionToProto(v)
+			if err != nil {
+				return nil, err
+			}
+			m.Value = &Statement_Function{Function: function}
+		case parser.Redirection:
+			m.Value = &Statement_Redirection{Redirection: redirectionToProto(v)}
+		case parser.Background:
+			m.Value = &Statement_Background{Background: &Background{Command: commandToProto(v.Command)}}
+		case parser.Return:
+			m.Value = &Statement_Return{Return: &Return{Value: v.Value, Code: int32(v.Code)}}
+		default:
+			return nil, fmt.Errorf("statement at %s has unknown value %T", stmt.Pos, stmt.Value)
+		}
+		result[i] = m
+	}
+	return result, nil
+}
+
+// statementsFromProto converts messages to statements
+func statementsFromProto(messages []*Statement) ([]parser.Statement, error) {
+	result := make([]parser.Statement, len(messages))
+	for i, m := range messages {
+		stmt := parser.Statement{Pos: positionFromProto(m.GetPos())}
+		var err error
+		switch v := m.GetValue().(type) {
+		case *Statement_Command:
+			stmt.Type, stmt.Value = parser.StatementCommand, commandFromProto(v.Command)
+		case *Statement_Assignment:
+			stmt.Type, stmt.Value = parser.StatementAssignment, parser.Assignment{
+				Name:     v.Assignment.GetName(),
+				Value:    v.Assignment.GetValue(),
+				IsLocal:  v.Assignment.GetIsLocal(),
+				IsExport: v.Assignment.GetIsExport(),
+			}
+		case *Statement_If:
+			stmt.Type = parser.StatementIf
+			stmt.Value, err = ifFromProto(v.If)
+		case *Statement_Loop:
+			stmt.Type = parser.StatementLoop
+			stmt.Value, err = loopFromProto(v.Loop)
+		case *Statement_Pipe:
+			pipe := parser.Pipe{Commands: make([]parser.Command, len(v.Pipe.GetCommands()))}
+			for j, cmd := range v.Pipe.GetCommands() {
+				pipe.Commands[j] = commandFromProto(cmd)
+			}
+			stmt.Type, stmt.Value = parser.StatementPipe, pipe
+		case *Statement_Subshell:
+			var body []parser.Statement
+			body, err = statementsFromProto(v.Subshell.GetStatements())
+			stmt.Type, stmt.Value = parser.StatementSubshell, parser.Subshell{Statements: body, Vars: v.Subshell.GetVars()}
+		case *Statement_Function:
+			stmt.Type = parser.StatementFunction
+			stmt.Value, err = functionFromProto(v.Function)
+		case *Statement_Redirection:
+			stmt.Type, stmt.Value = parser.StatementRedirection, redirectionFromProto(v.Redirection)
+		case *Statement_Background:
+			stmt.Type, stmt.Value = parser.StatementBackground, parser.Background{
+				Command: commandFromProto(v.Background.GetCommand()),
+			}
+		case *Statement_Return:
+			stmt.Type, stmt.Value = parser.StatementReturn, parser.Return{
+				Value: v.Return.GetValue(),
+				Code:  int(v.Return.GetCode()),
+			}
+		default:
+			return nil, fmt.Errorf("statement at %s has no value", stmt.Pos)
+		}
+		if err != nil {
+			return nil, err
+		}
+		result[i] = stmt
+	}
+	return result, nil
+}
+
+// functionToProto converts a function to its message
+func functionToProto(f *parser.Function) (*Function, error) {
+	body, err := statementsToProto(f.Statements)
+	if err != nil {
+		return nil, err
+	}
+	return &Function{
+		Name:       f.Name,
+		Statements: body,
+		Parameters: f.Parameters,
+		LocalVars:  f.LocalVars,
+		Pos:        positionToProto(f.Pos),
+	}, nil
+}
+
+// functionFromProto converts a message to a function
+func functionFromProto(m *Function) (*parser.Function, error) {
+	body, err := statementsFromProto(m.GetStatements())
+	if err != nil {
+		return nil, err
+	}
+	f := &parser.Function{
+		Name:       m.GetName(),
+		Statements: body,
+		Parameters: m.GetParameters(),
+		LocalVars:  make(map[string]string),
+		Pos:        positionFromProto(m.GetPos()),
+	}
+	for name, value := range m.GetLocalVars() {
+		f.LocalVars[name] = value
+	}
+	return f, nil
+}
+
+// ifToProto converts an if statement to its message
+func ifToProto(v parser.If) (*If, error) {
+	m := &If{ConditionType: v.ConditionType}
+	var err error
+	if m.Condition, err = statementsToProto(v.Condition); err != nil {
+		return nil, err
+	}
+	if m.ThenBlock, err = statementsToProto(v.ThenBlock); err != nil {
+		return nil, err
+	}
+	if m.ElseBlock, err = statementsToProto(v.ElseBlock); err != nil {
+		return nil, err
+	}
+	for _, elif := range v.ElifBlocks {
+		block := &ElifBlock{}
+		if block.Condition, err = statementsToProto(elif[0]); err != nil {
+			return nil, err
+		}
+		if block.ThenBlock, err = statementsToProto(elif[1]); err != nil {
+			return nil, err
+		}
+		m.ElifBlocks = append(m.ElifBlocks, block)
+	}
+	return m, nil
+}
+
+// ifFromProto converts a message to an if statement
+func ifFromProto(m *If) (parser.If, error) {
+	v := parser.If{ConditionType: m.GetConditionType()}
+	var err error
+	if v.Condition, err = statementsFromProto(m.GetCondition()); err != nil {
+		return v, err
+	}
+	if v.ThenBlock, err = statementsFromProto(m.GetThenBlock()); err != nil {
+		return v, err
+	}
+	if v.ElseBlock, err = statementsFromProto(m.GetElseBlock()); err != nil {
+		return v, err
+	}
+	for _, block := range m.GetElifBlocks() {
+		var elif [2][]parser.Statement
+		if elif[0], err = statementsFromProto(block.GetCondition()); err != nil {
+			return v, err
+		}
+		if elif[1], err = statementsFromProto(block.GetThenBlock()); err != nil {
+			return v, err
+		}
+		v.ElifBlocks = append(v.ElifBlocks, elif)
+	}
+	return v, nil
+}
+
+// loopToProto converts a loop to its message
+func loopToProto(v parser.Loop) (*Loop, error) {
+	m := &Loop{
+		Type:      v.Type,
+		IsRange:   v.IsRange,
+		RangeVar:  v.RangeVar,
+		RangeFrom: v.RangeFrom,
+		RangeTo:   v.RangeTo,
+		IsForEach: v.IsForEach,
+		Items:     v.Items,
+	}
+	var err error
+	if m.Init, err = statementsToProto(v.Init); err != nil {
+		return nil, err
+	}
+	if m.Condition, err = statementsToProto(v.Condition); err != nil {
+		return nil, err
+	}
+	if m.Update, err = statementsToProto(v.Update); err != nil {
+		return nil, err
+	}
+	if m.Body, err = statementsToProto(v.Body); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// loopFromProto converts a message to a loop
+func loopFromProto(m *Loop) (parser.Loop, error) {
+	v := parser.Loop{
+		Type:      m.GetType(),
+		IsRange:   m.GetIsRange(),
+		RangeVar:  m.GetRangeVar(),
+		RangeFrom: m.GetRangeFrom(),
+		RangeTo:   m.GetRangeTo(),
+		IsForEach: m.GetIsForEach(),
+		Items:     m.GetItems(),
+	}
+	var err error
+	if v.Init, err = statementsFromProto(m.GetInit()); err != nil {
+		return v, err
+	}
+	if v.Condition, err = statementsFromProto(m.GetCondition()); err != nil {
+		return v, err
+	}
+	if v.Update, err = statementsFromProto(m.GetUpdate()); err != nil {
+		return v, err
+	}
+	if v.Body, err = statementsFromProto(m.GetBody()); err != nil {
+		return v, err
+	}
+	return v, nil
+}
+
+// commandToProto converts a command to its message
+func commandToProto(c parser.Command) *Command {
+	return &Command{
+		Name:      c.Name,
+		Args:      c.Args,
+		IsBuiltin: c.IsBuiltin,
+		UseGexe:   c.UseGexe,
+		Pos:       positionToProto(c.Pos),
+		Globs:     indicesToProto(c.Globs),
+		Splits:    indicesToProto(c.Splits),
+		Redirects: redirectionsToProto(c.Redirects),
+	}
+}
+
+// commandFromProto converts a message to a command
+func commandFromProto(m *Command) parser.Command {
+	return parser.Command{
+		Name:      m.GetName(),
+		Args:      m.GetArgs(),
+		IsBuiltin: m.GetIsBuiltin(),
+		UseGexe:   m.GetUseGexe(),
+		Pos:       positionFromProto(m.GetPos()),
+		Globs:     indicesFromProto(m.GetGlobs()),
+		Splits:    indicesFromProto(m.GetSplits()),
+		Redirects: redirectionsFromProto(m.GetRedirects()),
+	}
+}
+
+// redirectionToProto converts a redirection to its message
+func redirectionToProto(r parser.Redirection) *Redirection {
+	return &Redirection{
+		Op:       r.Op,
+		Fd:       r.Fd,
+		Command:  commandToProto(r.Command),
+		Filename: r.Filename,
+		Pos:      positionToProto(r.Pos),
+	}
+}
+
+// redirectionFromProto converts a message to a redirection
+func redirectionFromProto(m *Redirection) parser.Redirection {
+	return parser.Redirection{
+		Op:       m.GetOp(),
+		Fd:       m.GetFd(),
+		Command:  commandFromProto(m.GetCommand()),
+		Filename: m.GetFilename(),
+		Pos:      positionFromProto(m.GetPos()),
+	}
+}
+
+// redirectionsToProto converts the redirections of a command to messages
+func redirectionsToProto(redirects []parser.Redirection) []*Redirection {
+	var m []*Redirection
+	for _, r := range redirects {
+		m = append(m, redirectionToProto(r))
+	}
+	return m
+}
+
+// redirectionsFromProto converts messages to the redirections of a command
+func redirectionsFromProto(m []*Redirection) []parser.Redirection {
+	var redirects []parser.Redirection
+	for _, r := range m {
+		redirects = append(redirects, redirectionFromProto(r))
+	}
+	return redirects
+}
+
+// indicesToProto converts indices of words to their message field
+func indicesToProto(indices []int) []int32 {
+	var m []int32
+	for _, i := range indices {
+		m = append(m, int32(i))
+	}
+	return m
+}
+
+// indicesFromProto converts a message field to indices of words
+func indicesFromProto(m []int32) []int {
+	var indices []int
+	for _, i := range m {
+		indices = append(indices, int(i))
+	}
+	return indices
+}
+
+// positionToProto converts a position to its message, or nil if unknown
+func positionToProto(p parser.Position) *Position {
+	if p == (parser.Position{}) {
+		return nil
+	}
+	return &Position{File: p.File, Line: uint32(p.Line), Column: uint32(p.Column)}
+}
+
+// positionFromProto converts a message to a position
+func positionFromProto(m *Position) parser.Position {
+	return parser.Position{File: m.GetFile(), Line: uint(m.GetLine()), Column: uint(m.GetColumn())}
+}
@@ -0,0 +1,1625 @@
+// Intermediate representation of bash2go, the form a Bash script takes
+// between parsing and Go code generation. It mirrors the structs of the
+// parser package, which this package converts to and from.
+//
+// Regenerate the Go code after editing with:
+//
+//   protoc --go_out=. --go_opt=paths=source_relative \
+//     --go-grpc_out=. --go-grpc_opt=paths=source_relative \
+//     pkg/rpc/bash2go.proto pkg/rpc/ir.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: pkg/rpc/ir.proto
+
+package rpc
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Severity classifies a diagnostic
+type Severity int32
+
+const (
+	Severity_SEVERITY_INFO    Severity = 0
+	Severity_SEVERITY_WARNING Severity = 1
+	Severity_SEVERITY_ERROR   Severity = 2
+)
+
+// Enum value maps for Severity.
+var (
+	Severity_name = map[int32]string{
+		0: "SEVERITY_INFO",
+		1: "SEVERITY_WARNING",
+		2: "SEVERITY_ERROR",
+	}
+	Severity_value = map[string]int32{
+		"SEVERITY_INFO":    0,
+		"SEVERITY_WARNING": 1,
+		"SEVERITY_ERROR":   2,
+	}
+)
+
+func (x Severity) Enum() *Severity {
+	p := new(Severity)
+	*p = x
+	return p
+}
+
+func (x Severity) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Severity) Descriptor() protoreflect.EnumDescriptor {
+	return file_pkg_rpc_ir_proto_enumTypes[0].Descriptor()
+}
+
+func (Severity) Type() protoreflect.EnumType {
+	return &file_pkg_rpc_ir_proto_enumTypes[0]
+}
+
+func (x Severity) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Severity.Descriptor instead.
+func (Severity) EnumDescriptor() ([]byte, []int) {
+	return file_pkg_rpc_ir_proto_rawDescGZIP(), []int{0}
+}
+
+// IntermediateRepresentation is a parsed script ready for code generation
+type IntermediateRepresentation struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Format version of the representation, parser.IRFormatVersion; readers
+	// reject versions they do not know
+	Version uint32 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	// Base name of the source script, if known
+	Filename string `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
+	// Global variables with their initial values
+	Variables map[string]string    `protobuf:"bytes,3,rep,name=variables,proto3" json:"variables,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Functions map[string]*Function `protobuf:"bytes,4,rep,name=functions,proto3" json:"functions,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Top-level statements of the script
+	MainStatements   []*Statement    `protobuf:"bytes,5,rep,name=main_statements,json=mainStatements,proto3" json:"main_statements,omitempty"`
+	RequiredPackages map[string]bool `protobuf:"bytes,6,rep,name=required_packages,json=requiredPackages,proto3" json:"required_packages,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	// Per-variable policies from bash2go:env directives: "runtime" or "convert"
+	EnvPolicies map[string]string `protobuf:"bytes,7,rep,name=env_policies,json=envPolicies,proto3" json:"env_policies,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Options enabled or disabled with shopt
+	ShellOptions map[string]bool `protobuf:"bytes,8,rep,name=shell_options,json=shellOptions,proto3" json:"shell_options,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	// Special variables such as $? and FUNCNAME read by the script
+	SpecialVars map[string]bool `protobuf:"bytes,9,rep,name=special_vars,json=specialVars,proto3" json:"special_vars,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	// Functions run as subcommands, if combined from several scripts
+	Subcommands   []string      `protobuf:"bytes,10,rep,name=subcommands,proto3" json:"subcommands,omitempty"`
+	Diagnostics   []*Diagnostic `protobuf:"bytes,11,rep,name=diagnostics,proto3" json:"diagnostics,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IntermediateRepresentation) Reset() {
+	*x = IntermediateRepresentation{}
+	mi := &file_pkg_rpc_ir_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IntermediateRepresentation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IntermediateRepresentation) ProtoMessage() {}
+
+func (x *IntermediateRepresentation) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpc_ir_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IntermediateRepresentation.ProtoReflect.Descriptor instead.
+func (*IntermediateRepresentation) Descriptor() ([]byte, []int) {
+	return file_pkg_rpc_ir_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *IntermediateRepresentation) GetVersion() uint32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *IntermediateRepresentation) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *IntermediateRepresentation) GetVariables() map[string]string {
+	if x != nil {
+		return x.Variables
+	}
+	return nil
+}
+
+func (x *IntermediateRepresentation) GetFunctions() map[string]*Function {
+	if x != nil {
+		return x.Functions
+	}
+	return nil
+}
+
+func (x *IntermediateRepresentation) GetMainStatements() []*Statement {
+	if x != nil {
+		return x.MainStatements
+	}
+	return nil
+}
+
+func (x *IntermediateRepresentation) GetRequiredPackages() map[string]bool {
+	if x != nil {
+		return x.RequiredPackages
+	}
+	return nil
+}
+
+func (x *IntermediateRepresentation) GetEnvPolicies() map[string]string {
+	if x != nil {
+		return x.EnvPolicies
+	}
+	return nil
+}
+
+func (x *IntermediateRepresentation) GetShellOptions() map[string]bool {
+	if x != nil {
+		return x.ShellOptions
+	}
+	return nil
+}
+
+func (x *IntermediateRepresentation) GetSpecialVars() map[string]bool {
+	if x != nil {
+		return x.SpecialVars
+	}
+	return nil
+}
+
+func (x *IntermediateRepresentation) GetSubcommands() []string {
+	if x != nil {
+		return x.Subcommands
+	}
+	return nil
+}
+
+func (x *IntermediateRepresentation) GetDiagnostics() []*Diagnostic {
+	if x != nil {
+		return x.Diagnostics
+	}
+	return nil
+}
+
+// Position is a location in the source script
+type Position struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	File          string                 `protobuf:"bytes,1,opt,name=file,proto3" json:"file,omitempty"`
+	Line          uint32                 `protobuf:"varint,2,opt,name=line,proto3" json:"line,omitempty"`
+	Column        uint32                 `protobuf:"varint,3,opt,name=column,proto3" json:"column,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Position) Reset() {
+	*x = Position{}
+	mi := &file_pkg_rpc_ir_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Position) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Position) ProtoMessage() {}
+
+func (x *Position) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpc_ir_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Position.ProtoReflect.Descriptor instead.
+func (*Position) Descriptor() ([]byte, []int) {
+	return file_pkg_rpc_ir_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Position) GetFile() string {
+	if x != nil {
+		return x.File
+	}
+	return ""
+}
+
+func (x *Position) GetLine() uint32 {
+	if x != nil {
+		return x.Line
+	}
+	return 0
+}
+
+func (x *Position) GetColumn() uint32 {
+	if x != nil {
+		return x.Column
+	}
+	return 0
+}
+
+type Function struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Statements    []*Statement           `protobuf:"bytes,2,rep,name=statements,proto3" json:"statements,omitempty"`
+	Parameters    []string               `protobuf:"bytes,3,rep,name=parameters,proto3" json:"parameters,omitempty"`
+	LocalVars     map[string]string      `protobuf:"bytes,4,rep,name=local_vars,json=localVars,proto3" json:"local_vars,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Pos           *Position              `protobuf:"bytes,5,opt,name=pos,proto3" json:"pos,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Function) Reset() {
+	*x = Function{}
+	mi := &file_pkg_rpc_ir_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Function) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Function) ProtoMessage() {}
+
+func (x *Function) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpc_ir_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Function.ProtoReflect.Descriptor instead.
+func (*Function) Descriptor() ([]byte, []int) {
+	return file_pkg_rpc_ir_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Function) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Function) GetStatements() []*Statement {
+	if x != nil {
+		return x.Statements
+	}
+	return nil
+}
+
+func (x *Function) GetParameters() []string {
+	if x != nil {
+		return x.Parameters
+	}
+	return nil
+}
+
+func (x *Function) GetLocalVars() map[string]string {
+	if x != nil {
+		return x.LocalVars
+	}
+	return nil
+}
+
+func (x *Function) GetPos() *Position {
+	if x != nil {
+		return x.Pos
+	}
+	return nil
+}
+
+// Statement is one statement of a script or block
+type Statement struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Pos   *Position              `protobuf:"bytes,1,opt,name=pos,proto3" json:"pos,omitempty"`
+	// Types that are valid to be assigned to Value:
+	//
+	//	*Statement_Command
+	//	*Statement_Assignment
+	//	*Statement_If
+	//	*Statement_Loop
+	//	*Statement_Pipe
+	//	*Statement_Subshell
+	//	*Statement_Function
+	//	*Statement_Redirection
+	//	*Statement_Background
+	//	*Statement_Return
+	Value         isStatement_Value `protobuf_oneof:"value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Statement) Reset() {
+	*x = Statement{}
+	mi := &file_pkg_rpc_ir_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Statement) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Statement) ProtoMessage() {}
+
+func (x *Statement) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpc_ir_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Statement.ProtoReflect.Descriptor instead.
+func (*Statement) Descriptor() ([]byte, []int) {
+	return file_pkg_rpc_ir_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Statement) GetPos() *Position {
+	if x != nil {
+		return x.Pos
+	}
+	return nil
+}
+
+func (x *Statement) GetValue() isStatement_Value {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *Statement) GetCommand() *Command {
+	if x != nil {
+		if x, ok := x.Value.(*Statement_Command); ok {
+			return x.Command
+		}
+	}
+	return nil
+}
+
+func (x *Statement) GetAssignment() *Assignment {
+	if x != nil {
+		if x, ok := x.Value.(*Statement_Assignment); ok {
+			return x.Assignment
+		}
+	}
+	return nil
+}
+
+func (x *Statement) GetIf() *If {
+	if x != nil {
+		if x, ok := x.Value.(*Statement_If); ok {
+			return x.If
+		}
+	}
+	return nil
+}
+
+func (x *Statement) GetLoop() *Loop {
+	if x != nil {
+		if x, ok := x.Value.(*Statement_Loop); ok {
+			return x.Loop
+		}
+	}
+	return nil
+}
+
+func (x *Statement) GetPipe() *Pipe {
+	if x != nil {
+		if x, ok := x.Value.(*Statement_Pipe); ok {
+			return x.Pipe
+		}
+	}
+	return nil
+}
+
+func (x *Statement) GetSubshell() *Subshell {
+	if x != nil {
+		if x, ok := x.Value.(*Statement_Subshell); ok {
+			return x.Subshell
+		}
+	}
+	return nil
+}
+
+func (x *Statement) GetFunction() *Function {
+	if x != nil {
+		if x, ok := x.Value.(*Statement_Function); ok {
+			return x.Function
+		}
+	}
+	return nil
+}
+
+func (x *Statement) GetRedirection() *Redirection {
+	if x != nil {
+		if x, ok := x.Value.(*Statement_Redirection); ok {
+			return x.Redirection
+		}
+	}
+	return nil
+}
+
+func (x *Statement) GetBackground() *Background {
+	if x != nil {
+		if x, ok := x.Value.(*Statement_Background); ok {
+			return x.Background
+		}
+	}
+	return nil
+}
+
+func (x *Statement) GetReturn() *Return {
+	if x != nil {
+		if x, ok := x.Value.(*Statement_Return); ok {
+			return x.Return
+		}
+	}
+	return nil
+}
+
+type isStatement_Value interface {
+	isStatement_Value()
+}
+
+type Statement_Command struct {
+	Command *Command `protobuf:"bytes,2,opt,name=command,proto3,oneof"`
+}
+
+type Statement_Assignment struct {
+	Assignment *Assignment `protobuf:"bytes,3,opt,name=assignment,proto3,oneof"`
+}
+
+type Statement_If struct {
+	If *If `protobuf:"bytes,4,opt,name=if,proto3,oneof"`
+}
+
+type Statement_Loop struct {
+	Loop *Loop `protobuf:"bytes,5,opt,name=loop,proto3,oneof"`
+}
+
+type Statement_Pipe struct {
+	Pipe *Pipe `protobuf:"bytes,6,opt,name=pipe,proto3,oneof"`
+}
+
+type Statement_Subshell struct {
+	Subshell *Subshell `protobuf:"bytes,7,opt,name=subshell,proto3,oneof"`
+}
+
+type Statement_Function struct {
+	// Function declaration; its body is in the functions map
+	Function *Function `protobuf:"bytes,8,opt,name=function,proto3,oneof"`
+}
+
+type Statement_Redirection struct {
+	Redirection *Redirection `protobuf:"bytes,9,opt,name=redirection,proto3,oneof"`
+}
+
+type Statement_Background struct {
+	Background *Background `protobuf:"bytes,10,opt,name=background,proto3,oneof"`
+}
+
+type Statement_Return struct {
+	Return *Return `protobuf:"bytes,11,opt,name=return,proto3,oneof"`
+}
+
+func (*Statement_Command) isStatement_Value() {}
+
+func (*Statement_Assignment) isStatement_Value() {}
+
+func (*Statement_If) isStatement_Value() {}
+
+func (*Statement_Loop) isStatement_Value() {}
+
+func (*Statement_Pipe) isStatement_Value() {}
+
+func (*Statement_Subshell) isStatement_Value() {}
+
+func (*Statement_Function) isStatement_Value() {}
+
+func (*Statement_Redirection) isStatement_Value() {}
+
+func (*Statement_Background) isStatement_Value() {}
+
+func (*Statement_Return) isStatement_Value() {}
+
+type Command struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Args          []string               `protobuf:"bytes,2,rep,name=args,proto3" json:"args,omitempty"`
+	IsBuiltin     bool                   `protobuf:"varint,3,opt,name=is_builtin,json=isBuiltin,proto3" json:"is_builtin,omitempty"`
+	UseGexe       bool                   `protobuf:"varint,4,opt,name=use_gexe,json=useGexe,proto3" json:"use_gexe,omitempty"`
+	Pos           *Position              `protobuf:"bytes,5,opt,name=pos,proto3" json:"pos,omitempty"`
+	Globs         []int32                `protobuf:"varint,6,rep,packed,name=globs,proto3" json:"globs,omitempty"`
+	Splits        []int32                `protobuf:"varint,7,rep,packed,name=splits,proto3" json:"splits,omitempty"`
+	Redirects     []*Redirection         `protobuf:"bytes,8,rep,name=redirects,proto3" json:"redirects,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Command) Reset() {
+	*x = Command{}
+	mi := &file_pkg_rpc_ir_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Command) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Command) ProtoMessage() {}
+
+func (x *Command) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpc_ir_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Command.ProtoReflect.Descriptor instead.
+func (*Command) Descriptor() ([]byte, []int) {
+	return file_pkg_rpc_ir_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Command) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Command) GetArgs() []string {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
+func (x *Command) GetIsBuiltin() bool {
+	if x != nil {
+		return x.IsBuiltin
+	}
+	return false
+}
+
+func (x *Command) GetUseGexe() bool {
+	if x != nil {
+		return x.UseGexe
+	}
+	return false
+}
+
+func (x *Command) GetPos() *Position {
+	if x != nil {
+		return x.Pos
+	}
+	return nil
+}
+
+func (x *Command) GetGlobs() []int32 {
+	if x != nil {
+		return x.Globs
+	}
+	return nil
+}
+
+func (x *Command) GetSplits() []int32 {
+	if x != nil {
+		return x.Splits
+	}
+	return nil
+}
+
+func (x *Command) GetRedirects() []*Redirection {
+	if x != nil {
+		return x.Redirects
+	}
+	return nil
+}
+
+type Assignment struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	IsLocal       bool                   `protobuf:"varint,3,opt,name=is_local,json=isLocal,proto3" json:"is_local,omitempty"`
+	IsExport      bool                   `protobuf:"varint,4,opt,name=is_export,json=isExport,proto3" json:"is_export,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Assignment) Reset() {
+	*x = Assignment{}
+	mi := &file_pkg_rpc_ir_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Assignment) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Assignment) ProtoMessage() {}
+
+func (x *Assignment) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpc_ir_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Assignment.ProtoReflect.Descriptor instead.
+func (*Assignment) Descriptor() ([]byte, []int) {
+	return file_pkg_rpc_ir_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Assignment) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Assignment) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *Assignment) GetIsLocal() bool {
+	if x != nil {
+		return x.IsLocal
+	}
+	return false
+}
+
+func (x *Assignment) GetIsExport() bool {
+	if x != nil {
+		return x.IsExport
+	}
+	return false
+}
+
+type If struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Condition  []*Statement           `protobuf:"bytes,1,rep,name=condition,proto3" json:"condition,omitempty"`
+	ThenBlock  []*Statement           `protobuf:"bytes,2,rep,name=then_block,json=thenBlock,proto3" json:"then_block,omitempty"`
+	ElseBlock  []*Statement           `protobuf:"bytes,3,rep,name=else_block,json=elseBlock,proto3" json:"else_block,omitempty"`
+	ElifBlocks []*ElifBlock           `protobuf:"bytes,4,rep,name=elif_blocks,json=elifBlocks,proto3" json:"elif_blocks,omitempty"`
+	// "file", "string", "number" or "command"
+	ConditionType string `protobuf:"bytes,5,opt,name=condition_type,json=conditionType,proto3" json:"condition_type,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *If) Reset() {
+	*x = If{}
+	mi := &file_pkg_rpc_ir_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *If) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*If) ProtoMessage() {}
+
+func (x *If) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpc_ir_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use If.ProtoReflect.Descriptor instead.
+func (*If) Descriptor() ([]byte, []int) {
+	return file_pkg_rpc_ir_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *If) GetCondition() []*Statement {
+	if x != nil {
+		return x.Condition
+	}
+	return nil
+}
+
+func (x *If) GetThenBlock() []*Statement {
+	if x != nil {
+		return x.ThenBlock
+	}
+	return nil
+}
+
+func (x *If) GetElseBlock() []*Statement {
+	if x != nil {
+		return x.ElseBlock
+	}
+	return nil
+}
+
+func (x *If) GetElifBlocks() []*ElifBlock {
+	if x != nil {
+		return x.ElifBlocks
+	}
+	return nil
+}
+
+func (x *If) GetConditionType() string {
+	if x != nil {
+		return x.ConditionType
+	}
+	return ""
+}
+
+type ElifBlock struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Condition     []*Statement           `protobuf:"bytes,1,rep,name=condition,proto3" json:"condition,omitempty"`
+	ThenBlock     []*Statement           `protobuf:"bytes,2,rep,name=then_block,json=thenBlock,proto3" json:"then_block,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ElifBlock) Reset() {
+	*x = ElifBlock{}
+	mi := &file_pkg_rpc_ir_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ElifBlock) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ElifBlock) ProtoMessage() {}
+
+func (x *ElifBlock) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpc_ir_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ElifBlock.ProtoReflect.Descriptor instead.
+func (*ElifBlock) Descriptor() ([]byte, []int) {
+	return file_pkg_rpc_ir_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ElifBlock) GetCondition() []*Statement {
+	if x != nil {
+		return x.Condition
+	}
+	return nil
+}
+
+func (x *ElifBlock) GetThenBlock() []*Statement {
+	if x != nil {
+		return x.ThenBlock
+	}
+	return nil
+}
+
+type Loop struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// "for", "while" or "until"
+	Type          string       `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Init          []*Statement `protobuf:"bytes,2,rep,name=init,proto3" json:"init,omitempty"`
+	Condition     []*Statement `protobuf:"bytes,3,rep,name=condition,proto3" json:"condition,omitempty"`
+	Update        []*Statement `protobuf:"bytes,4,rep,name=update,proto3" json:"update,omitempty"`
+	Body          []*Statement `protobuf:"bytes,5,rep,name=body,proto3" json:"body,omitempty"`
+	IsRange       bool         `protobuf:"varint,6,opt,name=is_range,json=isRange,proto3" json:"is_range,omitempty"`
+	RangeVar      string       `protobuf:"bytes,7,opt,name=range_var,json=rangeVar,proto3" json:"range_var,omitempty"`
+	RangeFrom     string       `protobuf:"bytes,8,opt,name=range_from,json=rangeFrom,proto3" json:"range_from,omitempty"`
+	RangeTo       string       `protobuf:"bytes,9,opt,name=range_to,json=rangeTo,proto3" json:"range_to,omitempty"`
+	IsForEach     bool         `protobuf:"varint,10,opt,name=is_for_each,json=isForEach,proto3" json:"is_for_each,omitempty"`
+	Items         string       `protobuf:"bytes,11,opt,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Loop) Reset() {
+	*x = Loop{}
+	mi := &file_pkg_rpc_ir_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Loop) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Loop) ProtoMessage() {}
+
+func (x *Loop) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpc_ir_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Loop.ProtoReflect.Descriptor instead.
+func (*Loop) Descriptor() ([]byte, []int) {
+	return file_pkg_rpc_ir_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Loop) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Loop) GetInit() []*Statement {
+	if x != nil {
+		return x.Init
+	}
+	return nil
+}
+
+func (x *Loop) GetCondition() []*Statement {
+	if x != nil {
+		return x.Condition
+	}
+	return nil
+}
+
+func (x *Loop) GetUpdate() []*Statement {
+	if x != nil {
+		return x.Update
+	}
+	return nil
+}
+
+func (x *Loop) GetBody() []*Statement {
+	if x != nil {
+		return x.Body
+	}
+	return nil
+}
+
+func (x *Loop) GetIsRange() bool {
+	if x != nil {
+		return x.IsRange
+	}
+	return false
+}
+
+func (x *Loop) GetRangeVar() string {
+	if x != nil {
+		return x.RangeVar
+	}
+	return ""
+}
+
+func (x *Loop) GetRangeFrom() string {
+	if x != nil {
+		return x.RangeFrom
+	}
+	return ""
+}
+
+func (x *Loop) GetRangeTo() string {
+	if x != nil {
+		return x.RangeTo
+	}
+	return ""
+}
+
+func (x *Loop) GetIsForEach() bool {
+	if x != nil {
+		return x.IsForEach
+	}
+	return false
+}
+
+func (x *Loop) GetItems() string {
+	if x != nil {
+		return x.Items
+	}
+	return ""
+}
+
+type Pipe struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Commands      []*Command             `protobuf:"bytes,1,rep,name=commands,proto3" json:"commands,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Pipe) Reset() {
+	*x = Pipe{}
+	mi := &file_pkg_rpc_ir_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Pipe) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Pipe) ProtoMessage() {}
+
+func (x *Pipe) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpc_ir_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Pipe.ProtoReflect.Descriptor instead.
+func (*Pipe) Descriptor() ([]byte, []int) {
+	return file_pkg_rpc_ir_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Pipe) GetCommands() []*Command {
+	if x != nil {
+		return x.Commands
+	}
+	return nil
+}
+
+type Subshell struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Statements []*Statement           `protobuf:"bytes,1,rep,name=statements,proto3" json:"statements,omitempty"`
+	// Script variables assigned in the subshell, restored when it ends
+	Vars          []string `protobuf:"bytes,2,rep,name=vars,proto3" json:"vars,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Subshell) Reset() {
+	*x = Subshell{}
+	mi := &file_pkg_rpc_ir_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Subshell) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Subshell) ProtoMessage() {}
+
+func (x *Subshell) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpc_ir_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Subshell.ProtoReflect.Descriptor instead.
+func (*Subshell) Descriptor() ([]byte, []int) {
+	return file_pkg_rpc_ir_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Subshell) GetStatements() []*Statement {
+	if x != nil {
+		return x.Statements
+	}
+	return nil
+}
+
+func (x *Subshell) GetVars() []string {
+	if x != nil {
+		return x.Vars
+	}
+	return nil
+}
+
+type Redirection struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// ">", ">>", "<", etc.
+	Op       string    `protobuf:"bytes,1,opt,name=op,proto3" json:"op,omitempty"`
+	Command  *Command  `protobuf:"bytes,2,opt,name=command,proto3" json:"command,omitempty"`
+	Filename string    `protobuf:"bytes,3,opt,name=filename,proto3" json:"filename,omitempty"`
+	Pos      *Position `protobuf:"bytes,4,opt,name=pos,proto3" json:"pos,omitempty"`
+	// File descriptor redirected, as in 2>file; empty for the default of
+	// the operator
+	Fd            string `protobuf:"bytes,5,opt,name=fd,proto3" json:"fd,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Redirection) Reset() {
+	*x = Redirection{}
+	mi := &file_pkg_rpc_ir_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Redirection) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Redirection) ProtoMessage() {}
+
+func (x *Redirection) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpc_ir_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Redirection.ProtoReflect.Descriptor instead.
+func (*Redirection) Descriptor() ([]byte, []int) {
+	return file_pkg_rpc_ir_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *Redirection) GetOp() string {
+	if x != nil {
+		return x.Op
+	}
+	return ""
+}
+
+func (x *Redirection) GetCommand() *Command {
+	if x != nil {
+		return x.Command
+	}
+	return nil
+}
+
+func (x *Redirection) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *Redirection) GetPos() *Position {
+	if x != nil {
+		return x.Pos
+	}
+	return nil
+}
+
+func (x *Redirection) GetFd() string {
+	if x != nil {
+		return x.Fd
+	}
+	return ""
+}
+
+type Background struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Command       *Command               `protobuf:"bytes,1,opt,name=command,proto3" json:"command,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Background) Reset() {
+	*x = Background{}
+	mi := &file_pkg_rpc_ir_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Background) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Background) ProtoMessage() {}
+
+func (x *Background) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpc_ir_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Background.ProtoReflect.Descriptor instead.
+func (*Background) Descriptor() ([]byte, []int) {
+	return file_pkg_rpc_ir_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Background) GetCommand() *Command {
+	if x != nil {
+		return x.Command
+	}
+	return nil
+}
+
+type Return struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Value         string                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Code          int32                  `protobuf:"varint,2,opt,name=code,proto3" json:"code,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Return) Reset() {
+	*x = Return{}
+	mi := &file_pkg_rpc_ir_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Return) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Return) ProtoMessage() {}
+
+func (x *Return) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpc_ir_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Return.ProtoReflect.Descriptor instead.
+func (*Return) Descriptor() ([]byte, []int) {
+	return file_pkg_rpc_ir_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *Return) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *Return) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+// Diagnostic is a problem found while converting or building a script
+type Diagnostic struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Severity      Severity               `protobuf:"varint,1,opt,name=severity,proto3,enum=bash2go.v1.Severity" json:"severity,omitempty"`
+	Code          string                 `protobuf:"bytes,2,opt,name=code,proto3" json:"code,omitempty"`
+	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	File          string                 `protobuf:"bytes,4,opt,name=file,proto3" json:"file,omitempty"`
+	Line          uint32                 `protobuf:"varint,5,opt,name=line,proto3" json:"line,omitempty"`
+	Column        uint32                 `protobuf:"varint,6,opt,name=column,proto3" json:"column,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Diagnostic) Reset() {
+	*x = Diagnostic{}
+	mi := &file_pkg_rpc_ir_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Diagnostic) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Diagnostic) ProtoMessage() {}
+
+func (x *Diagnostic) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpc_ir_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Diagnostic.ProtoReflect.Descriptor instead.
+func (*Diagnostic) Descriptor() ([]byte, []int) {
+	return file_pkg_rpc_ir_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *Diagnostic) GetSeverity() Severity {
+	if x != nil {
+		return x.Severity
+	}
+	return Severity_SEVERITY_INFO
+}
+
+func (x *Diagnostic) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *Diagnostic) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *Diagnostic) GetFile() string {
+	if x != nil {
+		return x.File
+	}
+	return ""
+}
+
+func (x *Diagnostic) GetLine() uint32 {
+	if x != nil {
+		return x.Line
+	}
+	return 0
+}
+
+func (x *Diagnostic) GetColumn() uint32 {
+	if x != nil {
+		return x.Column
+	}
+	return 0
+}
+
+var File_pkg_rpc_ir_proto protoreflect.FileDescriptor
+
+const file_pkg_rpc_ir_proto_rawDesc = "" +
+	"\n" +
+	"\x10pkg/rpc/ir.proto\x12\n" +
+	"bash2go.v1\"\xb2\t\n" +
+	"\x1aIntermediateRepresentation\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\rR\aversion\x12\x1a\n" +
+	"\bfilename\x18\x02 \x01(\tR\bfilename\x12S\n" +
+	"\tvariables\x18\x03 \x03(\v25.bash2go.v1.IntermediateRepresentation.VariablesEntryR\tvariables\x12S\n" +
+	"\tfunctions\x18\x04 \x03(\v25.bash2go.v1.IntermediateRepresentation.FunctionsEntryR\tfunctions\x12>\n" +
+	"\x0fmain_statements\x18\x05 \x03(\v2\x15.bash2go.v1.StatementR\x0emainStatements\x12i\n" +
+	"\x11required_packages\x18\x06 \x03(\v2<.bash2go.v1.IntermediateRepresentation.RequiredPackagesEntryR\x10requiredPackages\x12Z\n" +
+	"\fenv_policies\x18\a \x03(\v27.bash2go.v1.IntermediateRepresentation.EnvPoliciesEntryR\venvPolicies\x12]\n" +
+	"\rshell_options\x18\b \x03(\v28.bash2go.v1.IntermediateRepresentation.ShellOptionsEntryR\fshellOptions\x12Z\n" +
+	"\fspecial_vars\x18\t \x03(\v27.bash2go.v1.IntermediateRepresentation.SpecialVarsEntryR\vspecialVars\x12 \n" +
+	"\vsubcommands\x18\n" +
+	" \x03(\tR\vsubcommands\x128\n" +
+	"\vdiagnostics\x18\v \x03(\v2\x16.bash2go.v1.DiagnosticR\vdiagnostics\x1a<\n" +
+	"\x0eVariablesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1aR\n" +
+	"\x0eFunctionsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12*\n" +
+	"\x05value\x18\x02 \x01(\v2\x14.bash2go.v1.FunctionR\x05value:\x028\x01\x1aC\n" +
+	"\x15RequiredPackagesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\bR\x05value:\x028\x01\x1a>\n" +
+	"\x10EnvPoliciesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a?\n" +
+	"\x11ShellOptionsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\bR\x05value:\x028\x01\x1a>\n" +
+	"\x10SpecialVarsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\bR\x05value:\x028\x01\"J\n" +
+	"\bPosition\x12\x12\n" +
+	"\x04file\x18\x01 \x01(\tR\x04file\x12\x12\n" +
+	"\x04line\x18\x02 \x01(\rR\x04line\x12\x16\n" +
+	"\x06column\x18\x03 \x01(\rR\x06column\"\x9f\x02\n" +
+	"\bFunction\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x125\n" +
+	"\n" +
+	"statements\x18\x02 \x03(\v2\x15.bash2go.v1.StatementR\n" +
+	"statements\x12\x1e\n" +
+	"\n" +
+	"parameters\x18\x03 \x03(\tR\n" +
+	"parameters\x12B\n" +
+	"\n" +
+	"local_vars\x18\x04 \x03(\v2#.bash2go.v1.Function.LocalVarsEntryR\tlocalVars\x12&\n" +
+	"\x03pos\x18\x05 \x01(\v2\x14.bash2go.v1.PositionR\x03pos\x1a<\n" +
+	"\x0eLocalVarsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xa6\x04\n" +
+	"\tStatement\x12&\n" +
+	"\x03pos\x18\x01 \x01(\v2\x14.bash2go.v1.PositionR\x03pos\x12/\n" +
+	"\acommand\x18\x02 \x01(\v2\x13.bash2go.v1.CommandH\x00R\acommand\x128\n" +
+	"\n" +
+	"assignment\x18\x03 \x01(\v2\x16.bash2go.v1.AssignmentH\x00R\n" +
+	"assignment\x12 \n" +
+	"\x02if\x18\x04 \x01(\v2\x0e.bash2go.v1.IfH\x00R\x02if\x12&\n" +
+	"\x04loop\x18\x05 \x01(\v2\x10.bash2go.v1.LoopH\x00R\x04loop\x12&\n" +
+	"\x04pipe\x18\x06 \x01(\v2\x10.bash2go.v1.PipeH\x00R\x04pipe\x122\n" +
+	"\bsubshell\x18\a \x01(\v2\x14.bash2go.v1.SubshellH\x00R\bsubshell\x122\n" +
+	"\bfunction\x18\b \x01(\v2\x14.bash2go.v1.FunctionH\x00R\bfunction\x12;\n" +
+	"\vredirection\x18\t \x01(\v2\x17.bash2go.v1.RedirectionH\x00R\vredirection\x128\n" +
+	"\n" +
+	"background\x18\n" +
+	" \x01(\v2\x16.bash2go.v1.BackgroundH\x00R\n" +
+	"background\x12,\n" +
+	"\x06return\x18\v \x01(\v2\x12.bash2go.v1.ReturnH\x00R\x06returnB\a\n" +
+	"\x05value\"\xf8\x01\n" +
+	"\aCommand\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04args\x18\x02 \x03(\tR\x04args\x12\x1d\n" +
+	"\n" +
+	"is_builtin\x18\x03 \x01(\bR\tisBuiltin\x12\x19\n" +
+	"\buse_gexe\x18\x04 \x01(\bR\auseGexe\x12&\n" +
+	"\x03pos\x18\x05 \x01(\v2\x14.bash2go.v1.PositionR\x03pos\x12\x14\n" +
+	"\x05globs\x18\x06 \x03(\x05R\x05globs\x12\x16\n" +
+	"\x06splits\x18\a \x03(\x05R\x06splits\x125\n" +
+	"\tredirects\x18\b \x03(\v2\x17.bash2go.v1.RedirectionR\tredirects\"n\n" +
+	"\n" +
+	"Assignment\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value\x12\x19\n" +
+	"\bis_local\x18\x03 \x01(\bR\aisLocal\x12\x1b\n" +
+	"\tis_export\x18\x04 \x01(\bR\bisExport\"\x84\x02\n" +
+	"\x02If\x123\n" +
+	"\tcondition\x18\x01 \x03(\v2\x15.bash2go.v1.StatementR\tcondition\x124\n" +
+	"\n" +
+	"then_block\x18\x02 \x03(\v2\x15.bash2go.v1.StatementR\tthenBlock\x124\n" +
+	"\n" +
+	"else_block\x18\x03 \x03(\v2\x15.bash2go.v1.StatementR\telseBlock\x126\n" +
+	"\velif_blocks\x18\x04 \x03(\v2\x15.bash2go.v1.ElifBlockR\n" +
+	"elifBlocks\x12%\n" +
+	"\x0econdition_type\x18\x05 \x01(\tR\rconditionType\"v\n" +
+	"\tElifBlock\x123\n" +
+	"\tcondition\x18\x01 \x03(\v2\x15.bash2go.v1.StatementR\tcondition\x124\n" +
+	"\n" +
+	"then_block\x18\x02 \x03(\v2\x15.bash2go.v1.StatementR\tthenBlock\"\xfc\x02\n" +
+	"\x04Loop\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12)\n" +
+	"\x04init\x18\x02 \x03(\v2\x15.bash2go.v1.StatementR\x04init\x123\n" +
+	"\tcondition\x18\x03 \x03(\v2\x15.bash2go.v1.StatementR\tcondition\x12-\n" +
+	"\x06update\x18\x04 \x03(\v2\x15.bash2go.v1.StatementR\x06update\x12)\n" +
+	"\x04body\x18\x05 \x03(\v2\x15.bash2go.v1.StatementR\x04body\x12\x19\n" +
+	"\bis_range\x18\x06 \x01(\bR\aisRange\x12\x1b\n" +
+	"\trange_var\x18\a \x01(\tR\brangeVar\x12\x1d\n" +
+	"\n" +
+	"range_from\x18\b \x01(\tR\trangeFrom\x12\x19\n" +
+	"\brange_to\x18\t \x01(\tR\arangeTo\x12\x1e\n" +
+	"\vis_for_each\x18\n" +
+	" \x01(\bR\tisForEach\x12\x14\n" +
+	"\x05items\x18\v \x01(\tR\x05items\"7\n" +
+	"\x04Pipe\x12/\n" +
+	"\bcommands\x18\x01 \x03(\v2\x13.bash2go.v1.CommandR\bcommands\"U\n" +
+	"\bSubshell\x125\n" +
+	"\n" +
+	"statements\x18\x01 \x03(\v2\x15.bash2go.v1.StatementR\n" +
+	"statements\x12\x12\n" +
+	"\x04vars\x18\x02 \x03(\tR\x04vars\"\xa0\x01\n" +
+	"\vRedirection\x12\x0e\n" +
+	"\x02op\x18\x01 \x01(\tR\x02op\x12-\n" +
+	"\acommand\x18\x02 \x01(\v2\x13.bash2go.v1.CommandR\acommand\x12\x1a\n" +
+	"\bfilename\x18\x03 \x01(\tR\bfilename\x12&\n" +
+	"\x03pos\x18\x04 \x01(\v2\x14.bash2go.v1.PositionR\x03pos\x12\x0e\n" +
+	"\x02fd\x18\x05 \x01(\tR\x02fd\";\n" +
+	"\n" +
+	"Background\x12-\n" +
+	"\acommand\x18\x01 \x01(\v2\x13.bash2go.v1.CommandR\acommand\"2\n" +
+	"\x06Return\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\tR\x05value\x12\x12\n" +
+	"\x04code\x18\x02 \x01(\x05R\x04code\"\xac\x01\n" +
+	"\n" +
+	"Diagnostic\x120\n" +
+	"\bseverity\x18\x01 \x01(\x0e2\x14.bash2go.v1.SeverityR\bseverity\x12\x12\n" +
+	"\x04code\x18\x02 \x01(\tR\x04code\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\x12\x12\n" +
+	"\x04file\x18\x04 \x01(\tR\x04file\x12\x12\n" +
+	"\x04line\x18\x05 \x01(\rR\x04line\x12\x16\n" +
+	"\x06column\x18\x06 \x01(\rR\x06column*G\n" +
+	"\bSeverity\x12\x11\n" +
+	"\rSEVERITY_INFO\x10\x00\x12\x14\n" +
+	"\x10SEVERITY_WARNING\x10\x01\x12\x12\n" +
+	"\x0eSEVERITY_ERROR\x10\x02B!Z\x1fgithub.com/TFMV/bash2go/pkg/rpcb\x06proto3"
+
+var (
+	file_pkg_rpc_ir_proto_rawDescOnce sync.Once
+	file_pkg_rpc_ir_proto_rawDescData []byte
+)
+
+func file_pkg_rpc_ir_proto_rawDescGZIP() []byte {
+	file_pkg_rpc_ir_proto_rawDescOnce.Do(func() {
+		file_pkg_rpc_ir_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_pkg_rpc_ir_proto_rawDesc), len(file_pkg_rpc_ir_proto_rawDesc)))
+	})
+	return file_pkg_rpc_ir_proto_rawDescData
+}
+
+var file_pkg_rpc_ir_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_pkg_rpc_ir_proto_msgTypes = make([]protoimpl.MessageInfo, 22)
+var file_pkg_rpc_ir_proto_goTypes = []any{
+	(Severity)(0),                      // 0: bash2go.v1.Severity
+	(*IntermediateRepresentation)(nil), // 1: bash2go.v1.IntermediateRepresentation
+	(*Position)(nil),                   // 2: bash2go.v1.Position
+	(*Function)(nil),                   // 3: bash2go.v1.Function
+	(*Statement)(nil),                  // 4: bash2go.v1.Statement
+	(*Command)(nil),                    // 5: bash2go.v1.Command
+	(*Assignment)(nil),                 // 6: bash2go.v1.Assignment
+	(*If)(nil),                         // 7: bash2go.v1.If
+	(*ElifBlock)(nil),                  // 8: bash2go.v1.ElifBlock
+	(*Loop)(nil),                       // 9: bash2go.v1.Loop
+	(*Pipe)(nil),                       // 10: bash2go.v1.Pipe
+	(*Subshell)(nil),                   // 11: bash2go.v1.Subshell
+	(*Redirection)(nil),                // 12: bash2go.v1.Redirection
+	(*Background)(nil),                 // 13: bash2go.v1.Background
+	(*Return)(nil),                     // 14: bash2go.v1.Return
+	(*Diagnostic)(nil),                 // 15: bash2go.v1.Diagnostic
+	nil,                                // 16: bash2go.v1.IntermediateRepresentation.VariablesEntry
+	nil,                                // 17: bash2go.v1.IntermediateRepresentation.FunctionsEntry
+	nil,                                // 18: bash2go.v1.IntermediateRepresentation.RequiredPackagesEntry
+	nil,                                // 19: bash2go.v1.IntermediateRepresentation.EnvPoliciesEntry
+	nil,                                // 20: bash2go.v1.IntermediateRepresentation.ShellOptionsEntry
+	nil,                                // 21: bash2go.v1.IntermediateRepresentation.SpecialVarsEntry
+	nil,                                // 22: bash2go.v1.Function.LocalVarsEntry
+}
+var file_pkg_rpc_ir_proto_depIdxs = []int32{
+	16, // 0: bash2go.v1.IntermediateRepresentation.variables:type_name -> bash2go.v1.IntermediateRepresentation.VariablesEntry
+	17, // 1: bash2go.v1.IntermediateRepresentation.functions:type_name -> bash2go.v1.IntermediateRepresentation.FunctionsEntry
+	4,  // 2: bash2go.v1.IntermediateRepresentation.main_statements:type_name -> bash2go.v1.Statement
+	18, // 3: bash2go.v1.IntermediateRepresentation.required_packages:type_name -> bash2go.v1.IntermediateRepresentation.RequiredPackagesEntry
+	19, // 4: bash2go.v1.IntermediateRepresentation.env_policies:type_name -> bash2go.v1.IntermediateRepresentation.EnvPoliciesEntry
+	20, // 5: bash2go.v1.IntermediateRepresentation.shell_options:type_name -> bash2go.v1.IntermediateRepresentation.ShellOptionsEntry
+	21, // 6: bash2go.v1.IntermediateRepresentation.special_vars:type_name -> bash2go.v1.IntermediateRepresentation.SpecialVarsEntry
+	15, // 7: bash2go.v1.IntermediateRepresentation.diagnostics:type_name -> bash2go.v1.Diagnostic
+	4,  // 8: bash2go.v1.Function.statements:type_name -> bash2go.v1.Statement
+	22, // 9: bash2go.v1.Function.local_vars:type_name -> bash2go.v1.Function.LocalVarsEntry
+	2,  // 10: bash2go.v1.Function.pos:type_name -> bash2go.v1.Position
+	2,  // 11: bash2go.v1.Statement.pos:type_name -> bash2go.v1.Position
+	5,  // 12: bash2go.v1.Statement.command:type_name -> bash2go.v1.Command
+	6,  // 13: bash2go.v1.Statement.assignment:type_name -> bash2go.v1.Assignment
+	7,  // 14: bash2go.v1.Statement.if:type_name -> bash2go.v1.If
+	9,  // 15: bash2go.v1.Statement.loop:type_name -> bash2go.v1.Loop
+	10, // 16: bash2go.v1.Statement.pipe:type_name -> bash2go.v1.Pipe
+	11, // 17: bash2go.v1.Statement.subshell:type_name -> bash2go.v1.Subshell
+	3,  // 18: bash2go.v1.Statement.function:type_name -> bash2go.v1.Function
+	12, // 19: bash2go.v1.Statement.redirection:type_name -> bash2go.v1.Redirection
+	13, // 20: bash2go.v1.Statement.background:type_name -> bash2go.v1.Background
+	14, // 21: bash2go.v1.Statement.return:type_name -> bash2go.v1.Return
+	2,  // 22: bash2go.v1.Command.pos:type_name -> bash2go.v1.Position
+	12, // 23: bash2go.v1.Command.redirects:type_name -> bash2go.v1.Redirection
+	4,  // 24: bash2go.v1.If.condition:type_name -> bash2go.v1.Statement
+	4,  // 25: bash2go.v1.If.then_block:type_name -> bash2go.v1.Statement
+	4,  // 26: bash2go.v1.If.else_block:type_name -> bash2go.v1.Statement
+	8,  // 27: bash2go.v1.If.elif_blocks:type_name -> bash2go.v1.ElifBlock
+	4,  // 28: bash2go.v1.ElifBlock.condition:type_name -> bash2go.v1.Statement
+	4,  // 29: bash2go.v1.ElifBlock.then_block:type_name -> bash2go.v1.Statement
+	4,  // 30: bash2go.v1.Loop.init:type_name -> bash2go.v1.Statement
+	4,  // 31: bash2go.v1.Loop.condition:type_name -> bash2go.v1.Statement
+	4,  // 32: bash2go.v1.Loop.update:type_name -> bash2go.v1.Statement
+	4,  // 33: bash2go.v1.Loop.body:type_name -> bash2go.v1.Statement
+	5,  // 34: bash2go.v1.Pipe.commands:type_name -> bash2go.v1.Command
+	4,  // 35: bash2go.v1.Subshell.statements:type_name -> bash2go.v1.Statement
+	5,  // 36: bash2go.v1.Redirection.command:type_name -> bash2go.v1.Command
+	2,  // 37: bash2go.v1.Redirection.pos:type_name -> bash2go.v1.Position
+	5,  // 38: bash2go.v1.Background.command:type_name -> bash2go.v1.Command
+	0,  // 39: bash2go.v1.Diagnostic.severity:type_name -> bash2go.v1.Severity
+	3,  // 40: bash2go.v1.IntermediateRepresentation.FunctionsEntry.value:type_name -> bash2go.v1.Function
+	41, // [41:41] is the sub-list for method output_type
+	41, // [41:41] is the sub-list for method input_type
+	41, // [41:41] is the sub-list for extension type_name
+	41, // [41:41] is the sub-list for extension extendee
+	0,  // [0:41] is the sub-list for field type_name
+}
+
+func init() { file_pkg_rpc_ir_proto_init() }
+func file_pkg_rpc_ir_proto_init() {
+	if File_pkg_rpc_ir_proto != nil {
+		return
+	}
+	file_pkg_rpc_ir_proto_msgTypes[3].OneofWrappers = []any{
+		(*Statement_Command)(nil),
+		(*Statement_Assignment)(nil),
+		(*Statement_If)(nil),
+		(*Statement_Loop)(nil),
+		(*Statement_Pipe)(nil),
+		(*Statement_Subshell)(nil),
+		(*Statement_Function)(nil),
+		(*Statement_Redirection)(nil),
+		(*Statement_Background)(nil),
+		(*Statement_Return)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pkg_rpc_ir_proto_rawDesc), len(file_pkg_rpc_ir_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   22,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_pkg_rpc_ir_proto_goTypes,
+		DependencyIndexes: file_pkg_rpc_ir_proto_depIdxs,
+		EnumInfos:         file_pkg_rpc_ir_proto_enumTypes,
+		MessageInfos:      file_pkg_rpc_ir_proto_msgTypes,
+	}.Build()
+	File_pkg_rpc_ir_proto = out.File
+	file_pkg_rpc_ir_proto_goTypes = nil
+	file_pkg_rpc_ir_proto_depIdxs = nil
+}
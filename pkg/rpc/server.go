@@ -0,0 +1,276 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/TFMV/bash2go/compiler"
+	"github.com/TFMV/bash2go/diagnostics"
+	"github.com/TFMV/bash2go/parser"
+	"github.com/TFMV/bash2go/pkg/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// chunkSize is the size of the binary chunks streamed by Build, well below
+// the default 4 MB message limit of gRPC clients
+const chunkSize = 1 << 20
+
+// Server implements ConverterServer with the bash2go library
+type Server struct {
+	UnimplementedConverterServer
+
+	// BuildOptions holds the options every build starts from, such as the Go
+	// toolchain and whether to vet. Output paths are set per request.
+	BuildOptions compiler.BuildOptions
+
+	// ConvertEnv lets requests and the bash2go:env directives of scripts
+	// resolve environment variables when converting. Their values come from
+	// the environment of the server and are returned to clients, so requests
+	// asking for it are refused and directives ignored unless it is set.
+	ConvertEnv bool
+}
+
+// NewServer returns a server building with the given base options
+func NewServer(build compiler.BuildOptions) *Server {
+	return &Server{BuildOptions: build}
+}
+
+// Convert implements ConverterServer
+func (s *Server) Convert(ctx context.Context, req *ConvertRequest) (*ConvertResponse, error) {
+	options, err := s.apiOptions(req.Options)
+	if err != nil {
+		return nil, err
+	}
+	result, err := api.Convert(req.Script, options)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &ConvertResponse{
+		Code:        result.Code,
+		Diagnostics: toDiagnostics(fromAPI(result.Diagnostics)),
+		Metrics:     toMetrics(result.Metrics),
+	}, nil
+}
+
+// Check implements ConverterServer. Scripts that fail to convert are
+// reported in the response rather than as an error.
+func (s *Server) Check(ctx context.Context, req *CheckRequest) (*CheckResponse, error) {
+	options, err := s.apiOptions(req.Options)
+	if err != nil {
+		return nil, err
+	}
+	options.TypeCheck = true
+	result, err := api.Convert(req.Script, options)
+
+	resp := &CheckResponse{
+		Ok:          err == nil,
+		Diagnostics: toDiagnostics(fromAPI(result.Diagnostics)),
+		Metrics:     toMetrics(result.Metrics),
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	return resp, nil
+}
+
+// Build implements ConverterServer. Conversion and compile failures end the
+// stream with an unsuccessful result rather than an error.
+func (s *Server) Build(req *BuildRequest, stream Converter_BuildServer) error {
+	// Sends come from the build's progress callbacks as well as from here
+	var mu sync.Mutex
+	send := func(event *BuildEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return stream.Send(event)
+	}
+	logf := func(format string, args ...interface{}) {
+		send(&BuildEvent{Event: &BuildEvent_Log{Log: fmt.Sprintf(format, args...)}})
+	}
+
+	options, err := s.apiOptions(req.Options)
+	if err != nil {
+		return err
+	}
+	options.TypeCheck = true
+	result, err := api.Convert(req.Script, options)
+	findings := diagnostics.NewCollector()
+	for _, d := range fromAPI(result.Diagnostics) {
+		findings.Add(d)
+	}
+	finish := func(err error, checksum string) error {
+		res := &BuildResult{
+			Ok:          err == nil,
+			Sha256:      checksum,
+			Diagnostics: toDiagnostics(findings.Items()),
+			Metrics:     toMetrics(result.Metrics),
+		}
+		if err != nil {
+			res.Error = err.Error()
+		}
+		return send(&BuildEvent{Event: &BuildEvent_Result{Result: res}})
+	}
+	if err != nil {
+		return finish(err, "")
+	}
+
+	tempDir, err := os.MkdirTemp("", "bash2go-serve-")
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	goFile := filepath.Join(tempDir, "main.go")
+	if err := os.WriteFile(goFile, []byte(result.Code), 0644); err != nil {
+		return status.Errorf(codes.Internal, "failed to write Go code to file: %v", err)
+	}
+
+	build := s.BuildOptions
+	build.GoFile = goFile
+	build.OutputFile = filepath.Join(tempDir, "program")
+	build.TempDir = filepath.Join(tempDir, "build")
+	build.KeepTempFiles = true // Removed with tempDir
+	build.GOOS = req.Goos
+	build.GOARCH = req.Goarch
+	build.Static = build.Static || req.Static
+	build.Small = build.Small || req.Small
+	build.Diagnostics = findings
+	build.Logf = logf
+	build.Context = stream.Context() // Stops the build when the client leaves
+	build.Progress = func(phase string) func() {
+		logf("%s...\n", phase)
+		start := time.Now()
+		return func() {
+			logf("%s done in %s\n", phase, time.Since(start).Round(time.Millisecond))
+		}
+	}
+	if err := os.MkdirAll(build.TempDir, 0755); err != nil {
+		return status.Errorf(codes.Internal, "failed to create build directory: %v", err)
+	}
+	if err := compiler.BuildGoProgram(build); err != nil {
+		return finish(err, "")
+	}
+
+	// Stream the binary before the result that carries its checksum
+	binary, err := os.ReadFile(build.OutputFile)
+	if err != nil {
+		return finish(fmt.Errorf("failed to read output binary: %v", err), "")
+	}
+	for len(binary) > 0 {
+		n := min(chunkSize, len(binary))
+		if err := send(&BuildEvent{Event: &BuildEvent_BinaryChunk{BinaryChunk: binary[:n]}}); err != nil {
+			return err
+		}
+		binary = binary[n:]
+	}
+
+	checksum, err := compiler.FileSHA256(build.OutputFile)
+	return finish(err, checksum)
+}
+
+// Parse implements ConverterServer
+func (s *Server) Parse(ctx context.Context, req *ParseRequest) (*ParseResponse, error) {
+	parsed, err := parser.ParseBashString(string(req.Script))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to parse Bash script: %v", err)
+	}
+	parsed.Filename = req.Options.GetFilename()
+
+	ir, err := parser.BuildIR(parsed)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to build intermediate representation: %v", err)
+	}
+	m, err := IRToProto(ir)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &ParseResponse{Ir: m}, nil
+}
+
+// Generate implements ConverterServer
+func (s *Server) Generate(ctx context.Context, req *GenerateRequest) (*ConvertResponse, error) {
+	ir, err := IRFromProto(req.Ir)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	options, err := s.apiOptions(req.Options)
+	if err != nil {
+		return nil, err
+	}
+	options.TypeCheck = req.TypeCheck
+	result, err := api.GenerateIR(ir, options)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &ConvertResponse{
+		Code:        result.Code,
+		Diagnostics: toDiagnostics(fromAPI(result.Diagnostics)),
+		Metrics:     toMetrics(result.Metrics),
+	}, nil
+}
+
+// apiOptions converts request options to library options, refusing those
+// that resolve environment variables unless the server allows it
+func (s *Server) apiOptions(o *Options) (api.Options, error) {
+	options := api.Options{
+		Filename:        o.GetFilename(),
+		EnvPolicy:       o.GetEnvPolicy(),
+		ResolveEnv:      o.GetResolveEnv(),
+		RuntimeEnv:      o.GetRuntimeEnv(),
+		RuntimeEnvOnly:  !s.ConvertEnv,
+		BuildConstraint: o.GetBuildConstraint(),
+	}
+	if !s.ConvertEnv && (options.EnvPolicy == string(parser.EnvConvert) || len(options.ResolveEnv) > 0) {
+		return api.Options{}, status.Error(codes.PermissionDenied,
+			"resolving environment variables when converting is disabled on this server")
+	}
+	return options, nil
+}
+
+// fromAPI converts library diagnostics back to diagnostics of the
+// conversion, which builds add their own findings to
+func fromAPI(items []api.Diagnostic) []diagnostics.Diagnostic {
+	result := make([]diagnostics.Diagnostic, len(items))
+	for i, d := range items {
+		result[i] = diagnostics.Diagnostic{
+			Severity: diagnostics.Severity(d.Severity),
+			Code:     d.Code,
+			Message:  d.Message,
+			File:     d.File,
+			Line:     d.Line,
+			Column:   d.Column,
+		}
+	}
+	return result
+}
+
+// toDiagnostics converts diagnostics to messages
+func toDiagnostics(items []diagnostics.Diagnostic) []*Diagnostic {
+	result := make([]*Diagnostic, len(items))
+	for i, d := range items {
+		result[i] = &Diagnostic{
+			Severity: Severity(d.Severity),
+			Code:     d.Code,
+			Message:  d.Message,
+			File:     d.File,
+			Line:     uint32(d.Line),
+			Column:   uint32(d.Column),
+		}
+	}
+	return result
+}
+
+// toMetrics converts library metrics to a message
+func toMetrics(m api.Metrics) *Metrics {
+	return &Metrics{
+		Native:        int32(m.Native),
+		ExecFallbacks: int32(m.ExecFallbacks),
+		Unsupported:   int32(m.Unsupported),
+		Dependencies:  m.Dependencies,
+		NativePercent: m.NativePercent(),
+	}
+}
@@ -0,0 +1,154 @@
+package rpc_test
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/bash2go/compiler"
+	"github.com/TFMV/bash2go/pkg/rpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+// newClient starts a server on an in-memory listener and returns a client
+// connected to it
+func newClient(t *testing.T) rpc.ConverterClient {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	rpc.RegisterConverterServer(server, rpc.NewServer(compiler.DefaultBuildOptions("", "")))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return rpc.NewConverterClient(conn)
+}
+
+// TestServerConvert tests converting a script over gRPC
+func TestServerConvert(t *testing.T) {
+	client := newClient(t)
+
+	resp, err := client.Convert(context.Background(), &rpc.ConvertRequest{
+		Script:  []byte("NAME=world\necho \"hello $NAME\"\n"),
+		Options: &rpc.Options{Filename: "hello.sh"},
+	})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if !strings.Contains(resp.Code, "package main") {
+		t.Errorf("Expected a main package, got:\n%s", resp.Code)
+	}
+	if resp.Metrics.GetNative() == 0 {
+		t.Errorf("Expected natively translated statements, got %v", resp.Metrics)
+	}
+}
+
+// TestServerConvertEnv tests that the server keeps its environment out of
+// the code it returns unless it allows resolving variables when converting
+func TestServerConvertEnv(t *testing.T) {
+	t.Setenv("SECRET_TOKEN", "hunter2")
+	client := newClient(t)
+
+	_, err := client.Convert(context.Background(), &rpc.ConvertRequest{
+		Script:  []byte("echo \"$SECRET_TOKEN\"\n"),
+		Options: &rpc.Options{ResolveEnv: []string{"SECRET_TOKEN"}},
+	})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected ResolveEnv to be refused, got %v", err)
+	}
+
+	resp, err := client.Convert(context.Background(), &rpc.ConvertRequest{
+		Script: []byte("# bash2go:env convert SECRET_TOKEN\necho \"$SECRET_TOKEN\"\n"),
+	})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if strings.Contains(resp.Code, "hunter2") || !strings.Contains(resp.Code, `os.Getenv("SECRET_TOKEN")`) {
+		t.Errorf("Expected the variable to be read at runtime, got:\n%s", resp.Code)
+	}
+}
+
+// TestServerCheck tests that Check reports scripts that do not convert
+func TestServerCheck(t *testing.T) {
+	client := newClient(t)
+
+	resp, err := client.Check(context.Background(), &rpc.CheckRequest{
+		Script: []byte("echo ok\n"),
+	})
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if !resp.Ok {
+		t.Errorf("Expected a clean script to pass, got error %q", resp.Error)
+	}
+
+	resp, err = client.Check(context.Background(), &rpc.CheckRequest{
+		Script: []byte("if then fi\n"),
+	})
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if resp.Ok || resp.Error == "" {
+		t.Errorf("Expected a syntax error to fail the check, got %v", resp)
+	}
+}
+
+// TestServerParseGenerate tests that an IR returned by Parse generates the
+// same code as converting the script directly
+func TestServerParseGenerate(t *testing.T) {
+	client := newClient(t)
+	script := []byte("greet() {\n\techo \"hello $1\"\n}\nfor i in 1 2; do\n\techo $i\ndone\nls | wc -l > count.txt\n")
+	options := &rpc.Options{Filename: "greet.sh"}
+
+	parsed, err := client.Parse(context.Background(), &rpc.ParseRequest{Script: script, Options: options})
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if parsed.Ir.GetFilename() != "greet.sh" || parsed.Ir.GetFunctions()["greet"] == nil {
+		t.Fatalf("Unexpected IR: %v", parsed.Ir)
+	}
+
+	// The IR survives serialization
+	data, err := proto.Marshal(parsed.Ir)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	ir := &rpc.IntermediateRepresentation{}
+	if err := proto.Unmarshal(data, ir); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	generated, err := client.Generate(context.Background(), &rpc.GenerateRequest{Ir: ir, Options: options})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	converted, err := client.Convert(context.Background(), &rpc.ConvertRequest{Script: script, Options: options})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if generated.Code != converted.Code {
+		t.Errorf("Generated code differs from converted code:\n%s\n---\n%s", generated.Code, converted.Code)
+	}
+
+	// Statements without a value and unknown versions are rejected
+	ir.MainStatements = append(ir.MainStatements, &rpc.Statement{})
+	if _, err := client.Generate(context.Background(), &rpc.GenerateRequest{Ir: ir}); err == nil {
+		t.Error("Expected Generate to reject a statement without a value")
+	}
+	if _, err := client.Generate(context.Background(), &rpc.GenerateRequest{Ir: &rpc.IntermediateRepresentation{Version: 99}}); err == nil {
+		t.Error("Expected Generate to reject an unknown IR version")
+	}
+}
@@ -0,0 +1,274 @@
+// Package systemd extracts the commands of systemd service units, so that
+// their scripts can be converted to Go, and rewrites units to run the
+// converted program instead.
+package systemd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Subcommands of the generated program, in the order they are declared
+const (
+	Start  = "start"
+	Stop   = "stop"
+	Reload = "reload"
+)
+
+// subcommands maps the Exec directives of a service to the subcommand that
+// runs them
+var subcommands = map[string]string{
+	"ExecStartPre":  Start,
+	"ExecStart":     Start,
+	"ExecStartPost": Start,
+	"ExecStop":      Stop,
+	"ExecStopPost":  Stop,
+	"ExecReload":    Reload,
+}
+
+// Command is one Exec directive of the [Service] section
+type Command struct {
+	Directive string   // Directive name, such as ExecStart
+	Line      int      // Line of the directive
+	Prefixes  string   // Special executable prefixes such as - or @, which are not converted
+	Args      []string // Command line, unquoted
+}
+
+// Subcommand returns the subcommand of the generated program that runs c
+func (c Command) Subcommand() string {
+	return subcommands[c.Directive]
+}
+
+// ScriptFile returns the Bash script run by c, if it runs one, such as
+// /opt/app/start.sh or "bash /opt/app/start.sh"
+func (c Command) ScriptFile() (string, bool) {
+	args := c.Args
+	if len(args) > 1 && isShell(args[0]) && !strings.HasPrefix(args[1], "-") {
+		args = args[1:]
+	}
+	if len(args) > 0 && strings.HasSuffix(args[0], ".sh") {
+		return args[0], true
+	}
+	return "", false
+}
+
+// InlineScript returns the script c runs without a script file: the
+// argument of "bash -c", or the command line itself
+func (c Command) InlineScript() string {
+	if len(c.Args) > 2 && isShell(c.Args[0]) && c.Args[1] == "-c" {
+		return c.Args[2]
+	}
+	words := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		words[i] = quote(arg)
+	}
+	return strings.Join(words, " ")
+}
+
+// Unit is a parsed unit file
+type Unit struct {
+	lines    []string
+	service  [2]int // Range of lines of the [Service] section
+	Commands []Command
+}
+
+// Parse reads the unit file from r
+func Parse(r io.Reader) (*Unit, error) {
+	u := &Unit{service: [2]int{-1, -1}}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		u.lines = append(u.lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	section := ""
+	for i := 0; i < len(u.lines); i++ {
+		line := strings.TrimSpace(u.lines[i])
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			if section == "Service" {
+				u.service[1] = i
+			}
+			section = line[1 : len(line)-1]
+			if section == "Service" {
+				u.service = [2]int{i, len(u.lines)}
+			}
+			continue
+		}
+
+		// Join continuation lines
+		start := i
+		for strings.HasSuffix(line, "\\") && i+1 < len(u.lines) {
+			i++
+			line = strings.TrimSpace(strings.TrimSuffix(line, "\\")) + " " + strings.TrimSpace(u.lines[i])
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || section != "Service" || subcommands[key] == "" {
+			continue
+		}
+		if value == "" {
+			// An empty assignment resets the list of commands
+			u.Commands = removeDirective(u.Commands, key)
+			continue
+		}
+
+		prefixes := value[:len(value)-len(strings.TrimLeft(value, "-@:+!"))]
+		args, err := splitCommand(value[len(prefixes):])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %v", start+1, key, err)
+		}
+		if len(args) == 0 {
+			return nil, fmt.Errorf("line %d: %s has no command", start+1, key)
+		}
+		u.Commands = append(u.Commands, Command{
+			Directive: key,
+			Line:      start + 1,
+			Prefixes:  prefixes,
+			Args:      args,
+		})
+	}
+	if u.service[0] < 0 {
+		return nil, fmt.Errorf("no [Service] section")
+	}
+	return u, nil
+}
+
+// Subcommands returns the subcommands needed to run the commands of the
+// unit, in the order start, stop, reload
+func (u *Unit) Subcommands() []string {
+	var names []string
+	for _, name := range []string{Start, Stop, Reload} {
+		for _, c := range u.Commands {
+			if c.Subcommand() == name {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// Rewrite returns the unit with its Exec directives replaced by calls to
+// the subcommands of binary. Reloading signals the running program with
+// SIGHUP, which it handles by running the reload subcommand.
+func (u *Unit) Rewrite(binary string) string {
+	var b strings.Builder
+	inserted := false
+	for i := 0; i < len(u.lines); i++ {
+		line := u.lines[i]
+		if i > u.service[0] && i < u.service[1] {
+			key, _, _ := strings.Cut(strings.TrimSpace(line), "=")
+			if subcommands[strings.TrimSpace(key)] != "" {
+				// Skip the directive with its continuation lines
+				for strings.HasSuffix(strings.TrimSpace(u.lines[i]), "\\") && i+1 < len(u.lines) {
+					i++
+				}
+				if !inserted {
+					u.writeExec(&b, binary)
+					inserted = true
+				}
+				continue
+			}
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// writeExec writes the Exec directives running the subcommands of binary
+func (u *Unit) writeExec(b *strings.Builder, binary string) {
+	for _, name := range u.Subcommands() {
+		switch name {
+		case Start:
+			fmt.Fprintf(b, "ExecStart=%s start\n", binary)
+		case Stop:
+			fmt.Fprintf(b, "ExecStop=%s stop\n", binary)
+		case Reload:
+			b.WriteString("ExecReload=/bin/kill -HUP $MAINPID\n")
+		}
+	}
+}
+
+// removeDirective returns commands without those of the directive key
+func removeDirective(commands []Command, key string) []Command {
+	var kept []Command
+	for _, c := range commands {
+		if c.Directive != key {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// splitCommand splits a command line into its words, removing quotes and
+// escapes as systemd does
+func splitCommand(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	var inWord bool
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+				continue
+			}
+			if c == '\\' && i+1 < len(s) {
+				i++
+				c = s[i]
+			}
+		case c == '"' || c == '\'':
+			quote = c
+			inWord = true
+			continue
+		case c == '\\' && i+1 < len(s):
+			i++
+			c = s[i]
+		case c == ' ' || c == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+			continue
+		}
+		word.WriteByte(c)
+		inWord = true
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// isShell reports whether path runs a Bash-compatible shell
+func isShell(path string) bool {
+	switch filepath.Base(path) {
+	case "sh", "bash", "dash":
+		return true
+	}
+	return false
+}
+
+// quote quotes s as a single Bash word if needed
+func quote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-./=:,@%+$") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
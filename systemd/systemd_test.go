@@ -0,0 +1,90 @@
+package systemd
+
+import (
+	"strings"
+	"testing"
+)
+
+const unit = `[Unit]
+Description=Example app
+
+[Service]
+Type=simple
+ExecStartPre=-/bin/mkdir -p /var/lib/app
+ExecStart=/opt/app/start.sh --port 8080
+ExecStop=/bin/bash -c 'kill "$(cat /run/app.pid)" && \
+    rm -f /run/app.pid'
+ExecReload=/opt/app/reload.sh
+Restart=on-failure
+
+[Install]
+WantedBy=multi-user.target
+`
+
+// TestParse tests extracting the Exec directives of a service
+func TestParse(t *testing.T) {
+	u, err := Parse(strings.NewReader(unit))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(u.Commands) != 4 {
+		t.Fatalf("Expected 4 commands, got %d: %+v", len(u.Commands), u.Commands)
+	}
+
+	pre := u.Commands[0]
+	if pre.Subcommand() != Start || pre.Prefixes != "-" || pre.InlineScript() != "/bin/mkdir -p /var/lib/app" {
+		t.Errorf("Unexpected ExecStartPre: %+v", pre)
+	}
+	if file, ok := u.Commands[1].ScriptFile(); !ok || file != "/opt/app/start.sh" {
+		t.Errorf("Expected the ExecStart script file, got %q", file)
+	}
+
+	stop := u.Commands[2]
+	if _, ok := stop.ScriptFile(); ok {
+		t.Errorf("Expected ExecStop to be inline")
+	}
+	if got := stop.InlineScript(); got != `kill "$(cat /run/app.pid)" && rm -f /run/app.pid` {
+		t.Errorf("Unexpected ExecStop script: %q", got)
+	}
+
+	if got := strings.Join(u.Subcommands(), " "); got != "start stop reload" {
+		t.Errorf("Unexpected subcommands: %s", got)
+	}
+}
+
+// TestRewrite tests pointing a unit at the converted program
+func TestRewrite(t *testing.T) {
+	u, err := Parse(strings.NewReader(unit))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	got := u.Rewrite("/usr/local/bin/app")
+	want := `[Unit]
+Description=Example app
+
+[Service]
+Type=simple
+ExecStart=/usr/local/bin/app start
+ExecStop=/usr/local/bin/app stop
+ExecReload=/bin/kill -HUP $MAINPID
+Restart=on-failure
+
+[Install]
+WantedBy=multi-user.target
+`
+	if got != want {
+		t.Errorf("Unexpected unit:\n%s", got)
+	}
+}
+
+// TestParseErrors tests rejecting units that cannot be converted
+func TestParseErrors(t *testing.T) {
+	for _, unit := range []string{
+		"[Unit]\nDescription=No service\n",
+		"[Service]\nExecStart=/bin/echo 'unterminated\n",
+	} {
+		if _, err := Parse(strings.NewReader(unit)); err == nil {
+			t.Errorf("Expected Parse to fail for %q", unit)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+#!/bin/bash
+greet() {
+	echo "hello $1"
+}
+
+mkdir -p build
+cd build
+greet builder
@@ -0,0 +1,3 @@
+#!/bin/bash
+# Print a greeting
+echo "Hello, World!"
@@ -0,0 +1,4 @@
+#!/bin/bash
+for i in 1 2 3; do
+	echo "item $i"
+done
@@ -0,0 +1,4 @@
+#!/bin/bash
+NAME=world
+GREETING="hello $NAME"
+echo "$GREETING" $HOME
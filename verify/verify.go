@@ -0,0 +1,251 @@
+// Package verify runs Bash scripts and the programs converted from them on
+// the same recorded inputs and compares what they do, as a differential test
+// of the generator over a corpus of scripts.
+package verify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CasesSuffix is the suffix of the file recording the inputs of a corpus
+// script: the cases of foo.sh are read from foo.cases.yaml
+const CasesSuffix = ".cases.yaml"
+
+// DefaultTimeout bounds a single run of a script or program
+const DefaultTimeout = 30 * time.Second
+
+// maxFileSize is the largest file in the working directory compared after a
+// run; larger files are compared by size only
+const maxFileSize = 1 << 20
+
+// Case is one recorded set of inputs to run a script with
+type Case struct {
+	Name  string            `yaml:"name"`
+	Args  []string          `yaml:"args,omitempty"`
+	Stdin string            `yaml:"stdin,omitempty"`
+	Env   map[string]string `yaml:"env,omitempty"`
+	// Files are created in the working directory before the run, keyed by
+	// their relative path
+	Files map[string]string `yaml:"files,omitempty"`
+}
+
+// Script is a script of the corpus with its cases
+type Script struct {
+	Path  string
+	Cases []Case
+}
+
+// LoadCorpus returns the .sh scripts in dir with their cases. A script
+// without a cases file is run once without arguments or input.
+func LoadCorpus(dir string) ([]Script, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.sh"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no .sh scripts in %s", dir)
+	}
+	sort.Strings(paths)
+
+	scripts := make([]Script, 0, len(paths))
+	for _, path := range paths {
+		cases, err := loadCases(strings.TrimSuffix(path, ".sh") + CasesSuffix)
+		if err != nil {
+			return nil, err
+		}
+		scripts = append(scripts, Script{Path: path, Cases: cases})
+	}
+	return scripts, nil
+}
+
+// loadCases reads the cases file at path, if it exists
+func loadCases(path string) ([]Case, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []Case{{Name: "default"}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cases []Case
+	if err := yaml.Unmarshal(data, &cases); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("%s: no cases", path)
+	}
+	seen := make(map[string]bool)
+	for i := range cases {
+		if cases[i].Name == "" {
+			cases[i].Name = fmt.Sprintf("case-%d", i+1)
+		}
+		if seen[cases[i].Name] {
+			return nil, fmt.Errorf("%s: duplicate case %s", path, cases[i].Name)
+		}
+		seen[cases[i].Name] = true
+	}
+	return cases, nil
+}
+
+// Outcome is what a run of a script or program did
+type Outcome struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	// Files holds the contents of the regular files in the working directory
+	// after the run, keyed by their slash-separated relative path
+	Files map[string]string
+}
+
+// Run runs command in a new temporary working directory prepared for c and
+// returns its outcome. Runs that exceed timeout are killed and reported as
+// errors.
+func Run(command []string, c Case, timeout time.Duration) (Outcome, error) {
+	var outcome Outcome
+	dir, err := os.MkdirTemp("", "bash2go-verify-")
+	if err != nil {
+		return outcome, err
+	}
+	defer os.RemoveAll(dir)
+
+	for name, content := range c.Files {
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			return outcome, fmt.Errorf("case %s: file %s is outside the working directory", c.Name, name)
+		}
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return outcome, err
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return outcome, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, command[0], append(command[1:], c.Args...)...)
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(c.Stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = os.Environ()
+	for name, value := range c.Env {
+		cmd.Env = append(cmd.Env, name+"="+value)
+	}
+
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		return outcome, fmt.Errorf("timed out after %s", timeout)
+	case errors.As(err, &exitErr):
+		outcome.ExitCode = exitErr.ExitCode()
+	case err != nil:
+		return outcome, err
+	}
+	outcome.Stdout = stdout.String()
+	outcome.Stderr = stderr.String()
+	outcome.Files, err = readFiles(dir)
+	return outcome, err
+}
+
+// readFiles returns the contents of the regular files under dir
+func readFiles(dir string) (map[string]string, error) {
+	files := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if info.Size() > maxFileSize {
+			files[filepath.ToSlash(rel)] = fmt.Sprintf("<%d bytes>", info.Size())
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		files[filepath.ToSlash(rel)] = string(data)
+		return err
+	})
+	return files, err
+}
+
+// Compare describes how the outcome of a converted program differs from the
+// outcome of the script. Standard error is not compared, since the programs
+// word their error messages differently.
+func Compare(script, program Outcome) []string {
+	var diffs []string
+	if script.ExitCode != program.ExitCode {
+		diffs = append(diffs, fmt.Sprintf("exit code: bash %d, go %d", script.ExitCode, program.ExitCode))
+	}
+	if diff := difference(script.Stdout, program.Stdout); diff != "" {
+		diffs = append(diffs, "stdout: "+diff)
+	}
+
+	names := make(map[string]bool)
+	for name := range script.Files {
+		names[name] = true
+	}
+	for name := range program.Files {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		want, inScript := script.Files[name]
+		got, inProgram := program.Files[name]
+		switch {
+		case !inProgram:
+			diffs = append(diffs, fmt.Sprintf("file %s: not created by go", name))
+		case !inScript:
+			diffs = append(diffs, fmt.Sprintf("file %s: not created by bash", name))
+		default:
+			if diff := difference(want, got); diff != "" {
+				diffs = append(diffs, fmt.Sprintf("file %s: %s", name, diff))
+			}
+		}
+	}
+	return diffs
+}
+
+// difference describes the first line where got differs from want, or
+// returns an empty string if they are equal
+func difference(want, got string) string {
+	if want == got {
+		return ""
+	}
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+	for i := 0; ; i++ {
+		switch {
+		case i >= len(wantLines):
+			return fmt.Sprintf("line %d: go wrote extra %q", i+1, gotLines[i])
+		case i >= len(gotLines):
+			return fmt.Sprintf("line %d: go is missing %q", i+1, wantLines[i])
+		case wantLines[i] != gotLines[i]:
+			return fmt.Sprintf("line %d: bash %q, go %q", i+1, wantLines[i], gotLines[i])
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package verify
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLoadCorpus tests reading scripts and their recorded cases
+func TestLoadCorpus(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.sh":         "echo a\n",
+		"b.sh":         "echo \"$1\"\n",
+		"b.cases.yaml": "- name: hello\n  args: [hello]\n- stdin: input\n  env: {MODE: test}\n",
+		"notes.txt":    "not a script\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scripts, err := LoadCorpus(dir)
+	if err != nil {
+		t.Fatalf("LoadCorpus failed: %v", err)
+	}
+	if len(scripts) != 2 || filepath.Base(scripts[0].Path) != "a.sh" {
+		t.Fatalf("Unexpected scripts: %+v", scripts)
+	}
+	if len(scripts[0].Cases) != 1 || scripts[0].Cases[0].Name != "default" {
+		t.Errorf("Expected a default case without a cases file, got %+v", scripts[0].Cases)
+	}
+	cases := scripts[1].Cases
+	if len(cases) != 2 || cases[0].Args[0] != "hello" || cases[1].Name != "case-2" || cases[1].Env["MODE"] != "test" {
+		t.Errorf("Unexpected cases: %+v", cases)
+	}
+
+	// Duplicate case names are rejected
+	if err := os.WriteFile(filepath.Join(dir, "a.cases.yaml"), []byte("- name: x\n- name: x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCorpus(dir); err == nil {
+		t.Error("Expected LoadCorpus to reject duplicate case names")
+	}
+}
+
+// TestRunCompare tests running commands on a case and comparing outcomes
+func TestRunCompare(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test commands are shell scripts")
+	}
+
+	c := Case{
+		Name:  "copy",
+		Args:  []string{"arg"},
+		Stdin: "input\n",
+		Env:   map[string]string{"GREETING": "hi"},
+		Files: map[string]string{"in/data.txt": "data\n"},
+	}
+	script := `echo "$GREETING $1"; cat > out.txt; cat in/data.txt; exit 3`
+	want, err := Run([]string{"sh", "-c", script, "sh"}, c, time.Minute)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if want.Stdout != "hi arg\ndata\n" || want.ExitCode != 3 || want.Files["out.txt"] != "input\n" {
+		t.Errorf("Unexpected outcome: %+v", want)
+	}
+	if diffs := Compare(want, want); len(diffs) != 0 {
+		t.Errorf("Expected identical outcomes to match, got %v", diffs)
+	}
+
+	got, err := Run([]string{"sh", "-c", `echo "$GREETING"; echo changed > in/data.txt`, "sh"}, c, time.Minute)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	diffs := strings.Join(Compare(want, got), "\n")
+	for _, expected := range []string{
+		"exit code: bash 3, go 0",
+		`stdout: line 1: bash "hi arg", go "hi"`,
+		"file in/data.txt: line 1",
+		"file out.txt: not created by go",
+	} {
+		if !strings.Contains(diffs, expected) {
+			t.Errorf("Differences do not contain %q:\n%s", expected, diffs)
+		}
+	}
+
+	// Runs are bounded by the timeout
+	if _, err := Run([]string{"sleep", "5"}, Case{}, 50*time.Millisecond); err == nil {
+		t.Error("Expected Run to time out")
+	}
+}
@@ -0,0 +1,61 @@
+//go:build js && wasm
+
+// Command wasm exposes the bash2go transpiler to JavaScript. Built with
+// GOOS=js GOARCH=wasm, it registers a global bash2go object whose convert
+// function takes a Bash script and an optional options object, and returns
+// {code, diagnostics, metrics, error}.
+package main
+
+import (
+	"encoding/json"
+	"syscall/js"
+
+	"github.com/TFMV/bash2go/pkg/api"
+)
+
+func main() {
+	js.Global().Set("bash2go", js.ValueOf(map[string]interface{}{
+		"convert": js.FuncOf(convert),
+	}))
+
+	// Keep the Go runtime alive to serve calls from JavaScript
+	select {}
+}
+
+// response is the value returned to JavaScript by convert
+type response struct {
+	api.Result
+	Error string `json:"error,omitempty"`
+}
+
+// convert implements bash2go.convert(script, options)
+func convert(this js.Value, args []js.Value) interface{} {
+	if len(args) == 0 || args[0].Type() != js.TypeString {
+		return toJS(response{Error: "convert expects the Bash script as a string"})
+	}
+
+	var options api.Options
+	if len(args) > 1 && args[1].Type() == js.TypeObject {
+		// Options arrive as a plain object; decode them through JSON
+		data := js.Global().Get("JSON").Call("stringify", args[1]).String()
+		if err := json.Unmarshal([]byte(data), &options); err != nil {
+			return toJS(response{Error: "invalid options: " + err.Error()})
+		}
+	}
+
+	result, err := api.Convert([]byte(args[0].String()), options)
+	resp := response{Result: result}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	return toJS(resp)
+}
+
+// toJS converts resp to a JavaScript object
+func toJS(resp response) js.Value {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		data, _ = json.Marshal(response{Error: err.Error()})
+	}
+	return js.Global().Get("JSON").Call("parse", string(data))
+}